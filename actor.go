@@ -0,0 +1,24 @@
+package whisker
+
+import "context"
+
+// ActorSetting is the PostgreSQL setting that carries the current actor, the
+// user or service on whose behalf a statement writes. Like TenantSetting, the
+// store sets it on the connection of every statement, in a session or not,
+// so the history of collections created with documents.WithHistory records
+// it.
+const ActorSetting = "whisker.actor"
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the given actor. Writes run with this
+// context record it in document history.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor carried by ctx, if any.
+func ActorFrom(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok && actor != ""
+}
@@ -11,10 +11,12 @@ type backend struct {
 	codec        codecs.Codec
 	schema       *schema.Bootstrap
 	maxBatchSize int
+	clock        Clock
 }
 
 // Backend provides access to the core Whisker subsystems: database executor,
-// JSON codec, and schema bootstrap. Both Store and Session implement it.
+// JSON codec and schema bootstrap. Both Store and Session implement it, and
+// Clocked.
 type Backend interface {
 	DBExecutor() pg.Executor
 	JSONCodec() codecs.Codec
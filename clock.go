@@ -0,0 +1,34 @@
+package whisker
+
+import "time"
+
+// Clock supplies the timestamps Whisker writes to created_at, updated_at and
+// deleted_at columns. By default the database's now() is used; set a Clock
+// with WithClock to bind timestamps as parameters instead, for example to
+// freeze time in tests. Checkpoint bookkeeping and ORM hooks always use
+// database time.
+type Clock interface {
+	Now() time.Time
+}
+
+// FixedClock is a Clock that always returns the same instant.
+type FixedClock time.Time
+
+// Now returns the fixed instant.
+func (c FixedClock) Now() time.Time { return time.Time(c) }
+
+// Clocked is implemented by backends with a configured Clock. Store, Session
+// and the ReadOnly handle implement it.
+type Clocked interface {
+	// Clock returns the configured clock, or nil to use database time.
+	Clock() Clock
+}
+
+// ClockOf returns the clock of b, or nil, for database time, when b has
+// none or is not Clocked.
+func ClockOf(b Backend) Clock {
+	if c, ok := b.(Clocked); ok {
+		return c.Clock()
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package whisker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker/internal/codecs"
+	"github.com/ripkitten-co/whisker/internal/pg"
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+// plainBackend is a Backend that is not Clocked.
+type plainBackend struct{}
+
+func (plainBackend) DBExecutor() pg.Executor            { return nil }
+func (plainBackend) JSONCodec() codecs.Codec            { return nil }
+func (plainBackend) SchemaBootstrap() *schema.Bootstrap { return nil }
+func (plainBackend) MaxBatchSize() int                  { return 0 }
+
+func TestClockOf(t *testing.T) {
+	frozen := FixedClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	s := &Store{be: backend{clock: frozen}}
+	if got := ClockOf(s); got != frozen {
+		t.Errorf("store: got %v, want the configured clock", got)
+	}
+	if got := ClockOf(s.ReadOnly()); got != frozen {
+		t.Errorf("read-only handle: got %v, want the configured clock", got)
+	}
+	if got := ClockOf(plainBackend{}); got != nil {
+		t.Errorf("backend without a clock: got %v, want nil for database time", got)
+	}
+}
@@ -0,0 +1,183 @@
+package whisker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TryAdvisoryLock attempts a PostgreSQL session-level advisory lock on key.
+// The lock is held on a dedicated pooled connection until unlock is called,
+// which releases the lock and returns the connection to the pool. Returns
+// acquired=false, with a nil unlock, if another session holds the lock. Held
+// locks delay Shutdown until they are released. If the unlock statement
+// fails, the connection is closed instead of being returned to the pool, so
+// the server drops the lock rather than leaking it to the next borrower.
+func (s *Store) TryAdvisoryLock(ctx context.Context, key int64) (unlock func(context.Context) error, acquired bool, err error) {
+	if err := s.lc.acquire(); err != nil {
+		return nil, false, fmt.Errorf("whisker: advisory lock %d: %w", key, err)
+	}
+	conn, err := s.pool.PgxPool().Acquire(ctx)
+	if err != nil {
+		s.lc.release()
+		return nil, false, fmt.Errorf("whisker: advisory lock %d: acquire conn: %w", key, err)
+	}
+
+	err = conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired)
+	if err != nil {
+		conn.Release()
+		s.lc.release()
+		return nil, false, fmt.Errorf("whisker: advisory lock %d: %w", key, err)
+	}
+	if !acquired {
+		conn.Release()
+		s.lc.release()
+		return nil, false, nil
+	}
+	return s.advisoryUnlock(conn, key), true, nil
+}
+
+// AdvisoryLock takes a PostgreSQL session-level advisory lock on key, waiting
+// for other sessions to release it. Cancel ctx or give it a deadline to bound
+// the wait. The lock is held on a dedicated pooled connection until unlock is
+// called, as with TryAdvisoryLock. Waiters are queued by the server, so a
+// blocked AdvisoryLock is granted the lock before later TryAdvisoryLock calls.
+// A lock_timeout set with WithSessionTimeouts also bounds the wait.
+func (s *Store) AdvisoryLock(ctx context.Context, key int64) (unlock func(context.Context) error, err error) {
+	if err := s.lc.acquire(); err != nil {
+		return nil, fmt.Errorf("whisker: advisory lock %d: %w", key, err)
+	}
+	conn, err := s.pool.PgxPool().Acquire(ctx)
+	if err != nil {
+		s.lc.release()
+		return nil, fmt.Errorf("whisker: advisory lock %d: acquire conn: %w", key, err)
+	}
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		// the lock may have been granted as the wait was cancelled; closing
+		// the connection drops it either way
+		_ = conn.Hijack().Close(context.Background())
+		s.lc.release()
+		return nil, fmt.Errorf("whisker: advisory lock %d: %w", key, err)
+	}
+	return s.advisoryUnlock(conn, key), nil
+}
+
+// advisoryUnlock returns the unlock func for an advisory lock held on conn.
+func (s *Store) advisoryUnlock(conn *pgxpool.Conn, key int64) func(context.Context) error {
+	var once sync.Once
+	return func(ctx context.Context) error {
+		var unlockErr error
+		once.Do(func() {
+			defer s.lc.release()
+			var released bool
+			if err := conn.QueryRow(ctx, "SELECT pg_advisory_unlock($1)", key).Scan(&released); err != nil {
+				_ = conn.Hijack().Close(context.Background())
+				unlockErr = fmt.Errorf("whisker: advisory unlock %d: %w", key, err)
+				return
+			}
+			conn.Release()
+		})
+		return unlockErr
+	}
+}
+
+// Listener is a dedicated connection subscribed to a LISTEN/NOTIFY channel.
+// Notifications that arrive between calls to Wait are buffered.
+type Listener interface {
+	// Wait blocks until a notification arrives and returns its payload.
+	Wait(ctx context.Context) (string, error)
+	// Ping checks that the connection is still alive.
+	Ping(ctx context.Context) error
+	// Close unsubscribes and returns the connection to the pool.
+	Close(ctx context.Context) error
+}
+
+// Listen subscribes a dedicated pooled connection to channel. The connection
+// is held until the listener is closed, and open listeners delay Shutdown;
+// Shutdown interrupts their Wait calls, which then return ErrStoreClosed.
+func (s *Store) Listen(ctx context.Context, channel string) (Listener, error) {
+	if err := s.lc.acquire(); err != nil {
+		return nil, fmt.Errorf("whisker: listen %s: %w", channel, err)
+	}
+	conn, err := s.pool.PgxPool().Acquire(ctx)
+	if err != nil {
+		s.lc.release()
+		return nil, fmt.Errorf("whisker: listen %s: acquire conn: %w", channel, err)
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		conn.Release()
+		s.lc.release()
+		return nil, fmt.Errorf("whisker: listen %s: %w", channel, err)
+	}
+	return &listener{store: s, conn: conn, channel: channel}, nil
+}
+
+type listener struct {
+	store   *Store
+	conn    *pgxpool.Conn
+	channel string
+	once    sync.Once
+}
+
+func (l *listener) Wait(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(l.store.lc.ctx, cancel)
+	defer stop()
+
+	n, err := l.conn.Conn().WaitForNotification(ctx)
+	if err != nil {
+		if l.store.lc.ctx.Err() != nil {
+			err = ErrStoreClosed
+		}
+		return "", fmt.Errorf("whisker: listen %s: wait: %w", l.channel, err)
+	}
+	return n.Payload, nil
+}
+
+func (l *listener) Ping(ctx context.Context) error {
+	if err := l.conn.Ping(ctx); err != nil {
+		return fmt.Errorf("whisker: listen %s: ping: %w", l.channel, err)
+	}
+	return nil
+}
+
+// Close unlistens so the connection can be reused by the pool. A broken
+// connection is closed instead.
+func (l *listener) Close(ctx context.Context) error {
+	var err error
+	l.once.Do(func() {
+		defer l.store.lc.release()
+		if _, uerr := l.conn.Exec(ctx, "UNLISTEN "+pgx.Identifier{l.channel}.Sanitize()); uerr != nil {
+			_ = l.conn.Hijack().Close(context.Background())
+			err = fmt.Errorf("whisker: unlisten %s: %w", l.channel, uerr)
+			return
+		}
+		l.conn.Release()
+	})
+	return err
+}
+
+// WaitForNotification blocks until a NOTIFY arrives on channel or the context
+// is cancelled. Shutdown interrupts waiting callers, which then return
+// ErrStoreClosed. Long-lived consumers should hold a Listener instead of
+// subscribing on every call.
+func (s *Store) WaitForNotification(ctx context.Context, channel string) error {
+	l, err := s.Listen(ctx, channel)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		_ = l.Close(closeCtx)
+	}()
+
+	_, err = l.Wait(ctx)
+	return err
+}
@@ -0,0 +1,13 @@
+// Package whisker is a document store and event store on PostgreSQL.
+//
+// The root package holds the Store, Session and shared configuration. Typed
+// access lives in subpackages that accept any Backend, so the same code runs
+// against a Store or inside a Session:
+//
+//   - documents.Collection for JSONB document CRUD and queries
+//   - events.New for append-only event streams
+//   - projections for read models and side-effect handlers built from events
+//
+// documents.CollectionOf is the only collection implementation; there is no
+// separate collection type in this package.
+package whisker
@@ -0,0 +1,273 @@
+package documents
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+// Aggregation is an aggregate function computed by Query.Aggregate. Build one
+// with Count, Sum, Avg, Min or Max.
+type Aggregation struct {
+	fn    string
+	field string
+	alias string
+}
+
+// Count counts the documents of each group. Its result is an int64 named
+// "count".
+func Count() Aggregation {
+	return Aggregation{fn: "COUNT", alias: "count"}
+}
+
+// Sum adds up the numeric field over each group. Its result is named
+// "sum_<field>".
+func Sum(field string) Aggregation {
+	return Aggregation{fn: "SUM", field: field, alias: "sum_" + field}
+}
+
+// Avg averages the numeric field over each group. Its result is named
+// "avg_<field>".
+func Avg(field string) Aggregation {
+	return Aggregation{fn: "AVG", field: field, alias: "avg_" + field}
+}
+
+// Min is the smallest value of the numeric field in each group. Its result
+// is named "min_<field>".
+func Min(field string) Aggregation {
+	return Aggregation{fn: "MIN", field: field, alias: "min_" + field}
+}
+
+// Max is the largest value of the numeric field in each group. Its result
+// is named "max_<field>".
+func Max(field string) Aggregation {
+	return Aggregation{fn: "MAX", field: field, alias: "max_" + field}
+}
+
+// As returns the aggregation with its result named alias in
+// AggregateRow.Values.
+func (a Aggregation) As(alias string) Aggregation {
+	a.alias = alias
+	return a
+}
+
+// AggregateRow is one group of an aggregate query.
+type AggregateRow struct {
+	// Group maps each GroupBy field to the group's value as text. A field
+	// that is missing or null in the group's documents has no entry.
+	Group map[string]string
+	// Values maps each aggregation's name to its result, typed after the
+	// field: an int64 for counts and for the sum, minimum and maximum of an
+	// integer field, a time.Time for the minimum and maximum of a time.Time
+	// field, and a float64 otherwise, including every average. Aggregates
+	// over no values, such as the sum of a field no document has, have no
+	// entry; counts are always present.
+	Values map[string]any
+}
+
+// GroupBy groups the documents Aggregate computes over by the given fields,
+// one row per distinct combination of their values. Without GroupBy,
+// Aggregate returns a single row over every matching document.
+func (q *Query[T]) GroupBy(fields ...string) *Query[T] {
+	c := q.clone()
+	c.groupBys = append(c.groupBys, fields...)
+	return c
+}
+
+// GroupBy starts a query grouping the collection's documents by the given
+// fields. See Query.GroupBy.
+func (c *CollectionOf[T]) GroupBy(fields ...string) *Query[T] {
+	return c.Query().GroupBy(fields...)
+}
+
+// Aggregate computes aggs over the documents matching the query, per group
+// when GroupBy is set, so dashboards need no raw SQL:
+//
+//	rows, err := orders.GroupBy("status").Aggregate(ctx, documents.Sum("total"), documents.Count())
+//	for _, r := range rows {
+//		fmt.Println(r.Group["status"], r.Values["sum_total"], r.Values["count"])
+//	}
+//
+// Sum, Avg, Min and Max cast the field to numeric, or Min and Max of a
+// time.Time field to timestamptz, and fail on values that are not numbers or
+// times. See AggregateRow.Values for the type of each result. Rows are
+// ordered by the query's OrderBy fields, which must be group fields, or else
+// by the group fields. Limit and Offset page through the groups.
+func (q *Query[T]) Aggregate(ctx context.Context, aggs ...Aggregation) ([]AggregateRow, error) {
+	if len(aggs) == 0 {
+		return nil, fmt.Errorf("query: aggregate: no aggregations")
+	}
+	if err := q.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	q, err := q.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sql, args, err := q.toAggregateSQL(aggs)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.exec.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: aggregate: %w", err)
+	}
+	defer rows.Close()
+
+	var results []AggregateRow
+	for rows.Next() {
+		groups := make([]*string, len(q.groupBys))
+		values := make([]any, len(aggs))
+		dest := make([]any, 0, len(groups)+len(values))
+		for i := range groups {
+			dest = append(dest, &groups[i])
+		}
+		for i, a := range aggs {
+			values[i] = q.aggregateResult(a).dest()
+			dest = append(dest, values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("query: aggregate: scan: %w", err)
+		}
+
+		row := AggregateRow{Group: make(map[string]string, len(groups)), Values: make(map[string]any, len(values))}
+		for i, g := range groups {
+			if g != nil {
+				row.Group[q.groupBys[i]] = *g
+			}
+		}
+		for i, v := range values {
+			if v, ok := scanned(v); ok {
+				row.Values[aggs[i].alias] = v
+			}
+		}
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query: aggregate: %w", err)
+	}
+	return results, nil
+}
+
+func (q *Query[T]) toAggregateSQL(aggs []Aggregation) (string, []any, error) {
+	groups := make([]string, len(q.groupBys))
+	columns := make([]string, 0, len(groups)+len(aggs))
+	for i, f := range q.groupBys {
+		expr, err := q.resolve(f)
+		if err != nil {
+			return "", nil, err
+		}
+		groups[i] = expr
+		columns = append(columns, fmt.Sprintf("(%s)::text", expr))
+	}
+	for _, a := range aggs {
+		if a.fn == "COUNT" {
+			columns = append(columns, "COUNT(*)")
+			continue
+		}
+		expr, err := q.resolve(a.field)
+		if err != nil {
+			return "", nil, err
+		}
+		switch q.aggregateResult(a) {
+		case intResult:
+			columns = append(columns, fmt.Sprintf("%s((%s)::numeric)::bigint", a.fn, expr))
+		case timeResult:
+			columns = append(columns, fmt.Sprintf("%s((%s)::timestamptz)", a.fn, expr))
+		default:
+			columns = append(columns, fmt.Sprintf("%s((%s)::numeric)::float8", a.fn, expr))
+		}
+	}
+
+	builder, err := q.applyConditions(psql.Select(columns...).From(q.table))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(groups) > 0 {
+		builder = builder.GroupBy(groups...)
+	}
+
+	order := slices.Clone(groups)
+	if len(q.orderBys) > 0 {
+		order = order[:0]
+		for _, ob := range q.orderBys {
+			expr, err := q.resolve(ob.field)
+			if err != nil {
+				return "", nil, err
+			}
+			order = append(order, fmt.Sprintf("%s %s", expr, ob.direction))
+		}
+	}
+	if len(order) > 0 {
+		builder = builder.OrderBy(order...)
+	}
+
+	if q.limit != nil {
+		builder = builder.Limit(*q.limit)
+	}
+	if q.offset != nil {
+		builder = builder.Offset(*q.offset)
+	}
+	return builder.ToSql()
+}
+
+// aggregateResult is the Go type an aggregation's result is scanned into.
+type aggregateResult int
+
+const (
+	floatResult aggregateResult = iota
+	intResult
+	timeResult
+)
+
+// aggregateResult returns the type of a's result: whole numbers stay integers
+// and times stay times, so neither loses precision to a float64.
+func (q *Query[T]) aggregateResult(a Aggregation) aggregateResult {
+	m := meta.Analyze[T]()
+	switch {
+	case a.fn == "COUNT":
+		return intResult
+	case a.fn == "AVG":
+		return floatResult
+	case m.Integer(a.field):
+		return intResult
+	case (a.fn == "MIN" || a.fn == "MAX") && m.CastOf(a.field) == meta.CastTimestamptz:
+		return timeResult
+	}
+	return floatResult
+}
+
+// dest returns a scan destination for a nullable result of type r.
+func (r aggregateResult) dest() any {
+	switch r {
+	case intResult:
+		return new(*int64)
+	case timeResult:
+		return new(*time.Time)
+	}
+	return new(*float64)
+}
+
+// scanned returns the value scanned into dest, a destination from
+// aggregateResult.dest, and whether it was not null.
+func scanned(dest any) (any, bool) {
+	switch d := dest.(type) {
+	case **int64:
+		if *d != nil {
+			return **d, true
+		}
+	case **time.Time:
+		if *d != nil {
+			return **d, true
+		}
+	case **float64:
+		if *d != nil {
+			return **d, true
+		}
+	}
+	return nil, false
+}
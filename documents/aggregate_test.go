@@ -0,0 +1,142 @@
+package documents
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+func TestQuery_AggregateSQL(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   *Query[testDoc]
+		aggs    []Aggregation
+		wantSQL string
+	}{
+		{
+			name:    "ungrouped",
+			query:   &Query[testDoc]{table: "whisker_orders"},
+			aggs:    []Aggregation{Count(), Avg("total")},
+			wantSQL: "SELECT COUNT(*), AVG((data->>'total')::numeric)::float8 FROM whisker_orders",
+		},
+		{
+			name:  "grouped and filtered",
+			query: (&Query[testDoc]{table: "whisker_orders"}).Where("region", "=", "eu").GroupBy("status"),
+			aggs:  []Aggregation{Sum("total"), Count()},
+			wantSQL: "SELECT (data->>'status')::text, SUM((data->>'total')::numeric)::float8, COUNT(*) " +
+				"FROM whisker_orders WHERE data->>'region' = $1 GROUP BY data->>'status' ORDER BY data->>'status'",
+		},
+		{
+			name: "ordered and limited",
+			query: (&Query[testDoc]{table: "whisker_orders"}).GroupBy("status", "region").
+				OrderBy("region", Desc).Limit(5),
+			aggs: []Aggregation{Min("total"), Max("total")},
+			wantSQL: "SELECT (data->>'status')::text, (data->>'region')::text, MIN((data->>'total')::numeric)::float8, " +
+				"MAX((data->>'total')::numeric)::float8 FROM whisker_orders GROUP BY data->>'status', data->>'region' " +
+				"ORDER BY data->>'region' DESC LIMIT 5",
+		},
+		{
+			name: "generated column",
+			query: (&Query[testDoc]{
+				table:   "whisker_orders",
+				columns: []meta.ColumnMeta{{Name: "total", FieldJSONKey: "total"}},
+			}).GroupBy("status"),
+			aggs: []Aggregation{Sum("total")},
+			wantSQL: "SELECT (data->>'status')::text, SUM((total)::numeric)::float8 FROM whisker_orders " +
+				"GROUP BY data->>'status' ORDER BY data->>'status'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, _, err := tt.query.toAggregateSQL(tt.aggs)
+			if err != nil {
+				t.Fatalf("toAggregateSQL: %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("sql:\n got: %s\nwant: %s", sql, tt.wantSQL)
+			}
+		})
+	}
+}
+
+type lineDoc struct {
+	ID       string
+	Quantity int
+	Price    float64
+	ShipAt   time.Time
+	Version  int
+}
+
+func TestQuery_AggregateSQLKeepsTheFieldType(t *testing.T) {
+	tests := []struct {
+		agg     Aggregation
+		wantSQL string
+		want    any
+	}{
+		{Count(), "COUNT(*)", new(*int64)},
+		{Sum("quantity"), "SUM((data->>'quantity')::numeric)::bigint", new(*int64)},
+		{Max("quantity"), "MAX((data->>'quantity')::numeric)::bigint", new(*int64)},
+		{Avg("quantity"), "AVG((data->>'quantity')::numeric)::float8", new(*float64)},
+		{Sum("price"), "SUM((data->>'price')::numeric)::float8", new(*float64)},
+		{Min("shipAt"), "MIN((data->>'shipAt')::timestamptz)", new(*time.Time)},
+		{Sum("shipAt"), "SUM((data->>'shipAt')::numeric)::float8", new(*float64)},
+	}
+
+	q := &Query[lineDoc]{table: "whisker_lines"}
+	for _, tt := range tests {
+		t.Run(tt.agg.alias, func(t *testing.T) {
+			sql, _, err := q.toAggregateSQL([]Aggregation{tt.agg})
+			if err != nil {
+				t.Fatalf("toAggregateSQL: %v", err)
+			}
+			if want := "SELECT " + tt.wantSQL + " FROM whisker_lines"; sql != want {
+				t.Errorf("sql:\n got: %s\nwant: %s", sql, want)
+			}
+			if got := q.aggregateResult(tt.agg).dest(); reflect.TypeOf(got) != reflect.TypeOf(tt.want) {
+				t.Errorf("dest: got %T, want %T", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanned(t *testing.T) {
+	n := int64(3)
+	dest := intResult.dest().(**int64)
+	if _, ok := scanned(dest); ok {
+		t.Error("a null result should have no value")
+	}
+	*dest = &n
+	if v, ok := scanned(dest); !ok || v != int64(3) {
+		t.Errorf("got %v (%T), want int64 3", v, v)
+	}
+}
+
+func TestQuery_AggregateInvalidField(t *testing.T) {
+	q := &Query[testDoc]{table: "whisker_orders"}
+	if _, _, err := q.toAggregateSQL([]Aggregation{Sum("total; DROP")}); err == nil {
+		t.Error("expected error for invalid aggregate field")
+	}
+	if _, _, err := q.GroupBy("bad field").toAggregateSQL([]Aggregation{Count()}); err == nil {
+		t.Error("expected error for invalid group field")
+	}
+}
+
+func TestAggregation_Names(t *testing.T) {
+	for _, tt := range []struct {
+		agg  Aggregation
+		want string
+	}{
+		{Count(), "count"},
+		{Sum("total"), "sum_total"},
+		{Avg("total"), "avg_total"},
+		{Min("total"), "min_total"},
+		{Max("total").As("largest"), "largest"},
+	} {
+		if tt.agg.alias != tt.want {
+			t.Errorf("%s(%s): alias %q, want %q", tt.agg.fn, tt.agg.field, tt.agg.alias, tt.want)
+		}
+	}
+}
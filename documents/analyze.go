@@ -0,0 +1,142 @@
+package documents
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+// Analysis reports how a collection's documents and indexes are used, to
+// guide whisker:"index" and whisker:"column" tuning. See Analyze.
+type Analysis struct {
+	// Sampled is the number of documents inspected.
+	Sampled int64
+	// Keys lists the top-level JSONB keys found, most frequent first.
+	Keys []KeyStats
+	// Indexes lists the collection's secondary indexes by name.
+	Indexes []IndexUsage
+}
+
+// KeyStats describes one top-level JSONB key across the sampled documents.
+type KeyStats struct {
+	Key string
+	// Present is the number of sampled documents containing the key, and
+	// Frequency that number as a fraction of Analysis.Sampled.
+	Present   int64
+	Frequency float64
+	// Distinct is the number of distinct values among the sample; a low
+	// count relative to Present means an index on the key is unselective.
+	Distinct int64
+	// Samples holds a few distinct non-null values as text, in sort order.
+	Samples []string
+}
+
+// IndexUsage reports the scans recorded by PostgreSQL for one index since
+// its statistics were last reset.
+type IndexUsage struct {
+	Name      string
+	Scans     int64
+	SizeBytes int64
+	// Unused is true when the index has never been scanned: it slows writes
+	// without serving reads.
+	Unused bool
+}
+
+// AnalyzeOption configures Analyze.
+type AnalyzeOption func(*analyzeConfig)
+
+type analyzeConfig struct {
+	sampleSize   int
+	valueSamples int
+}
+
+// WithSampleSize sets how many documents Analyze inspects. Defaults to
+// 10000, which keeps the scan cheap on large collections.
+func WithSampleSize(n int) AnalyzeOption {
+	return func(c *analyzeConfig) { c.sampleSize = n }
+}
+
+// WithValueSamples sets how many distinct values are reported per key.
+// Defaults to 5.
+func WithValueSamples(n int) AnalyzeOption {
+	return func(c *analyzeConfig) { c.valueSamples = n }
+}
+
+// Analyze samples the documents of collection name and reports which
+// top-level keys they contain, how many distinct values each key takes, and
+// which of the collection's indexes PostgreSQL has never used (from
+// pg_stat_user_indexes). Primary keys are not reported.
+func Analyze(ctx context.Context, b whisker.Backend, name string, opts ...AnalyzeOption) (*Analysis, error) {
+	if err := schema.ValidateCollectionName(name); err != nil {
+		return nil, fmt.Errorf("collection %s: analyze: %w", name, err)
+	}
+	cfg := analyzeConfig{sampleSize: 10000, valueSamples: 5}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	table := "whisker_" + name
+	exec := b.DBExecutor()
+
+	a := &Analysis{}
+	err := exec.QueryRow(ctx,
+		fmt.Sprintf(`SELECT count(*) FROM (SELECT 1 FROM %s LIMIT $1) s`, table),
+		cfg.sampleSize,
+	).Scan(&a.Sampled)
+	if err != nil {
+		return nil, fmt.Errorf("collection %s: analyze: count: %w", name, err)
+	}
+
+	rows, err := exec.Query(ctx, fmt.Sprintf(
+		`WITH sample AS (SELECT data FROM %s LIMIT $1)
+		 SELECT e.key, count(*), count(DISTINCT e.value),
+		        (array_agg(DISTINCT left(e.value #>> '{}', 64)) FILTER (WHERE e.value <> 'null'::jsonb))[1:$2]
+		 FROM sample, jsonb_each(sample.data) e
+		 GROUP BY e.key
+		 ORDER BY count(*) DESC, e.key`, table),
+		cfg.sampleSize, cfg.valueSamples,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("collection %s: analyze: keys: %w", name, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var k KeyStats
+		if err := rows.Scan(&k.Key, &k.Present, &k.Distinct, &k.Samples); err != nil {
+			return nil, fmt.Errorf("collection %s: analyze: scan keys: %w", name, err)
+		}
+		if a.Sampled > 0 {
+			k.Frequency = float64(k.Present) / float64(a.Sampled)
+		}
+		a.Keys = append(a.Keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("collection %s: analyze: keys: %w", name, err)
+	}
+
+	idxRows, err := exec.Query(ctx,
+		`SELECT s.indexrelname, s.idx_scan, pg_relation_size(s.indexrelid)
+		 FROM pg_stat_user_indexes s
+		 JOIN pg_index i ON i.indexrelid = s.indexrelid
+		 WHERE s.relid = $1::regclass AND NOT i.indisprimary
+		 ORDER BY s.indexrelname`,
+		table,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("collection %s: analyze: indexes: %w", name, err)
+	}
+	defer idxRows.Close()
+	for idxRows.Next() {
+		var u IndexUsage
+		if err := idxRows.Scan(&u.Name, &u.Scans, &u.SizeBytes); err != nil {
+			return nil, fmt.Errorf("collection %s: analyze: scan indexes: %w", name, err)
+		}
+		u.Unused = u.Scans == 0
+		a.Indexes = append(a.Indexes, u)
+	}
+	if err := idxRows.Err(); err != nil {
+		return nil, fmt.Errorf("collection %s: analyze: indexes: %w", name, err)
+	}
+	return a, nil
+}
@@ -0,0 +1,78 @@
+//go:build integration
+
+package documents_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/documents"
+)
+
+type AnalyzedOrder struct {
+	ID     string
+	Status string `whisker:"index"`
+	Note   string `json:"note,omitempty"`
+}
+
+func TestAnalyze_ReportsKeysAndUnusedIndexes(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	orders := documents.Collection[AnalyzedOrder](store, "analyzed_orders")
+
+	for i := range 10 {
+		o := &AnalyzedOrder{ID: fmt.Sprintf("o%d", i), Status: []string{"paid", "open"}[i%2]}
+		if i < 3 {
+			o.Note = "rush"
+		}
+		if err := orders.Insert(ctx, o); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	a, err := documents.Analyze(ctx, store, "analyzed_orders")
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	if a.Sampled != 10 {
+		t.Errorf("sampled: got %d, want 10", a.Sampled)
+	}
+
+	keys := map[string]documents.KeyStats{}
+	for _, k := range a.Keys {
+		keys[k.Key] = k
+	}
+	status := keys["status"]
+	if status.Present != 10 || status.Distinct != 2 || strings.Join(status.Samples, ",") != "open,paid" {
+		t.Errorf("status: got %+v", status)
+	}
+	note := keys["note"]
+	if note.Present != 3 || note.Frequency != 0.3 {
+		t.Errorf("note: got %+v", note)
+	}
+	if a.Keys[0].Key != "status" {
+		t.Errorf("most frequent key: got %q, want status", a.Keys[0].Key)
+	}
+
+	if len(a.Indexes) != 1 {
+		t.Fatalf("got indexes %+v, want the status index only", a.Indexes)
+	}
+	if !a.Indexes[0].Unused {
+		t.Errorf("index %s: got %d scans, want unused", a.Indexes[0].Name, a.Indexes[0].Scans)
+	}
+
+	sample, err := documents.Analyze(ctx, store, "analyzed_orders", documents.WithSampleSize(4), documents.WithValueSamples(1))
+	if err != nil {
+		t.Fatalf("analyze sample: %v", err)
+	}
+	if sample.Sampled != 4 {
+		t.Errorf("sampled: got %d, want 4", sample.Sampled)
+	}
+	for _, k := range sample.Keys {
+		if len(k.Samples) > 1 {
+			t.Errorf("key %s: got %d value samples, want at most 1", k.Key, len(k.Samples))
+		}
+	}
+}
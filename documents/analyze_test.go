@@ -0,0 +1,14 @@
+package documents
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAnalyze_RejectsInvalidName(t *testing.T) {
+	_, err := Analyze(context.Background(), nil, "orders; DROP TABLE x")
+	if err == nil || !strings.Contains(err.Error(), "analyze") {
+		t.Errorf("got %v, want invalid name error", err)
+	}
+}
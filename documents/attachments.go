@@ -0,0 +1,241 @@
+package documents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/internal/pg"
+)
+
+// attachmentChunkSize is the number of bytes stored per attachment row.
+// Attach and OpenAttachment never hold more than one chunk in memory.
+const attachmentChunkSize = 256 << 10
+
+// AttachmentInfo describes a stored attachment.
+type AttachmentInfo struct {
+	Name string
+	Size int64
+}
+
+type beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+func (c *CollectionOf[T]) attachmentsTable() string {
+	return c.table + "_attachments"
+}
+
+func (c *CollectionOf[T]) ensureAttachments(ctx context.Context) error {
+	if err := c.ensure(ctx); err != nil {
+		return err
+	}
+	return c.schema.EnsureAttachments(ctx, c.exec, c.name)
+}
+
+// Attach streams r into the named attachment of document id, replacing any
+// previous content. Attachments are deleted along with their document.
+// Returns ErrNotFound if the document does not exist. The write is atomic: on
+// the store it runs in its own transaction, in a session it joins the
+// session's transaction.
+func (c *CollectionOf[T]) Attach(ctx context.Context, id, name string, r io.Reader) error {
+	if err := c.ensureAttachments(ctx); err != nil {
+		return err
+	}
+	if err := c.checkWrite(ctx, "attach"); err != nil {
+		return err
+	}
+	if err := c.owned(ctx, id); err != nil {
+		return &whisker.DocumentError{Collection: c.name, ID: id, Op: "attach", Err: err}
+	}
+	err := c.inTx(ctx, func(exec pg.Executor) error {
+		return c.writeAttachment(ctx, exec, id, name, r)
+	})
+	if err != nil {
+		return &whisker.DocumentError{Collection: c.name, ID: id, Op: "attach", Err: err}
+	}
+	return nil
+}
+
+func (c *CollectionOf[T]) writeAttachment(ctx context.Context, exec pg.Executor, id, name string, r io.Reader) error {
+	var exists bool
+	err := exec.QueryRow(ctx,
+		fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE id = $1)`, c.table), id,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return whisker.ErrNotFound
+	}
+
+	table := c.attachmentsTable()
+	if _, err := exec.Exec(ctx,
+		fmt.Sprintf(`DELETE FROM %s WHERE doc_id = $1 AND name = $2`, table), id, name,
+	); err != nil {
+		return err
+	}
+
+	insert := fmt.Sprintf(`INSERT INTO %s (doc_id, name, chunk, data) VALUES ($1, $2, $3, $4)`, table)
+	buf := make([]byte, attachmentChunkSize)
+	for chunk := 0; ; chunk++ {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 || chunk == 0 {
+			if _, err := exec.Exec(ctx, insert, id, name, chunk, buf[:n]); err != nil {
+				return err
+			}
+		}
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if readErr != nil {
+			return fmt.Errorf("read: %w", readErr)
+		}
+	}
+}
+
+// OpenAttachment returns a reader that streams the named attachment of
+// document id one chunk at a time. Returns ErrNotFound if the attachment does
+// not exist. Outside a session, chunks are read in separate statements, so a
+// concurrent Attach to the same name may interleave content.
+func (c *CollectionOf[T]) OpenAttachment(ctx context.Context, id, name string) (io.ReadCloser, error) {
+	if err := c.ensureAttachments(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.owned(ctx, id); err != nil {
+		return nil, &whisker.DocumentError{Collection: c.name, ID: id, Op: "open attachment", Err: err}
+	}
+	var chunks int
+	err := c.exec.QueryRow(ctx,
+		fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE doc_id = $1 AND name = $2`, c.attachmentsTable()), id, name,
+	).Scan(&chunks)
+	if err != nil {
+		return nil, &whisker.DocumentError{Collection: c.name, ID: id, Op: "open attachment", Err: err}
+	}
+	if chunks == 0 {
+		return nil, &whisker.DocumentError{Collection: c.name, ID: id, Op: "open attachment", Err: whisker.ErrNotFound}
+	}
+	return &attachmentReader{
+		ctx:    ctx,
+		exec:   c.exec,
+		query:  fmt.Sprintf(`SELECT data FROM %s WHERE doc_id = $1 AND name = $2 AND chunk = $3`, c.attachmentsTable()),
+		id:     id,
+		name:   name,
+		chunks: chunks,
+	}, nil
+}
+
+// Attachments lists the attachments of document id ordered by name.
+func (c *CollectionOf[T]) Attachments(ctx context.Context, id string) ([]AttachmentInfo, error) {
+	if err := c.ensureAttachments(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.owned(ctx, id); err != nil {
+		return nil, &whisker.DocumentError{Collection: c.name, ID: id, Op: "attachments", Err: err}
+	}
+	rows, err := c.exec.Query(ctx,
+		fmt.Sprintf(`SELECT name, SUM(length(data)) FROM %s WHERE doc_id = $1 GROUP BY name ORDER BY name`, c.attachmentsTable()), id,
+	)
+	if err != nil {
+		return nil, &whisker.DocumentError{Collection: c.name, ID: id, Op: "attachments", Err: err}
+	}
+	defer rows.Close()
+
+	var infos []AttachmentInfo
+	for rows.Next() {
+		var info AttachmentInfo
+		if err := rows.Scan(&info.Name, &info.Size); err != nil {
+			return nil, &whisker.DocumentError{Collection: c.name, ID: id, Op: "attachments", Err: fmt.Errorf("scan: %w", err)}
+		}
+		infos = append(infos, info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, &whisker.DocumentError{Collection: c.name, ID: id, Op: "attachments", Err: err}
+	}
+	return infos, nil
+}
+
+// Detach removes the named attachment of document id. Returns ErrNotFound if
+// it does not exist.
+func (c *CollectionOf[T]) Detach(ctx context.Context, id, name string) error {
+	if err := c.ensureAttachments(ctx); err != nil {
+		return err
+	}
+	if err := c.checkWrite(ctx, "detach"); err != nil {
+		return err
+	}
+	if err := c.owned(ctx, id); err != nil {
+		return &whisker.DocumentError{Collection: c.name, ID: id, Op: "detach", Err: err}
+	}
+	tag, err := c.exec.Exec(ctx,
+		fmt.Sprintf(`DELETE FROM %s WHERE doc_id = $1 AND name = $2`, c.attachmentsTable()), id, name,
+	)
+	if err != nil {
+		return &whisker.DocumentError{Collection: c.name, ID: id, Op: "detach", Err: err}
+	}
+	if tag.RowsAffected() == 0 {
+		return &whisker.DocumentError{Collection: c.name, ID: id, Op: "detach", Err: whisker.ErrNotFound}
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction. When the collection is bound to a session
+// the session's transaction is used as is.
+func (c *CollectionOf[T]) inTx(ctx context.Context, fn func(pg.Executor) error) error {
+	if t, ok := c.exec.(pg.Transactional); ok && t.InTransaction() {
+		return fn(c.exec)
+	}
+	b, ok := c.exec.(beginner)
+	if !ok {
+		return fn(c.exec)
+	}
+	tx, err := b.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// attachmentReader fetches attachment chunks lazily as they are consumed.
+type attachmentReader struct {
+	ctx    context.Context
+	exec   pg.Executor
+	query  string
+	id     string
+	name   string
+	chunks int
+	next   int
+	buf    []byte
+	closed bool
+}
+
+func (r *attachmentReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, errors.New("attachment reader closed")
+	}
+	for len(r.buf) == 0 {
+		if r.next >= r.chunks {
+			return 0, io.EOF
+		}
+		if err := r.exec.QueryRow(r.ctx, r.query, r.id, r.name, r.next).Scan(&r.buf); err != nil {
+			return 0, fmt.Errorf("read chunk %d of %s/%s: %w", r.next, r.id, r.name, err)
+		}
+		r.next++
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *attachmentReader) Close() error {
+	r.closed = true
+	r.buf = nil
+	return nil
+}
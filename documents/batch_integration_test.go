@@ -5,6 +5,7 @@ package documents_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/ripkitten-co/whisker"
@@ -90,6 +91,96 @@ func TestInsertMany_EmptySlice(t *testing.T) {
 	}
 }
 
+func TestInsertManyCopy_MatchesInsertMany(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	viaInsert := documents.Collection[User](store, "insert_many_rows")
+	viaCopy := documents.Collection[User](store, "insert_many_copy_rows")
+
+	newDocs := func() []*User {
+		docs := make([]*User, 500)
+		for i := range docs {
+			docs[i] = &User{ID: fmt.Sprintf("u%03d", i), Name: fmt.Sprintf("User %d", i), Email: "user@test.com"}
+		}
+		return docs
+	}
+	if err := viaInsert.InsertMany(ctx, newDocs()); err != nil {
+		t.Fatalf("insert many: %v", err)
+	}
+	docs := newDocs()
+	if err := viaCopy.InsertManyCopy(ctx, docs); err != nil {
+		t.Fatalf("insert many copy: %v", err)
+	}
+	for _, doc := range docs {
+		if doc.Version != 1 {
+			t.Fatalf("doc %s: version = %d, want 1", doc.ID, doc.Version)
+		}
+	}
+
+	want, err := viaInsert.Query().OrderBy("id", documents.Asc).Execute(ctx)
+	if err != nil {
+		t.Fatalf("query insert many: %v", err)
+	}
+	got, err := viaCopy.Query().OrderBy("id", documents.Asc).Execute(ctx)
+	if err != nil {
+		t.Fatalf("query insert many copy: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d docs, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if *got[i] != *want[i] {
+			t.Errorf("doc %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestInsertManyCopy_DuplicateID(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[User](store, "insert_many_copy_dup_users")
+
+	if err := users.Insert(ctx, &User{ID: "u1", Name: "Alice"}); err != nil {
+		t.Fatalf("seed insert: %v", err)
+	}
+	err := users.InsertManyCopy(ctx, []*User{
+		{ID: "u2", Name: "Bob"},
+		{ID: "u1", Name: "Alice Again"},
+	})
+	var batchErr *documents.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected BatchError, got %T: %v", err, err)
+	}
+	if !errors.Is(batchErr.Errors["u1"], whisker.ErrDuplicateID) {
+		t.Errorf("u1: got %v, want ErrDuplicateID", batchErr.Errors["u1"])
+	}
+	if _, err := users.Load(ctx, "u2"); !errors.Is(err, whisker.ErrNotFound) {
+		t.Errorf("u2 should not be inserted, got %v", err)
+	}
+}
+
+func TestInsertManyCopy_InSession(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	sess, err := store.Session(ctx)
+	if err != nil {
+		t.Fatalf("session: %v", err)
+	}
+	defer sess.Close(ctx)
+
+	users := documents.Collection[User](sess, "insert_many_copy_sess_users")
+	if err := users.InsertManyCopy(ctx, []*User{{ID: "u1", Name: "Alice"}}); err != nil {
+		t.Fatalf("insert many copy: %v", err)
+	}
+	if err := sess.Rollback(ctx); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if _, err := documents.Collection[User](store, "insert_many_copy_sess_users").Load(ctx, "u1"); !errors.Is(err, whisker.ErrNotFound) {
+		t.Errorf("rolled back copy should not persist, got %v", err)
+	}
+}
+
 func TestLoadMany_HappyPath(t *testing.T) {
 	store := setupStore(t)
 	ctx := context.Background()
@@ -368,6 +459,14 @@ func TestUpdateMany_VersionConflict(t *testing.T) {
 	if !errors.Is(batchErr.Errors["u1"], whisker.ErrConcurrencyConflict) {
 		t.Errorf("u1 error = %v, want ErrConcurrencyConflict", batchErr.Errors["u1"])
 	}
+
+	reloaded, err := users.Load(ctx, "u2")
+	if err != nil {
+		t.Fatalf("reload u2: %v", err)
+	}
+	if reloaded.Name != "Bob" || reloaded.Version != 1 || u2.Version != 1 {
+		t.Errorf("u2 was updated despite the batch failing: %+v", reloaded)
+	}
 }
 
 func TestUpdateMany_EmptySlice(t *testing.T) {
@@ -0,0 +1,145 @@
+package documents
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ripkitten-co/whisker/internal/meta"
+	"github.com/ripkitten-co/whisker/internal/pg"
+)
+
+// Delete removes every document matching the query's conditions in a single
+// statement and returns how many were removed:
+//
+//	n, err := sessions.Where("updated_at", "<", cutoff).Delete(ctx)
+//
+// The query must have at least one condition; Limit, Offset, OrderBy and
+// pagination are not supported. Lifecycle hooks do not run.
+func (q *Query[T]) Delete(ctx context.Context) (int64, error) {
+	if err := q.checkBulk("Delete"); err != nil {
+		return 0, err
+	}
+	if err := q.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+	q, err := q.scoped(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := pg.CheckWrite(ctx, q.exec); err != nil {
+		return 0, fmt.Errorf("query: delete: %w", err)
+	}
+
+	sql, args, err := q.toDeleteSQL()
+	if err != nil {
+		return 0, err
+	}
+	tag, err := q.exec.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("query: delete: %w", mapPgError(err))
+	}
+	return tag.RowsAffected(), nil
+}
+
+// UpdateSet sets the given top-level fields, by JSON key, on every document
+// matching the query's conditions in a single statement, leaving their other
+// fields as they are, and returns how many documents were updated:
+//
+//	n, err := orders.Where("status", "=", "shipped").
+//		Where("shippedAt", "<", cutoff).
+//		UpdateSet(ctx, map[string]any{"status": "archived"})
+//
+// Each updated document's version is incremented. The keys must be fields of
+// T; values are encoded with the collection's codec. The same restrictions
+// as Delete apply.
+func (q *Query[T]) UpdateSet(ctx context.Context, fields map[string]any) (int64, error) {
+	if err := q.checkBulk("UpdateSet"); err != nil {
+		return 0, err
+	}
+	if err := q.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+	q, err := q.scoped(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := pg.CheckWrite(ctx, q.exec); err != nil {
+		return 0, fmt.Errorf("query: update set: %w", err)
+	}
+
+	sql, args, err := q.toUpdateSetSQL(fields)
+	if err != nil {
+		return 0, err
+	}
+	tag, err := q.exec.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("query: update set: %w", mapPgError(err))
+	}
+	return tag.RowsAffected(), nil
+}
+
+// checkBulk rejects query settings a bulk write cannot honour, and bulk
+// writes without a condition, which would touch every document.
+func (q *Query[T]) checkBulk(op string) error {
+	switch {
+	case len(q.conditions) == 0:
+		return fmt.Errorf("query: %s requires at least one condition", op)
+	case q.limit != nil || q.offset != nil:
+		return fmt.Errorf("query: %s does not support Limit or Offset", op)
+	case len(q.orderBys) > 0 || q.afterVal != nil || q.cursor != "":
+		return fmt.Errorf("query: %s does not support OrderBy or pagination", op)
+	case len(q.selects) > 0 || len(q.groupBys) > 0:
+		return fmt.Errorf("query: %s does not support Select or GroupBy", op)
+	}
+	return nil
+}
+
+func (q *Query[T]) toDeleteSQL() (string, []any, error) {
+	preds, err := q.predicates()
+	if err != nil {
+		return "", nil, err
+	}
+	builder := psql.Delete(q.table)
+	for _, p := range preds {
+		builder = builder.Where(p)
+	}
+	return builder.ToSql()
+}
+
+func (q *Query[T]) toUpdateSetSQL(fields map[string]any) (string, []any, error) {
+	if len(fields) == 0 {
+		return "", nil, fmt.Errorf("query: UpdateSet requires at least one field")
+	}
+	known := make(map[string]bool)
+	for _, k := range fieldKeys[T]() {
+		known[k] = true
+	}
+	for _, k := range slices.Sorted(maps.Keys(fields)) {
+		if !known[k] {
+			return "", nil, fmt.Errorf("query: update set: unknown field %q", k)
+		}
+		if meta.Analyze[T]().Encrypted(k) {
+			return "", nil, fmt.Errorf("query: update set: field %s is encrypted and can only be written with its document", k)
+		}
+	}
+	data, err := q.codec.Marshal(fields)
+	if err != nil {
+		return "", nil, fmt.Errorf("query: update set: marshal: %w", err)
+	}
+
+	preds, err := q.predicates()
+	if err != nil {
+		return "", nil, err
+	}
+	builder := psql.Update(q.table).
+		Set("data", sq.Expr("data || ?::jsonb", string(data))).
+		Set("version", sq.Expr("version + 1")).
+		Set("updated_at", q.collection().now())
+	for _, p := range preds {
+		builder = builder.Where(p)
+	}
+	return builder.ToSql()
+}
@@ -0,0 +1,101 @@
+package documents
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/internal/codecs"
+)
+
+func TestQuery_DeleteSQL(t *testing.T) {
+	q := (&Query[testDoc]{table: "whisker_users"}).
+		Where("name", "=", "Alice").
+		Deleted(ExcludeDeleted)
+	sql, args, err := q.toDeleteSQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "DELETE FROM whisker_users WHERE deleted_at IS NULL AND data->>'name' = $1"
+	if sql != want {
+		t.Errorf("sql:\n got: %s\nwant: %s", sql, want)
+	}
+	if len(args) != 1 || args[0] != "Alice" {
+		t.Errorf("args: got %v", args)
+	}
+}
+
+func TestQuery_UpdateSetSQL(t *testing.T) {
+	frozen := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	col := &CollectionOf[testDoc]{table: "whisker_users", codec: codecs.NewWhisker(codecs.NewJSONIter())}
+	q := col.Query().Where("version", "<", 3)
+
+	sql, args, err := q.toUpdateSetSQL(map[string]any{"name": "Archived"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "UPDATE whisker_users SET data = data || $1::jsonb, version = version + 1, updated_at = now() WHERE version < $2"
+	if sql != want {
+		t.Errorf("sql:\n got: %s\nwant: %s", sql, want)
+	}
+	if len(args) != 2 || args[0] != `{"name":"Archived"}` || args[1] != 3 {
+		t.Errorf("args: got %v", args)
+	}
+
+	col.clock = whisker.FixedClock(frozen)
+	sql, args, err = q.toUpdateSetSQL(map[string]any{"name": "Archived"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sql, "updated_at = $2 WHERE version < $3") || args[1] != frozen {
+		t.Errorf("clock not used: %s %v", sql, args)
+	}
+}
+
+func TestQuery_UpdateSetRejectsFields(t *testing.T) {
+	q := (&CollectionOf[testDoc]{table: "whisker_users", codec: codecs.NewWhisker(codecs.NewJSONIter())}).
+		Where("name", "=", "a")
+	for _, fields := range []map[string]any{
+		nil,
+		{"nmae": "typo"},
+		{"id": "u2"},
+		{"version": 9},
+	} {
+		if _, _, err := q.toUpdateSetSQL(fields); err == nil {
+			t.Errorf("%v: expected error", fields)
+		}
+	}
+}
+
+func TestQuery_CheckBulk(t *testing.T) {
+	base := &Query[testDoc]{table: "whisker_users"}
+	cond := base.Where("name", "=", "a")
+	tests := []struct {
+		name    string
+		q       *Query[testDoc]
+		wantErr string
+	}{
+		{"condition", cond, ""},
+		{"no condition", base, "requires at least one condition"},
+		{"deleted filter only", base.Deleted(OnlyDeleted), "requires at least one condition"},
+		{"limit", cond.Limit(10), "Limit"},
+		{"offset", cond.Offset(10), "Offset"},
+		{"order", cond.OrderBy("name", Asc), "OrderBy"},
+		{"select", cond.Select("name"), "Select"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.q.checkBulk("Delete")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package documents
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/internal/meta"
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+// Event types in a collection's change feed.
+const (
+	DocumentInserted = "DocumentInserted"
+	DocumentUpdated  = "DocumentUpdated"
+	DocumentDeleted  = "DocumentDeleted"
+)
+
+// WithChangeFeed records every insert, update and delete on the collection
+// as an event in its change feed, a named event store (see ChangeFeed)
+// filled by a trigger in the same transaction as the write. Each document is
+// a stream keyed by its id. Subscribers bound to the feed with
+// projections.FromEventStore maintain read models over the collection with
+// the daemon's checkpoints, locks and rebuilds.
+//
+// Writes made before the feed was enabled are not in it. Event data is the
+// stored document, the last state for a delete; decode it with DecodeChange.
+func WithChangeFeed() CollectionOption {
+	return func(cfg *collectionConfig) {
+		cfg.changeFeed = true
+	}
+}
+
+// ChangeFeed returns the name of the event store holding the change feed of
+// the named collection.
+func ChangeFeed(collection string) string {
+	return schema.ChangeFeedStore(collection)
+}
+
+// changeMetadata is the metadata the change feed trigger records.
+type changeMetadata struct {
+	Version       int `json:"version"`
+	SchemaVersion int `json:"schema_version"`
+}
+
+// DecodeChange returns the document recorded by an event from the
+// collection's change feed, with its ID and Version set, migrated to the
+// latest data-schema version when the type has migrations.
+func (c *CollectionOf[T]) DecodeChange(evt events.Event) (*T, error) {
+	var md changeMetadata
+	if err := json.Unmarshal(evt.Metadata, &md); err != nil {
+		return nil, fmt.Errorf("collection %s: decode change %s: metadata: %w", c.name, evt.StreamID, err)
+	}
+	doc := new(T)
+	if err := decodeDoc(c.codec, migrationsFor[T](), evt.StreamID, evt.Data, md.SchemaVersion, doc); err != nil {
+		return nil, fmt.Errorf("collection %s: decode change %s: unmarshal: %w", c.name, evt.StreamID, err)
+	}
+	meta.SetID(doc, evt.StreamID)
+	meta.SetVersion(doc, md.Version)
+	return doc, nil
+}
@@ -0,0 +1,41 @@
+package documents
+
+import (
+	"testing"
+
+	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/internal/codecs"
+)
+
+type feedDoc struct {
+	ID      string
+	Name    string `json:"name"`
+	Version int
+}
+
+func TestDecodeChange(t *testing.T) {
+	c := &CollectionOf[feedDoc]{name: "users", codec: codecs.NewWhisker(codecs.NewJSONIter())}
+
+	doc, err := c.DecodeChange(events.Event{
+		StreamID: "u1",
+		Type:     DocumentUpdated,
+		Data:     []byte(`{"name":"Alice"}`),
+		Metadata: []byte(`{"version":3,"schema_version":1}`),
+	})
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc.ID != "u1" || doc.Name != "Alice" || doc.Version != 3 {
+		t.Errorf("got %+v", doc)
+	}
+
+	if _, err := c.DecodeChange(events.Event{StreamID: "u1", Data: []byte(`{}`)}); err == nil {
+		t.Error("expected error for missing metadata")
+	}
+}
+
+func TestChangeFeed(t *testing.T) {
+	if got := ChangeFeed("users"); got != "users_changes" {
+		t.Errorf("ChangeFeed = %q, want users_changes", got)
+	}
+}
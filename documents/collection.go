@@ -1,16 +1,21 @@
 package documents
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"sync/atomic"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/ripkitten-co/whisker"
 	"github.com/ripkitten-co/whisker/internal/codecs"
+	"github.com/ripkitten-co/whisker/internal/columns"
+	"github.com/ripkitten-co/whisker/internal/ident"
 	"github.com/ripkitten-co/whisker/internal/indexes"
 	"github.com/ripkitten-co/whisker/internal/meta"
 	"github.com/ripkitten-co/whisker/internal/pg"
@@ -29,30 +34,243 @@ type CollectionOf[T any] struct {
 	codec        codecs.Codec
 	schema       *schema.Bootstrap
 	indexes      []meta.IndexMeta
+	columns      []meta.ColumnMeta
 	maxBatchSize int
+	rlsPolicy    string
+	changeFeed   bool
+	watch        bool
+	tenancy      bool
+	history      bool
+	listen       func(ctx context.Context, channel string) (whisker.Listener, error)
+	clock        whisker.Clock
+	hooks        *Hooks[T]
+	configErr    error
+	access       meta.Accessor[T]
+	prepared     atomic.Pointer[preparedSQL]
+}
+
+// CollectionOption configures a collection during creation.
+type CollectionOption func(*collectionConfig)
+
+type collectionConfig struct {
+	rlsPolicy  string
+	changeFeed bool
+	watch      bool
+	tenancy    bool
+	history    bool
+	keys       KeyProvider
+	hooks      any
+	indexes    []IndexSpec
+}
+
+// WithRLS enables row-level security on the collection table and installs
+// policySQL as both the USING and WITH CHECK expression. Use TenantPolicy for
+// the common tenant-isolation case. Superusers and roles with BYPASSRLS are
+// not subject to policies.
+func WithRLS(policySQL string) CollectionOption {
+	return func(cfg *collectionConfig) {
+		cfg.rlsPolicy = policySQL
+	}
+}
+
+// TenantPolicy returns an RLS policy expression that restricts rows to those
+// whose JSONB field equals the tenant set on the session (see
+// whisker.WithTenant). Rows are invisible when no tenant is set.
+func TenantPolicy(field string) string {
+	return fmt.Sprintf("%s = current_setting(%s, true)", ident.JSONText(field), ident.Literal(whisker.TenantSetting))
 }
 
 // Collection creates a new typed collection backed by the given store.
-func Collection[T any](b whisker.Backend, name string) *CollectionOf[T] {
+func Collection[T any](b whisker.Backend, name string, opts ...CollectionOption) *CollectionOf[T] {
+	var cfg collectionConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
 	m := meta.Analyze[T]()
-	return &CollectionOf[T]{
+	c := &CollectionOf[T]{
 		name:         name,
 		table:        "whisker_" + name,
 		exec:         b.DBExecutor(),
 		codec:        b.JSONCodec(),
 		schema:       b.SchemaBootstrap(),
 		indexes:      m.Indexes,
+		columns:      m.Columns,
 		maxBatchSize: b.MaxBatchSize(),
+		rlsPolicy:    cfg.rlsPolicy,
+		changeFeed:   cfg.changeFeed,
+		watch:        cfg.watch,
+		tenancy:      cfg.tenancy,
+		history:      cfg.history,
+		clock:        whisker.ClockOf(b),
+		access:       meta.AccessorOf[T](),
+	}
+	if l, ok := b.(listenerBackend); ok {
+		c.listen = l.Listen
+	}
+	if misplaced := m.MisplacedEncrypt(); len(misplaced) > 0 {
+		c.configErr = fmt.Errorf("collection %s: fields %s are tagged encrypt but only top-level data fields can be encrypted", name, strings.Join(misplaced, ", "))
+	} else if fields := m.EncryptedKeys(); len(fields) > 0 {
+		if cfg.keys == nil {
+			c.configErr = fmt.Errorf("collection %s: fields %s are tagged encrypt but the collection has no WithEncryption", name, strings.Join(fields, ", "))
+		} else {
+			c.codec = newEncryptingCodec(c.codec, cfg.keys, name, fields)
+		}
+	}
+	if fields := m.UnpromotableColumns(); len(fields) > 0 {
+		c.configErr = cmp.Or(c.configErr, fmt.Errorf("collection %s: fields %s are tagged column but their column names are reserved or invalid", name, strings.Join(fields, ", ")))
+	}
+	if cfg.hooks != nil {
+		h, ok := cfg.hooks.(*Hooks[T])
+		if !ok {
+			c.configErr = fmt.Errorf("collection %s: hooks are %T, want %T", name, cfg.hooks, h)
+		}
+		c.hooks = h
+	}
+	if len(cfg.indexes) > 0 {
+		// the metadata's slice is shared by every collection of T
+		c.indexes = slices.Clone(c.indexes)
+		for _, spec := range cfg.indexes {
+			idx, err := spec.index(m)
+			if err != nil {
+				c.configErr = cmp.Or(c.configErr, fmt.Errorf("collection %s: %w", name, err))
+				continue
+			}
+			c.indexes = append(c.indexes, idx)
+		}
+	}
+	return c
+}
+
+// CollectionIn returns the collection at addr, of the form "db:collection",
+// in a registry of named stores. See whisker.Registry.
+func CollectionIn[T any](r *whisker.Registry, addr string, opts ...CollectionOption) (*CollectionOf[T], error) {
+	s, name, err := r.Resolve(addr)
+	if err != nil {
+		return nil, err
+	}
+	return Collection[T](s, name, opts...), nil
+}
+
+// now returns the value written to timestamp columns: the configured clock's
+// time, or the database's now() when no clock is set.
+func (c *CollectionOf[T]) now() any {
+	if c.clock == nil {
+		return sq.Expr("now()")
 	}
+	return c.clock.Now().UTC()
+}
+
+// stampColumns adds created_at and updated_at to an insert when a clock is
+// configured; otherwise the column defaults apply.
+func (c *CollectionOf[T]) stampColumns(cols []string, values []any) ([]string, []any) {
+	if c.clock == nil {
+		return cols, values
+	}
+	now := c.now()
+	return append(cols, "created_at", "updated_at"), append(values, now, now)
 }
 
 func (c *CollectionOf[T]) ensure(ctx context.Context) error {
+	if c.configErr != nil {
+		return c.configErr
+	}
+	if !c.schema.AutoMigrate() {
+		return schema.ValidateCollectionName(c.name)
+	}
 	if err := c.schema.EnsureCollection(ctx, c.exec, c.name); err != nil {
 		return err
 	}
+	if meta.Analyze[T]().UsesCast(meta.CastTimestamptz) {
+		// time columns and indexes are both built on whisker_timestamptz
+		if err := c.schema.EnsureTimestamptzFunc(ctx, c.exec); err != nil {
+			return fmt.Errorf("collection %s: %w", c.name, err)
+		}
+	}
+	if err := c.ensureColumns(ctx); err != nil {
+		return err
+	}
+	if chain := migrationsFor[T](); chain != nil {
+		if err := c.schema.EnsureSchemaVersion(ctx, c.exec, c.name); err != nil {
+			return err
+		}
+		if err := c.schema.EnsureMigrated(ctx, c.exec, c.name, chain.latest, func() error {
+			_, err := c.migrateAll(ctx, chain)
+			return err
+		}); err != nil {
+			return fmt.Errorf("collection %s: %w", c.name, err)
+		}
+	}
+	if c.tenancy {
+		if err := c.schema.EnsureTenant(ctx, c.exec, c.name); err != nil {
+			return err
+		}
+	}
+	if c.rlsPolicy != "" {
+		if err := c.schema.EnsureRLS(ctx, c.exec, c.name, c.rlsPolicy); err != nil {
+			return err
+		}
+	}
+	if c.changeFeed {
+		if err := c.schema.EnsureChangeFeed(ctx, c.exec, c.name); err != nil {
+			return err
+		}
+	}
+	if c.watch {
+		if err := c.schema.EnsureWatch(ctx, c.exec, c.name); err != nil {
+			return err
+		}
+	}
+	if c.history {
+		if err := c.schema.EnsureHistory(ctx, c.exec, c.name); err != nil {
+			return err
+		}
+	}
 	return c.ensureIndexes(ctx)
 }
 
+// Warm creates the collection's table, columns, policy and indexes now
+// rather than on first use. It implements whisker.Warmer.
+func (c *CollectionOf[T]) Warm(ctx context.Context) error {
+	return c.ensure(ctx)
+}
+
+// checkWrite fails with whisker.ErrMaintenance while the store is quiesced.
+func (c *CollectionOf[T]) checkWrite(ctx context.Context, op string) error {
+	if err := pg.CheckWrite(ctx, c.exec); err != nil {
+		return fmt.Errorf("collection %s: %s: %w", c.name, op, err)
+	}
+	return nil
+}
+
+func (c *CollectionOf[T]) ensureColumns(ctx context.Context) error {
+	for _, col := range c.columns {
+		key := columns.ColumnKey(c.name, col)
+		if c.schema.IsColumnCreated(key) {
+			continue
+		}
+		if _, err := c.exec.Exec(ctx, columns.ColumnDDL(c.name, col)); err != nil {
+			return fmt.Errorf("collection %s: add column %s: %w", c.name, col.Name, err)
+		}
+		if col.References != "" {
+			if err := c.ensureForeignKey(ctx, col); err != nil {
+				return err
+			}
+		}
+		c.schema.MarkColumnCreated(key)
+	}
+	return nil
+}
+
+func (c *CollectionOf[T]) ensureForeignKey(ctx context.Context, col meta.ColumnMeta) error {
+	if err := c.schema.EnsureCollection(ctx, c.exec, col.References); err != nil {
+		return fmt.Errorf("collection %s: referenced collection: %w", c.name, err)
+	}
+	if _, err := c.exec.Exec(ctx, columns.ForeignKeyDDL(c.name, col)); err != nil {
+		return fmt.Errorf("collection %s: add foreign key %s: %w", c.name, columns.ForeignKeyName(c.name, col), err)
+	}
+	return nil
+}
+
 func (c *CollectionOf[T]) ensureIndexes(ctx context.Context) error {
 	if len(c.indexes) == 0 {
 		return nil
@@ -60,16 +278,17 @@ func (c *CollectionOf[T]) ensureIndexes(ctx context.Context) error {
 	if tx, ok := c.exec.(pg.Transactional); ok && tx.InTransaction() {
 		return nil
 	}
+	for _, ext := range indexes.Extensions(c.indexes) {
+		if err := c.schema.EnsureExtension(ctx, c.exec, ext); err != nil {
+			return fmt.Errorf("collection %s: %w", c.name, err)
+		}
+	}
 	ddls := indexes.IndexDDLs(c.name, c.indexes)
 	for i, ddl := range ddls {
 		name := indexes.IndexName(c.name, c.indexes[i])
-		if c.schema.IsIndexCreated(name) {
-			continue
-		}
-		if _, err := c.exec.Exec(ctx, ddl); err != nil {
-			return fmt.Errorf("collection %s: create index %s: %w", c.name, name, err)
+		if err := c.schema.EnsureIndex(ctx, c.exec, name, ddl, indexes.Superseded(c.name, c.indexes[i])...); err != nil {
+			return fmt.Errorf("collection %s: %w", c.name, err)
 		}
-		c.schema.MarkIndexCreated(name)
 	}
 	return nil
 }
@@ -80,32 +299,147 @@ func (c *CollectionOf[T]) Insert(ctx context.Context, doc *T) error {
 	if err := c.ensure(ctx); err != nil {
 		return err
 	}
+	if err := c.checkWrite(ctx, "insert"); err != nil {
+		return err
+	}
+	tenant, err := c.tenant(ctx, "insert")
+	if err != nil {
+		return err
+	}
 
-	id, err := meta.ExtractID(doc)
+	id, err := c.access.ID(doc)
 	if err != nil {
 		return fmt.Errorf("collection %s: %w", c.name, err)
 	}
 	if id == "" {
 		return fmt.Errorf("collection %s: insert: ID must not be empty", c.name)
 	}
+	if err := c.runDocHooks(ctx, "before insert", id, doc); err != nil {
+		return err
+	}
 
-	data, err := c.codec.Marshal(doc)
+	var bufs encodeBuffers
+	defer bufs.release()
+	data, err := c.encode(doc, &bufs)
 	if err != nil {
 		return fmt.Errorf("collection %s: insert %s: marshal: %w", c.name, id, err)
 	}
 
-	sql, args, err := psql.Insert(c.table).Columns("id", "data").Values(id, data).ToSql()
-	if err != nil {
-		return fmt.Errorf("collection %s: insert %s: build sql: %w", c.name, id, err)
+	chain := migrationsFor[T]()
+	values := []any{id, data}
+	if chain != nil {
+		values = append(values, chain.latest)
+	}
+	cols, values := c.stampColumns(withSchemaVersion(chain, "id", "data"), values)
+	cols, values = withTenant(cols, values, tenant)
+	var sql string
+	args := values
+	if p := c.preparedFor(chain); p != nil {
+		sql = p.insert
+	} else {
+		sql, args, err = psql.Insert(c.table).Columns(cols...).Values(values...).ToSql()
+		if err != nil {
+			return fmt.Errorf("collection %s: insert %s: build sql: %w", c.name, id, err)
+		}
 	}
 
 	_, err = c.exec.Exec(ctx, sql, args...)
 	if err != nil {
-		return fmt.Errorf("collection %s: insert %s: %w", c.name, id, err)
+		return &whisker.DocumentError{Collection: c.name, ID: id, Op: "insert", Err: mapPgError(err)}
 	}
 
-	meta.SetVersion(doc, 1)
-	return nil
+	c.access.SetVersion(doc, 1)
+	return c.runDocHooks(ctx, "after insert", id, doc)
+}
+
+// Upsert inserts doc, or replaces the stored document with the same ID, in a
+// single INSERT ... ON CONFLICT statement, so importers need no
+// Load-then-Insert-or-Update round trips. A new document gets Version 1; a
+// replaced one gets its stored version plus one, whatever Version doc
+// carries: Upsert never fails with ErrConcurrencyConflict. Use Update for
+// optimistic concurrency. On success, the document's Version is set.
+func (c *CollectionOf[T]) Upsert(ctx context.Context, doc *T) error {
+	if err := c.ensure(ctx); err != nil {
+		return err
+	}
+	if err := c.checkWrite(ctx, "upsert"); err != nil {
+		return err
+	}
+	tenant, err := c.tenant(ctx, "upsert")
+	if err != nil {
+		return err
+	}
+
+	id, err := c.access.ID(doc)
+	if err != nil {
+		return fmt.Errorf("collection %s: %w", c.name, err)
+	}
+	if id == "" {
+		return fmt.Errorf("collection %s: upsert: ID must not be empty", c.name)
+	}
+	if err := c.runDocHooks(ctx, "before upsert", id, doc); err != nil {
+		return err
+	}
+	var bufs encodeBuffers
+	defer bufs.release()
+	data, err := c.encode(doc, &bufs)
+	if err != nil {
+		return fmt.Errorf("collection %s: upsert %s: marshal: %w", c.name, id, err)
+	}
+
+	builder := c.upsertBuilder(tenant)
+	builder = builder.Values(c.upsertValues(id, data, tenant)...)
+	sql, args, err := builder.Suffix(c.upsertSuffix(tenant)).ToSql()
+	if err != nil {
+		return fmt.Errorf("collection %s: upsert %s: build sql: %w", c.name, id, err)
+	}
+
+	var gotID string
+	var version int
+	if err := c.exec.QueryRow(ctx, sql, args...).Scan(&gotID, &version); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// the id belongs to another tenant, so the conflict clause
+			// left it alone
+			err = whisker.ErrDuplicateID
+		}
+		return &whisker.DocumentError{Collection: c.name, ID: id, Op: "upsert", Err: mapPgError(err)}
+	}
+	c.access.SetVersion(doc, version)
+	return c.runDocHooks(ctx, "after upsert", id, doc)
+}
+
+// upsertBuilder starts the INSERT of Upsert and UpsertMany. The table is
+// aliased t so the conflict clause can refer to the stored row.
+func (c *CollectionOf[T]) upsertBuilder(tenant string) sq.InsertBuilder {
+	cols, _ := c.stampColumns(withSchemaVersion(migrationsFor[T](), "id", "data"), nil)
+	cols, _ = withTenant(cols, nil, tenant)
+	return psql.Insert(c.table + " AS t").Columns(cols...)
+}
+
+func (c *CollectionOf[T]) upsertValues(id string, data []byte, tenant string) []any {
+	values := []any{id, data}
+	if chain := migrationsFor[T](); chain != nil {
+		values = append(values, chain.latest)
+	}
+	_, values = c.stampColumns(nil, values)
+	_, values = withTenant(nil, values, tenant)
+	return values
+}
+
+// upsertSuffix replaces a stored document and bumps its version. The
+// excluded row carries updated_at from the clock or the column default.
+// With a tenant, documents of other tenants are left alone and return no
+// row.
+func (c *CollectionOf[T]) upsertSuffix(tenant string) string {
+	set := "data = EXCLUDED.data, version = t.version + 1, updated_at = EXCLUDED.updated_at"
+	if migrationsFor[T]() != nil {
+		set += ", schema_version = EXCLUDED.schema_version"
+	}
+	where := ""
+	if tenant != "" {
+		where = " WHERE t.tenant_id = EXCLUDED.tenant_id"
+	}
+	return "ON CONFLICT (id) DO UPDATE SET " + set + where + " RETURNING t.id, t.version"
 }
 
 // Update replaces an existing document's data. If the document has a Version
@@ -115,48 +449,123 @@ func (c *CollectionOf[T]) Update(ctx context.Context, doc *T) error {
 	if err := c.ensure(ctx); err != nil {
 		return err
 	}
+	if err := c.checkWrite(ctx, "update"); err != nil {
+		return err
+	}
+	tenant, err := c.tenant(ctx, "update")
+	if err != nil {
+		return err
+	}
 
-	id, err := meta.ExtractID(doc)
+	id, err := c.access.ID(doc)
 	if err != nil {
 		return fmt.Errorf("collection %s: update: %w", c.name, err)
 	}
+	if err := c.runDocHooks(ctx, "before update", id, doc); err != nil {
+		return err
+	}
 
-	currentVersion, hasVersion := meta.ExtractVersion(doc)
-	data, err := c.codec.Marshal(doc)
+	currentVersion, hasVersion := c.access.Version(doc)
+	var bufs encodeBuffers
+	defer bufs.release()
+	data, err := c.encode(doc, &bufs)
 	if err != nil {
 		return fmt.Errorf("collection %s: update %s: marshal: %w", c.name, id, err)
 	}
 
 	newVersion := currentVersion + 1
+	query, args, err := c.updateSQL(id, data, currentVersion, newVersion, hasVersion, tenant)
+	if err != nil {
+		return fmt.Errorf("collection %s: update %s: build sql: %w", c.name, id, err)
+	}
+
+	tag, err := c.exec.Exec(ctx, query, args...)
+	if err != nil {
+		return &whisker.DocumentError{Collection: c.name, ID: id, Op: "update", Err: mapPgError(err)}
+	}
+
+	if tag.RowsAffected() == 0 {
+		if hasVersion {
+			return &whisker.DocumentError{Collection: c.name, ID: id, Op: "update", Err: whisker.ErrConcurrencyConflict}
+		}
+		return &whisker.DocumentError{Collection: c.name, ID: id, Op: "update", Err: whisker.ErrNotFound}
+	}
+
+	c.access.SetVersion(doc, newVersion)
+	return c.runDocHooks(ctx, "after update", id, doc)
+}
+
+// updateSQL builds Update's statement, or takes it from Prepare.
+func (c *CollectionOf[T]) updateSQL(id string, data []byte, currentVersion, newVersion int, hasVersion bool, tenant string) (string, []any, error) {
+	chain := migrationsFor[T]()
+	if p := c.preparedFor(chain); p != nil {
+		query := p.update
+		if hasVersion {
+			query = p.updateVersioned
+		}
+		return query, p.updateArgs(data, newVersion, c.now(), id, currentVersion, hasVersion), nil
+	}
+
 	builder := psql.Update(c.table).
 		Set("data", data).
 		Set("version", newVersion).
-		Set("updated_at", sq.Expr("now()")).
+		Set("updated_at", c.now()).
 		Where(sq.Eq{"id": id})
-
+	if chain != nil {
+		builder = builder.Set("schema_version", chain.latest)
+	}
 	if hasVersion {
 		builder = builder.Where(sq.Eq{"version": currentVersion})
 	}
+	return whereTenant(builder, tenant).ToSql()
+}
 
-	query, args, err := builder.ToSql()
+// Increment adds delta to the numeric top-level field of document id in a
+// single UPDATE, so concurrent counters never lose an update the way
+// Load-modify-Update would. A missing or null field counts as 0; a field
+// that is not a number fails. The document's version is bumped like any
+// write. Returns the new value, or ErrNotFound if the document is absent.
+func (c *CollectionOf[T]) Increment(ctx context.Context, id, field string, delta float64) (float64, error) {
+	if err := c.ensure(ctx); err != nil {
+		return 0, err
+	}
+	if err := c.checkWrite(ctx, "increment"); err != nil {
+		return 0, err
+	}
+	tenant, err := c.tenant(ctx, "increment")
 	if err != nil {
-		return fmt.Errorf("collection %s: update %s: build sql: %w", c.name, id, err)
+		return 0, err
 	}
 
-	tag, err := c.exec.Exec(ctx, query, args...)
+	query, args, err := c.incrementSQL(id, field, delta, tenant)
 	if err != nil {
-		return fmt.Errorf("collection %s: update %s: %w", c.name, id, err)
+		return 0, fmt.Errorf("collection %s: increment %s: %w", c.name, id, err)
 	}
-
-	if tag.RowsAffected() == 0 {
-		if hasVersion {
-			return fmt.Errorf("collection %s: update %s: %w", c.name, id, whisker.ErrConcurrencyConflict)
+	var value float64
+	if err := c.exec.QueryRow(ctx, query, args...).Scan(&value); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = whisker.ErrNotFound
 		}
-		return fmt.Errorf("collection %s: update %s: %w", c.name, id, whisker.ErrNotFound)
+		return 0, &whisker.DocumentError{Collection: c.name, ID: id, Op: "increment", Err: err}
 	}
+	return value, nil
+}
 
-	meta.SetVersion(doc, newVersion)
-	return nil
+func (c *CollectionOf[T]) incrementSQL(id, field string, delta float64, tenant string) (string, []any, error) {
+	if !ident.IsField(field) {
+		return "", nil, fmt.Errorf("invalid field name %q", field)
+	}
+	if meta.Analyze[T]().Encrypted(field) {
+		return "", nil, fmt.Errorf("field %s is encrypted and cannot be incremented", field)
+	}
+	builder := psql.Update(c.table).
+		Set("data", sq.Expr("jsonb_set(data, ?::text[], to_jsonb(COALESCE((data->>?)::numeric, 0) + ?))", []string{field}, field, delta)).
+		Set("version", sq.Expr("version + 1")).
+		Set("updated_at", c.now()).
+		Where(sq.Eq{"id": id})
+	return whereTenant(builder, tenant).
+		Suffix("RETURNING (data->>?)::float8", field).
+		ToSql()
 }
 
 // Delete removes a document by ID. Returns ErrNotFound if absent.
@@ -164,21 +573,31 @@ func (c *CollectionOf[T]) Delete(ctx context.Context, id string) error {
 	if err := c.ensure(ctx); err != nil {
 		return err
 	}
+	if err := c.checkWrite(ctx, "delete"); err != nil {
+		return err
+	}
+	tenant, err := c.tenant(ctx, "delete")
+	if err != nil {
+		return err
+	}
+	if err := c.runDeleteHooks(ctx, "before delete", id); err != nil {
+		return err
+	}
 
-	query, args, err := psql.Delete(c.table).Where(sq.Eq{"id": id}).ToSql()
+	query, args, err := whereTenant(psql.Delete(c.table).Where(sq.Eq{"id": id}), tenant).ToSql()
 	if err != nil {
 		return fmt.Errorf("collection %s: delete %s: build sql: %w", c.name, id, err)
 	}
 
 	tag, err := c.exec.Exec(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("collection %s: delete %s: %w", c.name, id, err)
+		return &whisker.DocumentError{Collection: c.name, ID: id, Op: "delete", Err: mapPgError(err)}
 	}
 
 	if tag.RowsAffected() == 0 {
-		return fmt.Errorf("collection %s: delete %s: %w", c.name, id, whisker.ErrNotFound)
+		return &whisker.DocumentError{Collection: c.name, ID: id, Op: "delete", Err: whisker.ErrNotFound}
 	}
-	return nil
+	return c.runDeleteHooks(ctx, "after delete", id)
 }
 
 // Count returns the total number of documents in the collection.
@@ -191,7 +610,11 @@ func (c *CollectionOf[T]) Exists(ctx context.Context, id string) (bool, error) {
 	if err := c.ensure(ctx); err != nil {
 		return false, err
 	}
-	builder := psql.Select("1").From(c.table).Where(sq.Eq{"id": id})
+	tenant, err := c.tenant(ctx, "exists")
+	if err != nil {
+		return false, err
+	}
+	builder := whereTenant(psql.Select("1").From(c.table).Where(sq.Eq{"id": id}), tenant)
 	innerSQL, args, err := builder.ToSql()
 	if err != nil {
 		return false, fmt.Errorf("collection %s: exists: build sql: %w", c.name, err)
@@ -205,35 +628,102 @@ func (c *CollectionOf[T]) Exists(ctx context.Context, id string) (bool, error) {
 	return exists, nil
 }
 
+// ExistsMany reports which of ids exist, mapping each to true or false. It
+// runs a single SELECT id ... WHERE id = ANY($1) and loads no documents, so
+// validating a list of references does not need LoadMany.
+func (c *CollectionOf[T]) ExistsMany(ctx context.Context, ids []string) (map[string]bool, error) {
+	exists := make(map[string]bool, len(ids))
+	if len(ids) == 0 {
+		return exists, nil
+	}
+	if err := c.checkBatchSize(len(ids)); err != nil {
+		return nil, err
+	}
+	if err := c.ensure(ctx); err != nil {
+		return nil, err
+	}
+	tenant, err := c.tenant(ctx, "exists many")
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err := c.existsManySQL(ids, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("collection %s: exists many: build sql: %w", c.name, err)
+	}
+	rows, err := c.exec.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("collection %s: exists many: %w", c.name, err)
+	}
+	defer rows.Close()
+
+	for _, id := range ids {
+		exists[id] = false
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("collection %s: exists many: scan: %w", c.name, err)
+		}
+		exists[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("collection %s: exists many: %w", c.name, err)
+	}
+	return exists, nil
+}
+
+func (c *CollectionOf[T]) existsManySQL(ids []string, tenant string) (string, []any, error) {
+	builder := psql.Select("id").From(c.table).Where(sq.Expr("id = ANY(?)", ids))
+	return whereTenant(builder, tenant).ToSql()
+}
+
 // Load retrieves a single document by ID. Returns ErrNotFound if absent.
 func (c *CollectionOf[T]) Load(ctx context.Context, id string) (*T, error) {
 	if err := c.ensure(ctx); err != nil {
 		return nil, err
 	}
-
-	sql, args, err := psql.Select("data", "version").From(c.table).Where(sq.Eq{"id": id}).ToSql()
+	tenant, err := c.tenant(ctx, "load")
 	if err != nil {
-		return nil, fmt.Errorf("collection %s: load %s: build sql: %w", c.name, id, err)
+		return nil, err
 	}
 
-	var data []byte
+	chain := migrationsFor[T]()
+	var sql string
+	args := []any{id}
+	if p := c.preparedFor(chain); p != nil {
+		sql = p.load
+	} else {
+		builder := psql.Select(withSchemaVersion(chain, "data", "version")...).From(c.table).Where(sq.Eq{"id": id})
+		sql, args, err = whereTenant(builder, tenant).ToSql()
+		if err != nil {
+			return nil, fmt.Errorf("collection %s: load %s: build sql: %w", c.name, id, err)
+		}
+	}
+
+	scanner := newDocScanner[T](c.codec, chain)
 	var version int
-	err = c.exec.QueryRow(ctx, sql, args...).Scan(&data, &version)
+	schemaVersion := 1
+	dest := []any{scanner.target(), &version}
+	if chain != nil {
+		dest = append(dest, &schemaVersion)
+	}
+	err = c.exec.QueryRow(ctx, sql, args...).Scan(dest...)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("collection %s: load %s: %w", c.name, id, whisker.ErrNotFound)
+			return nil, &whisker.DocumentError{Collection: c.name, ID: id, Op: "load", Err: whisker.ErrNotFound}
 		}
-		return nil, fmt.Errorf("collection %s: load %s: %w", c.name, id, err)
+		return nil, &whisker.DocumentError{Collection: c.name, ID: id, Op: "load", Err: err}
 	}
 
-	var doc T
-	if err := c.codec.Unmarshal(data, &doc); err != nil {
+	doc, err := scanner.decode(id, schemaVersion)
+	if err != nil {
 		return nil, fmt.Errorf("collection %s: load %s: unmarshal: %w", c.name, id, err)
 	}
 
-	meta.SetID(&doc, id)
-	meta.SetVersion(&doc, version)
-	return &doc, nil
+	c.access.SetID(doc, id)
+	c.access.SetVersion(doc, version)
+	return doc, nil
 }
 
 // InsertMany stores multiple documents in a single INSERT statement.
@@ -252,52 +742,194 @@ func (c *CollectionOf[T]) InsertMany(ctx context.Context, docs []*T) error {
 	if err := c.ensure(ctx); err != nil {
 		return err
 	}
+	if err := c.checkWrite(ctx, "insert many"); err != nil {
+		return err
+	}
+	tenant, err := c.tenant(ctx, "insert many")
+	if err != nil {
+		return err
+	}
+
+	var bufs encodeBuffers
+	defer bufs.release()
+
+	cols, ids, rows, err := c.insertRows(ctx, "insert many", docs, tenant, &bufs)
+	if err != nil {
+		return err
+	}
+	builder := psql.Insert(c.table).Columns(cols...)
+	for _, values := range rows {
+		builder = builder.Values(values...)
+	}
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		return fmt.Errorf("collection %s: insert many: build sql: %w", c.name, err)
+	}
+
+	if _, err := c.exec.Exec(ctx, sql, args...); err != nil {
+		return c.insertManyError("insert many", ids, err)
+	}
+	return c.afterInsertMany(ctx, docs, ids)
+}
 
-	builder := psql.Insert(c.table).Columns("id", "data")
-	ids := make([]string, len(docs))
+// insertRows runs the before insert hooks and encodes docs into the column
+// values of an insert, one row per document.
+func (c *CollectionOf[T]) insertRows(ctx context.Context, op string, docs []*T, tenant string, bufs *encodeBuffers) (cols, ids []string, rows [][]any, err error) {
+	chain := migrationsFor[T]()
+	cols, _ = c.stampColumns(withSchemaVersion(chain, "id", "data"), nil)
+	cols, _ = withTenant(cols, nil, tenant)
+	ids = make([]string, len(docs))
+	rows = make([][]any, len(docs))
 
 	for i, doc := range docs {
-		id, err := meta.ExtractID(doc)
+		id, err := c.access.ID(doc)
 		if err != nil {
-			return fmt.Errorf("collection %s: %w", c.name, err)
+			return nil, nil, nil, fmt.Errorf("collection %s: %w", c.name, err)
 		}
 		if id == "" {
-			return fmt.Errorf("collection %s: insert many: document %d: ID must not be empty", c.name, i)
+			return nil, nil, nil, fmt.Errorf("collection %s: %s: document %d: ID must not be empty", c.name, op, i)
 		}
 		ids[i] = id
+		if err := c.runDocHooks(ctx, "before insert", id, doc); err != nil {
+			return nil, nil, nil, err
+		}
 
-		data, err := c.codec.Marshal(doc)
+		data, err := c.encode(doc, bufs)
 		if err != nil {
-			return fmt.Errorf("collection %s: insert many %s: marshal: %w", c.name, id, err)
+			return nil, nil, nil, fmt.Errorf("collection %s: %s %s: marshal: %w", c.name, op, id, err)
 		}
-		builder = builder.Values(id, data)
+		values := []any{id, data}
+		if chain != nil {
+			values = append(values, chain.latest)
+		}
+		_, values = c.stampColumns(nil, values)
+		_, rows[i] = withTenant(nil, values, tenant)
 	}
+	return cols, ids, rows, nil
+}
 
-	sql, args, err := builder.ToSql()
+// insertManyError maps a failed batch insert to a BatchError when it hit an
+// existing ID.
+func (c *CollectionOf[T]) insertManyError(op string, ids []string, err error) error {
+	if !isPgUniqueViolation(err) {
+		return fmt.Errorf("collection %s: %s: %w", c.name, op, err)
+	}
+	var pgErr *pgconn.PgError
+	errors.As(err, &pgErr)
+	errs := map[string]error{}
+	if conflictID := extractConflictID(pgErr.Detail); conflictID != "" {
+		errs[conflictID] = whisker.ErrDuplicateID
+	} else {
+		for _, id := range ids {
+			errs[id] = whisker.ErrDuplicateID
+		}
+	}
+	return &BatchError{Op: "insert", Total: len(ids), Errors: errs}
+}
+
+func (c *CollectionOf[T]) afterInsertMany(ctx context.Context, docs []*T, ids []string) error {
+	for _, doc := range docs {
+		c.access.SetVersion(doc, 1)
+	}
+	for i, doc := range docs {
+		if err := c.runDocHooks(ctx, "after insert", ids[i], doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpsertMany is Upsert for a batch: it inserts or replaces every document in
+// a single statement and sets each document's Version. IDs must be unique
+// within the batch.
+func (c *CollectionOf[T]) UpsertMany(ctx context.Context, docs []*T) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	if err := c.checkBatchSize(len(docs)); err != nil {
+		return err
+	}
+	if err := c.ensure(ctx); err != nil {
+		return err
+	}
+	if err := c.checkWrite(ctx, "upsert many"); err != nil {
+		return err
+	}
+	tenant, err := c.tenant(ctx, "upsert many")
 	if err != nil {
-		return fmt.Errorf("collection %s: insert many: build sql: %w", c.name, err)
+		return err
 	}
 
-	_, err = c.exec.Exec(ctx, sql, args...)
+	var bufs encodeBuffers
+	defer bufs.release()
+
+	builder := c.upsertBuilder(tenant)
+	byID := make(map[string]*T, len(docs))
+	for i, doc := range docs {
+		id, err := c.access.ID(doc)
+		if err != nil {
+			return fmt.Errorf("collection %s: %w", c.name, err)
+		}
+		if id == "" {
+			return fmt.Errorf("collection %s: upsert many: document %d: ID must not be empty", c.name, i)
+		}
+		if _, dup := byID[id]; dup {
+			return fmt.Errorf("collection %s: upsert many: duplicate id %s in batch", c.name, id)
+		}
+		byID[id] = doc
+		if err := c.runDocHooks(ctx, "before upsert", id, doc); err != nil {
+			return err
+		}
+
+		data, err := c.encode(doc, &bufs)
+		if err != nil {
+			return fmt.Errorf("collection %s: upsert many %s: marshal: %w", c.name, id, err)
+		}
+		builder = builder.Values(c.upsertValues(id, data, tenant)...)
+	}
+
+	sql, args, err := builder.Suffix(c.upsertSuffix(tenant)).ToSql()
 	if err != nil {
-		if isPgUniqueViolation(err) {
-			var pgErr *pgconn.PgError
-			errors.As(err, &pgErr)
-			errs := map[string]error{}
-			if conflictID := extractConflictID(pgErr.Detail); conflictID != "" {
-				errs[conflictID] = whisker.ErrDuplicateID
-			} else {
-				for _, id := range ids {
-					errs[id] = whisker.ErrDuplicateID
-				}
-			}
-			return &BatchError{Op: "insert", Total: len(ids), Errors: errs}
+		return fmt.Errorf("collection %s: upsert many: build sql: %w", c.name, err)
+	}
+
+	rows, err := c.exec.Query(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("collection %s: upsert many: %w", c.name, mapPgError(err))
+	}
+	defer rows.Close()
+
+	versions := make(map[string]int, len(docs))
+	for rows.Next() {
+		var id string
+		var version int
+		if err := rows.Scan(&id, &version); err != nil {
+			return fmt.Errorf("collection %s: upsert many: scan: %w", c.name, err)
 		}
-		return fmt.Errorf("collection %s: insert many: %w", c.name, err)
+		versions[id] = version
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("collection %s: upsert many: %w", c.name, mapPgError(err))
 	}
 
+	if len(versions) < len(byID) {
+		// ids of other tenants' documents were left alone
+		errs := map[string]error{}
+		for id := range byID {
+			if _, ok := versions[id]; !ok {
+				errs[id] = whisker.ErrDuplicateID
+			}
+		}
+		return &BatchError{Op: "upsert", Total: len(docs), Errors: errs}
+	}
+	for id, doc := range byID {
+		c.access.SetVersion(doc, versions[id])
+	}
 	for _, doc := range docs {
-		meta.SetVersion(doc, 1)
+		id, _ := c.access.ID(doc)
+		if err := c.runDocHooks(ctx, "after upsert", id, doc); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -316,10 +948,16 @@ func (c *CollectionOf[T]) LoadMany(ctx context.Context, ids []string) ([]*T, err
 		return nil, err
 	}
 
-	query, args, err := psql.Select("id", "data", "version").
+	tenant, err := c.tenant(ctx, "load many")
+	if err != nil {
+		return nil, err
+	}
+
+	chain := migrationsFor[T]()
+	builder := psql.Select(withSchemaVersion(chain, "id", "data", "version")...).
 		From(c.table).
-		Where(sq.Eq{"id": ids}).
-		ToSql()
+		Where(sq.Eq{"id": ids})
+	query, args, err := whereTenant(builder, tenant).ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("collection %s: load many: build sql: %w", c.name, err)
 	}
@@ -333,22 +971,27 @@ func (c *CollectionOf[T]) LoadMany(ctx context.Context, ids []string) ([]*T, err
 	foundIDs := make(map[string]bool, len(ids))
 	docs := make([]*T, 0, len(ids))
 
+	scanner := newDocScanner[T](c.codec, chain)
 	for rows.Next() {
 		var id string
-		var data []byte
 		var version int
-		if err := rows.Scan(&id, &data, &version); err != nil {
+		schemaVersion := 1
+		dest := []any{&id, scanner.target(), &version}
+		if chain != nil {
+			dest = append(dest, &schemaVersion)
+		}
+		if err := rows.Scan(dest...); err != nil {
 			return nil, fmt.Errorf("collection %s: load many: scan: %w", c.name, err)
 		}
 
-		var doc T
-		if err := c.codec.Unmarshal(data, &doc); err != nil {
+		doc, err := scanner.decode(id, schemaVersion)
+		if err != nil {
 			return nil, fmt.Errorf("collection %s: load many %s: unmarshal: %w", c.name, id, err)
 		}
 
-		meta.SetID(&doc, id)
-		meta.SetVersion(&doc, version)
-		docs = append(docs, &doc)
+		c.access.SetID(doc, id)
+		c.access.SetVersion(doc, version)
+		docs = append(docs, doc)
 		foundIDs[id] = true
 	}
 
@@ -383,9 +1026,20 @@ func (c *CollectionOf[T]) DeleteMany(ctx context.Context, ids []string) error {
 	if err := c.ensure(ctx); err != nil {
 		return err
 	}
+	if err := c.checkWrite(ctx, "delete many"); err != nil {
+		return err
+	}
+	tenant, err := c.tenant(ctx, "delete many")
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := c.runDeleteHooks(ctx, "before delete", id); err != nil {
+			return err
+		}
+	}
 
-	query, args, err := psql.Delete(c.table).
-		Where(sq.Eq{"id": ids}).
+	query, args, err := whereTenant(psql.Delete(c.table).Where(sq.Eq{"id": ids}), tenant).
 		Suffix("RETURNING id").
 		ToSql()
 	if err != nil {
@@ -409,6 +1063,14 @@ func (c *CollectionOf[T]) DeleteMany(ctx context.Context, ids []string) error {
 	if err := rows.Err(); err != nil {
 		return fmt.Errorf("collection %s: delete many: %w", c.name, err)
 	}
+	for _, id := range ids {
+		if !deleted[id] {
+			continue
+		}
+		if err := c.runDeleteHooks(ctx, "after delete", id); err != nil {
+			return err
+		}
+	}
 
 	if len(deleted) < len(ids) {
 		errs := map[string]error{}
@@ -432,7 +1094,9 @@ type docInfo struct {
 // UpdateMany updates multiple documents in a single UPDATE...FROM VALUES statement.
 // Optimistic concurrency is enforced per document — if any document's version has
 // changed since it was loaded, the entire batch fails with a BatchError identifying
-// which documents had version conflicts vs which were missing.
+// which documents had version conflicts vs which were missing, and none is
+// updated. A concurrent write landing while the statement runs can still leave
+// the other documents updated; run UpdateMany in a session to roll them back.
 func (c *CollectionOf[T]) UpdateMany(ctx context.Context, docs []*T) error {
 	if len(docs) == 0 {
 		return nil
@@ -443,11 +1107,21 @@ func (c *CollectionOf[T]) UpdateMany(ctx context.Context, docs []*T) error {
 	if err := c.ensure(ctx); err != nil {
 		return err
 	}
+	if err := c.checkWrite(ctx, "update many"); err != nil {
+		return err
+	}
+	tenant, err := c.tenant(ctx, "update many")
+	if err != nil {
+		return err
+	}
+
+	var bufs encodeBuffers
+	defer bufs.release()
 
 	infos := make([]docInfo, len(docs))
 	seen := make(map[string]bool, len(docs))
 	for i, doc := range docs {
-		id, err := meta.ExtractID(doc)
+		id, err := c.access.ID(doc)
 		if err != nil {
 			return fmt.Errorf("collection %s: %w", c.name, err)
 		}
@@ -455,9 +1129,12 @@ func (c *CollectionOf[T]) UpdateMany(ctx context.Context, docs []*T) error {
 			return fmt.Errorf("collection %s: update many: duplicate id %s in batch", c.name, id)
 		}
 		seen[id] = true
+		if err := c.runDocHooks(ctx, "before update", id, doc); err != nil {
+			return err
+		}
 
-		currentVersion, _ := meta.ExtractVersion(doc)
-		data, err := c.codec.Marshal(doc)
+		currentVersion, _ := c.access.Version(doc)
+		data, err := c.encode(doc, &bufs)
 		if err != nil {
 			return fmt.Errorf("collection %s: update many %s: marshal: %w", c.name, id, err)
 		}
@@ -470,92 +1147,110 @@ func (c *CollectionOf[T]) UpdateMany(ctx context.Context, docs []*T) error {
 		}
 	}
 
-	args := make([]any, 0, len(infos)*4)
-	valueClauses := make([]string, len(infos))
-	for i, info := range infos {
-		base := i * 4
-		valueClauses[i] = fmt.Sprintf("($%d::text, $%d::jsonb, $%d::int, $%d::int)",
-			base+1, base+2, base+3, base+4)
-		args = append(args, info.id, info.data, info.newVersion, info.oldVersion)
-	}
-
-	sql := fmt.Sprintf(
-		`UPDATE %s AS t SET data = v.data, version = v.new_version, updated_at = now() `+
-			`FROM (VALUES %s) AS v(id, data, new_version, old_version) `+
-			`WHERE t.id = v.id AND t.version = v.old_version `+
-			`RETURNING t.id`,
-		c.table, strings.Join(valueClauses, ", "))
-
+	sql, args := c.updateManySQL(infos, tenant)
 	rows, err := c.exec.Query(ctx, sql, args...)
 	if err != nil {
 		return fmt.Errorf("collection %s: update many: %w", c.name, err)
 	}
 	defer rows.Close()
 
+	// one row per document: whether it was updated, and its stored version
+	// before the statement ran, NULL when it doesn't exist
+	stored := make(map[string]*int, len(docs))
 	updated := make(map[string]bool, len(docs))
 	for rows.Next() {
 		var id string
-		if err := rows.Scan(&id); err != nil {
+		var version *int
+		var ok bool
+		if err := rows.Scan(&id, &version, &ok); err != nil {
 			return fmt.Errorf("collection %s: update many: scan: %w", c.name, err)
 		}
-		updated[id] = true
+		stored[id] = version
+		updated[id] = ok
 	}
 	if err := rows.Err(); err != nil {
 		return fmt.Errorf("collection %s: update many: %w", c.name, err)
 	}
 
-	if len(updated) < len(docs) {
-		return c.identifyUpdateFailures(ctx, infos, updated)
+	if errs := updateFailures(infos, stored, updated); len(errs) > 0 {
+		return &BatchError{Op: "update", Total: len(infos), Errors: errs}
 	}
 
 	for i, doc := range docs {
-		meta.SetVersion(doc, infos[i].newVersion)
+		c.access.SetVersion(doc, infos[i].newVersion)
+	}
+	for i, doc := range docs {
+		if err := c.runDocHooks(ctx, "after update", infos[i].id, doc); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (c *CollectionOf[T]) identifyUpdateFailures(ctx context.Context, infos []docInfo, updated map[string]bool) error {
-	// collect IDs that failed
-	var failedIDs []string
-	for _, info := range infos {
-		if !updated[info.id] {
-			failedIDs = append(failedIDs, info.id)
-		}
+// updateManySQL builds the UpdateMany statement. It checks every document's
+// version against the stored one and updates them all only if none
+// conflicts or is missing, returning each document's stored version and
+// whether it was updated, so failures are told apart in the same round trip.
+func (c *CollectionOf[T]) updateManySQL(infos []docInfo, tenant string) (string, []any) {
+	args := make([]any, 0, len(infos)*4+1)
+	valueClauses := make([]string, len(infos))
+	for i, info := range infos {
+		base := i * 4
+		valueClauses[i] = fmt.Sprintf("($%d::text, $%d::jsonb, $%d::int, $%d::int)",
+			base+1, base+2, base+3, base+4)
+		args = append(args, info.id, info.data, info.newVersion, info.oldVersion)
 	}
 
-	// re-query to distinguish "not found" from "version conflict"
-	query, args, err := psql.Select("id").From(c.table).Where(sq.Eq{"id": failedIDs}).ToSql()
-	if err != nil {
-		return fmt.Errorf("collection %s: update many: identify failures: %w", c.name, err)
+	stamp := ""
+	if chain := migrationsFor[T](); chain != nil {
+		stamp = fmt.Sprintf(", schema_version = %d", chain.latest)
 	}
-
-	rows, err := c.exec.Query(ctx, query, args...)
-	if err != nil {
-		return fmt.Errorf("collection %s: update many: identify failures: %w", c.name, err)
+	args = append(args, pg.Timestamp(c.clock))
+	now := pg.NowExpr(fmt.Sprintf("$%d", len(args)))
+	// other tenants' documents count as missing
+	scope := ""
+	if tenant != "" {
+		args = append(args, tenant)
+		scope = fmt.Sprintf(" AND t.tenant_id = $%d", len(args))
 	}
-	defer rows.Close()
+	sql := fmt.Sprintf(
+		`WITH v(id, data, new_version, old_version) AS (VALUES %[4]s), `+
+			`cur AS (SELECT v.id, t.version, v.old_version FROM v LEFT JOIN %[1]s t ON t.id = v.id%[5]s), `+
+			`upd AS (UPDATE %[1]s AS t SET data = v.data, version = v.new_version, updated_at = %[2]s%[3]s `+
+			`FROM v WHERE t.id = v.id AND t.version = v.old_version%[5]s `+
+			`AND NOT EXISTS (SELECT 1 FROM cur WHERE cur.version IS DISTINCT FROM cur.old_version) `+
+			`RETURNING t.id) `+
+			`SELECT cur.id, cur.version, upd.id IS NOT NULL FROM cur LEFT JOIN upd ON upd.id = cur.id`,
+		c.table, now, stamp, strings.Join(valueClauses, ", "), scope)
+	return sql, args
+}
 
-	existing := make(map[string]bool)
-	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err != nil {
-			return fmt.Errorf("collection %s: update many: scan: %w", c.name, err)
+// updateFailures maps each document UpdateMany could not update to why: not
+// found, or a version conflict. Documents skipped only because another one
+// failed are left out. A document that matched but was not updated lost a
+// race with a concurrent write, which is a conflict too.
+func updateFailures(infos []docInfo, stored map[string]*int, updated map[string]bool) map[string]error {
+	errs := map[string]error{}
+	skipped := false
+	for _, info := range infos {
+		switch version := stored[info.id]; {
+		case updated[info.id]:
+		case version == nil:
+			errs[info.id] = whisker.ErrNotFound
+		case *version != info.oldVersion:
+			errs[info.id] = whisker.ErrConcurrencyConflict
+		default:
+			skipped = true
 		}
-		existing[id] = true
 	}
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("collection %s: update many: %w", c.name, err)
-	}
-
-	errs := map[string]error{}
-	for _, id := range failedIDs {
-		if existing[id] {
-			errs[id] = whisker.ErrConcurrencyConflict
-		} else {
-			errs[id] = whisker.ErrNotFound
+	if len(errs) == 0 && skipped {
+		for _, info := range infos {
+			if !updated[info.id] {
+				errs[info.id] = whisker.ErrConcurrencyConflict
+			}
 		}
 	}
-	return &BatchError{Op: "update", Total: len(infos), Errors: errs}
+	return errs
 }
 
 func (c *CollectionOf[T]) checkBatchSize(n int) error {
@@ -573,6 +1268,16 @@ func isPgUniqueViolation(err error) bool {
 	return false
 }
 
+// mapPgError wraps constraint violations with the matching whisker sentinel
+// while keeping the original pg error in the chain.
+func mapPgError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return fmt.Errorf("%w: %w", whisker.ErrForeignKey, err)
+	}
+	return err
+}
+
 // Detail format: "Key (id)=(somevalue) already exists."
 func extractConflictID(detail string) string {
 	start := strings.Index(detail, "(id)=(")
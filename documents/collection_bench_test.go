@@ -126,6 +126,35 @@ func BenchmarkInsertMany_VsLoop(b *testing.B) {
 	})
 }
 
+func BenchmarkInsertMany_VsCopy(b *testing.B) {
+	const size = 1000
+
+	for name, insert := range map[string]func(*CollectionOf[benchUser], context.Context, []*benchUser) error{
+		"insert": (*CollectionOf[benchUser]).InsertMany,
+		"copy":   (*CollectionOf[benchUser]).InsertManyCopy,
+	} {
+		b.Run(name, func(b *testing.B) {
+			store, ctx := setupBench(b)
+			users := Collection[benchUser](store, "bench_insert_many_vs_"+name)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				docs := make([]*benchUser, size)
+				for j := range size {
+					docs[j] = &benchUser{
+						ID:    fmt.Sprintf("u%d_%d", i, j),
+						Name:  "Alice",
+						Email: "alice@test.com",
+					}
+				}
+				if err := insert(users, ctx, docs); err != nil {
+					b.Fatalf("%s: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkInsert(b *testing.B) {
 	store, ctx := setupBench(b)
 	users := Collection[benchUser](store, "bench_insert")
@@ -139,6 +168,38 @@ func BenchmarkInsert(b *testing.B) {
 	}
 }
 
+func BenchmarkInsert_Prepared(b *testing.B) {
+	store, ctx := setupBench(b)
+	users := Collection[benchUser](store, "bench_insert_prepared")
+	if err := users.Prepare(ctx); err != nil {
+		b.Fatalf("prepare: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		u := &benchUser{ID: fmt.Sprintf("u%d", i), Name: "Alice", Email: "alice@test.com"}
+		if err := users.Insert(ctx, u); err != nil {
+			b.Fatalf("insert: %v", err)
+		}
+	}
+}
+
+func BenchmarkLoad_Prepared(b *testing.B) {
+	store, ctx := setupBench(b)
+	users := Collection[benchUser](store, "bench_load_prepared")
+	if err := users.Prepare(ctx); err != nil {
+		b.Fatalf("prepare: %v", err)
+	}
+	_ = users.Insert(ctx, &benchUser{ID: "u1", Name: "Alice", Email: "alice@test.com"})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		if _, err := users.Load(ctx, "u1"); err != nil {
+			b.Fatalf("load: %v", err)
+		}
+	}
+}
+
 func BenchmarkLoad(b *testing.B) {
 	store, ctx := setupBench(b)
 	users := Collection[benchUser](store, "bench_load")
@@ -167,6 +228,24 @@ func BenchmarkUpdate(b *testing.B) {
 	}
 }
 
+func BenchmarkUpdate_Prepared(b *testing.B) {
+	store, ctx := setupBench(b)
+	users := Collection[benchUser](store, "bench_update_prepared")
+	if err := users.Prepare(ctx); err != nil {
+		b.Fatalf("prepare: %v", err)
+	}
+	u := &benchUser{ID: "u1", Name: "Alice", Email: "alice@test.com"}
+	_ = users.Insert(ctx, u)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		u.Name = "Bob"
+		if err := users.Update(ctx, u); err != nil {
+			b.Fatalf("update: %v", err)
+		}
+	}
+}
+
 func BenchmarkDelete(b *testing.B) {
 	store, ctx := setupBench(b)
 	users := Collection[benchUser](store, "bench_delete")
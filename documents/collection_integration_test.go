@@ -3,11 +3,19 @@
 package documents_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/ripkitten-co/whisker"
 	"github.com/ripkitten-co/whisker/documents"
 	"github.com/ripkitten-co/whisker/internal/testutil"
@@ -54,6 +62,43 @@ func TestCollection_InsertAndLoad(t *testing.T) {
 	}
 }
 
+func TestCollection_PreparedRoundTrip(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[User](store, "prepared_users")
+	if err := users.Prepare(ctx); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+
+	u := &User{ID: "u1", Name: "Alice", Email: "alice@test.com"}
+	if err := users.Insert(ctx, u); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := users.Insert(ctx, &User{ID: "u1"}); !errors.Is(err, whisker.ErrDuplicateID) {
+		t.Errorf("duplicate insert: got %v, want ErrDuplicateID", err)
+	}
+
+	u.Name = "Bob"
+	if err := users.Update(ctx, u); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	stale := &User{ID: "u1", Name: "Carol", Version: 1}
+	if err := users.Update(ctx, stale); !errors.Is(err, whisker.ErrConcurrencyConflict) {
+		t.Errorf("stale update: got %v, want ErrConcurrencyConflict", err)
+	}
+
+	got, err := users.Load(ctx, "u1")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Name != "Bob" || got.Version != 2 {
+		t.Errorf("got %+v, want Bob at version 2", got)
+	}
+	if _, err := users.Load(ctx, "missing"); !errors.Is(err, whisker.ErrNotFound) {
+		t.Errorf("load missing: got %v, want ErrNotFound", err)
+	}
+}
+
 func TestCollection_LoadNotFound(t *testing.T) {
 	store := setupStore(t)
 	ctx := context.Background()
@@ -63,6 +108,10 @@ func TestCollection_LoadNotFound(t *testing.T) {
 	if !errors.Is(err, whisker.ErrNotFound) {
 		t.Errorf("got %v, want ErrNotFound", err)
 	}
+	var docErr *whisker.DocumentError
+	if !errors.As(err, &docErr) || docErr.Collection != "users" || docErr.ID != "nonexistent" || docErr.Op != "load" {
+		t.Errorf("got %#v, want DocumentError for users/nonexistent", err)
+	}
 }
 
 func TestCollection_UpdateWithConcurrency(t *testing.T) {
@@ -107,6 +156,120 @@ func TestCollection_UpdateConcurrencyConflict(t *testing.T) {
 	}
 }
 
+func TestCollection_Upsert(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[User](store, "upsert_users")
+
+	doc := &User{ID: "u1", Name: "Alice"}
+	if err := users.Upsert(ctx, doc); err != nil {
+		t.Fatalf("upsert new: %v", err)
+	}
+	if doc.Version != 1 {
+		t.Errorf("new version = %d, want 1", doc.Version)
+	}
+
+	// a stale version does not stop the replacement
+	stale := &User{ID: "u1", Name: "Alicia"}
+	if err := users.Upsert(ctx, stale); err != nil {
+		t.Fatalf("upsert existing: %v", err)
+	}
+	if stale.Version != 2 {
+		t.Errorf("replaced version = %d, want 2", stale.Version)
+	}
+
+	got, err := users.Load(ctx, "u1")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Name != "Alicia" || got.Version != 2 {
+		t.Errorf("got %+v", got)
+	}
+}
+
+type Counter struct {
+	ID      string
+	Hits    int
+	Version int
+}
+
+func TestCollection_Increment(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	counters := documents.Collection[Counter](store, "counters")
+
+	if err := counters.Insert(ctx, &Counter{ID: "c1"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	const workers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := counters.Increment(ctx, "c1", "hits", 1); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("increment: %v", err)
+	}
+
+	n, err := counters.Increment(ctx, "c1", "hits", -5)
+	if err != nil {
+		t.Fatalf("decrement: %v", err)
+	}
+	if n != workers-5 {
+		t.Errorf("value = %v, want %d", n, workers-5)
+	}
+	got, err := counters.Load(ctx, "c1")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Hits != workers-5 || got.Version != workers+2 {
+		t.Errorf("got %+v", got)
+	}
+
+	if _, err := counters.Increment(ctx, "missing", "hits", 1); !errors.Is(err, whisker.ErrNotFound) {
+		t.Errorf("missing doc: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestCollection_UpsertMany(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[User](store, "upsert_many_users")
+
+	if err := users.Insert(ctx, &User{ID: "u1", Name: "Alice"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	docs := []*User{{ID: "u1", Name: "Alicia"}, {ID: "u2", Name: "Bob"}}
+	if err := users.UpsertMany(ctx, docs); err != nil {
+		t.Fatalf("upsert many: %v", err)
+	}
+	if docs[0].Version != 2 || docs[1].Version != 1 {
+		t.Errorf("versions = %d, %d, want 2, 1", docs[0].Version, docs[1].Version)
+	}
+
+	got, err := users.Load(ctx, "u1")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Name != "Alicia" {
+		t.Errorf("u1 = %+v", got)
+	}
+
+	err = users.UpsertMany(ctx, []*User{{ID: "u3"}, {ID: "u3"}})
+	if err == nil || !strings.Contains(err.Error(), "duplicate id") {
+		t.Errorf("got %v, want duplicate id error", err)
+	}
+}
+
 func TestCollection_Delete(t *testing.T) {
 	store := setupStore(t)
 	ctx := context.Background()
@@ -239,6 +402,76 @@ func TestCollection_BtreeIndexesCreated(t *testing.T) {
 	}
 }
 
+type TenantOrder struct {
+	ID       string
+	TenantID string `whisker:"column,index(group=tenant_status,order=1)"`
+	Status   string `whisker:"index(group=tenant_status)"`
+	Version  int
+}
+
+func TestCollection_CompositeIndexCreated(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	orders := documents.Collection[TenantOrder](store, "composite_orders")
+
+	if err := orders.Insert(ctx, &TenantOrder{ID: "o1", TenantID: "t1", Status: "open"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	var def string
+	err := store.DBExecutor().QueryRow(ctx,
+		"SELECT indexdef FROM pg_indexes WHERE indexname = 'idx_whisker_composite_orders_tenant_status_group'",
+	).Scan(&def)
+	if err != nil {
+		t.Fatalf("query pg_indexes: %v", err)
+	}
+	if !strings.Contains(def, "(tenant_id, ((data ->> 'status'::text)))") {
+		t.Errorf("indexdef = %s, want tenant_id then status", def)
+	}
+
+	got, err := orders.Where("tenantID", "=", "t1").Where("status", "=", "open").Execute(ctx)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "o1" {
+		t.Errorf("got %+v, want o1", got)
+	}
+}
+
+func TestCollection_PartialIndexCreated(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	orders := documents.Collection[TenantOrder](store, "partial_orders",
+		documents.WithIndex(documents.IndexSpec{
+			Name:   "open",
+			Fields: []string{"tenantID"},
+			Where:  "data->>'status' = 'open'",
+		}),
+	)
+
+	if err := orders.Insert(ctx, &TenantOrder{ID: "o1", TenantID: "t1", Status: "open"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	var def string
+	err := store.DBExecutor().QueryRow(ctx,
+		"SELECT indexdef FROM pg_indexes WHERE indexname = 'idx_whisker_partial_orders_open_partial'",
+	).Scan(&def)
+	if err != nil {
+		t.Fatalf("query pg_indexes: %v", err)
+	}
+	if !strings.Contains(def, "(tenant_id) WHERE ((data ->> 'status'::text) = 'open'::text)") {
+		t.Errorf("indexdef = %s", def)
+	}
+
+	bad := documents.Collection[TenantOrder](store, "partial_orders",
+		documents.WithIndex(documents.IndexSpec{Name: "bad", Fields: []string{"tenantID"}, Where: "true; DROP TABLE x"}),
+	)
+	if err := bad.Insert(ctx, &TenantOrder{ID: "o2"}); err == nil {
+		t.Error("expected error for invalid index spec")
+	}
+}
+
 func TestCollection_GINIndexCreated(t *testing.T) {
 	store := setupStore(t)
 	ctx := context.Background()
@@ -387,6 +620,54 @@ func TestCollection_AfterCursor(t *testing.T) {
 	}
 }
 
+func TestCollection_ExecutePageWithTies(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[User](store, "page_users")
+
+	// names tie in threes and emails in pairs, so only the ID orders some documents
+	var docs []*User
+	for i := range 9 {
+		u := &User{ID: fmt.Sprintf("u%d", i), Name: fmt.Sprintf("name%d", i/3), Email: fmt.Sprintf("e%d@test.com", i%2)}
+		docs = append(docs, u)
+	}
+	if err := users.InsertMany(ctx, docs); err != nil {
+		t.Fatalf("insert many: %v", err)
+	}
+
+	for _, dir := range []documents.Direction{documents.Asc, documents.Desc} {
+		q := users.Query().OrderBy("name", dir).OrderBy("email", dir).Limit(2)
+		all, err := users.Query().OrderBy("name", dir).OrderBy("email", dir).OrderBy("id", documents.Asc).Execute(ctx)
+		if err != nil {
+			t.Fatalf("execute: %v", err)
+		}
+
+		var got []string
+		cursor := ""
+		for range 10 {
+			page, err := q.AfterCursor(cursor).ExecutePage(ctx)
+			if err != nil {
+				t.Fatalf("%s: page after %q: %v", dir, cursor, err)
+			}
+			for _, u := range page.Docs {
+				got = append(got, u.ID)
+			}
+			if cursor = page.NextCursor(); cursor == "" {
+				break
+			}
+		}
+		if len(got) != len(all) {
+			t.Fatalf("%s: paged %v, want %d documents", dir, got, len(all))
+		}
+		for i, u := range all {
+			if got[i] != u.ID {
+				t.Errorf("%s: paged %v, want order of %v", dir, got, all)
+				break
+			}
+		}
+	}
+}
+
 func TestCollection_QueryCount(t *testing.T) {
 	store := setupStore(t)
 	ctx := context.Background()
@@ -413,6 +694,40 @@ func TestCollection_QueryCount(t *testing.T) {
 	}
 }
 
+func TestCollection_QueryInAndOr(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[User](store, "in_or_users")
+
+	users.Insert(ctx, &User{ID: "u1", Name: "Alice", Email: "a@test.com"})
+	users.Insert(ctx, &User{ID: "u2", Name: "Bob", Email: "b@test.com"})
+	users.Insert(ctx, &User{ID: "u3", Name: "Carol", Email: "c@test.com"})
+
+	in, err := users.Where("name", "IN", []string{"Alice", "Carol"}).Count(ctx)
+	if err != nil {
+		t.Fatalf("in: %v", err)
+	}
+	if in != 2 {
+		t.Errorf("in: got %d, want 2", in)
+	}
+
+	notIn, err := users.Where("name", "NOT IN", []string{"Alice", "Carol"}).Count(ctx)
+	if err != nil {
+		t.Fatalf("not in: %v", err)
+	}
+	if notIn != 1 {
+		t.Errorf("not in: got %d, want 1", notIn)
+	}
+
+	or, err := users.Where("name", "=", "Alice").OrWhere("email", "=", "b@test.com").Count(ctx)
+	if err != nil {
+		t.Fatalf("or: %v", err)
+	}
+	if or != 2 {
+		t.Errorf("or: got %d, want 2", or)
+	}
+}
+
 func TestCollection_QueryExists(t *testing.T) {
 	store := setupStore(t)
 	ctx := context.Background()
@@ -437,6 +752,94 @@ func TestCollection_QueryExists(t *testing.T) {
 	}
 }
 
+func TestCollection_QueryIterate(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[User](store, "iter_users")
+
+	docs := make([]*User, 25)
+	for i := range docs {
+		docs[i] = &User{ID: fmt.Sprintf("u%02d", i), Name: "User", Email: fmt.Sprintf("u%02d@test.com", i)}
+	}
+	if err := users.InsertMany(ctx, docs); err != nil {
+		t.Fatalf("insert many: %v", err)
+	}
+
+	var ids []string
+	err := users.Query().OrderBy("email", documents.Asc).FetchSize(10).Iterate(ctx, func(u *User) error {
+		if u.Version != 1 {
+			t.Errorf("%s version = %d, want 1", u.ID, u.Version)
+		}
+		ids = append(ids, u.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("iterate: %v", err)
+	}
+	if len(ids) != 25 || ids[0] != "u00" || ids[24] != "u24" {
+		t.Errorf("got ids %v", ids)
+	}
+
+	stop := errors.New("stop")
+	seen := 0
+	err = users.Query().FetchSize(10).Iterate(ctx, func(*User) error {
+		seen++
+		if seen == 3 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || seen != 3 {
+		t.Errorf("got err %v after %d documents, want stop after 3", err, seen)
+	}
+}
+
+func TestCollection_CursorInSession(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	if err := documents.Collection[User](store, "cursor_users").InsertMany(ctx, []*User{
+		{ID: "u1", Name: "Alice"},
+		{ID: "u2", Name: "Bob"},
+		{ID: "u3", Name: "Carol"},
+	}); err != nil {
+		t.Fatalf("insert many: %v", err)
+	}
+
+	sess, err := store.Session(ctx)
+	if err != nil {
+		t.Fatalf("session: %v", err)
+	}
+	defer sess.Close(ctx)
+	users := documents.Collection[User](sess, "cursor_users")
+
+	cur, err := users.Query().OrderBy("name", documents.Asc).FetchSize(2).Cursor(ctx)
+	if err != nil {
+		t.Fatalf("cursor: %v", err)
+	}
+	var names []string
+	for cur.Next(ctx) {
+		u := cur.Doc()
+		names = append(names, u.Name)
+		// the session stays usable between fetches
+		u.Name += "!"
+		if err := users.Update(ctx, u); err != nil {
+			t.Fatalf("update %s: %v", u.ID, err)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		t.Fatalf("cursor: %v", err)
+	}
+	if err := cur.Close(ctx); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if strings.Join(names, ",") != "Alice,Bob,Carol" {
+		t.Errorf("got names %v", names)
+	}
+	if err := sess.Commit(ctx); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+}
+
 func TestCollection_ExistsByID(t *testing.T) {
 	store := setupStore(t)
 	ctx := context.Background()
@@ -460,3 +863,1304 @@ func TestCollection_ExistsByID(t *testing.T) {
 		t.Error("expected false")
 	}
 }
+
+func TestCollection_ExistsMany(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[User](store, "exists_many_users")
+
+	for _, id := range []string{"u1", "u3"} {
+		if err := users.Insert(ctx, &User{ID: id, Name: id}); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	exists, err := users.ExistsMany(ctx, []string{"u1", "u2", "u3", "u1"})
+	if err != nil {
+		t.Fatalf("exists many: %v", err)
+	}
+	want := map[string]bool{"u1": true, "u2": false, "u3": true}
+	if len(exists) != len(want) {
+		t.Fatalf("got %v, want %v", exists, want)
+	}
+	for id, ok := range want {
+		if exists[id] != ok {
+			t.Errorf("%s: got %v, want %v", id, exists[id], ok)
+		}
+	}
+}
+
+type ColumnUser struct {
+	ID       string
+	Email    string    `whisker:"column,index"`
+	Age      int       `whisker:"column"`
+	Rank     *int      `whisker:"column"`
+	Score    *float64  `whisker:"column"`
+	JoinedAt time.Time `whisker:"column"`
+	Version  int
+}
+
+func TestCollection_GeneratedColumns(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[ColumnUser](store, "col_users")
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, email := range []string{"c@test.com", "a@test.com", "b@test.com"} {
+		// ranks 2, 9 and 10 and scores 2.5, 9.5 and 10.5 order differently as text
+		rank, score := []int{2, 9, 10}[i], []float64{2.5, 9.5, 10.5}[i]
+		err := users.Insert(ctx, &ColumnUser{
+			ID: fmt.Sprintf("u%d", i), Email: email, Age: 20 + i,
+			Rank: &rank, Score: &score, JoinedAt: base.AddDate(0, 0, i),
+		})
+		if err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	if err := users.Insert(ctx, &ColumnUser{ID: "u3", Email: "d@test.com"}); err != nil {
+		t.Fatalf("insert without pointers: %v", err)
+	}
+
+	rows, err := store.DBExecutor().Query(ctx,
+		"SELECT column_name, data_type FROM information_schema.columns WHERE table_name = 'whisker_col_users' AND column_name IN ('email', 'age', 'rank', 'score', 'joined_at')",
+	)
+	if err != nil {
+		t.Fatalf("query columns: %v", err)
+	}
+	types := map[string]string{}
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			t.Fatalf("scan column: %v", err)
+		}
+		types[name] = typ
+	}
+	rows.Close()
+	want := map[string]string{
+		"email":     "text",
+		"age":       "bigint",
+		"rank":      "bigint",
+		"score":     "double precision",
+		"joined_at": "timestamp with time zone",
+	}
+	for name, typ := range want {
+		if types[name] != typ {
+			t.Errorf("column %s: type %q, want %q", name, types[name], typ)
+		}
+	}
+
+	ranked, err := users.Where("rank", ">", 5).OrderBy("score", documents.Asc).Execute(ctx)
+	if err != nil {
+		t.Fatalf("query rank: %v", err)
+	}
+	if len(ranked) != 2 || ranked[0].ID != "u1" || ranked[1].ID != "u2" {
+		t.Errorf("rank > 5: got %+v", ranked)
+	}
+
+	joined, err := users.Where("joinedAt", ">=", base.AddDate(0, 0, 1)).OrderBy("joinedAt", documents.Desc).Execute(ctx)
+	if err != nil {
+		t.Fatalf("query joinedAt: %v", err)
+	}
+	if len(joined) != 2 || joined[0].ID != "u2" || joined[1].ID != "u1" {
+		t.Errorf("joinedAt >= day 2: got %+v", joined)
+	}
+
+	got, err := users.Where("age", ">", 20).OrderBy("email", documents.Asc).Execute(ctx)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(got) != 2 || got[0].Email != "a@test.com" || got[1].Email != "b@test.com" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+type ReservedColumnUser struct {
+	ID        string
+	Email     string    `whisker:"column"`
+	CreatedAt time.Time `whisker:"column"`
+	Version   int
+}
+
+func TestCollection_ReservedGeneratedColumn(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[ReservedColumnUser](store, "reserved_col_users")
+
+	err := users.Insert(ctx, &ReservedColumnUser{ID: "u1", Email: "a@test.com"})
+	if err == nil || !strings.Contains(err.Error(), "CreatedAt") {
+		t.Fatalf("got %v, want an error naming CreatedAt", err)
+	}
+}
+
+type CIUser struct {
+	ID      string
+	Email   string `whisker:"index,ci"`
+	Version int
+}
+
+func TestCollection_WhereFold(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[CIUser](store, "ci_users")
+
+	if err := users.Insert(ctx, &CIUser{ID: "u1", Email: "Alice@Example.com"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	got, err := users.WhereFold("email", "alice@example.COM").Execute(ctx)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "u1" {
+		t.Errorf("got %+v", got)
+	}
+
+	var count int
+	err = store.DBExecutor().QueryRow(ctx,
+		"SELECT count(*) FROM pg_indexes WHERE tablename = 'whisker_ci_users' AND indexname = 'idx_whisker_ci_users_email_ci'",
+	).Scan(&count)
+	if err != nil {
+		t.Fatalf("query pg_indexes: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("ci index count = %d, want 1", count)
+	}
+}
+
+type Post struct {
+	ID      string
+	Body    string `whisker:"index,fts=english"`
+	Version int
+}
+
+func TestCollection_PatternAndTextSearch(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	posts := documents.Collection[Post](store, "fts_posts")
+
+	for _, p := range []*Post{
+		{ID: "p1", Body: "Fast cars and open roads"},
+		{ID: "p2", Body: "A slow boat down the river"},
+	} {
+		if err := posts.Insert(ctx, p); err != nil {
+			t.Fatalf("insert %s: %v", p.ID, err)
+		}
+	}
+
+	got, err := posts.Where("body", "ILIKE", "fast%").Execute(ctx)
+	if err != nil {
+		t.Fatalf("ilike: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "p1" {
+		t.Errorf("ilike: got %+v", got)
+	}
+
+	got, err = posts.WhereTextSearch("body", "car").Execute(ctx)
+	if err != nil {
+		t.Fatalf("text search: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "p1" {
+		t.Errorf("text search: got %+v", got)
+	}
+
+	var count int
+	err = store.DBExecutor().QueryRow(ctx,
+		"SELECT count(*) FROM pg_indexes WHERE tablename = 'whisker_fts_posts' AND indexname = 'idx_whisker_fts_posts_body_fts'",
+	).Scan(&count)
+	if err != nil {
+		t.Fatalf("query pg_indexes: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("fts index count = %d, want 1", count)
+	}
+}
+
+type Contact struct {
+	ID      string
+	Name    string `whisker:"index,trgm"`
+	Version int
+}
+
+func TestCollection_Similar(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	contacts := documents.Collection[Contact](store, "trgm_contacts")
+
+	for _, c := range []*Contact{
+		{ID: "c1", Name: "John Smith"},
+		{ID: "c2", Name: "Jane Doe"},
+	} {
+		if err := contacts.Insert(ctx, c); err != nil {
+			t.Fatalf("insert %s: %v", c.ID, err)
+		}
+	}
+
+	got, err := contacts.Similar("name", "jonh smith", 0.3).Execute(ctx)
+	if err != nil {
+		t.Fatalf("similar: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "c1" {
+		t.Errorf("similar: got %+v", got)
+	}
+
+	var count int
+	err = store.DBExecutor().QueryRow(ctx,
+		"SELECT count(*) FROM pg_indexes WHERE tablename = 'whisker_trgm_contacts' AND indexname = 'idx_whisker_trgm_contacts_name_trgm'",
+	).Scan(&count)
+	if err != nil {
+		t.Fatalf("query pg_indexes: %v", err)
+	}
+	if count != 1 {
+		t.Error("expected trigram index to exist")
+	}
+}
+
+type FKOrder struct {
+	ID      string
+	UserID  string `whisker:"fk=fk_users"`
+	Version int
+}
+
+func TestCollection_ForeignKey(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[User](store, "fk_users")
+	orders := documents.Collection[FKOrder](store, "fk_orders")
+
+	if err := users.Insert(ctx, &User{ID: "u1", Name: "Alice"}); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	if err := orders.Insert(ctx, &FKOrder{ID: "o1", UserID: "u1"}); err != nil {
+		t.Fatalf("insert order: %v", err)
+	}
+	if err := orders.Insert(ctx, &FKOrder{ID: "o2"}); err != nil {
+		t.Fatalf("insert order without reference: %v", err)
+	}
+
+	err := orders.Insert(ctx, &FKOrder{ID: "o3", UserID: "missing"})
+	if !errors.Is(err, whisker.ErrForeignKey) {
+		t.Fatalf("insert dangling reference: got %v, want ErrForeignKey", err)
+	}
+
+	err = users.Delete(ctx, "u1")
+	if !errors.Is(err, whisker.ErrForeignKey) {
+		t.Fatalf("delete referenced user: got %v, want ErrForeignKey", err)
+	}
+}
+
+type Note struct {
+	ID       string
+	TenantID string `json:"tenantId"`
+	Body     string
+	Version  int
+}
+
+func TestCollection_RowLevelSecurity(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	notes := documents.Collection[Note](store, "notes", documents.WithRLS(documents.TenantPolicy("tenantId")))
+	if _, err := notes.Count(ctx); err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+
+	// Superusers bypass RLS, so run the tenant sessions as a plain role that
+	// owns the table; FORCE ROW LEVEL SECURITY applies the policy to owners.
+	_, err := store.DBExecutor().Exec(ctx, `
+		CREATE ROLE whisker_tenant_app NOLOGIN;
+		GRANT USAGE, CREATE ON SCHEMA public TO whisker_tenant_app;
+		ALTER TABLE whisker_notes OWNER TO whisker_tenant_app`)
+	if err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+
+	insertAs := func(tenant string, n *Note) error {
+		sess, err := store.Session(whisker.WithTenant(ctx, tenant))
+		if err != nil {
+			return err
+		}
+		defer sess.Close(ctx)
+		if _, err := sess.DBExecutor().Exec(ctx, "SET LOCAL ROLE whisker_tenant_app"); err != nil {
+			return err
+		}
+		if err := documents.Collection[Note](sess, "notes", documents.WithRLS(documents.TenantPolicy("tenantId"))).Insert(ctx, n); err != nil {
+			return err
+		}
+		return sess.Commit(ctx)
+	}
+	countAs := func(tenant string) int64 {
+		t.Helper()
+		sess, err := store.Session(whisker.WithTenant(ctx, tenant))
+		if err != nil {
+			t.Fatalf("session: %v", err)
+		}
+		defer sess.Close(ctx)
+		if _, err := sess.DBExecutor().Exec(ctx, "SET LOCAL ROLE whisker_tenant_app"); err != nil {
+			t.Fatalf("set role: %v", err)
+		}
+		n, err := documents.Collection[Note](sess, "notes", documents.WithRLS(documents.TenantPolicy("tenantId"))).Count(ctx)
+		if err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		return n
+	}
+
+	if err := insertAs("acme", &Note{ID: "n1", TenantID: "acme", Body: "a"}); err != nil {
+		t.Fatalf("insert acme: %v", err)
+	}
+	if err := insertAs("globex", &Note{ID: "n2", TenantID: "globex", Body: "g"}); err != nil {
+		t.Fatalf("insert globex: %v", err)
+	}
+	if err := insertAs("acme", &Note{ID: "n3", TenantID: "globex", Body: "x"}); err == nil {
+		t.Error("expected cross-tenant insert to be rejected")
+	}
+
+	if got := countAs("acme"); got != 1 {
+		t.Errorf("acme count: got %d, want 1", got)
+	}
+	if got := countAs("globex"); got != 1 {
+		t.Errorf("globex count: got %d, want 1", got)
+	}
+}
+
+func TestCollection_Tenancy(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	acme := whisker.WithTenant(ctx, "acme")
+	globex := whisker.WithTenant(ctx, "globex")
+	users := documents.Collection[User](store, "tenant_users", documents.WithTenancy())
+
+	if err := users.Insert(ctx, &User{ID: "u0"}); !errors.Is(err, whisker.ErrNoTenant) {
+		t.Fatalf("insert without tenant: got %v, want ErrNoTenant", err)
+	}
+	if err := users.InsertMany(acme, []*User{{ID: "u1", Name: "Alice"}, {ID: "u2", Name: "Bob"}}); err != nil {
+		t.Fatalf("insert acme: %v", err)
+	}
+	if err := users.Insert(globex, &User{ID: "u3", Name: "Carol"}); err != nil {
+		t.Fatalf("insert globex: %v", err)
+	}
+
+	if _, err := users.Load(globex, "u1"); !errors.Is(err, whisker.ErrNotFound) {
+		t.Errorf("load other tenant: got %v, want ErrNotFound", err)
+	}
+	if err := users.Upsert(globex, &User{ID: "u1", Name: "Mallory"}); !errors.Is(err, whisker.ErrDuplicateID) {
+		t.Errorf("upsert other tenant: got %v, want ErrDuplicateID", err)
+	}
+	if err := users.Delete(globex, "u1"); !errors.Is(err, whisker.ErrNotFound) {
+		t.Errorf("delete other tenant: got %v, want ErrNotFound", err)
+	}
+	if err := users.Attach(globex, "u1", "avatar.png", strings.NewReader("x")); !errors.Is(err, whisker.ErrNotFound) {
+		t.Errorf("attach other tenant: got %v, want ErrNotFound", err)
+	}
+
+	u, err := users.Load(acme, "u1")
+	if err != nil || u.Name != "Alice" {
+		t.Fatalf("load acme: got %+v, %v", u, err)
+	}
+	u.Name = "Alicia"
+	if err := users.Update(acme, u); err != nil {
+		t.Fatalf("update acme: %v", err)
+	}
+
+	if n, err := users.Count(acme); err != nil || n != 2 {
+		t.Errorf("acme count: got %d, %v, want 2", n, err)
+	}
+	if n, err := users.Count(globex); err != nil || n != 1 {
+		t.Errorf("globex count: got %d, %v, want 1", n, err)
+	}
+	found, err := users.Where("name", "=", "Carol").Execute(acme)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(found) != 0 {
+		t.Errorf("acme query found %d of globex's documents", len(found))
+	}
+	if _, err := users.Query().Execute(ctx); !errors.Is(err, whisker.ErrNoTenant) {
+		t.Errorf("query without tenant: got %v, want ErrNoTenant", err)
+	}
+}
+
+func TestCollection_History(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[User](store, "audited_users", documents.WithHistory())
+
+	u := &User{ID: "u1", Name: "Alice"}
+	if err := users.Insert(ctx, u); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	sess, err := store.Session(whisker.WithActor(ctx, "admin@example.com"))
+	if err != nil {
+		t.Fatalf("session: %v", err)
+	}
+	u.Name = "Alicia"
+	if err := documents.Collection[User](sess, "audited_users", documents.WithHistory()).Update(ctx, u); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := sess.Commit(ctx); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	// a single statement records its actor too
+	u.Name = "Ali"
+	if err := users.Update(whisker.WithActor(ctx, "support@example.com"), u); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := users.Delete(ctx, "u1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	revs, err := users.History(ctx, "u1")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(revs) != 4 {
+		t.Fatalf("got %d revisions, want 4", len(revs))
+	}
+	if revs[0].Operation != documents.HistoryInsert || revs[0].Previous != nil {
+		t.Errorf("insert: got %+v", revs[0])
+	}
+	if revs[1].Operation != documents.HistoryUpdate || revs[1].Actor != "admin@example.com" ||
+		revs[1].Previous.Name != "Alice" || revs[1].Previous.Version != 1 {
+		t.Errorf("update: got %+v", revs[1])
+	}
+	if revs[2].Operation != documents.HistoryUpdate || revs[2].Actor != "support@example.com" || revs[2].Previous.Name != "Alicia" {
+		t.Errorf("update outside a session: got %+v", revs[2])
+	}
+	if revs[3].Operation != documents.HistoryDelete || revs[3].Actor != "" || revs[3].Previous.Name != "Ali" {
+		t.Errorf("delete: got %+v", revs[3])
+	}
+
+	if _, err := documents.Collection[User](store, "audited_users").History(ctx, "u1"); err == nil {
+		t.Error("expected an error without WithHistory")
+	}
+}
+
+func TestCollection_Attachments(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[User](store, "users")
+
+	if err := users.Insert(ctx, &User{ID: "u1", Name: "Alice"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	payload := bytes.Repeat([]byte("whisker!"), 100_000) // spans several chunks
+	if err := users.Attach(ctx, "u1", "avatar.png", bytes.NewReader(payload)); err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+
+	r, err := users.OpenAttachment(ctx, "u1", "avatar.png")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("content mismatch: got %d bytes, want %d", len(got), len(payload))
+	}
+
+	if err := users.Attach(ctx, "u1", "avatar.png", strings.NewReader("small")); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	infos, err := users.Attachments(ctx, "u1")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(infos) != 1 || infos[0].Name != "avatar.png" || infos[0].Size != 5 {
+		t.Errorf("attachments: got %+v", infos)
+	}
+
+	err = users.Attach(ctx, "missing", "a.txt", strings.NewReader("x"))
+	if !errors.Is(err, whisker.ErrNotFound) {
+		t.Errorf("attach to missing document: got %v, want ErrNotFound", err)
+	}
+	var docErr *whisker.DocumentError
+	if !errors.As(err, &docErr) || docErr.ID != "missing" || docErr.Op != "attach" {
+		t.Errorf("attach to missing document: got %#v, want a DocumentError", err)
+	}
+
+	if err := users.Delete(ctx, "u1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	_, err = users.OpenAttachment(ctx, "u1", "avatar.png")
+	if !errors.Is(err, whisker.ErrNotFound) {
+		t.Errorf("open after delete: got %v, want ErrNotFound", err)
+	}
+}
+
+type PersonV1 struct {
+	ID      string
+	First   string `json:"first"`
+	Last    string `json:"last"`
+	Version int
+}
+
+type PersonV2 struct {
+	ID       string
+	FullName string `json:"fullName"`
+	Version  int
+}
+
+func TestCollection_Migrations(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	v1 := documents.Collection[PersonV1](store, "people")
+	for _, p := range []*PersonV1{
+		{ID: "p1", First: "Ada", Last: "Lovelace"},
+		{ID: "p2", First: "Grace", Last: "Hopper"},
+	} {
+		if err := v1.Insert(ctx, p); err != nil {
+			t.Fatalf("insert %s: %v", p.ID, err)
+		}
+	}
+
+	err := documents.Migrate[PersonV2](1, 2, func(old json.RawMessage) (json.RawMessage, error) {
+		var p PersonV1
+		if err := json.Unmarshal(old, &p); err != nil {
+			return nil, err
+		}
+		return json.Marshal(map[string]string{"fullName": p.First + " " + p.Last})
+	})
+	if err != nil {
+		t.Fatalf("register migration: %v", err)
+	}
+	people := documents.Collection[PersonV2](store, "people")
+
+	got, err := people.Load(ctx, "p1")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.FullName != "Ada Lovelace" {
+		t.Errorf("load: got %q, want Ada Lovelace", got.FullName)
+	}
+
+	// the first use backfilled the stored documents, so loads stop migrating
+	var stale int
+	if err := store.DBExecutor().QueryRow(ctx,
+		`SELECT count(*) FROM whisker_people WHERE schema_version < 2 OR NOT data ? 'fullName'`).Scan(&stale); err != nil {
+		t.Fatalf("count stale: %v", err)
+	}
+	if stale != 0 {
+		t.Errorf("documents left at version 1 after first use: %d", stale)
+	}
+	if n, _ := people.MigrateAll(ctx); n != 0 {
+		t.Errorf("migrate all after backfill: got %d, want 0", n)
+	}
+
+	// a document written at version 1 later, e.g. by the previous release,
+	// is migrated on load and by MigrateAll
+	if err := v1.Insert(ctx, &PersonV1{ID: "p3", First: "Alan", Last: "Turing"}); err != nil {
+		t.Fatalf("insert p3: %v", err)
+	}
+	if got, err := people.Load(ctx, "p3"); err != nil || got.FullName != "Alan Turing" {
+		t.Errorf("lazy load: got %+v, %v", got, err)
+	}
+	n, err := people.MigrateAll(ctx)
+	if err != nil {
+		t.Fatalf("migrate all: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("migrated: got %d, want 1", n)
+	}
+
+	results, err := people.Where("fullName", "=", "Grace Hopper").Execute(ctx)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(results) != 1 || results[0].Version != 2 {
+		t.Errorf("query after migrate all: got %+v", results)
+	}
+}
+
+func TestQuery_SelectExecuteInto(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[User](store, "partial_users")
+
+	for _, u := range []*User{
+		{ID: "u1", Name: "Alice", Email: "alice@example.com"},
+		{ID: "u2", Name: "Bob", Email: "bob@example.com"},
+	} {
+		if err := users.Insert(ctx, u); err != nil {
+			t.Fatalf("insert %s: %v", u.ID, err)
+		}
+	}
+
+	var partials []struct {
+		ID      string
+		Name    string
+		Email   string
+		Version int
+	}
+	err := users.Query().Select("name").OrderBy("name", documents.Asc).ExecuteInto(ctx, &partials)
+	if err != nil {
+		t.Fatalf("execute into: %v", err)
+	}
+	if len(partials) != 2 || partials[0].ID != "u1" || partials[0].Name != "Alice" || partials[0].Version != 1 {
+		t.Fatalf("got %+v", partials)
+	}
+	if partials[0].Email != "" {
+		t.Errorf("unselected field fetched: %+v", partials[0])
+	}
+
+	var maps []map[string]any
+	if err := users.Where("name", "=", "Bob").Select("email").ExecuteInto(ctx, &maps); err != nil {
+		t.Fatalf("execute into maps: %v", err)
+	}
+	if len(maps) != 1 || maps[0]["email"] != "bob@example.com" || len(maps[0]) != 1 {
+		t.Errorf("got %+v", maps)
+	}
+
+	if _, err := users.Query().Select("name").Execute(ctx); err == nil {
+		t.Error("Execute should refuse a query with Select")
+	}
+}
+
+type Sale struct {
+	ID      string
+	Status  string
+	Total   float64
+	Items   int
+	Version int
+}
+
+func TestQuery_Aggregate(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	sales := documents.Collection[Sale](store, "agg_sales")
+
+	for _, s := range []*Sale{
+		{ID: "s1", Status: "paid", Total: 10, Items: 2},
+		{ID: "s2", Status: "paid", Total: 30, Items: 3},
+		{ID: "s3", Status: "open", Total: 5, Items: 1},
+		{ID: "s4", Total: 1},
+	} {
+		if err := sales.Insert(ctx, s); err != nil {
+			t.Fatalf("insert %s: %v", s.ID, err)
+		}
+	}
+
+	rows, err := sales.GroupBy("status").Aggregate(ctx, documents.Sum("total"), documents.Max("total").As("top"),
+		documents.Sum("items"), documents.Count())
+	if err != nil {
+		t.Fatalf("aggregate: %v", err)
+	}
+	got := make(map[string]map[string]any)
+	for _, r := range rows {
+		got[r.Group["status"]] = r.Values
+	}
+	if v := got["paid"]; v["sum_total"] != 40.0 || v["top"] != 30.0 || v["sum_items"] != int64(5) || v["count"] != int64(2) {
+		t.Errorf("paid: got %v", v)
+	}
+	if v := got["open"]; v["sum_total"] != 5.0 || v["count"] != int64(1) {
+		t.Errorf("open: got %v", v)
+	}
+	if len(rows) != 3 {
+		t.Errorf("got %d groups, want 3 (paid, open, empty status)", len(rows))
+	}
+
+	rows, err = sales.Where("status", "=", "paid").Aggregate(ctx, documents.Avg("total"))
+	if err != nil {
+		t.Fatalf("ungrouped aggregate: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Values["avg_total"] != 20.0 {
+		t.Errorf("ungrouped: got %+v", rows)
+	}
+
+	rows, err = sales.Where("status", "=", "refunded").Aggregate(ctx, documents.Sum("total"), documents.Count())
+	if err != nil {
+		t.Fatalf("empty aggregate: %v", err)
+	}
+	if _, ok := rows[0].Values["sum_total"]; ok || rows[0].Values["count"] != int64(0) {
+		t.Errorf("empty: got %+v", rows)
+	}
+}
+
+func TestCollection_ExportImportRoundTrip(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	src := documents.Collection[User](store, "export_users")
+	if err := src.InsertMany(ctx, []*User{
+		{ID: "u1", Name: "Alice", Email: "alice@test.com"},
+		{ID: "u2", Name: "Bob, Jr.", Email: "bob@test.com"},
+	}); err != nil {
+		t.Fatalf("insert many: %v", err)
+	}
+
+	for _, format := range []documents.Format{documents.JSONL, documents.CSV} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			n, err := documents.Export(ctx, src, &buf, format)
+			if err != nil || n != 2 {
+				t.Fatalf("export: n=%d err=%v", n, err)
+			}
+
+			dst := documents.Collection[User](store, "import_users_"+string(format))
+			var progress []documents.ImportResult
+			res, err := documents.Import(ctx, dst, &buf, format,
+				documents.WithImportBatchSize(1),
+				documents.WithImportProgress(func(r documents.ImportResult) { progress = append(progress, r) }))
+			if err != nil {
+				t.Fatalf("import: %v", err)
+			}
+			if res != (documents.ImportResult{Read: 2, Written: 2}) || len(progress) != 2 {
+				t.Errorf("got result %+v, progress %+v", res, progress)
+			}
+			got, err := dst.Load(ctx, "u2")
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if got.Name != "Bob, Jr." || got.Email != "bob@test.com" || got.Version != 1 {
+				t.Errorf("got %+v", got)
+			}
+		})
+	}
+}
+
+func TestCollection_ImportModes(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	fix := "id,name\nu1,Alicia\nu3,Carol\n"
+
+	tests := []struct {
+		mode      documents.ImportMode
+		written   int
+		wantName  string
+		wantEmail string
+	}{
+		{documents.ImportUpsert, 2, "Alicia", "alice@test.com"},
+		{documents.ImportReplace, 2, "Alicia", ""},
+		{documents.ImportSkip, 1, "Alice", "alice@test.com"},
+	}
+	for i, tt := range tests {
+		users := documents.Collection[User](store, fmt.Sprintf("import_modes_%d", i))
+		if err := users.Insert(ctx, &User{ID: "u1", Name: "Alice", Email: "alice@test.com"}); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+		res, err := documents.Import(ctx, users, strings.NewReader(fix), documents.CSV, documents.WithImportMode(tt.mode))
+		if err != nil {
+			t.Fatalf("mode %d: import: %v", tt.mode, err)
+		}
+		if res.Read != 2 || res.Written != tt.written {
+			t.Errorf("mode %d: got %+v, want %d written", tt.mode, res, tt.written)
+		}
+		got, err := users.Load(ctx, "u1")
+		if err != nil {
+			t.Fatalf("mode %d: load: %v", tt.mode, err)
+		}
+		if got.Name != tt.wantName || got.Email != tt.wantEmail {
+			t.Errorf("mode %d: got %+v", tt.mode, got)
+		}
+		if _, err := users.Load(ctx, "u3"); err != nil {
+			t.Errorf("mode %d: load u3: %v", tt.mode, err)
+		}
+	}
+
+	users := documents.Collection[User](store, "import_modes_bad")
+	_, err := documents.Import(ctx, users, strings.NewReader(`{"id":"u1","nmae":"x"}`), documents.JSONL)
+	if err == nil || !strings.Contains(err.Error(), "record 1") {
+		t.Errorf("got %v, want unknown field error for record 1", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	a := documents.Collection[User](store, "diff_users_a")
+	b := documents.Collection[User](store, "diff_users_b")
+	if err := a.InsertMany(ctx, []*User{
+		{ID: "u1", Name: "Alice", Email: "alice@test.com"},
+		{ID: "u2", Name: "Bob", Email: "bob@test.com"},
+		{ID: "u3", Name: "Carol", Email: "carol@test.com"},
+	}); err != nil {
+		t.Fatalf("insert a: %v", err)
+	}
+	if err := b.InsertMany(ctx, []*User{
+		{ID: "u2", Name: "Bob", Email: "bob@test.com"},
+		{ID: "u3", Name: "Carol", Email: "carol@example.com"},
+		{ID: "u4", Name: "Dave", Email: "dave@test.com"},
+	}); err != nil {
+		t.Fatalf("insert b: %v", err)
+	}
+
+	d, err := documents.Diff(ctx, a, b, documents.WithDiffPageSize(2))
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if len(d.Removed) != 1 || d.Removed[0] != "u1" || len(d.Added) != 1 || d.Added[0] != "u4" {
+		t.Errorf("got removed %v, added %v", d.Removed, d.Added)
+	}
+	if len(d.Changed) != 1 || d.Changed[0].ID != "u3" || len(d.Changed[0].Changes) != 1 ||
+		d.Changed[0].Changes[0].Path != "email" || string(d.Changed[0].Changes[0].New) != `"carol@example.com"` {
+		t.Errorf("got changed %+v", d.Changed)
+	}
+
+	d, err = documents.Diff(ctx, b, b)
+	if err != nil || !d.Equal() {
+		t.Errorf("diff with itself: %+v, %v", d, err)
+	}
+}
+
+func TestCollection_Hooks(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	var events []string
+	errNoEmail := errors.New("email is required")
+	hooks := documents.NewHooks[User]().
+		BeforeInsert(func(_ context.Context, u *User) error {
+			if u.Email == "" {
+				return errNoEmail
+			}
+			return nil
+		}).
+		BeforeUpdate(func(_ context.Context, u *User) error {
+			u.Name = strings.TrimSpace(u.Name)
+			return nil
+		}).
+		AfterInsert(func(_ context.Context, u *User) error {
+			events = append(events, fmt.Sprintf("inserted %s v%d", u.ID, u.Version))
+			return nil
+		}).
+		AfterUpdate(func(_ context.Context, u *User) error {
+			events = append(events, fmt.Sprintf("updated %s v%d", u.ID, u.Version))
+			return nil
+		}).
+		AfterDelete(func(_ context.Context, id string) error {
+			events = append(events, "deleted "+id)
+			return nil
+		})
+	users := documents.Collection[User](store, "hook_users", documents.WithHooks(hooks))
+
+	if err := users.Insert(ctx, &User{ID: "u0", Name: "Nobody"}); !errors.Is(err, errNoEmail) {
+		t.Fatalf("insert without email: got %v, want %v", err, errNoEmail)
+	}
+	if _, err := users.Load(ctx, "u0"); !errors.Is(err, whisker.ErrNotFound) {
+		t.Errorf("rejected document was stored: %v", err)
+	}
+
+	err := users.InsertMany(ctx, []*User{
+		{ID: "u1", Name: "Alice", Email: "alice@test.com"},
+		{ID: "u2", Name: "Bob"},
+	})
+	if !errors.Is(err, errNoEmail) {
+		t.Fatalf("insert many: got %v, want %v", err, errNoEmail)
+	}
+	if n, err := users.Count(ctx); err != nil || n != 0 {
+		t.Errorf("count after rejected batch = %d, %v; want 0", n, err)
+	}
+
+	alice := &User{ID: "u1", Name: "Alice", Email: "alice@test.com"}
+	bob := &User{ID: "u2", Name: "Bob", Email: "bob@test.com"}
+	if err := users.InsertMany(ctx, []*User{alice, bob}); err != nil {
+		t.Fatalf("insert many: %v", err)
+	}
+
+	alice.Name = "  Alice Smith  "
+	if err := users.Update(ctx, alice); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	loaded, err := users.Load(ctx, "u1")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if loaded.Name != "Alice Smith" {
+		t.Errorf("name = %q, want before hook's trimmed value", loaded.Name)
+	}
+
+	err = users.DeleteMany(ctx, []string{"u2", "u9"})
+	var batchErr *documents.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("delete many: got %v, want BatchError", err)
+	}
+
+	want := []string{"inserted u1 v1", "inserted u2 v1", "updated u1 v2", "deleted u2"}
+	if strings.Join(events, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got events %v, want %v", events, want)
+	}
+}
+
+func TestQuery_BulkDeleteAndUpdateSet(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[User](store, "bulk_users")
+	if err := users.InsertMany(ctx, []*User{
+		{ID: "u1", Name: "Alice", Email: "alice@old.com"},
+		{ID: "u2", Name: "Bob", Email: "bob@old.com"},
+		{ID: "u3", Name: "Carol", Email: "carol@new.com"},
+	}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	n, err := users.Where("email", "LIKE", "%@old.com").UpdateSet(ctx, map[string]any{"name": "Archived"})
+	if err != nil {
+		t.Fatalf("update set: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("updated %d, want 2", n)
+	}
+	bob, err := users.Load(ctx, "u2")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if bob.Name != "Archived" || bob.Email != "bob@old.com" || bob.Version != 2 {
+		t.Errorf("got %+v, want name Archived, email kept, version 2", bob)
+	}
+
+	n, err = users.Where("name", "=", "Archived").Delete(ctx)
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("deleted %d, want 2", n)
+	}
+	if count, err := users.Count(ctx); err != nil || count != 1 {
+		t.Errorf("count = %d, %v; want 1", count, err)
+	}
+
+	if _, err := users.Query().Delete(ctx); err == nil {
+		t.Error("expected error deleting without a condition")
+	}
+}
+
+type Reading struct {
+	ID      string
+	Sensor  string
+	Value   float64   `whisker:"index"`
+	TakenAt time.Time `whisker:"index"`
+	Version int
+}
+
+// legacyReading indexes value as text, as versions before typed indexes did.
+type legacyReading struct {
+	ID      string
+	Value   string `json:"value" whisker:"index"`
+	Version int
+}
+
+func TestCollection_TypedIndexDropsTextIndex(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	indexes := func() []string {
+		t.Helper()
+		rows, err := store.DBExecutor().Query(ctx,
+			"SELECT indexname FROM pg_indexes WHERE tablename = 'whisker_upgraded_readings' AND indexname LIKE 'idx_whisker_upgraded_readings_value%' ORDER BY indexname")
+		if err != nil {
+			t.Fatalf("query pg_indexes: %v", err)
+		}
+		names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return names
+	}
+
+	if _, err := documents.Collection[legacyReading](store, "upgraded_readings").Count(ctx); err != nil {
+		t.Fatalf("legacy count: %v", err)
+	}
+	if got := indexes(); !slices.Equal(got, []string{"idx_whisker_upgraded_readings_value"}) {
+		t.Fatalf("before: got %v", got)
+	}
+	if _, err := documents.Collection[Reading](store, "upgraded_readings").Count(ctx); err != nil {
+		t.Fatalf("typed count: %v", err)
+	}
+	if got := indexes(); !slices.Equal(got, []string{"idx_whisker_upgraded_readings_value_numeric"}) {
+		t.Errorf("after: got %v, want only the typed index", got)
+	}
+}
+
+func TestQuery_TypedFieldsCompareByValue(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	readings := documents.Collection[Reading](store, "typed_readings")
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, v := range []float64{9, 10, 100, 2.5} {
+		r := &Reading{ID: fmt.Sprintf("r%d", i), Sensor: "s1", Value: v, TakenAt: base.Add(time.Duration(i) * 90 * time.Minute).In(time.FixedZone("CET", 3600))}
+		if err := readings.Insert(ctx, r); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	big, err := readings.Where("value", ">", 9).OrderBy("value", documents.Asc).Execute(ctx)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(big) != 2 || big[0].Value != 10 || big[1].Value != 100 {
+		t.Errorf("value > 9: got %+v, want 10 then 100", big)
+	}
+
+	late, err := readings.Where("takenAt", ">=", base.Add(3*time.Hour)).Execute(ctx)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(late) != 2 {
+		t.Errorf("takenAt >= base+3h: got %d readings, want 2", len(late))
+	}
+
+	var defs []string
+	rows, err := store.DBExecutor().Query(ctx,
+		"SELECT indexdef FROM pg_indexes WHERE tablename = 'whisker_typed_readings' AND indexname IN ('idx_whisker_typed_readings_value_numeric', 'idx_whisker_typed_readings_takenAt_timestamptz') ORDER BY indexname")
+	if err != nil {
+		t.Fatalf("query pg_indexes: %v", err)
+	}
+	for rows.Next() {
+		var def string
+		if err := rows.Scan(&def); err != nil {
+			t.Fatal(err)
+		}
+		defs = append(defs, def)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(defs) != 2 || !strings.Contains(defs[0], "whisker_timestamptz") || !strings.Contains(defs[1], "::numeric") {
+		t.Errorf("typed indexes: got %v", defs)
+	}
+}
+
+type PromotedReading struct {
+	ID      string
+	TakenAt time.Time `whisker:"column"`
+	Level   *int      `whisker:"column"`
+	Version int
+}
+
+func TestQuery_PromotedTypedFieldsCompareByValue(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	readings := documents.Collection[PromotedReading](store, "promoted_readings")
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, level := range []int{10, 2, 9} {
+		r := &PromotedReading{ID: fmt.Sprintf("r%d", i), TakenAt: base.Add(time.Duration(i) * time.Hour), Level: &level}
+		if err := readings.Insert(ctx, r); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	late, err := readings.Where("takenAt", ">", base).Where("level", "<", 10).Execute(ctx)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(late) != 2 {
+		t.Errorf("takenAt > base and level < 10: got %+v, want r1 and r2", late)
+	}
+
+	var got []int
+	cursor := ""
+	for range 5 {
+		page, err := readings.Query().OrderBy("level", documents.Asc).Limit(1).AfterCursor(cursor).ExecutePage(ctx)
+		if err != nil {
+			t.Fatalf("page after %q: %v", cursor, err)
+		}
+		for _, r := range page.Docs {
+			got = append(got, *r.Level)
+		}
+		if cursor = page.NextCursor(); cursor == "" {
+			break
+		}
+	}
+	if !slices.Equal(got, []int{2, 9, 10}) {
+		t.Errorf("paged levels %v, want [2 9 10]", got)
+	}
+}
+
+type settableClock struct{ now time.Time }
+
+func (c *settableClock) Now() time.Time { return c.now }
+
+func TestQuery_ModifiedSince(t *testing.T) {
+	ctx := context.Background()
+	clock := &settableClock{now: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	store, err := whisker.New(ctx, testutil.SetupPostgres(t), whisker.WithClock(clock))
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	t.Cleanup(store.Close)
+	users := documents.Collection[User](store, "modified_users")
+
+	for _, id := range []string{"u1", "u2"} {
+		if err := users.Insert(ctx, &User{ID: id, Name: id}); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	synced := clock.now
+	clock.now = clock.now.Add(time.Hour)
+	u2, err := users.Load(ctx, "u2")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	u2.Name = "renamed"
+	if err := users.Update(ctx, u2); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	changed, err := users.Query().ModifiedSince(synced).Execute(ctx)
+	if err != nil {
+		t.Fatalf("modified since: %v", err)
+	}
+	if len(changed) != 1 || changed[0].ID != "u2" {
+		t.Errorf("modified since: got %+v, want u2", changed)
+	}
+
+	n, err := users.Where("created_at", "<=", synced.Format(time.RFC3339)).Where("version", ">", 1).Count(ctx)
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("created before sync and updated: got %d, want 1", n)
+	}
+}
+
+type Patient struct {
+	ID      string
+	Name    string
+	SSN     string `json:"ssn" whisker:"encrypt"`
+	Version int
+}
+
+func TestCollection_Encryption(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	keys := documents.StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{7}, 32)}}
+	patients := documents.Collection[Patient](store, "patients", documents.WithEncryption(keys))
+
+	if err := patients.Insert(ctx, &Patient{ID: "p1", Name: "Ann", SSN: "123-45-6789"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	var stored string
+	if err := store.DBExecutor().QueryRow(ctx, `SELECT data->>'ssn' FROM whisker_patients WHERE id = 'p1'`).Scan(&stored); err != nil {
+		t.Fatalf("read raw: %v", err)
+	}
+	if !strings.HasPrefix(stored, "whisker:enc:k1:") || strings.Contains(stored, "6789") {
+		t.Errorf("stored ssn: %q", stored)
+	}
+
+	found, err := patients.Where("name", "=", "Ann").Execute(ctx)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(found) != 1 || found[0].SSN != "123-45-6789" {
+		t.Errorf("query: got %+v", found)
+	}
+
+	if _, err := patients.Where("id", "=", "p1").UpdateSet(ctx, map[string]any{"ssn": "987-65-4321"}); err == nil {
+		t.Error("expected error setting an encrypted field with UpdateSet")
+	}
+	if _, err := patients.Increment(ctx, "p1", "ssn", 1); err == nil {
+		t.Error("expected error incrementing an encrypted field")
+	}
+	p, err := patients.Load(ctx, "p1")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	p.SSN = "987-65-4321"
+	if err := patients.Update(ctx, p); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if p, err = patients.Load(ctx, "p1"); err != nil || p.SSN != "987-65-4321" {
+		t.Errorf("load: got %+v, %v", p, err)
+	}
+
+	// ciphertext copied to another document does not decrypt there
+	if err := patients.Insert(ctx, &Patient{ID: "p2", Name: "Bo", SSN: "000"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, err := store.DBExecutor().Exec(ctx, `UPDATE whisker_patients SET data = (SELECT data FROM whisker_patients WHERE id = 'p1') WHERE id = 'p2'`); err != nil {
+		t.Fatalf("copy raw: %v", err)
+	}
+	if _, err := patients.Load(ctx, "p2"); err == nil {
+		t.Error("expected error loading ciphertext copied from another document")
+	}
+
+	if _, err := patients.Where("ssn", "=", "987-65-4321").Execute(ctx); err == nil {
+		t.Error("expected error querying an encrypted field")
+	}
+	unkeyed := documents.Collection[Patient](store, "patients")
+	if _, err := unkeyed.Load(ctx, "p1"); err == nil || !strings.Contains(err.Error(), "WithEncryption") {
+		t.Errorf("without WithEncryption: got %v", err)
+	}
+}
+
+type NestedPatient struct {
+	ID      string
+	Contact struct {
+		Phone string `whisker:"encrypt"`
+	}
+	Version int
+}
+
+func TestCollection_EncryptionOfNestedFieldsFails(t *testing.T) {
+	store := setupStore(t)
+	keys := documents.StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{7}, 32)}}
+	nested := documents.Collection[NestedPatient](store, "nested_patients", documents.WithEncryption(keys))
+	err := nested.Insert(context.Background(), &NestedPatient{ID: "n1"})
+	if err == nil || !strings.Contains(err.Error(), "Contact.Phone") {
+		t.Errorf("got %v, want an error naming Contact.Phone", err)
+	}
+}
+
+func TestCollection_EncryptedImport(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	keys := documents.StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{7}, 32)}}
+	patients := documents.Collection[Patient](store, "imported_patients", documents.WithEncryption(keys))
+
+	in := `{"id":"p1","name":"Ann","ssn":"123-45-6789"}` + "\n"
+	if _, err := documents.Import(ctx, patients, strings.NewReader(in), documents.JSONL); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	var raw string
+	if err := store.DBExecutor().QueryRow(ctx, `SELECT data::text FROM whisker_imported_patients WHERE id = 'p1'`).Scan(&raw); err != nil {
+		t.Fatalf("read raw: %v", err)
+	}
+	if strings.Contains(raw, "6789") || !strings.Contains(raw, "whisker:enc:k1:") {
+		t.Errorf("imported data: %s", raw)
+	}
+
+	// an export keeps the ciphertext, and imports back as it is
+	var buf bytes.Buffer
+	if _, err := documents.Export(ctx, patients, &buf, documents.JSONL); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if strings.Contains(buf.String(), "6789") {
+		t.Errorf("export holds plaintext: %s", buf.String())
+	}
+	if _, err := patients.Where("id", "=", "p1").Delete(ctx); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := documents.Import(ctx, patients, &buf, documents.JSONL); err != nil {
+		t.Fatalf("re-import: %v", err)
+	}
+	p, err := patients.Load(ctx, "p1")
+	if err != nil || p.SSN != "123-45-6789" {
+		t.Errorf("load: got %+v, %v", p, err)
+	}
+}
+
+func TestDiff_Encrypted(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	keys := documents.StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{7}, 32)}}
+	a := documents.Collection[Patient](store, "diff_patients_a", documents.WithEncryption(keys))
+	b := documents.Collection[Patient](store, "diff_patients_b", documents.WithEncryption(keys))
+	for _, col := range []*documents.CollectionOf[Patient]{a, b} {
+		if err := col.InsertMany(ctx, []*Patient{
+			{ID: "p1", Name: "Ann", SSN: "123-45-6789"},
+			{ID: "p2", Name: "Bo", SSN: "987-65-4321"},
+		}); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	d, err := documents.Diff(ctx, a, b)
+	if err != nil || !d.Equal() {
+		t.Errorf("identical encrypted collections: %+v, %v", d, err)
+	}
+}
@@ -0,0 +1,160 @@
+package documents
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker"
+)
+
+func TestUpsertSQL(t *testing.T) {
+	frozen := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name  string
+		clock whisker.Clock
+		want  string
+	}{
+		{
+			name: "database time",
+			want: "INSERT INTO whisker_users AS t (id,data) VALUES ($1,$2),($3,$4) " +
+				"ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data, version = t.version + 1, updated_at = EXCLUDED.updated_at " +
+				"RETURNING t.id, t.version",
+		},
+		{
+			name:  "clock",
+			clock: whisker.FixedClock(frozen),
+			want: "INSERT INTO whisker_users AS t (id,data,created_at,updated_at) VALUES ($1,$2,$3,$4),($5,$6,$7,$8) " +
+				"ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data, version = t.version + 1, updated_at = EXCLUDED.updated_at " +
+				"RETURNING t.id, t.version",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &CollectionOf[testDoc]{table: "whisker_users", clock: tc.clock}
+			sql, _, err := c.upsertBuilder("").
+				Values(c.upsertValues("u1", []byte("{}"), "")...).
+				Values(c.upsertValues("u2", []byte("{}"), "")...).
+				Suffix(c.upsertSuffix("")).
+				ToSql()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != tc.want {
+				t.Errorf("got:  %s\nwant: %s", sql, tc.want)
+			}
+		})
+	}
+}
+
+func TestIncrementSQL(t *testing.T) {
+	c := &CollectionOf[testDoc]{table: "whisker_counters"}
+	sql, args, err := c.incrementSQL("c1", "hits", 2.5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "UPDATE whisker_counters SET data = jsonb_set(data, $1::text[], to_jsonb(COALESCE((data->>$2)::numeric, 0) + $3)), " +
+		"version = version + 1, updated_at = now() WHERE id = $4 RETURNING (data->>$5)::float8"
+	if sql != want {
+		t.Errorf("got:  %s\nwant: %s", sql, want)
+	}
+	if len(args) != 5 || args[2] != 2.5 || args[3] != "c1" || args[4] != "hits" {
+		t.Errorf("args = %v", args)
+	}
+
+	if _, _, err := c.incrementSQL("c1", "hits'; DROP TABLE x; --", 1, ""); err == nil {
+		t.Error("expected error for invalid field")
+	}
+}
+
+func TestUpdateManySQL(t *testing.T) {
+	c := &CollectionOf[testDoc]{table: "whisker_users"}
+	sql, args := c.updateManySQL([]docInfo{
+		{id: "u1", data: []byte("{}"), oldVersion: 1, newVersion: 2},
+		{id: "u2", data: []byte("{}"), oldVersion: 4, newVersion: 5},
+	}, "")
+	for _, want := range []string{
+		"WITH v(id, data, new_version, old_version) AS (VALUES ($1::text, $2::jsonb, $3::int, $4::int), ($5::text, $6::jsonb, $7::int, $8::int))",
+		"FROM v LEFT JOIN whisker_users t ON t.id = v.id",
+		"UPDATE whisker_users AS t SET data = v.data, version = v.new_version",
+		"AND NOT EXISTS (SELECT 1 FROM cur WHERE cur.version IS DISTINCT FROM cur.old_version)",
+		"SELECT cur.id, cur.version, upd.id IS NOT NULL FROM cur LEFT JOIN upd ON upd.id = cur.id",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("missing %q in:\n%s", want, sql)
+		}
+	}
+	if len(args) != 9 || args[0] != "u1" || args[7] != 4 {
+		t.Errorf("args: got %v", args)
+	}
+}
+
+func TestExistsManySQL(t *testing.T) {
+	c := &CollectionOf[testDoc]{table: "whisker_users"}
+	ids := []string{"u1", "u2"}
+
+	sql, args, err := c.existsManySQL(ids, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "SELECT id FROM whisker_users WHERE id = ANY($1)"; sql != want {
+		t.Errorf("got:  %s\nwant: %s", sql, want)
+	}
+	if len(args) != 1 || len(args[0].([]string)) != 2 {
+		t.Errorf("args = %v, want the IDs as one array", args)
+	}
+
+	sql, args, err = c.existsManySQL(ids, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "SELECT id FROM whisker_users WHERE id = ANY($1) AND tenant_id = $2"; sql != want {
+		t.Errorf("got:  %s\nwant: %s", sql, want)
+	}
+	if len(args) != 2 || args[1] != "acme" {
+		t.Errorf("args = %v", args)
+	}
+}
+
+func TestUpdateFailures(t *testing.T) {
+	one, two := 1, 2
+	infos := []docInfo{{id: "u1", oldVersion: 1}, {id: "u2", oldVersion: 1}, {id: "u3", oldVersion: 1}}
+
+	tests := []struct {
+		name    string
+		stored  map[string]*int
+		updated map[string]bool
+		want    map[string]error
+	}{
+		{
+			name:    "all updated",
+			stored:  map[string]*int{"u1": &one, "u2": &one, "u3": &one},
+			updated: map[string]bool{"u1": true, "u2": true, "u3": true},
+			want:    map[string]error{},
+		},
+		{
+			name:   "conflict and missing abort the rest",
+			stored: map[string]*int{"u1": &two, "u2": nil, "u3": &one},
+			want:   map[string]error{"u1": whisker.ErrConcurrencyConflict, "u2": whisker.ErrNotFound},
+		},
+		{
+			name:    "lost race",
+			stored:  map[string]*int{"u1": &one, "u2": &one, "u3": &one},
+			updated: map[string]bool{"u1": true, "u3": true},
+			want:    map[string]error{"u2": whisker.ErrConcurrencyConflict},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := updateFailures(infos, tt.stored, tt.updated)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for id, err := range tt.want {
+				if !errors.Is(got[id], err) {
+					t.Errorf("%s: got %v, want %v", id, got[id], err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package documents
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ripkitten-co/whisker/internal/pg"
+)
+
+// InsertManyCopy is InsertMany for large imports: it streams the documents
+// to PostgreSQL with the COPY protocol instead of building one multi-row
+// INSERT, which is several times faster for thousands of documents. The
+// store's MaxBatchSize does not apply.
+//
+// It behaves like InsertMany otherwise: hooks run, Version is set to 1, and
+// an ID already in the collection fails the whole batch with a BatchError.
+// Executors without COPY support fall back to InsertMany, MaxBatchSize
+// included.
+func (c *CollectionOf[T]) InsertManyCopy(ctx context.Context, docs []*T) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	copier, ok := c.exec.(pg.Copier)
+	if !ok {
+		return c.InsertMany(ctx, docs)
+	}
+	if err := c.ensure(ctx); err != nil {
+		return err
+	}
+	if err := c.checkWrite(ctx, "insert many copy"); err != nil {
+		return err
+	}
+	tenant, err := c.tenant(ctx, "insert many copy")
+	if err != nil {
+		return err
+	}
+
+	var bufs encodeBuffers
+	defer bufs.release()
+
+	cols, ids, rows, err := c.insertRows(ctx, "insert many copy", docs, tenant, &bufs)
+	if err != nil {
+		return err
+	}
+	if _, err := copier.CopyFrom(ctx, pgx.Identifier{c.table}, cols, pgx.CopyFromRows(rows)); err != nil {
+		return c.insertManyError("insert many copy", ids, err)
+	}
+	return c.afterInsertMany(ctx, docs, ids)
+}
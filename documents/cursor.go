@@ -0,0 +1,177 @@
+package documents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ripkitten-co/whisker/internal/codecs"
+	"github.com/ripkitten-co/whisker/internal/pg"
+)
+
+// defaultFetchSize is the number of documents a Cursor fetches per round
+// trip when the query sets no FetchSize.
+const defaultFetchSize = 500
+
+// cursorSeq numbers server-side cursors, so several can be open in one
+// session's transaction.
+var cursorSeq atomic.Uint64
+
+// Cursor streams the results of a query from a server-side cursor, holding
+// at most one fetch of documents in memory. Call Next until it returns
+// false, then check Err, and always Close the cursor.
+//
+// Outside a session the cursor runs in a transaction of its own, which holds
+// a pool connection until Close. In a session it joins the session's
+// transaction. Each fetch is read in full before Next returns, so the
+// documents' handler may run other queries on the same session.
+type Cursor[T any] struct {
+	exec  pg.Executor
+	tx    pgx.Tx
+	codec codecs.Codec
+	name  string
+	fetch string
+
+	buf    []*T
+	doc    *T
+	done   bool
+	closed bool
+	err    error
+}
+
+// Cursor opens a cursor over the query's results.
+func (q *Query[T]) Cursor(ctx context.Context) (*Cursor[T], error) {
+	if err := q.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	q, err := q.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if q.fetchSize < 0 {
+		return nil, fmt.Errorf("query: cursor: fetch size must not be negative, got %d", q.fetchSize)
+	}
+	sql, args, err := q.toSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	fetchSize := q.fetchSize
+	if fetchSize == 0 {
+		fetchSize = defaultFetchSize
+	}
+	name := "whisker_cursor_" + strconv.FormatUint(cursorSeq.Add(1), 10)
+	c := &Cursor[T]{
+		exec:  q.exec,
+		codec: q.codec,
+		name:  name,
+		fetch: fmt.Sprintf("FETCH FORWARD %d FROM %s", fetchSize, name),
+	}
+
+	if t, ok := q.exec.(pg.Transactional); !ok || !t.InTransaction() {
+		b, ok := q.exec.(beginner)
+		if !ok {
+			return nil, errors.New("query: cursor: executor cannot begin a transaction")
+		}
+		tx, err := b.Begin(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("query: cursor: begin: %w", err)
+		}
+		c.exec, c.tx = tx, tx
+	}
+
+	if _, err := c.exec.Exec(ctx, "DECLARE "+name+" NO SCROLL CURSOR FOR "+sql, args...); err != nil {
+		c.rollback(ctx)
+		return nil, fmt.Errorf("query: cursor: declare: %w", err)
+	}
+	return c, nil
+}
+
+// Next advances to the next document, fetching the next batch when the
+// current one is used up. It returns false when the results are exhausted
+// or an error occurred; see Err.
+func (c *Cursor[T]) Next(ctx context.Context) bool {
+	if c.closed || c.err != nil {
+		return false
+	}
+	if len(c.buf) == 0 && !c.done {
+		rows, err := c.exec.Query(ctx, c.fetch)
+		if err != nil {
+			c.err = fmt.Errorf("query: cursor: fetch: %w", err)
+			return false
+		}
+		docs, err := scanDocs[T](rows, c.codec)
+		if err != nil {
+			c.err = fmt.Errorf("query: cursor: %w", err)
+			return false
+		}
+		c.buf = docs
+		c.done = len(docs) == 0
+	}
+	if len(c.buf) == 0 {
+		c.doc = nil
+		return false
+	}
+	c.doc, c.buf = c.buf[0], c.buf[1:]
+	return true
+}
+
+// Doc returns the current document.
+func (c *Cursor[T]) Doc() *T {
+	return c.doc
+}
+
+// Err returns the error that stopped Next, if any.
+func (c *Cursor[T]) Err() error {
+	return c.err
+}
+
+// Close closes the server-side cursor and ends the cursor's own transaction,
+// if it has one. It is safe to call more than once.
+func (c *Cursor[T]) Close(ctx context.Context) error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.buf, c.doc = nil, nil
+	if c.tx != nil {
+		// ending the transaction closes the cursor with it
+		if err := c.tx.Commit(ctx); err != nil {
+			return fmt.Errorf("query: cursor: close: %w", err)
+		}
+		return nil
+	}
+	if _, err := c.exec.Exec(ctx, "CLOSE "+c.name); err != nil {
+		return fmt.Errorf("query: cursor: close: %w", err)
+	}
+	return nil
+}
+
+func (c *Cursor[T]) rollback(ctx context.Context) {
+	if c.tx != nil {
+		_ = c.tx.Rollback(ctx)
+	}
+}
+
+// Iterate streams the query's results through fn, one document at a time,
+// with a Cursor. It stops at the first error fn returns and returns it.
+func (q *Query[T]) Iterate(ctx context.Context, fn func(*T) error) error {
+	cur, err := q.Cursor(ctx)
+	if err != nil {
+		return err
+	}
+	for cur.Next(ctx) {
+		if err := fn(cur.Doc()); err != nil {
+			_ = cur.Close(ctx)
+			return err
+		}
+	}
+	if err := cur.Err(); err != nil {
+		_ = cur.Close(ctx)
+		return err
+	}
+	return cur.Close(ctx)
+}
@@ -0,0 +1,59 @@
+package documents
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+func cursorQuery() *Query[testDoc] {
+	return &Query[testDoc]{
+		name:   "users",
+		table:  "whisker_users",
+		schema: schema.New(schema.WithAutoMigrate(false)),
+	}
+}
+
+func TestQuery_FetchSizeIsCloned(t *testing.T) {
+	base := cursorQuery()
+	q := base.FetchSize(50).Where("name", "=", "Alice")
+	if q.fetchSize != 50 {
+		t.Errorf("fetchSize = %d, want 50", q.fetchSize)
+	}
+	if base.fetchSize != 0 {
+		t.Errorf("base query modified: fetchSize = %d", base.fetchSize)
+	}
+}
+
+func TestQuery_CursorRejectsNegativeFetchSize(t *testing.T) {
+	_, err := cursorQuery().FetchSize(-1).Cursor(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "fetch size") {
+		t.Errorf("got %v, want fetch size error", err)
+	}
+}
+
+func TestQuery_CursorNeedsTransaction(t *testing.T) {
+	called := false
+	err := cursorQuery().Iterate(context.Background(), func(*testDoc) error {
+		called = true
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "cannot begin a transaction") {
+		t.Errorf("got %v, want transaction error", err)
+	}
+	if called {
+		t.Error("fn should not run")
+	}
+}
+
+func TestCursor_CloseIsIdempotent(t *testing.T) {
+	c := &Cursor[testDoc]{closed: true}
+	if err := c.Close(context.Background()); err != nil {
+		t.Errorf("close: %v", err)
+	}
+	if c.Next(context.Background()) {
+		t.Error("Next on a closed cursor should return false")
+	}
+}
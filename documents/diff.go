@@ -0,0 +1,276 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+// DiffResult is what Diff found between two collections, a and b. IDs are in
+// ascending order.
+type DiffResult struct {
+	// Added holds the IDs of documents in b but not in a.
+	Added []string
+	// Removed holds the IDs of documents in a but not in b.
+	Removed []string
+	// Changed holds the documents in both whose data differs.
+	Changed []DocumentDiff
+}
+
+// Equal reports whether Diff found no differences.
+func (r *DiffResult) Equal() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Changed) == 0
+}
+
+// DocumentDiff is a document whose data differs between the two collections
+// given to Diff.
+type DocumentDiff struct {
+	ID      string
+	Changes []FieldChange
+}
+
+// FieldChange is a value that differs between the two versions of a
+// document. Nested objects are compared key by key; arrays and other values
+// as a whole.
+type FieldChange struct {
+	// Path is the dotted path of the value, such as "address.city".
+	Path string
+	// Old is the value in a, New the value in b, as JSON. A value missing on
+	// one side is nil there.
+	Old json.RawMessage
+	New json.RawMessage
+}
+
+// DiffOption configures Diff.
+type DiffOption func(*diffConfig)
+
+type diffConfig struct {
+	pageSize int
+	ignore   map[string]bool
+}
+
+// WithDiffPageSize sets how many documents Diff reads from each collection
+// per query. Defaults to 1000.
+func WithDiffPageSize(n int) DiffOption {
+	return func(c *diffConfig) { c.pageSize = n }
+}
+
+// WithDiffIgnore leaves the values at the given dotted paths out of the
+// comparison, such as fields stamped differently in each environment.
+func WithDiffIgnore(paths ...string) DiffOption {
+	return func(c *diffConfig) {
+		for _, p := range paths {
+			c.ignore[p] = true
+		}
+	}
+}
+
+// Diff compares the documents of a and b by ID and data, to check that a
+// migration, a projection rebuild or replication to another region produced
+// what it should:
+//
+//	d, err := documents.Diff(ctx, documents.Collection[Order](primary, "orders"),
+//		documents.Collection[Order](replica, "orders"))
+//	for _, c := range d.Changed {
+//		log.Printf("%s: %+v", c.ID, c.Changes)
+//	}
+//
+// The collections may live in different stores. Documents are read as Load
+// reads them, with data migrations applied, and compared after encoding with
+// each collection's codec; versions and timestamps are not compared. Both
+// collections are paged through by ID, so a document written while Diff runs
+// may or may not be seen.
+func Diff[T any](ctx context.Context, a, b *CollectionOf[T], opts ...DiffOption) (*DiffResult, error) {
+	cfg := diffConfig{pageSize: 1000, ignore: make(map[string]bool)}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cfg.pageSize <= 0 {
+		return nil, fmt.Errorf("collection %s: diff: page size must be positive, got %d", a.name, cfg.pageSize)
+	}
+
+	pa := &diffPager[T]{col: a, pageSize: cfg.pageSize}
+	pb := &diffPager[T]{col: b, pageSize: cfg.pageSize}
+	res := &DiffResult{}
+	for {
+		da, err := pa.peek(ctx)
+		if err != nil {
+			return nil, err
+		}
+		db, err := pb.peek(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case da == nil && db == nil:
+			return res, nil
+		case db == nil || (da != nil && da.id < db.id):
+			res.Removed = append(res.Removed, da.id)
+			pa.next()
+		case da == nil || db.id < da.id:
+			res.Added = append(res.Added, db.id)
+			pb.next()
+		default:
+			var changes []FieldChange
+			if err := diffJSON(da.data, db.data, "", cfg.ignore, &changes); err != nil {
+				return nil, fmt.Errorf("collection %s: diff %s: %w", a.name, da.id, err)
+			}
+			if len(changes) > 0 {
+				res.Changed = append(res.Changed, DocumentDiff{ID: da.id, Changes: changes})
+			}
+			pa.next()
+			pb.next()
+		}
+	}
+}
+
+// diffDoc is a document read by Diff, encoded with its collection's codec.
+type diffDoc struct {
+	id   string
+	data []byte
+}
+
+// diffPager reads a collection's documents in ID order, a page at a time.
+type diffPager[T any] struct {
+	col      *CollectionOf[T]
+	pageSize int
+	page     []diffDoc
+	after    string
+	done     bool
+}
+
+// peek returns the next document without consuming it, or nil when the
+// collection is exhausted.
+func (p *diffPager[T]) peek(ctx context.Context) (*diffDoc, error) {
+	if len(p.page) == 0 && !p.done {
+		if err := p.fetch(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if len(p.page) == 0 {
+		return nil, nil
+	}
+	return &p.page[0], nil
+}
+
+func (p *diffPager[T]) next() {
+	p.page = p.page[1:]
+}
+
+// fetch reads the next page. IDs are ordered bytewise, as Go compares
+// strings, whatever the database's collation.
+func (p *diffPager[T]) fetch(ctx context.Context) error {
+	if err := p.col.ensure(ctx); err != nil {
+		return err
+	}
+	sql := fmt.Sprintf(`SELECT %s FROM %s WHERE id COLLATE "C" > $1 ORDER BY id COLLATE "C" LIMIT $2`,
+		strings.Join(withSchemaVersion(migrationsFor[T](), "id", "data", "version"), ", "), p.col.table)
+	rows, err := p.col.exec.Query(ctx, sql, p.after, p.pageSize)
+	if err != nil {
+		return fmt.Errorf("collection %s: diff: %w", p.col.name, err)
+	}
+	docs, err := scanDocs[T](rows, p.col.codec)
+	if err != nil {
+		return fmt.Errorf("collection %s: diff: %w", p.col.name, err)
+	}
+
+	p.done = len(docs) < p.pageSize
+	p.page = make([]diffDoc, len(docs))
+	for i, doc := range docs {
+		id, err := meta.ExtractID(doc)
+		if err != nil {
+			return fmt.Errorf("collection %s: diff: %w", p.col.name, err)
+		}
+		// compare plaintext: encryption draws a fresh nonce every time
+		data, err := plainCodec(p.col.codec).Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("collection %s: diff: marshal %s: %w", p.col.name, id, err)
+		}
+		p.page[i] = diffDoc{id: id, data: data}
+	}
+	if len(docs) > 0 {
+		p.after = p.page[len(docs)-1].id
+	}
+	return nil
+}
+
+// diffJSON appends the differences between the JSON values a and b, found at
+// path, to changes. Objects are compared key by key, in key order.
+func diffJSON(a, b json.RawMessage, path string, ignore map[string]bool, changes *[]FieldChange) error {
+	if ignore[path] {
+		return nil
+	}
+	oa, aObj := jsonObject(a)
+	ob, bObj := jsonObject(b)
+	if aObj && bObj {
+		keys := make([]string, 0, len(oa)+len(ob))
+		for k := range oa {
+			keys = append(keys, k)
+		}
+		for k := range ob {
+			if _, ok := oa[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			sub := k
+			if path != "" {
+				sub = path + "." + k
+			}
+			if err := diffJSON(oa[k], ob[k], sub, ignore, changes); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	equal, err := jsonEqual(a, b)
+	if err != nil {
+		return fmt.Errorf("compare %s: %w", path, err)
+	}
+	if !equal {
+		*changes = append(*changes, FieldChange{Path: path, Old: a, New: b})
+	}
+	return nil
+}
+
+// jsonObject decodes raw as an object's members, reporting false when it is
+// not an object.
+func jsonObject(raw json.RawMessage) (map[string]json.RawMessage, bool) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, false
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &obj); err != nil {
+		return nil, false
+	}
+	return obj, true
+}
+
+// jsonEqual reports whether a and b hold the same JSON value, regardless of
+// whitespace and key order. A missing value equals only another missing one.
+func jsonEqual(a, b json.RawMessage) (bool, error) {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b), nil
+	}
+	if bytes.Equal(a, b) {
+		return true, nil
+	}
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(va, vb), nil
+}
@@ -0,0 +1,67 @@
+package documents
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiffJSON(t *testing.T) {
+	a := `{"name":"Ann","age":30,"address":{"city":"Oslo","zip":"0150"},"tags":["a","b"],"gone":true}`
+	b := `{"tags":["a","b"], "age":31,"name":"Ann","address":{"zip":"0150","city":"Bergen"},"added":1.5}`
+
+	var changes []FieldChange
+	if err := diffJSON(json.RawMessage(a), json.RawMessage(b), "", map[string]bool{}, &changes); err != nil {
+		t.Fatal(err)
+	}
+	want := []FieldChange{
+		{Path: "added", New: json.RawMessage(`1.5`)},
+		{Path: "address.city", Old: json.RawMessage(`"Oslo"`), New: json.RawMessage(`"Bergen"`)},
+		{Path: "age", Old: json.RawMessage(`30`), New: json.RawMessage(`31`)},
+		{Path: "gone", Old: json.RawMessage(`true`)},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes %+v, want %d", len(changes), changes, len(want))
+	}
+	for i, c := range changes {
+		w := want[i]
+		if c.Path != w.Path || string(c.Old) != string(w.Old) || string(c.New) != string(w.New) {
+			t.Errorf("change %d = {%s %s %s}, want {%s %s %s}", i, c.Path, c.Old, c.New, w.Path, w.Old, w.New)
+		}
+	}
+}
+
+func TestDiffJSON_Ignore(t *testing.T) {
+	a := `{"name":"Ann","meta":{"syncedAt":"2026-01-01","source":"eu"}}`
+	b := `{"name":"Ann","meta":{"syncedAt":"2026-02-01","source":"us"}}`
+
+	var changes []FieldChange
+	ignore := map[string]bool{"meta.syncedAt": true}
+	if err := diffJSON(json.RawMessage(a), json.RawMessage(b), "", ignore, &changes); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "meta.source" {
+		t.Errorf("got %+v, want only meta.source", changes)
+	}
+}
+
+func TestJSONEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{`{"a":1,"b":[1,2]}`, `{ "b": [1, 2], "a": 1 }`, true},
+		{`[1,2]`, `[2,1]`, false},
+		{`1`, `1.0`, true},
+		{`null`, ``, false},
+		{``, ``, true},
+	}
+	for _, tt := range tests {
+		got, err := jsonEqual(json.RawMessage(tt.a), json.RawMessage(tt.b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("jsonEqual(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
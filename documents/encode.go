@@ -0,0 +1,52 @@
+package documents
+
+import "github.com/ripkitten-co/whisker/internal/codecs"
+
+// Marshaler is implemented by documents that encode themselves, skipping the
+// codec's reflection: AppendJSON appends the document's data fields, without
+// ID and Version, as a JSON object to dst. Writes encode into pooled buffers,
+// so a Marshaler that appends without allocating makes encoding
+// allocation-free:
+//
+//	func (c *Counter) AppendJSON(dst []byte) ([]byte, error) {
+//		dst = append(dst, `{"hits":`...)
+//		dst = strconv.AppendInt(dst, c.Hits, 10)
+//		return append(dst, '}'), nil
+//	}
+//
+// The output is stored as JSONB and must be valid JSON with the keys the
+// codec would use, so that queries and Load find the fields.
+type Marshaler = codecs.Marshaler
+
+// encodeBuffers holds the pooled buffers a write encoded its documents into,
+// until the statement using them has run.
+type encodeBuffers []*[]byte
+
+// encode encodes doc into a pooled buffer added to bufs.
+func (c *CollectionOf[T]) encode(doc *T, bufs *encodeBuffers) ([]byte, error) {
+	if e, ok := c.codec.(*encryptingCodec); ok {
+		// a Marshaler would skip the codec, and with it the encryption
+		id, err := c.access.ID(doc)
+		if err != nil {
+			return nil, err
+		}
+		return e.marshalDoc(id, doc)
+	}
+	buf := codecs.GetBuffer()
+	data, err := codecs.AppendMarshal(c.codec, *buf, doc)
+	if err != nil {
+		codecs.PutBuffer(buf)
+		return nil, err
+	}
+	*buf = data
+	*bufs = append(*bufs, buf)
+	return data, nil
+}
+
+// release returns the buffers to the pool. The encoded data must no longer be
+// in use.
+func (b encodeBuffers) release() {
+	for _, buf := range b {
+		codecs.PutBuffer(buf)
+	}
+}
@@ -0,0 +1,295 @@
+package documents
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ripkitten-co/whisker/internal/codecs"
+)
+
+// KeyProvider supplies the AES keys of WithEncryption by ID, so keys can be
+// rotated: values are encrypted with the current key and record its ID, and
+// are decrypted with the key of that ID. Keys are 16, 24 or 32 bytes long,
+// for AES-128, AES-192 or AES-256, and an ID must always name the same key:
+// ciphers are cached by ID.
+type KeyProvider interface {
+	// CurrentKey returns the ID and key new values are encrypted with.
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with the given ID.
+	Key(id string) ([]byte, error)
+}
+
+// ErrUnknownKey is returned by StaticKeys.Key for an ID it does not hold.
+var ErrUnknownKey = errors.New("documents: unknown encryption key")
+
+// StaticKeys is a KeyProvider holding its keys in memory. Current is the ID
+// of the key new values are encrypted with; keep retired keys in Keys until
+// no document uses them.
+type StaticKeys struct {
+	Current string
+	Keys    map[string][]byte
+}
+
+// CurrentKey returns the key named by Current.
+func (s StaticKeys) CurrentKey() (string, []byte, error) {
+	key, err := s.Key(s.Current)
+	return s.Current, key, err
+}
+
+// Key returns the key with the given ID, or ErrUnknownKey.
+func (s StaticKeys) Key(id string) ([]byte, error) {
+	key, ok := s.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// WithEncryption encrypts the fields of the document type tagged
+// whisker:"encrypt" with AES-GCM, using keys from keys. Values are encrypted
+// before they are written, by the collection's writes and by Import, and
+// decrypted on load, so PostgreSQL only ever stores ciphertext for them. The
+// other fields stay queryable. A collection of a type with encrypted fields
+// fails every operation without WithEncryption, as does a type that tags a
+// field of a nested struct, which cannot be encrypted.
+//
+// An encrypted value is stored as a string, "whisker:enc:", the key ID and
+// the nonce and ciphertext in base64. It is bound to the collection's name,
+// the document's ID and the field's JSON key, so it does not decrypt once
+// copied to another field, document or collection. It cannot be filtered,
+// sorted, aggregated, indexed, incremented or set with UpdateSet; those
+// operations fail. Export writes it encrypted, and Import takes it back only
+// into a collection of the same name. Values written before the field was
+// encrypted are read as they are and encrypted on the next write. Data-schema
+// migrations see the ciphertext.
+func WithEncryption(keys KeyProvider) CollectionOption {
+	return func(cfg *collectionConfig) {
+		cfg.keys = keys
+	}
+}
+
+// encryptedPrefix starts every encrypted value.
+const encryptedPrefix = "whisker:enc:"
+
+// encryptingCodec wraps a collection's codec, encrypting the values of the
+// given top-level JSON keys after marshaling a document and decrypting them
+// before unmarshaling it. Documents go through marshalDoc and unmarshalDoc,
+// which bind the values to the document's ID; Marshal and Unmarshal, which
+// cannot, fail rather than handle an encrypted value.
+type encryptingCodec struct {
+	inner      codecs.Codec
+	keys       KeyProvider
+	collection string
+	fields     []string
+	// aeads caches the AES-GCM cipher of each key ID.
+	aeads sync.Map
+}
+
+func newEncryptingCodec(inner codecs.Codec, keys KeyProvider, collection string, fields []string) *encryptingCodec {
+	return &encryptingCodec{inner: inner, keys: keys, collection: collection, fields: fields}
+}
+
+// Marshal marshals v, which must not set an encrypted field.
+func (e *encryptingCodec) Marshal(v any) ([]byte, error) {
+	data, err := e.inner.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if raw, ok := jsonObject(data); ok {
+		for _, field := range e.fields {
+			if val, ok := raw[field]; ok && string(val) != "null" {
+				return nil, fmt.Errorf("field %s is encrypted and can only be written with its document", field)
+			}
+		}
+	}
+	return data, nil
+}
+
+// Unmarshal unmarshals data, which must not hold an encrypted value.
+func (e *encryptingCodec) Unmarshal(data []byte, v any) error {
+	if bytes.Contains(data, []byte(encryptedPrefix)) {
+		return errors.New("encrypted fields can only be read with their document")
+	}
+	return e.inner.Unmarshal(data, v)
+}
+
+// marshalDoc marshals the document id, encrypting its encrypted fields.
+func (e *encryptingCodec) marshalDoc(id string, v any) ([]byte, error) {
+	data, err := e.inner.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return e.seal(id, data, false)
+}
+
+// unmarshalDoc unmarshals the document id, decrypting its encrypted fields.
+func (e *encryptingCodec) unmarshalDoc(id string, data []byte, v any) error {
+	data, err := e.open(id, data)
+	if err != nil {
+		return err
+	}
+	return e.inner.Unmarshal(data, v)
+}
+
+// seal encrypts the encrypted fields of the JSON object of document id.
+// Values already encrypted are kept when keepSealed is set, for records
+// imported from an export. Other JSON, and objects without those fields, are
+// returned unchanged.
+func (e *encryptingCodec) seal(id string, data []byte, keepSealed bool) ([]byte, error) {
+	raw, ok := jsonObject(data)
+	if !ok {
+		return data, nil
+	}
+	changed := false
+	for _, field := range e.fields {
+		val, ok := raw[field]
+		if !ok || string(val) == "null" {
+			continue
+		}
+		var s string
+		if keepSealed && json.Unmarshal(val, &s) == nil && strings.HasPrefix(s, encryptedPrefix) {
+			continue
+		}
+		sealed, err := e.encrypt(id, field, val)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt field %s: %w", field, err)
+		}
+		raw[field], changed = sealed, true
+	}
+	if !changed {
+		return data, nil
+	}
+	return json.Marshal(raw)
+}
+
+// open decrypts the encrypted fields of the JSON object of document id,
+// leaving values that are not encrypted as they are.
+func (e *encryptingCodec) open(id string, data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte(encryptedPrefix)) {
+		return data, nil
+	}
+	raw, ok := jsonObject(data)
+	if !ok {
+		return data, nil
+	}
+	for _, field := range e.fields {
+		var s string
+		if val, ok := raw[field]; !ok || json.Unmarshal(val, &s) != nil || !strings.HasPrefix(s, encryptedPrefix) {
+			continue
+		}
+		plain, err := e.decrypt(id, field, s)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt field %s: %w", field, err)
+		}
+		raw[field] = plain
+	}
+	return json.Marshal(raw)
+}
+
+// additionalData is the data a value of field of document id is bound to.
+func (e *encryptingCodec) additionalData(id, field string) []byte {
+	return []byte(e.collection + "\x00" + id + "\x00" + field)
+}
+
+// encrypt returns the JSON string holding the encryption of the JSON value
+// val of field of document id.
+func (e *encryptingCodec) encrypt(id, field string, val []byte) ([]byte, error) {
+	keyID, key, err := e.keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(keyID, ":") {
+		return nil, fmt.Errorf("key ID %q contains a colon", keyID)
+	}
+	aead, err := e.aead(keyID, key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(val)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nonce, nonce, val, e.additionalData(id, field))
+	return json.Marshal(encryptedPrefix + keyID + ":" + base64.RawStdEncoding.EncodeToString(sealed))
+}
+
+// decrypt returns the JSON value encrypted in s, a value of field of
+// document id.
+func (e *encryptingCodec) decrypt(id, field, s string) ([]byte, error) {
+	keyID, payload, ok := strings.Cut(strings.TrimPrefix(s, encryptedPrefix), ":")
+	if !ok {
+		return nil, errors.New("malformed value")
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("malformed value: %w", err)
+	}
+	aead, err := e.aead(keyID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("malformed value")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, e.additionalData(id, field))
+}
+
+// aead returns the cipher of key ID id, built from key, or from the key the
+// provider returns for id when key is nil.
+func (e *encryptingCodec) aead(id string, key []byte) (cipher.AEAD, error) {
+	if a, ok := e.aeads.Load(id); ok {
+		return a.(cipher.AEAD), nil
+	}
+	if key == nil {
+		var err error
+		if key, err = e.keys.Key(id); err != nil {
+			return nil, err
+		}
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	e.aeads.Store(id, aead)
+	return aead, nil
+}
+
+// marshalDoc marshals the document id with codec, encrypting its encrypted
+// fields when the collection has any.
+func marshalDoc(codec codecs.Codec, id string, v any) ([]byte, error) {
+	if e, ok := codec.(*encryptingCodec); ok {
+		return e.marshalDoc(id, v)
+	}
+	return codec.Marshal(v)
+}
+
+// unmarshalDoc unmarshals the stored document id with codec, decrypting its
+// encrypted fields when the collection has any.
+func unmarshalDoc(codec codecs.Codec, id string, data []byte, v any) error {
+	if e, ok := codec.(*encryptingCodec); ok {
+		return e.unmarshalDoc(id, data, v)
+	}
+	return codec.Unmarshal(data, v)
+}
+
+// plainCodec returns codec without encryption, for comparing documents by
+// their plaintext.
+func plainCodec(codec codecs.Codec) codecs.Codec {
+	if e, ok := codec.(*encryptingCodec); ok {
+		return e.inner
+	}
+	return codec
+}
@@ -0,0 +1,178 @@
+package documents
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/internal/codecs"
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+type patient struct {
+	ID      string
+	Name    string
+	SSN     string   `json:"ssn" whisker:"encrypt"`
+	Allergy []string `whisker:"encrypt"`
+	Version int
+}
+
+func testKeys() StaticKeys {
+	return StaticKeys{Current: "k1", Keys: map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 16),
+	}}
+}
+
+func newTestEncryptingCodec(keys KeyProvider) *encryptingCodec {
+	return newEncryptingCodec(codecs.NewWhisker(codecs.NewJSONIter()), keys, "patients", meta.Analyze[patient]().EncryptedKeys())
+}
+
+func TestEncryptingCodec_RoundTrip(t *testing.T) {
+	c := newTestEncryptingCodec(testKeys())
+	in := &patient{ID: "p1", Name: "Ann", SSN: "123-45-6789", Allergy: []string{"penicillin"}}
+
+	data, err := c.marshalDoc("p1", in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	for _, plain := range []string{"123-45-6789", "penicillin"} {
+		if bytes.Contains(data, []byte(plain)) {
+			t.Errorf("stored data contains %q: %s", plain, data)
+		}
+	}
+	if !bytes.Contains(data, []byte(`"name":"Ann"`)) || !bytes.Contains(data, []byte(`"ssn":"whisker:enc:k1:`)) {
+		t.Errorf("stored data: %s", data)
+	}
+
+	var out patient
+	if err := c.unmarshalDoc("p1", data, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.Name != "Ann" || out.SSN != in.SSN || len(out.Allergy) != 1 || out.Allergy[0] != "penicillin" {
+		t.Errorf("got %+v", out)
+	}
+}
+
+func TestEncryptingCodec_PlainMarshalAndUnmarshalRefuseEncryptedValues(t *testing.T) {
+	c := newTestEncryptingCodec(testKeys())
+	if _, err := c.Marshal(map[string]any{"ssn": "987-65-4321", "name": "Bo"}); err == nil {
+		t.Error("expected error marshaling an encrypted field without its document")
+	}
+	if data, err := c.Marshal(map[string]any{"name": "Bo"}); err != nil || string(data) != `{"name":"Bo"}` {
+		t.Errorf("unencrypted fields: got %s, %v", data, err)
+	}
+
+	data, err := c.marshalDoc("p1", &patient{SSN: "123"})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if err := c.Unmarshal(data, &patient{}); err == nil {
+		t.Error("expected error unmarshaling an encrypted value without its document")
+	}
+}
+
+func TestEncryptingCodec_RotationAndPlaintext(t *testing.T) {
+	keys := testKeys()
+	old := newTestEncryptingCodec(keys)
+	data, err := old.marshalDoc("p1", &patient{SSN: "111", Allergy: []string{"dust"}})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	keys.Current = "k2"
+	rotated := newTestEncryptingCodec(keys)
+	var out patient
+	if err := rotated.unmarshalDoc("p1", data, &out); err != nil || out.SSN != "111" {
+		t.Errorf("decrypt with a retired key: got %+v, %v", out, err)
+	}
+	fresh, err := rotated.marshalDoc("p1", &out)
+	if err != nil || !bytes.Contains(fresh, []byte("whisker:enc:k2:")) {
+		t.Errorf("re-encrypt with the current key: got %s, %v", fresh, err)
+	}
+
+	// documents written before the field was encrypted read as they are
+	if err := rotated.unmarshalDoc("p2", []byte(`{"name":"Cy","ssn":"222"}`), &out); err != nil || out.SSN != "222" {
+		t.Errorf("plaintext: got %+v, %v", out, err)
+	}
+}
+
+func TestEncryptingCodec_Failures(t *testing.T) {
+	c := newTestEncryptingCodec(testKeys())
+	data, err := c.marshalDoc("p1", &patient{SSN: "123"})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	// a value moved to another encrypted field does not decrypt
+	swapped := bytes.Replace(data, []byte(`"ssn"`), []byte(`"allergy"`), 1)
+	if err := c.unmarshalDoc("p1", swapped, &patient{}); err == nil {
+		t.Error("expected error for a value moved to another field")
+	}
+	// nor in another document
+	if err := c.unmarshalDoc("p2", data, &patient{}); err == nil {
+		t.Error("expected error for a value moved to another document")
+	}
+	// nor in another collection
+	other := newEncryptingCodec(c.inner, testKeys(), "clients", c.fields)
+	if err := other.unmarshalDoc("p1", data, &patient{}); err == nil {
+		t.Error("expected error for a value moved to another collection")
+	}
+
+	unknown := newTestEncryptingCodec(StaticKeys{Current: "k3", Keys: map[string][]byte{"k3": bytes.Repeat([]byte{3}, 32)}})
+	if err := unknown.unmarshalDoc("p1", data, &patient{}); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("got %v, want ErrUnknownKey", err)
+	}
+
+	short := newTestEncryptingCodec(StaticKeys{Current: "k", Keys: map[string][]byte{"k": []byte("short")}})
+	if _, err := short.marshalDoc("p1", &patient{SSN: "123"}); err == nil {
+		t.Error("expected error for an invalid AES key")
+	}
+}
+
+func TestEncryptingCodec_SealKeepsEncryptedValues(t *testing.T) {
+	c := newTestEncryptingCodec(testKeys())
+	data, err := c.marshalDoc("p1", &patient{SSN: "123"})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	sealed, err := c.seal("p1", data, true)
+	if err != nil || !bytes.Equal(sealed, data) {
+		t.Errorf("already encrypted: got %s, %v", sealed, err)
+	}
+	sealed, err = c.seal("p1", []byte(`{"name":"Ann","ssn":"456"}`), true)
+	if err != nil || bytes.Contains(sealed, []byte("456")) {
+		t.Errorf("plaintext: got %s, %v", sealed, err)
+	}
+}
+
+func TestEncryptedFieldsCannotBeIncrementedOrSet(t *testing.T) {
+	c := &CollectionOf[patient]{table: "whisker_patients"}
+	if _, _, err := c.incrementSQL("p1", "ssn", 1, ""); err == nil || !strings.Contains(err.Error(), "encrypted") {
+		t.Errorf("increment: got %v, want an encrypted field error", err)
+	}
+	q := &Query[patient]{table: "whisker_patients"}
+	if _, _, err := q.toUpdateSetSQL(map[string]any{"ssn": "1"}); err == nil || !strings.Contains(err.Error(), "encrypted") {
+		t.Errorf("update set: got %v, want an encrypted field error", err)
+	}
+}
+
+func TestQuery_EncryptedFieldsAreNotQueryable(t *testing.T) {
+	q := &Query[patient]{table: "whisker_patients"}
+	for _, bad := range []*Query[patient]{
+		q.Where("ssn", "=", "123"),
+		q.OrderBy("ssn", Asc),
+		q.WhereFold("allergy", "x"),
+	} {
+		if _, _, err := bad.toSQL(); err == nil || !strings.Contains(err.Error(), "encrypted") {
+			t.Errorf("got %v, want an encrypted field error", err)
+		}
+	}
+	if _, _, err := q.Where("name", "=", "Ann").toSQL(); err != nil {
+		t.Errorf("unencrypted field: %v", err)
+	}
+	if _, err := ParseFilter[patient]([]byte(`[{"field": "ssn", "op": "=", "value": "123"}]`)); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("filter on an encrypted field: got %v, want ErrInvalidFilter", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/ripkitten-co/whisker"
 )
 
@@ -122,3 +123,20 @@ func TestBatchError_IsMatchesInnerErrors(t *testing.T) {
 		t.Error("errors.Is should not match ErrNotFound when not present")
 	}
 }
+
+func TestMapPgError_ForeignKey(t *testing.T) {
+	pgErr := &pgconn.PgError{Code: "23503", Message: "violates foreign key constraint"}
+	err := mapPgError(pgErr)
+	if !errors.Is(err, whisker.ErrForeignKey) {
+		t.Errorf("expected ErrForeignKey, got %v", err)
+	}
+	var got *pgconn.PgError
+	if !errors.As(err, &got) {
+		t.Error("original pg error should remain in the chain")
+	}
+
+	other := errors.New("boom")
+	if mapPgError(other) != other {
+		t.Error("non-constraint errors should pass through unchanged")
+	}
+}
@@ -0,0 +1,97 @@
+package documents
+
+import (
+	"strconv"
+	"strings"
+)
+
+// fastSelectSQL builds the SQL of a query with at most one plain condition
+// and one sort key, the shape of most lookups, by concatenating strings
+// instead of going through the squirrel builder, which allocates a builder
+// map per call. Identifiers are resolved and operators checked exactly as the
+// builder path does, and the text is identical to the builder's, so pgx's
+// statement cache treats both the same. ok is false for any other query,
+// which selectSQL builds with squirrel.
+func (q *Query[T]) fastSelectSQL(columns []string) (sql string, args []any, ok bool, err error) {
+	if len(q.conditions) > 1 || len(q.orderBys) > 1 || q.cursor != "" || q.afterVal != nil || q.tenant != "" {
+		return "", nil, false, nil
+	}
+
+	var where, orderBy string
+	if len(q.conditions) == 1 {
+		c := q.conditions[0]
+		if c.anyOf != nil || c.textSearch || c.similar || !(allowedOps[c.op] || patternOps[c.op]) {
+			return "", nil, false, nil
+		}
+		resolve := q.resolveTyped
+		if c.fold || patternOps[c.op] {
+			resolve = q.resolve
+		}
+		field, err := resolve(c.field)
+		if err != nil {
+			return "", nil, true, err
+		}
+		value, err := q.conditionValue(c, field)
+		if err != nil {
+			return "", nil, true, err
+		}
+		if c.fold {
+			where = "lower(" + field + ") " + c.op + " lower($1)"
+		} else {
+			where = field + " " + c.op + " $1"
+		}
+		args = []any{value}
+	}
+	if len(q.orderBys) == 1 {
+		ob := q.orderBys[0]
+		field, err := q.resolveTyped(ob.field)
+		if err != nil {
+			return "", nil, true, err
+		}
+		orderBy = field + " " + string(ob.direction)
+	}
+
+	var b strings.Builder
+	b.Grow(64 + len(q.table) + len(where) + len(orderBy))
+	b.WriteString("SELECT ")
+	for i, col := range columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(col)
+	}
+	b.WriteString(" FROM ")
+	b.WriteString(q.table)
+
+	var deleted string
+	switch q.deleted {
+	case ExcludeDeleted:
+		deleted = "deleted_at IS NULL"
+	case OnlyDeleted:
+		deleted = "deleted_at IS NOT NULL"
+	}
+	switch {
+	case deleted != "" && where != "":
+		b.WriteString(" WHERE " + deleted + " AND ")
+		b.WriteString(where)
+	case deleted != "":
+		b.WriteString(" WHERE " + deleted)
+	case where != "":
+		b.WriteString(" WHERE ")
+		b.WriteString(where)
+	}
+
+	if orderBy != "" {
+		b.WriteString(" ORDER BY ")
+		b.WriteString(orderBy)
+	}
+	if q.limit != nil {
+		b.WriteString(" LIMIT ")
+		b.WriteString(strconv.FormatUint(*q.limit, 10))
+	}
+	if q.offset != nil {
+		b.WriteString(" OFFSET ")
+		b.WriteString(strconv.FormatUint(*q.offset, 10))
+	}
+	return b.String(), args, true, nil
+}
@@ -0,0 +1,102 @@
+package documents
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+func TestFastSelectSQL_MatchesBuilder(t *testing.T) {
+	base := &Query[testDoc]{
+		table:   "whisker_users",
+		columns: []meta.ColumnMeta{{FieldJSONKey: "email", Name: "email", SQLType: "TEXT"}},
+	}
+	tests := []struct {
+		name string
+		q    *Query[testDoc]
+	}{
+		{"no conditions", base},
+		{"equality", base.Where("id", "=", "u1")},
+		{"generated column", base.Where("email", "=", "a@b.c").Limit(1)},
+		{"json field", base.Where("name", ">=", "B")},
+		{"pattern", base.Where("name", "ILIKE", "al%")},
+		{"fold", base.WhereFold("name", "Alice")},
+		{"nested path", base.Where("data->'address'->>'city'", "=", "Oslo")},
+		{"order only", base.OrderBy("name", Desc)},
+		{"full", base.Where("name", "=", "Alice").OrderBy("version", Asc).Limit(10).Offset(5)},
+		{"exclude deleted", base.Deleted(ExcludeDeleted)},
+		{"only deleted with condition", base.Where("name", "=", "Alice").Deleted(OnlyDeleted).Limit(1)},
+	}
+	columns := withSchemaVersion(nil, "id", "data", "version")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, ok, err := tt.q.fastSelectSQL(columns)
+			if err != nil || !ok {
+				t.Fatalf("fast path not taken: ok=%v, err=%v", ok, err)
+			}
+			wantSQL, wantArgs, err := tt.q.buildSelectSQL(columns...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != wantSQL {
+				t.Errorf("sql:\n got: %s\nwant: %s", sql, wantSQL)
+			}
+			if len(args) != len(wantArgs) || (len(args) > 0 && !reflect.DeepEqual(args, wantArgs)) {
+				t.Errorf("args: got %v, want %v", args, wantArgs)
+			}
+		})
+	}
+}
+
+func TestFastSelectSQL_FallsBackToBuilder(t *testing.T) {
+	base := &Query[testDoc]{table: "whisker_users"}
+	for name, q := range map[string]*Query[testDoc]{
+		"two conditions": base.Where("name", "=", "a").Where("id", "=", "b"),
+		"two sort keys":  base.OrderBy("name", Asc).OrderBy("id", Asc),
+		"in":             base.Where("id", "IN", []string{"a"}),
+		"or":             base.Where("name", "=", "a").OrWhere("name", "=", "b"),
+		"text search":    base.WhereTextSearch("name", "alice"),
+		"similar":        base.Similar("name", "alice", 0.4),
+		"after":          base.OrderBy("name", Asc).After("a"),
+		"cursor":         base.AfterCursor("x"),
+		"bad operator":   base.Where("name", "DROP TABLE", "x"),
+	} {
+		if _, _, ok, _ := q.fastSelectSQL([]string{"id"}); ok {
+			t.Errorf("%s: fast path taken", name)
+		}
+	}
+}
+
+func TestFastSelectSQL_InvalidField(t *testing.T) {
+	q := (&Query[testDoc]{table: "whisker_users"}).Where("name; DROP TABLE x", "=", "a")
+	if _, _, err := q.toSQL(); err == nil {
+		t.Error("expected error for invalid field name")
+	}
+}
+
+func benchmarkLookup() *Query[testDoc] {
+	return (&Query[testDoc]{table: "whisker_users"}).Where("name", "=", "Alice").Limit(1)
+}
+
+func BenchmarkSelectSQL_FastPath(b *testing.B) {
+	q := benchmarkLookup()
+	columns := []string{"id", "data", "version"}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, _, err := q.selectSQL(columns...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSelectSQL_Builder(b *testing.B) {
+	q := benchmarkLookup()
+	columns := []string{"id", "data", "version"}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, _, err := q.buildSelectSQL(columns...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
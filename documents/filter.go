@@ -0,0 +1,193 @@
+package documents
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+// ErrInvalidFilter is returned by ParseFilter for malformed filters and for
+// fields or operators the filter does not allow. Its message is safe to show
+// to API clients.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// maxFilterConditions caps the conditions in one parsed filter, and
+// maxFilterListValues the values of one IN or NOT IN condition, so external
+// input cannot build arbitrarily large queries.
+const (
+	maxFilterConditions = 32
+	maxFilterListValues = 100
+)
+
+// Filter is a set of conditions parsed from external input by ParseFilter.
+// Apply it with Query.Filter.
+type Filter[T any] struct {
+	conditions []condition
+}
+
+// FilterOption configures ParseFilter.
+type FilterOption func(*filterConfig)
+
+type filterConfig struct {
+	fields map[string]bool
+}
+
+// AllowFields narrows the fields a filter may reference. By default every
+// top-level field of T is filterable, plus id, version, created_at and
+// updated_at; use AllowFields to keep internal fields out of public APIs.
+func AllowFields(fields ...string) FilterOption {
+	return func(c *filterConfig) {
+		c.fields = make(map[string]bool, len(fields))
+		for _, f := range fields {
+			c.fields[f] = true
+		}
+	}
+}
+
+// filterCondition is one element of the filter JSON.
+type filterCondition struct {
+	Field string          `json:"field"`
+	Op    string          `json:"op"`
+	Value json.RawMessage `json:"value"`
+	Fold  bool            `json:"fold"`
+}
+
+// ParseFilter parses and validates a filter sent by an API client. The
+// filter is a JSON array of conditions, all of which must match:
+//
+//	[
+//	  {"field": "status", "op": "=", "value": "paid"},
+//	  {"field": "total", "op": ">=", "value": 100},
+//	  {"field": "email", "op": "=", "value": "Ann@Example.com", "fold": true},
+//	  {"field": "region", "op": "IN", "value": ["eu", "us"]}
+//	]
+//
+// Operators are those of Query.Where; fold makes an equality
+// case-insensitive, as Query.WhereFold. Values must be strings, numbers or
+// booleans, or for IN and NOT IN arrays of up to 100 of them. Fields must be
+// top-level JSON keys of T or document columns, and within AllowFields when
+// given. Field names are never interpolated into SQL unless they pass this
+// whitelist; values are always bound as parameters.
+func ParseFilter[T any](data []byte, opts ...FilterOption) (*Filter[T], error) {
+	var cfg filterConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	known := filterableFields(meta.Analyze[T]())
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var raw []filterCondition
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("%w: trailing data after filter", ErrInvalidFilter)
+	}
+	if len(raw) > maxFilterConditions {
+		return nil, fmt.Errorf("%w: %d conditions, at most %d allowed", ErrInvalidFilter, len(raw), maxFilterConditions)
+	}
+
+	f := &Filter[T]{conditions: make([]condition, 0, len(raw))}
+	for i, rc := range raw {
+		if !known[rc.Field] || (cfg.fields != nil && !cfg.fields[rc.Field]) {
+			return nil, fmt.Errorf("%w: condition %d: field %q is not filterable", ErrInvalidFilter, i, rc.Field)
+		}
+		if !allowedOps[rc.Op] && !listOps[rc.Op] {
+			return nil, fmt.Errorf("%w: condition %d: unsupported operator %q", ErrInvalidFilter, i, rc.Op)
+		}
+		if rc.Fold && rc.Op != "=" {
+			return nil, fmt.Errorf("%w: condition %d: fold requires =", ErrInvalidFilter, i)
+		}
+		decode := filterValue
+		if listOps[rc.Op] {
+			decode = filterListValue
+		}
+		value, err := decode(rc.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: condition %d: %v", ErrInvalidFilter, i, err)
+		}
+		if err := filterColumnValue(&value, rc); err != nil {
+			return nil, fmt.Errorf("%w: condition %d: %v", ErrInvalidFilter, i, err)
+		}
+		f.conditions = append(f.conditions, condition{field: rc.Field, op: rc.Op, value: value, fold: rc.Fold})
+	}
+	return f, nil
+}
+
+func filterableFields(m *meta.StructMeta) map[string]bool {
+	fields := map[string]bool{"id": true, "version": true, "created_at": true, "updated_at": true}
+	for _, f := range m.Fields {
+		fields[f.JSONKey] = !f.Encrypted
+	}
+	return fields
+}
+
+// filterValue decodes a scalar condition value. Whole numbers become int64
+// so they bind like the values passed to Query.Where.
+func filterValue(raw json.RawMessage) (any, error) {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil || raw == nil {
+		return nil, errors.New("missing value")
+	}
+	switch v := v.(type) {
+	case string, bool:
+		return v, nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v), nil
+		}
+		return v, nil
+	default:
+		return nil, errors.New("value must be a string, number or boolean")
+	}
+}
+
+// filterColumnValue types the value of a condition on a fixed column, so a
+// timestamp that does not parse is rejected with the filter rather than when
+// the query runs.
+func filterColumnValue(value *any, rc filterCondition) error {
+	if rc.Fold {
+		if err := textOnly(rc.Field, "fold"); err != nil {
+			return err
+		}
+	}
+	var err error
+	if listOps[rc.Op] {
+		*value, err = columnListValue(rc.Field, *value)
+	} else {
+		*value, err = columnValue(rc.Field, *value)
+	}
+	return err
+}
+
+// filterListValue decodes the array value of an IN or NOT IN condition.
+func filterListValue(raw json.RawMessage) (any, error) {
+	var elems []json.RawMessage
+	if err := json.Unmarshal(raw, &elems); err != nil || elems == nil {
+		return nil, errors.New("value must be an array")
+	}
+	if len(elems) > maxFilterListValues {
+		return nil, fmt.Errorf("%d values, at most %d allowed", len(elems), maxFilterListValues)
+	}
+	values := make([]any, len(elems))
+	for i, e := range elems {
+		v, err := filterValue(e)
+		if err != nil {
+			return nil, fmt.Errorf("value %d: %v", i, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// Filter adds the conditions of a parsed filter to the query.
+func (q *Query[T]) Filter(f *Filter[T]) *Query[T] {
+	c := q.clone()
+	c.conditions = append(c.conditions, f.conditions...)
+	return c
+}
@@ -0,0 +1,125 @@
+package documents
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type filterDoc struct {
+	ID     string
+	Status string
+	Total  float64
+	Email  string `json:"email_address"`
+	Secret string `json:"-"`
+}
+
+func TestParseFilter_BuildsSQL(t *testing.T) {
+	f, err := ParseFilter[filterDoc]([]byte(`[
+		{"field": "status", "op": "=", "value": "paid"},
+		{"field": "total", "op": ">=", "value": 100},
+		{"field": "total", "op": "<", "value": 99.5},
+		{"field": "email_address", "op": "=", "value": "Ann@Example.com", "fold": true},
+		{"field": "id", "op": "!=", "value": "o-1"}
+	]`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	q := (&Query[filterDoc]{table: "whisker_orders"}).Filter(f)
+	sql, args, err := q.toSQL()
+	if err != nil {
+		t.Fatalf("toSQL: %v", err)
+	}
+	want := "SELECT id, data, version FROM whisker_orders WHERE data->>'status' = $1 AND (data->>'total')::numeric >= $2 AND (data->>'total')::numeric < $3 AND lower(data->>'email_address') = lower($4) AND id != $5"
+	if sql != want {
+		t.Errorf("sql:\n got: %s\nwant: %s", sql, want)
+	}
+	wantArgs := []any{"paid", int64(100), 99.5, "Ann@Example.com", "o-1"}
+	if len(args) != len(wantArgs) {
+		t.Fatalf("args: got %v, want %v", args, wantArgs)
+	}
+	for i := range args {
+		if args[i] != wantArgs[i] {
+			t.Errorf("arg[%d]: got %#v, want %#v", i, args[i], wantArgs[i])
+		}
+	}
+}
+
+func TestParseFilter_Rejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		opts   []FilterOption
+		want   string
+	}{
+		{"not an array", `{"field": "status"}`, nil, "cannot unmarshal"},
+		{"unknown key", `[{"field": "status", "op": "=", "value": "x", "or": []}]`, nil, "unknown field"},
+		{"trailing data", `[] []`, nil, "trailing data"},
+		{"unknown field", `[{"field": "owner", "op": "=", "value": "x"}]`, nil, `field "owner" is not filterable`},
+		{"ignored field", `[{"field": "secret", "op": "=", "value": "x"}]`, nil, `field "secret" is not filterable`},
+		{"json path", `[{"field": "data->>'status'", "op": "=", "value": "x"}]`, nil, "is not filterable"},
+		{"outside allow list", `[{"field": "total", "op": "=", "value": 1}]`, []FilterOption{AllowFields("status")}, `field "total" is not filterable`},
+		{"operator", `[{"field": "status", "op": "LIKE", "value": "x"}]`, nil, `unsupported operator "LIKE"`},
+		{"fold without equality", `[{"field": "status", "op": ">", "value": "x", "fold": true}]`, nil, "fold requires ="},
+		{"null value", `[{"field": "status", "op": "=", "value": null}]`, nil, "must be a string, number or boolean"},
+		{"object value", `[{"field": "status", "op": "=", "value": {"a": 1}}]`, nil, "must be a string, number or boolean"},
+		{"missing value", `[{"field": "status", "op": "="}]`, nil, "missing value"},
+		{"in scalar", `[{"field": "status", "op": "IN", "value": "x"}]`, nil, "must be an array"},
+		{"in nested", `[{"field": "status", "op": "IN", "value": [["x"]]}]`, nil, "value 0: value must be a string, number or boolean"},
+		{"in too many", `[{"field": "status", "op": "NOT IN", "value": [` + strings.Repeat(`"x",`, 100) + `"x"]}]`, nil, "at most 100"},
+		{"bad timestamp", `[{"field": "updated_at", "op": ">", "value": "yesterday"}]`, nil, "not an RFC 3339 timestamp"},
+		{"numeric timestamp", `[{"field": "created_at", "op": "IN", "value": [1]}]`, nil, "compares with a time.Time"},
+		{"fractional version", `[{"field": "version", "op": ">", "value": 1.5}]`, nil, "compares with an integer"},
+		{"fold on version", `[{"field": "version", "op": "=", "value": 1, "fold": true}]`, nil, "does not support fold"},
+		{"too many", "[" + strings.Repeat(`{"field": "status", "op": "=", "value": "x"},`, 32) + `{"field": "status", "op": "=", "value": "x"}]`, nil, "at most 32"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFilter[filterDoc]([]byte(tt.filter), tt.opts...)
+			if !errors.Is(err, ErrInvalidFilter) {
+				t.Fatalf("got %v, want ErrInvalidFilter", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilter_InOperators(t *testing.T) {
+	f, err := ParseFilter[filterDoc]([]byte(`[
+		{"field": "status", "op": "IN", "value": ["paid", "shipped"]},
+		{"field": "total", "op": "NOT IN", "value": [0, 1.5]}
+	]`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	sql, args, err := (&Query[filterDoc]{table: "whisker_orders"}).Filter(f).toSQL()
+	if err != nil {
+		t.Fatalf("toSQL: %v", err)
+	}
+	want := "SELECT id, data, version FROM whisker_orders WHERE data->>'status' IN ($1,$2) AND (data->>'total')::numeric NOT IN ($3,$4)"
+	if sql != want {
+		t.Errorf("sql:\n got: %s\nwant: %s", sql, want)
+	}
+	wantArgs := []any{"paid", "shipped", int64(0), 1.5}
+	if len(args) != len(wantArgs) {
+		t.Fatalf("args: got %v, want %v", args, wantArgs)
+	}
+	for i := range args {
+		if args[i] != wantArgs[i] {
+			t.Errorf("arg[%d]: got %#v, want %#v", i, args[i], wantArgs[i])
+		}
+	}
+}
+
+func TestParseFilter_AllowFields(t *testing.T) {
+	f, err := ParseFilter[filterDoc]([]byte(`[{"field": "status", "op": "=", "value": true}]`), AllowFields("status"))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(f.conditions) != 1 || f.conditions[0].value != true {
+		t.Errorf("got %+v, want one boolean condition", f.conditions)
+	}
+}
@@ -0,0 +1,105 @@
+package documents
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Operations recorded in a document's history.
+const (
+	HistoryInsert = "insert"
+	HistoryUpdate = "update"
+	HistoryDelete = "delete"
+)
+
+// WithHistory keeps an audit trail of the collection: a trigger records
+// every insert, update and delete in whisker_{name}_history, in the same
+// transaction as the write, with the document's state before it, the actor
+// and the time. Read it with History. Setting deleted_at counts as a delete,
+// and updates that change nothing are not recorded.
+//
+// The actor is the one carried by the context of the write, or of the
+// session it runs in (see whisker.WithActor); writes without one record
+// none. The history outlives its documents and is never pruned.
+func WithHistory() CollectionOption {
+	return func(cfg *collectionConfig) {
+		cfg.history = true
+	}
+}
+
+// Revision is one write in a document's history.
+type Revision[T any] struct {
+	// Operation is HistoryInsert, HistoryUpdate or HistoryDelete.
+	Operation string
+	// Previous is the document as it was before the write, with its ID and
+	// Version set; nil for an insert.
+	Previous *T
+	// Actor is the actor of the write, empty when it had none.
+	Actor string
+	// At is the database time of the write.
+	At time.Time
+}
+
+// History returns the writes recorded for document id, oldest first,
+// including those of a document since deleted. The collection must have
+// been created WithHistory; only writes made since are recorded.
+func (c *CollectionOf[T]) History(ctx context.Context, id string) ([]Revision[T], error) {
+	if !c.history {
+		return nil, fmt.Errorf("collection %s: history: not enabled, see WithHistory", c.name)
+	}
+	if err := c.ensure(ctx); err != nil {
+		return nil, err
+	}
+	tenant, err := c.tenant(ctx, "history")
+	if err != nil {
+		return nil, err
+	}
+
+	builder := psql.Select("operation", "version", "schema_version", "data", "COALESCE(actor, '')", "recorded_at").
+		From(c.table + "_history").
+		Where(sq.Eq{"doc_id": id}).
+		OrderBy("seq")
+	sql, args, err := whereTenant(builder, tenant).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("collection %s: history %s: build sql: %w", c.name, id, err)
+	}
+	rows, err := c.exec.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("collection %s: history %s: %w", c.name, id, err)
+	}
+	defer rows.Close()
+
+	chain := migrationsFor[T]()
+	var revs []Revision[T]
+	for rows.Next() {
+		var rev Revision[T]
+		var version, schemaVersion *int
+		var data []byte
+		if err := rows.Scan(&rev.Operation, &version, &schemaVersion, &data, &rev.Actor, &rev.At); err != nil {
+			return nil, fmt.Errorf("collection %s: history %s: scan: %w", c.name, id, err)
+		}
+		if data != nil {
+			sv := 1
+			if schemaVersion != nil {
+				sv = *schemaVersion
+			}
+			doc := new(T)
+			if err := decodeDoc(c.codec, chain, id, data, sv, doc); err != nil {
+				return nil, fmt.Errorf("collection %s: history %s: unmarshal: %w", c.name, id, err)
+			}
+			c.access.SetID(doc, id)
+			if version != nil {
+				c.access.SetVersion(doc, *version)
+			}
+			rev.Previous = doc
+		}
+		revs = append(revs, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("collection %s: history %s: %w", c.name, id, err)
+	}
+	return revs, nil
+}
@@ -0,0 +1,70 @@
+package documents
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ripkitten-co/whisker/internal/ident"
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+// IndexSpec declares a B-tree index that struct tags cannot express, such as
+// a partial index over only the rows a hot query reads:
+//
+//	documents.WithIndex(documents.IndexSpec{
+//		Name:   "open_by_tenant",
+//		Fields: []string{"tenantId", "createdAt"},
+//		Where:  "data->>'status' = 'open'",
+//	})
+//
+// creates idx_whisker_orders_open_by_tenant_partial on (data->>'tenantId',
+// data->>'createdAt') WHERE data->>'status' = 'open', or the fields' generated
+// columns where they have one. Without Where the index is a plain composite
+// index named like those from whisker:"index(group=...)" tags.
+//
+// PostgreSQL only uses a partial index when it can prove a query's
+// conditions imply Where, so a literal in Where should match a literal in
+// the query; a parameter bound to the same value is not enough once a cached
+// statement switches to a generic plan. Like all indexes, it is created once
+// under its name: changing Fields or Where later needs the old index dropped.
+type IndexSpec struct {
+	// Name identifies the index within the collection; letters, digits and
+	// underscores.
+	Name string
+	// Fields are the top-level JSON keys indexed, in order.
+	Fields []string
+	// Where is an SQL predicate over the table's columns, trusted like the
+	// policy of WithRLS.
+	Where string
+}
+
+// WithIndex adds the index spec describes to the collection, created with its
+// struct-tag indexes. An invalid spec fails the collection's first operation.
+func WithIndex(spec IndexSpec) CollectionOption {
+	return func(cfg *collectionConfig) {
+		cfg.indexes = append(cfg.indexes, spec)
+	}
+}
+
+// index converts the spec to index metadata for documents described by m,
+// indexing fields promoted to generated columns by their column and numeric
+// and time fields by their typed value.
+func (spec IndexSpec) index(m *meta.StructMeta) (meta.IndexMeta, error) {
+	if !ident.IsField(spec.Name) {
+		return meta.IndexMeta{}, fmt.Errorf("index %q: invalid name", spec.Name)
+	}
+	if len(spec.Fields) == 0 {
+		return meta.IndexMeta{}, fmt.Errorf("index %s: at least one field required", spec.Name)
+	}
+	if strings.ContainsAny(spec.Where, ";") || strings.Contains(spec.Where, "--") || strings.Contains(spec.Where, "/*") {
+		return meta.IndexMeta{}, fmt.Errorf("index %s: where must be a single expression", spec.Name)
+	}
+	idx := meta.IndexMeta{Type: meta.IndexBtree, Group: spec.Name, Where: strings.TrimSpace(spec.Where)}
+	for _, f := range spec.Fields {
+		if !ident.IsField(f) {
+			return meta.IndexMeta{}, fmt.Errorf("index %s: invalid field %q", spec.Name, f)
+		}
+		idx.Keys = append(idx.Keys, m.IndexKeyFor(f))
+	}
+	return idx, nil
+}
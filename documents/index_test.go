@@ -0,0 +1,57 @@
+package documents
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+type specOrder struct {
+	ID        string
+	TenantID  string `json:"tenantId" whisker:"column"`
+	CreatedAt time.Time
+	Status    string
+}
+
+func TestIndexSpec(t *testing.T) {
+	idx, err := IndexSpec{
+		Name:   "open_by_tenant",
+		Fields: []string{"tenantId", "createdAt", "status"},
+		Where:  " data->>'status' = 'open' ",
+	}.index(meta.Analyze[specOrder]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := meta.IndexMeta{
+		Type:  meta.IndexBtree,
+		Group: "open_by_tenant",
+		Keys: []meta.IndexKey{
+			{FieldJSONKey: "tenantId", Column: "tenant_id"},
+			{FieldJSONKey: "createdAt", Cast: meta.CastTimestamptz},
+			{FieldJSONKey: "status"},
+		},
+		Where: "data->>'status' = 'open'",
+	}
+	if !reflect.DeepEqual(idx, want) {
+		t.Errorf("got %+v\nwant %+v", idx, want)
+	}
+}
+
+func TestIndexSpec_Invalid(t *testing.T) {
+	for name, spec := range map[string]IndexSpec{
+		"no name":         {Fields: []string{"a"}},
+		"bad name":        {Name: "a b", Fields: []string{"a"}},
+		"no fields":       {Name: "a"},
+		"bad field":       {Name: "a", Fields: []string{"a'); DROP TABLE x; --"}},
+		"two statements":  {Name: "a", Fields: []string{"a"}, Where: "true; DROP TABLE x"},
+		"comment":         {Name: "a", Fields: []string{"a"}, Where: "true --"},
+		"block comment":   {Name: "a", Fields: []string{"a"}, Where: "true /* x */"},
+		"nested bad name": {Name: "a.b", Fields: []string{"a"}},
+	} {
+		if _, err := spec.index(meta.Analyze[specOrder]()); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package documents
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+// Page is one page of ExecutePage results.
+type Page[T any] struct {
+	Docs []*T
+	next string
+}
+
+// NextCursor returns the cursor for the following page, to pass to
+// AfterCursor, or "" when this is the last page.
+func (p *Page[T]) NextCursor() string {
+	return p.next
+}
+
+// AfterCursor resumes the query after the document a cursor returned by
+// Page.NextCursor points at. Unlike After, which only compares the first
+// OrderBy field, the cursor holds the values of every OrderBy field plus the
+// ID, which breaks ties, so documents sharing a sort value are neither
+// repeated nor skipped between pages:
+//
+//	q := orders.Query().OrderBy("status", documents.Asc).OrderBy("total", documents.Desc).Limit(50)
+//	page, err := q.ExecutePage(ctx)
+//	next, err := q.AfterCursor(page.NextCursor()).ExecutePage(ctx)
+//
+// The cursor must come from a query with the same OrderBy fields. It is
+// opaque but not signed: it holds sort values in plain form. An empty token
+// starts from the first page.
+func (q *Query[T]) AfterCursor(token string) *Query[T] {
+	c := q.clone()
+	c.cursor = token
+	return c
+}
+
+// ExecutePage runs the query, which must have a Limit, and returns one page
+// of results with the cursor for the next. Results are ordered by the
+// query's OrderBy fields, then by ID.
+func (q *Query[T]) ExecutePage(ctx context.Context) (*Page[T], error) {
+	if q.limit == nil {
+		return nil, fmt.Errorf("query: ExecutePage requires Limit")
+	}
+	if len(q.selects) > 0 {
+		return nil, fmt.Errorf("query: Select requires ExecuteInto")
+	}
+	if err := q.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	q, err := q.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := *q.limit
+	keyed := q.withTiebreaker().Limit(limit + 1)
+
+	columns := withSchemaVersion(migrationsFor[T](), "id", "data", "version")
+	for _, ob := range keyed.orderBys {
+		expr, err := keyed.resolveTyped(ob.field)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, fmt.Sprintf("(%s)::text", expr))
+	}
+	sql, args, err := keyed.selectSQL(columns...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.exec.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: execute page: %w", err)
+	}
+	// each row's sort values, from which the next page's cursor is built
+	var sortValues [][]*string
+	docs, err := scanDocsWith[T](rows, q.codec, func() []any {
+		values := make([]*string, len(keyed.orderBys))
+		sortValues = append(sortValues, values)
+		dest := make([]any, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		return dest
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// one extra row was read to tell whether another page follows
+	page := &Page[T]{Docs: docs}
+	if uint64(len(docs)) > limit {
+		page.Docs = docs[:limit]
+		page.next, err = keyed.encodeCursor(sortValues[limit-1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// withTiebreaker returns the query ordered by ID after its own OrderBy
+// fields, so that its sort order is total.
+func (q *Query[T]) withTiebreaker() *Query[T] {
+	if slices.ContainsFunc(q.orderBys, func(ob orderByClause) bool { return ob.field == "id" }) {
+		return q
+	}
+	return q.OrderBy("id", Asc)
+}
+
+// cursorToken is the decoded form of a keyset cursor: the sort keys of the
+// query it came from and the sort values of the document it points at.
+type cursorToken struct {
+	Keys   []string  `json:"k"`
+	Values []*string `json:"v"`
+}
+
+func (q *Query[T]) cursorKeys() []string {
+	keys := make([]string, len(q.orderBys))
+	for i, ob := range q.orderBys {
+		keys[i] = ob.field + " " + string(ob.direction)
+	}
+	return keys
+}
+
+func (q *Query[T]) encodeCursor(values []*string) (string, error) {
+	data, err := json.Marshal(cursorToken{Keys: q.cursorKeys(), Values: values})
+	if err != nil {
+		return "", fmt.Errorf("query: encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func (q *Query[T]) decodeCursor() ([]*string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(q.cursor)
+	if err != nil {
+		return nil, fmt.Errorf("query: invalid cursor: %w", err)
+	}
+	var tok cursorToken
+	if err := json.Unmarshal(data, &tok); err != nil {
+		return nil, fmt.Errorf("query: invalid cursor: %w", err)
+	}
+	if !slices.Equal(tok.Keys, q.cursorKeys()) || len(tok.Values) != len(tok.Keys) {
+		return nil, fmt.Errorf("query: cursor was issued for order %v, query is ordered by %v", tok.Keys, q.cursorKeys())
+	}
+	return tok.Values, nil
+}
+
+// cursorCondition matches the documents after the cursor in the query's
+// order, which must end with the ID. A document is after the cursor when it
+// equals the cursor's values on the first i sort fields and comes after it on
+// the next. NULLs sort as PostgreSQL sorts them: last ascending, first
+// descending.
+func (q *Query[T]) cursorCondition() (sq.Sqlizer, error) {
+	values, err := q.decodeCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	var alternatives []string
+	var args []any
+	var eqs []string
+	var eqArgs []any
+	for i, ob := range q.orderBys {
+		expr, err := q.resolveTyped(ob.field)
+		if err != nil {
+			return nil, err
+		}
+		value := fmt.Sprintf("CAST(?::text AS %s)", q.cursorType(ob.field))
+
+		var after string
+		var afterArgs []any
+		switch {
+		case values[i] == nil && ob.direction == Desc:
+			after = expr + " IS NOT NULL"
+		case values[i] == nil:
+			// nothing sorts after NULL ascending
+		case ob.direction == Desc:
+			after, afterArgs = fmt.Sprintf("%s < %s", expr, value), []any{*values[i]}
+		case notNullColumns[ob.field]:
+			after, afterArgs = fmt.Sprintf("%s > %s", expr, value), []any{*values[i]}
+		default:
+			after, afterArgs = fmt.Sprintf("(%s > %s OR %s IS NULL)", expr, value, expr), []any{*values[i]}
+		}
+		if after != "" {
+			alternatives = append(alternatives, "("+strings.Join(append(slices.Clone(eqs), after), " AND ")+")")
+			args = append(append(args, eqArgs...), afterArgs...)
+		}
+
+		if values[i] == nil {
+			eqs = append(eqs, expr+" IS NULL")
+		} else {
+			eqs = append(eqs, fmt.Sprintf("%s = %s", expr, value))
+			eqArgs = append(eqArgs, *values[i])
+		}
+	}
+	if len(alternatives) == 0 {
+		return sq.Expr("FALSE"), nil
+	}
+	return sq.Expr("("+strings.Join(alternatives, " OR ")+")", args...), nil
+}
+
+// notNullColumns are the fixed columns that never hold NULL.
+var notNullColumns = map[string]bool{"id": true, "version": true, "created_at": true, "updated_at": true}
+
+// cursorType is the SQL type of a sort field's expression, to which cursor
+// values, kept as text, are cast back for comparison.
+func (q *Query[T]) cursorType(field string) string {
+	if c, ok := q.typedColumn(field); ok {
+		return c.SQLType
+	}
+	switch field {
+	case "id":
+		return "text"
+	case "version":
+		return "integer"
+	case "created_at", "updated_at", "deleted_at":
+		return "timestamptz"
+	}
+	if cast := meta.Analyze[T]().CastOf(field); cast != "" {
+		return cast
+	}
+	if strings.HasPrefix(field, "data->") && !strings.HasPrefix(field[strings.LastIndex(field, "->"):], "->>") {
+		return "jsonb"
+	}
+	return "text"
+}
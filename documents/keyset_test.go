@@ -0,0 +1,118 @@
+package documents
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestQuery_CursorRoundTrip(t *testing.T) {
+	q := (&Query[testDoc]{table: "whisker_users"}).OrderBy("name", Asc).withTiebreaker()
+	token, err := q.encodeCursor([]*string{strPtr("Bob"), strPtr("u7")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := q.AfterCursor(token).decodeCursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || *values[0] != "Bob" || *values[1] != "u7" {
+		t.Errorf("got %v", values)
+	}
+
+	other := (&Query[testDoc]{table: "whisker_users"}).OrderBy("name", Desc).withTiebreaker()
+	if _, err := other.AfterCursor(token).decodeCursor(); err == nil || !strings.Contains(err.Error(), "cursor was issued for order") {
+		t.Errorf("got %v, want order mismatch error", err)
+	}
+	if _, err := q.AfterCursor("not a cursor!").decodeCursor(); err == nil {
+		t.Error("expected error for malformed cursor")
+	}
+}
+
+func TestQuery_AfterCursorSQL(t *testing.T) {
+	base := &Query[testDoc]{table: "whisker_users"}
+	tests := []struct {
+		name     string
+		query    *Query[testDoc]
+		values   []*string
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "ascending with tiebreaker",
+			query:    base.OrderBy("name", Asc),
+			values:   []*string{strPtr("Bob"), strPtr("u7")},
+			wantSQL:  "SELECT id, data, version FROM whisker_users WHERE (((data->>'name' > CAST($1::text AS text) OR data->>'name' IS NULL)) OR (data->>'name' = CAST($2::text AS text) AND id > CAST($3::text AS text))) ORDER BY data->>'name' ASC, id ASC",
+			wantArgs: []any{"Bob", "Bob", "u7"},
+		},
+		{
+			name:     "descending timestamp",
+			query:    base.OrderBy("created_at", Desc),
+			values:   []*string{strPtr("2026-01-02 03:04:05.123456+00"), strPtr("u7")},
+			wantSQL:  "SELECT id, data, version FROM whisker_users WHERE ((created_at < CAST($1::text AS timestamptz)) OR (created_at = CAST($2::text AS timestamptz) AND id > CAST($3::text AS text))) ORDER BY created_at DESC, id ASC",
+			wantArgs: []any{"2026-01-02 03:04:05.123456+00", "2026-01-02 03:04:05.123456+00", "u7"},
+		},
+		{
+			name:     "null ascending",
+			query:    base.OrderBy("name", Asc),
+			values:   []*string{nil, strPtr("u7")},
+			wantSQL:  "SELECT id, data, version FROM whisker_users WHERE ((data->>'name' IS NULL AND id > CAST($1::text AS text))) ORDER BY data->>'name' ASC, id ASC",
+			wantArgs: []any{"u7"},
+		},
+		{
+			name:     "null descending",
+			query:    base.OrderBy("name", Desc),
+			values:   []*string{nil, strPtr("u7")},
+			wantSQL:  "SELECT id, data, version FROM whisker_users WHERE ((data->>'name' IS NOT NULL) OR (data->>'name' IS NULL AND id > CAST($1::text AS text))) ORDER BY data->>'name' DESC, id ASC",
+			wantArgs: []any{"u7"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.query.withTiebreaker().encodeCursor(tt.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sql, args, err := tt.query.AfterCursor(token).toSQL()
+			if err != nil {
+				t.Fatalf("toSQL: %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("sql:\n got: %s\nwant: %s", sql, tt.wantSQL)
+			}
+			if len(args) != len(tt.wantArgs) {
+				t.Fatalf("args: got %v, want %v", args, tt.wantArgs)
+			}
+			for i, a := range args {
+				if a != tt.wantArgs[i] {
+					t.Errorf("arg[%d]: got %v, want %v", i, a, tt.wantArgs[i])
+				}
+			}
+		})
+	}
+
+	if _, _, err := base.OrderBy("name", Asc).After("Bob").AfterCursor("x").toSQL(); err == nil {
+		t.Error("expected error combining After and AfterCursor")
+	}
+}
+
+func TestQuery_CursorType(t *testing.T) {
+	q := &Query[testDoc]{columns: []meta.ColumnMeta{{FieldJSONKey: "total", Name: "total", SQLType: "NUMERIC"}}}
+	tests := map[string]string{
+		"total":                 "NUMERIC",
+		"version":               "integer",
+		"updated_at":            "timestamptz",
+		"name":                  "text",
+		"data->'address'":       "jsonb",
+		"data->'address'->>'c'": "text",
+	}
+	for field, want := range tests {
+		if got := q.cursorType(field); got != want {
+			t.Errorf("cursorType(%q) = %q, want %q", field, got, want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package documents
+
+import (
+	"context"
+	"fmt"
+)
+
+// DocHook is a lifecycle callback receiving the document being written.
+type DocHook[T any] func(ctx context.Context, doc *T) error
+
+// DeleteHook is a lifecycle callback receiving the ID of the document being
+// deleted.
+type DeleteHook func(ctx context.Context, id string) error
+
+// Hooks holds lifecycle callbacks for the documents of a collection, for
+// validation, auditing or cache invalidation. Attach them with WithHooks:
+//
+//	var userHooks = documents.NewHooks[User]().
+//		BeforeInsert(func(ctx context.Context, u *User) error {
+//			if u.Email == "" {
+//				return errors.New("email is required")
+//			}
+//			return nil
+//		}).
+//		AfterUpdate(func(ctx context.Context, u *User) error {
+//			cache.Forget("user:" + u.ID)
+//			return nil
+//		})
+//
+//	users := documents.Collection[User](store, "users", documents.WithHooks(userHooks))
+//
+// Before hooks run in registration order ahead of the write and may modify
+// the document; the first error aborts the operation, batches included, with
+// nothing written. After hooks run once the write succeeded, with Version
+// set; their error is returned but the write has happened, unless it ran in a
+// session the caller then rolls back. Batch variants (InsertMany, UpdateMany,
+// UpsertMany, DeleteMany) run the hooks once per document. Increment,
+// MigrateAll, Import and the bulk Query.Delete and Query.UpdateSet write
+// without hooks.
+//
+// Register callbacks at startup: Hooks is not safe to modify while in use.
+type Hooks[T any] struct {
+	beforeInsert []DocHook[T]
+	afterInsert  []DocHook[T]
+	beforeUpdate []DocHook[T]
+	afterUpdate  []DocHook[T]
+	beforeUpsert []DocHook[T]
+	afterUpsert  []DocHook[T]
+	beforeDelete []DeleteHook
+	afterDelete  []DeleteHook
+}
+
+// NewHooks returns an empty set of lifecycle callbacks for documents of type
+// T.
+func NewHooks[T any]() *Hooks[T] {
+	return &Hooks[T]{}
+}
+
+// BeforeInsert adds fn to run before Insert and InsertMany write a document.
+func (h *Hooks[T]) BeforeInsert(fn DocHook[T]) *Hooks[T] {
+	h.beforeInsert = append(h.beforeInsert, fn)
+	return h
+}
+
+// AfterInsert adds fn to run after Insert and InsertMany wrote a document.
+func (h *Hooks[T]) AfterInsert(fn DocHook[T]) *Hooks[T] {
+	h.afterInsert = append(h.afterInsert, fn)
+	return h
+}
+
+// BeforeUpdate adds fn to run before Update and UpdateMany write a document.
+func (h *Hooks[T]) BeforeUpdate(fn DocHook[T]) *Hooks[T] {
+	h.beforeUpdate = append(h.beforeUpdate, fn)
+	return h
+}
+
+// AfterUpdate adds fn to run after Update and UpdateMany wrote a document.
+func (h *Hooks[T]) AfterUpdate(fn DocHook[T]) *Hooks[T] {
+	h.afterUpdate = append(h.afterUpdate, fn)
+	return h
+}
+
+// BeforeUpsert adds fn to run before Upsert and UpsertMany write a document,
+// whether it is new or replaces a stored one.
+func (h *Hooks[T]) BeforeUpsert(fn DocHook[T]) *Hooks[T] {
+	h.beforeUpsert = append(h.beforeUpsert, fn)
+	return h
+}
+
+// AfterUpsert adds fn to run after Upsert and UpsertMany wrote a document.
+func (h *Hooks[T]) AfterUpsert(fn DocHook[T]) *Hooks[T] {
+	h.afterUpsert = append(h.afterUpsert, fn)
+	return h
+}
+
+// BeforeDelete adds fn to run before Delete and DeleteMany remove a
+// document.
+func (h *Hooks[T]) BeforeDelete(fn DeleteHook) *Hooks[T] {
+	h.beforeDelete = append(h.beforeDelete, fn)
+	return h
+}
+
+// AfterDelete adds fn to run after Delete and DeleteMany removed a document.
+// It does not run for IDs that were not found.
+func (h *Hooks[T]) AfterDelete(fn DeleteHook) *Hooks[T] {
+	h.afterDelete = append(h.afterDelete, fn)
+	return h
+}
+
+// WithHooks attaches lifecycle callbacks to the collection. Pass the same
+// Hooks to every Collection call for the collection, session-bound ones
+// included, so that all writes run them. The Hooks must be for the
+// collection's document type.
+func WithHooks[T any](h *Hooks[T]) CollectionOption {
+	return func(cfg *collectionConfig) {
+		cfg.hooks = h
+	}
+}
+
+// runDocHooks runs the callbacks of op ("before insert", "after update", ...)
+// for doc, stopping at the first error.
+func (c *CollectionOf[T]) runDocHooks(ctx context.Context, op string, id string, doc *T) error {
+	if c.hooks == nil {
+		return nil
+	}
+	for _, fn := range c.hooks.docHooks(op) {
+		if err := fn(ctx, doc); err != nil {
+			return fmt.Errorf("collection %s: %s %s: %w", c.name, op, id, err)
+		}
+	}
+	return nil
+}
+
+// runDeleteHooks runs the "before delete" or "after delete" callbacks for
+// id, stopping at the first error.
+func (c *CollectionOf[T]) runDeleteHooks(ctx context.Context, op string, id string) error {
+	if c.hooks == nil {
+		return nil
+	}
+	fns := c.hooks.beforeDelete
+	if op == "after delete" {
+		fns = c.hooks.afterDelete
+	}
+	for _, fn := range fns {
+		if err := fn(ctx, id); err != nil {
+			return fmt.Errorf("collection %s: %s %s: %w", c.name, op, id, err)
+		}
+	}
+	return nil
+}
+
+func (h *Hooks[T]) docHooks(op string) []DocHook[T] {
+	switch op {
+	case "before insert":
+		return h.beforeInsert
+	case "after insert":
+		return h.afterInsert
+	case "before update":
+		return h.beforeUpdate
+	case "after update":
+		return h.afterUpdate
+	case "before upsert":
+		return h.beforeUpsert
+	case "after upsert":
+		return h.afterUpsert
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package documents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRunDocHooks_RunsInOrderAndStopsAtFirstError(t *testing.T) {
+	var calls []string
+	record := func(name string, err error) DocHook[testDoc] {
+		return func(ctx context.Context, doc *testDoc) error {
+			calls = append(calls, name+":"+doc.Name)
+			return err
+		}
+	}
+	errInvalid := errors.New("invalid")
+	h := NewHooks[testDoc]().
+		BeforeInsert(record("first", nil)).
+		BeforeInsert(record("second", errInvalid)).
+		BeforeInsert(record("third", nil)).
+		AfterInsert(record("after", nil))
+	c := &CollectionOf[testDoc]{name: "users", hooks: h}
+
+	err := c.runDocHooks(context.Background(), "before insert", "u1", &testDoc{ID: "u1", Name: "Ann"})
+	if !errors.Is(err, errInvalid) {
+		t.Fatalf("got %v, want %v", err, errInvalid)
+	}
+	if want := "collection users: before insert u1: invalid"; err.Error() != want {
+		t.Errorf("got %q, want %q", err, want)
+	}
+	if want := []string{"first:Ann", "second:Ann"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+}
+
+func TestRunDocHooks_SelectsHooksByOp(t *testing.T) {
+	var calls []string
+	record := func(op string) DocHook[testDoc] {
+		return func(context.Context, *testDoc) error {
+			calls = append(calls, op)
+			return nil
+		}
+	}
+	h := NewHooks[testDoc]().
+		BeforeInsert(record("before insert")).
+		AfterInsert(record("after insert")).
+		BeforeUpdate(record("before update")).
+		AfterUpdate(record("after update")).
+		BeforeUpsert(record("before upsert")).
+		AfterUpsert(record("after upsert"))
+	c := &CollectionOf[testDoc]{name: "users", hooks: h}
+
+	ops := []string{"before insert", "after insert", "before update", "after update", "before upsert", "after upsert"}
+	for _, op := range ops {
+		if err := c.runDocHooks(context.Background(), op, "u1", &testDoc{}); err != nil {
+			t.Fatalf("%s: %v", op, err)
+		}
+	}
+	if !reflect.DeepEqual(calls, ops) {
+		t.Errorf("got calls %v, want %v", calls, ops)
+	}
+}
+
+func TestRunDeleteHooks(t *testing.T) {
+	var calls []string
+	h := NewHooks[testDoc]().
+		BeforeDelete(func(_ context.Context, id string) error {
+			calls = append(calls, "before "+id)
+			if id == "locked" {
+				return fmt.Errorf("%s is locked", id)
+			}
+			return nil
+		}).
+		AfterDelete(func(_ context.Context, id string) error {
+			calls = append(calls, "after "+id)
+			return nil
+		})
+	c := &CollectionOf[testDoc]{name: "users", hooks: h}
+	ctx := context.Background()
+
+	if err := c.runDeleteHooks(ctx, "before delete", "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.runDeleteHooks(ctx, "after delete", "u1"); err != nil {
+		t.Fatal(err)
+	}
+	err := c.runDeleteHooks(ctx, "before delete", "locked")
+	if want := "collection users: before delete locked: locked is locked"; err == nil || err.Error() != want {
+		t.Errorf("got %v, want %q", err, want)
+	}
+	if want := []string{"before u1", "after u1", "before locked"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+}
+
+func TestRunHooks_NoHooks(t *testing.T) {
+	c := &CollectionOf[testDoc]{name: "users"}
+	if err := c.runDocHooks(context.Background(), "before insert", "u1", &testDoc{}); err != nil {
+		t.Errorf("doc hooks: %v", err)
+	}
+	if err := c.runDeleteHooks(context.Background(), "before delete", "u1"); err != nil {
+		t.Errorf("delete hooks: %v", err)
+	}
+}
+
+func TestWithHooks_RejectsOtherDocumentType(t *testing.T) {
+	var cfg collectionConfig
+	WithHooks(NewHooks[transferDoc]())(&cfg)
+	if _, ok := cfg.hooks.(*Hooks[testDoc]); ok {
+		t.Fatal("hooks for transferDoc should not match testDoc")
+	}
+	if _, ok := cfg.hooks.(*Hooks[transferDoc]); !ok {
+		t.Errorf("got %T, want *Hooks[transferDoc]", cfg.hooks)
+	}
+}
@@ -0,0 +1,203 @@
+package documents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/ripkitten-co/whisker/internal/codecs"
+	"github.com/ripkitten-co/whisker/internal/pg"
+)
+
+// MigrationFunc rewrites a document's JSON from one data-schema version to the
+// next.
+type MigrationFunc func(old json.RawMessage) (json.RawMessage, error)
+
+type migrationStep struct {
+	to int
+	fn MigrationFunc
+}
+
+type migrationChain struct {
+	steps  map[int]migrationStep
+	latest int
+}
+
+// migrations maps a document type to its registered migration chain. Chains
+// are copied on write so readers never see a partially registered step.
+var (
+	migrationsMu sync.Mutex
+	migrations   sync.Map // reflect.Type -> *migrationChain
+)
+
+// Migrate registers a migration of documents of type T from data-schema
+// version from to version to. Every collection of T tracks the version each
+// document was written at in a schema_version column. Documents written before
+// any migration was registered are at version 1, and writes always stamp the
+// latest registered version. With auto-migrate, the first use of a collection
+// after a new latest version is registered rewrites the documents stored at
+// older versions, as MigrateAll does, once per database: the version is
+// recorded with the table. Documents written at an older version afterwards,
+// say by a process still running the previous release, and those of stores
+// without auto-migrate, are migrated in memory when loaded or queried; use
+// CollectionOf.MigrateAll to rewrite them. Register migrations at startup,
+// before the collection is used.
+func Migrate[T any](from, to int, fn MigrationFunc) error {
+	if from < 1 || to <= from {
+		return fmt.Errorf("documents: migrate %d -> %d: target version must be greater than source version, starting at 1", from, to)
+	}
+	typ := reflect.TypeFor[T]()
+
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+
+	next := &migrationChain{steps: map[int]migrationStep{}}
+	if v, ok := migrations.Load(typ); ok {
+		cur := v.(*migrationChain)
+		for k, s := range cur.steps {
+			next.steps[k] = s
+		}
+		next.latest = cur.latest
+	}
+	if _, dup := next.steps[from]; dup {
+		return fmt.Errorf("documents: migrate %s %d -> %d: a migration from version %d is already registered", typ, from, to, from)
+	}
+	next.steps[from] = migrationStep{to: to, fn: fn}
+	next.latest = max(next.latest, to)
+	migrations.Store(typ, next)
+	return nil
+}
+
+// migrationsFor returns the migration chain registered for T, or nil.
+func migrationsFor[T any]() *migrationChain {
+	v, ok := migrations.Load(reflect.TypeFor[T]())
+	if !ok {
+		return nil
+	}
+	return v.(*migrationChain)
+}
+
+// apply migrates data from version to the latest version.
+func (m *migrationChain) apply(data []byte, version int) ([]byte, error) {
+	for version < m.latest {
+		step, ok := m.steps[version]
+		if !ok {
+			return nil, fmt.Errorf("migrate: no migration from version %d", version)
+		}
+		out, err := step.fn(data)
+		if err != nil {
+			return nil, fmt.Errorf("migrate %d -> %d: %w", version, step.to, err)
+		}
+		data, version = out, step.to
+	}
+	return data, nil
+}
+
+// decodeDoc unmarshals the stored document id, migrating it first when it
+// was written at an older data-schema version.
+func decodeDoc[T any](codec codecs.Codec, chain *migrationChain, id string, data []byte, schemaVersion int, doc *T) error {
+	if chain != nil && schemaVersion < chain.latest {
+		migrated, err := chain.apply(data, schemaVersion)
+		if err != nil {
+			return err
+		}
+		data = migrated
+	}
+	return unmarshalDoc(codec, id, data, doc)
+}
+
+// withSchemaVersion appends schema_version to cols when the document type has
+// migrations, so collections without them keep working against tables that
+// predate the column.
+func withSchemaVersion(chain *migrationChain, cols ...string) []string {
+	if chain != nil {
+		return append(cols, "schema_version")
+	}
+	return cols
+}
+
+// MigrateAll rewrites every document stored at an older data-schema version
+// to the latest one, in keyset batches ordered by id. Each rewrite bumps the
+// document Version; documents modified concurrently are skipped, since any
+// write already stamps the latest version. Returns the number of documents
+// rewritten.
+func (c *CollectionOf[T]) MigrateAll(ctx context.Context) (int, error) {
+	chain := migrationsFor[T]()
+	if chain == nil {
+		return 0, nil
+	}
+	if err := c.ensure(ctx); err != nil {
+		return 0, err
+	}
+	if err := c.checkWrite(ctx, "migrate all"); err != nil {
+		return 0, err
+	}
+	return c.migrateAll(ctx, chain)
+}
+
+// migrateAll is MigrateAll without ensuring the table, so ensure can run it
+// to backfill the documents stored before the latest migration.
+func (c *CollectionOf[T]) migrateAll(ctx context.Context, chain *migrationChain) (int, error) {
+	batch := c.maxBatchSize
+	if batch <= 0 {
+		batch = 500
+	}
+	selectSQL := fmt.Sprintf(
+		`SELECT id, data, version, schema_version FROM %s WHERE schema_version < $1 AND id > $2 ORDER BY id LIMIT $3`,
+		c.table,
+	)
+	updateSQL := fmt.Sprintf(
+		`UPDATE %s SET data = $1, schema_version = $2, version = version + 1, updated_at = %s WHERE id = $3 AND version = $4`,
+		c.table, pg.NowExpr("$5"),
+	)
+
+	type pending struct {
+		id            string
+		data          []byte
+		version       int
+		schemaVersion int
+	}
+
+	migrated := 0
+	after := ""
+	for {
+		rows, err := c.exec.Query(ctx, selectSQL, chain.latest, after, batch)
+		if err != nil {
+			return migrated, fmt.Errorf("collection %s: migrate all: %w", c.name, err)
+		}
+		var docs []pending
+		for rows.Next() {
+			var p pending
+			if err := rows.Scan(&p.id, &p.data, &p.version, &p.schemaVersion); err != nil {
+				rows.Close()
+				return migrated, fmt.Errorf("collection %s: migrate all: scan: %w", c.name, err)
+			}
+			docs = append(docs, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return migrated, fmt.Errorf("collection %s: migrate all: %w", c.name, err)
+		}
+
+		for _, p := range docs {
+			data, err := chain.apply(p.data, p.schemaVersion)
+			if err != nil {
+				return migrated, fmt.Errorf("collection %s: migrate all %s: %w", c.name, p.id, err)
+			}
+			tag, err := c.exec.Exec(ctx, updateSQL, data, chain.latest, p.id, p.version, pg.Timestamp(c.clock))
+			if err != nil {
+				return migrated, fmt.Errorf("collection %s: migrate all %s: %w", c.name, p.id, err)
+			}
+			if tag.RowsAffected() > 0 {
+				migrated++
+			}
+		}
+
+		if len(docs) < batch {
+			return migrated, nil
+		}
+		after = docs[len(docs)-1].id
+	}
+}
@@ -0,0 +1,116 @@
+package documents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/internal/codecs"
+)
+
+type migratedDoc struct {
+	ID       string
+	FullName string `json:"fullName"`
+	Version  int
+}
+
+func TestMigrate_AppliesChain(t *testing.T) {
+	type chainDoc struct{ ID string }
+	steps := []struct{ from, to int }{{1, 2}, {2, 4}}
+	for _, s := range steps {
+		to := s.to
+		err := Migrate[chainDoc](s.from, s.to, func(old json.RawMessage) (json.RawMessage, error) {
+			return json.RawMessage(fmt.Sprintf(`%s,"v%d":true}`, strings.TrimSuffix(string(old), "}"), to)), nil
+		})
+		if err != nil {
+			t.Fatalf("register %d -> %d: %v", s.from, s.to, err)
+		}
+	}
+
+	chain := migrationsFor[chainDoc]()
+	if chain == nil || chain.latest != 4 {
+		t.Fatalf("chain: got %+v, want latest 4", chain)
+	}
+	got, err := chain.apply([]byte(`{"a":1}`), 1)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if want := `{"a":1,"v2":true,"v4":true}`; string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if got, _ := chain.apply([]byte(`{"a":1}`), 2); string(got) != `{"a":1,"v4":true}` {
+		t.Errorf("from 2: got %s", got)
+	}
+	if _, err := chain.apply([]byte(`{}`), 3); err == nil {
+		t.Error("expected error for version with no migration")
+	}
+}
+
+func TestMigrate_Validation(t *testing.T) {
+	type validationDoc struct{ ID string }
+	noop := func(old json.RawMessage) (json.RawMessage, error) { return old, nil }
+
+	if err := Migrate[validationDoc](2, 2, noop); err == nil {
+		t.Error("expected error when to <= from")
+	}
+	if err := Migrate[validationDoc](0, 1, noop); err == nil {
+		t.Error("expected error when from < 1")
+	}
+	if err := Migrate[validationDoc](1, 2, noop); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := Migrate[validationDoc](1, 3, noop); err == nil {
+		t.Error("expected error for duplicate source version")
+	}
+}
+
+func TestDecodeDoc_MigratesOldVersions(t *testing.T) {
+	chain := &migrationChain{
+		latest: 2,
+		steps: map[int]migrationStep{1: {to: 2, fn: func(old json.RawMessage) (json.RawMessage, error) {
+			var v1 struct {
+				First string `json:"first"`
+				Last  string `json:"last"`
+			}
+			if err := json.Unmarshal(old, &v1); err != nil {
+				return nil, err
+			}
+			return json.Marshal(map[string]string{"fullName": v1.First + " " + v1.Last})
+		}}},
+	}
+	codec := codecs.NewJSONIter()
+
+	var doc migratedDoc
+	if err := decodeDoc(codec, chain, "d1", []byte(`{"first":"Ada","last":"Lovelace"}`), 1, &doc); err != nil {
+		t.Fatalf("decode v1: %v", err)
+	}
+	if doc.FullName != "Ada Lovelace" {
+		t.Errorf("v1: got %q", doc.FullName)
+	}
+
+	doc = migratedDoc{}
+	if err := decodeDoc(codec, chain, "d1", []byte(`{"fullName":"Grace Hopper"}`), 2, &doc); err != nil {
+		t.Fatalf("decode v2: %v", err)
+	}
+	if doc.FullName != "Grace Hopper" {
+		t.Errorf("v2: got %q", doc.FullName)
+	}
+
+	failing := &migrationChain{latest: 2, steps: map[int]migrationStep{1: {to: 2, fn: func(json.RawMessage) (json.RawMessage, error) {
+		return nil, errors.New("boom")
+	}}}}
+	if err := decodeDoc(codec, failing, "d1", []byte(`{}`), 1, &doc); err == nil {
+		t.Error("expected migration error to propagate")
+	}
+}
+
+func TestWithSchemaVersion(t *testing.T) {
+	if got := withSchemaVersion(nil, "id", "data"); strings.Join(got, ",") != "id,data" {
+		t.Errorf("without chain: got %v", got)
+	}
+	if got := withSchemaVersion(&migrationChain{}, "id", "data"); strings.Join(got, ",") != "id,data,schema_version" {
+		t.Errorf("with chain: got %v", got)
+	}
+}
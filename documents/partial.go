@@ -0,0 +1,116 @@
+package documents
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ripkitten-co/whisker/internal/ident"
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+// Select restricts the documents ExecuteInto fetches to the named top-level
+// fields, so only those keys of each JSONB document leave the database.
+// Fields are JSON keys, as in Where. The ID and version are always fetched.
+// Fields are read as stored: data migrations registered for T do not run on
+// partial documents. Execute, Cursor and Iterate return full documents and
+// refuse a query with Select.
+func (q *Query[T]) Select(fields ...string) *Query[T] {
+	c := q.clone()
+	c.selects = append(c.selects, fields...)
+	return c
+}
+
+// partialData returns the expression selecting the data of a partial
+// document: a JSONB object holding the selected keys, or the whole document
+// when nothing is selected.
+func (q *Query[T]) partialData() (string, error) {
+	if len(q.selects) == 0 {
+		return "data", nil
+	}
+	pairs := make([]string, len(q.selects))
+	for i, f := range q.selects {
+		if !ident.IsField(f) {
+			return "", fmt.Errorf("query: invalid select field %q", f)
+		}
+		pairs[i] = ident.Literal(f) + ", data->" + ident.Literal(f)
+	}
+	return "jsonb_build_object(" + strings.Join(pairs, ", ") + ") AS data", nil
+}
+
+func (q *Query[T]) toPartialSQL() (string, []any, error) {
+	data, err := q.partialData()
+	if err != nil {
+		return "", nil, err
+	}
+	return q.selectSQL("id", data, "version")
+}
+
+// ExecuteInto runs the query and decodes the matching documents, restricted
+// to the fields given to Select, into dest, a pointer to a slice of structs,
+// struct pointers or maps. Struct elements get the document's ID and version
+// like full documents do. Declare a struct with just the selected fields to
+// read a narrow view of wide documents:
+//
+//	var partials []struct{ ID, Name, Email string }
+//	err := users.Query().Select("name", "email").ExecuteInto(ctx, &partials)
+func (q *Query[T]) ExecuteInto(ctx context.Context, dest any) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Pointer || slice.IsNil() || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("query: execute into: dest must be a pointer to a slice, got %T", dest)
+	}
+	slice = slice.Elem()
+	elem := slice.Type().Elem()
+	target, isPtr := elem, false
+	if elem.Kind() == reflect.Pointer {
+		target, isPtr = elem.Elem(), true
+	}
+	if target.Kind() != reflect.Struct && target.Kind() != reflect.Map {
+		return fmt.Errorf("query: execute into: unsupported element type %s", elem)
+	}
+
+	if err := q.ensureTable(ctx); err != nil {
+		return err
+	}
+	q, err := q.scoped(ctx)
+	if err != nil {
+		return err
+	}
+	sql, args, err := q.toPartialSQL()
+	if err != nil {
+		return err
+	}
+	rows, err := q.exec.Query(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("query: execute into: %w", err)
+	}
+	defer rows.Close()
+
+	results := reflect.MakeSlice(slice.Type(), 0, 0)
+	for rows.Next() {
+		var id string
+		var data []byte
+		var version int
+		if err := rows.Scan(&id, &data, &version); err != nil {
+			return fmt.Errorf("query: scan: %w", err)
+		}
+		v := reflect.New(target)
+		if err := unmarshalDoc(q.codec, id, data, v.Interface()); err != nil {
+			return fmt.Errorf("query: unmarshal: %w", err)
+		}
+		if target.Kind() == reflect.Struct {
+			meta.SetID(v.Interface(), id)
+			meta.SetVersion(v.Interface(), version)
+		}
+		if !isPtr {
+			v = v.Elem()
+		}
+		results = reflect.Append(results, v)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query: execute into: %w", err)
+	}
+	slice.Set(results)
+	return nil
+}
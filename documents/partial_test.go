@@ -0,0 +1,67 @@
+package documents
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestQuery_SelectSQL(t *testing.T) {
+	q := &Query[testDoc]{table: "whisker_users"}
+	sql, args, err := q.Select("name", "email").Where("status", "=", "active").Limit(10).toPartialSQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT id, jsonb_build_object('name', data->'name', 'email', data->'email') AS data, version " +
+		"FROM whisker_users WHERE data->>'status' = $1 LIMIT 10"
+	if sql != want {
+		t.Errorf("sql:\n got: %s\nwant: %s", sql, want)
+	}
+	if len(args) != 1 || args[0] != "active" {
+		t.Errorf("args = %v", args)
+	}
+
+	sql, _, err = q.toPartialSQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != "SELECT id, data, version FROM whisker_users" {
+		t.Errorf("without Select: %s", sql)
+	}
+}
+
+func TestQuery_SelectInvalidField(t *testing.T) {
+	q := &Query[testDoc]{table: "whisker_users"}
+	_, _, err := q.Select("name', data) --").toPartialSQL()
+	if err == nil || !strings.Contains(err.Error(), "invalid select field") {
+		t.Errorf("got %v, want invalid select field", err)
+	}
+}
+
+func TestQuery_SelectDoesNotMutateBase(t *testing.T) {
+	base := (&Query[testDoc]{table: "whisker_users"}).Select("name")
+	_ = base.Select("email")
+	if len(base.selects) != 1 {
+		t.Errorf("base selects = %v", base.selects)
+	}
+}
+
+func TestQuery_ExecuteRejectsSelect(t *testing.T) {
+	_, _, err := (&Query[testDoc]{table: "whisker_users"}).Select("name").toSQL()
+	if err == nil || !strings.Contains(err.Error(), "ExecuteInto") {
+		t.Errorf("got %v, want ExecuteInto error", err)
+	}
+}
+
+func TestQuery_ExecuteIntoDest(t *testing.T) {
+	q := cursorQuery().Select("name")
+	for name, dest := range map[string]any{
+		"not a pointer":     []testDoc{},
+		"pointer to struct": &testDoc{},
+		"slice of strings":  &[]string{},
+	} {
+		if err := q.ExecuteInto(context.Background(), dest); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
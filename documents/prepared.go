@@ -0,0 +1,99 @@
+package documents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// preparedSQL holds the fixed statements Prepare builds for a collection.
+// Their text never changes, so pgx's statement cache prepares each once per
+// pool connection and reuses it.
+type preparedSQL struct {
+	// chain is the migration chain the statements were built for; they are
+	// bypassed if migrations are registered for the type afterwards.
+	chain           *migrationChain
+	stamped         bool
+	insert          string
+	load            string
+	update          string
+	updateVersioned string
+}
+
+// Prepare readies the collection's hot path: it creates the schema, as on
+// first use, and builds the Insert, Load and Update statements once, so later
+// calls skip SQL building entirely. The statements have fixed text, which
+// pgx's statement cache prepares on each pool connection the first time that
+// connection runs them.
+//
+// Call Prepare at startup for the hottest collections, after registering any
+// migrations for the document type. Collections that are never prepared work
+// exactly the same, only building their SQL per call.
+func (c *CollectionOf[T]) Prepare(ctx context.Context) error {
+	if err := c.ensure(ctx); err != nil {
+		return err
+	}
+	c.prepared.Store(buildPreparedSQL(c.table, migrationsFor[T](), c.clock != nil))
+	return nil
+}
+
+// preparedFor returns the prepared statements if Prepare has been called and
+// still match the type's migrations, or nil.
+func (c *CollectionOf[T]) preparedFor(chain *migrationChain) *preparedSQL {
+	p := c.prepared.Load()
+	if p == nil || p.chain != chain || c.tenancy {
+		return nil
+	}
+	return p
+}
+
+// buildPreparedSQL renders the statements with the same columns and argument
+// order as the squirrel builders in Insert, Load and Update.
+func buildPreparedSQL(table string, chain *migrationChain, stamped bool) *preparedSQL {
+	p := &preparedSQL{chain: chain, stamped: stamped}
+
+	insertCols := withSchemaVersion(chain, "id", "data")
+	if stamped {
+		insertCols = append(insertCols, "created_at", "updated_at")
+	}
+	params := make([]string, len(insertCols))
+	for i := range params {
+		params[i] = fmt.Sprintf("$%d", i+1)
+	}
+	p.insert = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table, strings.Join(insertCols, ","), strings.Join(params, ","))
+
+	p.load = fmt.Sprintf("SELECT %s FROM %s WHERE id = $1",
+		strings.Join(withSchemaVersion(chain, "data", "version"), ", "), table)
+
+	// arguments: data, version, [updated_at], [schema_version], id, [version]
+	n := 3
+	set := "data = $1, version = $2, updated_at = now()"
+	if stamped {
+		set = "data = $1, version = $2, updated_at = $3"
+		n++
+	}
+	if chain != nil {
+		set += fmt.Sprintf(", schema_version = $%d", n)
+		n++
+	}
+	p.update = fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d", table, set, n)
+	p.updateVersioned = fmt.Sprintf("%s AND version = $%d", p.update, n+1)
+	return p
+}
+
+// updateArgs returns the arguments of p.update or p.updateVersioned.
+func (p *preparedSQL) updateArgs(data []byte, newVersion int, now any, id string, currentVersion int, hasVersion bool) []any {
+	args := []any{data, newVersion}
+	if p.stamped {
+		args = append(args, now)
+	}
+	if p.chain != nil {
+		args = append(args, p.chain.latest)
+	}
+	args = append(args, id)
+	if hasVersion {
+		args = append(args, currentVersion)
+	}
+	return args
+}
@@ -0,0 +1,74 @@
+package documents
+
+import (
+	"reflect"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// The prepared statements must match what the squirrel builders produce, so
+// prepared and unprepared collections behave identically.
+func TestBuildPreparedSQL_MatchesBuilders(t *testing.T) {
+	chain := &migrationChain{latest: 3}
+	for _, tc := range []struct {
+		name    string
+		chain   *migrationChain
+		stamped bool
+	}{
+		{"plain", nil, false},
+		{"stamped", nil, true},
+		{"migrations", chain, false},
+		{"stamped migrations", chain, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := buildPreparedSQL("whisker_users", tc.chain, tc.stamped)
+
+			cols := withSchemaVersion(tc.chain, "id", "data")
+			values := []any{"u1", []byte("{}")}
+			if tc.chain != nil {
+				values = append(values, tc.chain.latest)
+			}
+			if tc.stamped {
+				cols = append(cols, "created_at", "updated_at")
+				values = append(values, "t", "t")
+			}
+			want, _, _ := psql.Insert("whisker_users").Columns(cols...).Values(values...).ToSql()
+			if p.insert != want {
+				t.Errorf("insert:\ngot:  %s\nwant: %s", p.insert, want)
+			}
+
+			want, _, _ = psql.Select(withSchemaVersion(tc.chain, "data", "version")...).
+				From("whisker_users").Where(sq.Eq{"id": "u1"}).ToSql()
+			if p.load != want {
+				t.Errorf("load:\ngot:  %s\nwant: %s", p.load, want)
+			}
+
+			for _, versioned := range []bool{false, true} {
+				var now any = sq.Expr("now()")
+				if tc.stamped {
+					now = "t"
+				}
+				builder := psql.Update("whisker_users").
+					Set("data", []byte("{}")).Set("version", 2).Set("updated_at", now).
+					Where(sq.Eq{"id": "u1"})
+				if tc.chain != nil {
+					builder = builder.Set("schema_version", tc.chain.latest)
+				}
+				got := p.update
+				if versioned {
+					builder = builder.Where(sq.Eq{"version": 1})
+					got = p.updateVersioned
+				}
+				want, wantArgs, _ := builder.ToSql()
+				if got != want {
+					t.Errorf("update (versioned=%v):\ngot:  %s\nwant: %s", versioned, got, want)
+				}
+				args := p.updateArgs([]byte("{}"), 2, now, "u1", 1, versioned)
+				if !reflect.DeepEqual(args, wantArgs) {
+					t.Errorf("update (versioned=%v): got args %v, want %v", versioned, args, wantArgs)
+				}
+			}
+		})
+	}
+}
@@ -3,10 +3,16 @@ package documents
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"slices"
 	"strings"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 	"github.com/ripkitten-co/whisker/internal/codecs"
+	"github.com/ripkitten-co/whisker/internal/ident"
+	"github.com/ripkitten-co/whisker/internal/indexes"
 	"github.com/ripkitten-co/whisker/internal/meta"
 	"github.com/ripkitten-co/whisker/internal/pg"
 	"github.com/ripkitten-co/whisker/schema"
@@ -20,6 +26,19 @@ const (
 	Desc Direction = "DESC"
 )
 
+// DeletedFilter controls how tombstoned documents (deleted_at set) are
+// treated by a query.
+type DeletedFilter int
+
+const (
+	// IncludeDeleted applies no tombstone filter. This is the default.
+	IncludeDeleted DeletedFilter = iota
+	// ExcludeDeleted hides tombstoned documents.
+	ExcludeDeleted
+	// OnlyDeleted returns only tombstoned documents.
+	OnlyDeleted
+)
+
 type orderByClause struct {
 	field     string
 	direction Direction
@@ -27,6 +46,7 @@ type orderByClause struct {
 
 var knownColumns = map[string]bool{
 	"id": true, "version": true, "created_at": true, "updated_at": true,
+	"deleted_at": true,
 }
 
 func resolveField(field string) (string, error) {
@@ -36,15 +56,117 @@ func resolveField(field string) (string, error) {
 	if knownColumns[field] {
 		return field, nil
 	}
-	if strings.Contains(field, "->") {
+	if strings.HasPrefix(field, "data->") {
+		if err := ident.ValidateJSONPath(field); err != nil {
+			return "", fmt.Errorf("query: %w", err)
+		}
 		return field, nil
 	}
-	for _, c := range field {
-		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' {
-			return "", fmt.Errorf("query: invalid field name %q", field)
+	if !ident.IsField(field) {
+		return "", fmt.Errorf("query: invalid field name %q", field)
+	}
+	return ident.JSONText(field), nil
+}
+
+// resolve maps a field to its generated column when the document type promotes
+// it with whisker:"column", and to a JSONB path otherwise.
+func (q *Query[T]) resolve(field string) (string, error) {
+	if meta.Analyze[T]().Encrypted(field) {
+		return "", fmt.Errorf("query: field %s is encrypted and cannot be queried", field)
+	}
+	for _, c := range q.columns {
+		if c.FieldJSONKey == field {
+			return c.Name, nil
+		}
+	}
+	return resolveField(field)
+}
+
+// resolveTyped is resolve for comparisons and sorting: a top-level data
+// field of a numeric or time type is cast to it, so that it compares by value
+// rather than as text, with the expression its index is built on.
+func (q *Query[T]) resolveTyped(field string) (string, error) {
+	if c, ok := q.typedColumn(field); ok {
+		return c.Name, nil
+	}
+	if !knownColumns[field] {
+		if cast := meta.Analyze[T]().CastOf(field); cast != "" {
+			return ident.JSONTyped(field, cast), nil
+		}
+	}
+	return q.resolve(field)
+}
+
+// typedColumn returns the generated column comparisons of field use. A TEXT
+// column, as foreign keys have, is skipped when the field has a Cast, so the
+// field still compares by value.
+func (q *Query[T]) typedColumn(field string) (meta.ColumnMeta, bool) {
+	for _, c := range q.columns {
+		if c.FieldJSONKey != field {
+			continue
+		}
+		if c.SQLType == "TEXT" && meta.Analyze[T]().CastOf(field) != "" {
+			return meta.ColumnMeta{}, false
+		}
+		return c, true
+	}
+	return meta.ColumnMeta{}, false
+}
+
+// columnValue checks a value compared with one of the fixed columns and
+// converts it to the column's type, so a comparison never falls back to text:
+// created_at, updated_at and deleted_at take a time.Time or an RFC 3339
+// string, bound as a time.Time, and version any integer. Other fields pass
+// through.
+func columnValue(field string, value any) (any, error) {
+	switch field {
+	case "created_at", "updated_at", "deleted_at":
+		switch v := value.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, fmt.Errorf("query: %s: %q is not an RFC 3339 timestamp", field, v)
+			}
+			return t, nil
+		}
+		return nil, fmt.Errorf("query: %s compares with a time.Time, got %T", field, value)
+	case "version":
+		switch reflect.ValueOf(value).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return value, nil
+		}
+		return nil, fmt.Errorf("query: version compares with an integer, got %T", value)
+	}
+	return value, nil
+}
+
+// columnListValue is columnValue for each element of an IN or NOT IN list.
+func columnListValue(field string, value any) (any, error) {
+	v := reflect.ValueOf(value)
+	if !knownColumns[field] || field == "id" || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) {
+		return value, nil
+	}
+	values := make([]any, v.Len())
+	for i := range values {
+		elem, err := columnValue(field, v.Index(i).Interface())
+		if err != nil {
+			return nil, err
 		}
+		values[i] = elem
+	}
+	return values, nil
+}
+
+// textOnly fails for a LIKE pattern or a case-insensitive match on one of
+// the fixed columns that do not hold text.
+func textOnly(field, op string) error {
+	if knownColumns[field] && field != "id" {
+		return fmt.Errorf("query: %s is not text and does not support %s", field, op)
 	}
-	return fmt.Sprintf("data->>'%s'", field), nil
+	return nil
 }
 
 var allowedOps = map[string]bool{
@@ -53,10 +175,31 @@ var allowedOps = map[string]bool{
 	">=": true, "<=": true,
 }
 
+// listOps take a slice value, each element bound as its own parameter.
+var listOps = map[string]bool{"IN": true, "NOT IN": true}
+
+// patternOps match a pattern value. They are left out of ParseFilter, where a
+// client-supplied leading wildcard would force a full scan.
+var patternOps = map[string]bool{
+	"LIKE": true, "NOT LIKE": true,
+	"ILIKE": true, "NOT ILIKE": true,
+}
+
 type condition struct {
 	field string
 	op    string
 	value any
+	fold  bool
+	// anyOf makes the condition a group that matches when all conditions of
+	// any one of its alternatives match. See Or and WhereGroup.
+	anyOf [][]condition
+	// textSearch makes the condition a full-text match of value as plain
+	// text. See WhereTextSearch.
+	textSearch bool
+	// similar makes the condition a trigram similarity match of value of at
+	// least threshold. See Similar.
+	similar   bool
+	threshold float64
 }
 
 // Query builds and executes filtered, sorted, paginated queries against a
@@ -68,24 +211,38 @@ type Query[T any] struct {
 	codec      codecs.Codec
 	schema     *schema.Bootstrap
 	indexes    []meta.IndexMeta
+	columns    []meta.ColumnMeta
+	col        *CollectionOf[T]
 	conditions []condition
 	orderBys   []orderByClause
 	limit      *uint64
 	offset     *uint64
 	afterVal   any
+	cursor     string
+	deleted    DeletedFilter
+	fetchSize  int
+	selects    []string
+	groupBys   []string
+	tenant     string
 }
 
 func (q *Query[T]) clone() *Query[T] {
 	c := &Query[T]{
-		name:     q.name,
-		table:    q.table,
-		exec:     q.exec,
-		codec:    q.codec,
-		schema:   q.schema,
-		indexes:  q.indexes,
-		limit:    q.limit,
-		offset:   q.offset,
-		afterVal: q.afterVal,
+		name:      q.name,
+		table:     q.table,
+		exec:      q.exec,
+		codec:     q.codec,
+		schema:    q.schema,
+		indexes:   q.indexes,
+		columns:   q.columns,
+		col:       q.col,
+		limit:     q.limit,
+		offset:    q.offset,
+		afterVal:  q.afterVal,
+		cursor:    q.cursor,
+		deleted:   q.deleted,
+		fetchSize: q.fetchSize,
+		tenant:    q.tenant,
 	}
 	if len(q.conditions) > 0 {
 		c.conditions = make([]condition, len(q.conditions))
@@ -95,6 +252,12 @@ func (q *Query[T]) clone() *Query[T] {
 		c.orderBys = make([]orderByClause, len(q.orderBys))
 		copy(c.orderBys, q.orderBys)
 	}
+	if len(q.selects) > 0 {
+		c.selects = slices.Clone(q.selects)
+	}
+	if len(q.groupBys) > 0 {
+		c.groupBys = slices.Clone(q.groupBys)
+	}
 	return c
 }
 
@@ -107,6 +270,8 @@ func (c *CollectionOf[T]) Query() *Query[T] {
 		codec:   c.codec,
 		schema:  c.schema,
 		indexes: c.indexes,
+		columns: c.columns,
+		col:     c,
 	}
 }
 
@@ -115,14 +280,175 @@ func (c *CollectionOf[T]) Where(field, op string, value any) *Query[T] {
 	return c.Query().Where(field, op, value)
 }
 
+// WhereFold starts a query with a case-insensitive equality condition.
+func (c *CollectionOf[T]) WhereFold(field string, value any) *Query[T] {
+	return c.Query().WhereFold(field, value)
+}
+
+// WhereTextSearch starts a query with a full-text search condition.
+func (c *CollectionOf[T]) WhereTextSearch(field, query string) *Query[T] {
+	return c.Query().WhereTextSearch(field, query)
+}
+
 // Where adds a filter condition. Field names are resolved to JSONB paths
-// automatically. Supported operators: =, !=, >, <, >=, <=.
+// automatically. Supported operators: =, !=, >, <, >=, <=; LIKE, NOT LIKE,
+// ILIKE and NOT ILIKE, which take a pattern; and IN and NOT IN, which take a
+// slice or array value:
+//
+//	users.Where("status", "IN", []string{"active", "trial"})
+//	users.Where("email", "ILIKE", "%@example.com")
+//
+// An empty IN list matches no documents and an empty NOT IN list matches all.
 func (q *Query[T]) Where(field, op string, value any) *Query[T] {
 	c := q.clone()
-	c.conditions = append(c.conditions, condition{field, op, value})
+	c.conditions = append(c.conditions, condition{field: field, op: op, value: value})
+	return c
+}
+
+// ModifiedSince restricts the query to documents updated after t, compared
+// with the updated_at column:
+//
+//	changed, err := orders.Query().ModifiedSince(lastSync).Execute(ctx)
+//
+// Where also compares created_at, updated_at and deleted_at with a
+// time.Time, or an RFC 3339 string, and version with an integer, binding the
+// value as the column's type.
+func (q *Query[T]) ModifiedSince(t time.Time) *Query[T] {
+	return q.Where("updated_at", ">", t)
+}
+
+// WhereFold adds a case-insensitive equality condition, compiled to
+// lower(field) = lower($n). Pair it with a whisker:"index,ci" tag so the
+// lookup can use a matching functional index.
+func (q *Query[T]) WhereFold(field string, value any) *Query[T] {
+	c := q.clone()
+	c.conditions = append(c.conditions, condition{field: field, op: "=", value: value, fold: true})
+	return c
+}
+
+// Or matches documents that satisfy either the conditions added so far or
+// all the conditions fn adds to the query it is given:
+//
+//	users.Where("status", "=", "active").Or(func(q *documents.Query[User]) *documents.Query[User] {
+//		return q.Where("status", "=", "pending")
+//	})
+//
+// compiles to (status = 'active' OR status = 'pending'). Conditions added
+// after Or are ANDed with the whole disjunction; use WhereGroup to OR within
+// a narrower group. Only the conditions of the query fn returns are used, not
+// its ordering or paging. On a query without conditions, Or is WhereGroup.
+func (q *Query[T]) Or(fn func(*Query[T]) *Query[T]) *Query[T] {
+	group := fn(q.sub()).conditions
+	c := q.clone()
+	switch {
+	case len(q.conditions) == 0:
+		c.conditions = []condition{{anyOf: [][]condition{group}}}
+	case len(q.conditions) == 1 && len(q.conditions[0].anyOf) > 1:
+		// extend a previous Or instead of nesting it
+		prev := q.conditions[0].anyOf
+		anyOf := make([][]condition, len(prev), len(prev)+1)
+		copy(anyOf, prev)
+		c.conditions = []condition{{anyOf: append(anyOf, group)}}
+	default:
+		c.conditions = []condition{{anyOf: [][]condition{q.conditions, group}}}
+	}
+	return c
+}
+
+// OrWhere is Or with a single condition.
+func (q *Query[T]) OrWhere(field, op string, value any) *Query[T] {
+	return q.Or(func(g *Query[T]) *Query[T] { return g.Where(field, op, value) })
+}
+
+// WhereGroup adds the conditions fn adds to the query it is given as one
+// parenthesized condition, ANDed with the others. Use it to scope an Or:
+//
+//	orders.Where("tenant", "=", tenant).WhereGroup(func(q *documents.Query[Order]) *documents.Query[Order] {
+//		return q.Where("status", "=", "paid").OrWhere("total", "=", 0)
+//	})
+//
+// compiles to tenant = $1 AND (status = $2 OR total = $3).
+func (q *Query[T]) WhereGroup(fn func(*Query[T]) *Query[T]) *Query[T] {
+	group := fn(q.sub()).conditions
+	c := q.clone()
+	c.conditions = append(c.conditions, condition{anyOf: [][]condition{group}})
+	return c
+}
+
+// sub returns an empty query on the same collection for building a group.
+func (q *Query[T]) sub() *Query[T] {
+	return &Query[T]{
+		name:    q.name,
+		table:   q.table,
+		exec:    q.exec,
+		codec:   q.codec,
+		schema:  q.schema,
+		indexes: q.indexes,
+		columns: q.columns,
+		col:     q.col,
+	}
+}
+
+// WhereTextSearch adds a full-text condition: the field's text must match
+// every word of query, compiled to
+// to_tsvector(config, field) @@ plainto_tsquery(config, $n). The text search
+// configuration is the one of the field's whisker:"index,fts" tag, so that
+// the index applies, or "simple" when the field has no such index.
+func (q *Query[T]) WhereTextSearch(field, query string) *Query[T] {
+	c := q.clone()
+	c.conditions = append(c.conditions, condition{field: field, value: query, textSearch: true})
+	return c
+}
+
+// DefaultSimilarityThreshold is pg_trgm's default similarity threshold, the
+// lowest Similar threshold a whisker:"index,trgm" index serves.
+const DefaultSimilarityThreshold = 0.3
+
+// Similar adds a fuzzy match: the trigram similarity of the field's text to
+// term, between 0 and 1, must be at least threshold. It finds names and
+// emails despite typos, and needs the pg_trgm extension, which a
+// whisker:"index,trgm" tag on the field creates along with its index:
+//
+//	users.Query().Similar("name", "jonh smith", 0.4).Execute(ctx)
+//
+// The index serves thresholds of at least DefaultSimilarityThreshold, and
+// assumes the database leaves pg_trgm.similarity_threshold at that default;
+// lower thresholds scan the table.
+func (q *Query[T]) Similar(field, term string, threshold float64) *Query[T] {
+	c := q.clone()
+	c.conditions = append(c.conditions, condition{field: field, value: term, similar: true, threshold: threshold})
 	return c
 }
 
+// Similar starts a query with a trigram similarity condition. See
+// Query.Similar.
+func (c *CollectionOf[T]) Similar(field, term string, threshold float64) *Query[T] {
+	return c.Query().Similar(field, term, threshold)
+}
+
+// similarCondition compiles a Similar condition. Only the % operator can use
+// a trigram index, so thresholds it covers pair it with the exact test.
+func similarCondition(field string, term any, threshold float64) (sq.Sqlizer, error) {
+	if threshold <= 0 || threshold > 1 {
+		return nil, fmt.Errorf("query: similarity threshold must be in (0, 1], got %v", threshold)
+	}
+	if threshold >= DefaultSimilarityThreshold {
+		return sq.Expr(fmt.Sprintf("(%s %% ? AND similarity(%s, ?) >= ?)", field, field), term, term, threshold), nil
+	}
+	return sq.Expr(fmt.Sprintf("similarity(%s, ?) >= ?", field), term, threshold), nil
+}
+
+// textSearchConfig returns the text search configuration of the field's
+// full-text index, or the default.
+func (q *Query[T]) textSearchConfig(field string) string {
+	for _, idx := range q.indexes {
+		if idx.Type == meta.IndexFTS && idx.FieldJSONKey == field {
+			return idx.TextSearchConfig
+		}
+	}
+	return meta.DefaultTextSearchConfig
+}
+
 // OrderBy adds a sort clause. Multiple calls add secondary sort keys.
 func (q *Query[T]) OrderBy(field string, dir Direction) *Query[T] {
 	c := q.clone()
@@ -154,31 +480,206 @@ func (q *Query[T]) After(value any) *Query[T] {
 	return c
 }
 
+// FetchSize sets how many documents Cursor and Iterate fetch per round trip.
+// Defaults to 500. Execute is not affected.
+func (q *Query[T]) FetchSize(n int) *Query[T] {
+	c := q.clone()
+	c.fetchSize = n
+	return c
+}
+
+// Deleted sets the tombstone filter. Tombstones are written by projections in
+// soft-delete mode; plain collection deletes remove rows outright.
+func (q *Query[T]) Deleted(f DeletedFilter) *Query[T] {
+	c := q.clone()
+	c.deleted = f
+	return c
+}
+
 func (q *Query[T]) applyConditions(builder sq.SelectBuilder) (sq.SelectBuilder, error) {
+	preds, err := q.predicates()
+	if err != nil {
+		return builder, err
+	}
+	for _, p := range preds {
+		builder = builder.Where(p)
+	}
+	return builder, nil
+}
+
+// predicates compiles the tombstone filter and the conditions, to be ANDed
+// in a WHERE clause.
+func (q *Query[T]) predicates() ([]sq.Sqlizer, error) {
+	var preds []sq.Sqlizer
+	switch q.deleted {
+	case ExcludeDeleted:
+		preds = append(preds, sq.Expr("deleted_at IS NULL"))
+	case OnlyDeleted:
+		preds = append(preds, sq.Expr("deleted_at IS NOT NULL"))
+	}
+	if q.tenant != "" {
+		preds = append(preds, sq.Eq{"tenant_id": q.tenant})
+	}
 	for _, c := range q.conditions {
-		if !allowedOps[c.op] {
-			return builder, fmt.Errorf("query: unsupported operator %q", c.op)
+		expr, err := q.conditionSQL(c)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, expr)
+	}
+	return preds, nil
+}
+
+// conditionSQL compiles one condition, recursing into groups.
+func (q *Query[T]) conditionSQL(c condition) (sq.Sqlizer, error) {
+	if c.anyOf != nil {
+		or := make(sq.Or, 0, len(c.anyOf))
+		for _, alt := range c.anyOf {
+			and := make(sq.And, 0, len(alt))
+			for _, ac := range alt {
+				expr, err := q.conditionSQL(ac)
+				if err != nil {
+					return nil, err
+				}
+				and = append(and, expr)
+			}
+			if len(and) == 1 {
+				or = append(or, and[0])
+				continue
+			}
+			or = append(or, and)
+		}
+		if len(or) == 1 {
+			return or[0], nil
+		}
+		return or, nil
+	}
+
+	if c.textSearch {
+		field, err := q.resolve(c.field)
+		if err != nil {
+			return nil, err
 		}
-		field, err := resolveField(c.field)
+		config := q.textSearchConfig(c.field)
+		return sq.Expr(fmt.Sprintf("%s @@ plainto_tsquery('%s', ?)", indexes.TextSearchVector(config, field), config), c.value), nil
+	}
+
+	if c.similar {
+		field, err := q.resolve(c.field)
 		if err != nil {
-			return builder, err
+			return nil, err
 		}
-		expr := fmt.Sprintf("%s %s ?", field, c.op)
-		builder = builder.Where(sq.Expr(expr, c.value))
+		return similarCondition(field, c.value, c.threshold)
 	}
-	return builder, nil
+
+	if !allowedOps[c.op] && !listOps[c.op] && !patternOps[c.op] {
+		return nil, fmt.Errorf("query: unsupported operator %q", c.op)
+	}
+	resolve := q.resolveTyped
+	if c.fold || patternOps[c.op] {
+		resolve = q.resolve
+	}
+	field, err := resolve(c.field)
+	if err != nil {
+		return nil, err
+	}
+	fixed := field == c.field
+	if listOps[c.op] {
+		value := c.value
+		if fixed {
+			if value, err = columnListValue(field, value); err != nil {
+				return nil, err
+			}
+		}
+		return listCondition(field, c.op, value)
+	}
+	value, err := q.conditionValue(c, field)
+	if err != nil {
+		return nil, err
+	}
+	expr := fmt.Sprintf("%s %s ?", field, c.op)
+	if c.fold {
+		expr = fmt.Sprintf("lower(%s) %s lower(?)", field, c.op)
+	}
+	return sq.Expr(expr, value), nil
 }
 
-func (q *Query[T]) ensureTable(ctx context.Context) error {
-	col := &CollectionOf[T]{
+// conditionValue returns the value c binds, typed for the fixed column its
+// field resolved to, if any.
+func (q *Query[T]) conditionValue(c condition, field string) (any, error) {
+	if field != c.field {
+		return c.value, nil
+	}
+	if c.fold {
+		if err := textOnly(field, "case-insensitive matching"); err != nil {
+			return nil, err
+		}
+	}
+	if patternOps[c.op] {
+		if err := textOnly(field, c.op); err != nil {
+			return nil, err
+		}
+	}
+	return columnValue(field, c.value)
+}
+
+// listCondition compiles an IN or NOT IN condition, binding each element of
+// value as a parameter.
+func listCondition(field, op string, value any) (sq.Sqlizer, error) {
+	v := reflect.ValueOf(value)
+	if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, fmt.Errorf("query: %s on %s requires a slice value, got %T", op, field, value)
+	}
+	if v.Len() == 0 {
+		if op == "IN" {
+			return sq.Expr("FALSE"), nil
+		}
+		return sq.Expr("TRUE"), nil
+	}
+	args := make([]any, v.Len())
+	for i := range args {
+		args[i] = v.Index(i).Interface()
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")
+	return sq.Expr(fmt.Sprintf("%s %s (%s)", field, op, placeholders), args...), nil
+}
+
+// collection returns the collection the query runs on.
+func (q *Query[T]) collection() *CollectionOf[T] {
+	if q.col != nil {
+		return q.col
+	}
+	return &CollectionOf[T]{
 		name:    q.name,
 		table:   q.table,
 		exec:    q.exec,
 		codec:   q.codec,
 		schema:  q.schema,
 		indexes: q.indexes,
+		columns: q.columns,
+	}
+}
+
+func (q *Query[T]) ensureTable(ctx context.Context) error {
+	if err := q.collection().ensure(ctx); err != nil {
+		return err
+	}
+	if q.deleted != IncludeDeleted {
+		return q.schema.EnsureDeletedAt(ctx, q.exec, q.name)
+	}
+	return nil
+}
+
+// scoped returns the query restricted to ctx's tenant when the collection
+// was created WithTenancy.
+func (q *Query[T]) scoped(ctx context.Context) (*Query[T], error) {
+	tenant, err := q.collection().tenant(ctx, "query")
+	if err != nil || tenant == "" {
+		return q, err
 	}
-	return col.ensure(ctx)
+	c := q.clone()
+	c.tenant = tenant
+	return c, nil
 }
 
 func (q *Query[T]) toCountSQL() (string, []any, error) {
@@ -208,6 +709,10 @@ func (q *Query[T]) Count(ctx context.Context) (int64, error) {
 	if err := q.ensureTable(ctx); err != nil {
 		return 0, err
 	}
+	q, err := q.scoped(ctx)
+	if err != nil {
+		return 0, err
+	}
 	sql, args, err := q.toCountSQL()
 	if err != nil {
 		return 0, err
@@ -225,6 +730,10 @@ func (q *Query[T]) Exists(ctx context.Context) (bool, error) {
 	if err := q.ensureTable(ctx); err != nil {
 		return false, err
 	}
+	q, err := q.scoped(ctx)
+	if err != nil {
+		return false, err
+	}
 	sql, args, err := q.toExistsSQL()
 	if err != nil {
 		return false, err
@@ -238,7 +747,30 @@ func (q *Query[T]) Exists(ctx context.Context) (bool, error) {
 }
 
 func (q *Query[T]) toSQL() (string, []any, error) {
-	builder := psql.Select("id", "data", "version").From(q.table)
+	if len(q.selects) > 0 {
+		return "", nil, fmt.Errorf("query: Select requires ExecuteInto")
+	}
+	return q.selectSQL(withSchemaVersion(migrationsFor[T](), "id", "data", "version")...)
+}
+
+// selectSQL builds the query selecting columns, with its conditions,
+// ordering and pagination.
+func (q *Query[T]) selectSQL(columns ...string) (string, []any, error) {
+	if sql, args, ok, err := q.fastSelectSQL(columns); ok {
+		return sql, args, err
+	}
+	return q.buildSelectSQL(columns...)
+}
+
+// buildSelectSQL is selectSQL for any query, with the squirrel builder.
+func (q *Query[T]) buildSelectSQL(columns ...string) (string, []any, error) {
+	if q.cursor != "" {
+		if q.afterVal != nil {
+			return "", nil, fmt.Errorf("query: After and AfterCursor cannot be combined")
+		}
+		q = q.withTiebreaker()
+	}
+	builder := psql.Select(columns...).From(q.table)
 
 	var err error
 	builder, err = q.applyConditions(builder)
@@ -246,12 +778,19 @@ func (q *Query[T]) toSQL() (string, []any, error) {
 		return "", nil, err
 	}
 
+	if q.cursor != "" {
+		cond, err := q.cursorCondition()
+		if err != nil {
+			return "", nil, err
+		}
+		builder = builder.Where(cond)
+	}
 	if q.afterVal != nil {
 		if len(q.orderBys) == 0 {
 			return "", nil, fmt.Errorf("query: After requires at least one OrderBy clause")
 		}
 		ob := q.orderBys[0]
-		field, err := resolveField(ob.field)
+		field, err := q.resolveTyped(ob.field)
 		if err != nil {
 			return "", nil, err
 		}
@@ -265,7 +804,7 @@ func (q *Query[T]) toSQL() (string, []any, error) {
 	if len(q.orderBys) > 0 {
 		clauses := make([]string, len(q.orderBys))
 		for i, ob := range q.orderBys {
-			field, err := resolveField(ob.field)
+			field, err := q.resolveTyped(ob.field)
 			if err != nil {
 				return "", nil, err
 			}
@@ -286,15 +825,11 @@ func (q *Query[T]) toSQL() (string, []any, error) {
 
 // Execute runs the query and returns matching documents.
 func (q *Query[T]) Execute(ctx context.Context) ([]*T, error) {
-	col := &CollectionOf[T]{
-		name:    q.name,
-		table:   q.table,
-		exec:    q.exec,
-		codec:   q.codec,
-		schema:  q.schema,
-		indexes: q.indexes,
+	if err := q.ensureTable(ctx); err != nil {
+		return nil, err
 	}
-	if err := col.ensure(ctx); err != nil {
+	q, err := q.scoped(ctx)
+	if err != nil {
 		return nil, err
 	}
 
@@ -307,24 +842,46 @@ func (q *Query[T]) Execute(ctx context.Context) ([]*T, error) {
 	if err != nil {
 		return nil, fmt.Errorf("query: execute: %w", err)
 	}
+	return scanDocs[T](rows, q.codec)
+}
+
+// scanDocs reads every row of a query selecting id, data, version and, when
+// T has migrations, schema_version, and closes rows.
+func scanDocs[T any](rows pgx.Rows, codec codecs.Codec) ([]*T, error) {
+	return scanDocsWith[T](rows, codec, nil)
+}
+
+// scanDocsWith is scanDocs for queries selecting more columns after the
+// document's, scanned into the destinations extra returns for each row.
+func scanDocsWith[T any](rows pgx.Rows, codec codecs.Codec, extra func() []any) ([]*T, error) {
 	defer rows.Close()
 
+	chain := migrationsFor[T]()
+	scanner := newDocScanner[T](codec, chain)
+	access := meta.AccessorOf[T]()
 	var results []*T
 	for rows.Next() {
 		var id string
-		var data []byte
 		var version int
-		if err := rows.Scan(&id, &data, &version); err != nil {
+		schemaVersion := 1
+		dest := []any{&id, scanner.target(), &version}
+		if chain != nil {
+			dest = append(dest, &schemaVersion)
+		}
+		if extra != nil {
+			dest = append(dest, extra()...)
+		}
+		if err := rows.Scan(dest...); err != nil {
 			return nil, fmt.Errorf("query: scan: %w", err)
 		}
 
-		var doc T
-		if err := q.codec.Unmarshal(data, &doc); err != nil {
+		doc, err := scanner.decode(id, schemaVersion)
+		if err != nil {
 			return nil, fmt.Errorf("query: unmarshal: %w", err)
 		}
-		meta.SetID(&doc, id)
-		meta.SetVersion(&doc, version)
-		results = append(results, &doc)
+		access.SetID(doc, id)
+		access.SetVersion(doc, version)
+		results = append(results, doc)
 	}
 
 	return results, rows.Err()
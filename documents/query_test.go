@@ -1,6 +1,12 @@
 package documents
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
 
 type testDoc struct {
 	ID      string
@@ -74,6 +80,8 @@ func TestResolveField(t *testing.T) {
 		{name: "raw jsonb expression", field: "data->'addr'->>'city'", want: "data->'addr'->>'city'"},
 		{name: "empty field", field: "", wantErr: true},
 		{name: "invalid characters", field: "name'; DROP", wantErr: true},
+		{name: "raw expression injection", field: "data->>'x'; DROP TABLE whisker_users; --", wantErr: true},
+		{name: "arrow outside data column", field: "version->'x'", wantErr: true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -388,3 +396,417 @@ func TestQuery_InvalidOperator(t *testing.T) {
 		t.Fatal("expected error for invalid operator")
 	}
 }
+
+func TestQuery_DeletedFilterSQL(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  DeletedFilter
+		wantSQL string
+	}{
+		{
+			name:    "include deleted",
+			filter:  IncludeDeleted,
+			wantSQL: "SELECT id, data, version FROM whisker_users WHERE data->>'name' = $1",
+		},
+		{
+			name:    "exclude deleted",
+			filter:  ExcludeDeleted,
+			wantSQL: "SELECT id, data, version FROM whisker_users WHERE deleted_at IS NULL AND data->>'name' = $1",
+		},
+		{
+			name:    "only deleted",
+			filter:  OnlyDeleted,
+			wantSQL: "SELECT id, data, version FROM whisker_users WHERE deleted_at IS NOT NULL AND data->>'name' = $1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &Query[testDoc]{table: "whisker_users"}
+			q = q.Where("name", "=", "Alice").Deleted(tt.filter)
+			gotSQL, _, err := q.toSQL()
+			if err != nil {
+				t.Fatalf("toSQL: %v", err)
+			}
+			if gotSQL != tt.wantSQL {
+				t.Errorf("sql:\n got: %s\nwant: %s", gotSQL, tt.wantSQL)
+			}
+		})
+	}
+}
+
+func TestQuery_GeneratedColumnPreferred(t *testing.T) {
+	q := &Query[testDoc]{
+		table:   "whisker_users",
+		columns: []meta.ColumnMeta{{FieldJSONKey: "email", Name: "email", SQLType: "TEXT"}},
+	}
+	q = q.Where("email", "=", "a@b.c").Where("name", "=", "Alice").OrderBy("email", Asc)
+
+	gotSQL, _, err := q.toSQL()
+	if err != nil {
+		t.Fatalf("toSQL: %v", err)
+	}
+	want := "SELECT id, data, version FROM whisker_users WHERE email = $1 AND data->>'name' = $2 ORDER BY email ASC"
+	if gotSQL != want {
+		t.Errorf("sql:\n got: %s\nwant: %s", gotSQL, want)
+	}
+}
+
+func TestQuery_WhereFoldSQL(t *testing.T) {
+	q := &Query[testDoc]{table: "whisker_users"}
+	q = q.WhereFold("email", "Alice@Example.com")
+
+	gotSQL, gotArgs, err := q.toSQL()
+	if err != nil {
+		t.Fatalf("toSQL: %v", err)
+	}
+	want := "SELECT id, data, version FROM whisker_users WHERE lower(data->>'email') = lower($1)"
+	if gotSQL != want {
+		t.Errorf("sql:\n got: %s\nwant: %s", gotSQL, want)
+	}
+	if len(gotArgs) != 1 || gotArgs[0] != "Alice@Example.com" {
+		t.Errorf("args: got %v", gotArgs)
+	}
+}
+
+func TestQuery_OrSQL(t *testing.T) {
+	status := func(v string) func(*Query[testDoc]) *Query[testDoc] {
+		return func(q *Query[testDoc]) *Query[testDoc] { return q.Where("status", "=", v) }
+	}
+	tests := []struct {
+		name     string
+		build    func(q *Query[testDoc]) *Query[testDoc]
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name: "or",
+			build: func(q *Query[testDoc]) *Query[testDoc] {
+				return q.Where("status", "=", "active").Or(status("pending"))
+			},
+			wantSQL:  "SELECT id, data, version FROM whisker_users WHERE (data->>'status' = $1 OR data->>'status' = $2)",
+			wantArgs: []any{"active", "pending"},
+		},
+		{
+			name: "chained or stays flat",
+			build: func(q *Query[testDoc]) *Query[testDoc] {
+				return q.Where("status", "=", "active").OrWhere("status", "=", "pending").OrWhere("status", "=", "trial")
+			},
+			wantSQL:  "SELECT id, data, version FROM whisker_users WHERE (data->>'status' = $1 OR data->>'status' = $2 OR data->>'status' = $3)",
+			wantArgs: []any{"active", "pending", "trial"},
+		},
+		{
+			name: "or groups anded conditions",
+			build: func(q *Query[testDoc]) *Query[testDoc] {
+				return q.Where("status", "=", "active").Where("plan", "=", "pro").Or(status("trial"))
+			},
+			wantSQL:  "SELECT id, data, version FROM whisker_users WHERE ((data->>'status' = $1 AND data->>'plan' = $2) OR data->>'status' = $3)",
+			wantArgs: []any{"active", "pro", "trial"},
+		},
+		{
+			name: "where after or",
+			build: func(q *Query[testDoc]) *Query[testDoc] {
+				return q.Where("status", "=", "active").Or(status("pending")).Where("name", "=", "Alice")
+			},
+			wantSQL:  "SELECT id, data, version FROM whisker_users WHERE (data->>'status' = $1 OR data->>'status' = $2) AND data->>'name' = $3",
+			wantArgs: []any{"active", "pending", "Alice"},
+		},
+		{
+			name: "where group",
+			build: func(q *Query[testDoc]) *Query[testDoc] {
+				return q.Where("tenant", "=", "acme").WhereGroup(func(g *Query[testDoc]) *Query[testDoc] {
+					return g.Where("status", "=", "paid").OrWhere("total", "=", 0)
+				})
+			},
+			wantSQL:  "SELECT id, data, version FROM whisker_users WHERE data->>'tenant' = $1 AND (data->>'status' = $2 OR data->>'total' = $3)",
+			wantArgs: []any{"acme", "paid", 0},
+		},
+		{
+			name:     "or without prior conditions",
+			build:    func(q *Query[testDoc]) *Query[testDoc] { return q.Or(status("pending")) },
+			wantSQL:  "SELECT id, data, version FROM whisker_users WHERE data->>'status' = $1",
+			wantArgs: []any{"pending"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.build(&Query[testDoc]{table: "whisker_users"})
+			gotSQL, gotArgs, err := q.toSQL()
+			if err != nil {
+				t.Fatalf("toSQL: %v", err)
+			}
+			if gotSQL != tt.wantSQL {
+				t.Errorf("sql:\n got: %s\nwant: %s", gotSQL, tt.wantSQL)
+			}
+			if len(gotArgs) != len(tt.wantArgs) {
+				t.Fatalf("args: got %v, want %v", gotArgs, tt.wantArgs)
+			}
+			for i := range gotArgs {
+				if gotArgs[i] != tt.wantArgs[i] {
+					t.Errorf("arg %d: got %v, want %v", i, gotArgs[i], tt.wantArgs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestQuery_InSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       string
+		value    any
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "in strings",
+			op:       "IN",
+			value:    []string{"active", "trial"},
+			wantSQL:  "SELECT id, data, version FROM whisker_users WHERE data->>'status' IN ($1,$2) AND data->>'name' = $3",
+			wantArgs: []any{"active", "trial", "Alice"},
+		},
+		{
+			name:     "not in array",
+			op:       "NOT IN",
+			value:    [1]int{3},
+			wantSQL:  "SELECT id, data, version FROM whisker_users WHERE data->>'status' NOT IN ($1) AND data->>'name' = $2",
+			wantArgs: []any{3, "Alice"},
+		},
+		{
+			name:     "empty in",
+			op:       "IN",
+			value:    []string{},
+			wantSQL:  "SELECT id, data, version FROM whisker_users WHERE FALSE AND data->>'name' = $1",
+			wantArgs: []any{"Alice"},
+		},
+		{
+			name:     "empty not in",
+			op:       "NOT IN",
+			value:    []any(nil),
+			wantSQL:  "SELECT id, data, version FROM whisker_users WHERE TRUE AND data->>'name' = $1",
+			wantArgs: []any{"Alice"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := (&Query[testDoc]{table: "whisker_users"}).Where("status", tt.op, tt.value).Where("name", "=", "Alice")
+			gotSQL, gotArgs, err := q.toSQL()
+			if err != nil {
+				t.Fatalf("toSQL: %v", err)
+			}
+			if gotSQL != tt.wantSQL {
+				t.Errorf("sql:\n got: %s\nwant: %s", gotSQL, tt.wantSQL)
+			}
+			if len(gotArgs) != len(tt.wantArgs) {
+				t.Fatalf("args: got %v, want %v", gotArgs, tt.wantArgs)
+			}
+			for i := range gotArgs {
+				if gotArgs[i] != tt.wantArgs[i] {
+					t.Errorf("arg %d: got %v, want %v", i, gotArgs[i], tt.wantArgs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestQuery_LikeSQL(t *testing.T) {
+	for _, op := range []string{"LIKE", "NOT LIKE", "ILIKE", "NOT ILIKE"} {
+		q := (&Query[testDoc]{table: "whisker_users"}).Where("email", op, "%@example.com")
+		gotSQL, gotArgs, err := q.toSQL()
+		if err != nil {
+			t.Fatalf("%s: toSQL: %v", op, err)
+		}
+		want := "SELECT id, data, version FROM whisker_users WHERE data->>'email' " + op + " $1"
+		if gotSQL != want {
+			t.Errorf("sql:\n got: %s\nwant: %s", gotSQL, want)
+		}
+		if len(gotArgs) != 1 || gotArgs[0] != "%@example.com" {
+			t.Errorf("%s: args: got %v", op, gotArgs)
+		}
+	}
+}
+
+func TestQuery_WhereTextSearchSQL(t *testing.T) {
+	q := &Query[testDoc]{
+		table:   "whisker_posts",
+		indexes: []meta.IndexMeta{{FieldJSONKey: "title", Type: meta.IndexFTS, TextSearchConfig: "english"}},
+	}
+	q = q.WhereTextSearch("title", "fast cars").WhereTextSearch("body", "red")
+
+	gotSQL, gotArgs, err := q.toSQL()
+	if err != nil {
+		t.Fatalf("toSQL: %v", err)
+	}
+	want := "SELECT id, data, version FROM whisker_posts WHERE to_tsvector('english', data->>'title') @@ plainto_tsquery('english', $1)" +
+		" AND to_tsvector('simple', data->>'body') @@ plainto_tsquery('simple', $2)"
+	if gotSQL != want {
+		t.Errorf("sql:\n got: %s\nwant: %s", gotSQL, want)
+	}
+	if len(gotArgs) != 2 || gotArgs[0] != "fast cars" || gotArgs[1] != "red" {
+		t.Errorf("args: got %v", gotArgs)
+	}
+}
+
+func TestQuery_SimilarSQL(t *testing.T) {
+	q := (&Query[testDoc]{table: "whisker_users"}).Similar("name", "jonh", 0.4).Similar("email", "jon@", 0.1)
+
+	gotSQL, gotArgs, err := q.toSQL()
+	if err != nil {
+		t.Fatalf("toSQL: %v", err)
+	}
+	want := "SELECT id, data, version FROM whisker_users WHERE (data->>'name' % $1 AND similarity(data->>'name', $2) >= $3)" +
+		" AND similarity(data->>'email', $4) >= $5"
+	if gotSQL != want {
+		t.Errorf("sql:\n got: %s\nwant: %s", gotSQL, want)
+	}
+	wantArgs := []any{"jonh", "jonh", 0.4, "jon@", 0.1}
+	if len(gotArgs) != len(wantArgs) {
+		t.Fatalf("args: got %v, want %v", gotArgs, wantArgs)
+	}
+	for i := range wantArgs {
+		if gotArgs[i] != wantArgs[i] {
+			t.Errorf("arg[%d]: got %v, want %v", i, gotArgs[i], wantArgs[i])
+		}
+	}
+
+	for _, threshold := range []float64{0, -0.5, 1.5} {
+		if _, _, err := (&Query[testDoc]{table: "whisker_users"}).Similar("name", "x", threshold).toSQL(); err == nil {
+			t.Errorf("expected error for threshold %v", threshold)
+		}
+	}
+}
+
+func TestQuery_InRequiresSlice(t *testing.T) {
+	for _, v := range []any{"active", []byte("active"), nil} {
+		q := (&Query[testDoc]{table: "whisker_users"}).Where("status", "IN", v)
+		if _, _, err := q.toSQL(); err == nil {
+			t.Errorf("expected error for IN value %#v", v)
+		}
+	}
+}
+
+func TestQuery_OrDoesNotMutateBase(t *testing.T) {
+	base := (&Query[testDoc]{table: "whisker_users"}).Where("status", "=", "active").OrWhere("status", "=", "pending")
+	_ = base.OrWhere("status", "=", "trial")
+
+	gotSQL, _, err := base.toSQL()
+	if err != nil {
+		t.Fatalf("toSQL: %v", err)
+	}
+	if strings.Count(gotSQL, "OR") != 1 {
+		t.Errorf("base query changed: %s", gotSQL)
+	}
+}
+
+func TestQuery_OrInvalidOperator(t *testing.T) {
+	q := (&Query[testDoc]{table: "whisker_users"}).Where("status", "=", "active").OrWhere("name", "DROP TABLE", "x")
+	if _, _, err := q.toSQL(); err == nil {
+		t.Fatal("expected error for invalid operator in group")
+	}
+}
+
+func TestTenantPolicy(t *testing.T) {
+	got := TenantPolicy("tenantId")
+	want := "data->>'tenantId' = current_setting('whisker.tenant_id', true)"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func FuzzResolveField(f *testing.F) {
+	for _, seed := range []string{"name", "id", "data->'addr'->>'city'", "name'; DROP", "data->>'a''b'", "data->0"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, field string) {
+		got, err := resolveField(field)
+		if err != nil {
+			return
+		}
+		rest := stripLiterals(t, got)
+		if strings.Contains(rest, "--") || strings.Trim(rest, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_->") != "" {
+			t.Fatalf("resolveField(%q) = %q has SQL outside its literals", field, got)
+		}
+	})
+}
+
+// stripLiterals removes single-quoted SQL literals from expr and fails if a
+// literal is left unterminated.
+func stripLiterals(t *testing.T, expr string) string {
+	var b strings.Builder
+	in := false
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case !in && c == '\'':
+			in = true
+		case in && c == '\'' && i+1 < len(expr) && expr[i+1] == '\'':
+			i++
+		case in && c == '\'':
+			in = false
+		case !in:
+			b.WriteByte(c)
+		}
+	}
+	if in {
+		t.Fatalf("unterminated literal in %q", expr)
+	}
+	return b.String()
+}
+
+func TestQuery_ColumnComparisonsAreTyped(t *testing.T) {
+	since := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	q := (&Query[testDoc]{table: "whisker_users"}).ModifiedSince(since).Where("created_at", "<=", "2026-05-02T00:00:00Z").Where("version", ">=", 2)
+	gotSQL, gotArgs, err := q.toSQL()
+	if err != nil {
+		t.Fatalf("toSQL: %v", err)
+	}
+	want := "SELECT id, data, version FROM whisker_users WHERE updated_at > $1 AND created_at <= $2 AND version >= $3"
+	if gotSQL != want {
+		t.Errorf("sql:\n got: %s\nwant: %s", gotSQL, want)
+	}
+	wantArgs := []any{since, since.Add(12 * time.Hour), 2}
+	if len(gotArgs) != len(wantArgs) {
+		t.Fatalf("args: got %v, want %v", gotArgs, wantArgs)
+	}
+	for i := range wantArgs {
+		if gotArgs[i] != wantArgs[i] {
+			t.Errorf("arg[%d]: got %#v, want %#v", i, gotArgs[i], wantArgs[i])
+		}
+	}
+
+	fast, fastArgs, ok, err := (&Query[testDoc]{table: "whisker_users"}).Where("updated_at", ">", "2026-05-01T12:00:00Z").fastSelectSQL([]string{"id"})
+	if err != nil || !ok {
+		t.Fatalf("fast path: ok %v, err %v", ok, err)
+	}
+	if fast != "SELECT id FROM whisker_users WHERE updated_at > $1" || fastArgs[0] != since {
+		t.Errorf("fast path: got %s %v", fast, fastArgs)
+	}
+
+	in := (&Query[testDoc]{table: "whisker_users"}).Where("created_at", "IN", []string{"2026-05-01T12:00:00Z"})
+	if _, inArgs, err := in.toSQL(); err != nil || inArgs[0] != since {
+		t.Errorf("IN: got %v, %v", inArgs, err)
+	}
+}
+
+func TestQuery_ColumnComparisonsRejectMistypedValues(t *testing.T) {
+	tests := []struct {
+		field, op string
+		value     any
+	}{
+		{"updated_at", ">", 1714564800},
+		{"updated_at", ">", "yesterday"},
+		{"created_at", "IN", []int{1}},
+		{"version", ">", "2"},
+		{"version", "=", 1.5},
+		{"updated_at", "LIKE", "2026-%"},
+	}
+	for _, tt := range tests {
+		q := (&Query[testDoc]{table: "whisker_users"}).Where(tt.field, tt.op, tt.value)
+		if _, _, err := q.toSQL(); err == nil {
+			t.Errorf("%s %s %#v: expected error", tt.field, tt.op, tt.value)
+		}
+	}
+	if _, _, err := (&Query[testDoc]{table: "whisker_users"}).WhereFold("version", 1).toSQL(); err == nil {
+		t.Error("expected error for WhereFold on version")
+	}
+}
@@ -0,0 +1,72 @@
+package documents
+
+import (
+	"errors"
+
+	"github.com/ripkitten-co/whisker/internal/codecs"
+)
+
+// docScanner is the scan destination for a document's data column. It
+// implements pgtype.BytesScanner, so pgx hands it the JSONB straight from
+// the connection's read buffer and the document is decoded in place, without
+// first copying the bytes into a []byte of its own.
+//
+// Documents of types with migrations still go through a []byte: the
+// migration chain needs the schema_version column, which is scanned after
+// data, before it can decode. So do documents with encrypted fields, which
+// need the document's ID.
+type docScanner[T any] struct {
+	codec codecs.Codec
+	chain *migrationChain
+	doc   *T
+	data  []byte
+	err   error
+}
+
+func newDocScanner[T any](codec codecs.Codec, chain *migrationChain) *docScanner[T] {
+	return &docScanner[T]{codec: codec, chain: chain}
+}
+
+// target readies the scanner for the next row and returns the destination
+// to pass to Scan for the data column.
+func (s *docScanner[T]) target() any {
+	s.doc, s.data, s.err = new(T), nil, nil
+	if s.buffered() {
+		return &s.data
+	}
+	return s
+}
+
+// buffered reports whether the data column is scanned into a []byte and
+// decoded by decode rather than in place.
+func (s *docScanner[T]) buffered() bool {
+	_, encrypted := s.codec.(*encryptingCodec)
+	return s.chain != nil || encrypted
+}
+
+// ScanBytes decodes src, which is only valid during the call. A decoding
+// error is kept for decode rather than returned, so it is reported as an
+// unmarshal error and not as a scan error.
+func (s *docScanner[T]) ScanBytes(src []byte) error {
+	if src == nil {
+		s.err = errors.New("document data is null")
+		return nil
+	}
+	s.err = s.codec.Unmarshal(src, s.doc)
+	return nil
+}
+
+// decode returns the document id scanned for the current row, migrating it
+// from schemaVersion first when the type has migrations.
+func (s *docScanner[T]) decode(id string, schemaVersion int) (*T, error) {
+	if s.buffered() {
+		if err := decodeDoc(s.codec, s.chain, id, s.data, schemaVersion, s.doc); err != nil {
+			return nil, err
+		}
+		return s.doc, nil
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.doc, nil
+}
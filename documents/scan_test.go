@@ -0,0 +1,96 @@
+package documents
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/internal/codecs"
+)
+
+type scanDoc struct {
+	ID    string
+	Name  string
+	Email string
+}
+
+func TestDocScanner_DecodesInPlace(t *testing.T) {
+	s := newDocScanner[scanDoc](codecs.NewWhisker(codecs.NewJSONIter()), nil)
+	buf := []byte(`{"name":"Alice","email":"alice@test.com"}`)
+	if _, ok := s.target().(*docScanner[scanDoc]); !ok {
+		t.Fatal("expected the scanner itself as the data destination")
+	}
+	if err := s.ScanBytes(buf); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	// the driver reuses its buffer once the row is scanned
+	copy(buf, `{"name":"Zzzzz"`)
+
+	doc, err := s.decode("d1", 1)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc.Name != "Alice" || doc.Email != "alice@test.com" {
+		t.Errorf("got %+v", doc)
+	}
+
+	s.target()
+	if err := s.ScanBytes([]byte(`{"name":`)); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if _, err := s.decode("d1", 1); err == nil {
+		t.Error("expected the unmarshal error from decode")
+	}
+}
+
+func TestDocScanner_MigratesThroughBytes(t *testing.T) {
+	chain := &migrationChain{latest: 2, steps: map[int]migrationStep{
+		1: {to: 2, fn: func(json.RawMessage) (json.RawMessage, error) {
+			return json.RawMessage(`{"name":"migrated"}`), nil
+		}},
+	}}
+	s := newDocScanner[scanDoc](codecs.NewWhisker(codecs.NewJSONIter()), chain)
+	dst, ok := s.target().(*[]byte)
+	if !ok {
+		t.Fatal("expected a []byte destination for a type with migrations")
+	}
+	*dst = []byte(`{"name":"old"}`)
+
+	doc, err := s.decode("d1", 1)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc.Name != "migrated" {
+		t.Errorf("got %+v, want the migrated document", doc)
+	}
+}
+
+var scanPayload = []byte(`{"name":"Alice","email":"alice@test.com"}`)
+
+// BenchmarkDecode_CopyThenUnmarshal is the path Load took before docScanner:
+// pgx copies the JSONB into a fresh []byte, which is then unmarshaled.
+func BenchmarkDecode_CopyThenUnmarshal(b *testing.B) {
+	codec := codecs.NewWhisker(codecs.NewJSONIter())
+	b.ReportAllocs()
+	for b.Loop() {
+		data := make([]byte, len(scanPayload))
+		copy(data, scanPayload)
+		var doc scanDoc
+		if err := codec.Unmarshal(data, &doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecode_ScanBytes(b *testing.B) {
+	s := newDocScanner[scanDoc](codecs.NewWhisker(codecs.NewJSONIter()), nil)
+	b.ReportAllocs()
+	for b.Loop() {
+		s.target()
+		if err := s.ScanBytes(scanPayload); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := s.decode("d1", 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
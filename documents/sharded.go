@@ -0,0 +1,168 @@
+package documents
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/internal/pg"
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+// ShardedCollectionOf is a collection spread over several tables,
+// whisker_{name}_0 to whisker_{name}_{n-1}, each document living in the
+// shard schema.ShardOf picks for its ID. It reads the read models of a
+// sharded projection (see projections.Projection.Sharded): loads go to one
+// shard, queries fan out to every shard and merge.
+type ShardedCollectionOf[T any] struct {
+	name   string
+	shards []*CollectionOf[T]
+}
+
+// Sharded returns the collection name spread over shards tables. opts apply
+// to every shard.
+func Sharded[T any](b whisker.Backend, name string, shards int, opts ...CollectionOption) *ShardedCollectionOf[T] {
+	s := &ShardedCollectionOf[T]{name: name, shards: make([]*CollectionOf[T], max(shards, 1))}
+	for i := range s.shards {
+		s.shards[i] = Collection[T](b, schema.ShardName(name, i), opts...)
+	}
+	return s
+}
+
+// Shard returns the shard holding the document id. Write through it to keep
+// documents where the projection and Load expect them.
+func (s *ShardedCollectionOf[T]) Shard(id string) *CollectionOf[T] {
+	return s.shards[schema.ShardOf(id, len(s.shards))]
+}
+
+// Shards returns every shard, in shard order.
+func (s *ShardedCollectionOf[T]) Shards() []*CollectionOf[T] {
+	return s.shards
+}
+
+// Load reads the document id from its shard. Returns ErrNotFound if absent.
+func (s *ShardedCollectionOf[T]) Load(ctx context.Context, id string) (*T, error) {
+	return s.Shard(id).Load(ctx, id)
+}
+
+// Query starts a query that build applies to every shard's Query. Execute
+// runs it on all shards, concurrently outside a session, and merges the
+// results.
+//
+//	open, err := orders.Query(func(q *documents.Query[Order]) *documents.Query[Order] {
+//		return q.Where("status", "=", "open").OrderBy("total", documents.Desc).Limit(10)
+//	}).SortFunc(func(a, b *Order) int { return cmp.Compare(b.Total, a.Total) }).Execute(ctx)
+func (s *ShardedCollectionOf[T]) Query(build func(*Query[T]) *Query[T]) *ShardedQuery[T] {
+	return &ShardedQuery[T]{coll: s, build: build}
+}
+
+// ShardedQuery is a query fanned out to every shard of a
+// ShardedCollectionOf.
+type ShardedQuery[T any] struct {
+	coll  *ShardedCollectionOf[T]
+	build func(*Query[T]) *Query[T]
+	cmp   func(a, b *T) int
+}
+
+// SortFunc orders the merged results with cmp, which should agree with the
+// query's OrderBy so the per-shard Limit keeps the right documents. Without
+// it results are concatenated in shard order.
+func (q *ShardedQuery[T]) SortFunc(cmp func(a, b *T) int) *ShardedQuery[T] {
+	c := *q
+	c.cmp = cmp
+	return &c
+}
+
+// queries builds the query of every shard. Offsets cannot be applied per
+// shard, so they are refused.
+func (q *ShardedQuery[T]) queries() ([]*Query[T], error) {
+	qs := make([]*Query[T], len(q.coll.shards))
+	for i, shard := range q.coll.shards {
+		qs[i] = shard.Query()
+		if q.build != nil {
+			qs[i] = q.build(qs[i])
+		}
+		if qs[i].offset != nil {
+			return nil, fmt.Errorf("collection %s: sharded query: Offset is not supported across shards; use After", q.coll.name)
+		}
+	}
+	return qs, nil
+}
+
+// Execute runs the query on every shard and returns the merged results,
+// sorted by SortFunc and cut to the query's Limit.
+func (q *ShardedQuery[T]) Execute(ctx context.Context) ([]*T, error) {
+	qs, err := q.queries()
+	if err != nil {
+		return nil, err
+	}
+	results := make([][]*T, len(qs))
+	if err := q.fanOut(len(qs), func(i int) error {
+		docs, err := qs[i].Execute(ctx)
+		results[i] = docs
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("collection %s: sharded query: %w", q.coll.name, err)
+	}
+
+	merged := slices.Concat(results...)
+	if q.cmp != nil {
+		slices.SortStableFunc(merged, q.cmp)
+	}
+	if limit := qs[0].limit; limit != nil && uint64(len(merged)) > *limit {
+		merged = merged[:*limit]
+	}
+	return merged, nil
+}
+
+// Count returns the number of documents matching the query across all
+// shards.
+func (q *ShardedQuery[T]) Count(ctx context.Context) (int64, error) {
+	qs, err := q.queries()
+	if err != nil {
+		return 0, err
+	}
+	counts := make([]int64, len(qs))
+	if err := q.fanOut(len(qs), func(i int) error {
+		n, err := qs[i].Count(ctx)
+		counts[i] = n
+		return err
+	}); err != nil {
+		return 0, fmt.Errorf("collection %s: sharded count: %w", q.coll.name, err)
+	}
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	return total, nil
+}
+
+// fanOut calls fn for 0 to n-1 and returns the error of the lowest-numbered
+// call that failed. Calls run concurrently, except in a session, whose single
+// connection runs one query at a time.
+func (q *ShardedQuery[T]) fanOut(n int, fn func(i int) error) error {
+	errs := make([]error, n)
+	if tx, ok := q.coll.shards[0].exec.(pg.Transactional); ok && tx.InTransaction() {
+		for i := range n {
+			errs[i] = fn(i)
+		}
+	} else {
+		var wg sync.WaitGroup
+		for i := range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = fn(i)
+			}()
+		}
+		wg.Wait()
+	}
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("shard %d: %w", i, err)
+		}
+	}
+	return nil
+}
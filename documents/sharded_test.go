@@ -0,0 +1,30 @@
+package documents
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+func TestSharded_RoutesByShardOf(t *testing.T) {
+	s := &ShardedCollectionOf[testDoc]{name: "orders"}
+	for i := range 4 {
+		s.shards = append(s.shards, &CollectionOf[testDoc]{name: schema.ShardName("orders", i), table: "whisker_" + schema.ShardName("orders", i)})
+	}
+	for _, id := range []string{"o1", "o2", "o3", "o4", "o5"} {
+		want := "whisker_" + schema.ShardName("orders", schema.ShardOf(id, 4))
+		if got := s.Shard(id).table; got != want {
+			t.Errorf("Shard(%q) = %s, want %s", id, got, want)
+		}
+	}
+}
+
+func TestShardedQuery_RejectsOffset(t *testing.T) {
+	s := &ShardedCollectionOf[testDoc]{name: "orders", shards: []*CollectionOf[testDoc]{{name: "orders_0", table: "whisker_orders_0"}}}
+	_, err := s.Query(func(q *Query[testDoc]) *Query[testDoc] { return q.Offset(10) }).Execute(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "Offset") {
+		t.Errorf("got %v, want offset error", err)
+	}
+}
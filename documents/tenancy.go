@@ -0,0 +1,80 @@
+package documents
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/internal/ident"
+)
+
+// WithTenancy scopes the collection to tenants: every document records the
+// tenant of the context that wrote it (see whisker.WithTenant) in a
+// tenant_id column, and loads, writes, deletes, queries and attachments only
+// see the documents of the context's tenant. They fail with whisker.ErrNoTenant when
+// the context has none. IDs remain unique across tenants, so inserting an ID
+// another tenant uses fails with ErrDuplicateID and Upsert refuses to
+// replace it.
+//
+// Maintenance that spans the whole table, such as MigrateAll, Diff and
+// backfills, is not scoped. Add WithRLS(TenantColumnPolicy()) to have
+// PostgreSQL enforce the scoping as well, for sessions started with a
+// tenant.
+func WithTenancy() CollectionOption {
+	return func(cfg *collectionConfig) {
+		cfg.tenancy = true
+	}
+}
+
+// TenantColumnPolicy returns an RLS policy expression that restricts rows to
+// those whose tenant_id column, filled in by WithTenancy, equals the tenant
+// set on the session. Rows are invisible when no tenant is set.
+func TenantColumnPolicy() string {
+	return fmt.Sprintf("tenant_id = current_setting(%s, true)", ident.Literal(whisker.TenantSetting))
+}
+
+// tenant returns the tenant ctx scopes the collection to, or "" for a
+// collection without tenancy.
+func (c *CollectionOf[T]) tenant(ctx context.Context, op string) (string, error) {
+	if !c.tenancy {
+		return "", nil
+	}
+	id, ok := whisker.TenantFrom(ctx)
+	if !ok {
+		return "", fmt.Errorf("collection %s: %s: %w", c.name, op, whisker.ErrNoTenant)
+	}
+	return id, nil
+}
+
+// withTenant adds the tenant_id column to an insert's columns and values.
+func withTenant(cols []string, values []any, tenant string) ([]string, []any) {
+	if tenant == "" {
+		return cols, values
+	}
+	return append(cols, "tenant_id"), append(values, tenant)
+}
+
+// whereTenant restricts a statement to the rows of tenant, if any.
+func whereTenant[B interface{ Where(any, ...any) B }](b B, tenant string) B {
+	if tenant == "" {
+		return b
+	}
+	return b.Where(sq.Eq{"tenant_id": tenant})
+}
+
+// owned fails with ErrNotFound unless document id belongs to ctx's tenant,
+// for operations on a document's satellite rows, such as attachments.
+func (c *CollectionOf[T]) owned(ctx context.Context, id string) error {
+	if !c.tenancy {
+		return nil
+	}
+	exists, err := c.Exists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return whisker.ErrNotFound
+	}
+	return nil
+}
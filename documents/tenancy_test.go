@@ -0,0 +1,67 @@
+package documents
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ripkitten-co/whisker"
+)
+
+func TestTenant(t *testing.T) {
+	c := &CollectionOf[testDoc]{name: "users", tenancy: true}
+	if _, err := c.tenant(context.Background(), "load"); !errors.Is(err, whisker.ErrNoTenant) {
+		t.Fatalf("got %v, want ErrNoTenant", err)
+	}
+	got, err := c.tenant(whisker.WithTenant(context.Background(), "acme"), "load")
+	if err != nil || got != "acme" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+
+	c.tenancy = false
+	if got, err := c.tenant(context.Background(), "load"); err != nil || got != "" {
+		t.Fatalf("without tenancy: got %q, %v", got, err)
+	}
+}
+
+func TestTenantSQL(t *testing.T) {
+	c := &CollectionOf[testDoc]{table: "whisker_users"}
+
+	sql, args, err := c.upsertBuilder("acme").
+		Values(c.upsertValues("u1", []byte("{}"), "acme")...).
+		Suffix(c.upsertSuffix("acme")).
+		ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "INSERT INTO whisker_users AS t (id,data,tenant_id) VALUES ($1,$2,$3) " +
+		"ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data, version = t.version + 1, updated_at = EXCLUDED.updated_at " +
+		"WHERE t.tenant_id = EXCLUDED.tenant_id RETURNING t.id, t.version"
+	if sql != want || args[2] != "acme" {
+		t.Errorf("upsert: got %s %v", sql, args)
+	}
+
+	sql, args, err = c.incrementSQL("c1", "hits", 1, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sql, "WHERE id = $4 AND tenant_id = $5 RETURNING") || args[4] != "acme" {
+		t.Errorf("increment: got %s %v", sql, args)
+	}
+
+	sql, args = c.updateManySQL([]docInfo{{id: "u1", data: []byte("{}"), oldVersion: 1, newVersion: 2}}, "acme")
+	if strings.Count(sql, "AND t.tenant_id = $6") != 2 || args[5] != "acme" {
+		t.Errorf("update many: got %s %v", sql, args)
+	}
+
+	q := c.Query().Where("name", "=", "Ada")
+	q.tenant = "acme"
+	sql, args, err = q.toSQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sql, "WHERE tenant_id = $1 AND ") || args[0] != "acme" {
+		t.Errorf("query: got %s %v", sql, args)
+	}
+}
@@ -0,0 +1,429 @@
+package documents
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+// Format is a document file format for Export and Import.
+type Format string
+
+const (
+	// JSONL is one JSON object per line: the document's fields plus its ID
+	// under "id".
+	JSONL Format = "jsonl"
+	// CSV has a header row naming the columns, "id" first, then the
+	// document's top-level fields. Strings are written as is; other values,
+	// objects and arrays included, as JSON. An empty cell is a missing field.
+	CSV Format = "csv"
+)
+
+// Export writes every document of col to w in format, ordered by ID, and
+// returns how many it wrote. Documents are read through a cursor (see
+// Query.Iterate), so large collections are streamed and data migrations
+// apply. Versions and timestamps are not exported.
+func Export[T any](ctx context.Context, col *CollectionOf[T], w io.Writer, format Format) (int, error) {
+	bw := bufio.NewWriter(w)
+	var write func(id string, fields map[string]json.RawMessage) error
+	var keys []string
+	switch format {
+	case JSONL:
+		write = func(id string, fields map[string]json.RawMessage) error {
+			return writeJSONL(bw, id, fields)
+		}
+	case CSV:
+		keys = fieldKeys[T]()
+		cw := csv.NewWriter(bw)
+		if err := cw.Write(append([]string{"id"}, keys...)); err != nil {
+			return 0, fmt.Errorf("collection %s: export: %w", col.name, err)
+		}
+		write = func(id string, fields map[string]json.RawMessage) error {
+			record := make([]string, 1, len(keys)+1)
+			record[0] = id
+			for _, k := range keys {
+				record = append(record, csvCell(fields[k]))
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		return 0, fmt.Errorf("collection %s: export: unknown format %q", col.name, format)
+	}
+
+	n := 0
+	err := col.Query().OrderBy("id", Asc).Iterate(ctx, func(doc *T) error {
+		id, err := meta.ExtractID(doc)
+		if err != nil {
+			return err
+		}
+		data, err := marshalDoc(col.codec, id, doc)
+		if err != nil {
+			return fmt.Errorf("marshal %s: %w", id, err)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return fmt.Errorf("marshal %s: %w", id, err)
+		}
+		if err := write(id, fields); err != nil {
+			return fmt.Errorf("write %s: %w", id, err)
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		return n, fmt.Errorf("collection %s: export: %w", col.name, err)
+	}
+	if err := bw.Flush(); err != nil {
+		return n, fmt.Errorf("collection %s: export: %w", col.name, err)
+	}
+	return n, nil
+}
+
+func writeJSONL(w io.Writer, id string, fields map[string]json.RawMessage) error {
+	out := make(map[string]json.RawMessage, len(fields)+1)
+	for k, v := range fields {
+		out[k] = v
+	}
+	rawID, err := json.Marshal(id)
+	if err != nil {
+		return err
+	}
+	out["id"] = rawID
+	line, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// csvCell renders a JSON value as a CSV cell: strings unquoted, null or
+// missing as empty, anything else as JSON.
+func csvCell(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s
+		}
+	}
+	return string(raw)
+}
+
+// fieldKeys returns the JSON keys of T's data fields in declaration order.
+func fieldKeys[T any]() []string {
+	m := meta.Analyze[T]()
+	keys := make([]string, len(m.Fields))
+	for i, f := range m.Fields {
+		keys[i] = f.JSONKey
+	}
+	return keys
+}
+
+// ImportMode decides what Import does with a document whose ID is already
+// stored.
+type ImportMode int
+
+const (
+	// ImportUpsert merges the imported fields into the stored document,
+	// keeping fields the import does not mention. It suits one-off fixes
+	// such as a CSV of id,status. It is the default.
+	ImportUpsert ImportMode = iota
+	// ImportReplace replaces the stored document with the imported one.
+	ImportReplace
+	// ImportSkip leaves the stored document untouched.
+	ImportSkip
+)
+
+// ImportOption configures Import.
+type ImportOption func(*importConfig)
+
+type importConfig struct {
+	mode      ImportMode
+	batchSize int
+	progress  func(ImportResult)
+}
+
+// WithImportMode sets what Import does with documents that already exist.
+// Defaults to ImportUpsert.
+func WithImportMode(m ImportMode) ImportOption {
+	return func(c *importConfig) { c.mode = m }
+}
+
+// WithImportBatchSize sets how many documents Import writes per statement.
+// Defaults to 500.
+func WithImportBatchSize(n int) ImportOption {
+	return func(c *importConfig) { c.batchSize = n }
+}
+
+// WithImportProgress calls fn after each batch Import writes with the
+// totals so far.
+func WithImportProgress(fn func(ImportResult)) ImportOption {
+	return func(c *importConfig) { c.progress = fn }
+}
+
+// ImportResult counts what Import did.
+type ImportResult struct {
+	// Read is the number of documents read from the input.
+	Read int
+	// Written is the number of documents inserted or changed; documents
+	// skipped with ImportSkip are not counted.
+	Written int
+}
+
+// importRecord is one document read by Import: its ID and the JSON of the
+// fields it sets.
+type importRecord struct {
+	id   string
+	data []byte
+}
+
+// Import reads documents in format from r and writes them to col in batches,
+// resolving documents that already exist according to the mode (ImportUpsert
+// by default). Every record must have a non-empty "id"; fields that T does
+// not declare are rejected, so typos in a header fail the import. Records
+// are checked by decoding them into T before they are written.
+//
+// Each batch is its own statement: when Import fails, earlier batches stay
+// written. Run it in a session to import all or nothing. Imported documents
+// are stamped with the latest data-schema version; ImportUpsert merges into
+// stored documents without migrating them, so run MigrateAll first when T
+// has migrations.
+func Import[T any](ctx context.Context, col *CollectionOf[T], r io.Reader, format Format, opts ...ImportOption) (ImportResult, error) {
+	cfg := importConfig{batchSize: 500}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	var res ImportResult
+	if cfg.batchSize <= 0 {
+		return res, fmt.Errorf("collection %s: import: batch size must be positive, got %d", col.name, cfg.batchSize)
+	}
+	if err := col.checkBatchSize(cfg.batchSize); err != nil {
+		return res, err
+	}
+
+	var next func() (map[string]json.RawMessage, error)
+	switch format {
+	case JSONL:
+		next = jsonlReader(r)
+	case CSV:
+		var err error
+		if next, err = csvReader[T](r); err != nil {
+			return res, fmt.Errorf("collection %s: import: %w", col.name, err)
+		}
+	default:
+		return res, fmt.Errorf("collection %s: import: unknown format %q", col.name, format)
+	}
+
+	known := make(map[string]bool)
+	for _, k := range fieldKeys[T]() {
+		known[k] = true
+	}
+	var batch []importRecord
+	seen := make(map[string]bool)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := col.importBatch(ctx, batch, cfg.mode)
+		if err != nil {
+			return err
+		}
+		res.Written += n
+		batch, seen = batch[:0], make(map[string]bool)
+		if cfg.progress != nil {
+			cfg.progress(res)
+		}
+		return nil
+	}
+
+	for {
+		fields, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return res, fmt.Errorf("collection %s: import record %d: %w", col.name, res.Read+1, err)
+		}
+		rec, err := toImportRecord[T](col, fields, known)
+		if err != nil {
+			return res, fmt.Errorf("collection %s: import record %d: %w", col.name, res.Read+1, err)
+		}
+		res.Read++
+
+		// a statement cannot write the same row twice
+		if seen[rec.id] || len(batch) == cfg.batchSize {
+			if err := flush(); err != nil {
+				return res, err
+			}
+		}
+		seen[rec.id] = true
+		batch = append(batch, rec)
+	}
+	if err := flush(); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// toImportRecord validates a record's fields against T and splits off its ID.
+func toImportRecord[T any](col *CollectionOf[T], fields map[string]json.RawMessage, known map[string]bool) (importRecord, error) {
+	var id string
+	rawID, ok := fields["id"]
+	if ok {
+		if err := json.Unmarshal(rawID, &id); err != nil {
+			return importRecord{}, fmt.Errorf("id must be a string: %w", err)
+		}
+	}
+	if id == "" {
+		return importRecord{}, fmt.Errorf("missing id")
+	}
+	delete(fields, "id")
+	for k := range fields {
+		if !known[k] {
+			return importRecord{}, fmt.Errorf("%s: unknown field %q", id, k)
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return importRecord{}, fmt.Errorf("%s: %w", id, err)
+	}
+	if e, ok := col.codec.(*encryptingCodec); ok {
+		// records are written as they are, so encrypt them here; values of an
+		// export are already encrypted
+		if data, err = e.seal(id, data, true); err != nil {
+			return importRecord{}, fmt.Errorf("%s: %w", id, err)
+		}
+	}
+	if err := unmarshalDoc(col.codec, id, data, new(T)); err != nil {
+		return importRecord{}, fmt.Errorf("%s: %w", id, err)
+	}
+	return importRecord{id: id, data: data}, nil
+}
+
+// importBatch writes a batch of records with IDs unique within it, and
+// returns how many rows it inserted or changed.
+func (c *CollectionOf[T]) importBatch(ctx context.Context, batch []importRecord, mode ImportMode) (int, error) {
+	if err := c.ensure(ctx); err != nil {
+		return 0, err
+	}
+	if err := c.checkWrite(ctx, "import"); err != nil {
+		return 0, err
+	}
+	tenant, err := c.tenant(ctx, "import")
+	if err != nil {
+		return 0, err
+	}
+	sql, args, err := c.importSQL(batch, mode, tenant)
+	if err != nil {
+		return 0, fmt.Errorf("collection %s: import: build sql: %w", c.name, err)
+	}
+	tag, err := c.exec.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("collection %s: import: %w", c.name, mapPgError(err))
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func (c *CollectionOf[T]) importSQL(batch []importRecord, mode ImportMode, tenant string) (string, []any, error) {
+	builder := c.upsertBuilder(tenant)
+	for _, rec := range batch {
+		builder = builder.Values(c.upsertValues(rec.id, rec.data, tenant)...)
+	}
+	var suffix string
+	switch mode {
+	case ImportUpsert:
+		suffix = "ON CONFLICT (id) DO UPDATE SET data = t.data || EXCLUDED.data, version = t.version + 1, updated_at = EXCLUDED.updated_at"
+		if tenant != "" {
+			suffix += " WHERE t.tenant_id = EXCLUDED.tenant_id"
+		}
+	case ImportReplace:
+		suffix, _, _ = strings.Cut(c.upsertSuffix(tenant), " RETURNING")
+	case ImportSkip:
+		suffix = "ON CONFLICT (id) DO NOTHING"
+	default:
+		return "", nil, fmt.Errorf("unknown import mode %d", mode)
+	}
+	return builder.Suffix(suffix).ToSql()
+}
+
+// jsonlReader returns a function reading one JSON object per call from r.
+func jsonlReader(r io.Reader) func() (map[string]json.RawMessage, error) {
+	dec := json.NewDecoder(r)
+	return func() (map[string]json.RawMessage, error) {
+		var fields map[string]json.RawMessage
+		if err := dec.Decode(&fields); err != nil {
+			return nil, err
+		}
+		if fields == nil {
+			return nil, fmt.Errorf("record is not an object")
+		}
+		return fields, nil
+	}
+}
+
+// csvReader reads the header of r and returns a function reading one record
+// per call as JSON fields, typed after T's fields.
+func csvReader[T any](r io.Reader) (func() (map[string]json.RawMessage, error), error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return func() (map[string]json.RawMessage, error) { return nil, io.EOF }, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	strs := stringFields[T]()
+	return func() (map[string]json.RawMessage, error) {
+		record, err := cr.Read()
+		if err != nil {
+			return nil, err
+		}
+		fields := make(map[string]json.RawMessage, len(header))
+		for i, key := range header {
+			cell := record[i]
+			if cell == "" {
+				continue
+			}
+			if key == "id" || strs[key] || !json.Valid([]byte(cell)) {
+				fields[key], _ = json.Marshal(cell)
+				continue
+			}
+			fields[key] = json.RawMessage(bytes.Clone([]byte(cell)))
+		}
+		return fields, nil
+	}, nil
+}
+
+// stringFields reports which of T's JSON keys hold strings, whose CSV cells
+// are taken literally even when they look like JSON.
+func stringFields[T any]() map[string]bool {
+	t := reflect.TypeFor[T]()
+	m := meta.Analyze[T]()
+	strs := make(map[string]bool, len(m.Fields))
+	for _, f := range m.Fields {
+		ft := t.Field(f.Index).Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		strs[f.JSONKey] = ft.Kind() == reflect.String
+	}
+	return strs
+}
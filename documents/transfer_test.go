@@ -0,0 +1,147 @@
+package documents
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/internal/codecs"
+)
+
+type transferDoc struct {
+	ID    string
+	Name  string
+	Age   int
+	Tags  []string
+	Notes *string
+}
+
+func TestCSVCell(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{`"alice"`, "alice"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`42`, "42"},
+		{`true`, "true"},
+		{`null`, ""},
+		{``, ""},
+		{`["a","b"]`, `["a","b"]`},
+	}
+	for _, tt := range tests {
+		if got := csvCell(json.RawMessage(tt.raw)); got != tt.want {
+			t.Errorf("csvCell(%s) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestCSVReader_TypesCellsByField(t *testing.T) {
+	in := "id,name,age,tags,notes\n" +
+		"1,42,7,\"[\"\"a\"\"]\",\n" +
+		"2,Bob,,,[not json\n"
+	next, err := csvReader[transferDoc](strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"id": `"1"`, "name": `"42"`, "age": `7`, "tags": `["a"]`}
+	if len(first) != len(want) {
+		t.Errorf("got fields %v, want %v", first, want)
+	}
+	for k, v := range want {
+		if string(first[k]) != v {
+			t.Errorf("%s = %s, want %s", k, first[k], v)
+		}
+	}
+
+	second, err := next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := second["age"]; ok {
+		t.Error("empty cell should be omitted")
+	}
+	if string(second["notes"]) != `"[not json"` {
+		t.Errorf("notes = %s, want quoted string", second["notes"])
+	}
+
+	if _, err := next(); !errors.Is(err, io.EOF) {
+		t.Errorf("got %v, want EOF", err)
+	}
+}
+
+func TestToImportRecord(t *testing.T) {
+	c := &CollectionOf[transferDoc]{name: "people", codec: codecs.NewWhisker(codecs.NewJSONIter())}
+	known := map[string]bool{"name": true, "age": true, "tags": true, "notes": true}
+
+	tests := []struct {
+		name    string
+		in      string
+		wantErr string
+	}{
+		{"valid", `{"id":"p1","name":"Ann","age":3}`, ""},
+		{"missing id", `{"name":"Ann"}`, "missing id"},
+		{"numeric id", `{"id":1}`, "id must be a string"},
+		{"unknown field", `{"id":"p1","nmae":"Ann"}`, `unknown field "nmae"`},
+		{"wrong type", `{"id":"p1","age":"three"}`, "p1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.in), &fields); err != nil {
+				t.Fatal(err)
+			}
+			rec, err := toImportRecord(c, fields, known)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rec.id != "p1" || string(rec.data) != `{"age":3,"name":"Ann"}` {
+				t.Errorf("got %+v", rec)
+			}
+		})
+	}
+}
+
+func TestImportSQL_Modes(t *testing.T) {
+	c := &CollectionOf[transferDoc]{table: "whisker_people"}
+	batch := []importRecord{{id: "p1", data: []byte(`{}`)}, {id: "p2", data: []byte(`{}`)}}
+	prefix := "INSERT INTO whisker_people AS t (id,data) VALUES ($1,$2),($3,$4) "
+
+	tests := []struct {
+		mode ImportMode
+		want string
+	}{
+		{ImportUpsert, "ON CONFLICT (id) DO UPDATE SET data = t.data || EXCLUDED.data, version = t.version + 1, updated_at = EXCLUDED.updated_at"},
+		{ImportReplace, "ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data, version = t.version + 1, updated_at = EXCLUDED.updated_at"},
+		{ImportSkip, "ON CONFLICT (id) DO NOTHING"},
+	}
+	for _, tt := range tests {
+		sql, args, err := c.importSQL(batch, tt.mode, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sql != prefix+tt.want {
+			t.Errorf("mode %d:\ngot:  %s\nwant: %s", tt.mode, sql, prefix+tt.want)
+		}
+		if len(args) != 4 {
+			t.Errorf("mode %d: args = %v", tt.mode, args)
+		}
+	}
+
+	if _, _, err := c.importSQL(batch, ImportMode(9), ""); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
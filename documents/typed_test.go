@@ -0,0 +1,102 @@
+package documents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+type typedOrder struct {
+	ID       string
+	Status   string
+	Total    float64
+	Items    int `whisker:"column"`
+	PlacedAt time.Time
+	Version  int
+}
+
+func TestQuery_TypedComparisons(t *testing.T) {
+	base := &Query[typedOrder]{table: "whisker_orders", columns: meta.Analyze[typedOrder]().Columns}
+	tests := []struct {
+		name string
+		q    *Query[typedOrder]
+		want string
+	}{
+		{"numeric", base.Where("total", ">=", 10), "SELECT id, data, version FROM whisker_orders WHERE (data->>'total')::numeric >= $1"},
+		{"time", base.Where("placedAt", "<", time.Now()), "SELECT id, data, version FROM whisker_orders WHERE whisker_timestamptz(data->>'placedAt') < $1"},
+		{"list", base.Where("total", "IN", []int{1, 2}), "SELECT id, data, version FROM whisker_orders WHERE (data->>'total')::numeric IN ($1,$2)"},
+		{"pattern stays text", base.Where("total", "LIKE", "1%"), "SELECT id, data, version FROM whisker_orders WHERE data->>'total' LIKE $1"},
+		{"fold stays text", base.WhereFold("status", "open"), "SELECT id, data, version FROM whisker_orders WHERE lower(data->>'status') = lower($1)"},
+		{"column", base.Where("items", ">", 2), "SELECT id, data, version FROM whisker_orders WHERE items > $1"},
+		{"order", base.OrderBy("placedAt", Desc).OrderBy("total", Asc), "SELECT id, data, version FROM whisker_orders ORDER BY whisker_timestamptz(data->>'placedAt') DESC, (data->>'total')::numeric ASC"},
+		{"after", base.OrderBy("total", Asc).After(5), "SELECT id, data, version FROM whisker_orders WHERE (data->>'total')::numeric > $1 ORDER BY (data->>'total')::numeric ASC"},
+		{"text", base.Where("status", "=", "open"), "SELECT id, data, version FROM whisker_orders WHERE data->>'status' = $1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, _, err := tt.q.toSQL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != tt.want {
+				t.Errorf("sql:\n got: %s\nwant: %s", sql, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuery_CursorTypeOfTypedFields(t *testing.T) {
+	q := &Query[typedOrder]{table: "whisker_orders"}
+	for field, want := range map[string]string{
+		"total":    "numeric",
+		"placedAt": "timestamptz",
+		"status":   "text",
+	} {
+		if got := q.cursorType(field); got != want {
+			t.Errorf("cursorType(%q) = %q, want %q", field, got, want)
+		}
+	}
+}
+
+type promotedOrder struct {
+	ID         string
+	PlacedAt   time.Time `whisker:"column"`
+	Priority   *int      `whisker:"column"`
+	CustomerNo int       `whisker:"fk=customers"`
+	Version    int
+}
+
+func TestQuery_TypedComparisonsOnPromotedFields(t *testing.T) {
+	base := &Query[promotedOrder]{table: "whisker_orders", columns: meta.Analyze[promotedOrder]().Columns}
+	tests := []struct {
+		name string
+		q    *Query[promotedOrder]
+		want string
+	}{
+		{"time column", base.Where("placedAt", ">=", time.Now()), "SELECT id, data, version FROM whisker_orders WHERE placed_at >= $1"},
+		{"pointer column", base.Where("priority", ">", 2).OrderBy("priority", Desc), "SELECT id, data, version FROM whisker_orders WHERE priority > $1 ORDER BY priority DESC"},
+		{"text foreign key", base.Where("customerNo", "<", 10), "SELECT id, data, version FROM whisker_orders WHERE (data->>'customerNo')::numeric < $1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, _, err := tt.q.toSQL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != tt.want {
+				t.Errorf("sql:\n got: %s\nwant: %s", sql, tt.want)
+			}
+		})
+	}
+
+	for field, want := range map[string]string{
+		"placedAt":   "TIMESTAMPTZ",
+		"priority":   "BIGINT",
+		"customerNo": "numeric",
+	} {
+		if got := base.cursorType(field); got != want {
+			t.Errorf("cursorType(%q) = %q, want %q", field, got, want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package documents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ripkitten-co/whisker"
+)
+
+// WithWatch installs a trigger that signals every insert, update and delete
+// on the collection with NOTIFY, so Watch and WatchQuery can follow its
+// documents. The trigger adds a notification to each written row, which
+// bulk writes pay for every document, so it is opt-in.
+func WithWatch() CollectionOption {
+	return func(cfg *collectionConfig) {
+		cfg.watch = true
+	}
+}
+
+// Change is a change to a watched document. Type is DocumentInserted,
+// DocumentUpdated or DocumentDeleted, with setting deleted_at reported as a
+// delete; Version is the document's version after the change.
+type Change struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Version int    `json:"version"`
+}
+
+// notification is the payload of a watch notification: the change and the
+// tenant of the document, empty for collections without tenancy.
+type notification struct {
+	Change
+	Tenant string `json:"tenant"`
+}
+
+// listenerBackend is implemented by backends that can hold a LISTEN
+// connection, as *whisker.Store does.
+type listenerBackend interface {
+	Listen(ctx context.Context, channel string) (whisker.Listener, error)
+}
+
+// changeFilter decides whether a change is sent to a watcher, and how.
+type changeFilter func(ctx context.Context, ch Change) (Change, bool, error)
+
+// Watch follows the document id of a collection created WithWatch, sending
+// each change to it made from now on:
+//
+//	changes, err := users.Watch(ctx, "u1")
+//	for ch := range changes {
+//		log.Printf("%s %s at version %d", ch.Type, ch.ID, ch.Version)
+//	}
+//
+// Load the document to get its data. In a collection WithTenancy, only the
+// changes of the context's tenant are sent. Watch holds one connection for
+// LISTEN, so it needs the collection to be created from a *whisker.Store.
+// The channel is closed when ctx is cancelled, the store shuts down or the
+// connection is lost; watch again and reload to resume.
+func (c *CollectionOf[T]) Watch(ctx context.Context, id string) (<-chan Change, error) {
+	return c.watchChanges(ctx, "watch", func(context.Context) (changeFilter, error) {
+		return func(_ context.Context, ch Change) (Change, bool, error) {
+			return ch, ch.ID == id, nil
+		}, nil
+	})
+}
+
+// WatchQuery follows the results of a query on a collection created
+// WithWatch, sending a change each time they change: DocumentInserted when a
+// document enters them, by insert or by an update that makes it match,
+// DocumentUpdated when one of them changes and still matches, and
+// DocumentDeleted when one leaves them:
+//
+//	changes, err := orders.WatchQuery(ctx, orders.Query().Where("status", "=", "open"))
+//
+// Each change of the collection costs one lookup by id to test the
+// conditions. Limit, Offset, pagination, Select and GroupBy are not
+// supported; OrderBy is ignored. The channel is closed as for Watch, and
+// also when a lookup fails.
+func (c *CollectionOf[T]) WatchQuery(ctx context.Context, q *Query[T]) (<-chan Change, error) {
+	if q.table != c.table {
+		return nil, fmt.Errorf("collection %s: watch query: query is on %s", c.name, q.name)
+	}
+	if err := q.checkWatch(); err != nil {
+		return nil, err
+	}
+	return c.watchChanges(ctx, "watch query", func(ctx context.Context) (changeFilter, error) {
+		ids, err := q.ids(ctx)
+		if err != nil {
+			return nil, err
+		}
+		matched := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			matched[id] = true
+		}
+		return func(ctx context.Context, ch Change) (Change, bool, error) {
+			was := matched[ch.ID]
+			is, err := q.contains(ctx, ch.ID)
+			if err != nil {
+				return ch, false, err
+			}
+			switch {
+			case is && !was:
+				matched[ch.ID] = true
+				ch.Type = DocumentInserted
+			case is:
+				ch.Type = DocumentUpdated
+			case was:
+				delete(matched, ch.ID)
+				ch.Type = DocumentDeleted
+			default:
+				return ch, false, nil
+			}
+			return ch, true, nil
+		}, nil
+	})
+}
+
+// watchChanges listens for the collection's changes, then builds the filter
+// with start, so no change made while it runs is missed, and sends what the
+// filter passes.
+func (c *CollectionOf[T]) watchChanges(ctx context.Context, op string, start func(context.Context) (changeFilter, error)) (<-chan Change, error) {
+	if !c.watch {
+		return nil, fmt.Errorf("collection %s: %s: collection is not created WithWatch", c.name, op)
+	}
+	if c.listen == nil {
+		return nil, fmt.Errorf("collection %s: %s: backend cannot listen", c.name, op)
+	}
+	if err := c.ensure(ctx); err != nil {
+		return nil, err
+	}
+	tenant, err := c.tenant(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+	l, err := c.listen(ctx, c.schema.WatchChannel(c.name))
+	if err != nil {
+		return nil, fmt.Errorf("collection %s: %s: %w", c.name, op, err)
+	}
+	filter, err := start(ctx)
+	if err != nil {
+		closeListener(ctx, l)
+		return nil, fmt.Errorf("collection %s: %s: %w", c.name, op, err)
+	}
+
+	out := make(chan Change)
+	go func() {
+		defer close(out)
+		defer closeListener(ctx, l)
+		for {
+			payload, err := l.Wait(ctx)
+			if err != nil {
+				return
+			}
+			var n notification
+			if err := json.Unmarshal([]byte(payload), &n); err != nil {
+				continue
+			}
+			if tenant != "" && n.Tenant != tenant {
+				continue
+			}
+			ch, ok, err := filter(ctx, n.Change)
+			if err != nil {
+				return
+			}
+			if !ok {
+				continue
+			}
+			select {
+			case out <- ch:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// closeListener closes l even when ctx is already cancelled.
+func closeListener(ctx context.Context, l whisker.Listener) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	_ = l.Close(ctx)
+}
+
+// checkWatch rejects query settings WatchQuery cannot honour.
+func (q *Query[T]) checkWatch() error {
+	switch {
+	case q.limit != nil || q.offset != nil:
+		return fmt.Errorf("query: WatchQuery does not support Limit or Offset")
+	case q.afterVal != nil || q.cursor != "":
+		return fmt.Errorf("query: WatchQuery does not support pagination")
+	case len(q.selects) > 0 || len(q.groupBys) > 0:
+		return fmt.Errorf("query: WatchQuery does not support Select or GroupBy")
+	}
+	return nil
+}
+
+// ids returns the ids of the documents the query matches.
+func (q *Query[T]) ids(ctx context.Context) ([]string, error) {
+	if err := q.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	q, err := q.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sql, args, err := q.selectSQL("id")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.exec.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: ids: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("query: ids: %w", err)
+	}
+	return ids, nil
+}
+
+// contains reports whether the query matches the document id.
+func (q *Query[T]) contains(ctx context.Context, id string) (bool, error) {
+	return q.byID(id).Exists(ctx)
+}
+
+// byID narrows the query's conditions down to the document id.
+func (q *Query[T]) byID(id string) *Query[T] {
+	m := q.sub()
+	m.deleted = q.deleted
+	m.conditions = []condition{{field: "id", op: "=", value: id}}
+	if len(q.conditions) > 0 {
+		m.conditions = append(m.conditions, condition{anyOf: [][]condition{q.conditions}})
+	}
+	return m
+}
@@ -0,0 +1,134 @@
+//go:build integration
+
+package documents_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/documents"
+)
+
+func nextChange(t *testing.T, changes <-chan documents.Change) documents.Change {
+	t.Helper()
+	select {
+	case ch, ok := <-changes:
+		if !ok {
+			t.Fatal("watch channel closed")
+		}
+		return ch
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for change")
+	}
+	return documents.Change{}
+}
+
+func TestCollection_Watch(t *testing.T) {
+	store := setupStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	users := documents.Collection[User](store, "watched_users", documents.WithWatch())
+
+	changes, err := users.Watch(ctx, "u1")
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+
+	if err := users.Insert(ctx, &User{ID: "u2", Name: "Bob"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	u := &User{ID: "u1", Name: "Alice"}
+	if err := users.Insert(ctx, u); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if got, want := nextChange(t, changes), (documents.Change{Type: documents.DocumentInserted, ID: "u1", Version: 1}); got != want {
+		t.Errorf("insert: got %+v, want %+v", got, want)
+	}
+
+	u.Name = "Alicia"
+	if err := users.Update(ctx, u); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got, want := nextChange(t, changes), (documents.Change{Type: documents.DocumentUpdated, ID: "u1", Version: 2}); got != want {
+		t.Errorf("update: got %+v, want %+v", got, want)
+	}
+
+	if err := users.Delete(ctx, "u1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if got := nextChange(t, changes); got.Type != documents.DocumentDeleted || got.ID != "u1" {
+		t.Errorf("delete: got %+v", got)
+	}
+}
+
+func TestCollection_WatchQuery(t *testing.T) {
+	store := setupStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	users := documents.Collection[User](store, "watched_query_users", documents.WithWatch())
+
+	bob := &User{ID: "u2", Name: "Bob", Email: "bob@old.com"}
+	if err := users.Insert(ctx, bob); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	changes, err := users.WatchQuery(ctx, users.Where("email", "LIKE", "%@old.com"))
+	if err != nil {
+		t.Fatalf("watch query: %v", err)
+	}
+
+	if err := users.Insert(ctx, &User{ID: "u1", Name: "Alice", Email: "alice@new.com"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := users.Insert(ctx, &User{ID: "u3", Name: "Carol", Email: "carol@old.com"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if got := nextChange(t, changes); got.Type != documents.DocumentInserted || got.ID != "u3" {
+		t.Errorf("insert: got %+v, want u3 inserted", got)
+	}
+
+	bob.Email = "bob@new.com"
+	if err := users.Update(ctx, bob); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got := nextChange(t, changes); got.Type != documents.DocumentDeleted || got.ID != "u2" {
+		t.Errorf("update out of results: got %+v, want u2 deleted", got)
+	}
+
+	bob.Email = "bob@old.com"
+	if err := users.Update(ctx, bob); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got := nextChange(t, changes); got.Type != documents.DocumentInserted || got.ID != "u2" {
+		t.Errorf("update into results: got %+v, want u2 inserted", got)
+	}
+}
+
+func TestCollection_WatchTenancy(t *testing.T) {
+	store := setupStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	acme := whisker.WithTenant(ctx, "acme")
+	globex := whisker.WithTenant(ctx, "globex")
+	users := documents.Collection[User](store, "watched_tenant_users", documents.WithWatch(), documents.WithTenancy())
+
+	if _, err := users.Watch(ctx, "u1"); !errors.Is(err, whisker.ErrNoTenant) {
+		t.Fatalf("watch without tenant: got %v, want ErrNoTenant", err)
+	}
+	changes, err := users.WatchQuery(acme, users.Query())
+	if err != nil {
+		t.Fatalf("watch query: %v", err)
+	}
+
+	if err := users.Insert(globex, &User{ID: "g1", Name: "Gus"}); err != nil {
+		t.Fatalf("insert globex: %v", err)
+	}
+	if err := users.Insert(acme, &User{ID: "a1", Name: "Ann"}); err != nil {
+		t.Fatalf("insert acme: %v", err)
+	}
+	if got := nextChange(t, changes); got.ID != "a1" {
+		t.Errorf("got %+v, want only acme's a1", got)
+	}
+}
@@ -0,0 +1,140 @@
+package documents
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+type fakeListener struct {
+	payloads chan string
+	closed   chan struct{}
+}
+
+func (l *fakeListener) Wait(ctx context.Context) (string, error) {
+	select {
+	case p := <-l.payloads:
+		return p, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (l *fakeListener) Ping(context.Context) error { return nil }
+
+func (l *fakeListener) Close(context.Context) error {
+	close(l.closed)
+	return nil
+}
+
+func watchedCollection(l *fakeListener, channel *string) *CollectionOf[testDoc] {
+	return &CollectionOf[testDoc]{
+		name:   "users",
+		table:  "whisker_users",
+		schema: schema.New(schema.WithAutoMigrate(false)),
+		watch:  true,
+		listen: func(_ context.Context, ch string) (whisker.Listener, error) {
+			*channel = ch
+			return l, nil
+		},
+	}
+}
+
+func TestWatch_SendsChangesToDocument(t *testing.T) {
+	l := &fakeListener{payloads: make(chan string, 3), closed: make(chan struct{})}
+	var channel string
+	c := watchedCollection(l, &channel)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes, err := c.Watch(ctx, "u1")
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if channel != "whisker_users_changed" {
+		t.Errorf("listened on %q, want whisker_users_changed", channel)
+	}
+
+	l.payloads <- `{"type":"DocumentUpdated","id":"u2","version":4}`
+	l.payloads <- `not json`
+	l.payloads <- `{"type":"DocumentUpdated","id":"u1","version":2}`
+	select {
+	case ch := <-changes:
+		if ch != (Change{Type: DocumentUpdated, ID: "u1", Version: 2}) {
+			t.Errorf("got %+v", ch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for change")
+	}
+
+	cancel()
+	if _, ok := <-changes; ok {
+		t.Fatal("channel should close when ctx is cancelled")
+	}
+	select {
+	case <-l.closed:
+	case <-time.After(time.Second):
+		t.Fatal("listener was not closed")
+	}
+}
+
+func TestWatch_RequiresWithWatchAndListener(t *testing.T) {
+	c := &CollectionOf[testDoc]{name: "users", table: "whisker_users", schema: schema.New(schema.WithAutoMigrate(false))}
+	if _, err := c.Watch(context.Background(), "u1"); err == nil {
+		t.Error("expected error for a collection without WithWatch")
+	}
+	c.watch = true
+	if _, err := c.Watch(context.Background(), "u1"); err == nil {
+		t.Error("expected error for a backend that cannot listen")
+	}
+
+	var channel string
+	c = watchedCollection(nil, &channel)
+	c.listen = func(context.Context, string) (whisker.Listener, error) {
+		return nil, whisker.ErrStoreClosed
+	}
+	if _, err := c.Watch(context.Background(), "u1"); !errors.Is(err, whisker.ErrStoreClosed) {
+		t.Errorf("got %v, want ErrStoreClosed", err)
+	}
+}
+
+func TestWatchQuery_RejectsUnsupportedQueries(t *testing.T) {
+	var channel string
+	c := watchedCollection(nil, &channel)
+	for name, q := range map[string]*Query[testDoc]{
+		"limit":    c.Query().Limit(10),
+		"offset":   c.Query().Offset(10),
+		"after":    c.Query().OrderBy("name", Asc).After("a"),
+		"select":   c.Query().Select("name"),
+		"group by": c.Query().GroupBy("name"),
+		"other":    (&CollectionOf[testDoc]{name: "admins", table: "whisker_admins"}).Query(),
+	} {
+		if _, err := c.WatchQuery(context.Background(), q); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestQuery_ByIDScopesConditions(t *testing.T) {
+	q := (&Query[testDoc]{table: "whisker_users"}).
+		Where("name", "=", "a").OrWhere("name", "=", "b").
+		Deleted(ExcludeDeleted)
+	sql, args, err := q.byID("u1").toExistsSQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT EXISTS(SELECT 1 FROM whisker_users WHERE deleted_at IS NULL AND id = $1 AND (data->>'name' = $2 OR data->>'name' = $3))"
+	if sql != want {
+		t.Errorf("sql:\n got: %s\nwant: %s", sql, want)
+	}
+	if len(args) != 3 || args[0] != "u1" {
+		t.Errorf("args: got %v", args)
+	}
+
+	if sql, _, _ := (&Query[testDoc]{table: "whisker_users"}).byID("u1").toExistsSQL(); sql != "SELECT EXISTS(SELECT 1 FROM whisker_users WHERE id = $1)" {
+		t.Errorf("no conditions: got %s", sql)
+	}
+}
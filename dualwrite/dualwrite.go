@@ -0,0 +1,231 @@
+// Package dualwrite mirrors document collection writes into event streams (and
+// event appends back into documents) during a transition from CRUD to event
+// sourcing. Both sides are written in one transaction, and Verify replays a
+// stream to check it still agrees with the stored document.
+package dualwrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/documents"
+	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+// ErrDrift is returned by Verify when a document and the state replayed from
+// its stream disagree.
+var ErrDrift = errors.New("document and stream disagree")
+
+// Op identifies the collection write being mirrored.
+type Op int
+
+const (
+	OpInsert Op = iota
+	OpUpdate
+	OpDelete
+)
+
+// MirrorFunc maps a collection write to the events appended alongside it. For
+// OpDelete, doc holds the document as it was before deletion.
+type MirrorFunc[T any] func(op Op, doc *T) []events.Event
+
+// ApplyFunc folds an event into document state. It receives nil for the first
+// event of a stream; returning nil deletes the document.
+type ApplyFunc[T any] func(state *T, evt events.Event) (*T, error)
+
+// Writer performs paired document and event writes for one collection.
+type Writer[T any] struct {
+	store      *whisker.Store
+	collection string
+	mirror     MirrorFunc[T]
+	apply      ApplyFunc[T]
+	streamID   func(docID string) string
+}
+
+// New creates a writer for the whisker_{collection} table. Each document maps
+// to the stream with the same ID unless WithStreamID is used.
+func New[T any](store *whisker.Store, collection string, mirror MirrorFunc[T]) *Writer[T] {
+	return &Writer[T]{
+		store:      store,
+		collection: collection,
+		mirror:     mirror,
+		streamID:   func(id string) string { return id },
+	}
+}
+
+// WithStreamID sets the document-to-stream mapping. Returns the writer for
+// method chaining.
+func (w *Writer[T]) WithStreamID(fn func(docID string) string) *Writer[T] {
+	w.streamID = fn
+	return w
+}
+
+// WithApply sets the fold used by Append and Verify. Returns the writer for
+// method chaining.
+func (w *Writer[T]) WithApply(fn ApplyFunc[T]) *Writer[T] {
+	w.apply = fn
+	return w
+}
+
+// Insert stores a new document and appends its mirrored events atomically.
+func (w *Writer[T]) Insert(ctx context.Context, doc *T) error {
+	return w.inSession(ctx, "insert", func(col *documents.CollectionOf[T], es *events.Store) error {
+		if err := col.Insert(ctx, doc); err != nil {
+			return err
+		}
+		return w.appendMirrored(ctx, es, OpInsert, doc)
+	})
+}
+
+// Update replaces a document and appends its mirrored events atomically.
+func (w *Writer[T]) Update(ctx context.Context, doc *T) error {
+	return w.inSession(ctx, "update", func(col *documents.CollectionOf[T], es *events.Store) error {
+		if err := col.Update(ctx, doc); err != nil {
+			return err
+		}
+		return w.appendMirrored(ctx, es, OpUpdate, doc)
+	})
+}
+
+// Delete removes a document and appends its mirrored events atomically.
+func (w *Writer[T]) Delete(ctx context.Context, id string) error {
+	return w.inSession(ctx, "delete", func(col *documents.CollectionOf[T], es *events.Store) error {
+		doc, err := col.Load(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := col.Delete(ctx, id); err != nil {
+			return err
+		}
+		return w.appendMirrored(ctx, es, OpDelete, doc)
+	})
+}
+
+// Append writes events to the document's stream and folds them into the
+// stored document in the same transaction. Requires WithApply.
+func (w *Writer[T]) Append(ctx context.Context, docID string, expectedVersion int, evts []events.Event) error {
+	if w.apply == nil {
+		return fmt.Errorf("dualwrite %s: append %s: no apply function configured", w.collection, docID)
+	}
+	return w.inSession(ctx, "append", func(col *documents.CollectionOf[T], es *events.Store) error {
+		if err := es.Append(ctx, w.streamID(docID), expectedVersion, evts); err != nil {
+			return err
+		}
+
+		state, err := col.Load(ctx, docID)
+		existed := err == nil
+		if err != nil && !errors.Is(err, whisker.ErrNotFound) {
+			return err
+		}
+		var loadedVersion int
+		if existed {
+			loadedVersion, _ = meta.ExtractVersion(state)
+		}
+
+		for _, evt := range evts {
+			if state, err = w.apply(state, evt); err != nil {
+				return fmt.Errorf("apply %s: %w", evt.Type, err)
+			}
+		}
+
+		if state == nil {
+			if existed {
+				return col.Delete(ctx, docID)
+			}
+			return nil
+		}
+		meta.SetID(state, docID)
+		if existed {
+			meta.SetVersion(state, loadedVersion)
+			return col.Update(ctx, state)
+		}
+		return col.Insert(ctx, state)
+	})
+}
+
+// Verify replays the document's stream through the apply function and
+// compares the result with the stored document. Returns ErrDrift when they
+// disagree. Requires WithApply.
+func (w *Writer[T]) Verify(ctx context.Context, docID string) error {
+	if w.apply == nil {
+		return fmt.Errorf("dualwrite %s: verify %s: no apply function configured", w.collection, docID)
+	}
+
+	stored, err := documents.Collection[T](w.store, w.collection).Load(ctx, docID)
+	if err != nil && !errors.Is(err, whisker.ErrNotFound) {
+		return fmt.Errorf("dualwrite %s: verify %s: %w", w.collection, docID, err)
+	}
+
+	evts, err := events.New(w.store).ReadStream(ctx, w.streamID(docID), 0)
+	if err != nil {
+		return fmt.Errorf("dualwrite %s: verify %s: %w", w.collection, docID, err)
+	}
+
+	var replayed *T
+	for _, evt := range evts {
+		if replayed, err = w.apply(replayed, evt); err != nil {
+			return fmt.Errorf("dualwrite %s: verify %s: apply %s: %w", w.collection, docID, evt.Type, err)
+		}
+	}
+
+	same, err := w.equal(stored, replayed)
+	if err != nil {
+		return fmt.Errorf("dualwrite %s: verify %s: %w", w.collection, docID, err)
+	}
+	if !same {
+		return fmt.Errorf("dualwrite %s: verify %s: %w", w.collection, docID, ErrDrift)
+	}
+	return nil
+}
+
+// equal compares document data as serialized by the store codec, ignoring ID
+// and Version which live outside the JSONB payload.
+func (w *Writer[T]) equal(a, b *T) (bool, error) {
+	if a == nil || b == nil {
+		return a == nil && b == nil, nil
+	}
+	codec := w.store.JSONCodec()
+	ad, err := codec.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	bd, err := codec.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(ad, bd), nil
+}
+
+func (w *Writer[T]) appendMirrored(ctx context.Context, es *events.Store, op Op, doc *T) error {
+	evts := w.mirror(op, doc)
+	if len(evts) == 0 {
+		return nil
+	}
+	id, err := meta.ExtractID(doc)
+	if err != nil {
+		return err
+	}
+	streamID := w.streamID(id)
+	version, err := es.StreamVersion(ctx, streamID)
+	if err != nil {
+		return err
+	}
+	return es.Append(ctx, streamID, version, evts)
+}
+
+func (w *Writer[T]) inSession(ctx context.Context, op string, fn func(*documents.CollectionOf[T], *events.Store) error) error {
+	sess, err := w.store.Session(ctx)
+	if err != nil {
+		return fmt.Errorf("dualwrite %s: %s: %w", w.collection, op, err)
+	}
+	defer func() { _ = sess.Close(ctx) }()
+
+	if err := fn(documents.Collection[T](sess, w.collection), events.New(sess)); err != nil {
+		return fmt.Errorf("dualwrite %s: %s: %w", w.collection, op, err)
+	}
+	return sess.Commit(ctx)
+}
@@ -0,0 +1,142 @@
+//go:build integration
+
+package dualwrite_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/documents"
+	"github.com/ripkitten-co/whisker/dualwrite"
+	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/internal/testutil"
+)
+
+type Account struct {
+	ID      string
+	Owner   string
+	Version int
+}
+
+func setupStore(t *testing.T) *whisker.Store {
+	t.Helper()
+	connStr := testutil.SetupPostgres(t)
+	store, err := whisker.New(context.Background(), connStr)
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func mirror(op dualwrite.Op, doc *Account) []events.Event {
+	switch op {
+	case dualwrite.OpInsert:
+		return []events.Event{{Type: "AccountOpened", Data: []byte(`{"owner":"` + doc.Owner + `"}`)}}
+	case dualwrite.OpUpdate:
+		return []events.Event{{Type: "OwnerChanged", Data: []byte(`{"owner":"` + doc.Owner + `"}`)}}
+	default:
+		return []events.Event{{Type: "AccountClosed", Data: []byte(`{}`)}}
+	}
+}
+
+func apply(state *Account, evt events.Event) (*Account, error) {
+	switch evt.Type {
+	case "AccountOpened", "OwnerChanged":
+		owner := string(evt.Data[len(`{"owner":"`) : len(evt.Data)-2])
+		if state == nil {
+			state = &Account{}
+		}
+		state.Owner = owner
+		return state, nil
+	case "AccountClosed":
+		return nil, nil
+	}
+	return state, nil
+}
+
+func TestWriter_MirrorsCollectionWrites(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	w := dualwrite.New[Account](store, "accounts", mirror).
+		WithStreamID(func(id string) string { return "account-" + id }).
+		WithApply(apply)
+
+	acct := &Account{ID: "a1", Owner: "alice"}
+	if err := w.Insert(ctx, acct); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	acct.Owner = "bob"
+	if err := w.Update(ctx, acct); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	stream, err := events.New(store).ReadStream(ctx, "account-a1", 0)
+	if err != nil {
+		t.Fatalf("read stream: %v", err)
+	}
+	if len(stream) != 2 {
+		t.Fatalf("got %d events, want 2", len(stream))
+	}
+
+	if err := w.Verify(ctx, "a1"); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	if err := w.Delete(ctx, "a1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := w.Verify(ctx, "a1"); err != nil {
+		t.Fatalf("verify after delete: %v", err)
+	}
+}
+
+func TestWriter_AppendUpdatesDocument(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	w := dualwrite.New[Account](store, "accounts", mirror).WithApply(apply)
+
+	err := w.Append(ctx, "a2", 0, []events.Event{
+		{Type: "AccountOpened", Data: []byte(`{"owner":"carol"}`)},
+	})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	got, err := documents.Collection[Account](store, "accounts").Load(ctx, "a2")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Owner != "carol" {
+		t.Errorf("owner: got %q, want %q", got.Owner, "carol")
+	}
+}
+
+func TestWriter_VerifyDetectsDrift(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	w := dualwrite.New[Account](store, "accounts", mirror).WithApply(apply)
+	if err := w.Insert(ctx, &Account{ID: "a3", Owner: "dave"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// bypass the writer so the stream falls behind
+	col := documents.Collection[Account](store, "accounts")
+	doc, err := col.Load(ctx, "a3")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	doc.Owner = "eve"
+	if err := col.Update(ctx, doc); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	if err := w.Verify(ctx, "a3"); !errors.Is(err, dualwrite.ErrDrift) {
+		t.Fatalf("got %v, want ErrDrift", err)
+	}
+}
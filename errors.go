@@ -1,6 +1,9 @@
 package whisker
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrNotFound is returned when a document or stream does not exist.
@@ -18,4 +21,91 @@ var (
 
 	// ErrBatchTooLarge is returned when a batch exceeds the configured maximum size.
 	ErrBatchTooLarge = errors.New("batch too large")
+
+	// ErrForeignKey is returned when a write violates a whisker:"fk=..."
+	// reference: the referenced document is missing, or a deleted document is
+	// still referenced.
+	ErrForeignKey = errors.New("foreign key violation")
+
+	// ErrStoreClosed is returned when starting a session, lock or listener on
+	// a store that is shutting down.
+	ErrStoreClosed = errors.New("store closed")
+
+	// ErrMaintenance is returned by writes while the store is quiesced for
+	// maintenance (see Store.Quiesce).
+	ErrMaintenance = errors.New("store quiesced for maintenance")
+
+	// ErrReadOnly is returned by writes through a read-only handle (see
+	// Store.ReadOnly).
+	ErrReadOnly = errors.New("read-only store")
+
+	// ErrNoTenant is returned by operations on a tenant-scoped collection or
+	// event store when the context carries no tenant (see WithTenant).
+	ErrNoTenant = errors.New("no tenant in context")
 )
+
+// DocumentError describes a failed operation on one document. Err is the
+// cause, usually one of the sentinels above, so errors.Is still matches it;
+// use errors.As to read the collection and id without parsing the message.
+type DocumentError struct {
+	Collection string
+	ID         string
+	// Op is the operation that failed, such as "insert", "update",
+	// "delete", "load" or, for attachments, "attach" and "detach".
+	Op  string
+	Err error
+}
+
+func (e *DocumentError) Error() string {
+	return fmt.Sprintf("collection %s: %s %s: %v", e.Collection, e.Op, e.ID, e.Err)
+}
+
+func (e *DocumentError) Unwrap() error { return e.Err }
+
+// StreamError describes a failed operation on an event stream, such as an
+// append rejected with ErrConcurrencyConflict or ErrStreamExists, which Err
+// wraps. ExpectedVersion is set for appends rejected by their version check.
+type StreamError struct {
+	StreamID        string
+	ExpectedVersion int
+	// Op is the operation that failed, e.g. "append".
+	Op  string
+	Err error
+}
+
+func (e *StreamError) Error() string {
+	if e.ExpectedVersion > 0 {
+		return fmt.Sprintf("events: %s %s: expected version %d: %v", e.Op, e.StreamID, e.ExpectedVersion, e.Err)
+	}
+	return fmt.Sprintf("events: %s %s: %v", e.Op, e.StreamID, e.Err)
+}
+
+func (e *StreamError) Unwrap() error { return e.Err }
+
+// errorCodes maps the sentinels to the codes Code returns.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrNotFound, "not_found"},
+	{ErrConcurrencyConflict, "concurrency_conflict"},
+	{ErrStreamExists, "stream_exists"},
+	{ErrDuplicateID, "duplicate_id"},
+	{ErrBatchTooLarge, "batch_too_large"},
+	{ErrForeignKey, "foreign_key"},
+	{ErrStoreClosed, "store_closed"},
+	{ErrMaintenance, "maintenance"},
+	{ErrReadOnly, "read_only"},
+}
+
+// Code returns a stable, machine-readable code for the sentinel err wraps,
+// such as "not_found" or "concurrency_conflict", for APIs that map errors to
+// responses. It returns "" when err wraps none of them.
+func Code(err error) string {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package whisker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDocumentError_MatchesSentinel(t *testing.T) {
+	var err error = &DocumentError{Collection: "users", ID: "u1", Op: "load", Err: ErrNotFound}
+	err = fmt.Errorf("handler: %w", err)
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Error("errors.Is(err, ErrNotFound) = false")
+	}
+	var docErr *DocumentError
+	if !errors.As(err, &docErr) {
+		t.Fatal("errors.As(err, *DocumentError) = false")
+	}
+	if docErr.Collection != "users" || docErr.ID != "u1" || docErr.Op != "load" {
+		t.Errorf("got %+v", docErr)
+	}
+	if want := "handler: collection users: load u1: not found"; err.Error() != want {
+		t.Errorf("message = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestStreamError_MatchesSentinel(t *testing.T) {
+	err := &StreamError{
+		StreamID:        "order-1",
+		ExpectedVersion: 2,
+		Op:              "append",
+		Err:             ErrConcurrencyConflict,
+	}
+
+	if !errors.Is(err, ErrConcurrencyConflict) {
+		t.Error("errors.Is(err, ErrConcurrencyConflict) = false")
+	}
+	if want := "events: append order-1: expected version 2: concurrency conflict"; err.Error() != want {
+		t.Errorf("message = %q, want %q", err.Error(), want)
+	}
+	tenantless := &StreamError{StreamID: "order-1", Op: "read", Err: ErrNoTenant}
+	if want := "events: read order-1: no tenant in context"; tenantless.Error() != want {
+		t.Errorf("message = %q, want %q", tenantless.Error(), want)
+	}
+}
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&DocumentError{Collection: "users", ID: "u1", Op: "update", Err: ErrConcurrencyConflict}, "concurrency_conflict"},
+		{&StreamError{StreamID: "s", Op: "append", Err: ErrStreamExists}, "stream_exists"},
+		{fmt.Errorf("%w: insert violates fk", ErrForeignKey), "foreign_key"},
+		{ErrReadOnly, "read_only"},
+		{errors.New("boom"), ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := Code(tt.err); got != tt.want {
+			t.Errorf("Code(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// ReadStreamAsOf returns the events of a stream that had been appended by t:
+// those whose created_at is at or before t, in version order. Returns an
+// empty slice if the stream did not exist yet. created_at is the database
+// time of the append, or the store's clock when one is configured.
+func (es *Store) ReadStreamAsOf(ctx context.Context, streamID string, t time.Time) ([]Event, error) {
+	return es.readStream(ctx, streamID, sq.LtOrEq{"created_at": t})
+}
+
+// HeadPositionAsOf returns the highest global_position of the events
+// appended by t, those whose created_at is at or before t, or 0 if there
+// were none. Global positions follow commit order while created_at is taken
+// when the append starts, so an append still in flight at t may end up
+// either side of the returned position. It is scoped to the tenant of ctx
+// as HeadPosition is.
+func (es *Store) HeadPositionAsOf(ctx context.Context, t time.Time) (int64, error) {
+	if err := es.ensure(ctx); err != nil {
+		return 0, err
+	}
+
+	builder := psql.Select("COALESCE(MAX(global_position), 0)").From(es.table).Where(sq.LtOrEq{"created_at": t})
+	sql, args, err := whereLogTenant(builder, es.logTenant(ctx)).ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("events: head position as of %s: build sql: %w", t.Format(time.RFC3339), err)
+	}
+	var pos int64
+	err = es.exec.QueryRow(ctx, sql, args...).Scan(&pos)
+	if err != nil {
+		return 0, fmt.Errorf("events: head position as of %s: %w", t.Format(time.RFC3339), err)
+	}
+	return pos, nil
+}
+
+// Reducer folds one event into the state of an aggregate.
+type Reducer[S any] func(state S, evt Event) (S, error)
+
+// FoldAsOf rebuilds the state of a stream as it was at t: it folds the
+// events ReadStreamAsOf returns through reduce, starting from initial. Use
+// it to answer what an aggregate looked like at a point in the past. A
+// stream that did not exist yet yields initial.
+func FoldAsOf[S any](ctx context.Context, es *Store, streamID string, t time.Time, initial S, reduce Reducer[S]) (S, error) {
+	evts, err := es.ReadStreamAsOf(ctx, streamID, t)
+	if err != nil {
+		return initial, err
+	}
+	return Fold(evts, initial, reduce)
+}
+
+// Fold folds evts through reduce in order, starting from initial. It stops
+// at the first error reduce returns.
+func Fold[S any](evts []Event, initial S, reduce Reducer[S]) (S, error) {
+	state := initial
+	for _, evt := range evts {
+		next, err := reduce(state, evt)
+		if err != nil {
+			return state, fmt.Errorf("events: fold %s version %d: %w", evt.StreamID, evt.Version, err)
+		}
+		state = next
+	}
+	return state, nil
+}
@@ -0,0 +1,48 @@
+package events
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFold(t *testing.T) {
+	evts := []Event{
+		{StreamID: "s", Version: 1, Type: "A"},
+		{StreamID: "s", Version: 2, Type: "B"},
+	}
+	got, err := Fold(evts, "", func(state string, evt Event) (string, error) {
+		return state + evt.Type, nil
+	})
+	if err != nil || got != "AB" {
+		t.Errorf("got %q, %v, want AB", got, err)
+	}
+
+	got, err = Fold(nil, "initial", func(string, Event) (string, error) {
+		t.Error("reduce called without events")
+		return "", nil
+	})
+	if err != nil || got != "initial" {
+		t.Errorf("no events: got %q, %v", got, err)
+	}
+}
+
+func TestFold_StopsAtError(t *testing.T) {
+	boom := errors.New("boom")
+	evts := []Event{
+		{StreamID: "s", Version: 1, Type: "A"},
+		{StreamID: "s", Version: 2, Type: "Bad"},
+		{StreamID: "s", Version: 3, Type: "C"},
+	}
+	got, err := Fold(evts, "", func(state string, evt Event) (string, error) {
+		if evt.Type == "Bad" {
+			return state, boom
+		}
+		return state + evt.Type, nil
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("got %v, want boom", err)
+	}
+	if got != "A" {
+		t.Errorf("got state %q, want the state before the failing event", got)
+	}
+}
@@ -0,0 +1,117 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ripkitten-co/whisker"
+)
+
+// RawDoc is an undecoded document row passed to a backfill function.
+type RawDoc struct {
+	ID        string
+	Data      []byte
+	Version   int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// BackfillFunc maps an existing document to the synthetic events that seed
+// its stream. Events with an empty StreamID go to the stream named after the
+// document ID. Returning no events skips the document.
+type BackfillFunc func(doc RawDoc) []Event
+
+const defaultBackfillBatch = 500
+
+// BackfillFromCollection walks the whisker_{collection} table in keyset
+// batches ordered by id and appends the events returned by fn as new streams.
+// Streams that already have events are left untouched, so an interrupted
+// backfill can be rerun safely. Returns the number of streams seeded.
+func BackfillFromCollection(ctx context.Context, b whisker.Backend, collection string, fn BackfillFunc) (int, error) {
+	exec := b.DBExecutor()
+	if err := b.SchemaBootstrap().EnsureCollection(ctx, exec, collection); err != nil {
+		return 0, fmt.Errorf("events: backfill %s: %w", collection, err)
+	}
+
+	es := New(b)
+	batch := b.MaxBatchSize()
+	if batch <= 0 {
+		batch = defaultBackfillBatch
+	}
+	query := fmt.Sprintf(
+		`SELECT id, data, version, created_at, updated_at FROM whisker_%s WHERE id > $1 ORDER BY id LIMIT $2`,
+		collection,
+	)
+
+	seeded := 0
+	after := ""
+	for {
+		docs, err := readBackfillBatch(ctx, es, query, after, batch)
+		if err != nil {
+			return seeded, fmt.Errorf("events: backfill %s: %w", collection, err)
+		}
+		for _, doc := range docs {
+			n, err := es.seedStreams(ctx, doc.ID, fn(doc))
+			seeded += n
+			if err != nil {
+				return seeded, fmt.Errorf("events: backfill %s: document %s: %w", collection, doc.ID, err)
+			}
+		}
+		if len(docs) < batch {
+			return seeded, nil
+		}
+		after = docs[len(docs)-1].ID
+	}
+}
+
+func readBackfillBatch(ctx context.Context, es *Store, query, after string, limit int) ([]RawDoc, error) {
+	rows, err := es.exec.Query(ctx, query, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []RawDoc
+	for rows.Next() {
+		var d RawDoc
+		if err := rows.Scan(&d.ID, &d.Data, &d.Version, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		docs = append(docs, d)
+	}
+	return docs, rows.Err()
+}
+
+// seedStreams groups evts by stream and appends each group to a new stream,
+// skipping streams that already exist.
+func (es *Store) seedStreams(ctx context.Context, docID string, evts []Event) (int, error) {
+	var order []string
+	groups := make(map[string][]Event)
+	for _, evt := range evts {
+		streamID := evt.StreamID
+		if streamID == "" {
+			streamID = docID
+		}
+		if _, ok := groups[streamID]; !ok {
+			order = append(order, streamID)
+		}
+		groups[streamID] = append(groups[streamID], evt)
+	}
+
+	seeded := 0
+	for _, streamID := range order {
+		version, err := es.StreamVersion(ctx, streamID)
+		if err != nil {
+			return seeded, err
+		}
+		if version > 0 {
+			continue
+		}
+		if err := es.Append(ctx, streamID, 0, groups[streamID]); err != nil {
+			return seeded, err
+		}
+		seeded++
+	}
+	return seeded, nil
+}
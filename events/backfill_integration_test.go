@@ -0,0 +1,59 @@
+//go:build integration
+
+package events_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/documents"
+	"github.com/ripkitten-co/whisker/events"
+)
+
+type backfillUser struct {
+	ID      string
+	Name    string
+	Version int
+}
+
+func TestBackfillFromCollection(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[backfillUser](store, "backfill_users")
+
+	for _, id := range []string{"u1", "u2", "u3"} {
+		if err := users.Insert(ctx, &backfillUser{ID: id, Name: "name-" + id}); err != nil {
+			t.Fatalf("insert %s: %v", id, err)
+		}
+	}
+
+	toEvents := func(doc events.RawDoc) []events.Event {
+		return []events.Event{{StreamID: "user-" + doc.ID, Type: "UserImported", Data: doc.Data}}
+	}
+
+	n, err := events.BackfillFromCollection(ctx, store, "backfill_users", toEvents)
+	if err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("seeded: got %d, want 3", n)
+	}
+
+	es := events.New(store)
+	got, err := es.ReadStream(ctx, "user-u2", 0)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(got) != 1 || got[0].Type != "UserImported" {
+		t.Fatalf("stream user-u2: got %+v", got)
+	}
+
+	// rerun skips streams that already exist
+	n, err = events.BackfillFromCollection(ctx, store, "backfill_users", toEvents)
+	if err != nil {
+		t.Fatalf("second backfill: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("second run seeded: got %d, want 0", n)
+	}
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// CorrelationKey is the metadata key Filter.CorrelationID matches.
+const CorrelationKey = "correlation_id"
+
+const (
+	defaultBrowseLimit = 100
+	maxBrowseLimit     = 1000
+)
+
+// Filter selects events for Browse. Zero-valued fields match every event.
+type Filter struct {
+	StreamID string
+	// Types matches events of any of the given types.
+	Types []string
+	// Since and Until bound created_at: Since is inclusive, Until exclusive.
+	Since time.Time
+	Until time.Time
+	// Metadata matches events whose metadata contains the given object, as
+	// with the jsonb @> operator.
+	Metadata map[string]any
+	// CorrelationID matches the CorrelationKey metadata field, following a
+	// flow of work across streams.
+	CorrelationID string
+	// AfterPosition is the cursor: only events after this global position
+	// are returned. Pass the previous Page's Next to read on.
+	AfterPosition int64
+	// Limit caps the page size. Defaults to 100; at most 1000.
+	Limit int
+}
+
+// Page is one page of Browse results in global position order.
+type Page struct {
+	Events []Event
+	// Next is the cursor for the following page, or 0 when this is the last.
+	Next int64
+}
+
+// Browse returns the events matching f across all streams, oldest first, a
+// page at a time. It backs debugging tools such as event browsers; use
+// ReadStream or ReadAll to consume events.
+func (es *Store) Browse(ctx context.Context, f Filter) (Page, error) {
+	if err := es.ensure(ctx); err != nil {
+		return Page{}, err
+	}
+	if err := es.ensureIndex(ctx); err != nil {
+		return Page{}, err
+	}
+
+	builder, limit, err := browseQuery(es.table, f)
+	if err != nil {
+		return Page{}, fmt.Errorf("events: browse: %w", err)
+	}
+	sql, args, err := whereLogTenant(builder, es.logTenant(ctx)).ToSql()
+	if err != nil {
+		return Page{}, fmt.Errorf("events: browse: build sql: %w", err)
+	}
+
+	rows, err := es.exec.Query(ctx, sql, args...)
+	if err != nil {
+		return Page{}, fmt.Errorf("events: browse: %w", err)
+	}
+	defer rows.Close()
+
+	var page Page
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.StreamID, &e.Version, &e.Type, &e.Data, &e.Metadata, &e.CreatedAt, &e.GlobalPosition); err != nil {
+			return Page{}, fmt.Errorf("events: browse: scan: %w", err)
+		}
+		page.Events = append(page.Events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return Page{}, fmt.Errorf("events: browse: %w", err)
+	}
+
+	// one extra row was read to tell whether another page follows
+	if len(page.Events) > limit {
+		page.Events = page.Events[:limit]
+		page.Next = page.Events[limit-1].GlobalPosition
+	}
+	return page, nil
+}
+
+// browseQuery builds the query for f on table, reading one row past the page
+// limit.
+func browseQuery(table string, f Filter) (sq.SelectBuilder, int, error) {
+	limit := f.Limit
+	switch {
+	case limit < 0:
+		return sq.SelectBuilder{}, 0, fmt.Errorf("negative limit %d", limit)
+	case limit == 0:
+		limit = defaultBrowseLimit
+	case limit > maxBrowseLimit:
+		limit = maxBrowseLimit
+	}
+
+	builder := psql.
+		Select("stream_id", "version", "type", "data", "metadata", "created_at", "global_position").
+		From(table).
+		Where(sq.Gt{"global_position": f.AfterPosition}).
+		OrderBy("global_position ASC").
+		Limit(uint64(limit + 1))
+
+	if f.StreamID != "" {
+		builder = builder.Where(sq.Eq{"stream_id": f.StreamID})
+	}
+	if len(f.Types) > 0 {
+		builder = builder.Where(sq.Eq{"type": f.Types})
+	}
+	if !f.Since.IsZero() {
+		builder = builder.Where(sq.GtOrEq{"created_at": f.Since})
+	}
+	if !f.Until.IsZero() {
+		builder = builder.Where(sq.Lt{"created_at": f.Until})
+	}
+	if len(f.Metadata) > 0 {
+		contained, err := json.Marshal(f.Metadata)
+		if err != nil {
+			return sq.SelectBuilder{}, 0, fmt.Errorf("encode metadata filter: %w", err)
+		}
+		builder = builder.Where(sq.Expr("metadata @> ?::jsonb", string(contained)))
+	}
+	if f.CorrelationID != "" {
+		builder = builder.Where(sq.Expr("metadata->>'"+CorrelationKey+"' = ?", f.CorrelationID))
+	}
+	return builder, limit, nil
+}
@@ -0,0 +1,60 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBrowseQuery(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	builder, limit, err := browseQuery("whisker_events", Filter{
+		StreamID:      "order-1",
+		Types:         []string{"OrderCreated", "OrderPaid"},
+		Since:         since,
+		Metadata:      map[string]any{"tenant": "acme"},
+		CorrelationID: "req-7",
+		AfterPosition: 42,
+		Limit:         10,
+	})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if limit != 10 {
+		t.Errorf("limit = %d, want 10", limit)
+	}
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		t.Fatalf("to sql: %v", err)
+	}
+	want := "SELECT stream_id, version, type, data, metadata, created_at, global_position FROM whisker_events" +
+		" WHERE global_position > $1 AND stream_id = $2 AND type IN ($3,$4) AND created_at >= $5" +
+		" AND metadata @> $6::jsonb AND metadata->>'correlation_id' = $7 ORDER BY global_position ASC LIMIT 11"
+	if sql != want {
+		t.Errorf("sql:\n got: %s\nwant: %s", sql, want)
+	}
+	if len(args) != 7 || args[5] != `{"tenant":"acme"}` || args[6] != "req-7" {
+		t.Errorf("args = %v", args)
+	}
+}
+
+func TestBrowseQuery_Limits(t *testing.T) {
+	tests := []struct {
+		in, want int
+	}{
+		{0, defaultBrowseLimit},
+		{5, 5},
+		{maxBrowseLimit + 1, maxBrowseLimit},
+	}
+	for _, tt := range tests {
+		_, limit, err := browseQuery("whisker_events", Filter{Limit: tt.in})
+		if err != nil {
+			t.Fatalf("limit %d: %v", tt.in, err)
+		}
+		if limit != tt.want {
+			t.Errorf("limit %d: got %d, want %d", tt.in, limit, tt.want)
+		}
+	}
+	if _, _, err := browseQuery("whisker_events", Filter{Limit: -1}); err == nil {
+		t.Error("expected error for negative limit")
+	}
+}
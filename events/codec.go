@@ -0,0 +1,119 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/ripkitten-co/whisker/internal/codecs"
+)
+
+// Option configures an event store.
+type Option func(*Store)
+
+// WithCodec sets the codec Encode, AppendTyped and Decode use for event
+// payloads. Defaults to the store's document codec, so payloads get the same
+// field naming as documents, including leaving out ID and Version fields.
+// A codec that does not produce JSON, such as one for protobuf, needs
+// WithBinaryPayloads.
+func WithCodec(c codecs.Codec) Option {
+	return func(es *Store) { es.codec = c }
+}
+
+// WithBinaryPayloads stores event data in a BYTEA column instead of JSONB,
+// for payloads that are not JSON. It only shapes the table when it is
+// created, and a JSONB table already in place is reported as an error, so
+// binary events belong in their own named store. Warm the store at startup:
+// readers that open it without this option, such as a projection daemon,
+// would otherwise create a JSONB table first. Metadata stays JSONB.
+func WithBinaryPayloads() Option {
+	return func(es *Store) { es.binary = true }
+}
+
+// WithoutNotify stops Append from notifying projection pollers, saving a
+// round trip per append during bulk imports. Pollers still find the events on
+// their next polling interval. Use a separate store for the import:
+//
+//	importer := events.New(store, events.WithoutNotify())
+func WithoutNotify() Option {
+	return func(es *Store) { es.noNotify = true }
+}
+
+// WithTenancy records the tenant of the context that appends events (see
+// whisker.WithTenant) in a tenant_id column of the events table, and scopes
+// Append, StreamVersion and the stream reads to the context's tenant. They
+// fail with whisker.ErrNoTenant when the context has none. Stream IDs remain
+// unique across tenants, so appending to another tenant's new stream fails
+// with ErrStreamExists. ReadAll, ReadAllExcept, Browse, HeadPosition and
+// HeadPositionAsOf read the whole log only when the context has no tenant, as
+// for projections, which see every tenant's events with Event.TenantID
+// telling them apart; with a tenant they are restricted to its events.
+func WithTenancy() Option {
+	return func(es *Store) { es.tenancy = true }
+}
+
+// EventTyper is implemented by payloads that name their own event type.
+type EventTyper interface {
+	EventType() string
+}
+
+// TypeOf returns the event type recorded for payload: its EventType if it
+// implements EventTyper, and otherwise the name of its Go type, pointers
+// dereferenced. It returns "" for unnamed types such as maps.
+func TypeOf(payload any) string {
+	if t, ok := payload.(EventTyper); ok {
+		return t.EventType()
+	}
+	rt := reflect.TypeOf(payload)
+	for rt != nil && rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	if rt == nil {
+		return ""
+	}
+	return rt.Name()
+}
+
+// Encode returns an event of type TypeOf(payload) whose data is payload
+// encoded with the store's codec.
+func (es *Store) Encode(payload any) (Event, error) {
+	evt, err := es.encode(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("events: %w", err)
+	}
+	return evt, nil
+}
+
+func (es *Store) encode(payload any) (Event, error) {
+	typ := TypeOf(payload)
+	if typ == "" {
+		return Event{}, fmt.Errorf("encode %T: unnamed type; implement EventTyper", payload)
+	}
+	data, err := es.codec.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("encode %s: %w", typ, err)
+	}
+	return Event{Type: typ, Data: data}, nil
+}
+
+// AppendTyped encodes each payload as Encode does and appends the events to
+// the stream, with the same concurrency checks as Append.
+func (es *Store) AppendTyped(ctx context.Context, streamID string, expectedVersion int, payloads ...any) error {
+	evts := make([]Event, len(payloads))
+	for i, p := range payloads {
+		evt, err := es.encode(p)
+		if err != nil {
+			return fmt.Errorf("events: append %s: %w", streamID, err)
+		}
+		evts[i] = evt
+	}
+	return es.Append(ctx, streamID, expectedVersion, evts)
+}
+
+// Decode decodes the data of evt into v with the store's codec.
+func (es *Store) Decode(evt Event, v any) error {
+	if err := es.codec.Unmarshal(evt.Data, v); err != nil {
+		return fmt.Errorf("events: decode %s %s/%d: %w", evt.Type, evt.StreamID, evt.Version, err)
+	}
+	return nil
+}
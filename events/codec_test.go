@@ -0,0 +1,84 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/internal/codecs"
+)
+
+type OrderPlaced struct {
+	OrderID string
+	Total   int
+}
+
+type renamed struct{}
+
+func (renamed) EventType() string { return "order.renamed" }
+
+func TestTypeOf(t *testing.T) {
+	for _, tc := range []struct {
+		payload any
+		want    string
+	}{
+		{OrderPlaced{}, "OrderPlaced"},
+		{&OrderPlaced{}, "OrderPlaced"},
+		{renamed{}, "order.renamed"},
+		{map[string]any{}, ""},
+		{nil, ""},
+	} {
+		if got := TypeOf(tc.payload); got != tc.want {
+			t.Errorf("TypeOf(%#v) = %q, want %q", tc.payload, got, tc.want)
+		}
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	es := &Store{codec: codecs.NewWhisker(codecs.NewJSONIter())}
+
+	evt, err := es.Encode(&OrderPlaced{OrderID: "o-1", Total: 42})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if evt.Type != "OrderPlaced" {
+		t.Errorf("type: got %q", evt.Type)
+	}
+
+	var got OrderPlaced
+	if err := es.Decode(evt, &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got != (OrderPlaced{OrderID: "o-1", Total: 42}) {
+		t.Errorf("got %+v", got)
+	}
+}
+
+type failingCodec struct{}
+
+func (failingCodec) Marshal(any) ([]byte, error) { return nil, errors.New("boom") }
+func (failingCodec) Unmarshal([]byte, any) error { return errors.New("boom") }
+
+func TestEncodeErrors(t *testing.T) {
+	es := &Store{codec: failingCodec{}}
+	if _, err := es.Encode(map[string]any{"a": 1}); err == nil || !strings.Contains(err.Error(), "implement EventTyper") {
+		t.Errorf("unnamed type: got %v", err)
+	}
+	if _, err := es.Encode(OrderPlaced{}); err == nil || !strings.Contains(err.Error(), "encode OrderPlaced: boom") {
+		t.Errorf("codec failure: got %v", err)
+	}
+	err := es.AppendTyped(context.Background(), "order-1", 0, OrderPlaced{})
+	if err == nil || !strings.HasPrefix(err.Error(), "events: append order-1: encode OrderPlaced") {
+		t.Errorf("append typed: got %v", err)
+	}
+}
+
+func TestWithOptions(t *testing.T) {
+	es := &Store{}
+	WithCodec(failingCodec{})(es)
+	WithBinaryPayloads()(es)
+	if _, ok := es.codec.(failingCodec); !ok || !es.binary {
+		t.Errorf("options not applied: %+v", es)
+	}
+}
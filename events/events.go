@@ -4,11 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/internal/codecs"
 	"github.com/ripkitten-co/whisker/internal/pg"
 	"github.com/ripkitten-co/whisker/schema"
 )
@@ -24,21 +28,148 @@ type Event struct {
 	Metadata       []byte
 	CreatedAt      time.Time
 	GlobalPosition int64
+	// TenantID is the tenant that appended the event, read only by stores
+	// opened WithTenancy.
+	TenantID string
 }
 
 // Store provides append-only event stream operations backed by a single
-// whisker_events table.
+// events table: whisker_events, or whisker_events_{name} for a named store.
 type Store struct {
 	exec   pg.Executor
 	schema *schema.Bootstrap
+	clock  whisker.Clock
+	name   string
+	table  string
+	codec  codecs.Codec
+	binary bool
+
+	tenancy bool
+
+	noNotify bool
+	// notified records that this transaction's store has already signalled
+	// pollers; PostgreSQL delivers a transaction's notifications on commit,
+	// folding duplicates into one.
+	notified atomic.Bool
 }
 
 // New creates an event store using the given backend's executor and schema.
-func New(b whisker.Backend) *Store {
-	return &Store{
+func New(b whisker.Backend, opts ...Option) *Store {
+	return NewNamed(b, "", opts...)
+}
+
+// NewNamed creates the named event store, kept in its own
+// whisker_events_{name} table with its own global positions, index and
+// append notifications, so that unrelated bounded contexts don't share one
+// log. Streams of different stores are independent: the same stream ID may
+// exist in each. An empty name is the default store New returns. The name
+// follows the rules for collection names; an invalid one fails on first use.
+func NewNamed(b whisker.Backend, name string, opts ...Option) *Store {
+	es := &Store{
 		exec:   b.DBExecutor(),
 		schema: b.SchemaBootstrap(),
+		clock:  whisker.ClockOf(b),
+		name:   name,
+		table:  schema.EventsTable(name),
+		codec:  b.JSONCodec(),
+	}
+	for _, o := range opts {
+		o(es)
+	}
+	return es
+}
+
+// Name returns the store's name, empty for the default store.
+func (es *Store) Name() string {
+	return es.name
+}
+
+func (es *Store) ensure(ctx context.Context) error {
+	var err error
+	if es.binary {
+		err = es.schema.EnsureBinaryEventStore(ctx, es.exec, es.name)
+	} else {
+		err = es.schema.EnsureEventStore(ctx, es.exec, es.name)
+	}
+	if err != nil || !es.tenancy {
+		return err
+	}
+	return es.schema.EnsureTenant(ctx, es.exec, strings.TrimPrefix(es.table, "whisker_"))
+}
+
+// tenant returns the tenant ctx scopes the store to, or "" for a store
+// without tenancy.
+func (es *Store) tenant(ctx context.Context, op, streamID string) (string, error) {
+	if !es.tenancy {
+		return "", nil
+	}
+	id, ok := whisker.TenantFrom(ctx)
+	if !ok {
+		return "", &whisker.StreamError{StreamID: streamID, Op: op, Err: whisker.ErrNoTenant}
+	}
+	return id, nil
+}
+
+// logTenant returns the tenant reads across streams are restricted to: the
+// tenant of ctx for a store with tenancy, and "" when the store has none or
+// ctx carries none, as for projections, which read every tenant's events.
+func (es *Store) logTenant(ctx context.Context) string {
+	if !es.tenancy {
+		return ""
+	}
+	id, _ := whisker.TenantFrom(ctx)
+	return id
+}
+
+// whereLogTenant restricts a read across streams to the events of tenant, if
+// any.
+func whereLogTenant(b sq.SelectBuilder, tenant string) sq.SelectBuilder {
+	if tenant == "" {
+		return b
+	}
+	return b.Where(sq.Eq{"tenant_id": tenant})
+}
+
+// streamWhere is the condition selecting the rows of a stream, restricted to
+// tenant if any.
+func streamWhere(streamID, tenant string) sq.Eq {
+	if tenant == "" {
+		return sq.Eq{"stream_id": streamID}
 	}
+	return sq.Eq{"stream_id": streamID, "tenant_id": tenant}
+}
+
+// columns returns the columns reads select, in the order scanEvent expects.
+func (es *Store) columns() []string {
+	cols := []string{"stream_id", "version", "type", "data", "metadata", "created_at", "global_position"}
+	if es.tenancy {
+		cols = append(cols, "COALESCE(tenant_id, '')")
+	}
+	return cols
+}
+
+// scanEvent scans a row selecting columns().
+func (es *Store) scanEvent(rows pgx.Rows) (Event, error) {
+	var e Event
+	dest := []any{&e.StreamID, &e.Version, &e.Type, &e.Data, &e.Metadata, &e.CreatedAt, &e.GlobalPosition}
+	if es.tenancy {
+		dest = append(dest, &e.TenantID)
+	}
+	err := rows.Scan(dest...)
+	return e, err
+}
+
+func (es *Store) ensureIndex(ctx context.Context) error {
+	return es.schema.EnsureEventStoreGlobalPositionIndex(ctx, es.exec, es.name)
+}
+
+// Warm creates the store's events table and its global position index now
+// rather than on first use. It implements whisker.Warmer.
+func (es *Store) Warm(ctx context.Context) error {
+	if err := es.ensure(ctx); err != nil {
+		return err
+	}
+	return es.ensureIndex(ctx)
 }
 
 // Append writes events to a stream with optimistic concurrency control.
@@ -50,31 +181,45 @@ func (es *Store) Append(ctx context.Context, streamID string, expectedVersion in
 		return fmt.Errorf("events: append %s: at least one event required", streamID)
 	}
 
-	if err := es.schema.EnsureEvents(ctx, es.exec); err != nil {
+	if err := es.ensure(ctx); err != nil {
+		return err
+	}
+	if err := pg.CheckWrite(ctx, es.exec); err != nil {
+		return &whisker.StreamError{StreamID: streamID, Op: "append", Err: err}
+	}
+	tenant, err := es.tenant(ctx, "append", streamID)
+	if err != nil {
 		return err
 	}
 
 	if expectedVersion > 0 {
-		var currentVersion int
-		err := es.exec.QueryRow(ctx,
-			"SELECT COALESCE(MAX(version), 0) FROM whisker_events WHERE stream_id = $1",
-			streamID,
-		).Scan(&currentVersion)
+		currentVersion, err := es.version(ctx, streamID, tenant)
 		if err != nil {
 			return fmt.Errorf("events: append %s: check version: %w", streamID, err)
 		}
 		if currentVersion != expectedVersion {
-			return fmt.Errorf("events: append %s: expected version %d but got %d: %w",
-				streamID, expectedVersion, currentVersion, whisker.ErrConcurrencyConflict)
+			return &whisker.StreamError{StreamID: streamID, ExpectedVersion: expectedVersion, Op: "append", Err: whisker.ErrConcurrencyConflict}
 		}
 	}
 
-	builder := psql.Insert("whisker_events").
+	builder := psql.Insert(es.table).
 		Columns("stream_id", "version", "type", "data", "metadata")
+	if es.clock != nil {
+		builder = builder.Columns("created_at")
+	}
+	if tenant != "" {
+		builder = builder.Columns("tenant_id")
+	}
 
 	for i, evt := range evts {
-		version := expectedVersion + i + 1
-		builder = builder.Values(streamID, version, evt.Type, evt.Data, evt.Metadata)
+		values := []any{streamID, expectedVersion + i + 1, evt.Type, evt.Data, evt.Metadata}
+		if es.clock != nil {
+			values = append(values, es.clock.Now().UTC())
+		}
+		if tenant != "" {
+			values = append(values, tenant)
+		}
+		builder = builder.Values(values...)
 	}
 
 	sql, args, err := builder.ToSql()
@@ -87,35 +232,122 @@ func (es *Store) Append(ctx context.Context, streamID string, expectedVersion in
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
 			if expectedVersion == 0 {
-				return fmt.Errorf("events: append %s: %w", streamID, whisker.ErrStreamExists)
+				return &whisker.StreamError{StreamID: streamID, Op: "append", Err: whisker.ErrStreamExists}
 			}
-			return fmt.Errorf("events: append %s: %w", streamID, whisker.ErrConcurrencyConflict)
+			return &whisker.StreamError{StreamID: streamID, ExpectedVersion: expectedVersion, Op: "append", Err: whisker.ErrConcurrencyConflict}
 		}
 		return fmt.Errorf("events: append %s: %w", streamID, err)
 	}
 
-	// best-effort notification for projection pollers
-	_, _ = es.exec.Exec(ctx, "SELECT pg_notify('whisker_events', '')")
-
+	es.notify(ctx)
 	return nil
 }
 
+// notify wakes projection pollers listening on the table's channel. It is
+// best-effort: pollers also poll on an interval. In a session only the first
+// append notifies, since later notifications would be folded into it on
+// commit anyway. A notification that fails is not recorded, so the next
+// append tries again.
+func (es *Store) notify(ctx context.Context) {
+	if es.noNotify {
+		return
+	}
+	tx, ok := es.exec.(pg.Transactional)
+	inTx := ok && tx.InTransaction()
+	if inTx && es.notified.Load() {
+		return
+	}
+	if _, err := es.exec.Exec(ctx, "SELECT pg_notify($1, '')", es.schema.NotifyChannel(es.table)); err == nil && inTx {
+		es.notified.Store(true)
+	}
+}
+
+// StreamVersion returns the current version of a stream, or 0 if the stream
+// has no events.
+func (es *Store) StreamVersion(ctx context.Context, streamID string) (int, error) {
+	if err := es.ensure(ctx); err != nil {
+		return 0, err
+	}
+	tenant, err := es.tenant(ctx, "version", streamID)
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := es.version(ctx, streamID, tenant)
+	if err != nil {
+		return 0, fmt.Errorf("events: version %s: %w", streamID, err)
+	}
+	return version, nil
+}
+
+// version returns the current version of a stream of tenant.
+func (es *Store) version(ctx context.Context, streamID, tenant string) (int, error) {
+	sql, args, err := psql.Select("COALESCE(MAX(version), 0)").
+		From(es.table).
+		Where(streamWhere(streamID, tenant)).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+	var version int
+	err = es.exec.QueryRow(ctx, sql, args...).Scan(&version)
+	return version, err
+}
+
+// HeadPosition returns the highest global_position in the event store, or 0
+// if there are no events. With WithTenancy and a tenant in ctx, it is the
+// highest of the tenant's events.
+func (es *Store) HeadPosition(ctx context.Context) (int64, error) {
+	if err := es.ensure(ctx); err != nil {
+		return 0, err
+	}
+	if err := es.ensureIndex(ctx); err != nil {
+		return 0, err
+	}
+
+	builder := whereLogTenant(psql.Select("COALESCE(MAX(global_position), 0)").From(es.table), es.logTenant(ctx))
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("events: head position: build sql: %w", err)
+	}
+	var pos int64
+	err = es.exec.QueryRow(ctx, sql, args...).Scan(&pos)
+	if err != nil {
+		return 0, fmt.Errorf("events: head position: %w", err)
+	}
+	return pos, nil
+}
+
 // ReadStream returns all events for a stream starting from fromVersion.
 // Pass 0 to read from the beginning. Returns an empty slice if the stream
 // doesn't exist.
 func (es *Store) ReadStream(ctx context.Context, streamID string, fromVersion int) ([]Event, error) {
-	if err := es.schema.EnsureEvents(ctx, es.exec); err != nil {
+	var where []sq.Sqlizer
+	if fromVersion > 0 {
+		where = append(where, sq.GtOrEq{"version": fromVersion})
+	}
+	return es.readStream(ctx, streamID, where...)
+}
+
+// readStream returns the events of a stream that match every condition in
+// where, in version order.
+func (es *Store) readStream(ctx context.Context, streamID string, where ...sq.Sqlizer) ([]Event, error) {
+	if err := es.ensure(ctx); err != nil {
+		return nil, err
+	}
+	tenant, err := es.tenant(ctx, "read", streamID)
+	if err != nil {
 		return nil, err
 	}
 
 	builder := psql.
-		Select("stream_id", "version", "type", "data", "metadata", "created_at", "global_position").
-		From("whisker_events").
-		Where(sq.Eq{"stream_id": streamID}).
+		Select(es.columns()...).
+		From(es.table).
+		Where(streamWhere(streamID, tenant)).
 		OrderBy("version ASC")
 
-	if fromVersion > 0 {
-		builder = builder.Where(sq.GtOrEq{"version": fromVersion})
+	for _, w := range where {
+		builder = builder.Where(w)
 	}
 
 	sql, args, err := builder.ToSql()
@@ -131,8 +363,8 @@ func (es *Store) ReadStream(ctx context.Context, streamID string, fromVersion in
 
 	var result []Event
 	for rows.Next() {
-		var e Event
-		if err := rows.Scan(&e.StreamID, &e.Version, &e.Type, &e.Data, &e.Metadata, &e.CreatedAt, &e.GlobalPosition); err != nil {
+		e, err := es.scanEvent(rows)
+		if err != nil {
 			return nil, fmt.Errorf("events: read %s: scan: %w", streamID, err)
 		}
 		result = append(result, e)
@@ -145,23 +377,57 @@ func (es *Store) ReadStream(ctx context.Context, streamID string, fromVersion in
 	return result, nil
 }
 
+// ReadStreamStrict is ReadStream for callers that must tell a missing stream
+// from one with no events at or after fromVersion: it returns a StreamError
+// wrapping whisker.ErrNotFound when the stream has no events at all, and an
+// empty slice only when the stream exists.
+func (es *Store) ReadStreamStrict(ctx context.Context, streamID string, fromVersion int) ([]Event, error) {
+	evts, err := es.ReadStream(ctx, streamID, fromVersion)
+	if err != nil || len(evts) > 0 {
+		return evts, err
+	}
+
+	tenant, err := es.tenant(ctx, "read", streamID)
+	if err != nil {
+		return nil, err
+	}
+	sql, args, err := psql.Select("1").From(es.table).Where(streamWhere(streamID, tenant)).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("events: read %s: build sql: %w", streamID, err)
+	}
+	var exists bool
+	err = es.exec.QueryRow(ctx, "SELECT EXISTS ("+sql+")", args...).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("events: read %s: %w", streamID, err)
+	}
+	if !exists {
+		return nil, &whisker.StreamError{StreamID: streamID, Op: "read", Err: whisker.ErrNotFound}
+	}
+	return evts, nil
+}
+
 // ReadAll returns events across all streams ordered by global_position.
 // Pass afterPosition 0 to start from the beginning. Returns up to limit events.
+// With WithTenancy and a tenant in ctx, only the tenant's events are read.
 func (es *Store) ReadAll(ctx context.Context, afterPosition int64, limit int) ([]Event, error) {
-	if err := es.schema.EnsureEvents(ctx, es.exec); err != nil {
+	if err := es.ensure(ctx); err != nil {
 		return nil, err
 	}
-	if err := es.schema.EnsureEventsGlobalPositionIndex(ctx, es.exec); err != nil {
+	if err := es.ensureIndex(ctx); err != nil {
 		return nil, err
 	}
 
 	builder := psql.
-		Select("stream_id", "version", "type", "data", "metadata", "created_at", "global_position").
-		From("whisker_events").
+		Select(es.columns()...).
+		From(es.table).
 		Where(sq.Gt{"global_position": afterPosition}).
 		OrderBy("global_position ASC").
 		Limit(uint64(limit))
+	return es.readAll(ctx, whereLogTenant(builder, es.logTenant(ctx)))
+}
 
+// readAll runs a query across all streams selecting columns().
+func (es *Store) readAll(ctx context.Context, builder sq.SelectBuilder) ([]Event, error) {
 	sql, args, err := builder.ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("events: read all: build sql: %w", err)
@@ -175,8 +441,8 @@ func (es *Store) ReadAll(ctx context.Context, afterPosition int64, limit int) ([
 
 	var result []Event
 	for rows.Next() {
-		var e Event
-		if err := rows.Scan(&e.StreamID, &e.Version, &e.Type, &e.Data, &e.Metadata, &e.CreatedAt, &e.GlobalPosition); err != nil {
+		e, err := es.scanEvent(rows)
+		if err != nil {
 			return nil, fmt.Errorf("events: read all: scan: %w", err)
 		}
 		result = append(result, e)
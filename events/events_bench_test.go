@@ -39,6 +39,48 @@ func BenchmarkAppend(b *testing.B) {
 	}
 }
 
+func BenchmarkAppend_WithoutNotify(b *testing.B) {
+	store, ctx := setupEventBench(b)
+	es := New(store, WithoutNotify())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		streamID := fmt.Sprintf("quiet-%d", i)
+		err := es.Append(ctx, streamID, 0, []Event{
+			{Type: "UserCreated", Data: []byte(`{"name":"Alice"}`)},
+		})
+		if err != nil {
+			b.Fatalf("append: %v", err)
+		}
+	}
+}
+
+// BenchmarkAppend_Session appends 100 streams per session, which notifies
+// once.
+func BenchmarkAppend_Session(b *testing.B) {
+	store, ctx := setupEventBench(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		sess, err := store.Session(ctx)
+		if err != nil {
+			b.Fatalf("session: %v", err)
+		}
+		es := New(sess)
+		for j := range 100 {
+			err := es.Append(ctx, fmt.Sprintf("sess-%d-%d", i, j), 0, []Event{
+				{Type: "UserCreated", Data: []byte(`{"name":"Alice"}`)},
+			})
+			if err != nil {
+				b.Fatalf("append: %v", err)
+			}
+		}
+		if err := sess.Commit(ctx); err != nil {
+			b.Fatalf("commit: %v", err)
+		}
+	}
+}
+
 func BenchmarkAppendBatch(b *testing.B) {
 	store, ctx := setupEventBench(b)
 	es := New(store)
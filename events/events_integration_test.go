@@ -4,6 +4,7 @@ package events_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"testing"
 	"time"
@@ -85,6 +86,10 @@ func TestEvents_AppendWrongVersion(t *testing.T) {
 	if !errors.Is(err, whisker.ErrConcurrencyConflict) {
 		t.Errorf("got %v, want ErrConcurrencyConflict", err)
 	}
+	var streamErr *whisker.StreamError
+	if !errors.As(err, &streamErr) || streamErr.StreamID != "order-1" || streamErr.ExpectedVersion != 5 {
+		t.Errorf("got %#v, want StreamError for order-1 at version 5", err)
+	}
 }
 
 func TestEvents_ReadStreamFromVersion(t *testing.T) {
@@ -209,3 +214,433 @@ func TestEvents_AppendSendsNotification(t *testing.T) {
 		t.Errorf("channel: got %q, want %q", notification.Channel, "whisker_events")
 	}
 }
+
+func TestEvents_BrowseFiltersAndPages(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	err := es.Append(ctx, "order-1", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{}`), Metadata: []byte(`{"correlation_id":"req-1","tenant":"acme"}`)},
+		{Type: "OrderPaid", Data: []byte(`{}`), Metadata: []byte(`{"correlation_id":"req-2","tenant":"acme"}`)},
+	})
+	if err != nil {
+		t.Fatalf("append order: %v", err)
+	}
+	err = es.Append(ctx, "invoice-1", 0, []events.Event{
+		{Type: "InvoiceIssued", Data: []byte(`{}`), Metadata: []byte(`{"correlation_id":"req-1","tenant":"other"}`)},
+	})
+	if err != nil {
+		t.Fatalf("append invoice: %v", err)
+	}
+
+	flow, err := es.Browse(ctx, events.Filter{CorrelationID: "req-1"})
+	if err != nil {
+		t.Fatalf("browse correlation: %v", err)
+	}
+	if len(flow.Events) != 2 || flow.Events[0].StreamID != "order-1" || flow.Events[1].StreamID != "invoice-1" || flow.Next != 0 {
+		t.Errorf("correlation page = %+v", flow)
+	}
+
+	acme, err := es.Browse(ctx, events.Filter{Metadata: map[string]any{"tenant": "acme"}, Types: []string{"OrderPaid", "InvoiceIssued"}})
+	if err != nil {
+		t.Fatalf("browse metadata: %v", err)
+	}
+	if len(acme.Events) != 1 || acme.Events[0].Type != "OrderPaid" {
+		t.Errorf("metadata page = %+v", acme)
+	}
+
+	first, err := es.Browse(ctx, events.Filter{Limit: 2})
+	if err != nil {
+		t.Fatalf("browse first page: %v", err)
+	}
+	if len(first.Events) != 2 || first.Next != first.Events[1].GlobalPosition {
+		t.Fatalf("first page = %+v", first)
+	}
+	second, err := es.Browse(ctx, events.Filter{Limit: 2, AfterPosition: first.Next})
+	if err != nil {
+		t.Fatalf("browse second page: %v", err)
+	}
+	if len(second.Events) != 1 || second.Events[0].Type != "InvoiceIssued" || second.Next != 0 {
+		t.Errorf("second page = %+v", second)
+	}
+
+	none, err := es.Browse(ctx, events.Filter{Until: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("browse until: %v", err)
+	}
+	if len(none.Events) != 0 {
+		t.Errorf("until an hour ago: got %d events", len(none.Events))
+	}
+}
+
+func TestEvents_ReadStreamStrict(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	_, err := es.ReadStreamStrict(ctx, "missing", 0)
+	if !errors.Is(err, whisker.ErrNotFound) {
+		t.Errorf("missing stream: got %v, want ErrNotFound", err)
+	}
+
+	err = es.Append(ctx, "order-1", 0, []events.Event{{Type: "OrderCreated", Data: []byte(`{}`)}})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	got, err := es.ReadStreamStrict(ctx, "order-1", 5)
+	if err != nil {
+		t.Fatalf("past the end: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("past the end: got %d events, want 0", len(got))
+	}
+
+	got, err = es.ReadStreamStrict(ctx, "order-1", 0)
+	if err != nil || len(got) != 1 {
+		t.Errorf("from start: got %d events, %v", len(got), err)
+	}
+}
+
+func TestEvents_MaintenanceReportAndHelpers(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	if err := es.Append(ctx, "maint-1", 0, []events.Event{
+		{Type: "Created", Data: []byte(`{}`)},
+		{Type: "Updated", Data: []byte(`{}`)},
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if _, err := es.ReadAll(ctx, 0, 10); err != nil {
+		t.Fatalf("read all: %v", err)
+	}
+
+	report, err := events.Maintenance(ctx, store)
+	if err != nil {
+		t.Fatalf("maintenance: %v", err)
+	}
+	if len(report.Tables) == 0 || report.Tables[0].Table != "whisker_events" {
+		t.Fatalf("expected whisker_events first, got %+v", report.Tables)
+	}
+	ev := report.Tables[0]
+	if ev.TableBytes == 0 || ev.IndexBytes == 0 || ev.XIDAge <= 0 {
+		t.Errorf("unexpected health: %+v", ev)
+	}
+	var found bool
+	for _, idx := range ev.Indexes {
+		found = found || idx.Name == "idx_whisker_events_global_position"
+	}
+	if !found {
+		t.Errorf("global_position index missing from %+v", ev.Indexes)
+	}
+
+	if err := events.ReindexGlobalPosition(ctx, store); err != nil {
+		t.Fatalf("reindex: %v", err)
+	}
+	if err := events.ClusterByGlobalPosition(ctx, store); err != nil {
+		t.Fatalf("cluster: %v", err)
+	}
+	evts, err := es.ReadStream(ctx, "maint-1", 0)
+	if err != nil || len(evts) != 2 {
+		t.Fatalf("read after cluster: %d events, %v", len(evts), err)
+	}
+
+	sess, err := store.Session(ctx)
+	if err != nil {
+		t.Fatalf("session: %v", err)
+	}
+	defer sess.Close(ctx)
+	if err := events.ReindexGlobalPosition(ctx, sess); err == nil {
+		t.Error("expected reindex inside a session to fail")
+	}
+}
+
+func TestEvents_NamedStoresAreIsolated(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	orders := events.New(store)
+	billing := events.NewNamed(store, "billing")
+
+	if err := orders.Append(ctx, "acct-1", 0, []events.Event{{Type: "OrderPlaced", Data: []byte(`{}`)}}); err != nil {
+		t.Fatalf("append default: %v", err)
+	}
+	// the same stream ID starts afresh in another store
+	err := billing.Append(ctx, "acct-1", 0, []events.Event{
+		{Type: "InvoiceIssued", Data: []byte(`{}`)},
+		{Type: "InvoicePaid", Data: []byte(`{}`)},
+	})
+	if err != nil {
+		t.Fatalf("append billing: %v", err)
+	}
+
+	got, err := billing.ReadAll(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("read all: %v", err)
+	}
+	if len(got) != 2 || got[0].Type != "InvoiceIssued" || got[0].GlobalPosition != 1 {
+		t.Errorf("billing events: %+v", got)
+	}
+	if v, err := orders.StreamVersion(ctx, "acct-1"); err != nil || v != 1 {
+		t.Errorf("default stream version: got %d, %v; want 1", v, err)
+	}
+
+	var exists bool
+	if err := store.PgxPool().QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM pg_indexes WHERE indexname = 'idx_whisker_events_billing_global_position')",
+	).Scan(&exists); err != nil || !exists {
+		t.Errorf("expected the billing global position index, exists=%v err=%v", exists, err)
+	}
+
+	if err := events.NewNamed(store, "bad-name").Append(ctx, "s", 0, []events.Event{{Type: "X", Data: []byte(`{}`)}}); err == nil {
+		t.Error("expected an invalid store name to fail")
+	}
+}
+
+func TestEvents_AppendWithRetryRereadsOnConflict(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	if err := es.Append(ctx, "cart-1", 0, []events.Event{{Type: "CartOpened", Data: []byte(`{}`)}}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	loads := 0
+	err := es.AppendWithRetry(ctx, "cart-1", func() (int, []events.Event, error) {
+		loads++
+		version, err := es.StreamVersion(ctx, "cart-1")
+		if err != nil {
+			return 0, nil, err
+		}
+		if loads == 1 {
+			// another writer slips in between the read and the append
+			if err := es.Append(ctx, "cart-1", version, []events.Event{{Type: "ItemAdded", Data: []byte(`{}`)}}); err != nil {
+				return 0, nil, err
+			}
+		}
+		return version, []events.Event{{Type: "CartCheckedOut", Data: []byte(`{}`)}}, nil
+	}, 3)
+	if err != nil {
+		t.Fatalf("append with retry: %v", err)
+	}
+	if loads != 2 {
+		t.Errorf("load ran %d times, want 2", loads)
+	}
+
+	got, err := es.ReadStream(ctx, "cart-1", 0)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(got) != 3 || got[2].Type != "CartCheckedOut" || got[2].Version != 3 {
+		t.Errorf("stream: %+v", got)
+	}
+
+	err = es.AppendWithRetry(ctx, "cart-1", func() (int, []events.Event, error) {
+		return 1, []events.Event{{Type: "Stale", Data: []byte(`{}`)}}, nil
+	}, 2)
+	if !errors.Is(err, whisker.ErrConcurrencyConflict) {
+		t.Errorf("got %v, want ErrConcurrencyConflict after exhausting attempts", err)
+	}
+}
+
+type ItemAdded struct {
+	SKU      string
+	Quantity int
+}
+
+func TestEvents_AppendTypedAndDecode(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	if err := es.AppendTyped(ctx, "cart-t1", 0, ItemAdded{SKU: "w-1", Quantity: 2}); err != nil {
+		t.Fatalf("append typed: %v", err)
+	}
+	got, err := es.ReadStream(ctx, "cart-t1", 0)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(got) != 1 || got[0].Type != "ItemAdded" {
+		t.Fatalf("events: %+v", got)
+	}
+	var item ItemAdded
+	if err := es.Decode(got[0], &item); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if item != (ItemAdded{SKU: "w-1", Quantity: 2}) {
+		t.Errorf("decoded %+v", item)
+	}
+}
+
+// reading is a payload encoded as four raw bytes, standing in for protobuf.
+type reading struct{ Value uint32 }
+
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	r := v.(*reading)
+	return []byte{byte(r.Value), byte(r.Value >> 8), byte(r.Value >> 16), byte(r.Value >> 24)}, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	if len(data) != 4 {
+		return errors.New("want 4 bytes")
+	}
+	v.(*reading).Value = uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16 | uint32(data[3])<<24
+	return nil
+}
+
+func TestEvents_BinaryPayloads(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.NewNamed(store, "telemetry", events.WithCodec(rawCodec{}), events.WithBinaryPayloads())
+
+	if err := es.Warm(ctx); err != nil {
+		t.Fatalf("warm: %v", err)
+	}
+	if err := es.AppendTyped(ctx, "sensor-1", 0, &reading{Value: 0x00c0ffee}); err != nil {
+		t.Fatalf("append typed: %v", err)
+	}
+
+	// a reader opened without the options still reads the raw bytes
+	got, err := events.NewNamed(store, "telemetry").ReadAll(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("read all: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d events, want 1", len(got))
+	}
+	var r reading
+	if err := es.Decode(got[0], &r); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if r.Value != 0x00c0ffee {
+		t.Errorf("value: got %#x", r.Value)
+	}
+}
+
+// stepClock is a clock tests move by hand.
+type stepClock struct{ now time.Time }
+
+func (c *stepClock) Now() time.Time { return c.now }
+
+func TestEvents_ReadStreamAsOf(t *testing.T) {
+	ctx := context.Background()
+	clock := &stepClock{now: time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)}
+	store, err := whisker.New(ctx, testutil.SetupPostgres(t), whisker.WithClock(clock))
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	t.Cleanup(store.Close)
+	es := events.New(store)
+
+	err = es.Append(ctx, "account-1", 0, []events.Event{
+		{Type: "Deposited", Data: []byte(`{"amount":100}`)},
+		{Type: "Deposited", Data: []byte(`{"amount":50}`)},
+	})
+	if err != nil {
+		t.Fatalf("append february: %v", err)
+	}
+	clock.now = time.Date(2024, 3, 15, 9, 0, 0, 0, time.UTC)
+	err = es.Append(ctx, "account-1", 2, []events.Event{{Type: "Withdrawn", Data: []byte(`{"amount":30}`)}})
+	if err != nil {
+		t.Fatalf("append march: %v", err)
+	}
+
+	march1 := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	got, err := es.ReadStreamAsOf(ctx, "account-1", march1)
+	if err != nil {
+		t.Fatalf("read as of: %v", err)
+	}
+	if len(got) != 2 || got[1].Version != 2 {
+		t.Fatalf("got %d events, want versions 1 and 2", len(got))
+	}
+	head, err := es.HeadPositionAsOf(ctx, march1)
+	if err != nil {
+		t.Fatalf("head position as of: %v", err)
+	}
+	if head != got[1].GlobalPosition {
+		t.Errorf("head position as of march: got %d, want %d", head, got[1].GlobalPosition)
+	}
+
+	balance := func(total int, evt events.Event) (int, error) {
+		var p struct{ Amount int }
+		if err := json.Unmarshal(evt.Data, &p); err != nil {
+			return total, err
+		}
+		if evt.Type == "Withdrawn" {
+			return total - p.Amount, nil
+		}
+		return total + p.Amount, nil
+	}
+	for _, tc := range []struct {
+		at   time.Time
+		want int
+	}{
+		{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0},
+		{march1, 150},
+		{time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), 120},
+	} {
+		got, err := events.FoldAsOf(ctx, es, "account-1", tc.at, 0, balance)
+		if err != nil {
+			t.Fatalf("fold as of %s: %v", tc.at, err)
+		}
+		if got != tc.want {
+			t.Errorf("balance as of %s = %d, want %d", tc.at.Format(time.DateOnly), got, tc.want)
+		}
+	}
+}
+
+func TestEvents_Tenancy(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	acme := whisker.WithTenant(ctx, "acme")
+	globex := whisker.WithTenant(ctx, "globex")
+	es := events.NewNamed(store, "tenants", events.WithTenancy())
+
+	evts := []events.Event{{Type: "OrderCreated", Data: []byte(`{}`)}}
+	if err := es.Append(ctx, "order-1", 0, evts); !errors.Is(err, whisker.ErrNoTenant) {
+		t.Fatalf("append without tenant: got %v, want ErrNoTenant", err)
+	}
+	if err := es.Append(acme, "order-1", 0, evts); err != nil {
+		t.Fatalf("append acme: %v", err)
+	}
+	if err := es.Append(globex, "order-1", 0, evts); !errors.Is(err, whisker.ErrStreamExists) {
+		t.Errorf("append to another tenant's stream: got %v, want ErrStreamExists", err)
+	}
+	if err := es.Append(globex, "order-1", 1, evts); !errors.Is(err, whisker.ErrConcurrencyConflict) {
+		t.Errorf("append to another tenant's stream: got %v, want ErrConcurrencyConflict", err)
+	}
+
+	got, err := es.ReadStream(globex, "order-1", 0)
+	if err != nil || len(got) != 0 {
+		t.Errorf("globex read: got %d events, %v", len(got), err)
+	}
+	if _, err := es.ReadStreamStrict(globex, "order-1", 0); !errors.Is(err, whisker.ErrNotFound) {
+		t.Errorf("globex strict read: got %v, want ErrNotFound", err)
+	}
+	got, err = es.ReadStream(acme, "order-1", 0)
+	if err != nil || len(got) != 1 || got[0].TenantID != "acme" {
+		t.Fatalf("acme read: got %+v, %v", got, err)
+	}
+
+	all, err := es.ReadAll(ctx, 0, 10)
+	if err != nil || len(all) != 1 || all[0].TenantID != "acme" {
+		t.Errorf("read all: got %+v, %v", all, err)
+	}
+	if all, err := es.ReadAll(globex, 0, 10); err != nil || len(all) != 0 {
+		t.Errorf("globex read all: got %+v, %v", all, err)
+	}
+	if head, err := es.HeadPosition(globex); err != nil || head != 0 {
+		t.Errorf("globex head: got %d, %v", head, err)
+	}
+	if head, err := es.HeadPosition(acme); err != nil || head != all[0].GlobalPosition {
+		t.Errorf("acme head: got %d, %v", head, err)
+	}
+	if page, err := es.Browse(globex, events.Filter{}); err != nil || len(page.Events) != 0 {
+		t.Errorf("globex browse: got %+v, %v", page, err)
+	}
+}
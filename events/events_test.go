@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+type notifyExec struct {
+	inTx     bool
+	channels []string
+	// fail is the number of notifications to fail before succeeding.
+	fail int
+}
+
+func (e *notifyExec) Exec(_ context.Context, _ string, args ...any) (pgconn.CommandTag, error) {
+	if e.fail > 0 {
+		e.fail--
+		return pgconn.CommandTag{}, errors.New("connection reset")
+	}
+	e.channels = append(e.channels, args[0].(string))
+	return pgconn.CommandTag{}, nil
+}
+
+func (e *notifyExec) Query(context.Context, string, ...any) (pgx.Rows, error) { return nil, nil }
+
+func (e *notifyExec) QueryRow(context.Context, string, ...any) pgx.Row { return nil }
+
+func (e *notifyExec) InTransaction() bool { return e.inTx }
+
+func TestNotify(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		inTx bool
+		fail int
+		opts []Option
+		want int
+	}{
+		{name: "every append", want: 3},
+		{name: "once per transaction", inTx: true, want: 1},
+		{name: "retried in transaction after a failure", inTx: true, fail: 1, want: 1},
+		{name: "disabled", opts: []Option{WithoutNotify()}, want: 0},
+		{name: "disabled in transaction", inTx: true, opts: []Option{WithoutNotify()}, want: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			exec := &notifyExec{inTx: tc.inTx, fail: tc.fail}
+			es := &Store{exec: exec, schema: schema.New(), table: schema.EventsTable("")}
+			for _, o := range tc.opts {
+				o(es)
+			}
+			for range 3 {
+				es.notify(context.Background())
+			}
+			if len(exec.channels) != tc.want {
+				t.Fatalf("notified %d times, want %d", len(exec.channels), tc.want)
+			}
+			for _, ch := range exec.channels {
+				if ch != "whisker_events" {
+					t.Errorf("channel = %q, want whisker_events", ch)
+				}
+			}
+		})
+	}
+}
+
+func TestTenancy(t *testing.T) {
+	es := &Store{table: schema.EventsTable("")}
+	WithTenancy()(es)
+	_, err := es.tenant(context.Background(), "append", "order-1")
+	if !errors.Is(err, whisker.ErrNoTenant) {
+		t.Fatalf("got %v, want ErrNoTenant", err)
+	}
+	var streamErr *whisker.StreamError
+	if !errors.As(err, &streamErr) || streamErr.StreamID != "order-1" || streamErr.Op != "append" {
+		t.Errorf("got %#v, want a StreamError for the append to order-1", err)
+	}
+
+	sql, args, err := psql.Select("1").From(es.table).Where(streamWhere("order-1", "acme")).ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != "SELECT 1 FROM whisker_events WHERE stream_id = $1 AND tenant_id = $2" || args[1] != "acme" {
+		t.Errorf("got %s %v", sql, args)
+	}
+	if cols := es.columns(); cols[len(cols)-1] != "COALESCE(tenant_id, '')" {
+		t.Errorf("columns = %v", cols)
+	}
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Exclude selects events a reader skips: those of any of Types, and those of
+// streams whose ID starts with any of StreamPrefixes. The zero value skips
+// nothing.
+type Exclude struct {
+	Types          []string
+	StreamPrefixes []string
+}
+
+// IsZero reports whether x skips nothing.
+func (x Exclude) IsZero() bool {
+	return len(x.Types) == 0 && len(x.StreamPrefixes) == 0
+}
+
+// Excludes reports whether evt is one of the events x skips.
+func (x Exclude) Excludes(evt Event) bool {
+	for _, t := range x.Types {
+		if evt.Type == t {
+			return true
+		}
+	}
+	for _, p := range x.StreamPrefixes {
+		if strings.HasPrefix(evt.StreamID, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// where returns the conditions leaving out the events x skips.
+func (x Exclude) where() ([]sq.Sqlizer, error) {
+	var where []sq.Sqlizer
+	if len(x.Types) > 0 {
+		where = append(where, sq.NotEq{"type": x.Types})
+	}
+	for _, p := range x.StreamPrefixes {
+		if p == "" {
+			return nil, errors.New("empty stream prefix excludes every event")
+		}
+		where = append(where, sq.NotLike{"stream_id": likeEscaper.Replace(p) + "%"})
+	}
+	return where, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ReadAllExcept is ReadAll without the events x skips, which are filtered
+// out in SQL so they are neither transferred nor decoded. It also returns
+// the position the read covers: that of the last event when it returns
+// limit events, otherwise the head of the log when the read started, so a
+// consumer can move its checkpoint past skipped events that no returned
+// event follows.
+func (es *Store) ReadAllExcept(ctx context.Context, afterPosition int64, limit int, x Exclude) ([]Event, int64, error) {
+	where, err := x.where()
+	if err != nil {
+		return nil, 0, fmt.Errorf("events: read all: %w", err)
+	}
+	// bound the read by the head taken first, so the head is only reported
+	// as covered when every event up to it was considered
+	head, err := es.HeadPosition(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	builder := psql.
+		Select(es.columns()...).
+		From(es.table).
+		Where(sq.Gt{"global_position": afterPosition}).
+		Where(sq.LtOrEq{"global_position": head}).
+		OrderBy("global_position ASC").
+		Limit(uint64(limit))
+	for _, w := range where {
+		builder = builder.Where(w)
+	}
+	evts, err := es.readAll(ctx, whereLogTenant(builder, es.logTenant(ctx)))
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(evts) == limit {
+		return evts, evts[len(evts)-1].GlobalPosition, nil
+	}
+	return evts, max(head, afterPosition), nil
+}
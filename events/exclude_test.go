@@ -0,0 +1,49 @@
+package events
+
+import "testing"
+
+func TestExclude_Excludes(t *testing.T) {
+	x := Exclude{Types: []string{"Heartbeat"}, StreamPrefixes: []string{"test-"}}
+	for _, tc := range []struct {
+		evt  Event
+		want bool
+	}{
+		{Event{StreamID: "order-1", Type: "OrderCreated"}, false},
+		{Event{StreamID: "order-1", Type: "Heartbeat"}, true},
+		{Event{StreamID: "test-1", Type: "OrderCreated"}, true},
+		{Event{StreamID: "contest-1", Type: "OrderCreated"}, false},
+	} {
+		if got := x.Excludes(tc.evt); got != tc.want {
+			t.Errorf("%s %s: got %v, want %v", tc.evt.StreamID, tc.evt.Type, got, tc.want)
+		}
+	}
+	if (Exclude{}).Excludes(Event{Type: "Heartbeat"}) {
+		t.Error("zero Exclude skipped an event")
+	}
+}
+
+func TestExclude_Where(t *testing.T) {
+	where, err := Exclude{Types: []string{"Heartbeat", "Ping"}, StreamPrefixes: []string{"test_%"}}.where()
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := psql.Select("1").From("whisker_events")
+	for _, w := range where {
+		builder = builder.Where(w)
+	}
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT 1 FROM whisker_events WHERE type NOT IN ($1,$2) AND stream_id NOT LIKE $3"
+	if sql != want {
+		t.Errorf("got:  %s\nwant: %s", sql, want)
+	}
+	if args[2] != `test\_\%%` {
+		t.Errorf("pattern = %v", args[2])
+	}
+
+	if _, err := (Exclude{StreamPrefixes: []string{""}}).where(); err == nil {
+		t.Error("expected an error for an empty prefix")
+	}
+}
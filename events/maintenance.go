@@ -0,0 +1,168 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/internal/pg"
+)
+
+// maintainedTables are the append-heavy tables Maintenance reports on.
+var maintainedTables = []string{"whisker_events", "whisker_projection_checkpoints"}
+
+const globalPositionIndex = "idx_whisker_events_global_position"
+
+// MaintenanceReport describes the vacuum health of the events table and the
+// projection checkpoints table. See Maintenance.
+type MaintenanceReport struct {
+	// Tables lists the tables that exist, events first.
+	Tables []TableHealth
+}
+
+// TableHealth reports the size, dead tuples and vacuum state of one table,
+// from PostgreSQL's statistics views.
+type TableHealth struct {
+	Table      string
+	LiveTuples int64
+	DeadTuples int64
+	// TableBytes and IndexBytes are the on-disk sizes of the table, TOAST
+	// included, and of all its indexes.
+	TableBytes int64
+	IndexBytes int64
+	// EstimatedBloatBytes estimates the space held by dead tuples in the
+	// table and its indexes, as their share of all tuples applied to the
+	// sizes. VACUUM makes it reusable; only a rewrite such as CLUSTER or
+	// REINDEX gives it back.
+	EstimatedBloatBytes int64
+	// LastVacuum and LastAnalyze are the latest manual or automatic runs, or
+	// zero if there has been none since statistics were reset.
+	LastVacuum  time.Time
+	LastAnalyze time.Time
+	// XIDAge is the age, in transactions, of the table's oldest unfrozen
+	// transaction ID: how far back its unvacuumed horizon reaches. PostgreSQL
+	// forces an anti-wraparound vacuum when it passes
+	// autovacuum_freeze_max_age (200 million by default).
+	XIDAge  int64
+	Indexes []IndexHealth
+}
+
+// IndexHealth reports the size and use of one index.
+type IndexHealth struct {
+	Name  string
+	Bytes int64
+	Scans int64
+}
+
+// Maintenance reports table and index sizes, estimated bloat, dead tuples and
+// the oldest unvacuumed transaction horizon of whisker_events and
+// whisker_projection_checkpoints, for deciding when to vacuum, reindex or
+// cluster. Tables that do not exist yet are left out. It only reads
+// statistics and is cheap to run.
+func Maintenance(ctx context.Context, b whisker.Backend) (*MaintenanceReport, error) {
+	exec := b.DBExecutor()
+	report := &MaintenanceReport{}
+	for _, table := range maintainedTables {
+		h := TableHealth{Table: table}
+		var lastVacuum, lastAnalyze *time.Time
+		err := exec.QueryRow(ctx,
+			`SELECT COALESCE(s.n_live_tup, 0), COALESCE(s.n_dead_tup, 0),
+			        pg_table_size(c.oid), pg_indexes_size(c.oid),
+			        GREATEST(s.last_vacuum, s.last_autovacuum), GREATEST(s.last_analyze, s.last_autoanalyze),
+			        age(c.relfrozenxid)
+			 FROM pg_class c
+			 LEFT JOIN pg_stat_user_tables s ON s.relid = c.oid
+			 WHERE c.oid = to_regclass($1)`,
+			table,
+		).Scan(&h.LiveTuples, &h.DeadTuples, &h.TableBytes, &h.IndexBytes, &lastVacuum, &lastAnalyze, &h.XIDAge)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("events: maintenance: %s: %w", table, err)
+		}
+		if lastVacuum != nil {
+			h.LastVacuum = *lastVacuum
+		}
+		if lastAnalyze != nil {
+			h.LastAnalyze = *lastAnalyze
+		}
+		h.EstimatedBloatBytes = estimateBloat(h.TableBytes+h.IndexBytes, h.LiveTuples, h.DeadTuples)
+
+		indexes, err := indexHealth(ctx, exec, table)
+		if err != nil {
+			return nil, fmt.Errorf("events: maintenance: %s: %w", table, err)
+		}
+		h.Indexes = indexes
+		report.Tables = append(report.Tables, h)
+	}
+	return report, nil
+}
+
+func indexHealth(ctx context.Context, exec pg.Executor, table string) ([]IndexHealth, error) {
+	rows, err := exec.Query(ctx,
+		`SELECT indexrelname, pg_relation_size(indexrelid), idx_scan
+		 FROM pg_stat_user_indexes
+		 WHERE relid = $1::regclass
+		 ORDER BY indexrelname`,
+		table,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("indexes: %w", err)
+	}
+	defer rows.Close()
+	var out []IndexHealth
+	for rows.Next() {
+		var ih IndexHealth
+		if err := rows.Scan(&ih.Name, &ih.Bytes, &ih.Scans); err != nil {
+			return nil, fmt.Errorf("scan indexes: %w", err)
+		}
+		out = append(out, ih)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("indexes: %w", err)
+	}
+	return out, nil
+}
+
+// estimateBloat attributes to dead tuples their share of bytes.
+func estimateBloat(bytes, live, dead int64) int64 {
+	if dead <= 0 || live+dead <= 0 {
+		return 0
+	}
+	return int64(float64(bytes) * float64(dead) / float64(live+dead))
+}
+
+// ReindexGlobalPosition rebuilds the global_position index of whisker_events
+// with REINDEX CONCURRENTLY, reclaiming index bloat without blocking appends
+// or reads. It takes longer than a plain REINDEX and cannot run inside a
+// session.
+func ReindexGlobalPosition(ctx context.Context, b whisker.Backend) error {
+	exec := b.DBExecutor()
+	if tx, ok := exec.(pg.Transactional); ok && tx.InTransaction() {
+		return fmt.Errorf("events: reindex %s: cannot run inside a transaction", globalPositionIndex)
+	}
+	if _, err := exec.Exec(ctx, "REINDEX INDEX CONCURRENTLY "+globalPositionIndex); err != nil {
+		return fmt.Errorf("events: reindex %s: %w", globalPositionIndex, err)
+	}
+	return nil
+}
+
+// ClusterByGlobalPosition rewrites whisker_events in global_position order,
+// reclaiming all bloat and making ReadAll and projection catch-up read
+// sequentially. CLUSTER holds an ACCESS EXCLUSIVE lock for the whole rewrite,
+// blocking every append and read of events: run it in a maintenance window,
+// e.g. while the store is quiesced.
+func ClusterByGlobalPosition(ctx context.Context, b whisker.Backend) error {
+	exec := b.DBExecutor()
+	if err := b.SchemaBootstrap().EnsureEventsGlobalPositionIndex(ctx, exec); err != nil {
+		return err
+	}
+	if _, err := exec.Exec(ctx, "CLUSTER whisker_events USING "+globalPositionIndex); err != nil {
+		return fmt.Errorf("events: cluster whisker_events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package events
+
+import "testing"
+
+func TestEstimateBloat(t *testing.T) {
+	for _, tc := range []struct {
+		bytes, live, dead, want int64
+	}{
+		{bytes: 1000, live: 75, dead: 25, want: 250},
+		{bytes: 1000, live: 100, dead: 0, want: 0},
+		{bytes: 1000, live: 0, dead: 0, want: 0},
+		{bytes: 1000, live: 0, dead: 10, want: 1000},
+	} {
+		if got := estimateBloat(tc.bytes, tc.live, tc.dead); got != tc.want {
+			t.Errorf("estimateBloat(%d, %d, %d) = %d, want %d", tc.bytes, tc.live, tc.dead, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ripkitten-co/whisker"
+)
+
+// LoadFunc decides what to append to a stream for AppendWithRetry: it reads
+// the stream's current state, typically by folding ReadStream, and returns
+// the version it read along with the events to append at that version.
+// Returning no events appends nothing.
+type LoadFunc func() (expectedVersion int, evts []Event, err error)
+
+// AppendWithRetry runs the compare-and-append loop of a command handler: it
+// calls load and appends the events it returns at the version it read. If
+// another writer appended first, the append fails with
+// whisker.ErrConcurrencyConflict (or whisker.ErrStreamExists for a new
+// stream), and AppendWithRetry calls load again so the decision is made
+// against the new state, up to attempts times in all.
+//
+// load must re-read the stream on every call; retrying with the events from a
+// stale read would defeat the concurrency check. Errors from load and other
+// append errors are returned at once. When every attempt conflicts, the last
+// conflict is returned.
+func (es *Store) AppendWithRetry(ctx context.Context, streamID string, load LoadFunc, attempts int) error {
+	if attempts < 1 {
+		return fmt.Errorf("events: append %s: attempts must be at least 1, got %d", streamID, attempts)
+	}
+
+	var err error
+	for range attempts {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("events: append %s: %w", streamID, ctxErr)
+		}
+		expectedVersion, evts, loadErr := load()
+		if loadErr != nil {
+			return fmt.Errorf("events: append %s: load: %w", streamID, loadErr)
+		}
+		if len(evts) == 0 {
+			return nil
+		}
+		err = es.Append(ctx, streamID, expectedVersion, evts)
+		if !isConflict(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// isConflict reports whether err means another writer appended to the stream
+// first.
+func isConflict(err error) bool {
+	return errors.Is(err, whisker.ErrConcurrencyConflict) || errors.Is(err, whisker.ErrStreamExists)
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ripkitten-co/whisker"
+)
+
+func TestAppendWithRetry_RejectsZeroAttempts(t *testing.T) {
+	es := &Store{}
+	called := false
+	err := es.AppendWithRetry(context.Background(), "order-1", func() (int, []Event, error) {
+		called = true
+		return 0, nil, nil
+	}, 0)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if called {
+		t.Error("load should not run")
+	}
+}
+
+func TestAppendWithRetry_LoadErrorStops(t *testing.T) {
+	es := &Store{}
+	boom := errors.New("boom")
+	calls := 0
+	err := es.AppendWithRetry(context.Background(), "order-1", func() (int, []Event, error) {
+		calls++
+		return 0, nil, boom
+	}, 3)
+	if !errors.Is(err, boom) {
+		t.Fatalf("got %v, want boom", err)
+	}
+	if calls != 1 {
+		t.Errorf("load ran %d times, want 1", calls)
+	}
+}
+
+func TestAppendWithRetry_NothingToAppend(t *testing.T) {
+	es := &Store{}
+	err := es.AppendWithRetry(context.Background(), "order-1", func() (int, []Event, error) {
+		return 3, nil, nil
+	}, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestIsConflict(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{&whisker.StreamError{StreamID: "s", Op: "append", Err: whisker.ErrConcurrencyConflict}, true},
+		{&whisker.StreamError{StreamID: "s", Op: "append", Err: whisker.ErrStreamExists}, true},
+		{fmt.Errorf("events: append s: %w", errors.New("connection reset")), false},
+		{nil, false},
+	} {
+		if got := isConflict(tc.err); got != tc.want {
+			t.Errorf("isConflict(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
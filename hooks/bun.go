@@ -43,11 +43,15 @@ func (a *bunAdapter) ExecContext(ctx context.Context, query string, args ...any)
 }
 
 func (a *bunAdapter) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := a.pool.prepareRead(ctx, query, args); err != nil {
+		return nil, err
+	}
 	rewritten, newArgs := a.rewriteQuery(query, args)
 	return a.db.QueryContext(ctx, rewritten, newArgs...)
 }
 
 func (a *bunAdapter) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	_ = a.pool.prepareRead(ctx, query, args)
 	rewritten, newArgs := a.rewriteQuery(query, args)
 	return a.db.QueryRowContext(ctx, rewritten, newArgs...)
 }
@@ -4,6 +4,7 @@ package hooks
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	whisker "github.com/ripkitten-co/whisker"
@@ -119,3 +120,53 @@ func TestBun_CreateTableInsertAndSelect(t *testing.T) {
 		t.Errorf("whisker version = %d, want 1", doc.Version)
 	}
 }
+
+type BunMember struct {
+	bun.BaseModel `bun:"table:members"`
+	ID            string `bun:"id,pk"`
+	Name          string `bun:"name"`
+	Tier          int    `bun:"tier"`
+	Version       int    `bun:"version"`
+}
+
+func TestBun_PaginatedListAndCount(t *testing.T) {
+	connStr := testutil.SetupPostgres(t)
+	ctx := context.Background()
+
+	store, err := whisker.New(ctx, connStr)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	pool := NewPool(store)
+	Register[BunMember](pool, "members")
+
+	bunDB, adapter := OpenBun(pool)
+	defer bunDB.Close()
+
+	// tiers chosen so text ordering ("10" < "2" < "9") differs from numeric
+	for i, tier := range []int{2, 10, 9, 1} {
+		m := &BunMember{ID: fmt.Sprintf("m%d", i), Name: fmt.Sprintf("member %d", i), Tier: tier}
+		if _, err := bunDB.NewInsert().Model(m).Conn(adapter).Exec(ctx); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	var page []BunMember
+	total, err := bunDB.NewSelect().Model(&page).
+		Where("tier > ?", 1).
+		Order("tier DESC").
+		Limit(2).Offset(1).
+		Conn(adapter).
+		ScanAndCount(ctx)
+	if err != nil {
+		t.Fatalf("scan and count: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("total = %d, want 3", total)
+	}
+	if len(page) != 2 || page[0].Tier != 9 || page[1].Tier != 2 {
+		t.Errorf("page = %+v, want tiers 9, 2", page)
+	}
+}
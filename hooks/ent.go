@@ -33,6 +33,9 @@ func (d *entDriver) ExecContext(ctx context.Context, query string, args ...any)
 }
 
 func (d *entDriver) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := d.pool.prepareRead(ctx, query, args); err != nil {
+		return nil, err
+	}
 	rewritten, newArgs := d.rewriteQuery(query, args)
 	return d.db.QueryContext(ctx, rewritten, newArgs...)
 }
@@ -4,6 +4,7 @@ package hooks
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	whisker "github.com/ripkitten-co/whisker"
@@ -118,3 +119,80 @@ func TestEntDriver_QuotedIdentifiers(t *testing.T) {
 		t.Errorf("version = %d, want 1", doc.Version)
 	}
 }
+
+type entMember struct {
+	ID      string
+	Name    string
+	Tier    int
+	Version int
+}
+
+// TestEntDriver_PaginatedListAndCount feeds the SQL Ent generates for a
+// paginated listing (Order, Limit, Offset) and its companion Count.
+func TestEntDriver_PaginatedListAndCount(t *testing.T) {
+	connStr := testutil.SetupPostgres(t)
+	ctx := context.Background()
+
+	store, err := whisker.New(ctx, connStr)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	pool := NewPool(store)
+	Register[entMember](pool, "members")
+
+	driver := EntDriver(pool)
+
+	for i, tier := range []int{2, 10, 9, 1} {
+		_, err := driver.ExecContext(ctx,
+			`INSERT INTO "members" ("id", "name", "tier") VALUES ($1, $2, $3)`,
+			fmt.Sprintf("m%d", i), fmt.Sprintf("member %d", i), tier,
+		)
+		if err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	rows, err := driver.QueryContext(ctx,
+		`SELECT "members"."id", "members"."name", "members"."tier" FROM "members" WHERE "members"."tier" > $1 ORDER BY "members"."tier" DESC LIMIT 2 OFFSET 1`,
+		1,
+	)
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id, name string
+		var tier, version int
+		if err := rows.Scan(&id, &name, &tier, &version); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != "m2" || ids[1] != "m0" {
+		t.Errorf("ids = %v, want [m2 m0]", ids)
+	}
+
+	countRows, err := driver.QueryContext(ctx,
+		`SELECT COUNT(*) FROM "members" WHERE "members"."tier" > $1`, 1,
+	)
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	defer countRows.Close()
+	var total int
+	if !countRows.Next() {
+		t.Fatal("expected count row")
+	}
+	if err := countRows.Scan(&total); err != nil {
+		t.Fatalf("scan count: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("total = %d, want 3", total)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/ripkitten-co/whisker/internal/ident"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -56,11 +57,15 @@ func (c *gormConnPool) ExecContext(ctx context.Context, query string, args ...an
 }
 
 func (c *gormConnPool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := c.pool.prepareRead(ctx, query, args); err != nil {
+		return nil, err
+	}
 	rewritten, newArgs := c.rewriteQuery(query, args)
 	return c.db.QueryContext(ctx, rewritten, newArgs...)
 }
 
 func (c *gormConnPool) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	_ = c.pool.prepareRead(ctx, query, args)
 	rewritten, newArgs := c.rewriteQuery(query, args)
 	return c.db.QueryRowContext(ctx, rewritten, newArgs...)
 }
@@ -139,21 +144,21 @@ func (c *gormConnPool) rewriteQuery(query string, args []any) (string, []any) {
 }
 
 // rewriteGORMSelect rewrites a SELECT to extract JSONB fields as named columns
-// so that database/sql returns proper column names for GORM's scanner.
+// so that database/sql returns proper column names for GORM's scanner. COUNT
+// queries keep their select list.
 func rewriteGORMSelect(info *modelInfo, query string, args []any) (string, []any) {
+	query, locking := splitLockingClause(query)
 	rewritten := replaceTableName(query, info.name, info.table)
 
-	upper := strings.ToUpper(rewritten)
-	whereIdx := strings.Index(upper, " WHERE ")
-	if whereIdx >= 0 {
-		before := rewritten[:whereIdx+7]
-		after := rewritten[whereIdx+7:]
-		after = rewriteColumnRefs(after, info)
-		rewritten = before + after
+	count := isCountSelect(rewritten)
+	rewritten = rewriteSelectRefs(rewritten, info, count)
+	if !count {
+		rewritten = rewriteGORMSelectColumns(rewritten, info)
 	}
-
-	rewritten = rewriteGORMSelectColumns(rewritten, info)
-	return rewritten, args
+	if info.legacyTable != "" && locking == "" {
+		rewritten = dualRead(rewritten, info)
+	}
+	return appendLockingClause(rewritten, locking, info), args
 }
 
 // rewriteGORMSelectColumns replaces the column list with JSONB extraction
@@ -167,11 +172,19 @@ func rewriteGORMSelectColumns(query string, info *modelInfo) string {
 	}
 
 	var cols []string
-	cols = append(cols, "id")
+	cols = append(cols, fixedColumnAs("id", info.idColumn))
 	for _, dc := range info.dataCols {
-		cols = append(cols, fmt.Sprintf("data->>'%s' AS \"%s\"", dc.jsonKey, dc.name))
+		cols = append(cols, fmt.Sprintf("%s AS %s", ident.JSONText(dc.jsonKey), ident.QuoteIdent(dc.name)))
 	}
-	cols = append(cols, "version")
+	cols = append(cols, fixedColumnAs("version", info.versionCol))
 
 	return query[:selectIdx+7] + strings.Join(cols, ", ") + query[fromIdx:]
 }
+
+// fixedColumnAs selects the id or version column under the ORM's name for it.
+func fixedColumnAs(column, name string) string {
+	if column == name {
+		return column
+	}
+	return column + " AS " + ident.QuoteIdent(name)
+}
@@ -2,9 +2,13 @@ package hooks
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
 	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/internal/columns"
+	"github.com/ripkitten-co/whisker/internal/indexes"
+	"github.com/ripkitten-co/whisker/internal/meta"
 )
 
 // Pool wraps a Whisker store and presents a pgx-compatible query interface.
@@ -27,10 +31,17 @@ func NewPool(store *whisker.Store) *Pool {
 }
 
 // Register teaches the pool about a model so its SQL can be intercepted.
-func Register[T any](p *Pool, name string) {
-	p.reg.register(name, analyzeModel[T](name))
+func Register[T any](p *Pool, name string, opts ...ModelOption) {
+	info := analyzeModel[T](name)
+	for _, o := range opts {
+		o(info)
+	}
+	p.reg.register(name, info)
 }
 
+// ensureTable creates the model's whisker table, generated columns and
+// indexes on first use. Its errors carry the hooks prefix, so callers return
+// them as they are.
 func (p *Pool) ensureTable(ctx context.Context, info *modelInfo) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -45,9 +56,64 @@ func (p *Pool) ensureTable(ctx context.Context, info *modelInfo) error {
 	}
 
 	if _, err := p.store.DBExecutor().Exec(ctx, ddl); err != nil {
-		return err
+		return fmt.Errorf("hooks: ensure table %s: %w", info.table, err)
+	}
+	if info.meta.UsesCast(meta.CastTimestamptz) {
+		// time columns and indexes are both built on whisker_timestamptz
+		if err := p.store.SchemaBootstrap().EnsureTimestamptzFunc(ctx, p.store.DBExecutor()); err != nil {
+			return fmt.Errorf("hooks: ensure table %s: %w", info.table, err)
+		}
+	}
+	if err := p.ensureColumns(ctx, info); err != nil {
+		return fmt.Errorf("hooks: ensure table %s: %w", info.table, err)
+	}
+	if err := p.ensureIndexes(ctx, info); err != nil {
+		return fmt.Errorf("hooks: ensure table %s: %w", info.table, err)
 	}
 
 	p.ensured[info.table] = struct{}{}
 	return nil
 }
+
+// ensureColumns adds the generated columns declared with whisker:"column"
+// and whisker:"fk" tags, as documents collections do.
+func (p *Pool) ensureColumns(ctx context.Context, info *modelInfo) error {
+	exec, bootstrap := p.store.DBExecutor(), p.store.SchemaBootstrap()
+	for _, col := range info.meta.Columns {
+		key := columns.ColumnKey(info.name, col)
+		if bootstrap.IsColumnCreated(key) {
+			continue
+		}
+		if _, err := exec.Exec(ctx, columns.ColumnDDL(info.name, col)); err != nil {
+			return fmt.Errorf("add column %s: %w", col.Name, err)
+		}
+		if col.References != "" {
+			if err := bootstrap.EnsureCollection(ctx, exec, col.References); err != nil {
+				return fmt.Errorf("referenced collection: %w", err)
+			}
+			if _, err := exec.Exec(ctx, columns.ForeignKeyDDL(info.name, col)); err != nil {
+				return fmt.Errorf("add foreign key %s: %w", columns.ForeignKeyName(info.name, col), err)
+			}
+		}
+		bootstrap.MarkColumnCreated(key)
+	}
+	return nil
+}
+
+// ensureIndexes creates the indexes declared with whisker:"index" tags,
+// under the same names documents collections use.
+func (p *Pool) ensureIndexes(ctx context.Context, info *modelInfo) error {
+	exec, bootstrap := p.store.DBExecutor(), p.store.SchemaBootstrap()
+	for _, ext := range indexes.Extensions(info.meta.Indexes) {
+		if err := bootstrap.EnsureExtension(ctx, exec, ext); err != nil {
+			return err
+		}
+	}
+	for i, ddl := range indexes.IndexDDLs(info.name, info.meta.Indexes) {
+		name := indexes.IndexName(info.name, info.meta.Indexes[i])
+		if err := bootstrap.EnsureIndex(ctx, exec, name, ddl, indexes.Superseded(info.name, info.meta.Indexes[i])...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ripkitten-co/whisker/internal/ident"
+)
+
+// ModelOption configures how Register treats a model.
+type ModelOption func(*modelInfo)
+
+// WithLegacyTable puts the model in dual-read mode for a gradual migration
+// off a plain relational table. Writes go to the whisker table as usual;
+// single-table SELECTs and COUNTs also see rows of the legacy table whose id
+// is not in the whisker table yet, with columns named like the ORM's columns
+// packed into the document. Legacy columns the table lacks read as null. The
+// table name may be schema-qualified ("legacy.users").
+//
+// Joins and locking reads (FOR UPDATE and friends) only see whisker rows, as
+// do UPDATE and DELETE: backfill a row before writing to it, or enable
+// WithBackfillOnRead.
+func WithLegacyTable(table string) ModelOption {
+	return func(m *modelInfo) { m.legacyTable = table }
+}
+
+// WithBackfillOnRead copies legacy rows into the whisker table as SELECTs
+// return them, so the legacy table drains as the application reads. Only the
+// rows of the page being read are copied. Backfill is best effort: a failure
+// is logged and the read still returns the legacy rows. Has no effect
+// without WithLegacyTable.
+func WithBackfillOnRead() ModelOption {
+	return func(m *modelInfo) { m.backfillOnRead = true }
+}
+
+// legacySource is the FROM item a dual-read SELECT reads instead of the
+// whisker table: whisker rows plus legacy rows not migrated yet, flagged by
+// whisker_legacy.
+func legacySource(info *modelInfo) string {
+	return fmt.Sprintf("(SELECT id, data, version, created_at, updated_at, false AS whisker_legacy FROM %s"+
+		" UNION ALL SELECT id, data, version, now(), now(), true FROM (%s) l"+
+		" WHERE NOT EXISTS (SELECT 1 FROM %s w WHERE w.id = l.id))",
+		info.table, legacyRows(info, info.legacyTable), info.table)
+}
+
+// legacyRows selects the rows of a plain table as (id, data, version, key),
+// with the columns named like the model's ORM columns packed into data.
+// Columns the table lacks become null. id is the id column as text; key is
+// the column itself, which pages can be compared on without casting every
+// row.
+func legacyRows(info *modelInfo, table string) string {
+	pairs := make([]string, 0, len(info.dataCols))
+	for _, dc := range info.dataCols {
+		pairs = append(pairs, fmt.Sprintf("%s, to_jsonb(l)->%s", ident.Literal(dc.jsonKey), ident.Literal(dc.name)))
+	}
+	data := "'{}'::jsonb"
+	if len(pairs) > 0 {
+		data = fmt.Sprintf("jsonb_build_object(%s)", strings.Join(pairs, ", "))
+	}
+	return fmt.Sprintf("SELECT l.%[1]s::text AS id, %[2]s AS data, COALESCE((to_jsonb(l)->>%[3]s)::integer, 1) AS version, l.%[1]s AS key FROM %[4]s l",
+		ident.QuoteIdent(info.idColumn), data, ident.Literal(info.versionCol), quoteTable(table))
+}
+
+// quoteTable quotes a table name that may be schema-qualified.
+func quoteTable(table string) string {
+	return pgx.Identifier(strings.Split(table, ".")).Sanitize()
+}
+
+// dualRead points the FROM item of a rewritten single-table SELECT at
+// legacySource, keeping the ORM's alias or, without one, the whisker table
+// name so qualified references still resolve.
+func dualRead(sql string, info *modelInfo) string {
+	fromIdx := strings.Index(strings.ToUpper(sql), " FROM ")
+	if fromIdx == -1 {
+		return sql
+	}
+	start := fromIdx + 6
+	rest := sql[start:]
+	if !strings.HasPrefix(rest, info.table) || (len(rest) > len(info.table) && isIdentChar(rest[len(info.table)])) {
+		return sql
+	}
+	source := legacySource(info)
+	if fromAlias(rest) == "" {
+		source += " AS " + info.table
+	}
+	return sql[:start] + source + rest[len(info.table):]
+}
+
+// backfillSQL turns a dual-read SELECT, as rewritten by rewriteSelect, into
+// an INSERT copying the legacy rows of the page it reads. It takes the same
+// arguments.
+func backfillSQL(read string, info *modelInfo) string {
+	fromIdx := strings.Index(strings.ToUpper(read), " FROM ")
+	page := "SELECT *" + read[fromIdx:]
+	return fmt.Sprintf("INSERT INTO %s (id, data, version) SELECT id, data, version FROM (%s) AS page"+
+		" WHERE page.whisker_legacy ON CONFLICT (id) DO NOTHING", info.table, page)
+}
+
+// prepareRead readies a SELECT on a dual-read model: the whisker table must
+// exist for the union to read it, and with WithBackfillOnRead the legacy rows
+// the query returns are copied first.
+func (p *Pool) prepareRead(ctx context.Context, sql string, args []any) error {
+	table, op, ok := parseSQL(sql)
+	if !ok || op != opSelect {
+		return nil
+	}
+	info, found := p.reg.lookupByTable(table)
+	if !found || info.legacyTable == "" {
+		return nil
+	}
+	if err := p.ensureTable(ctx, info); err != nil {
+		return err
+	}
+	if _, locking := splitLockingClause(sql); !info.backfillOnRead || locking != "" || isCountSelect(sql) {
+		return nil
+	}
+
+	read, _, err := rewriteSelect(info, sql, args)
+	if err != nil {
+		return err
+	}
+	if _, err := p.store.DBExecutor().Exec(ctx, backfillSQL(read, info), args...); err != nil {
+		p.store.Logger().Warn("hooks: backfill on read", "table", info.table, "legacy_table", info.legacyTable, "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package hooks
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDualRead_ReplacesFromItem(t *testing.T) {
+	info := analyzeModel[testUser]("users")
+	WithLegacyTable("legacy.users")(info)
+
+	got, _, err := rewriteSelect(info, "SELECT * FROM users WHERE name = $1 ORDER BY email LIMIT $2", nil)
+	if err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	if !strings.HasPrefix(got, "SELECT id, data, version FROM (SELECT id, data, version, created_at, updated_at, false AS whisker_legacy FROM whisker_users UNION ALL ") {
+		t.Errorf("source not replaced: %s", got)
+	}
+	for _, want := range []string{
+		`FROM "legacy"."users" l) l WHERE NOT EXISTS (SELECT 1 FROM whisker_users w WHERE w.id = l.id)`,
+		`jsonb_build_object('name', to_jsonb(l)->'name', 'email', to_jsonb(l)->'email')`,
+		`) AS whisker_users WHERE data->>'name' = $1 ORDER BY data->>'email' LIMIT $2`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestDualRead_KeepsAliasAndSkipsLocking(t *testing.T) {
+	info := analyzeModel[testUser]("users")
+	WithLegacyTable("users")(info)
+
+	got, _ := rewriteGORMSelect(info, `SELECT count(*) FROM "users" AS "u" WHERE "u"."name" = $1`, nil)
+	if !strings.HasPrefix(got, "SELECT count(*) FROM (SELECT ") || !strings.HasSuffix(got, `) AS "u" WHERE "u".data->>'name' = $1`) {
+		t.Errorf("count: %s", got)
+	}
+
+	locked, _, err := rewriteSelect(info, "SELECT * FROM users WHERE id = $1 FOR UPDATE", nil)
+	if err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	if want := "SELECT id, data, version FROM whisker_users WHERE id = $1 FOR UPDATE"; locked != want {
+		t.Errorf("locking read:\n got: %s\nwant: %s", locked, want)
+	}
+}
+
+func TestBackfillSQL(t *testing.T) {
+	info := analyzeModel[testUser]("users")
+	WithLegacyTable("users")(info)
+
+	read, _, err := rewriteSelect(info, "SELECT * FROM users ORDER BY name LIMIT $1", nil)
+	if err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	got := backfillSQL(read, info)
+	if !strings.HasPrefix(got, "INSERT INTO whisker_users (id, data, version) SELECT id, data, version FROM (SELECT * FROM (SELECT ") {
+		t.Errorf("prefix: %s", got)
+	}
+	if !strings.HasSuffix(got, "ORDER BY data->>'name' LIMIT $1) AS page WHERE page.whisker_legacy ON CONFLICT (id) DO NOTHING") {
+		t.Errorf("suffix: %s", got)
+	}
+}
@@ -0,0 +1,173 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrMigrationIncomplete is returned by Migrate when, after copying, some
+// rows of the source table have no whisker row.
+var ErrMigrationIncomplete = errors.New("hooks: migration incomplete")
+
+// MigrateOption configures Migrate.
+type MigrateOption func(*migrateConfig)
+
+type migrateConfig struct {
+	source    string
+	batchSize int
+	progress  func(MigrateProgress)
+}
+
+// WithSourceTable sets the plain table Migrate reads. Defaults to the
+// model's legacy table (see WithLegacyTable), or else the registered name.
+// The name may be schema-qualified.
+func WithSourceTable(table string) MigrateOption {
+	return func(c *migrateConfig) { c.source = table }
+}
+
+// WithMigrateBatchSize sets how many rows Migrate copies per statement.
+// Defaults to 1000.
+func WithMigrateBatchSize(n int) MigrateOption {
+	return func(c *migrateConfig) { c.batchSize = n }
+}
+
+// WithProgress registers a callback Migrate invokes after every batch.
+func WithProgress(fn func(MigrateProgress)) MigrateOption {
+	return func(c *migrateConfig) { c.progress = fn }
+}
+
+// MigrateProgress reports how far a Migrate call has got.
+type MigrateProgress struct {
+	// Total is the number of source rows counted before copying started.
+	Total int64
+	// Read counts the source rows processed so far.
+	Read int64
+	// Copied counts the rows written to the whisker table so far; the rest
+	// of Read already had a whisker row and were left alone.
+	Copied  int64
+	Elapsed time.Duration
+}
+
+// MigrateResult summarizes a finished Migrate call and its verification.
+type MigrateResult struct {
+	MigrateProgress
+	// SourceRows and TargetRows count the source rows and the whisker rows
+	// with a source row's id after copying. They match on success.
+	SourceRows int64
+	TargetRows int64
+	// SourceChecksum and TargetChecksum are md5 digests of the source rows,
+	// packed as documents, and of the matching whisker rows, in id order.
+	SourceChecksum string
+	TargetChecksum string
+	// Mismatched counts rows whose whisker document differs from the packed
+	// source row: rows written through whisker since the cutover, or copies
+	// gone wrong.
+	Mismatched int64
+}
+
+// Migrate copies an existing plain table into the registered model's whisker
+// table: the id column becomes the document id, the columns named like the
+// model's ORM columns are packed into the JSONB document, and a version
+// column, if the table has one, is kept. Rows are read in id order and
+// written in batches, each its own statement, so an interrupted migration can
+// simply be run again: rows that already have a whisker row are skipped,
+// never overwritten.
+//
+// After copying, Migrate verifies that every source row has a whisker row,
+// returning ErrMigrationIncomplete otherwise, and compares checksums of the
+// two sides. Rows written to the source table during the run may be missed;
+// stop writing to it, or use dual-read mode, before the final run.
+func Migrate(ctx context.Context, p *Pool, name string, opts ...MigrateOption) (*MigrateResult, error) {
+	info, ok := p.reg.lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("hooks: migrate %s: model not registered", name)
+	}
+	cfg := migrateConfig{source: info.legacyTable, batchSize: 1000}
+	if cfg.source == "" {
+		cfg.source = name
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cfg.batchSize <= 0 {
+		return nil, fmt.Errorf("hooks: migrate %s: batch size must be positive", name)
+	}
+
+	if err := p.ensureTable(ctx, info); err != nil {
+		return nil, err
+	}
+
+	exec := p.store.DBExecutor()
+	rows := legacyRows(info, cfg.source)
+	start := time.Now()
+
+	res := &MigrateResult{}
+	if err := exec.QueryRow(ctx, fmt.Sprintf("SELECT count(*) FROM %s", quoteTable(cfg.source))).Scan(&res.Total); err != nil {
+		return nil, fmt.Errorf("hooks: migrate %s: count source: %w", name, err)
+	}
+
+	// pages follow the source's id column in its own type, so each is an
+	// index range scan; the last id of a page comes back as text and
+	// PostgreSQL parses it into that type for the next
+	batch := func(where string) string {
+		return fmt.Sprintf(`WITH batch AS (
+	SELECT id, data, version, key FROM (%s) l %s ORDER BY key LIMIT $1
+), copied AS (
+	INSERT INTO %s (id, data, version) SELECT id, data, version FROM batch
+	ON CONFLICT (id) DO NOTHING RETURNING 1
+)
+SELECT (SELECT count(*) FROM batch), (SELECT key::text FROM batch ORDER BY key DESC LIMIT 1), (SELECT count(*) FROM copied)`,
+			rows, where, info.table)
+	}
+	first, next := batch(""), batch("WHERE key > $2")
+	var after *string
+	for {
+		var read, copied int64
+		var last *string
+		var err error
+		if after == nil {
+			err = exec.QueryRow(ctx, first, cfg.batchSize).Scan(&read, &last, &copied)
+		} else {
+			err = exec.QueryRow(ctx, next, cfg.batchSize, *after).Scan(&read, &last, &copied)
+		}
+		if err != nil {
+			if after == nil {
+				return nil, fmt.Errorf("hooks: migrate %s: copy: %w", name, err)
+			}
+			return nil, fmt.Errorf("hooks: migrate %s: copy after %q: %w", name, *after, err)
+		}
+		if read == 0 {
+			break
+		}
+		res.Read += read
+		res.Copied += copied
+		res.Elapsed = time.Since(start)
+		if cfg.progress != nil {
+			cfg.progress(res.MigrateProgress)
+		}
+		after = last
+	}
+
+	verify := fmt.Sprintf(`WITH src AS (%s)
+SELECT
+	(SELECT count(*) FROM src),
+	(SELECT count(*) FROM src JOIN %[2]s w ON w.id = src.id),
+	(SELECT count(*) FROM src JOIN %[2]s w ON w.id = src.id WHERE w.data IS DISTINCT FROM src.data),
+	COALESCE((SELECT md5(string_agg(src.id || ':' || src.data::text, ',' ORDER BY src.id)) FROM src), ''),
+	COALESCE((SELECT md5(string_agg(w.id || ':' || w.data::text, ',' ORDER BY w.id)) FROM src JOIN %[2]s w ON w.id = src.id), '')`,
+		rows, info.table)
+	err := exec.QueryRow(ctx, verify).Scan(
+		&res.SourceRows, &res.TargetRows, &res.Mismatched, &res.SourceChecksum, &res.TargetChecksum,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("hooks: migrate %s: verify: %w", name, err)
+	}
+	res.Elapsed = time.Since(start)
+	if res.TargetRows != res.SourceRows {
+		return res, fmt.Errorf("hooks: migrate %s: %d of %d source rows have no whisker row: %w",
+			name, res.SourceRows-res.TargetRows, res.SourceRows, ErrMigrationIncomplete)
+	}
+	return res, nil
+}
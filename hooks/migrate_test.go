@@ -0,0 +1,42 @@
+package hooks
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMigrate_RequiresRegisteredModel(t *testing.T) {
+	pool := NewPool(nil)
+	if _, err := Migrate(context.Background(), pool, "users"); err == nil {
+		t.Fatal("expected error for unregistered model")
+	}
+}
+
+func TestMigrate_RejectsNonPositiveBatchSize(t *testing.T) {
+	pool := NewPool(nil)
+	Register[testUser](pool, "users")
+	if _, err := Migrate(context.Background(), pool, "users", WithMigrateBatchSize(0)); err == nil {
+		t.Fatal("expected error for zero batch size")
+	}
+}
+
+func TestMigrate_EnsureErrorsArePrefixedOnce(t *testing.T) {
+	pool := NewPool(nil)
+	Register[testUser](pool, "bad name")
+	_, err := Migrate(context.Background(), pool, "bad name")
+	if err == nil {
+		t.Fatal("expected error for an invalid collection name")
+	}
+	if n := strings.Count(err.Error(), "hooks:"); n != 1 {
+		t.Errorf("got %q, want a single hooks prefix", err)
+	}
+}
+
+func TestLegacyRows_KeepsTheNativeKey(t *testing.T) {
+	info := analyzeModel[testUser]("users")
+	got := legacyRows(info, "users")
+	if !strings.HasPrefix(got, `SELECT l."id"::text AS id, `) || !strings.HasSuffix(got, `, l."id" AS key FROM "users" l`) {
+		t.Errorf("got %s", got)
+	}
+}
@@ -24,7 +24,7 @@ func (p *Pool) Exec(ctx context.Context, sql string, args ...any) (pgconn.Comman
 	switch op {
 	case opInsert:
 		if err := p.ensureTable(ctx, info); err != nil {
-			return pgconn.CommandTag{}, fmt.Errorf("hooks: ensure table %s: %w", info.table, err)
+			return pgconn.CommandTag{}, err
 		}
 		rewritten, newArgs, err := rewriteInsert(info, sql, args)
 		if err != nil {
@@ -80,6 +80,9 @@ func (p *Pool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, er
 		return p.store.DBExecutor().Query(ctx, rewritten, newArgs...)
 
 	case opSelect:
+		if err := p.prepareRead(ctx, sql, args); err != nil {
+			return nil, err
+		}
 		rewritten, newArgs, err := rewriteSelect(info, sql, args)
 		if err != nil {
 			return nil, err
@@ -88,6 +91,9 @@ func (p *Pool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, er
 		if err != nil {
 			return nil, err
 		}
+		if isCountSelect(sql) {
+			return rows, nil
+		}
 		return &translatedRows{inner: rows, info: info}, nil
 
 	default:
@@ -81,3 +81,199 @@ func TestPool_Passthrough(t *testing.T) {
 		t.Fatalf("passthrough: %v", err)
 	}
 }
+
+type poolTestAccount struct {
+	Key   string `whisker:"id"`
+	Name  string `json:"display_name" whisker:"index"`
+	Email string `whisker:"index,ci"`
+	Tier  int    `whisker:"column,index"`
+}
+
+func TestPool_EnsureTableCreatesDeclaredIndexes(t *testing.T) {
+	connStr := testutil.SetupPostgres(t)
+	ctx := context.Background()
+
+	store, err := whisker.New(ctx, connStr)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	pool := NewPool(store)
+	Register[poolTestAccount](pool, "accounts")
+
+	_, err = pool.Exec(ctx,
+		"INSERT INTO accounts (key, name, email, tier) VALUES ($1, $2, $3, $4)",
+		"a1", "Alice", "Alice@Test.com", 3,
+	)
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	for _, name := range []string{"idx_whisker_accounts_display_name", "idx_whisker_accounts_email_ci", "idx_whisker_accounts_tier"} {
+		var exists bool
+		err := store.PgxPool().QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM pg_indexes WHERE indexname = $1)", name,
+		).Scan(&exists)
+		if err != nil {
+			t.Fatalf("check index %s: %v", name, err)
+		}
+		if !exists {
+			t.Errorf("index %s not created", name)
+		}
+	}
+
+	var tier int
+	var name string
+	err = store.PgxPool().QueryRow(ctx,
+		"SELECT tier, data->>'display_name' FROM whisker_accounts WHERE id = $1", "a1",
+	).Scan(&tier, &name)
+	if err != nil {
+		t.Fatalf("read row: %v", err)
+	}
+	if tier != 3 || name != "Alice" {
+		t.Errorf("tier, name = %d, %q, want 3, Alice", tier, name)
+	}
+}
+
+func TestPool_DualReadFromLegacyTable(t *testing.T) {
+	connStr := testutil.SetupPostgres(t)
+	ctx := context.Background()
+
+	store, err := whisker.New(ctx, connStr)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	_, err = store.PgxPool().Exec(ctx, `
+		CREATE TABLE users (id TEXT PRIMARY KEY, name TEXT NOT NULL, email TEXT NOT NULL);
+		INSERT INTO users VALUES ('u1', 'Alice', 'alice@test.com'), ('u2', 'Bob', 'bob@test.com')`)
+	if err != nil {
+		t.Fatalf("create legacy table: %v", err)
+	}
+
+	pool := NewPool(store)
+	Register[poolTestUser](pool, "users", WithLegacyTable("users"), WithBackfillOnRead())
+
+	_, err = pool.Exec(ctx,
+		"INSERT INTO users (id, name, email) VALUES ($1, $2, $3)",
+		"u3", "Carol", "carol@test.com",
+	)
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	var total int
+	if err := pool.QueryRow(ctx, "SELECT count(*) FROM users").Scan(&total); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("total = %d, want 3", total)
+	}
+
+	var id, name, email string
+	var version int
+	err = pool.QueryRow(ctx, "SELECT id, name, email, version FROM users WHERE name = $1", "Alice").
+		Scan(&id, &name, &email, &version)
+	if err != nil {
+		t.Fatalf("read legacy row: %v", err)
+	}
+	if id != "u1" || email != "alice@test.com" || version != 1 {
+		t.Errorf("got (%s, %s, %d)", id, email, version)
+	}
+
+	var migrated []string
+	rows, err := store.PgxPool().Query(ctx, "SELECT id FROM whisker_users ORDER BY id")
+	if err != nil {
+		t.Fatalf("list whisker rows: %v", err)
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		migrated = append(migrated, id)
+	}
+	rows.Close()
+	if len(migrated) != 2 || migrated[0] != "u1" || migrated[1] != "u3" {
+		t.Errorf("whisker rows = %v, want [u1 u3]: only the read legacy row is backfilled", migrated)
+	}
+
+	if err := pool.QueryRow(ctx, "SELECT count(*) FROM users").Scan(&total); err != nil {
+		t.Fatalf("count after backfill: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("total after backfill = %d, want 3", total)
+	}
+}
+
+func TestMigrate_CopiesPlainTableInBatches(t *testing.T) {
+	connStr := testutil.SetupPostgres(t)
+	ctx := context.Background()
+
+	store, err := whisker.New(ctx, connStr)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	_, err = store.PgxPool().Exec(ctx, `
+		CREATE TABLE legacy_users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, email TEXT, version INTEGER NOT NULL DEFAULT 1);
+		INSERT INTO legacy_users (id, name, email, version)
+		SELECT g, 'user ' || g, 'user' || g || '@test.com', g % 3 + 1 FROM generate_series(1, 11) g`)
+	if err != nil {
+		t.Fatalf("create legacy table: %v", err)
+	}
+
+	pool := NewPool(store)
+	Register[poolTestUser](pool, "users")
+
+	// a row written through whisker after the cutover is left alone
+	_, err = pool.Exec(ctx, "INSERT INTO users (id, name, email) VALUES ($1, $2, $3)", "2", "Renamed", "two@test.com")
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	var progress []MigrateProgress
+	res, err := Migrate(ctx, pool, "users",
+		WithSourceTable("legacy_users"),
+		WithMigrateBatchSize(2),
+		WithProgress(func(p MigrateProgress) { progress = append(progress, p) }),
+	)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if res.Total != 11 || res.Read != 11 || res.Copied != 10 {
+		t.Errorf("total, read, copied = %d, %d, %d, want 11, 11, 10", res.Total, res.Read, res.Copied)
+	}
+	// the integer ids page in numeric order, across 9 and 10
+	if len(progress) != 6 || progress[5].Read != 11 {
+		t.Errorf("progress = %+v, want 6 batches ending at 11 rows", progress)
+	}
+	if res.SourceRows != 11 || res.TargetRows != 11 || res.Mismatched != 1 {
+		t.Errorf("source, target, mismatched = %d, %d, %d, want 11, 11, 1", res.SourceRows, res.TargetRows, res.Mismatched)
+	}
+	if res.SourceChecksum == "" || res.SourceChecksum == res.TargetChecksum {
+		t.Errorf("checksums %q, %q should differ by the renamed row", res.SourceChecksum, res.TargetChecksum)
+	}
+
+	var name string
+	var version int
+	err = pool.QueryRow(ctx, "SELECT id, name, email, version FROM users WHERE id = $1", "4").
+		Scan(new(string), &name, new(string), &version)
+	if err != nil {
+		t.Fatalf("read migrated row: %v", err)
+	}
+	if name != "user 4" || version != 2 {
+		t.Errorf("name, version = %q, %d, want user 4, 2", name, version)
+	}
+
+	again, err := Migrate(ctx, pool, "users", WithSourceTable("legacy_users"))
+	if err != nil {
+		t.Fatalf("migrate again: %v", err)
+	}
+	if again.Copied != 0 {
+		t.Errorf("second run copied %d rows, want 0", again.Copied)
+	}
+}
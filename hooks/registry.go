@@ -4,6 +4,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 
 	"github.com/ripkitten-co/whisker/internal/meta"
@@ -12,6 +13,7 @@ import (
 type columnInfo struct {
 	name    string // SQL column name the ORM sees
 	jsonKey string // JSONB field key in data column
+	cast    string // SQL type ORDER BY casts to; empty sorts as text
 }
 
 type modelInfo struct {
@@ -22,6 +24,10 @@ type modelInfo struct {
 	dataCols   []columnInfo
 	structType reflect.Type
 	meta       *meta.StructMeta
+
+	// dual-read mode, see WithLegacyTable
+	legacyTable    string
+	backfillOnRead bool
 }
 
 type registry struct {
@@ -39,6 +45,11 @@ func newRegistry() *registry {
 	}
 }
 
+// analyzeModel derives a model's columns from the same metadata documents
+// use, so whisker and json tags mean the same thing in both: json names the
+// JSONB key, whisker:"id" and whisker:"version" pick the fields stored in the
+// id and version columns, and whisker:"index" and whisker:"column" declare
+// what ensureTable creates.
 func analyzeModel[T any](name string) *modelInfo {
 	m := meta.Analyze[T]()
 	t := reflect.TypeOf((*T)(nil)).Elem()
@@ -54,20 +65,52 @@ func analyzeModel[T any](name string) *modelInfo {
 		dataCols = append(dataCols, columnInfo{
 			name:    toLowerSnake(sf.Name),
 			jsonKey: f.JSONKey,
+			cast:    sortCast(sf.Type),
 		})
 	}
 
 	return &modelInfo{
 		name:       name,
 		table:      "whisker_" + name,
-		idColumn:   "id",
-		versionCol: "version",
+		idColumn:   fieldColumn(t, m.IDIndex, "id"),
+		versionCol: fieldColumn(t, m.VersionIndex, "version"),
 		dataCols:   dataCols,
 		structType: t,
 		meta:       m,
 	}
 }
 
+// fieldColumn returns the column name the ORM uses for the struct field at
+// index i, or def when the struct has no such field.
+func fieldColumn(t reflect.Type, i int, def string) string {
+	if i < 0 {
+		return def
+	}
+	return toLowerSnake(t.Field(i).Name)
+}
+
+// sortCast returns the SQL type a field is cast to when ordering by it, so
+// numbers, booleans and times sort by value instead of as JSONB text.
+func sortCast(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeFor[time.Time]() {
+		return "timestamptz"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return "bigint"
+	case reflect.Uint64, reflect.Float32, reflect.Float64:
+		return "numeric"
+	case reflect.Bool:
+		return "boolean"
+	default:
+		return ""
+	}
+}
+
 func (r *registry) register(name string, info *modelInfo) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -71,3 +71,62 @@ func TestToLowerSnake(t *testing.T) {
 		}
 	}
 }
+
+type testTaggedAccount struct {
+	Key     string `whisker:"id"`
+	Rev     int    `whisker:"version"`
+	Name    string `json:"display_name" whisker:"index"`
+	Email   string `whisker:"index,ci"`
+	Tier    int    `whisker:"column"`
+	Secret  string `json:"-"`
+	Created string
+}
+
+func TestRegister_HonorsWhiskerAndJSONTags(t *testing.T) {
+	info := analyzeModel[testTaggedAccount]("accounts")
+
+	if info.idColumn != "key" || info.versionCol != "rev" {
+		t.Errorf("id, version = %q, %q, want key, rev", info.idColumn, info.versionCol)
+	}
+	keys := map[string]string{}
+	for _, dc := range info.dataCols {
+		keys[dc.name] = dc.jsonKey
+	}
+	want := map[string]string{"name": "display_name", "email": "email", "tier": "tier", "created": "created"}
+	if len(keys) != len(want) {
+		t.Errorf("data columns = %v, want %v", keys, want)
+	}
+	for name, key := range want {
+		if keys[name] != key {
+			t.Errorf("column %s: json key = %q, want %q", name, keys[name], key)
+		}
+	}
+	if len(info.meta.Indexes) != 2 || len(info.meta.Columns) != 1 {
+		t.Errorf("indexes = %+v, columns = %+v", info.meta.Indexes, info.meta.Columns)
+	}
+}
+
+func TestRewrite_TaggedIDAndVersion(t *testing.T) {
+	info := analyzeModel[testTaggedAccount]("accounts")
+
+	ins, args, err := rewriteInsert(info, "INSERT INTO accounts (key, name) VALUES ($1, $2)", []any{"a1", "Alice"})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if !containsSubstring(ins, "'display_name', $2::text") || args[0] != "a1" {
+		t.Errorf("insert: %s %v", ins, args)
+	}
+
+	sel, _, err := rewriteSelect(info, `SELECT * FROM accounts WHERE "key" = $1 AND rev > $2 ORDER BY name`, nil)
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if want := `SELECT id, data, version FROM whisker_accounts WHERE id = $1 AND version > $2 ORDER BY data->>'display_name'`; sel != want {
+		t.Errorf("select:\n got: %s\nwant: %s", sel, want)
+	}
+
+	gormSel, _ := rewriteGORMSelect(info, `SELECT * FROM accounts`, nil)
+	if !containsSubstring(gormSel, `SELECT id AS "key", `) || !containsSubstring(gormSel, `, version AS "rev" FROM`) {
+		t.Errorf("gorm select: %s", gormSel)
+	}
+}
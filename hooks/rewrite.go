@@ -3,6 +3,8 @@ package hooks
 import (
 	"fmt"
 	"strings"
+
+	"github.com/ripkitten-co/whisker/internal/ident"
 )
 
 // rewriteInsert transforms an ORM INSERT targeting a plain table into a
@@ -47,7 +49,7 @@ func rewriteInsert(info *modelInfo, sql string, args []any) (string, []any, erro
 		if !exists {
 			continue
 		}
-		jsonPairs = append(jsonPairs, fmt.Sprintf("'%s', $%d::text", dc.jsonKey, argIdx))
+		jsonPairs = append(jsonPairs, fmt.Sprintf("%s, $%d::text", ident.Literal(dc.jsonKey), argIdx))
 		newArgs = append(newArgs, val)
 		argIdx++
 	}
@@ -66,24 +68,158 @@ func rewriteInsert(info *modelInfo, sql string, args []any) (string, []any, erro
 }
 
 // rewriteSelect transforms an ORM SELECT into a Whisker JSONB query.
-// Column references in WHERE are translated to JSONB paths.
-// The result includes (id, data, version) — caller unpacks via rows wrapper.
+// Column references after FROM are translated to JSONB paths.
+// The result includes (id, data, version) — caller unpacks via rows wrapper —
+// except for COUNT queries, whose select list is kept.
 func rewriteSelect(info *modelInfo, sql string, args []any) (string, []any, error) {
+	sql, locking := splitLockingClause(sql)
+	rewritten := replaceTableName(sql, info.name, info.table)
+
+	count := isCountSelect(rewritten)
+	rewritten = rewriteSelectRefs(rewritten, info, count)
+	if !count {
+		rewritten = rewriteSelectColumns(rewritten, info)
+	}
+	if info.legacyTable != "" && locking == "" {
+		rewritten = dualRead(rewritten, info)
+	}
+
+	return appendLockingClause(rewritten, locking, info), args, nil
+}
+
+// isCountSelect reports whether a SELECT computes a COUNT, as ORMs do for the
+// total of a paginated listing. Such queries return the count, not rows of
+// the model.
+func isCountSelect(sql string) bool {
 	upper := strings.ToUpper(sql)
+	selectIdx := strings.Index(upper, "SELECT ")
+	fromIdx := strings.Index(upper, " FROM ")
+	if selectIdx == -1 || fromIdx < selectIdx {
+		return false
+	}
+	list := strings.TrimSpace(upper[selectIdx+7 : fromIdx])
+	return strings.HasPrefix(list, "COUNT(") || strings.HasPrefix(list, "COUNT (")
+}
 
-	rewritten := replaceTableName(sql, info.name, info.table)
+// rewriteSelectRefs translates column references in a single-table SELECT
+// from FROM onwards, or from the select list when withList is set. Columns
+// may be qualified by the model name, the whisker table or the alias given in
+// FROM. ORDER BY terms on numeric, boolean and time fields are cast so rows
+// sort by value rather than as text; LIMIT and OFFSET pass through.
+func rewriteSelectRefs(sql string, info *modelInfo, withList bool) string {
+	upper := strings.ToUpper(sql)
+	fromIdx := strings.Index(upper, " FROM ")
+	if fromIdx == -1 {
+		return sql
+	}
+	alias := fromAlias(sql[fromIdx+6:])
+	own := func(qualifier string) bool {
+		return qualifier == "" || strings.EqualFold(qualifier, info.name) ||
+			strings.EqualFold(qualifier, info.table) || (alias != "" && strings.EqualFold(qualifier, alias))
+	}
+	filter := func(qualifier, column string) (string, bool) {
+		if !own(qualifier) {
+			return "", false
+		}
+		return info.jsonPath(column)
+	}
+	order := func(qualifier, column string) (string, bool) {
+		if !own(qualifier) {
+			return "", false
+		}
+		return info.sortPath(column)
+	}
 
-	whereIdx := strings.Index(upper, " WHERE ")
-	if whereIdx >= 0 {
-		before := rewritten[:whereIdx+7]
-		after := rewritten[whereIdx+7:]
-		after = rewriteColumnRefs(after, info)
-		rewritten = before + after
+	start := fromIdx
+	if selectIdx := strings.Index(upper, "SELECT "); withList && selectIdx >= 0 && selectIdx < fromIdx {
+		start = selectIdx + 7
+	}
+	head, tail := sql[:start], sql[start:]
+	orderIdx := strings.LastIndex(strings.ToUpper(tail), " ORDER BY ")
+	if orderIdx == -1 {
+		return head + rewriteRefs(tail, filter)
 	}
+	return head + rewriteRefs(tail[:orderIdx], filter) + rewriteRefs(tail[orderIdx:], order)
+}
 
-	rewritten = rewriteSelectColumns(rewritten, info)
+// fromClauseKeywords end a FROM item, so they are never taken for an alias.
+var fromClauseKeywords = map[string]bool{
+	"WHERE": true, "ORDER": true, "GROUP": true, "HAVING": true, "LIMIT": true,
+	"OFFSET": true, "FOR": true, "JOIN": true, "LEFT": true, "RIGHT": true,
+	"INNER": true, "OUTER": true, "CROSS": true, "FULL": true, "ON": true,
+	"WINDOW": true, "UNION": true, "FETCH": true,
+}
 
-	return rewritten, args, nil
+// fromAlias returns the alias of the FROM item at the start of rest, with or
+// without AS, or "" when the table is not aliased.
+func fromAlias(rest string) string {
+	rest = strings.TrimLeft(rest, " \t\n")
+	if rest == "" {
+		return ""
+	}
+	_, _, end := readIdent(rest, 0)
+	rest = strings.TrimLeft(rest[end:], " \t\n")
+	if len(rest) > 3 && strings.EqualFold(rest[:3], "AS ") {
+		rest = strings.TrimLeft(rest[3:], " \t\n")
+	}
+	if rest == "" || (rest[0] != '"' && !isIdentChar(rest[0])) {
+		return ""
+	}
+	alias, quoted, _ := readIdent(rest, 0)
+	if !quoted && fromClauseKeywords[strings.ToUpper(alias)] {
+		return ""
+	}
+	return alias
+}
+
+// lockingStrengths are the row-locking clauses PostgreSQL accepts at the end
+// of a SELECT.
+var lockingStrengths = []string{"FOR UPDATE", "FOR NO KEY UPDATE", "FOR SHARE", "FOR KEY SHARE"}
+
+// splitLockingClause separates the trailing row-locking clause of a SELECT
+// (FOR UPDATE, FOR SHARE and friends, with any OF, NOWAIT or SKIP LOCKED)
+// so that column rewriting never touches it. Keywords inside quotes and
+// subqueries are ignored. locking is empty when the query has none.
+func splitLockingClause(sql string) (body, locking string) {
+	upper := strings.ToUpper(sql)
+	depth := 0
+	var quote byte
+	for i := 0; i < len(upper); i++ {
+		c := upper[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && c == 'F' && (i == 0 || !isIdentChar(upper[i-1])):
+			for _, strength := range lockingStrengths {
+				end := i + len(strength)
+				if strings.HasPrefix(upper[i:], strength) && (end == len(upper) || !isIdentChar(upper[end])) {
+					return strings.TrimRight(sql[:i], " \t\n"), sql[i:]
+				}
+			}
+		}
+	}
+	return sql, ""
+}
+
+// appendLockingClause re-attaches a clause split off by splitLockingClause,
+// pointing OF table references at the whisker tables. Aliases are left as
+// they are.
+func appendLockingClause(sql, locking string, infos ...*modelInfo) string {
+	if locking == "" {
+		return sql
+	}
+	for _, info := range infos {
+		locking = replaceTableName(locking, info.name, info.table)
+	}
+	return sql + " " + locking
 }
 
 func replaceTableName(sql, oldTable, newTable string) string {
@@ -116,11 +252,200 @@ func isIdentChar(c byte) bool {
 	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_'
 }
 
+// rewriteColumnRefs translates references to the model's data columns in a
+// WHERE clause (and anything after it) to JSONB paths. Bare, quoted and
+// table-qualified references are handled; see rewriteRefs.
 func rewriteColumnRefs(whereClause string, info *modelInfo) string {
-	for _, dc := range info.dataCols {
-		whereClause = replaceWord(whereClause, dc.name, fmt.Sprintf("data->>'%s'", dc.jsonKey))
+	return rewriteRefs(whereClause, func(qualifier, column string) (string, bool) {
+		if qualifier != "" && !strings.EqualFold(qualifier, info.name) && !strings.EqualFold(qualifier, info.table) {
+			return "", false
+		}
+		return info.jsonPath(column)
+	})
+}
+
+// jsonPath returns the JSONB path for a data column. The ORM's names for
+// the id and version fields map to the id and version columns.
+func (m *modelInfo) jsonPath(column string) (string, bool) {
+	if fixed, ok := m.fixedColumn(column); ok {
+		return fixed, true
+	}
+	for _, dc := range m.dataCols {
+		if strings.EqualFold(dc.name, column) {
+			return ident.JSONText(dc.jsonKey), true
+		}
+	}
+	return "", false
+}
+
+// sortPath returns the ORDER BY expression for a data column: its JSONB
+// path, cast to the field's type when text ordering would be wrong.
+func (m *modelInfo) sortPath(column string) (string, bool) {
+	if fixed, ok := m.fixedColumn(column); ok {
+		return fixed, true
+	}
+	for _, dc := range m.dataCols {
+		if strings.EqualFold(dc.name, column) {
+			if dc.cast == "" {
+				return ident.JSONText(dc.jsonKey), true
+			}
+			return "(" + ident.JSONText(dc.jsonKey) + ")::" + dc.cast, true
+		}
+	}
+	return "", false
+}
+
+// fixedColumn maps the ORM's name for the id or version field to the real
+// column when the two differ, as with a whisker:"id" tag on a field not
+// called ID.
+func (m *modelInfo) fixedColumn(column string) (string, bool) {
+	switch {
+	case m.idColumn != "id" && strings.EqualFold(column, m.idColumn):
+		return "id", true
+	case m.versionCol != "version" && strings.EqualFold(column, m.versionCol):
+		return "version", true
+	}
+	return "", false
+}
+
+// sqlKeywords are never treated as column references when unquoted, so a
+// data column named like one cannot swallow IN, BETWEEN, IS NULL and the
+// like. Most are reserved words that PostgreSQL only accepts as quoted
+// column names, which are still rewritten.
+var sqlKeywords = map[string]bool{
+	"and": true, "or": true, "not": true, "in": true, "is": true, "null": true,
+	"between": true, "symmetric": true, "any": true, "all": true, "some": true,
+	"like": true, "ilike": true, "similar": true, "to": true, "exists": true,
+	"true": true, "false": true, "distinct": true, "from": true, "collate": true,
+	"case": true, "when": true, "then": true, "else": true, "end": true,
+	"order": true, "asc": true, "desc": true, "limit": true, "offset": true,
+	"nulls": true, "by": true, "as": true,
+}
+
+// rewriteRefs rewrites column references in a SQL fragment in a single pass.
+// resolve maps a reference to its replacement; qualifier is the table or
+// alias before the dot, or empty for a bare column. The replacement keeps the
+// qualifier as written. String literals, placeholders, casts, function names
+// and unquoted keywords are copied unchanged, so boolean groups, IN and ANY
+// lists, BETWEEN and IS NULL predicates keep their structure.
+func rewriteRefs(sql string, resolve func(qualifier, column string) (string, bool)) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+	i := 0
+	for i < len(sql) {
+		c := sql[i]
+		switch {
+		case c == '\'':
+			end := skipLiteral(sql, i)
+			b.WriteString(sql[i:end])
+			i = end
+		case c == '$' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(sql) && isIdentChar(sql[end]) {
+				end++
+			}
+			b.WriteString(sql[i:end])
+			i = end
+		case c == ':' && i+1 < len(sql) && sql[i+1] == ':':
+			// a cast: the type name that follows is not a column
+			end := i + 2
+			for end < len(sql) && (isIdentChar(sql[end]) || sql[end] == '"') {
+				end++
+			}
+			b.WriteString(sql[i:end])
+			i = end
+		case c == '"' || isIdentChar(c):
+			end := rewriteRef(&b, sql, i, resolve)
+			i = end
+		default:
+			b.WriteByte(c)
+			i++
+		}
 	}
-	return whereClause
+	return b.String()
+}
+
+// rewriteRef writes the possibly qualified identifier starting at start,
+// rewritten when resolve recognizes it, and returns the index after it.
+func rewriteRef(b *strings.Builder, sql string, start int, resolve func(qualifier, column string) (string, bool)) int {
+	first, firstQuoted, end := readIdent(sql, start)
+	qualifier, qualifierRaw := "", ""
+	column, quoted := first, firstQuoted
+	if end+1 < len(sql) && sql[end] == '.' && (sql[end+1] == '"' || isIdentChar(sql[end+1])) {
+		qualifier, qualifierRaw = first, sql[start:end]
+		column, quoted, end = readIdent(sql, end+1)
+	}
+	raw := sql[start:end]
+
+	rest := strings.TrimLeft(sql[end:], " \t\n")
+	isCall := strings.HasPrefix(rest, "(")
+	if isCall || (!quoted && qualifier == "" && sqlKeywords[strings.ToLower(column)]) {
+		b.WriteString(raw)
+		return end
+	}
+	replacement, ok := resolve(qualifier, column)
+	if !ok {
+		b.WriteString(raw)
+		return end
+	}
+	if qualifier != "" {
+		if rest, ok := strings.CutPrefix(replacement, "("); ok {
+			// a cast replacement: qualify the path inside the parentheses
+			replacement = "(" + qualifierRaw + "." + rest
+		} else {
+			replacement = qualifierRaw + "." + replacement
+		}
+	}
+	if strings.HasPrefix(rest, "::") {
+		// :: binds tighter than ->>
+		replacement = "(" + replacement + ")"
+	}
+	b.WriteString(replacement)
+	return end
+}
+
+// readIdent reads a bare or double-quoted identifier starting at start and
+// returns its name and the index after it.
+func readIdent(sql string, start int) (name string, quoted bool, end int) {
+	if sql[start] != '"' {
+		end = start
+		for end < len(sql) && isIdentChar(sql[end]) {
+			end++
+		}
+		return sql[start:end], false, end
+	}
+	var sb strings.Builder
+	i := start + 1
+	for i < len(sql) {
+		if sql[i] == '"' {
+			if i+1 < len(sql) && sql[i+1] == '"' {
+				sb.WriteByte('"')
+				i += 2
+				continue
+			}
+			return sb.String(), true, i + 1
+		}
+		sb.WriteByte(sql[i])
+		i++
+	}
+	return sb.String(), true, len(sql)
+}
+
+// skipLiteral returns the index after the single-quoted literal starting at
+// start, honouring doubled quotes.
+func skipLiteral(sql string, start int) int {
+	i := start + 1
+	for i < len(sql) {
+		if sql[i] == '\'' {
+			if i+1 < len(sql) && sql[i+1] == '\'' {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return len(sql)
 }
 
 func rewriteSelectColumns(sql string, info *modelInfo) string {
@@ -145,7 +470,7 @@ func rewriteUpdate(info *modelInfo, sql string, args []any) (string, []any, erro
 	for i, col := range setCols {
 		for _, dc := range info.dataCols {
 			if strings.EqualFold(col, dc.name) {
-				jsonPairs = append(jsonPairs, fmt.Sprintf("'%s', $%d::text", dc.jsonKey, argIdx))
+				jsonPairs = append(jsonPairs, fmt.Sprintf("%s, $%d::text", ident.Literal(dc.jsonKey), argIdx))
 				newArgs = append(newArgs, setArgs[i])
 				argIdx++
 				break
@@ -256,6 +581,9 @@ func renumberArgs(sql string, oldStart, newStart int) string {
 // rewriteCreateTable replaces an ORM-generated CREATE TABLE with Whisker's
 // standard document table DDL.
 func rewriteCreateTable(info *modelInfo, _ string) (string, error) {
+	if err := ident.ValidateCollection(info.name); err != nil {
+		return "", fmt.Errorf("hooks: %w", err)
+	}
 	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
 	id TEXT PRIMARY KEY,
 	data JSONB NOT NULL,
@@ -275,19 +603,22 @@ type tableAlias struct {
 // All registered table references are rewritten to their whisker_ equivalents,
 // and qualified column references are translated to JSONB paths.
 func rewriteJoin(r *registry, sql string, args []any) (string, []any, error) {
+	sql, locking := splitLockingClause(sql)
 	aliases, err := extractTableAliases(r, sql)
 	if err != nil {
 		return "", nil, err
 	}
 
 	rewritten := sql
+	infos := make([]*modelInfo, 0, len(aliases))
 	for _, ta := range aliases {
 		rewritten = replaceWord(rewritten, ta.info.name, ta.info.table)
+		infos = append(infos, ta.info)
 	}
 
 	rewritten = rewriteQualifiedRefs(rewritten, aliases)
 
-	return rewritten, args, nil
+	return appendLockingClause(rewritten, locking, infos...), args, nil
 }
 
 // extractTableAliases finds "table alias" pairs from FROM and JOIN clauses.
@@ -352,6 +683,10 @@ func extractTableAndAlias(s string) (table, alias string) {
 		return table, table
 	}
 	nextWord := extractFirstWord(rest)
+	if strings.EqualFold(nextWord, "AS") {
+		rest = strings.TrimSpace(rest[len(nextWord):])
+		nextWord = extractFirstWord(rest)
+	}
 	upperNext := strings.ToUpper(nextWord)
 	if upperNext == "ON" || upperNext == "WHERE" || upperNext == "JOIN" ||
 		upperNext == "LEFT" || upperNext == "RIGHT" || upperNext == "INNER" ||
@@ -365,15 +700,17 @@ func extractTableAndAlias(s string) (table, alias string) {
 // rewriteQualifiedRefs rewrites alias.column references to JSONB paths.
 // Real columns (id, version) stay as-is; data columns become alias.data->>'jsonKey'.
 func rewriteQualifiedRefs(sql string, aliases []tableAlias) string {
-	for _, ta := range aliases {
-		for _, dc := range ta.info.dataCols {
-			// alias.column_name -> alias.data->>'jsonKey'
-			old := ta.alias + "." + dc.name
-			replacement := ta.alias + ".data->>'" + dc.jsonKey + "'"
-			sql = replaceWord(sql, old, replacement)
+	return rewriteRefs(sql, func(qualifier, column string) (string, bool) {
+		if qualifier == "" {
+			return "", false
 		}
-	}
-	return sql
+		for _, ta := range aliases {
+			if strings.EqualFold(qualifier, ta.alias) || strings.EqualFold(qualifier, ta.info.table) {
+				return ta.info.jsonPath(column)
+			}
+		}
+		return "", false
+	})
 }
 
 // parseQuotedString reads a single-quoted SQL string starting after the
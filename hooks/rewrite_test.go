@@ -226,3 +226,219 @@ func TestRewrite_Delete(t *testing.T) {
 		t.Errorf("args = %v, want [u1]", newArgs)
 	}
 }
+
+type testJob struct {
+	ID     string
+	Queue  string
+	Locked bool
+}
+
+func TestRewrite_Select_PreservesLockingClause(t *testing.T) {
+	r := newRegistry()
+	r.register("jobs", analyzeModel[testJob]("jobs"))
+	info, _ := r.lookup("jobs")
+
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "for update skip locked",
+			sql:  "SELECT id, queue, locked FROM jobs WHERE queue = $1 LIMIT 1 FOR UPDATE SKIP LOCKED",
+			want: "SELECT id, data, version FROM whisker_jobs WHERE data->>'queue' = $1 LIMIT 1 FOR UPDATE SKIP LOCKED",
+		},
+		{
+			name: "for share of table nowait",
+			sql:  `SELECT * FROM "jobs" WHERE locked = $1 FOR SHARE OF "jobs" NOWAIT`,
+			want: "SELECT id, data, version FROM whisker_jobs WHERE data->>'locked' = $1 FOR SHARE OF whisker_jobs NOWAIT",
+		},
+		{
+			name: "for no key update",
+			sql:  "SELECT * FROM jobs WHERE id = $1 FOR NO KEY UPDATE",
+			want: "SELECT id, data, version FROM whisker_jobs WHERE id = $1 FOR NO KEY UPDATE",
+		},
+		{
+			name: "keyword in literal",
+			sql:  "SELECT * FROM jobs WHERE queue = 'for update'",
+			want: "SELECT id, data, version FROM whisker_jobs WHERE data->>'queue' = 'for update'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := rewriteSelect(info, tt.sql, nil)
+			if err != nil {
+				t.Fatalf("rewrite: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("sql:\n got: %s\nwant: %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewrite_GORMSelect_PreservesLockingClause(t *testing.T) {
+	info := analyzeModel[testJob]("jobs")
+
+	got, _ := rewriteGORMSelect(info, `SELECT * FROM "jobs" WHERE "queue" = $1 FOR UPDATE SKIP LOCKED`, nil)
+	if !containsSubstring(got, " FOR UPDATE SKIP LOCKED") || containsSubstring(got, "SKIP data") {
+		t.Errorf("locking clause mangled: %s", got)
+	}
+}
+
+func TestRewrite_Join_PreservesLockingClause(t *testing.T) {
+	r := newRegistry()
+	r.register("users", analyzeModel[testUser]("users"))
+	r.register("orders", analyzeModel[testOrder]("orders"))
+
+	sql := "SELECT u.id, o.id FROM users u JOIN orders o ON o.user_id = u.id WHERE u.name = $1 FOR UPDATE OF u SKIP LOCKED"
+	got, _, err := rewriteJoin(r, sql, nil)
+	if err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	if !containsSubstring(got, " FOR UPDATE OF u SKIP LOCKED") {
+		t.Errorf("locking clause not preserved: %s", got)
+	}
+}
+
+type testProfile struct {
+	ID   string
+	Name string
+	Data string
+	Tier int
+}
+
+func TestRewrite_ColumnRefsInPredicates(t *testing.T) {
+	info := analyzeModel[testProfile]("profiles")
+
+	tests := []struct {
+		name  string
+		where string
+		want  string
+	}{
+		{
+			name:  "in list",
+			where: "name IN ($1,$2)",
+			want:  "data->>'name' IN ($1,$2)",
+		},
+		{
+			name:  "any",
+			where: "tier = ANY($1) AND id <> $2",
+			want:  "data->>'tier' = ANY($1) AND id <> $2",
+		},
+		{
+			name:  "nested boolean groups",
+			where: "(name = $1 OR (tier BETWEEN $2 AND $3)) AND NOT (name IS NULL)",
+			want:  "(data->>'name' = $1 OR (data->>'tier' BETWEEN $2 AND $3)) AND NOT (data->>'name' IS NULL)",
+		},
+		{
+			name:  "quoted and qualified",
+			where: `"profiles"."name" IN ($1) AND whisker_profiles."tier" IS NOT NULL AND "id" = $2`,
+			want:  `"profiles".data->>'name' IN ($1) AND whisker_profiles.data->>'tier' IS NOT NULL AND "id" = $2`,
+		},
+		{
+			name:  "other table qualifier untouched",
+			where: "accounts.name = $1",
+			want:  "accounts.name = $1",
+		},
+		{
+			name:  "literals, casts and functions",
+			where: "name = 'name' AND lower(name) = 'it''s name' AND tier::int > $1",
+			want:  "data->>'name' = 'name' AND lower(data->>'name') = 'it''s name' AND (data->>'tier')::int > $1",
+		},
+		{
+			name:  "column named data",
+			where: "name = $1 OR data = $2",
+			want:  "data->>'name' = $1 OR data->>'data' = $2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewriteColumnRefs(tt.where, info); got != tt.want {
+				t.Errorf("where:\n got: %s\nwant: %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewrite_UpdateAndDeleteWithInList(t *testing.T) {
+	info := analyzeModel[testProfile]("profiles")
+
+	del, _, err := rewriteDelete(info, `DELETE FROM "profiles" WHERE "name" IN ($1,$2) OR (tier < $3)`, []any{"a", "b", 1})
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if want := `DELETE FROM whisker_profiles WHERE data->>'name' IN ($1,$2) OR (data->>'tier' < $3)`; del != want {
+		t.Errorf("delete:\n got: %s\nwant: %s", del, want)
+	}
+
+	upd, args, err := rewriteUpdate(info, `UPDATE profiles SET tier = $1 WHERE name IN ($2,$3)`, []any{2, "a", "b"})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if !containsSubstring(upd, "WHERE data->>'name' IN ($2,$3)") || len(args) != 3 {
+		t.Errorf("update: %s %v", upd, args)
+	}
+}
+
+func TestRewrite_PaginatedSelect(t *testing.T) {
+	info := analyzeModel[testProfile]("profiles")
+
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "order by real column without where",
+			sql:  "SELECT * FROM profiles ORDER BY created_at DESC LIMIT $1 OFFSET $2",
+			want: "SELECT id, data, version FROM whisker_profiles ORDER BY created_at DESC LIMIT $1 OFFSET $2",
+		},
+		{
+			name: "order by data columns with casts",
+			sql:  "SELECT * FROM profiles WHERE name <> $1 ORDER BY tier DESC NULLS LAST, name LIMIT $2 OFFSET $3",
+			want: "SELECT id, data, version FROM whisker_profiles WHERE data->>'name' <> $1 ORDER BY (data->>'tier')::bigint DESC NULLS LAST, data->>'name' LIMIT $2 OFFSET $3",
+		},
+		{
+			name: "bun alias",
+			sql:  `SELECT "p"."id", "p"."name" FROM "profiles" AS "p" WHERE ("p"."name" = $1) ORDER BY "p"."tier" ASC LIMIT 10`,
+			want: `SELECT id, data, version FROM whisker_profiles AS "p" WHERE ("p".data->>'name' = $1) ORDER BY ("p".data->>'tier')::bigint ASC LIMIT 10`,
+		},
+		{
+			name: "count",
+			sql:  `SELECT count(*) FROM "profiles" AS "p" WHERE ("p"."tier" > $1)`,
+			want: `SELECT count(*) FROM whisker_profiles AS "p" WHERE ("p".data->>'tier' > $1)`,
+		},
+		{
+			name: "count distinct data column",
+			sql:  `SELECT COUNT(DISTINCT "profiles"."name") FROM "profiles"`,
+			want: `SELECT COUNT(DISTINCT whisker_profiles.data->>'name') FROM whisker_profiles`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := rewriteSelect(info, tt.sql, nil)
+			if err != nil {
+				t.Fatalf("rewrite: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("sql:\n got: %s\nwant: %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewrite_GORMPaginatedSelect(t *testing.T) {
+	info := analyzeModel[testProfile]("profiles")
+
+	got, _ := rewriteGORMSelect(info, `SELECT "users"."id" FROM "profiles" AS "users" ORDER BY "users"."tier" DESC LIMIT 10 OFFSET 20`, nil)
+	want := `SELECT id, data->>'name' AS "name", data->>'data' AS "data", data->>'tier' AS "tier", version FROM whisker_profiles AS "users" ORDER BY ("users".data->>'tier')::bigint DESC LIMIT 10 OFFSET 20`
+	if got != want {
+		t.Errorf("select:\n got: %s\nwant: %s", got, want)
+	}
+
+	count, _ := rewriteGORMSelect(info, `SELECT count(*) FROM "profiles" WHERE "name" = $1`, nil)
+	if want := `SELECT count(*) FROM whisker_profiles WHERE data->>'name' = $1`; count != want {
+		t.Errorf("count:\n got: %s\nwant: %s", count, want)
+	}
+}
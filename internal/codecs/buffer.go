@@ -0,0 +1,61 @@
+package codecs
+
+import "sync"
+
+// Marshaler is implemented by documents that encode their own data fields,
+// skipping reflection. AppendJSON appends the document as a JSON object to
+// dst and returns the extended slice. Like WhiskerCodec's output, the object
+// must leave out the ID and Version fields.
+type Marshaler interface {
+	AppendJSON(dst []byte) ([]byte, error)
+}
+
+// AppendMarshaler is implemented by codecs that can encode into a caller's
+// buffer instead of allocating a new one.
+type AppendMarshaler interface {
+	AppendMarshal(dst []byte, v any) ([]byte, error)
+}
+
+// AppendMarshal appends the encoding of v to dst. A Marshaler encodes itself;
+// otherwise c's AppendMarshal is used when it has one, and its Marshal
+// output is copied when it doesn't.
+func AppendMarshal(c Codec, dst []byte, v any) ([]byte, error) {
+	if m, ok := v.(Marshaler); ok {
+		return m.AppendJSON(dst)
+	}
+	if am, ok := c.(AppendMarshaler); ok {
+		return am.AppendMarshal(dst, v)
+	}
+	data, err := c.Marshal(v)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, data...), nil
+}
+
+// maxPooledBuffer is the capacity above which buffers are dropped rather than
+// pooled, so one huge document doesn't pin its memory.
+const maxPooledBuffer = 64 << 10
+
+var buffers = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+// GetBuffer returns an empty buffer from the pool. Return it with PutBuffer
+// once nothing refers to its contents.
+func GetBuffer() *[]byte {
+	b := buffers.Get().(*[]byte)
+	*b = (*b)[:0]
+	return b
+}
+
+// PutBuffer returns b to the pool.
+func PutBuffer(b *[]byte) {
+	if cap(*b) > maxPooledBuffer {
+		return
+	}
+	buffers.Put(b)
+}
@@ -0,0 +1,133 @@
+package codecs
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+type selfEncoded struct {
+	ID      string
+	Hits    int64
+	Version int
+}
+
+func (d *selfEncoded) AppendJSON(dst []byte) ([]byte, error) {
+	if d.Hits < 0 {
+		return dst, errors.New("negative hits")
+	}
+	dst = append(dst, `{"hits":`...)
+	dst = strconv.AppendInt(dst, d.Hits, 10)
+	return append(dst, '}'), nil
+}
+
+func TestAppendMarshal_JSONIterMatchesMarshal(t *testing.T) {
+	c := NewJSONIter()
+	v := map[string]any{"name": "<Alice>", "tags": []string{"a"}}
+	want, err := c.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := AppendMarshal(c, []byte("prefix:"), v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "prefix:"+string(want) {
+		t.Errorf("got %s, want prefix:%s", got, want)
+	}
+}
+
+func TestAppendMarshal_JSONIterDoesNotRetainBuffer(t *testing.T) {
+	c := NewJSONIter()
+	dst := make([]byte, 0, 256)
+	first, err := c.AppendMarshal(dst, map[string]string{"a": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := string(first)
+	for range 10 {
+		if _, err := c.Marshal(map[string]string{"b": "overwritten"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(first) != saved {
+		t.Errorf("buffer was reused by a later encode: %s, want %s", first, saved)
+	}
+}
+
+func TestAppendMarshal_Marshaler(t *testing.T) {
+	w := NewWhisker(NewJSONIter())
+	doc := &selfEncoded{ID: "c1", Hits: 42, Version: 3}
+
+	got, err := AppendMarshal(w, nil, doc)
+	if err != nil || string(got) != `{"hits":42}` {
+		t.Errorf("AppendMarshal = %s, %v", got, err)
+	}
+	got, err = w.Marshal(doc)
+	if err != nil || string(got) != `{"hits":42}` {
+		t.Errorf("Marshal = %s, %v", got, err)
+	}
+	if _, err := w.Marshal(&selfEncoded{Hits: -1}); err == nil {
+		t.Error("expected AppendJSON error")
+	}
+}
+
+func TestAppendMarshal_WhiskerStructMatchesMarshal(t *testing.T) {
+	type doc struct {
+		ID      string
+		Name    string
+		Version int
+	}
+	w := NewWhisker(NewJSONIter())
+	d := &doc{ID: "u1", Name: "Alice", Version: 2}
+	want, err := w.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := w.AppendMarshal(nil, d)
+	if err != nil || string(got) != string(want) {
+		t.Errorf("got %s, %v; want %s", got, err, want)
+	}
+}
+
+// plainCodec hides the AppendMarshal of the codec it wraps.
+type plainCodec struct{ Codec }
+
+func TestWhiskerMarshal_DoesNotRetainPooledBuffer(t *testing.T) {
+	type doc struct {
+		ID      string
+		Name    string
+		Version int
+	}
+	for name, inner := range map[string]Codec{"appending": NewJSONIter(), "plain": plainCodec{NewJSONIter()}} {
+		t.Run(name, func(t *testing.T) {
+			w := NewWhisker(inner)
+			first, err := w.Marshal(&doc{ID: "u1", Name: "Alice", Version: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for range 10 {
+				if _, err := w.Marshal(&doc{ID: "u2", Name: "overwritten"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if string(first) != `{"name":"Alice"}` {
+				t.Errorf("got %s, want {\"name\":\"Alice\"}", first)
+			}
+		})
+	}
+}
+
+func TestPutBuffer_DropsLargeBuffers(t *testing.T) {
+	b := GetBuffer()
+	if len(*b) != 0 {
+		t.Fatalf("got buffer of length %d, want empty", len(*b))
+	}
+	*b = make([]byte, 0, maxPooledBuffer+1)
+	PutBuffer(b)
+	for range 100 {
+		if got := GetBuffer(); cap(*got) > maxPooledBuffer {
+			t.Fatal("oversized buffer was pooled")
+		}
+	}
+}
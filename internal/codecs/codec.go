@@ -1,6 +1,7 @@
 package codecs
 
-// Codec marshals and unmarshals values to and from bytes.
+// Codec marshals and unmarshals values to and from bytes. Unmarshal may be
+// handed the driver's read buffer and must not retain data after returning.
 type Codec interface {
 	Marshal(v any) ([]byte, error)
 	Unmarshal(data []byte, v any) error
@@ -156,3 +156,31 @@ func BenchmarkWhisker_Unmarshal(b *testing.B) {
 		_ = c.Unmarshal(data, &d)
 	}
 }
+
+func BenchmarkWhisker_AppendMarshal_Pooled(b *testing.B) {
+	type doc struct {
+		ID      string
+		Name    string
+		Email   string
+		Version int
+	}
+	c := NewWhisker(NewJSONIter())
+	d := doc{ID: "u1", Name: "Alice", Email: "alice@test.com", Version: 3}
+	b.ReportAllocs()
+	for b.Loop() {
+		buf := GetBuffer()
+		*buf, _ = c.AppendMarshal(*buf, d)
+		PutBuffer(buf)
+	}
+}
+
+func BenchmarkWhisker_AppendMarshal_Marshaler(b *testing.B) {
+	c := NewWhisker(NewJSONIter())
+	d := &selfEncoded{ID: "c1", Hits: 42, Version: 3}
+	b.ReportAllocs()
+	for b.Loop() {
+		buf := GetBuffer()
+		*buf, _ = c.AppendMarshal(*buf, d)
+		PutBuffer(buf)
+	}
+}
@@ -20,3 +20,21 @@ func (c *JSONIterCodec) Marshal(v any) ([]byte, error) {
 func (c *JSONIterCodec) Unmarshal(data []byte, v any) error {
 	return json.Unmarshal(data, v)
 }
+
+// AppendMarshal encodes v straight into dst.
+func (c *JSONIterCodec) AppendMarshal(dst []byte, v any) ([]byte, error) {
+	stream := json.BorrowStream(nil)
+	own := stream.Buffer()
+	defer func() {
+		// the pooled stream must not keep the caller's buffer; give it back
+		// its own so later encodes don't allocate a new one
+		stream.SetBuffer(own[:0])
+		json.ReturnStream(stream)
+	}()
+	stream.SetBuffer(dst)
+	stream.WriteVal(v)
+	if stream.Error != nil {
+		return dst, stream.Error
+	}
+	return stream.Buffer(), nil
+}
@@ -1,6 +1,7 @@
 package codecs
 
 import (
+	"bytes"
 	stdjson "encoding/json"
 	"fmt"
 	"reflect"
@@ -9,7 +10,8 @@ import (
 )
 
 // WhiskerCodec wraps another codec and excludes ID and Version fields during
-// marshaling. Only document data fields are serialized to JSONB.
+// marshaling. Only document data fields are serialized to JSONB. Values that
+// are not structs, such as maps, are passed to the inner codec unchanged.
 type WhiskerCodec struct {
 	inner Codec
 }
@@ -20,22 +22,64 @@ func NewWhisker(inner Codec) *WhiskerCodec {
 	return &WhiskerCodec{inner: inner}
 }
 
+// Marshal encodes v into a pooled buffer with AppendMarshal and returns a
+// copy, so the buffer grows once per pool entry rather than on every call. An
+// inner codec that cannot append marshals directly, as copying its output
+// into the buffer would only add an allocation.
 func (c *WhiskerCodec) Marshal(v any) ([]byte, error) {
+	_, self := v.(Marshaler)
+	if _, ok := c.inner.(AppendMarshaler); !ok && !self {
+		out, ok := c.dataFields(v)
+		if !ok {
+			return c.inner.Marshal(v)
+		}
+		return c.inner.Marshal(out)
+	}
+	buf := GetBuffer()
+	defer PutBuffer(buf)
+	data, err := c.AppendMarshal(*buf, v)
+	if err != nil {
+		return nil, err
+	}
+	*buf = data
+	return bytes.Clone(data), nil
+}
+
+// AppendMarshal is Marshal appending to dst, reusing its capacity.
+func (c *WhiskerCodec) AppendMarshal(dst []byte, v any) ([]byte, error) {
+	if m, ok := v.(Marshaler); ok {
+		return m.AppendJSON(dst)
+	}
+	out, ok := c.dataFields(v)
+	if !ok {
+		return AppendMarshal(c.inner, dst, v)
+	}
+	return AppendMarshal(c.inner, dst, out)
+}
+
+// dataFields returns the data fields of a struct by JSON key, reporting false
+// for values that are not structs.
+func (c *WhiskerCodec) dataFields(v any) (map[string]any, bool) {
 	val := reflect.ValueOf(v)
 	if val.Kind() == reflect.Ptr {
 		val = val.Elem()
 	}
+	if val.Kind() != reflect.Struct {
+		return nil, false
+	}
 	m := meta.AnalyzeType(val.Type())
 
 	out := make(map[string]any, len(m.Fields))
 	for _, f := range m.Fields {
 		out[f.JSONKey] = val.Field(f.Index).Interface()
 	}
-
-	return c.inner.Marshal(out)
+	return out, true
 }
 
 func (c *WhiskerCodec) Unmarshal(data []byte, v any) error {
+	if t := reflect.TypeOf(v); t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return c.inner.Unmarshal(data, v)
+	}
 	var raw map[string]stdjson.RawMessage
 	if err := c.inner.Unmarshal(data, &raw); err != nil {
 		return err
@@ -237,3 +237,21 @@ func TestWhiskerCodec_UnmarshalNumericFields(t *testing.T) {
 		t.Errorf("Score = %f, want 3.14", doc.Score)
 	}
 }
+
+func TestWhiskerCodec_MapPassesThrough(t *testing.T) {
+	c := newWhisker()
+	original := map[string]any{"ID": "kept", "status": "paid"}
+
+	data, err := c.Marshal(&original)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var got map[string]any
+	if err := c.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got["status"] != "paid" || got["ID"] != "kept" {
+		t.Errorf("got %v, want map keys unchanged", got)
+	}
+}
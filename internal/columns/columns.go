@@ -0,0 +1,57 @@
+package columns
+
+import (
+	"fmt"
+
+	"github.com/ripkitten-co/whisker/internal/ident"
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+// Expr returns the SQL expression that derives the column value from the
+// JSONB document.
+func Expr(col meta.ColumnMeta) string {
+	if col.References != "" {
+		// empty strings become NULL so unset references don't violate the FK
+		return fmt.Sprintf("(NULLIF(%s, ''))", ident.JSONText(col.FieldJSONKey))
+	}
+	switch col.SQLType {
+	case "TEXT":
+		return fmt.Sprintf("(%s)", ident.JSONText(col.FieldJSONKey))
+	case "TIMESTAMPTZ":
+		// generated columns need an immutable expression, as indexes do
+		return fmt.Sprintf("(%s)", ident.JSONTyped(col.FieldJSONKey, meta.CastTimestamptz))
+	}
+	return fmt.Sprintf("((%s)::%s)", ident.JSONText(col.FieldJSONKey), col.SQLType)
+}
+
+// ColumnDDL returns the ALTER TABLE statement that adds a stored generated
+// column for the given field. Adding a stored column rewrites the table, so
+// this is best done before the collection grows large.
+func ColumnDDL(collection string, col meta.ColumnMeta) string {
+	return fmt.Sprintf(
+		"ALTER TABLE whisker_%s ADD COLUMN IF NOT EXISTS %s %s GENERATED ALWAYS AS %s STORED",
+		collection, col.Name, col.SQLType, Expr(col),
+	)
+}
+
+// ColumnKey returns the cache key used to track creation of a generated column.
+func ColumnKey(collection string, col meta.ColumnMeta) string {
+	return fmt.Sprintf("whisker_%s.%s", collection, col.Name)
+}
+
+// ForeignKeyName returns the constraint name for a referencing column.
+func ForeignKeyName(collection string, col meta.ColumnMeta) string {
+	return fmt.Sprintf("fk_whisker_%s_%s", collection, col.Name)
+}
+
+// ForeignKeyDDL returns an idempotent statement adding a foreign key from the
+// generated column to the referenced collection's id. PostgreSQL has no
+// ADD CONSTRAINT IF NOT EXISTS, so the check runs in a DO block.
+func ForeignKeyDDL(collection string, col meta.ColumnMeta) string {
+	name := ForeignKeyName(collection, col)
+	return fmt.Sprintf(`DO $$ BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = %s) THEN
+		ALTER TABLE whisker_%s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES whisker_%s (id);
+	END IF;
+END $$`, ident.Literal(name), collection, name, col.Name, col.References)
+}
@@ -0,0 +1,56 @@
+package columns
+
+import (
+	"testing"
+
+	"github.com/ripkitten-co/whisker/internal/meta"
+)
+
+func TestColumnDDL_Text(t *testing.T) {
+	got := ColumnDDL("users", meta.ColumnMeta{FieldJSONKey: "email", Name: "email", SQLType: "TEXT"})
+	want := "ALTER TABLE whisker_users ADD COLUMN IF NOT EXISTS email TEXT GENERATED ALWAYS AS (data->>'email') STORED"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestColumnDDL_Cast(t *testing.T) {
+	got := ColumnDDL("orders", meta.ColumnMeta{FieldJSONKey: "itemCount", Name: "item_count", SQLType: "BIGINT"})
+	want := "ALTER TABLE whisker_orders ADD COLUMN IF NOT EXISTS item_count BIGINT GENERATED ALWAYS AS ((data->>'itemCount')::BIGINT) STORED"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestColumnDDL_Timestamptz(t *testing.T) {
+	got := ColumnDDL("tasks", meta.ColumnMeta{FieldJSONKey: "due", Name: "due", SQLType: "TIMESTAMPTZ"})
+	want := "ALTER TABLE whisker_tasks ADD COLUMN IF NOT EXISTS due TIMESTAMPTZ GENERATED ALWAYS AS (whisker_timestamptz(data->>'due')) STORED"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestColumnKey(t *testing.T) {
+	got := ColumnKey("users", meta.ColumnMeta{Name: "email"})
+	if got != "whisker_users.email" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestForeignKeyDDL(t *testing.T) {
+	col := meta.ColumnMeta{FieldJSONKey: "userId", Name: "user_id", SQLType: "TEXT", References: "users"}
+
+	if got := Expr(col); got != "(NULLIF(data->>'userId', ''))" {
+		t.Errorf("Expr = %s", got)
+	}
+
+	got := ForeignKeyDDL("orders", col)
+	want := `DO $$ BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_whisker_orders_user_id') THEN
+		ALTER TABLE whisker_orders ADD CONSTRAINT fk_whisker_orders_user_id FOREIGN KEY (user_id) REFERENCES whisker_users (id);
+	END IF;
+END $$`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
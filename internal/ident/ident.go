@@ -0,0 +1,101 @@
+// Package ident validates and quotes the names and JSON paths that Whisker
+// interpolates into SQL. Every place that assembles SQL from a collection
+// name, field name or JSON key goes through this package, so there is one
+// definition of what is safe.
+package ident
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	collectionName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,54}$`)
+	jsonPath       = regexp.MustCompile(`^data(?:->>?(?:'(?:[^'\x00]|'')*'|[0-9]+))+$`)
+)
+
+// ValidateCollection checks that name is a valid collection identifier
+// (alphanumeric + underscores, max 55 characters, starts with a letter). The
+// limit leaves room for the whisker_ prefix and derived suffixes within
+// PostgreSQL's 63-byte identifier limit.
+func ValidateCollection(name string) error {
+	if !collectionName.MatchString(name) {
+		return fmt.Errorf("invalid collection name %q: must be alphanumeric with underscores, max 55 chars", name)
+	}
+	return nil
+}
+
+// IsField reports whether s is a plain document field name: one or more ASCII
+// letters, digits or underscores.
+func IsField(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsIdentifier reports whether s can be used unquoted as a SQL identifier:
+// lower-case ASCII letters, digits and underscores, starting with a letter,
+// at most 63 bytes.
+func IsIdentifier(s string) bool {
+	if s == "" || len(s) > 63 || s[0] < 'a' || s[0] > 'z' {
+		return false
+	}
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateJSONPath checks that expr is a raw JSONB path expression on the
+// data column, such as data->'addr'->>'city' or data->'tags'->>0. Keys must be
+// single-quoted literals with embedded quotes doubled.
+func ValidateJSONPath(expr string) error {
+	if !jsonPath.MatchString(expr) {
+		return fmt.Errorf("invalid JSON path %q: must be data followed by ->'key', ->>'key' or ->N steps", expr)
+	}
+	return nil
+}
+
+// Literal quotes s as a SQL string literal, doubling embedded single quotes.
+// NUL bytes, which PostgreSQL text cannot hold, are dropped.
+func Literal(s string) string {
+	s = strings.ReplaceAll(s, "\x00", "")
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// QuoteIdent quotes s as a SQL identifier, doubling embedded double quotes.
+// NUL bytes are dropped.
+func QuoteIdent(s string) string {
+	s = strings.ReplaceAll(s, "\x00", "")
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// JSONText returns the expression extracting key from the data column as
+// text.
+func JSONText(key string) string {
+	return "data->>" + Literal(key)
+}
+
+// JSONTyped returns the expression extracting key from the data column as
+// the given cast type, numeric or timestamptz, and as text for any other.
+// Times go through whisker_timestamptz, an IMMUTABLE wrapper of the cast,
+// since index expressions must be immutable and the cast itself depends on
+// the session time zone.
+func JSONTyped(key, cast string) string {
+	switch cast {
+	case "numeric":
+		return "(" + JSONText(key) + ")::numeric"
+	case "timestamptz":
+		return "whisker_timestamptz(" + JSONText(key) + ")"
+	}
+	return JSONText(key)
+}
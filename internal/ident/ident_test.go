@@ -0,0 +1,191 @@
+package ident
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCollection(t *testing.T) {
+	valid := []string{"users", "Order_items", "a", "a" + strings.Repeat("b", 54)}
+	invalid := []string{"", "1users", "_users", "users;drop", "user-s", "a" + strings.Repeat("b", 55), "naïve"}
+	for _, name := range valid {
+		if err := ValidateCollection(name); err != nil {
+			t.Errorf("ValidateCollection(%q): unexpected error %v", name, err)
+		}
+	}
+	for _, name := range invalid {
+		if err := ValidateCollection(name); err == nil {
+			t.Errorf("ValidateCollection(%q): expected error", name)
+		}
+	}
+}
+
+func TestIsField(t *testing.T) {
+	for s, want := range map[string]bool{
+		"name": true, "firstName": true, "2fa": true, "a_b": true,
+		"": false, "a b": false, "a'b": false, "a-b": false, "a.b": false,
+	} {
+		if got := IsField(s); got != want {
+			t.Errorf("IsField(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestIsIdentifier(t *testing.T) {
+	for s, want := range map[string]bool{
+		"email": true, "first_name": true, "a1": true,
+		"": false, "Email": false, "1a": false, "_a": false, "a-b": false, strings.Repeat("a", 64): false,
+	} {
+		if got := IsIdentifier(s); got != want {
+			t.Errorf("IsIdentifier(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestValidateJSONPath(t *testing.T) {
+	valid := []string{"data->'a'", "data->>'a'", "data->'addr'->>'city'", "data->'tags'->>0", "data->>'it''s'", "data->>'a b'"}
+	invalid := []string{
+		"", "data", "data->", "data->>a", "data->'a", "data->'a'; DROP TABLE x",
+		"data->>'a' OR 1=1", "version->'a'", "data->'a'->>'b' --", "data->>'it's'",
+	}
+	for _, expr := range valid {
+		if err := ValidateJSONPath(expr); err != nil {
+			t.Errorf("ValidateJSONPath(%q): unexpected error %v", expr, err)
+		}
+	}
+	for _, expr := range invalid {
+		if err := ValidateJSONPath(expr); err == nil {
+			t.Errorf("ValidateJSONPath(%q): expected error", expr)
+		}
+	}
+}
+
+func TestQuoting(t *testing.T) {
+	if got := Literal("it's"); got != "'it''s'" {
+		t.Errorf("Literal: got %s", got)
+	}
+	if got := QuoteIdent(`we"ird`); got != `"we""ird"` {
+		t.Errorf("QuoteIdent: got %s", got)
+	}
+	if got := JSONText("name"); got != "data->>'name'" {
+		t.Errorf("JSONText: got %s", got)
+	}
+	for cast, want := range map[string]string{
+		"":            "data->>'age'",
+		"numeric":     "(data->>'age')::numeric",
+		"timestamptz": "whisker_timestamptz(data->>'age')",
+	} {
+		if got := JSONTyped("age", cast); got != want {
+			t.Errorf("JSONTyped(%q): got %s, want %s", cast, got, want)
+		}
+	}
+}
+
+// unquote reverses quoting with the given delimiter and reports whether s was
+// a single well-formed quoted token.
+func unquote(s string, q byte) (string, bool) {
+	if len(s) < 2 || s[0] != q || s[len(s)-1] != q {
+		return "", false
+	}
+	inner := s[1 : len(s)-1]
+	var b strings.Builder
+	for i := 0; i < len(inner); i++ {
+		if inner[i] == q {
+			if i+1 >= len(inner) || inner[i+1] != q {
+				return "", false
+			}
+			i++
+		}
+		b.WriteByte(inner[i])
+	}
+	return b.String(), true
+}
+
+func FuzzLiteral(f *testing.F) {
+	for _, seed := range []string{"", "a", "it's", "''", "'; DROP TABLE x; --", "a\x00b"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		got, ok := unquote(Literal(s), '\'')
+		if !ok {
+			t.Fatalf("Literal(%q) = %q is not a single literal", s, Literal(s))
+		}
+		if want := strings.ReplaceAll(s, "\x00", ""); got != want {
+			t.Fatalf("Literal(%q) round-trips to %q", s, got)
+		}
+	})
+}
+
+func FuzzQuoteIdent(f *testing.F) {
+	for _, seed := range []string{"", "a", `we"ird`, `""`, `x"; DROP TABLE y; --`} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if _, ok := unquote(QuoteIdent(s), '"'); !ok {
+			t.Fatalf("QuoteIdent(%q) = %q is not a single identifier", s, QuoteIdent(s))
+		}
+	})
+}
+
+func FuzzValidateJSONPath(f *testing.F) {
+	for _, seed := range []string{"data->'a'", "data->>'it''s'", "data->'a'; DROP", "data->0->>'b'"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, expr string) {
+		if ValidateJSONPath(expr) != nil {
+			return
+		}
+		// every step must be an arrow followed by a whole literal or index
+		rest := strings.TrimPrefix(expr, "data")
+		for rest != "" {
+			switch {
+			case strings.HasPrefix(rest, "->>"):
+				rest = rest[3:]
+			case strings.HasPrefix(rest, "->"):
+				rest = rest[2:]
+			default:
+				t.Fatalf("accepted %q with unexpected token at %q", expr, rest)
+			}
+			if rest != "" && rest[0] == '\'' {
+				end := 1
+				for end < len(rest) {
+					if rest[end] == '\'' {
+						if end+1 < len(rest) && rest[end+1] == '\'' {
+							end += 2
+							continue
+						}
+						break
+					}
+					end++
+				}
+				if end >= len(rest) {
+					t.Fatalf("accepted %q with unterminated literal", expr)
+				}
+				rest = rest[end+1:]
+				continue
+			}
+			n := 0
+			for n < len(rest) && rest[n] >= '0' && rest[n] <= '9' {
+				n++
+			}
+			if n == 0 {
+				t.Fatalf("accepted %q with empty step", expr)
+			}
+			rest = rest[n:]
+		}
+	})
+}
+
+func FuzzValidateCollection(f *testing.F) {
+	for _, seed := range []string{"users", "a;b", "1x", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		if ValidateCollection(name) != nil {
+			return
+		}
+		if !IsField(name) || len(name) > 55 {
+			t.Fatalf("accepted unsafe collection name %q", name)
+		}
+	})
+}
@@ -2,17 +2,97 @@ package indexes
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/ripkitten-co/whisker/internal/ident"
 	"github.com/ripkitten-co/whisker/internal/meta"
 )
 
-func btreeDDL(collection, field string) string {
+func btreeDDL(collection string, idx meta.IndexMeta) string {
 	return fmt.Sprintf(
-		"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_%s_%s ON whisker_%s ((data->>'%s'))",
-		collection, field, collection, field,
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON whisker_%s ((%s))",
+		IndexName(collection, idx), collection, ident.JSONTyped(idx.FieldJSONKey, idx.Cast),
 	)
 }
 
+func columnDDL(collection, field, column string) string {
+	return fmt.Sprintf(
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_%s_%s ON whisker_%s (%s)",
+		collection, field, collection, column,
+	)
+}
+
+func lowerDDL(collection string, idx meta.IndexMeta) string {
+	expr := ident.JSONText(idx.FieldJSONKey)
+	if idx.Column != "" {
+		expr = idx.Column
+	}
+	return fmt.Sprintf(
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON whisker_%s ((lower(%s)))",
+		IndexName(collection, idx), collection, expr,
+	)
+}
+
+func ftsDDL(collection string, idx meta.IndexMeta) string {
+	expr := ident.JSONText(idx.FieldJSONKey)
+	if idx.Column != "" {
+		expr = idx.Column
+	}
+	return fmt.Sprintf(
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON whisker_%s USING GIN ((%s))",
+		IndexName(collection, idx), collection, TextSearchVector(idx.TextSearchConfig, expr),
+	)
+}
+
+// TextSearchVector returns the to_tsvector expression full-text indexes are
+// built on, which queries must repeat exactly for the index to apply.
+func TextSearchVector(config, expr string) string {
+	return fmt.Sprintf("to_tsvector('%s', %s)", config, expr)
+}
+
+func trgmDDL(collection string, idx meta.IndexMeta) string {
+	expr := ident.JSONText(idx.FieldJSONKey)
+	if idx.Column != "" {
+		expr = idx.Column
+	}
+	return fmt.Sprintf(
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON whisker_%s USING GIN ((%s) gin_trgm_ops)",
+		IndexName(collection, idx), collection, expr,
+	)
+}
+
+// Extensions returns the PostgreSQL extensions the given indexes need, to
+// create before their DDL runs.
+func Extensions(indexes []meta.IndexMeta) []string {
+	for _, idx := range indexes {
+		if idx.Type == meta.IndexTrgm {
+			return []string{"pg_trgm"}
+		}
+	}
+	return nil
+}
+
+// compositeDDL indexes the group's keys in order, each by its generated
+// column when it has one, restricted to the rows matching idx.Where when set.
+func compositeDDL(collection string, idx meta.IndexMeta) string {
+	exprs := make([]string, len(idx.Keys))
+	for i, k := range idx.Keys {
+		if k.Column != "" {
+			exprs[i] = k.Column
+			continue
+		}
+		exprs[i] = "(" + ident.JSONTyped(k.FieldJSONKey, k.Cast) + ")"
+	}
+	ddl := fmt.Sprintf(
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON whisker_%s (%s)",
+		IndexName(collection, idx), collection, strings.Join(exprs, ", "),
+	)
+	if idx.Where != "" {
+		ddl += " WHERE " + idx.Where
+	}
+	return ddl
+}
+
 func ginDDL(collection string) string {
 	return fmt.Sprintf(
 		"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_%s_data_gin ON whisker_%s USING GIN (data)",
@@ -25,9 +105,38 @@ func IndexName(collection string, idx meta.IndexMeta) string {
 	if idx.Type == meta.IndexGIN {
 		return fmt.Sprintf("idx_whisker_%s_data_gin", collection)
 	}
+	if idx.Type == meta.IndexFTS {
+		return fmt.Sprintf("idx_whisker_%s_%s_fts", collection, idx.FieldJSONKey)
+	}
+	if idx.Type == meta.IndexTrgm {
+		return fmt.Sprintf("idx_whisker_%s_%s_trgm", collection, idx.FieldJSONKey)
+	}
+	if idx.Group != "" && idx.Where != "" {
+		return fmt.Sprintf("idx_whisker_%s_%s_partial", collection, idx.Group)
+	}
+	if idx.Group != "" {
+		return fmt.Sprintf("idx_whisker_%s_%s_group", collection, idx.Group)
+	}
+	if idx.CaseInsensitive {
+		return fmt.Sprintf("idx_whisker_%s_%s_ci", collection, idx.FieldJSONKey)
+	}
+	if idx.Cast != "" {
+		// distinct from the text index of the same field made before casts
+		return fmt.Sprintf("idx_whisker_%s_%s_%s", collection, idx.FieldJSONKey, idx.Cast)
+	}
 	return fmt.Sprintf("idx_whisker_%s_%s", collection, idx.FieldJSONKey)
 }
 
+// Superseded returns the names of the indexes idx replaces: for a numeric
+// or time field, the text index of the same field that earlier versions
+// built before fields were indexed by their typed value.
+func Superseded(collection string, idx meta.IndexMeta) []string {
+	if idx.Type != meta.IndexBtree || idx.Group != "" || idx.CaseInsensitive || idx.Column != "" || idx.Cast == "" {
+		return nil
+	}
+	return []string{fmt.Sprintf("idx_whisker_%s_%s", collection, idx.FieldJSONKey)}
+}
+
 // IndexDDLs returns CREATE INDEX CONCURRENTLY DDL statements for the given
 // collection and index definitions.
 func IndexDDLs(collection string, indexes []meta.IndexMeta) []string {
@@ -38,9 +147,25 @@ func IndexDDLs(collection string, indexes []meta.IndexMeta) []string {
 	for _, idx := range indexes {
 		switch idx.Type {
 		case meta.IndexBtree:
-			ddls = append(ddls, btreeDDL(collection, idx.FieldJSONKey))
+			if idx.Group != "" {
+				ddls = append(ddls, compositeDDL(collection, idx))
+				continue
+			}
+			if idx.CaseInsensitive {
+				ddls = append(ddls, lowerDDL(collection, idx))
+				continue
+			}
+			if idx.Column != "" {
+				ddls = append(ddls, columnDDL(collection, idx.FieldJSONKey, idx.Column))
+				continue
+			}
+			ddls = append(ddls, btreeDDL(collection, idx))
 		case meta.IndexGIN:
 			ddls = append(ddls, ginDDL(collection))
+		case meta.IndexFTS:
+			ddls = append(ddls, ftsDDL(collection, idx))
+		case meta.IndexTrgm:
+			ddls = append(ddls, trgmDDL(collection, idx))
 		}
 	}
 	return ddls
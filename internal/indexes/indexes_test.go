@@ -1,19 +1,74 @@
 package indexes
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/ripkitten-co/whisker/internal/meta"
 )
 
 func TestBtreeDDL(t *testing.T) {
-	got := btreeDDL("users", "name")
+	got := btreeDDL("users", meta.IndexMeta{FieldJSONKey: "name"})
 	want := `CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_users_name ON whisker_users ((data->>'name'))`
 	if got != want {
 		t.Errorf("got:\n%s\nwant:\n%s", got, want)
 	}
 }
 
+func TestIndexDDLs_GeneratedColumn(t *testing.T) {
+	ddls := IndexDDLs("users", []meta.IndexMeta{{FieldJSONKey: "email", Type: meta.IndexBtree, Column: "email"}})
+	want := `CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_users_email ON whisker_users (email)`
+	if len(ddls) != 1 || ddls[0] != want {
+		t.Errorf("got %v, want [%s]", ddls, want)
+	}
+}
+
+func TestIndexDDLs_FullText(t *testing.T) {
+	ddls := IndexDDLs("posts", []meta.IndexMeta{
+		{FieldJSONKey: "body", Type: meta.IndexFTS, TextSearchConfig: "simple"},
+		{FieldJSONKey: "title", Type: meta.IndexFTS, Column: "title", TextSearchConfig: "english"},
+	})
+	want := []string{
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_posts_body_fts ON whisker_posts USING GIN ((to_tsvector('simple', data->>'body')))`,
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_posts_title_fts ON whisker_posts USING GIN ((to_tsvector('english', title)))`,
+	}
+	if len(ddls) != len(want) {
+		t.Fatalf("got %v, want %v", ddls, want)
+	}
+	for i := range want {
+		if ddls[i] != want[i] {
+			t.Errorf("ddls[%d]:\n got: %s\nwant: %s", i, ddls[i], want[i])
+		}
+	}
+}
+
+func TestIndexDDLs_Trigram(t *testing.T) {
+	idxs := []meta.IndexMeta{
+		{FieldJSONKey: "name", Type: meta.IndexTrgm},
+		{FieldJSONKey: "email", Type: meta.IndexTrgm, Column: "email"},
+	}
+	ddls := IndexDDLs("users", idxs)
+	want := []string{
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_users_name_trgm ON whisker_users USING GIN ((data->>'name') gin_trgm_ops)`,
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_users_email_trgm ON whisker_users USING GIN ((email) gin_trgm_ops)`,
+	}
+	if len(ddls) != len(want) {
+		t.Fatalf("got %v, want %v", ddls, want)
+	}
+	for i := range want {
+		if ddls[i] != want[i] {
+			t.Errorf("ddls[%d]:\n got: %s\nwant: %s", i, ddls[i], want[i])
+		}
+	}
+
+	if ext := Extensions(idxs); len(ext) != 1 || ext[0] != "pg_trgm" {
+		t.Errorf("Extensions = %v, want [pg_trgm]", ext)
+	}
+	if ext := Extensions([]meta.IndexMeta{{FieldJSONKey: "name"}}); ext != nil {
+		t.Errorf("Extensions = %v, want none", ext)
+	}
+}
+
 func TestGINDDL(t *testing.T) {
 	got := ginDDL("users")
 	want := `CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_users_data_gin ON whisker_users USING GIN (data)`
@@ -66,3 +121,91 @@ func TestIndexName_GIN(t *testing.T) {
 		t.Errorf("got %q", got)
 	}
 }
+
+func TestIndexDDLs_CaseInsensitive(t *testing.T) {
+	ddls := IndexDDLs("users", []meta.IndexMeta{
+		{FieldJSONKey: "email", Type: meta.IndexBtree, CaseInsensitive: true},
+		{FieldJSONKey: "handle", Type: meta.IndexBtree, Column: "handle", CaseInsensitive: true},
+	})
+	want := []string{
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_users_email_ci ON whisker_users ((lower(data->>'email')))`,
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_users_handle_ci ON whisker_users ((lower(handle)))`,
+	}
+	if len(ddls) != len(want) {
+		t.Fatalf("len(ddls) = %d, want %d", len(ddls), len(want))
+	}
+	for i := range want {
+		if ddls[i] != want[i] {
+			t.Errorf("ddls[%d]:\n got: %s\nwant: %s", i, ddls[i], want[i])
+		}
+	}
+}
+
+func TestIndexDDLs_Composite(t *testing.T) {
+	ddls := IndexDDLs("orders", []meta.IndexMeta{{
+		Type:  meta.IndexBtree,
+		Group: "tenant_status",
+		Keys:  []meta.IndexKey{{FieldJSONKey: "tenantId", Column: "tenant_id"}, {FieldJSONKey: "status"}},
+	}})
+	want := `CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_orders_tenant_status_group ON whisker_orders (tenant_id, (data->>'status'))`
+	if len(ddls) != 1 || ddls[0] != want {
+		t.Errorf("got %v, want [%s]", ddls, want)
+	}
+}
+
+func TestIndexDDLs_Typed(t *testing.T) {
+	ddls := IndexDDLs("orders", []meta.IndexMeta{
+		{FieldJSONKey: "total", Type: meta.IndexBtree, Cast: meta.CastNumeric},
+		{FieldJSONKey: "placedAt", Type: meta.IndexBtree, Cast: meta.CastTimestamptz},
+		{Type: meta.IndexBtree, Group: "by_day", Keys: []meta.IndexKey{
+			{FieldJSONKey: "tenantId"},
+			{FieldJSONKey: "placedAt", Cast: meta.CastTimestamptz},
+		}},
+	})
+	want := []string{
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_orders_total_numeric ON whisker_orders (((data->>'total')::numeric))`,
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_orders_placedAt_timestamptz ON whisker_orders ((whisker_timestamptz(data->>'placedAt')))`,
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_orders_by_day_group ON whisker_orders ((data->>'tenantId'), (whisker_timestamptz(data->>'placedAt')))`,
+	}
+	if len(ddls) != len(want) {
+		t.Fatalf("got %d DDLs, want %d", len(ddls), len(want))
+	}
+	for i := range want {
+		if ddls[i] != want[i] {
+			t.Errorf("got:\n%s\nwant:\n%s", ddls[i], want[i])
+		}
+	}
+}
+
+func TestSuperseded(t *testing.T) {
+	for _, tt := range []struct {
+		idx  meta.IndexMeta
+		want []string
+	}{
+		{meta.IndexMeta{FieldJSONKey: "total", Type: meta.IndexBtree, Cast: meta.CastNumeric}, []string{"idx_whisker_orders_total"}},
+		{meta.IndexMeta{FieldJSONKey: "placedAt", Type: meta.IndexBtree, Cast: meta.CastTimestamptz}, []string{"idx_whisker_orders_placedAt"}},
+		{meta.IndexMeta{FieldJSONKey: "name", Type: meta.IndexBtree}, nil},
+		{meta.IndexMeta{FieldJSONKey: "total", Type: meta.IndexBtree, Column: "total"}, nil},
+		{meta.IndexMeta{Type: meta.IndexBtree, Group: "by_day", Keys: []meta.IndexKey{{FieldJSONKey: "placedAt", Cast: meta.CastTimestamptz}}}, nil},
+	} {
+		if got := Superseded("orders", tt.idx); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Superseded(%+v) = %v, want %v", tt.idx, got, tt.want)
+		}
+	}
+}
+
+func TestIndexDDLs_Partial(t *testing.T) {
+	idx := meta.IndexMeta{
+		Type:  meta.IndexBtree,
+		Group: "open_by_tenant",
+		Keys:  []meta.IndexKey{{FieldJSONKey: "tenantId"}},
+		Where: "data->>'status' = 'open'",
+	}
+	if got := IndexName("orders", idx); got != "idx_whisker_orders_open_by_tenant_partial" {
+		t.Errorf("name = %q", got)
+	}
+	want := `CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_orders_open_by_tenant_partial ON whisker_orders ((data->>'tenantId')) WHERE data->>'status' = 'open'`
+	if ddls := IndexDDLs("orders", []meta.IndexMeta{idx}); len(ddls) != 1 || ddls[0] != want {
+		t.Errorf("got %v, want [%s]", ddls, want)
+	}
+}
@@ -0,0 +1,139 @@
+package meta
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// fieldAccess locates the ID or Version field within a struct, so it can be
+// read and written through a pointer to the document without reflect.Value.
+// fast is false when the field is missing or of a kind that needs reflection.
+type fieldAccess struct {
+	offset uintptr
+	kind   reflect.Kind
+	fast   bool
+}
+
+func compileAccess(t reflect.Type, index int, kinds ...reflect.Kind) fieldAccess {
+	if index == -1 {
+		return fieldAccess{}
+	}
+	f := t.Field(index)
+	for _, k := range kinds {
+		if f.Type.Kind() == k {
+			return fieldAccess{offset: f.Offset, kind: k, fast: true}
+		}
+	}
+	return fieldAccess{}
+}
+
+var versionKinds = []reflect.Kind{reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8}
+
+func (a fieldAccess) int(p unsafe.Pointer) int {
+	p = unsafe.Add(p, a.offset)
+	switch a.kind {
+	case reflect.Int64:
+		return int(*(*int64)(p))
+	case reflect.Int32:
+		return int(*(*int32)(p))
+	case reflect.Int16:
+		return int(*(*int16)(p))
+	case reflect.Int8:
+		return int(*(*int8)(p))
+	}
+	return *(*int)(p)
+}
+
+func (a fieldAccess) setInt(p unsafe.Pointer, v int) {
+	p = unsafe.Add(p, a.offset)
+	switch a.kind {
+	case reflect.Int64:
+		*(*int64)(p) = int64(v)
+	case reflect.Int32:
+		*(*int32)(p) = int32(v)
+	case reflect.Int16:
+		*(*int16)(p) = int16(v)
+	case reflect.Int8:
+		*(*int8)(p) = int8(v)
+	default:
+		*(*int)(p) = v
+	}
+}
+
+// Accessor reads and writes the ID and Version fields of *T documents
+// through field offsets computed once, skipping the per-call type lookup and
+// reflect.Value handling of ExtractID and friends. Keep one per collection;
+// the zero Accessor works too, looking the metadata up on each call.
+type Accessor[T any] struct {
+	m *StructMeta
+}
+
+// AccessorOf returns the Accessor for documents of type T.
+func AccessorOf[T any]() Accessor[T] {
+	return Accessor[T]{m: Analyze[T]()}
+}
+
+func (a Accessor[T]) meta() *StructMeta {
+	if a.m == nil {
+		return Analyze[T]()
+	}
+	return a.m
+}
+
+// ID is ExtractID for *T.
+func (a Accessor[T]) ID(doc *T) (string, error) {
+	m := a.meta()
+	if m.id.fast && doc != nil {
+		return *(*string)(unsafe.Add(unsafe.Pointer(doc), m.id.offset)), nil
+	}
+	return ExtractID(doc)
+}
+
+// Version is ExtractVersion for *T.
+func (a Accessor[T]) Version(doc *T) (int, bool) {
+	m := a.meta()
+	if m.version.fast && doc != nil {
+		return m.version.int(unsafe.Pointer(doc)), true
+	}
+	return ExtractVersion(doc)
+}
+
+// SetID is SetID for *T.
+func (a Accessor[T]) SetID(doc *T, id string) {
+	m := a.meta()
+	if m.id.fast && doc != nil {
+		*(*string)(unsafe.Add(unsafe.Pointer(doc), m.id.offset)) = id
+		return
+	}
+	SetID(doc, id)
+}
+
+// SetVersion is SetVersion for *T.
+func (a Accessor[T]) SetVersion(doc *T, version int) {
+	m := a.meta()
+	if m.version.fast && doc != nil {
+		m.version.setInt(unsafe.Pointer(doc), version)
+		return
+	}
+	SetVersion(doc, version)
+}
+
+// structPointer returns the struct a document pointer points at, and its
+// metadata, or false for anything but a non-nil pointer to a struct.
+func structPointer(doc any) (unsafe.Pointer, *StructMeta, bool) {
+	t := reflect.TypeOf(doc)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, nil, false
+	}
+	p := reflect.ValueOf(doc).UnsafePointer()
+	if p == nil {
+		return nil, nil, false
+	}
+	return p, AnalyzeType(t.Elem()), true
+}
+
+// missingID reports a document type without an ID field.
+func missingID(t reflect.Type) error {
+	return fmt.Errorf("whisker: no ID field in %s", t.Name())
+}
@@ -1,11 +1,19 @@
 package meta
 
 import (
+	"cmp"
 	"fmt"
+	"math"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
+	"unsafe"
+
+	"github.com/ripkitten-co/whisker/internal/ident"
 )
 
 // StructMeta holds reflection metadata for a document struct: which fields
@@ -15,26 +23,102 @@ type StructMeta struct {
 	VersionIndex int
 	Fields       []FieldMeta
 	Indexes      []IndexMeta
+	Columns      []ColumnMeta
+
+	// casts maps the JSON keys of data fields with a Cast to it.
+	casts map[string]string
+	// integers holds the JSON keys of data fields of an integer type.
+	integers map[string]bool
+	// encrypted holds the JSON keys of data fields tagged whisker:"encrypt".
+	encrypted map[string]bool
+	// misplacedEncrypt holds the paths of the fields tagged whisker:"encrypt"
+	// that are not top-level data fields.
+	misplacedEncrypt []string
+	// unpromotable holds the paths of the fields tagged whisker:"column" or
+	// fk whose column name is reserved or not an identifier.
+	unpromotable []string
+
+	// id and version locate the ID and Version fields for fast access.
+	id      fieldAccess
+	version fieldAccess
 }
 
-// FieldMeta describes a single data field in a document struct.
+// FieldMeta describes a single data field in a document struct. Cast is the
+// SQL type the field is compared and indexed as, empty for text. Encrypted
+// fields, tagged whisker:"encrypt", are stored as ciphertext and have no Cast.
 type FieldMeta struct {
-	Index   int
-	JSONKey string
+	Index     int
+	JSONKey   string
+	Cast      string
+	Encrypted bool
 }
 
-// IndexType distinguishes B-tree and GIN index strategies.
+// Casts of data fields that compare by value rather than as text: numbers,
+// and times, which encoding/json writes as RFC 3339 strings.
+const (
+	CastNumeric     = "numeric"
+	CastTimestamptz = "timestamptz"
+)
+
+// IndexType distinguishes B-tree, GIN, full-text and trigram index
+// strategies.
 type IndexType int
 
 const (
 	IndexBtree IndexType = iota
 	IndexGIN
+	IndexFTS
+	IndexTrgm
 )
 
-// IndexMeta describes an index to create on a collection.
+// DefaultTextSearchConfig is the text search configuration of whisker:"index,fts"
+// indexes that do not name one with fts=config.
+const DefaultTextSearchConfig = "simple"
+
+// IndexMeta describes an index to create on a collection. Column is set when
+// the indexed field is promoted to a generated column, in which case the index
+// targets the column instead of the JSONB expression. CaseInsensitive indexes
+// lower(field) to serve case-insensitive lookups. IndexFTS indexes
+// to_tsvector(TextSearchConfig, field) for full-text search. IndexTrgm indexes
+// the field's trigrams for similarity search. A composite B-tree index, from
+// fields tagged whisker:"index(group=name)", has Group set and covers Keys in
+// order instead of a single field. Where, an SQL predicate, makes a composite
+// index partial: only rows matching it are indexed.
 type IndexMeta struct {
+	FieldJSONKey     string
+	Type             IndexType
+	Column           string
+	Cast             string
+	CaseInsensitive  bool
+	TextSearchConfig string
+	Group            string
+	Keys             []IndexKey
+	Where            string
+}
+
+// IndexKey is one field of a composite index.
+type IndexKey struct {
+	FieldJSONKey string
+	Column       string
+	Cast         string
+}
+
+// ColumnMeta describes a JSONB field promoted to a stored generated column via
+// the whisker:"column" tag. References names the collection whose id the
+// column must match when declared with whisker:"fk=collection".
+type ColumnMeta struct {
 	FieldJSONKey string
-	Type         IndexType
+	Name         string
+	SQLType      string
+	References   string
+}
+
+// reservedColumns are the fixed columns of every collection table; generated
+// columns may not shadow them.
+var reservedColumns = map[string]bool{
+	"id": true, "data": true, "version": true,
+	"created_at": true, "updated_at": true, "deleted_at": true,
+	"schema_version": true,
 }
 
 var cache sync.Map
@@ -62,7 +146,10 @@ func analyze(t reflect.Type) *StructMeta {
 	applyWhiskerTags(t, m)
 	applyConventionDefaults(t, m)
 	collectDataFields(t, m)
+	collectColumns(t, m)
 	collectIndexes(t, m)
+	m.id = compileAccess(t, m.IDIndex, reflect.String)
+	m.version = compileAccess(t, m.VersionIndex, versionKinds...)
 	return m
 }
 
@@ -111,22 +198,206 @@ func jsonKeyForField(f reflect.StructField) string {
 
 func collectDataFields(t reflect.Type, m *StructMeta) {
 	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.IsExported() || f.Anonymous {
+			m.misplacedEncrypt = append(m.misplacedEncrypt, nestedEncrypt(f.Type, f.Name, map[reflect.Type]bool{t: true})...)
+		}
 		if i == m.IDIndex || i == m.VersionIndex {
+			if hasEncryptTag(f) {
+				m.misplacedEncrypt = append(m.misplacedEncrypt, f.Name)
+			}
 			continue
 		}
-		f := t.Field(i)
 		if !f.IsExported() {
 			continue
 		}
 		if f.Tag.Get("json") == "-" {
 			continue
 		}
-		m.Fields = append(m.Fields, FieldMeta{Index: i, JSONKey: jsonKeyForField(f)})
+		fm := FieldMeta{Index: i, JSONKey: jsonKeyForField(f), Cast: castFor(f.Type)}
+		if hasEncryptTag(f) {
+			fm.Encrypted, fm.Cast = true, ""
+			if m.encrypted == nil {
+				m.encrypted = make(map[string]bool)
+			}
+			m.encrypted[fm.JSONKey] = true
+		}
+		if fm.Cast != "" {
+			if m.casts == nil {
+				m.casts = make(map[string]string)
+			}
+			m.casts[fm.JSONKey] = fm.Cast
+			if isInteger(f.Type) {
+				if m.integers == nil {
+					m.integers = make(map[string]bool)
+				}
+				m.integers[fm.JSONKey] = true
+			}
+		}
+		m.Fields = append(m.Fields, fm)
+	}
+}
+
+func hasEncryptTag(f reflect.StructField) bool {
+	_, ok := tagOptions(f.Tag.Get("whisker"))["encrypt"]
+	return ok
+}
+
+// nestedEncrypt returns the paths, below path, of the exported fields tagged
+// whisker:"encrypt" in the structs a field of type t holds, directly or
+// through pointers, slices, arrays and maps. Embedded structs count as
+// nested: their fields are not top-level data fields of the document.
+func nestedEncrypt(t reflect.Type, path string, seen map[reflect.Type]bool) []string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return nil
+	}
+	seen[t] = true
+	var paths []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		if hasEncryptTag(f) {
+			paths = append(paths, path+"."+f.Name)
+		}
+		paths = append(paths, nestedEncrypt(f.Type, path+"."+f.Name, seen)...)
+	}
+	return paths
+}
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	jsonMarshaler = reflect.TypeFor[interface{ MarshalJSON() ([]byte, error) }]()
+	textMarshaler = reflect.TypeFor[interface{ MarshalText() ([]byte, error) }]()
+)
+
+// castFor returns the Cast of a field of type t: numeric for integers and
+// floats, timestamptz for time.Time, and none for everything else, including
+// types with their own JSON or text encoding, which need not be a number.
+func castFor(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return CastTimestamptz
+	}
+	if t.Implements(jsonMarshaler) || t.Implements(textMarshaler) ||
+		reflect.PointerTo(t).Implements(jsonMarshaler) || reflect.PointerTo(t).Implements(textMarshaler) {
+		return ""
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return CastNumeric
+	}
+	return ""
+}
+
+func isInteger(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// UsesCast reports whether any data field has the given Cast.
+func (m *StructMeta) UsesCast(cast string) bool {
+	for _, c := range m.casts {
+		if c == cast {
+			return true
+		}
+	}
+	return false
+}
+
+// CastOf returns the Cast of the data field with the given JSON key, empty
+// for text fields and keys that are not top-level data fields.
+func (m *StructMeta) CastOf(jsonKey string) string {
+	return m.casts[jsonKey]
+}
+
+// Integer reports whether the data field with the given JSON key has an
+// integer type, and so a numeric Cast with whole values.
+func (m *StructMeta) Integer(jsonKey string) bool {
+	return m.integers[jsonKey]
+}
+
+// Encrypted reports whether the data field with the given JSON key is tagged
+// whisker:"encrypt".
+func (m *StructMeta) Encrypted(jsonKey string) bool {
+	return m.encrypted[jsonKey]
+}
+
+// MisplacedEncrypt returns the paths of the fields tagged whisker:"encrypt"
+// that cannot be encrypted: the ID and Version fields, and fields of nested or
+// embedded structs. Only top-level data fields are encrypted.
+func (m *StructMeta) MisplacedEncrypt() []string {
+	return m.misplacedEncrypt
+}
+
+// UnpromotableColumns returns the paths of the fields tagged
+// whisker:"column" or fk that cannot be promoted, because their column name
+// is one of the table's own, such as data or version, or is not a valid
+// identifier. Such fields get no column.
+func (m *StructMeta) UnpromotableColumns() []string {
+	return m.unpromotable
+}
+
+// EncryptedKeys returns the JSON keys of the fields tagged whisker:"encrypt",
+// sorted.
+func (m *StructMeta) EncryptedKeys() []string {
+	keys := make([]string, 0, len(m.encrypted))
+	for k := range m.encrypted {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func collectColumns(t reflect.Type, m *StructMeta) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Tag.Get("json") == "-" {
+			continue
+		}
+		opts := tagOptions(f.Tag.Get("whisker"))
+		if _, ok := opts["encrypt"]; ok {
+			// ciphertext is not worth a column
+			continue
+		}
+		_, column := opts["column"]
+		ref := opts["fk"]
+		if !column && ref == "" {
+			continue
+		}
+		key := jsonKeyForField(f)
+		name := toSnakeCase(key)
+		if reservedColumns[name] || !ident.IsIdentifier(name) {
+			m.unpromotable = append(m.unpromotable, f.Name)
+			continue
+		}
+		col := ColumnMeta{FieldJSONKey: key, Name: name, SQLType: sqlTypeFor(f.Type), References: ref}
+		if ref != "" {
+			// ids are TEXT, so the referencing column must be too
+			col.SQLType = "TEXT"
+		}
+		m.Columns = append(m.Columns, col)
 	}
 }
 
 func collectIndexes(t reflect.Type, m *StructMeta) {
 	hasGIN := false
+	groups := make(map[string]*indexGroup)
 	for i := 0; i < t.NumField(); i++ {
 		f := t.Field(i)
 		if !f.IsExported() {
@@ -135,18 +406,251 @@ func collectIndexes(t reflect.Type, m *StructMeta) {
 		if f.Tag.Get("json") == "-" {
 			continue
 		}
-		tag := f.Tag.Get("whisker")
-		switch tag {
-		case "index":
-			key := jsonKeyForField(f)
-			m.Indexes = append(m.Indexes, IndexMeta{FieldJSONKey: key, Type: IndexBtree})
-		case "index,gin":
+		opts := tagOptions(f.Tag.Get("whisker"))
+		if _, ok := opts["index"]; !ok {
+			continue
+		}
+		if _, ok := opts["encrypt"]; ok {
+			// ciphertext is random, so an index could serve no lookup
+			continue
+		}
+		if _, ok := opts["gin"]; ok {
 			if !hasGIN {
 				m.Indexes = append(m.Indexes, IndexMeta{Type: IndexGIN})
 				hasGIN = true
 			}
+			continue
+		}
+		key := jsonKeyForField(f)
+		if !ident.IsField(key) {
+			// the key becomes part of the index name
+			continue
+		}
+		if config, ok := opts["fts"]; ok {
+			if config == "" {
+				config = DefaultTextSearchConfig
+			}
+			if !ident.IsIdentifier(config) {
+				continue
+			}
+			m.Indexes = append(m.Indexes, IndexMeta{
+				FieldJSONKey:     key,
+				Type:             IndexFTS,
+				Column:           m.columnFor(key),
+				TextSearchConfig: config,
+			})
+			continue
+		}
+		if _, ok := opts["trgm"]; ok {
+			m.Indexes = append(m.Indexes, IndexMeta{
+				FieldJSONKey: key,
+				Type:         IndexTrgm,
+				Column:       m.columnFor(key),
+			})
+			continue
+		}
+		single := false
+		for _, args := range optionValues(f.Tag.Get("whisker"), "index") {
+			if args == "" {
+				single = true
+				continue
+			}
+			name, order, ok := parseIndexGroup(args)
+			if !ok {
+				continue
+			}
+			g := groups[name]
+			if g == nil {
+				g = &indexGroup{pos: len(m.Indexes)}
+				groups[name] = g
+				m.Indexes = append(m.Indexes, IndexMeta{Type: IndexBtree, Group: name})
+			}
+			g.members = append(g.members, groupMember{m.IndexKeyFor(key), order})
+		}
+		if !single {
+			continue
+		}
+		_, ci := opts["ci"]
+		idx := IndexMeta{
+			FieldJSONKey:    key,
+			Type:            IndexBtree,
+			Column:          m.columnFor(key),
+			CaseInsensitive: ci,
+		}
+		if idx.Column == "" && !ci {
+			idx.Cast = m.CastOf(key)
+		}
+		m.Indexes = append(m.Indexes, idx)
+	}
+	for _, g := range groups {
+		slices.SortStableFunc(g.members, func(a, b groupMember) int { return cmp.Compare(a.order, b.order) })
+		keys := make([]IndexKey, len(g.members))
+		for i, mem := range g.members {
+			keys[i] = mem.key
+		}
+		m.Indexes[g.pos].Keys = keys
+	}
+}
+
+// indexGroup collects the members of a composite index while fields are
+// scanned; pos is the index's place in StructMeta.Indexes.
+type indexGroup struct {
+	pos     int
+	members []groupMember
+}
+
+type groupMember struct {
+	key   IndexKey
+	order int
+}
+
+// parseIndexGroup reads the arguments of whisker:"index(group=name)" or
+// "index(group=name,order=n)". Members without an order keep their field
+// order, after those with one.
+func parseIndexGroup(args string) (name string, order int, ok bool) {
+	order = math.MaxInt
+	for _, part := range strings.Split(args, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "group":
+			name = v
+		case "order":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return "", 0, false
+			}
+			order = n
+		default:
+			return "", 0, false
+		}
+	}
+	// the group name becomes part of the index name
+	return name, order, ident.IsField(name)
+}
+
+// IndexKeyFor returns the composite index key of the data field jsonKey: its
+// generated column when it has one, its JSON value with its Cast otherwise.
+func (m *StructMeta) IndexKeyFor(jsonKey string) IndexKey {
+	if col := m.columnFor(jsonKey); col != "" {
+		return IndexKey{FieldJSONKey: jsonKey, Column: col}
+	}
+	return IndexKey{FieldJSONKey: jsonKey, Cast: m.CastOf(jsonKey)}
+}
+
+func (m *StructMeta) columnFor(jsonKey string) string {
+	for _, c := range m.Columns {
+		if c.FieldJSONKey == jsonKey {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+// tagOptions splits a whisker struct tag into its comma-separated options.
+// Options of the form key=value keep their value, and those of the form
+// key(args) their args, which may contain commas; bare options map to "".
+func tagOptions(tag string) map[string]string {
+	opts := make(map[string]string)
+	for tag != "" {
+		var part string
+		part, tag = nextTagOption(tag)
+		part = strings.TrimSpace(part)
+		if name, args, ok := strings.Cut(part, "("); ok && strings.HasSuffix(args, ")") {
+			if name = strings.TrimSpace(name); name != "" {
+				opts[name] = strings.TrimSuffix(args, ")")
+			}
+			continue
+		}
+		k, v, _ := strings.Cut(part, "=")
+		if k != "" {
+			opts[k] = v
+		}
+	}
+	return opts
+}
+
+// optionValues returns the value of every occurrence of the named option in
+// a whisker struct tag, for options that may repeat, such as a field's
+// index(group=...) memberships.
+func optionValues(tag, name string) []string {
+	var values []string
+	for tag != "" {
+		var part string
+		part, tag = nextTagOption(tag)
+		if opts := tagOptions(part); len(opts) == 1 {
+			if v, ok := opts[name]; ok {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// nextTagOption splits the first option off tag at a comma outside
+// parentheses.
+func nextTagOption(tag string) (option, rest string) {
+	depth := 0
+	for i, r := range tag {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth <= 0 {
+				return tag[:i], tag[i+1:]
+			}
 		}
 	}
+	return tag, ""
+}
+
+// sqlTypeFor returns the type of the generated column for a field of type t,
+// typed like its Cast so that a promoted field compares by value too: uint64
+// exceeds BIGINT and is NUMERIC, and time.Time is TIMESTAMPTZ.
+func sqlTypeFor(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return "TIMESTAMPTZ"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return "BIGINT"
+	case reflect.Uint64:
+		return "NUMERIC"
+	case reflect.Float32, reflect.Float64:
+		return "DOUBLE PRECISION"
+	case reflect.Bool:
+		return "BOOLEAN"
+	default:
+		return "TEXT"
+	}
+}
+
+// toSnakeCase converts a camelCase JSON key to the snake_case name used for
+// generated columns (e.g. "firstName" -> "first_name", "userID" -> "user_id").
+func toSnakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	b.Grow(len(s) + 4)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+					b.WriteByte('_')
+				}
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
 }
 
 func jsonKeyFromTag(tag string) string {
@@ -200,9 +704,12 @@ func analyzeValue(doc any) (reflect.Value, *StructMeta) {
 
 // ExtractID reads the ID field value from a document struct.
 func ExtractID(doc any) (string, error) {
+	if p, m, ok := structPointer(doc); ok && m.id.fast {
+		return *(*string)(unsafe.Add(p, m.id.offset)), nil
+	}
 	v, m := analyzeValue(doc)
 	if m.IDIndex == -1 {
-		return "", fmt.Errorf("whisker: no ID field in %s", v.Type().Name())
+		return "", missingID(v.Type())
 	}
 	return fmt.Sprint(v.Field(m.IDIndex).Interface()), nil
 }
@@ -210,6 +717,9 @@ func ExtractID(doc any) (string, error) {
 // ExtractVersion reads the Version field value. Returns (0, false) if the
 // struct has no version field.
 func ExtractVersion(doc any) (int, bool) {
+	if p, m, ok := structPointer(doc); ok && m.version.fast {
+		return m.version.int(p), true
+	}
 	v, m := analyzeValue(doc)
 	if m.VersionIndex == -1 {
 		return 0, false
@@ -219,6 +729,10 @@ func ExtractVersion(doc any) (int, bool) {
 
 // SetVersion writes the version number into the document's Version field.
 func SetVersion(doc any, version int) {
+	if p, m, ok := structPointer(doc); ok && m.version.fast {
+		m.version.setInt(p, version)
+		return
+	}
 	v, m := analyzeValue(doc)
 	if m.VersionIndex == -1 {
 		return
@@ -228,6 +742,10 @@ func SetVersion(doc any, version int) {
 
 // SetID writes the id string into the document's ID field.
 func SetID(doc any, id string) {
+	if p, m, ok := structPointer(doc); ok && m.id.fast {
+		*(*string)(unsafe.Add(p, m.id.offset)) = id
+		return
+	}
 	v, m := analyzeValue(doc)
 	if m.IDIndex == -1 {
 		return
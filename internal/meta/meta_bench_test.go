@@ -65,3 +65,21 @@ func BenchmarkSetID(b *testing.B) {
 		SetID(&doc, "u999")
 	}
 }
+
+func BenchmarkAccessor_ID(b *testing.B) {
+	acc := AccessorOf[benchDoc]()
+	doc := benchDoc{ID: "u1", Name: "Alice", Email: "alice@test.com", Version: 1}
+	b.ReportAllocs()
+	for b.Loop() {
+		_, _ = acc.ID(&doc)
+	}
+}
+
+func BenchmarkAccessor_SetVersion(b *testing.B) {
+	acc := AccessorOf[benchDoc]()
+	doc := benchDoc{ID: "u1", Name: "Alice", Email: "alice@test.com", Version: 1}
+	b.ReportAllocs()
+	for b.Loop() {
+		acc.SetVersion(&doc, 42)
+	}
+}
@@ -1,7 +1,10 @@
 package meta
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
+	"time"
 )
 
 type conventionDoc struct {
@@ -63,6 +66,67 @@ type mixedIndexDoc struct {
 	Version int      `whisker:"version"`
 }
 
+type columnDoc struct {
+	ID        string
+	Email     string `whisker:"column,index"`
+	ItemCount int    `whisker:"column"`
+	Data      string `whisker:"column"`
+	Version   int
+}
+
+type typedColumnDoc struct {
+	ID       string
+	Rank     *int       `whisker:"column"`
+	Score    *float64   `whisker:"column"`
+	Serial   uint64     `whisker:"column"`
+	Due      time.Time  `whisker:"column"`
+	Reminder *time.Time `whisker:"column"`
+	Version  int
+}
+
+type ciIndexDoc struct {
+	ID    string
+	Email string `whisker:"index,ci"`
+}
+
+type ftsDoc struct {
+	ID    string
+	Body  string `whisker:"index,fts"`
+	Title string `whisker:"index,fts=english"`
+	Notes string `whisker:"index,fts=Bad-Config"`
+}
+
+type fkDoc struct {
+	ID     string
+	UserID string `whisker:"fk=users"`
+}
+
+type compositeDoc struct {
+	ID        string
+	Status    string `whisker:"index(group=tenant_status)"`
+	CreatedAt string `whisker:"index(group=tenant_created, order=2)"`
+	TenantID  string `json:"tenantId" whisker:"column,index(group=tenant_status,order=1),index(group=x)"`
+	Region    string `whisker:"index(group=bad-name)"`
+	Email     string `whisker:"index"`
+	Owner     string `whisker:"index(group=tenant_created,order=1)"`
+}
+
+type money int64
+
+func (m money) MarshalJSON() ([]byte, error) { return json.Marshal(float64(m) / 100) }
+
+type typedDoc struct {
+	ID       string
+	Age      int        `whisker:"index"`
+	Score    *float64   `whisker:"index(group=recent),index"`
+	PlacedAt time.Time  `whisker:"index(group=recent,order=1)"`
+	ShipAt   *time.Time `json:"shipAt"`
+	Count    uint32     `whisker:"column,index"`
+	Price    money      `whisker:"index"`
+	Name     string     `whisker:"index"`
+	Version  int
+}
+
 type noIndexDoc struct {
 	ID      string
 	Name    string
@@ -307,3 +371,320 @@ func TestAnalyze_NoIndexes(t *testing.T) {
 		t.Errorf("len(Indexes) = %d, want 0", len(m.Indexes))
 	}
 }
+
+func TestAnalyze_GeneratedColumns(t *testing.T) {
+	m := Analyze[columnDoc]()
+	if len(m.Columns) != 2 {
+		t.Fatalf("len(Columns) = %d, want 2 (reserved name skipped)", len(m.Columns))
+	}
+	if got := m.UnpromotableColumns(); len(got) != 1 || got[0] != "Data" {
+		t.Errorf("UnpromotableColumns() = %v, want [Data]", got)
+	}
+	if c := m.Columns[0]; c.Name != "email" || c.FieldJSONKey != "email" || c.SQLType != "TEXT" {
+		t.Errorf("Columns[0] = %+v", c)
+	}
+	if c := m.Columns[1]; c.Name != "item_count" || c.FieldJSONKey != "itemCount" || c.SQLType != "BIGINT" {
+		t.Errorf("Columns[1] = %+v", c)
+	}
+	if len(m.Indexes) != 1 || m.Indexes[0].Column != "email" {
+		t.Errorf("Indexes = %+v, want btree on column email", m.Indexes)
+	}
+}
+
+func TestAnalyze_GeneratedColumnsTypedLikeTheirCast(t *testing.T) {
+	want := map[string]string{
+		"rank":     "BIGINT",
+		"score":    "DOUBLE PRECISION",
+		"serial":   "NUMERIC",
+		"due":      "TIMESTAMPTZ",
+		"reminder": "TIMESTAMPTZ",
+	}
+	m := Analyze[typedColumnDoc]()
+	if len(m.Columns) != len(want) {
+		t.Fatalf("len(Columns) = %d, want %d", len(m.Columns), len(want))
+	}
+	for _, c := range m.Columns {
+		if c.SQLType != want[c.Name] {
+			t.Errorf("%s: SQLType = %s, want %s", c.Name, c.SQLType, want[c.Name])
+		}
+	}
+}
+
+func TestTagOptions(t *testing.T) {
+	opts := tagOptions("index, gin,fk=users")
+	if _, ok := opts["index"]; !ok {
+		t.Error("missing index option")
+	}
+	if _, ok := opts["gin"]; !ok {
+		t.Error("missing gin option")
+	}
+	if opts["fk"] != "users" {
+		t.Errorf("fk = %q, want users", opts["fk"])
+	}
+}
+
+func TestTagOptions_Args(t *testing.T) {
+	opts := tagOptions("column, index(group=tenant_status,order=1),fk=users")
+	want := map[string]string{"column": "", "index": "group=tenant_status,order=1", "fk": "users"}
+	if !reflect.DeepEqual(opts, want) {
+		t.Errorf("got %v, want %v", opts, want)
+	}
+}
+
+func TestAnalyze_CompositeIndex(t *testing.T) {
+	m := Analyze[compositeDoc]()
+	want := []IndexMeta{
+		{Type: IndexBtree, Group: "tenant_status", Keys: []IndexKey{
+			{FieldJSONKey: "tenantId", Column: "tenant_id"},
+			{FieldJSONKey: "status"},
+		}},
+		{Type: IndexBtree, Group: "tenant_created", Keys: []IndexKey{
+			{FieldJSONKey: "owner"},
+			{FieldJSONKey: "createdAt"},
+		}},
+		{Type: IndexBtree, Group: "x", Keys: []IndexKey{{FieldJSONKey: "tenantId", Column: "tenant_id"}}},
+		{FieldJSONKey: "email", Type: IndexBtree},
+	}
+	if !reflect.DeepEqual(m.Indexes, want) {
+		t.Errorf("got %+v\nwant %+v", m.Indexes, want)
+	}
+}
+
+func TestAnalyze_Casts(t *testing.T) {
+	m := Analyze[typedDoc]()
+	for key, want := range map[string]string{
+		"age":      CastNumeric,
+		"score":    CastNumeric,
+		"placedAt": CastTimestamptz,
+		"shipAt":   CastTimestamptz,
+		"count":    CastNumeric,
+		"price":    "",
+		"name":     "",
+		"version":  "",
+		"missing":  "",
+	} {
+		if got := m.CastOf(key); got != want {
+			t.Errorf("CastOf(%q) = %q, want %q", key, got, want)
+		}
+	}
+	for key, want := range map[string]bool{"age": true, "count": true, "score": false, "placedAt": false, "price": false} {
+		if got := m.Integer(key); got != want {
+			t.Errorf("Integer(%q) = %v, want %v", key, got, want)
+		}
+	}
+
+	want := []IndexMeta{
+		{FieldJSONKey: "age", Type: IndexBtree, Cast: CastNumeric},
+		{Type: IndexBtree, Group: "recent", Keys: []IndexKey{
+			{FieldJSONKey: "placedAt", Cast: CastTimestamptz},
+			{FieldJSONKey: "score", Cast: CastNumeric},
+		}},
+		{FieldJSONKey: "score", Type: IndexBtree, Cast: CastNumeric},
+		{FieldJSONKey: "count", Type: IndexBtree, Column: "count"},
+		{FieldJSONKey: "price", Type: IndexBtree},
+		{FieldJSONKey: "name", Type: IndexBtree},
+	}
+	if !reflect.DeepEqual(m.Indexes, want) {
+		t.Errorf("got %+v\nwant %+v", m.Indexes, want)
+	}
+}
+
+type secretDoc struct {
+	ID        string
+	Name      string    `whisker:"index"`
+	SSN       string    `json:"ssn" whisker:"encrypt,index,column"`
+	BirthDate time.Time `whisker:"encrypt"`
+}
+
+func TestAnalyze_Encrypted(t *testing.T) {
+	m := Analyze[secretDoc]()
+	if got := m.EncryptedKeys(); !reflect.DeepEqual(got, []string{"birthDate", "ssn"}) {
+		t.Errorf("EncryptedKeys() = %v", got)
+	}
+	if !m.Encrypted("ssn") || m.Encrypted("name") {
+		t.Error("Encrypted reports the wrong fields")
+	}
+	if got := m.CastOf("birthDate"); got != "" {
+		t.Errorf("CastOf(birthDate) = %q, want none for ciphertext", got)
+	}
+	if len(m.Columns) != 0 {
+		t.Errorf("columns: got %+v, want none", m.Columns)
+	}
+	if len(m.Indexes) != 1 || m.Indexes[0].FieldJSONKey != "name" {
+		t.Errorf("indexes: got %+v, want only name", m.Indexes)
+	}
+	if got := m.MisplacedEncrypt(); len(got) != 0 {
+		t.Errorf("MisplacedEncrypt() = %v, want none", got)
+	}
+}
+
+type secretAddress struct {
+	Street string `whisker:"encrypt"`
+}
+
+type secretContact struct {
+	Phone string `whisker:"encrypt"`
+}
+
+type nestedSecretDoc struct {
+	ID string `whisker:"encrypt"`
+	secretContact
+	Home     secretAddress
+	Previous []*secretAddress
+	Name     string `whisker:"encrypt"`
+}
+
+func TestAnalyze_MisplacedEncrypt(t *testing.T) {
+	m := Analyze[nestedSecretDoc]()
+	want := []string{"ID", "secretContact.Phone", "Home.Street", "Previous.Street"}
+	if got := m.MisplacedEncrypt(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MisplacedEncrypt() = %v, want %v", got, want)
+	}
+	if got := m.EncryptedKeys(); !reflect.DeepEqual(got, []string{"name"}) {
+		t.Errorf("EncryptedKeys() = %v", got)
+	}
+}
+
+func TestAnalyze_CaseInsensitiveIndex(t *testing.T) {
+	m := Analyze[ciIndexDoc]()
+	if len(m.Indexes) != 1 {
+		t.Fatalf("len(Indexes) = %d, want 1", len(m.Indexes))
+	}
+	if idx := m.Indexes[0]; idx.Type != IndexBtree || idx.FieldJSONKey != "email" || !idx.CaseInsensitive {
+		t.Errorf("Indexes[0] = %+v, want case-insensitive btree on 'email'", idx)
+	}
+}
+
+func TestAnalyze_FullTextIndex(t *testing.T) {
+	m := Analyze[ftsDoc]()
+	want := []IndexMeta{
+		{FieldJSONKey: "body", Type: IndexFTS, TextSearchConfig: "simple"},
+		{FieldJSONKey: "title", Type: IndexFTS, TextSearchConfig: "english"},
+	}
+	if len(m.Indexes) != len(want) {
+		t.Fatalf("got %+v, want %+v", m.Indexes, want)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(m.Indexes[i], want[i]) {
+			t.Errorf("index %d: got %+v, want %+v", i, m.Indexes[i], want[i])
+		}
+	}
+}
+
+type trgmDoc struct {
+	ID    string
+	Name  string `whisker:"index,trgm"`
+	Email string `whisker:"index,trgm,column"`
+}
+
+func TestAnalyze_TrigramIndex(t *testing.T) {
+	m := Analyze[trgmDoc]()
+	want := []IndexMeta{
+		{FieldJSONKey: "name", Type: IndexTrgm},
+		{FieldJSONKey: "email", Type: IndexTrgm, Column: "email"},
+	}
+	if len(m.Indexes) != len(want) {
+		t.Fatalf("got %+v, want %+v", m.Indexes, want)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(m.Indexes[i], want[i]) {
+			t.Errorf("index %d: got %+v, want %+v", i, m.Indexes[i], want[i])
+		}
+	}
+}
+
+func TestAnalyze_ForeignKey(t *testing.T) {
+	m := Analyze[fkDoc]()
+	if len(m.Columns) != 1 {
+		t.Fatalf("len(Columns) = %d, want 1", len(m.Columns))
+	}
+	c := m.Columns[0]
+	if c.Name != "user_id" || c.FieldJSONKey != "userID" || c.SQLType != "TEXT" || c.References != "users" {
+		t.Errorf("Columns[0] = %+v", c)
+	}
+}
+
+func TestToSnakeCase(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"email", "email"},
+		{"firstName", "first_name"},
+		{"userID", "user_id"},
+		{"httpStatusCode", "http_status_code"},
+		{"apiURLPath", "api_url_path"},
+	}
+	for _, tt := range tests {
+		if got := toSnakeCase(tt.in); got != tt.want {
+			t.Errorf("toSnakeCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAccessor(t *testing.T) {
+	acc := AccessorOf[taggedDoc]()
+	doc := &taggedDoc{Key: "k1", Rev: 2}
+	if id, err := acc.ID(doc); err != nil || id != "k1" {
+		t.Errorf("ID: got %q, %v", id, err)
+	}
+	if v, ok := acc.Version(doc); !ok || v != 2 {
+		t.Errorf("Version: got %d, %v", v, ok)
+	}
+	acc.SetID(doc, "k2")
+	acc.SetVersion(doc, 7)
+	if doc.Key != "k2" || doc.Rev != 7 {
+		t.Errorf("got %+v", doc)
+	}
+}
+
+func TestAccessor_SizedVersion(t *testing.T) {
+	type doc64 struct {
+		ID  string
+		Rev int64 `whisker:"version"`
+	}
+	type doc32 struct {
+		ID  string
+		Rev int32 `whisker:"version"`
+	}
+	d64 := &doc64{ID: "a", Rev: 1 << 40}
+	if v, ok := AccessorOf[doc64]().Version(d64); !ok || v != 1<<40 {
+		t.Errorf("int64 version: got %d, %v", v, ok)
+	}
+	SetVersion(d64, 3)
+	if d64.Rev != 3 {
+		t.Errorf("int64 SetVersion: got %d", d64.Rev)
+	}
+	d32 := &doc32{ID: "a", Rev: 4}
+	if v, ok := ExtractVersion(d32); !ok || v != 4 {
+		t.Errorf("int32 version: got %d, %v", v, ok)
+	}
+	AccessorOf[doc32]().SetVersion(d32, 9)
+	if d32.Rev != 9 {
+		t.Errorf("int32 SetVersion: got %d", d32.Rev)
+	}
+}
+
+func TestAccessor_NonStringIDFallsBack(t *testing.T) {
+	type intIDDoc struct {
+		ID      int
+		Version int
+	}
+	doc := &intIDDoc{ID: 42, Version: 1}
+	id, err := AccessorOf[intIDDoc]().ID(doc)
+	if err != nil || id != "42" {
+		t.Errorf("got %q, %v", id, err)
+	}
+	if id, err := ExtractID(*doc); err != nil || id != "42" {
+		t.Errorf("value doc: got %q, %v", id, err)
+	}
+}
+
+func TestAccessor_Zero(t *testing.T) {
+	var acc Accessor[conventionDoc]
+	doc := &conventionDoc{ID: "abc", Version: 1}
+	acc.SetVersion(doc, 5)
+	if v, _ := acc.Version(doc); v != 5 {
+		t.Errorf("got %d, want 5", v)
+	}
+	if _, err := AccessorOf[noIDDoc]().ID(&noIDDoc{}); err == nil {
+		t.Error("expected error for missing ID field")
+	}
+}
@@ -2,7 +2,9 @@ package pg
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -17,14 +19,91 @@ type Executor interface {
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 }
 
+// Timestamp returns the SQL parameter for the current time: the clock's time,
+// or nil when clock is nil. Pair it with NowExpr so a nil parameter falls back
+// to database time.
+func Timestamp(clock interface{ Now() time.Time }) any {
+	if clock == nil {
+		return nil
+	}
+	return clock.Now().UTC()
+}
+
+// NowExpr formats the SQL expression for a timestamp parameter placeholder
+// that falls back to database time when the parameter is NULL.
+func NowExpr(placeholder string) string {
+	return "COALESCE(" + placeholder + "::timestamptz, now())"
+}
+
 // Transactional indicates whether the executor is running inside a transaction.
 type Transactional interface {
 	InTransaction() bool
 }
 
+// Copier is implemented by executors that can bulk load rows with the COPY
+// protocol.
+type Copier interface {
+	CopyFrom(ctx context.Context, table pgx.Identifier, columns []string, rows pgx.CopyFromSource) (int64, error)
+}
+
+// WriteGate is implemented by executors that can refuse writes, for example
+// while the store is quiesced for maintenance.
+type WriteGate interface {
+	CheckWrite(ctx context.Context) error
+}
+
+// CheckWrite asks exec's write gate, if it has one, whether writes may
+// proceed.
+func CheckWrite(ctx context.Context, exec Executor) error {
+	if g, ok := exec.(WriteGate); ok {
+		return g.CheckWrite(ctx)
+	}
+	return nil
+}
+
 // Pool wraps a pgxpool.Pool.
 type Pool struct {
 	pool *pgxpool.Pool
+
+	retries int
+	backoff time.Duration
+}
+
+// SetRetries makes the pool retry statements and transaction begins that
+// fail before reaching the server, such as when no connection can be
+// established, up to retries times. It waits backoff before the first retry
+// and doubles the wait after each one. Statements the server may have seen
+// are never retried.
+func (p *Pool) SetRetries(retries int, backoff time.Duration) {
+	p.retries = retries
+	p.backoff = backoff
+}
+
+// retry runs fn until it succeeds, fails with an error that is not safe to
+// retry, ctx is done or the retries are used up.
+func (p *Pool) retry(ctx context.Context, fn func() error) error {
+	delay := p.backoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.retries || !Retryable(err) {
+			return err
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		delay *= 2
+	}
+}
+
+// Retryable reports whether err is guaranteed to have happened before the
+// statement reached the server, so running it again cannot apply it twice.
+func Retryable(err error) bool {
+	var ce *pgconn.ConnectError
+	return pgconn.SafeToRetry(err) || errors.As(err, &ce)
 }
 
 // NewPool connects to PostgreSQL and returns a connection pool.
@@ -36,24 +115,71 @@ func NewPool(ctx context.Context, connString string) (*Pool, error) {
 	return &Pool{pool: pool}, nil
 }
 
+// NewPoolFromConfig connects to PostgreSQL using a parsed pool configuration.
+func NewPoolFromConfig(ctx context.Context, cfg *pgxpool.Config) (*Pool, error) {
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("pg: connect: %w", err)
+	}
+	return &Pool{pool: pool}, nil
+}
+
 func (p *Pool) Close() {
 	p.pool.Close()
 }
 
-func (p *Pool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
-	return p.pool.Exec(ctx, sql, args...)
+func (p *Pool) Exec(ctx context.Context, sql string, args ...any) (tag pgconn.CommandTag, err error) {
+	err = p.retry(ctx, func() error {
+		tag, err = p.pool.Exec(ctx, sql, args...)
+		return err
+	})
+	return tag, err
 }
 
-func (p *Pool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
-	return p.pool.Query(ctx, sql, args...)
+func (p *Pool) Query(ctx context.Context, sql string, args ...any) (rows pgx.Rows, err error) {
+	err = p.retry(ctx, func() error {
+		rows, err = p.pool.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
 }
 
 func (p *Pool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
-	return p.pool.QueryRow(ctx, sql, args...)
+	if p.retries == 0 {
+		return p.pool.QueryRow(ctx, sql, args...)
+	}
+	return retryRow{p: p, ctx: ctx, sql: sql, args: args}
+}
+
+// retryRow runs its query when scanned, so a failure to connect can be
+// retried.
+type retryRow struct {
+	p    *Pool
+	ctx  context.Context
+	sql  string
+	args []any
+}
+
+func (r retryRow) Scan(dest ...any) error {
+	return r.p.retry(r.ctx, func() error {
+		return r.p.pool.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	})
+}
+
+func (p *Pool) CopyFrom(ctx context.Context, table pgx.Identifier, columns []string, rows pgx.CopyFromSource) (int64, error) {
+	return p.pool.CopyFrom(ctx, table, columns, rows)
 }
 
 func (p *Pool) Begin(ctx context.Context) (pgx.Tx, error) {
-	return p.pool.Begin(ctx)
+	return p.BeginTx(ctx, pgx.TxOptions{})
+}
+
+func (p *Pool) BeginTx(ctx context.Context, opts pgx.TxOptions) (tx pgx.Tx, err error) {
+	err = p.retry(ctx, func() error {
+		tx, err = p.pool.BeginTx(ctx, opts)
+		return err
+	})
+	return tx, err
 }
 
 // PgxPool returns the underlying pgxpool.Pool for use with stdlib adapters.
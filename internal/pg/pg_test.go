@@ -0,0 +1,73 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type safeErr struct{}
+
+func (safeErr) Error() string     { return "not sent" }
+func (safeErr) SafeToRetry() bool { return true }
+
+func TestPool_Retry(t *testing.T) {
+	p := &Pool{}
+	p.SetRetries(2, time.Millisecond)
+
+	calls := 0
+	err := p.retry(context.Background(), func() error {
+		calls++
+		return safeErr{}
+	})
+	if !errors.Is(err, safeErr{}) || calls != 3 {
+		t.Errorf("retryable: got %v after %d calls, want 3 calls", err, calls)
+	}
+
+	calls = 0
+	err = p.retry(context.Background(), func() error {
+		calls++
+		if calls == 1 {
+			return &pgconn.ConnectError{}
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("connect error: got %v after %d calls, want success on the second", err, calls)
+	}
+
+	calls = 0
+	err = p.retry(context.Background(), func() error {
+		calls++
+		return errors.New("sent")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("not retryable: got %v after %d calls, want 1 call", err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p.SetRetries(2, time.Hour)
+	calls = 0
+	_ = p.retry(ctx, func() error {
+		calls++
+		return safeErr{}
+	})
+	if calls != 1 {
+		t.Errorf("cancelled context: got %d calls, want 1", calls)
+	}
+}
+
+func TestPool_NoRetriesByDefault(t *testing.T) {
+	calls := 0
+	_ = (&Pool{}).retry(context.Background(), func() error {
+		calls++
+		return safeErr{}
+	})
+	if calls != 1 {
+		t.Errorf("got %d calls, want 1", calls)
+	}
+}
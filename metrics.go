@@ -0,0 +1,44 @@
+package whisker
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// QueryStat describes one statement run on the store's connections, for
+// WithQueryObserver.
+type QueryStat struct {
+	// SQL is the statement's text, without its arguments.
+	SQL string
+	// Duration is how long the statement took, from sending it to reading
+	// its last row.
+	Duration time.Duration
+	// Err is the error the statement failed with, or nil.
+	Err error
+}
+
+// queryTracer reports every statement to an observer.
+type queryTracer struct {
+	observe func(QueryStat)
+}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql string
+	at  time.Time
+}
+
+func (t queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{sql: data.SQL, at: time.Now()})
+}
+
+func (t queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	t.observe(QueryStat{SQL: start.sql, Duration: time.Since(start.at), Err: data.Err})
+}
@@ -0,0 +1,30 @@
+package whisker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestQueryTracer_ReportsEachStatement(t *testing.T) {
+	var stats []QueryStat
+	tr := queryTracer{observe: func(s QueryStat) { stats = append(stats, s) }}
+
+	ctx := tr.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tr.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	boom := errors.New("boom")
+	ctx = tr.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 2"})
+	tr.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: boom})
+
+	if len(stats) != 2 {
+		t.Fatalf("got %d stats, want 2", len(stats))
+	}
+	if stats[0].SQL != "SELECT 1" || stats[0].Err != nil || stats[0].Duration < 0 {
+		t.Errorf("first: got %+v", stats[0])
+	}
+	if stats[1].SQL != "SELECT 2" || stats[1].Err != boom {
+		t.Errorf("second: got %+v", stats[1])
+	}
+}
@@ -1,32 +1,499 @@
 package whisker
 
-import "github.com/ripkitten-co/whisker/internal/codecs"
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ripkitten-co/whisker/internal/codecs"
+	"github.com/ripkitten-co/whisker/internal/ident"
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+// Config holds every Store setting. Zero values mean "use the default", so a
+// partially filled Config can be passed to WithConfig. Functional options
+// and WithConfig may be mixed; later options win.
+type Config struct {
+	// MaxConns caps the connection pool size. Zero keeps the pgx default
+	// (the greater of 4 and the number of CPUs).
+	MaxConns int32
+	// MinConns is the number of idle connections kept open.
+	MinConns int32
+	// MaxConnLifetime closes connections older than this.
+	MaxConnLifetime time.Duration
+	// MaxConnIdleTime closes connections idle for longer than this.
+	MaxConnIdleTime time.Duration
+	// ApplicationName is set on every connection, identifying Whisker's
+	// sessions in pg_stat_activity and the server log. Empty keeps the
+	// connection string's value.
+	ApplicationName string
+	// SearchPath sets the search_path of every connection, e.g. "app, public".
+	// Empty keeps the server default.
+	SearchPath string
+	// Schema is the PostgreSQL schema every whisker_* table is created and
+	// looked up in, e.g. one per tenant. It is put first on the search_path,
+	// ahead of SearchPath or, without one, public, and created on first use
+	// unless auto-migrate is disabled. Empty keeps the search_path as is.
+	Schema string
+	// ExtensionSchema is the PostgreSQL schema extensions Whisker needs, such
+	// as pg_trgm, are installed into. It must exist and be on the
+	// search_path. Empty means public.
+	ExtensionSchema string
+	// NotifyNamespace qualifies the LISTEN/NOTIFY channels event appends are
+	// signalled on, so that apps or tenants sharing a database don't wake
+	// each other's pollers. Empty derives it from the first schema of the
+	// search_path, set here or in the connection string; without one the
+	// channels are unqualified.
+	NotifyNamespace string
+	// LockTimeout aborts statements that wait longer than this for a lock.
+	// Zero keeps the server default.
+	LockTimeout time.Duration
+	// IdleInTransactionTimeout makes the server close sessions left idle
+	// inside a transaction for longer than this. Zero keeps the server
+	// default.
+	IdleInTransactionTimeout time.Duration
+	// Codec serializes documents. Defaults to jsoniter.
+	Codec codecs.Codec
+	// MaxBatchSize caps the documents per batch operation. Defaults to 1000.
+	MaxBatchSize int
+	// Clock supplies timestamps. Nil uses the database's now().
+	Clock Clock
+	// Logger receives background errors, e.g. from the projection daemon.
+	// Defaults to slog.Default().
+	Logger *slog.Logger
+	// DisableAutoMigrate stops Whisker from creating tables, columns,
+	// policies and indexes on first use. The schema must then be managed
+	// out of band.
+	DisableAutoMigrate bool
+	// EnableQuiesce makes writes check the cluster-wide maintenance flag set
+	// by Store.Quiesce, at the cost of one extra round trip per write.
+	EnableQuiesce bool
+	// ShutdownTimeout bounds how long Close waits for open sessions and
+	// advisory locks. Defaults to 30 seconds.
+	ShutdownTimeout time.Duration
+	// EnsureObserver receives the latency of every schema DDL run on first
+	// use, e.g. to export it as a metric.
+	EnsureObserver func(schema.EnsureStat)
+	// TablePolicy, when set, returns the row-level security policy installed
+	// on every whisker_* table as it is created; see WithTablePolicy.
+	TablePolicy func(table string) string
+	// QueryObserver receives the latency and outcome of every statement run
+	// on the store's connections, e.g. to export them as metrics.
+	QueryObserver func(QueryStat)
+	// MaxRetries is how many times a statement or session begin that failed
+	// before reaching the server, such as when no connection could be
+	// established, is retried. Zero disables retries.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, doubled after each
+	// one. Defaults to 100 milliseconds.
+	RetryBackoff time.Duration
+}
 
 // Option configures a Store during creation.
-type Option func(*storeConfig)
+type Option func(*Config)
+
+func defaultConfig() *Config {
+	return &Config{
+		Codec:           codecs.NewJSONIter(),
+		MaxBatchSize:    1000,
+		ShutdownTimeout: 30 * time.Second,
+		RetryBackoff:    100 * time.Millisecond,
+	}
+}
+
+// Environment variables read by ConfigFromEnv.
+const (
+	EnvMaxConns           = "WHISKER_MAX_CONNS"
+	EnvMinConns           = "WHISKER_MIN_CONNS"
+	EnvMaxConnLifetime    = "WHISKER_MAX_CONN_LIFETIME"
+	EnvMaxConnIdleTime    = "WHISKER_MAX_CONN_IDLE_TIME"
+	EnvMaxBatchSize       = "WHISKER_MAX_BATCH_SIZE"
+	EnvDisableAutoMigrate = "WHISKER_DISABLE_AUTO_MIGRATE"
+	EnvShutdownTimeout    = "WHISKER_SHUTDOWN_TIMEOUT"
+	EnvEnableQuiesce      = "WHISKER_ENABLE_QUIESCE"
+	EnvApplicationName    = "WHISKER_APPLICATION_NAME"
+	EnvSearchPath         = "WHISKER_SEARCH_PATH"
+	EnvSchema             = "WHISKER_SCHEMA"
+	EnvNotifyNamespace    = "WHISKER_NOTIFY_NAMESPACE"
+	EnvLockTimeout        = "WHISKER_LOCK_TIMEOUT"
+	EnvIdleInTxTimeout    = "WHISKER_IDLE_IN_TRANSACTION_TIMEOUT"
+	EnvMaxRetries         = "WHISKER_MAX_RETRIES"
+	EnvRetryBackoff       = "WHISKER_RETRY_BACKOFF"
+)
+
+// ConfigFromEnv builds a Config from WHISKER_* environment variables. Unset
+// variables leave the field at its zero value. Durations use Go syntax
+// ("30m"); booleans accept strconv.ParseBool values.
+func ConfigFromEnv() (Config, error) {
+	var cfg Config
+	if err := envInt32(EnvMaxConns, &cfg.MaxConns); err != nil {
+		return Config{}, err
+	}
+	if err := envInt32(EnvMinConns, &cfg.MinConns); err != nil {
+		return Config{}, err
+	}
+	if err := envDuration(EnvMaxConnLifetime, &cfg.MaxConnLifetime); err != nil {
+		return Config{}, err
+	}
+	if err := envDuration(EnvMaxConnIdleTime, &cfg.MaxConnIdleTime); err != nil {
+		return Config{}, err
+	}
+	if err := envDuration(EnvShutdownTimeout, &cfg.ShutdownTimeout); err != nil {
+		return Config{}, err
+	}
+	if err := envDuration(EnvLockTimeout, &cfg.LockTimeout); err != nil {
+		return Config{}, err
+	}
+	if err := envDuration(EnvIdleInTxTimeout, &cfg.IdleInTransactionTimeout); err != nil {
+		return Config{}, err
+	}
+	if err := envDuration(EnvRetryBackoff, &cfg.RetryBackoff); err != nil {
+		return Config{}, err
+	}
+	cfg.ApplicationName = os.Getenv(EnvApplicationName)
+	cfg.SearchPath = os.Getenv(EnvSearchPath)
+	cfg.Schema = os.Getenv(EnvSchema)
+	cfg.NotifyNamespace = os.Getenv(EnvNotifyNamespace)
+	if v, ok := os.LookupEnv(EnvMaxBatchSize); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("whisker: %s: %w", EnvMaxBatchSize, err)
+		}
+		cfg.MaxBatchSize = n
+	}
+	if v, ok := os.LookupEnv(EnvMaxRetries); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("whisker: %s: %w", EnvMaxRetries, err)
+		}
+		cfg.MaxRetries = n
+	}
+	if v, ok := os.LookupEnv(EnvDisableAutoMigrate); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("whisker: %s: %w", EnvDisableAutoMigrate, err)
+		}
+		cfg.DisableAutoMigrate = b
+	}
+	if v, ok := os.LookupEnv(EnvEnableQuiesce); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("whisker: %s: %w", EnvEnableQuiesce, err)
+		}
+		cfg.EnableQuiesce = b
+	}
+	return cfg, nil
+}
+
+func envInt32(name string, dst *int32) error {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return fmt.Errorf("whisker: %s: %w", name, err)
+	}
+	*dst = int32(n)
+	return nil
+}
 
-type storeConfig struct {
-	codec        codecs.Codec
-	maxBatchSize int
+func envDuration(name string, dst *time.Duration) error {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("whisker: %s: %w", name, err)
+	}
+	*dst = d
+	return nil
 }
 
-func defaultConfig() *storeConfig {
-	return &storeConfig{
-		codec:        codecs.NewJSONIter(),
-		maxBatchSize: 1000,
+// WithConfig applies every non-zero field of c.
+func WithConfig(c Config) Option {
+	return func(cfg *Config) {
+		if c.MaxConns != 0 {
+			cfg.MaxConns = c.MaxConns
+		}
+		if c.MinConns != 0 {
+			cfg.MinConns = c.MinConns
+		}
+		if c.MaxConnLifetime != 0 {
+			cfg.MaxConnLifetime = c.MaxConnLifetime
+		}
+		if c.MaxConnIdleTime != 0 {
+			cfg.MaxConnIdleTime = c.MaxConnIdleTime
+		}
+		if c.ApplicationName != "" {
+			cfg.ApplicationName = c.ApplicationName
+		}
+		if c.SearchPath != "" {
+			cfg.SearchPath = c.SearchPath
+		}
+		if c.Schema != "" {
+			cfg.Schema = c.Schema
+		}
+		if c.ExtensionSchema != "" {
+			cfg.ExtensionSchema = c.ExtensionSchema
+		}
+		if c.NotifyNamespace != "" {
+			cfg.NotifyNamespace = c.NotifyNamespace
+		}
+		if c.LockTimeout != 0 {
+			cfg.LockTimeout = c.LockTimeout
+		}
+		if c.IdleInTransactionTimeout != 0 {
+			cfg.IdleInTransactionTimeout = c.IdleInTransactionTimeout
+		}
+		if c.Codec != nil {
+			cfg.Codec = c.Codec
+		}
+		if c.MaxBatchSize != 0 {
+			cfg.MaxBatchSize = c.MaxBatchSize
+		}
+		if c.Clock != nil {
+			cfg.Clock = c.Clock
+		}
+		if c.Logger != nil {
+			cfg.Logger = c.Logger
+		}
+		if c.DisableAutoMigrate {
+			cfg.DisableAutoMigrate = true
+		}
+		if c.EnableQuiesce {
+			cfg.EnableQuiesce = true
+		}
+		if c.ShutdownTimeout != 0 {
+			cfg.ShutdownTimeout = c.ShutdownTimeout
+		}
+		if c.EnsureObserver != nil {
+			cfg.EnsureObserver = c.EnsureObserver
+		}
+		if c.TablePolicy != nil {
+			cfg.TablePolicy = c.TablePolicy
+		}
+		if c.QueryObserver != nil {
+			cfg.QueryObserver = c.QueryObserver
+		}
+		if c.MaxRetries != 0 {
+			cfg.MaxRetries = c.MaxRetries
+		}
+		if c.RetryBackoff != 0 {
+			cfg.RetryBackoff = c.RetryBackoff
+		}
 	}
 }
 
 // WithCodec overrides the default JSON codec (jsoniter).
 func WithCodec(c codecs.Codec) Option {
-	return func(cfg *storeConfig) {
-		cfg.codec = c
+	return func(cfg *Config) {
+		cfg.Codec = c
+	}
+}
+
+// WithClock sets the clock used for document, event and read-model
+// timestamps. The default uses the database's now().
+func WithClock(c Clock) Option {
+	return func(cfg *Config) {
+		cfg.Clock = c
 	}
 }
 
 // WithMaxBatchSize sets the maximum number of documents per batch operation.
 func WithMaxBatchSize(n int) Option {
-	return func(cfg *storeConfig) {
-		cfg.maxBatchSize = n
+	return func(cfg *Config) {
+		cfg.MaxBatchSize = n
+	}
+}
+
+// WithPoolSize sets the minimum idle and maximum total pool connections.
+func WithPoolSize(minConns, maxConns int32) Option {
+	return func(cfg *Config) {
+		cfg.MinConns = minConns
+		cfg.MaxConns = maxConns
+	}
+}
+
+// WithConnLifetime sets how long a connection may live and how long it may
+// sit idle before the pool closes it. Zero leaves a limit at its default.
+func WithConnLifetime(maxLifetime, maxIdle time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.MaxConnLifetime = maxLifetime
+		cfg.MaxConnIdleTime = maxIdle
+	}
+}
+
+// WithApplicationName sets application_name on every pooled connection, so
+// DBAs can attribute Whisker's sessions in pg_stat_activity.
+func WithApplicationName(name string) Option {
+	return func(cfg *Config) {
+		cfg.ApplicationName = name
+	}
+}
+
+// WithSearchPath sets the search_path of every pooled connection.
+func WithSearchPath(path string) Option {
+	return func(cfg *Config) {
+		cfg.SearchPath = path
+	}
+}
+
+// WithSchema keeps every whisker_* table (collections, event stores,
+// checkpoints) in the named PostgreSQL schema, creating it on first use, so
+// tenants can each have their own schema in one database. The schema is put
+// first on the search_path of every pooled connection, followed by the
+// WithSearchPath value or public, where shared extensions usually live. The
+// NOTIFY channels are namespaced by it too.
+func WithSchema(name string) Option {
+	return func(cfg *Config) {
+		cfg.Schema = name
+	}
+}
+
+// WithExtensionSchema installs the extensions Whisker creates on first use,
+// such as pg_trgm for trigram indexes, into the named schema instead of
+// public. The schema must exist and be on the search_path.
+func WithExtensionSchema(name string) Option {
+	return func(cfg *Config) {
+		cfg.ExtensionSchema = name
+	}
+}
+
+// WithNotifyNamespace sets the namespace of the LISTEN/NOTIFY channels event
+// appends are signalled on, overriding the one derived from the search_path.
+// Every process sharing an event store must use the same namespace.
+func WithNotifyNamespace(ns string) Option {
+	return func(cfg *Config) {
+		cfg.NotifyNamespace = ns
+	}
+}
+
+// WithSessionTimeouts sets lock_timeout and
+// idle_in_transaction_session_timeout on every pooled connection, bounding
+// how long Whisker's statements wait for locks and how long its transactions
+// may sit idle. Zero leaves a timeout at the server default.
+func WithSessionTimeouts(lockTimeout, idleInTransaction time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.LockTimeout = lockTimeout
+		cfg.IdleInTransactionTimeout = idleInTransaction
+	}
+}
+
+// runtimeParams returns the session settings sent when each connection
+// starts, so they cost no extra round trip.
+func (c *Config) runtimeParams() map[string]string {
+	params := map[string]string{}
+	if c.ApplicationName != "" {
+		params["application_name"] = c.ApplicationName
+	}
+	if c.SearchPath != "" {
+		params["search_path"] = c.SearchPath
+	}
+	if c.Schema != "" {
+		rest := c.SearchPath
+		if rest == "" {
+			rest = "public"
+		}
+		params["search_path"] = ident.QuoteIdent(c.Schema) + ", " + rest
+	}
+	if c.LockTimeout > 0 {
+		params["lock_timeout"] = millis(c.LockTimeout)
+	}
+	if c.IdleInTransactionTimeout > 0 {
+		params["idle_in_transaction_session_timeout"] = millis(c.IdleInTransactionTimeout)
+	}
+	return params
+}
+
+// millis formats d in milliseconds, the unit of PostgreSQL's timeout
+// settings, rounding up so a positive duration never becomes 0 (disabled).
+func millis(d time.Duration) string {
+	ms := (d + time.Millisecond - 1) / time.Millisecond
+	return strconv.FormatInt(int64(ms), 10)
+}
+
+// WithLogger sets the logger for background errors.
+func WithLogger(l *slog.Logger) Option {
+	return func(cfg *Config) {
+		cfg.Logger = l
+	}
+}
+
+// WithAutoMigrate enables or disables automatic schema creation. Enabled by
+// default.
+func WithAutoMigrate(enabled bool) Option {
+	return func(cfg *Config) {
+		cfg.DisableAutoMigrate = !enabled
+	}
+}
+
+// WithShutdownTimeout sets how long Close waits for open sessions and
+// advisory locks before giving up.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.ShutdownTimeout = d
+	}
+}
+
+// WithQuiesce enables or disables the maintenance check on writes. Disabled
+// by default. Every instance that should pause during Store.Quiesce must
+// enable it.
+func WithQuiesce(enabled bool) Option {
+	return func(cfg *Config) {
+		cfg.EnableQuiesce = enabled
+	}
+}
+
+// WithTablePolicy enables row-level security on every whisker_* table
+// Whisker creates and installs the policy expression policy returns for the
+// table's name, or none when it returns "". It is meant for deployments that
+// connect with a database role per tenant:
+//
+//	whisker.WithTablePolicy(func(table string) string {
+//		if table == "whisker_projection_checkpoints" {
+//			return ""
+//		}
+//		return "pg_has_role('app_tenants', 'MEMBER')"
+//	})
+//
+// Superusers and roles with BYPASSRLS ignore the policies. See
+// schema.WithTablePolicy.
+func WithTablePolicy(policy func(table string) string) Option {
+	return func(cfg *Config) {
+		cfg.TablePolicy = policy
+	}
+}
+
+// WithEnsureObserver registers fn to receive the wait and DDL latency of
+// every table, column, policy and index Whisker creates on first use. fn is
+// called concurrently and must not block.
+func WithEnsureObserver(fn func(schema.EnsureStat)) Option {
+	return func(cfg *Config) {
+		cfg.EnsureObserver = fn
+	}
+}
+
+// WithQueryObserver registers fn to receive the latency and outcome of every
+// statement run on the store's connections, in sessions too. fn is called
+// concurrently and must not block.
+func WithQueryObserver(fn func(QueryStat)) Option {
+	return func(cfg *Config) {
+		cfg.QueryObserver = fn
+	}
+}
+
+// WithRetries retries statements and session begins that fail before
+// reaching the server, such as when the database is briefly unreachable, up
+// to n times, waiting backoff before the first retry and doubling the wait
+// after each one. A statement the server may have seen is never retried, so
+// a write is not applied twice, and neither is a statement inside a session.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.MaxRetries = n
+		cfg.RetryBackoff = backoff
 	}
 }
@@ -0,0 +1,165 @@
+//go:build integration
+
+package whisker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/documents"
+	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/internal/testutil"
+)
+
+func TestStore_SessionSettings(t *testing.T) {
+	connStr := testutil.SetupPostgres(t)
+	ctx := context.Background()
+	store, err := whisker.New(ctx, connStr,
+		whisker.WithApplicationName("whisker-test"),
+		whisker.WithSearchPath("public"),
+		whisker.WithSessionTimeouts(2*time.Second, time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	for setting, want := range map[string]string{
+		"application_name":                    "whisker-test",
+		"search_path":                         "public",
+		"lock_timeout":                        "2s",
+		"idle_in_transaction_session_timeout": "1min",
+	} {
+		var got string
+		if err := store.DBExecutor().QueryRow(ctx, "SELECT current_setting($1)", setting).Scan(&got); err != nil {
+			t.Fatalf("read %s: %v", setting, err)
+		}
+		if got != want {
+			t.Errorf("%s: got %q, want %q", setting, got, want)
+		}
+	}
+}
+
+func TestStore_WithSchemaIsolatesTenants(t *testing.T) {
+	connStr := testutil.SetupPostgres(t)
+	ctx := context.Background()
+
+	open := func(schema string) *whisker.Store {
+		t.Helper()
+		store, err := whisker.New(ctx, connStr, whisker.WithSchema(schema))
+		if err != nil {
+			t.Fatalf("create store: %v", err)
+		}
+		t.Cleanup(func() {
+			_, _ = store.DBExecutor().Exec(ctx, "DROP SCHEMA IF EXISTS "+schema+" CASCADE")
+			store.Close()
+		})
+		return store
+	}
+	a, b := open("tenant_a"), open("tenant_b")
+
+	if err := documents.Collection[Order](a, "orders").Insert(ctx, &Order{ID: "o1", Item: "a"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := events.New(a).Append(ctx, "order-o1", 0, []events.Event{{Type: "OrderPlaced", Data: []byte(`{}`)}}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	for _, table := range []string{"tenant_a.whisker_orders", "tenant_a.whisker_events"} {
+		var exists bool
+		if err := a.DBExecutor().QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+			t.Fatalf("check %s: %v", table, err)
+		}
+		if !exists {
+			t.Errorf("%s not created in the tenant schema", table)
+		}
+	}
+	n, err := documents.Collection[Order](b, "orders").Count(ctx)
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("tenant_b sees %d of tenant_a's orders", n)
+	}
+}
+
+func TestStore_WithTablePolicy(t *testing.T) {
+	connStr := testutil.SetupPostgres(t)
+	ctx := context.Background()
+	store, err := whisker.New(ctx, connStr, whisker.WithTablePolicy(func(table string) string {
+		if table == "whisker_projection_checkpoints" {
+			return ""
+		}
+		return "pg_has_role('whisker_tenants', 'MEMBER')"
+	}))
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	type Note struct {
+		ID   string
+		Body string
+	}
+	notes := documents.Collection[Note](store, "notes")
+	if err := notes.Insert(ctx, &Note{ID: "n1", Body: "hello"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// superusers bypass RLS, so read as plain roles in and out of the group
+	_, err = store.DBExecutor().Exec(ctx, `
+		CREATE ROLE whisker_tenants NOLOGIN;
+		CREATE ROLE whisker_acme NOLOGIN IN ROLE whisker_tenants;
+		CREATE ROLE whisker_stranger NOLOGIN;
+		GRANT SELECT ON whisker_notes TO whisker_acme, whisker_stranger`)
+	if err != nil {
+		t.Fatalf("create roles: %v", err)
+	}
+	countAs := func(role string) int64 {
+		t.Helper()
+		sess, err := store.Session(ctx)
+		if err != nil {
+			t.Fatalf("session: %v", err)
+		}
+		defer sess.Close(ctx)
+		if _, err := sess.DBExecutor().Exec(ctx, "SET LOCAL ROLE "+role); err != nil {
+			t.Fatalf("set role: %v", err)
+		}
+		var n int64
+		if err := sess.DBExecutor().QueryRow(ctx, "SELECT count(*) FROM whisker_notes").Scan(&n); err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		return n
+	}
+	if got := countAs("whisker_acme"); got != 1 {
+		t.Errorf("member count: got %d, want 1", got)
+	}
+	if got := countAs("whisker_stranger"); got != 0 {
+		t.Errorf("non-member count: got %d, want 0", got)
+	}
+
+	// a restarted store finds the policy in place and leaves it alone
+	policyOID := func() (oid uint32) {
+		t.Helper()
+		if err := store.DBExecutor().QueryRow(ctx, "SELECT oid FROM pg_policy WHERE polname = 'whisker_notes_rls'").Scan(&oid); err != nil {
+			t.Fatalf("policy oid: %v", err)
+		}
+		return oid
+	}
+	before := policyOID()
+	restarted, err := whisker.New(ctx, connStr, whisker.WithTablePolicy(func(string) string {
+		return "pg_has_role('whisker_tenants', 'MEMBER')"
+	}))
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	t.Cleanup(restarted.Close)
+	if _, err := documents.Collection[Note](restarted, "notes").Count(ctx); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if after := policyOID(); after != before {
+		t.Errorf("policy reinstalled on restart: oid %d, was %d", after, before)
+	}
+}
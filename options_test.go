@@ -0,0 +1,127 @@
+package whisker
+
+import (
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvMaxConns, "20")
+	t.Setenv(EnvMinConns, "2")
+	t.Setenv(EnvMaxConnLifetime, "30m")
+	t.Setenv(EnvMaxConnIdleTime, "5m")
+	t.Setenv(EnvMaxBatchSize, "250")
+	t.Setenv(EnvDisableAutoMigrate, "true")
+	t.Setenv(EnvShutdownTimeout, "10s")
+	t.Setenv(EnvEnableQuiesce, "1")
+	t.Setenv(EnvApplicationName, "billing")
+	t.Setenv(EnvSearchPath, "app, public")
+	t.Setenv(EnvSchema, "tenant_a")
+	t.Setenv(EnvNotifyNamespace, "billing")
+	t.Setenv(EnvLockTimeout, "2s")
+	t.Setenv(EnvIdleInTxTimeout, "1m")
+	t.Setenv(EnvMaxRetries, "3")
+	t.Setenv(EnvRetryBackoff, "250ms")
+
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Config{
+		MaxConns:                 20,
+		MinConns:                 2,
+		MaxConnLifetime:          30 * time.Minute,
+		MaxConnIdleTime:          5 * time.Minute,
+		MaxBatchSize:             250,
+		DisableAutoMigrate:       true,
+		ShutdownTimeout:          10 * time.Second,
+		EnableQuiesce:            true,
+		ApplicationName:          "billing",
+		SearchPath:               "app, public",
+		Schema:                   "tenant_a",
+		NotifyNamespace:          "billing",
+		LockTimeout:              2 * time.Second,
+		IdleInTransactionTimeout: time.Minute,
+		MaxRetries:               3,
+		RetryBackoff:             250 * time.Millisecond,
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("got %+v, want %+v", cfg, want)
+	}
+}
+
+func TestConfigFromEnv_Invalid(t *testing.T) {
+	for _, env := range []string{EnvMaxConns, EnvMaxConnLifetime, EnvMaxBatchSize, EnvDisableAutoMigrate, EnvShutdownTimeout, EnvEnableQuiesce, EnvLockTimeout, EnvIdleInTxTimeout, EnvMaxRetries, EnvRetryBackoff} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, "not-a-value")
+			if _, err := ConfigFromEnv(); err == nil {
+				t.Errorf("expected error for invalid %s", env)
+			}
+		})
+	}
+}
+
+func TestWithConfig_KeepsDefaultsForZeroFields(t *testing.T) {
+	logger := slog.Default()
+	cfg := defaultConfig()
+	WithConfig(Config{MaxConns: 8, Logger: logger})(cfg)
+
+	if cfg.MaxConns != 8 || cfg.Logger != logger {
+		t.Errorf("set fields not applied: %+v", cfg)
+	}
+	if cfg.MaxBatchSize != 1000 || cfg.Codec == nil || cfg.RetryBackoff != 100*time.Millisecond {
+		t.Errorf("defaults overwritten by zero fields: %+v", cfg)
+	}
+	if cfg.DisableAutoMigrate {
+		t.Error("auto-migrate disabled by zero Config")
+	}
+}
+
+func TestOptionsOrder(t *testing.T) {
+	cfg := defaultConfig()
+	for _, o := range []Option{
+		WithConfig(Config{MaxBatchSize: 10}),
+		WithMaxBatchSize(20),
+		WithPoolSize(1, 4),
+		WithAutoMigrate(false),
+	} {
+		o(cfg)
+	}
+	if cfg.MaxBatchSize != 20 || cfg.MinConns != 1 || cfg.MaxConns != 4 || !cfg.DisableAutoMigrate {
+		t.Errorf("got %+v", cfg)
+	}
+}
+
+func TestConfig_RuntimeParams(t *testing.T) {
+	cfg := defaultConfig()
+	if params := cfg.runtimeParams(); len(params) != 0 {
+		t.Errorf("expected no settings by default, got %v", params)
+	}
+
+	WithApplicationName("billing")(cfg)
+	WithSearchPath("app, public")(cfg)
+	WithSessionTimeouts(1500*time.Microsecond, time.Minute)(cfg)
+	want := map[string]string{
+		"application_name":                    "billing",
+		"search_path":                         "app, public",
+		"lock_timeout":                        "2",
+		"idle_in_transaction_session_timeout": "60000",
+	}
+	if got := cfg.runtimeParams(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestConfig_RuntimeParamsSchema(t *testing.T) {
+	cfg := defaultConfig()
+	WithSchema("Tenant_A")(cfg)
+	if got := cfg.runtimeParams()["search_path"]; got != `"Tenant_A", public` {
+		t.Errorf("search_path: got %q", got)
+	}
+	WithSearchPath("shared, public")(cfg)
+	if got := cfg.runtimeParams()["search_path"]; got != `"Tenant_A", shared, public` {
+		t.Errorf("search_path with WithSearchPath: got %q", got)
+	}
+}
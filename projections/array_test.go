@@ -0,0 +1,103 @@
+package projections
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+type cartLine struct {
+	SKU string `json:"sku"`
+	Qty int    `json:"qty"`
+}
+
+type cart struct {
+	ID    string
+	Lines []cartLine
+}
+
+func cartProjection() *Projection[cart] {
+	return New[cart](newFakeStore(), "carts").
+		AppendOn("LineAdded", "lines", func(_ context.Context, evt events.Event) (any, error) {
+			var l cartLine
+			return l, json.Unmarshal(evt.Data, &l)
+		}).
+		RemoveOn("LineRemoved", "lines", func(_ context.Context, evt events.Event) (any, error) {
+			return map[string]any{"sku": string(evt.Data)}, nil
+		})
+}
+
+func TestProjection_AppendAndRemoveOn(t *testing.T) {
+	evts := []events.Event{
+		{StreamID: "c1", Type: "LineAdded", Data: []byte(`{"sku":"A1","qty":1}`)},
+		{StreamID: "c1", Type: "LineAdded", Data: []byte(`{"sku":"B2","qty":3}`)},
+		{StreamID: "c1", Type: "LineAdded", Data: []byte(`{"sku":"A1","qty":2}`)},
+		{StreamID: "c1", Type: "LineRemoved", Data: []byte(`A1`)},
+		{StreamID: "c1", Type: "LineAdded", Data: []byte(`{"sku":"C3","qty":1}`)},
+	}
+	got, err := cartProjection().dryRun(context.Background(), "c1", evts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []cartLine{{"B2", 3}, {"C3", 1}}
+	if got == nil || !slices.Equal(got.Lines, want) {
+		t.Errorf("got %+v, want lines %+v", got, want)
+	}
+
+	types := cartProjection().EventTypes()
+	slices.Sort(types)
+	if !slices.Equal(types, []string{"LineAdded", "LineRemoved"}) {
+		t.Errorf("EventTypes = %v", types)
+	}
+}
+
+func TestProjection_OnReplacesAppendOn(t *testing.T) {
+	p := cartProjection().On("LineAdded", func(_ context.Context, evt events.Event, _ *cart) (*cart, error) {
+		return &cart{ID: evt.StreamID, Lines: []cartLine{}}, nil
+	})
+	got, err := p.dryRun(context.Background(), "c1", []events.Event{{StreamID: "c1", Type: "LineAdded", Data: []byte(`{}`)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || len(got.Lines) != 0 {
+		t.Errorf("got %+v, want a cart without lines", got)
+	}
+}
+
+func TestProjection_AppendOnNeedsArrayStore(t *testing.T) {
+	ps := &memoryStore{docs: map[string][]byte{}}
+	err := cartProjection().Process(context.Background(), []events.Event{{StreamID: "c1", Type: "LineAdded", Data: []byte(`{}`)}}, ps)
+	if err == nil || !strings.Contains(err.Error(), "does not support array updates") {
+		t.Errorf("got %v, want unsupported store error", err)
+	}
+}
+
+func TestJSONContains(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{`{"sku":"A1","qty":2}`, `{"sku":"A1"}`, true},
+		{`{"sku":"A1","qty":2}`, `{"sku":"B2"}`, false},
+		{`{"tags":["x","y"]}`, `{"tags":["y"]}`, true},
+		{`"A1"`, `"A1"`, true},
+		{`1`, `"1"`, false},
+		{`{"sku":"A1"}`, `{"sku":"A1","qty":2}`, false},
+	}
+	for _, tt := range tests {
+		var a, b any
+		if err := json.Unmarshal([]byte(tt.a), &a); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(tt.b), &b); err != nil {
+			t.Fatal(err)
+		}
+		if got := jsonContains(a, b); got != tt.want {
+			t.Errorf("jsonContains(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -2,8 +2,10 @@ package projections
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/ripkitten-co/whisker"
@@ -71,7 +73,27 @@ func (cs *CheckpointStore) Save(ctx context.Context, name string, position int64
 	return nil
 }
 
-// SetStatus updates the status column for the named projection.
+// Init creates the checkpoint of the named projection at position unless it
+// already has one, and reports whether it did.
+func (cs *CheckpointStore) Init(ctx context.Context, name string, position int64) (bool, error) {
+	if err := cs.ensure(ctx); err != nil {
+		return false, fmt.Errorf("checkpoint %s: ensure table: %w", name, err)
+	}
+
+	tag, err := cs.exec.Exec(ctx,
+		`INSERT INTO whisker_projection_checkpoints (projection_name, last_position, updated_at)
+		 VALUES ($1, $2, now())
+		 ON CONFLICT (projection_name) DO NOTHING`,
+		name, position,
+	)
+	if err != nil {
+		return false, fmt.Errorf("checkpoint %s: init: %w", name, err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// SetStatus updates the status column for the named projection. Setting it
+// to "running" clears the recorded Failure.
 func (cs *CheckpointStore) SetStatus(ctx context.Context, name string, status string) error {
 	if err := cs.ensure(ctx); err != nil {
 		return fmt.Errorf("checkpoint %s: ensure table: %w", name, err)
@@ -80,7 +102,8 @@ func (cs *CheckpointStore) SetStatus(ctx context.Context, name string, status st
 	_, err := cs.exec.Exec(ctx,
 		`INSERT INTO whisker_projection_checkpoints (projection_name, last_position, status, updated_at)
 		 VALUES ($1, 0, $2, now())
-		 ON CONFLICT (projection_name) DO UPDATE SET status = $2, updated_at = now()`,
+		 ON CONFLICT (projection_name) DO UPDATE SET status = $2, updated_at = now(),
+		 failure = CASE WHEN $2 = 'running' THEN NULL ELSE whisker_projection_checkpoints.failure END`,
 		name, status,
 	)
 	if err != nil {
@@ -89,7 +112,33 @@ func (cs *CheckpointStore) SetStatus(ctx context.Context, name string, status st
 	return nil
 }
 
-// Reset sets the projection position back to 0 with status 'rebuilding'.
+// DeadLetter sets the named projection's status to dead_letter and records
+// why. The failure is listed with the checkpoint until the status changes
+// back to running.
+func (cs *CheckpointStore) DeadLetter(ctx context.Context, name string, f Failure) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("checkpoint %s: dead letter: marshal: %w", name, err)
+	}
+	if err := cs.ensure(ctx); err != nil {
+		return fmt.Errorf("checkpoint %s: ensure table: %w", name, err)
+	}
+
+	_, err = cs.exec.Exec(ctx,
+		`INSERT INTO whisker_projection_checkpoints (projection_name, last_position, status, failure, updated_at)
+		 VALUES ($1, 0, 'dead_letter', $2, now())
+		 ON CONFLICT (projection_name) DO UPDATE SET status = 'dead_letter', failure = $2, updated_at = now()`,
+		name, data,
+	)
+	if err != nil {
+		return fmt.Errorf("checkpoint %s: dead letter: %w", name, err)
+	}
+	return nil
+}
+
+// Reset sets the projection position back to 0 with status 'rebuilding' and
+// clears its metadata, which described the progress being discarded, and any
+// recorded failure.
 func (cs *CheckpointStore) Reset(ctx context.Context, name string) error {
 	if err := cs.ensure(ctx); err != nil {
 		return fmt.Errorf("checkpoint %s: ensure table: %w", name, err)
@@ -98,7 +147,7 @@ func (cs *CheckpointStore) Reset(ctx context.Context, name string) error {
 	_, err := cs.exec.Exec(ctx,
 		`INSERT INTO whisker_projection_checkpoints (projection_name, last_position, status, updated_at)
 		 VALUES ($1, 0, 'rebuilding', now())
-		 ON CONFLICT (projection_name) DO UPDATE SET last_position = 0, status = 'rebuilding', updated_at = now()`,
+		 ON CONFLICT (projection_name) DO UPDATE SET last_position = 0, status = 'rebuilding', metadata = NULL, failure = NULL, updated_at = now()`,
 		name,
 	)
 	if err != nil {
@@ -106,3 +155,288 @@ func (cs *CheckpointStore) Reset(ctx context.Context, name string) error {
 	}
 	return nil
 }
+
+// Delete removes the named projection's checkpoint row, metadata and
+// ownership included. It reports whether a row existed.
+func (cs *CheckpointStore) Delete(ctx context.Context, name string) (bool, error) {
+	if err := cs.ensure(ctx); err != nil {
+		return false, fmt.Errorf("checkpoint %s: ensure table: %w", name, err)
+	}
+
+	tag, err := cs.exec.Exec(ctx, `DELETE FROM whisker_projection_checkpoints WHERE projection_name = $1`, name)
+	if err != nil {
+		return false, fmt.Errorf("checkpoint %s: delete: %w", name, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// StatusDecommissioned is the status of a checkpoint retired by
+// Decommission.
+const StatusDecommissioned = "decommissioned"
+
+// Decommissioned is the metadata Decommission leaves in a retired
+// projection's checkpoint.
+type Decommissioned struct {
+	At time.Time `json:"at"`
+	// Position is the position the projection had reached.
+	Position int64 `json:"position"`
+	// DroppedTables are the read model tables dropped with it.
+	DroppedTables []string `json:"droppedTables,omitempty"`
+}
+
+// Decommission marks the named projection's checkpoint as decommissioned:
+// the position goes back to 0, ownership, version and failure are cleared,
+// and rec, with Position set to the position reached, replaces the metadata;
+// decommissioning it again keeps the position first recorded. The row is
+// created if there was none; Decommission reports whether there was.
+func (cs *CheckpointStore) Decommission(ctx context.Context, name string, rec Decommissioned) (bool, error) {
+	if err := cs.ensure(ctx); err != nil {
+		return false, fmt.Errorf("checkpoint %s: ensure table: %w", name, err)
+	}
+
+	existed := true
+	var status string
+	var prev []byte
+	err := cs.exec.QueryRow(ctx,
+		`SELECT last_position, status, metadata FROM whisker_projection_checkpoints WHERE projection_name = $1`,
+		name,
+	).Scan(&rec.Position, &status, &prev)
+	if errors.Is(err, pgx.ErrNoRows) {
+		existed = false
+	} else if err != nil {
+		return false, fmt.Errorf("checkpoint %s: decommission: %w", name, err)
+	}
+	if status == StatusDecommissioned {
+		// decommissioned again: keep the position first recorded
+		var before Decommissioned
+		if json.Unmarshal(prev, &before) == nil {
+			rec.Position = before.Position
+		}
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return false, fmt.Errorf("checkpoint %s: decommission: marshal: %w", name, err)
+	}
+
+	_, err = cs.exec.Exec(ctx,
+		`INSERT INTO whisker_projection_checkpoints (projection_name, last_position, status, metadata, updated_at)
+		 VALUES ($1, 0, $2, $3, now())
+		 ON CONFLICT (projection_name) DO UPDATE SET last_position = 0, status = $2, metadata = $3, updated_at = now(),
+		 owner_instance = NULL, owner_host = NULL, owner_acquired_at = NULL, subscriber_version = NULL, failure = NULL`,
+		name, StatusDecommissioned, data,
+	)
+	if err != nil {
+		return false, fmt.Errorf("checkpoint %s: decommission: %w", name, err)
+	}
+	return existed, nil
+}
+
+// SaveMeta stores meta, encoded as JSON, alongside the named projection's
+// checkpoint, replacing any metadata saved before. It is meant for small
+// per-projection bookkeeping such as the last processed business date or
+// partition offsets. Saving it through a session-backed CheckpointStore
+// commits it atomically with the projection's own writes.
+func (cs *CheckpointStore) SaveMeta(ctx context.Context, name string, meta any) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("checkpoint %s: save meta: marshal: %w", name, err)
+	}
+	if err := cs.ensure(ctx); err != nil {
+		return fmt.Errorf("checkpoint %s: ensure table: %w", name, err)
+	}
+
+	_, err = cs.exec.Exec(ctx,
+		`INSERT INTO whisker_projection_checkpoints (projection_name, last_position, metadata, updated_at)
+		 VALUES ($1, 0, $2, now())
+		 ON CONFLICT (projection_name) DO UPDATE SET metadata = $2, updated_at = now()`,
+		name, data,
+	)
+	if err != nil {
+		return fmt.Errorf("checkpoint %s: save meta: %w", name, err)
+	}
+	return nil
+}
+
+// LoadMeta decodes the metadata saved for the named projection into dest. It
+// reports false, leaving dest untouched, if none has been saved.
+func (cs *CheckpointStore) LoadMeta(ctx context.Context, name string, dest any) (bool, error) {
+	if err := cs.ensure(ctx); err != nil {
+		return false, fmt.Errorf("checkpoint %s: ensure table: %w", name, err)
+	}
+
+	var data []byte
+	err := cs.exec.QueryRow(ctx,
+		`SELECT metadata FROM whisker_projection_checkpoints WHERE projection_name = $1`,
+		name,
+	).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("checkpoint %s: load meta: %w", name, err)
+	}
+	if data == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return false, fmt.Errorf("checkpoint %s: load meta: unmarshal: %w", name, err)
+	}
+	return true, nil
+}
+
+// SaveVersion records version as the subscriber version the named
+// projection's read model was built with. See Versioned.
+func (cs *CheckpointStore) SaveVersion(ctx context.Context, name string, version int) error {
+	if err := cs.ensure(ctx); err != nil {
+		return fmt.Errorf("checkpoint %s: ensure table: %w", name, err)
+	}
+
+	_, err := cs.exec.Exec(ctx,
+		`INSERT INTO whisker_projection_checkpoints (projection_name, last_position, subscriber_version, updated_at)
+		 VALUES ($1, 0, $2, now())
+		 ON CONFLICT (projection_name) DO UPDATE SET subscriber_version = $2, updated_at = now()`,
+		name, version,
+	)
+	if err != nil {
+		return fmt.Errorf("checkpoint %s: save version: %w", name, err)
+	}
+	return nil
+}
+
+// LoadVersion returns the subscriber version recorded for the named
+// projection. It reports false if none has been recorded.
+func (cs *CheckpointStore) LoadVersion(ctx context.Context, name string) (int, bool, error) {
+	if err := cs.ensure(ctx); err != nil {
+		return 0, false, fmt.Errorf("checkpoint %s: ensure table: %w", name, err)
+	}
+
+	var version *int
+	err := cs.exec.QueryRow(ctx,
+		`SELECT subscriber_version FROM whisker_projection_checkpoints WHERE projection_name = $1`,
+		name,
+	).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("checkpoint %s: load version: %w", name, err)
+	}
+	if version == nil {
+		return 0, false, nil
+	}
+	return *version, true, nil
+}
+
+// Owner identifies the daemon instance that last processed a projection.
+type Owner struct {
+	InstanceID string
+	Hostname   string
+	AcquiredAt time.Time
+}
+
+// Checkpoint is the stored progress of a projection or handler.
+type Checkpoint struct {
+	Name      string
+	Position  int64
+	Status    string
+	UpdatedAt time.Time
+	// Owner is nil when no instance currently owns the projection.
+	Owner *Owner
+	// Metadata is the JSON saved with SaveMeta, or nil.
+	Metadata json.RawMessage
+	// Version is the subscriber version the read model was built with, or 0
+	// if none has been recorded. See Versioned.
+	Version int
+	// Failure is why the projection was dead-lettered, or nil.
+	Failure *Failure
+}
+
+// Claim records instanceID on hostname as the owner of the named projection.
+// AcquiredAt only changes when ownership moves to a different instance.
+func (cs *CheckpointStore) Claim(ctx context.Context, name, instanceID, hostname string) error {
+	if err := cs.ensure(ctx); err != nil {
+		return fmt.Errorf("checkpoint %s: ensure table: %w", name, err)
+	}
+
+	_, err := cs.exec.Exec(ctx,
+		`INSERT INTO whisker_projection_checkpoints (projection_name, last_position, updated_at, owner_instance, owner_host, owner_acquired_at)
+		 VALUES ($1, 0, now(), $2, $3, now())
+		 ON CONFLICT (projection_name) DO UPDATE SET owner_instance = $2, owner_host = $3, owner_acquired_at = now()
+		 WHERE whisker_projection_checkpoints.owner_instance IS DISTINCT FROM $2`,
+		name, instanceID, hostname,
+	)
+	if err != nil {
+		return fmt.Errorf("checkpoint %s: claim: %w", name, err)
+	}
+	return nil
+}
+
+// Unclaim clears the owner of the named projection if it is still instanceID.
+func (cs *CheckpointStore) Unclaim(ctx context.Context, name, instanceID string) error {
+	if err := cs.ensure(ctx); err != nil {
+		return fmt.Errorf("checkpoint %s: ensure table: %w", name, err)
+	}
+
+	_, err := cs.exec.Exec(ctx,
+		`UPDATE whisker_projection_checkpoints
+		 SET owner_instance = NULL, owner_host = NULL, owner_acquired_at = NULL
+		 WHERE projection_name = $1 AND owner_instance = $2`,
+		name, instanceID,
+	)
+	if err != nil {
+		return fmt.Errorf("checkpoint %s: unclaim: %w", name, err)
+	}
+	return nil
+}
+
+// List returns every checkpoint ordered by name, including its owner,
+// metadata, subscriber version and dead-letter failure.
+func (cs *CheckpointStore) List(ctx context.Context) ([]Checkpoint, error) {
+	if err := cs.ensure(ctx); err != nil {
+		return nil, fmt.Errorf("checkpoints: ensure table: %w", err)
+	}
+
+	rows, err := cs.exec.Query(ctx,
+		`SELECT projection_name, last_position, status, updated_at, owner_instance, owner_host, owner_acquired_at, metadata, subscriber_version, failure
+		 FROM whisker_projection_checkpoints ORDER BY projection_name`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoints: list: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Checkpoint
+	for rows.Next() {
+		var c Checkpoint
+		var instance, host *string
+		var acquired *time.Time
+		var version *int
+		var failure []byte
+		if err := rows.Scan(&c.Name, &c.Position, &c.Status, &c.UpdatedAt, &instance, &host, &acquired, &c.Metadata, &version, &failure); err != nil {
+			return nil, fmt.Errorf("checkpoints: list: scan: %w", err)
+		}
+		if failure != nil {
+			c.Failure = new(Failure)
+			if err := json.Unmarshal(failure, c.Failure); err != nil {
+				return nil, fmt.Errorf("checkpoints: list: %s: failure: %w", c.Name, err)
+			}
+		}
+		if instance != nil {
+			c.Owner = &Owner{InstanceID: *instance}
+			if host != nil {
+				c.Owner.Hostname = *host
+			}
+			if acquired != nil {
+				c.Owner.AcquiredAt = *acquired
+			}
+		}
+		if version != nil {
+			c.Version = *version
+		}
+		result = append(result, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("checkpoints: list: %w", err)
+	}
+	return result, nil
+}
@@ -103,3 +103,151 @@ func TestCheckpoint_Reset(t *testing.T) {
 		t.Errorf("status after reset: got %q, want %q", status, "rebuilding")
 	}
 }
+
+func TestCheckpoint_ClaimAndList(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	cs := projections.NewCheckpointStore(store)
+
+	if err := cs.Save(ctx, "a_proj", 7); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := cs.Claim(ctx, "b_proj", "instance-1", "host-1"); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	list, err := cs.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("got %d checkpoints, want 2", len(list))
+	}
+	if list[0].Name != "a_proj" || list[0].Position != 7 || list[0].Owner != nil {
+		t.Errorf("a_proj: got %+v, want position 7 and no owner", list[0])
+	}
+	owner := list[1].Owner
+	if owner == nil || owner.InstanceID != "instance-1" || owner.Hostname != "host-1" || owner.AcquiredAt.IsZero() {
+		t.Fatalf("b_proj owner: got %+v", owner)
+	}
+
+	// re-claiming by the same instance keeps the original acquisition time
+	if err := cs.Claim(ctx, "b_proj", "instance-1", "host-1"); err != nil {
+		t.Fatalf("reclaim: %v", err)
+	}
+	list, _ = cs.List(ctx)
+	if !list[1].Owner.AcquiredAt.Equal(owner.AcquiredAt) {
+		t.Errorf("acquired_at changed on reclaim: %v -> %v", owner.AcquiredAt, list[1].Owner.AcquiredAt)
+	}
+
+	// another instance cannot unclaim
+	if err := cs.Unclaim(ctx, "b_proj", "instance-2"); err != nil {
+		t.Fatalf("unclaim: %v", err)
+	}
+	list, _ = cs.List(ctx)
+	if list[1].Owner == nil {
+		t.Fatal("owner cleared by a different instance")
+	}
+
+	if err := cs.Unclaim(ctx, "b_proj", "instance-1"); err != nil {
+		t.Fatalf("unclaim: %v", err)
+	}
+	list, _ = cs.List(ctx)
+	if list[1].Owner != nil {
+		t.Errorf("owner not cleared: %+v", list[1].Owner)
+	}
+}
+
+func TestCheckpoint_Version(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	cs := projections.NewCheckpointStore(store)
+
+	if _, found, err := cs.LoadVersion(ctx, "ledger"); err != nil || found {
+		t.Fatalf("initial load version: found=%v err=%v", found, err)
+	}
+	if err := cs.Save(ctx, "ledger", 7); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := cs.SaveVersion(ctx, "ledger", 2); err != nil {
+		t.Fatalf("save version: %v", err)
+	}
+
+	version, found, err := cs.LoadVersion(ctx, "ledger")
+	if err != nil || !found || version != 2 {
+		t.Errorf("load version: got %d found=%v err=%v, want 2", version, found, err)
+	}
+	cps, err := cs.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(cps) != 1 || cps[0].Version != 2 || cps[0].Position != 7 {
+		t.Errorf("list: got %+v", cps)
+	}
+}
+
+func TestCheckpoint_Meta(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	cs := projections.NewCheckpointStore(store)
+
+	type meta struct {
+		BusinessDate string         `json:"business_date"`
+		Offsets      map[string]int `json:"offsets"`
+	}
+
+	var got meta
+	found, err := cs.LoadMeta(ctx, "ledger", &got)
+	if err != nil {
+		t.Fatalf("initial load meta: %v", err)
+	}
+	if found {
+		t.Fatal("expected no metadata before SaveMeta")
+	}
+
+	if err := cs.Save(ctx, "ledger", 7); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	want := meta{BusinessDate: "2026-10-17", Offsets: map[string]int{"p0": 3, "p1": 5}}
+	if err := cs.SaveMeta(ctx, "ledger", want); err != nil {
+		t.Fatalf("save meta: %v", err)
+	}
+
+	found, err = cs.LoadMeta(ctx, "ledger", &got)
+	if err != nil {
+		t.Fatalf("load meta: %v", err)
+	}
+	if !found {
+		t.Fatal("expected metadata after SaveMeta")
+	}
+	if got.BusinessDate != want.BusinessDate || got.Offsets["p0"] != 3 || got.Offsets["p1"] != 5 {
+		t.Errorf("meta: got %+v, want %+v", got, want)
+	}
+
+	pos, _, err := cs.Load(ctx, "ledger")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if pos != 7 {
+		t.Errorf("position: got %d, want 7 (SaveMeta must not touch it)", pos)
+	}
+
+	list, err := cs.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list) != 1 || len(list[0].Metadata) == 0 {
+		t.Fatalf("list: expected one checkpoint with metadata, got %+v", list)
+	}
+
+	if err := cs.Reset(ctx, "ledger"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	found, err = cs.LoadMeta(ctx, "ledger", &got)
+	if err != nil {
+		t.Fatalf("load meta after reset: %v", err)
+	}
+	if found {
+		t.Error("expected Reset to clear metadata")
+	}
+}
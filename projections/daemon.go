@@ -2,12 +2,17 @@ package projections
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"log/slog"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/internal/pg"
 	"github.com/ripkitten-co/whisker/schema"
 )
 
@@ -17,6 +22,19 @@ type DaemonOption func(*daemonConfig)
 type daemonConfig struct {
 	pollingInterval time.Duration
 	batchSize       int
+	instanceID      string
+	processTimeout  time.Duration
+
+	checkpointEvery    int
+	checkpointInterval time.Duration
+
+	eventStore    string
+	sharedPolling bool
+
+	slowBatch     time.Duration
+	profileLabels bool
+
+	autoRebuild bool
 }
 
 // WithPollingInterval sets how often each worker polls for new events.
@@ -31,17 +49,100 @@ func WithBatchSize(n int) DaemonOption {
 	return func(c *daemonConfig) { c.batchSize = n }
 }
 
+// WithInstanceID sets the identifier this daemon records as the owner of the
+// projections it processes. Defaults to hostname-pid-random, which is unique
+// per process.
+func WithInstanceID(id string) DaemonOption {
+	return func(c *daemonConfig) { c.instanceID = id }
+}
+
+// WithProcessTimeout bounds how long a worker waits for one batch to be
+// processed before cancelling it and counting the batch as failed. See
+// Worker.SetProcessTimeout. Defaults to no timeout.
+func WithProcessTimeout(d time.Duration) DaemonOption {
+	return func(c *daemonConfig) { c.processTimeout = d }
+}
+
+// WithCheckpointBatching saves each worker's checkpoint at most once every n
+// events or interval instead of after every batch, easing write load while
+// projections catch up. A worker still saves at the end of every drain
+// cycle, when it releases its lock. After a crash, events processed since
+// the last save are processed again. See Worker.SetCheckpointBatching.
+// Defaults to saving after every batch.
+func WithCheckpointBatching(n int, interval time.Duration) DaemonOption {
+	return func(c *daemonConfig) {
+		c.checkpointEvery = n
+		c.checkpointInterval = interval
+	}
+}
+
+// WithEventStore makes the daemon's subscribers read the named event store
+// (see events.NewNamed) instead of the default one. Their checkpoints, as
+// listed by Status, are named "store:subscriber", so one daemon per bounded
+// context can run the same subscriber names side by side. See
+// Worker.SetEventStore.
+func WithEventStore(name string) DaemonOption {
+	return func(c *daemonConfig) { c.eventStore = name }
+}
+
+// WithSharedPolling makes the daemon's workers share their event reads: when
+// several subscribers are caught up to the same position, one query reads
+// the new events and every worker is served from it, instead of each issuing
+// an identical read. Workers still filter and checkpoint independently. Once
+// a read finds no new events, workers polling within the following 100ms (or
+// half the polling interval, if shorter) trust it, so a new event may wait
+// for their next poll. Events are shared between subscribers, so
+// subscribers must not modify their Data or Metadata.
+func WithSharedPolling() DaemonOption {
+	return func(c *daemonConfig) { c.sharedPolling = true }
+}
+
+// WithSlowBatchLog logs, at warn level, every batch a subscriber takes at
+// least threshold to process, with the batch's event types, busiest streams
+// and positions and the bytes allocated meanwhile, to diagnose slow
+// projections after the fact. See Worker.SetSlowBatchThreshold. Disabled by
+// default.
+func WithSlowBatchLog(threshold time.Duration) DaemonOption {
+	return func(c *daemonConfig) { c.slowBatch = threshold }
+}
+
+// WithProfileLabels runs each subscriber's batches under the pprof label
+// whisker_subscriber, so CPU and goroutine profiles show time per
+// projection. See Worker.SetProfileLabels.
+func WithProfileLabels() DaemonOption {
+	return func(c *daemonConfig) { c.profileLabels = true }
+}
+
+// WithAutoRebuild makes the daemon rebuild a read-model projection, as
+// Rebuild does, when the version it declares (see Versioned) differs from
+// the one its read model was built with, instead of only marking the
+// checkpoint "outdated". The rebuild runs when the projection's worker first
+// takes its lock. Handlers and links are never rebuilt automatically, since
+// replaying them would repeat side effects or emitted events.
+func WithAutoRebuild() DaemonOption {
+	return func(c *daemonConfig) { c.autoRebuild = true }
+}
+
 // Daemon runs registered subscribers in independent goroutines, each with its
 // own checkpoint and advisory lock. It is the main entry point for running
 // projections and side-effect handlers.
 type Daemon struct {
-	store       *whisker.Store
+	store       Store
 	config      daemonConfig
+	hostname    string
 	subscribers []Subscriber
+	// options holds the SubscriberOptions of subscribers, by checkpoint name
+	options map[string]subscriberConfig
+	// readModels maps the read model tables of the registered subscribers to
+	// the checkpoint name of the one writing each
+	readModels map[string]string
+
+	statsMu    sync.Mutex
+	throughput map[string]*throughput
 }
 
 // NewDaemon creates a daemon bound to the given store.
-func NewDaemon(store *whisker.Store, opts ...DaemonOption) *Daemon {
+func NewDaemon(store Store, opts ...DaemonOption) *Daemon {
 	cfg := daemonConfig{
 		pollingInterval: 5 * time.Second,
 		batchSize:       100,
@@ -49,24 +150,117 @@ func NewDaemon(store *whisker.Store, opts ...DaemonOption) *Daemon {
 	for _, o := range opts {
 		o(&cfg)
 	}
-	return &Daemon{store: store, config: cfg}
+	hostname, _ := os.Hostname()
+	if cfg.instanceID == "" {
+		cfg.instanceID = defaultInstanceID(hostname)
+	}
+	return &Daemon{store: store, config: cfg, hostname: hostname}
+}
+
+// NewDaemonIn creates a daemon bound to the store registered as db in r.
+// Its projections read events from, and write read models to, that store.
+func NewDaemonIn(r *whisker.Registry, db string, opts ...DaemonOption) (*Daemon, error) {
+	s, err := r.Store(db)
+	if err != nil {
+		return nil, fmt.Errorf("daemon: %w", err)
+	}
+	return NewDaemon(s, opts...), nil
+}
+
+func defaultInstanceID(hostname string) string {
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(b[:]))
+}
+
+// InstanceID returns the identifier recorded as the owner of the projections
+// this daemon processes.
+func (d *Daemon) InstanceID() string {
+	return d.config.instanceID
+}
+
+// Status returns the checkpoint of every projection and handler known to the
+// database, including which instance owns it, for operators and admin
+// endpoints.
+func (d *Daemon) Status(ctx context.Context) ([]Checkpoint, error) {
+	cps, err := NewCheckpointStore(d.store).List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("daemon: status: %w", err)
+	}
+	return cps, nil
 }
 
 // Add registers a subscriber (projection or handler) to be run by the daemon.
 // Must be called before Run.
-func (d *Daemon) Add(sub Subscriber) {
+//
+// A read model table is named after its projection alone, whatever event
+// store the projection reads, so Add panics when sub's read model, or that
+// of a member of a group, is already written by a registered subscriber,
+// such as a projection of the same name bound to another store.
+func (d *Daemon) Add(sub Subscriber, opts ...SubscriberOption) {
+	if err := d.claimReadModels(sub); err != nil {
+		panic(err)
+	}
 	d.subscribers = append(d.subscribers, sub)
+	if len(opts) == 0 {
+		return
+	}
+	var cfg subscriberConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	inner, eventStore := unwrapEventStore(sub)
+	if eventStore == "" {
+		eventStore = d.config.eventStore
+	}
+	if d.options == nil {
+		d.options = make(map[string]subscriberConfig)
+	}
+	d.options[checkpointName(eventStore, inner.Name())] = cfg
+}
+
+// claimReadModels records the read model tables sub writes, failing if a
+// registered subscriber already writes one of them.
+func (d *Daemon) claimReadModels(sub Subscriber) error {
+	inner, eventStore := unwrapEventStore(sub)
+	if eventStore == "" {
+		eventStore = d.config.eventStore
+	}
+	name := checkpointName(eventStore, inner.Name())
+	tables := readModelTables(inner)
+	for _, table := range tables {
+		if owner, ok := d.readModels[table]; ok {
+			return fmt.Errorf("projections: %s and %s both write the read model whisker_%s", owner, name, table)
+		}
+	}
+	if d.readModels == nil {
+		d.readModels = make(map[string]string)
+	}
+	for _, table := range tables {
+		d.readModels[table] = name
+	}
+	return nil
 }
 
 // Run starts all subscribers in separate goroutines and blocks until the
-// context is cancelled.
+// context is cancelled or the store shuts down. Workers release their
+// advisory locks before Run returns.
 func (d *Daemon) Run(ctx context.Context) {
 	var wg sync.WaitGroup
 
+	var shared *dispatcher
+	if d.config.sharedPolling {
+		es := events.NewNamed(d.store, d.config.eventStore)
+		shared = newDispatcher(es.ReadAll, d.config.batchSize, d.config.pollingInterval)
+	}
+
 	for _, sub := range d.subscribers {
-		w := NewWorker(d.store, sub)
+		w := d.newWorker(sub)
 		w.batchSize = d.config.batchSize
-		w.poller = NewPoller(d.store, d.config.batchSize)
+		w.poller = NewNamedPoller(d.store, w.eventStore, d.config.batchSize)
+		if w.eventStore == d.config.eventStore {
+			w.poller.shared = shared
+		}
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
@@ -77,8 +271,33 @@ func (d *Daemon) Run(ctx context.Context) {
 	wg.Wait()
 }
 
+func (d *Daemon) newWorker(sub Subscriber) *Worker {
+	sub, eventStore := unwrapEventStore(sub)
+	w := NewWorker(d.store, sub)
+	w.instanceID = d.config.instanceID
+	w.hostname = d.hostname
+	w.processTimeout = d.config.processTimeout
+	w.slowBatch = d.config.slowBatch
+	w.profileLabels = d.config.profileLabels
+	w.SetCheckpointBatching(d.config.checkpointEvery, d.config.checkpointInterval)
+	w.throughput = d.throughputFor(sub.Name())
+	if eventStore == "" {
+		eventStore = d.config.eventStore
+	}
+	w.SetEventStore(eventStore)
+	if cfg, ok := d.options[w.name()]; ok {
+		w.SetStartFrom(cfg.start)
+		w.SetExclude(cfg.exclude)
+	}
+	return w
+}
+
 func (d *Daemon) runWorker(ctx context.Context, w *Worker) {
-	drainBatches(ctx, w)
+	defer unclaim(ctx, w)
+
+	if !d.drainBatches(ctx, w) {
+		return
+	}
 
 	ticker := time.NewTicker(d.config.pollingInterval)
 	defer ticker.Stop()
@@ -88,43 +307,171 @@ func (d *Daemon) runWorker(ctx context.Context, w *Worker) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			drainBatches(ctx, w)
+			if !d.drainBatches(ctx, w) {
+				return
+			}
 		}
 	}
 }
 
-func drainBatches(ctx context.Context, w *Worker) {
+// lockReleaseTimeout bounds how long a worker waits to release its advisory
+// lock after its context has been cancelled.
+const lockReleaseTimeout = 5 * time.Second
+
+// unclaim clears the worker's ownership of its subscriber when it stops.
+func unclaim(ctx context.Context, w *Worker) {
+	if !w.claimed {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lockReleaseTimeout)
+	defer cancel()
+	if err := w.checkpoint.Unclaim(ctx, w.name(), w.instanceID); err != nil {
+		w.store.Logger().Debug("unclaim projection", "worker", w.name(), "error", err)
+	}
+}
+
+// drainBatches processes batches until the subscriber is caught up. It
+// reports false once the store has shut down and the worker should stop.
+func (d *Daemon) drainBatches(ctx context.Context, w *Worker) bool {
 	acquired, err := w.TryAcquireLock(ctx)
+	if errors.Is(err, whisker.ErrStoreClosed) {
+		return false
+	}
 	if err != nil {
-		slog.Error("acquire lock", "worker", w.subscriber.Name(), "error", err)
-		return
+		w.store.Logger().Error("acquire lock", "worker", w.name(), "error", err)
+		return true
 	}
 	if !acquired {
-		return
+		return true
+	}
+	defer releaseLock(ctx, w)
+	defer flushCheckpoint(ctx, w)
+
+	// before Claim, which creates the checkpoint at position 0
+	if err := w.initCheckpoint(ctx); err != nil {
+		w.store.Logger().Error("init checkpoint", "worker", w.name(), "error", err)
+		return true
 	}
-	defer func() {
-		if err := w.ReleaseLock(ctx); err != nil {
-			slog.Error("release lock", "worker", w.subscriber.Name(), "error", err)
+	if w.instanceID != "" {
+		if err := w.checkpoint.Claim(ctx, w.name(), w.instanceID, w.hostname); err != nil {
+			w.store.Logger().Error("claim projection", "worker", w.name(), "error", err)
+		} else {
+			w.claimed = true
 		}
-	}()
+	}
+
+	if !w.versionChecked {
+		if err := d.checkVersion(ctx, w); err != nil {
+			w.store.Logger().Error("check projection version", "worker", w.name(), "error", err)
+			return true
+		}
+		w.versionChecked = true
+	}
 
 	for {
 		if ctx.Err() != nil {
-			return
+			return true
 		}
-		n, err := w.ProcessBatch(ctx)
+		n, err := w.processBatchTimed(ctx)
 		if err != nil {
-			slog.Error("process batch", "worker", w.subscriber.Name(), "error", err)
-			return
+			w.store.Logger().Error("process batch", "worker", w.name(), "error", err)
+			return true
 		}
 		if n == 0 {
-			return
+			return true
 		}
 	}
 }
 
-// Rebuild drops the read model table for the named projection, resets its
-// checkpoint to zero, and replays all events from the beginning.
+// checkVersion compares the version w's subscriber declares with the one
+// recorded in its checkpoint. A first version is recorded as is. On a
+// mismatch it rebuilds a read model with WithAutoRebuild, and otherwise
+// marks a running checkpoint "outdated" and leaves it processing new events
+// until Rebuild or AcceptVersion. w holds the lock.
+func (d *Daemon) checkVersion(ctx context.Context, w *Worker) error {
+	want := subscriberVersion(w.subscriber)
+	if want <= 0 {
+		return nil
+	}
+	name := w.name()
+	got, recorded, err := w.checkpoint.LoadVersion(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !recorded {
+		return w.checkpoint.SaveVersion(ctx, name, want)
+	}
+	if got == want {
+		return nil
+	}
+
+	if _, ok := w.subscriber.(ReadModel); ok && d.config.autoRebuild {
+		d.store.Logger().Info("projection version changed, rebuilding",
+			"projection", name, "recorded_version", got, "version", want)
+		return d.rebuild(ctx, w.subscriber.Name(), w)
+	}
+	d.store.Logger().Warn("projection outdated, rebuild it or accept the version",
+		"projection", name, "recorded_version", got, "version", want)
+	_, status, err := w.checkpoint.Load(ctx, name)
+	if err != nil {
+		return err
+	}
+	if status != "running" {
+		return nil
+	}
+	return w.checkpoint.SetStatus(ctx, name, "outdated")
+}
+
+// AcceptVersion records the version the named subscriber declares as the
+// one its read model was built with, and clears an "outdated" status, for
+// version changes that need no rebuild.
+func (d *Daemon) AcceptVersion(ctx context.Context, name string) error {
+	sub, err := d.findSubscriber(name)
+	if err != nil {
+		return err
+	}
+	w := d.newWorker(sub)
+	version := subscriberVersion(w.subscriber)
+	if version <= 0 {
+		return fmt.Errorf("daemon: accept version %s: subscriber is not versioned", name)
+	}
+	cs := NewCheckpointStore(d.store)
+	if err := cs.SaveVersion(ctx, w.name(), version); err != nil {
+		return fmt.Errorf("daemon: accept version %s: %w", name, err)
+	}
+	_, status, err := cs.Load(ctx, w.name())
+	if err != nil {
+		return fmt.Errorf("daemon: accept version %s: %w", name, err)
+	}
+	if status == "outdated" {
+		if err := cs.SetStatus(ctx, w.name(), "running"); err != nil {
+			return fmt.Errorf("daemon: accept version %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// flushCheckpoint saves any position deferred by checkpoint batching. It runs
+// before the lock is released so the next holder starts from it.
+func flushCheckpoint(ctx context.Context, w *Worker) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lockReleaseTimeout)
+	defer cancel()
+	if err := w.FlushCheckpoint(ctx); err != nil {
+		w.store.Logger().Error("flush checkpoint", "worker", w.name(), "error", err)
+	}
+}
+
+// releaseLock releases the worker's advisory lock even when ctx is already
+// cancelled, so a stopping daemon does not leave the lock to time out with
+// its connection.
+func releaseLock(ctx context.Context, w *Worker) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lockReleaseTimeout)
+	defer cancel()
+	if err := w.ReleaseLock(ctx); err != nil {
+		w.store.Logger().Error("release lock", "worker", w.name(), "error", err)
+	}
+}
+
 func (d *Daemon) findSubscriber(name string) (Subscriber, error) {
 	for _, s := range d.subscribers {
 		if s.Name() == name {
@@ -134,18 +481,27 @@ func (d *Daemon) findSubscriber(name string) (Subscriber, error) {
 	return nil, fmt.Errorf("daemon: subscriber %q not found", name)
 }
 
-func (d *Daemon) Rebuild(ctx context.Context, name string) error {
-	if err := schema.ValidateCollectionName(name); err != nil {
-		return fmt.Errorf("daemon: rebuild: %w", err)
+// Rebuild drops the read model table for the named projection, resets its
+// checkpoint to zero, and replays all events from the beginning. Every shard
+// of a sharded projection is dropped. A Versioned
+// projection's current version is recorded once the replay is done. It fails
+// immediately if another instance holds the projection's lock; use
+// RebuildWait to wait for it.
+//
+// Only a ReadModel can be rebuilt: replaying a handler or link would repeat
+// every side effect or emitted event, so they fail with ErrNotReadModel
+// before anything is dropped. See ResetHandler to reprocess part of their
+// events on purpose.
+func (d *Daemon) Rebuild(ctx context.Context, name string, opts ...RebuildOption) error {
+	cfg := rebuildOptions(opts)
+	if cfg.plan != nil {
+		return d.dryRun(ctx, name, cfg.plan)
 	}
-
-	sub, err := d.findSubscriber(name)
+	w, err := d.rebuildWorker(name)
 	if err != nil {
 		return err
 	}
 
-	w := NewWorker(d.store, sub)
-
 	acquired, err := w.TryAcquireLock(ctx)
 	if err != nil {
 		return fmt.Errorf("daemon: rebuild %s: acquire lock: %w", name, err)
@@ -153,34 +509,171 @@ func (d *Daemon) Rebuild(ctx context.Context, name string) error {
 	if !acquired {
 		return fmt.Errorf("daemon: rebuild %s: another instance holds the lock", name)
 	}
-	defer func() {
-		if err := w.ReleaseLock(ctx); err != nil {
-			slog.Error("release lock", "worker", name, "error", err)
+	defer releaseLock(ctx, w)
+	return d.rebuild(ctx, name, w)
+}
+
+// ErrNotReadModel is returned by Rebuild and RebuildWait for subscribers
+// that are not a ReadModel.
+var ErrNotReadModel = errors.New("projections: not a read-model projection")
+
+// ErrLockTimeout is returned by RebuildWait when the projection's lock is not
+// released within the timeout.
+var ErrLockTimeout = errors.New("projections: lock wait timed out")
+
+// RebuildOption configures RebuildWait.
+type RebuildOption func(*rebuildConfig)
+
+type rebuildConfig struct {
+	force bool
+	plan  *RebuildPlan
+}
+
+func rebuildOptions(opts []RebuildOption) rebuildConfig {
+	var cfg rebuildConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return cfg
+}
+
+// dryRun fills plan for DryRun.
+func (d *Daemon) dryRun(ctx context.Context, name string, plan *RebuildPlan) error {
+	p, err := d.PlanRebuild(ctx, name)
+	if err != nil {
+		return err
+	}
+	*plan = *p
+	return nil
+}
+
+// WithForce makes RebuildWait ask the instance holding the projection's lock
+// to stop, by setting the projection's checkpoint status to "stopped": its
+// worker finishes the batch in hand and releases the lock. If the lock is
+// still not acquired, the previous status is restored.
+func WithForce() RebuildOption {
+	return func(c *rebuildConfig) { c.force = true }
+}
+
+// RebuildWait is Rebuild, but waits up to timeout for another instance to
+// release the projection's lock instead of failing at once. The wait is
+// fair: a waiting RebuildWait gets the lock before instances that try for it
+// later. A timeout of zero or less waits until ctx is done. Returns an error
+// wrapping ErrLockTimeout if the lock is not acquired in time; the timeout
+// does not bound the rebuild itself.
+func (d *Daemon) RebuildWait(ctx context.Context, name string, timeout time.Duration, opts ...RebuildOption) error {
+	cfg := rebuildOptions(opts)
+	if cfg.plan != nil {
+		return d.dryRun(ctx, name, cfg.plan)
+	}
+	w, err := d.rebuildWorker(name)
+	if err != nil {
+		return err
+	}
+
+	cs := NewCheckpointStore(d.store)
+	var prevStatus string
+	if cfg.force {
+		if _, prevStatus, err = cs.Load(ctx, w.name()); err != nil {
+			return fmt.Errorf("daemon: rebuild %s: %w", name, err)
+		}
+		if err := cs.SetStatus(ctx, w.name(), "stopped"); err != nil {
+			return fmt.Errorf("daemon: rebuild %s: stop holder: %w", name, err)
 		}
-	}()
+	}
 
-	exec := d.store.DBExecutor()
+	lockCtx, cancel := context.WithCancel(ctx)
+	if timeout > 0 {
+		cancel()
+		lockCtx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	err = w.AcquireLock(lockCtx)
+	timedOut := errors.Is(lockCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+	cancel()
+	if err != nil {
+		if cfg.force {
+			restoreCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lockReleaseTimeout)
+			if serr := cs.SetStatus(restoreCtx, w.name(), prevStatus); serr != nil {
+				d.store.Logger().Error("restore projection status", "projection", name, "error", serr)
+			}
+			cancel()
+		}
+		if timedOut {
+			return fmt.Errorf("daemon: rebuild %s: lock not released within %s: %w", name, timeout, ErrLockTimeout)
+		}
+		return fmt.Errorf("daemon: rebuild %s: %w", name, err)
+	}
+	defer releaseLock(ctx, w)
+	return d.rebuild(ctx, name, w)
+}
 
-	_, err = exec.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS whisker_%s", name))
+// rebuildWorker returns a worker for the named subscriber to rebuild with.
+func (d *Daemon) rebuildWorker(name string) (*Worker, error) {
+	if err := schema.ValidateCollectionName(name); err != nil {
+		return nil, fmt.Errorf("daemon: rebuild: %w", err)
+	}
+	sub, err := d.findSubscriber(name)
 	if err != nil {
-		return fmt.Errorf("daemon: drop table whisker_%s: %w", name, err)
+		return nil, err
+	}
+	w := d.newWorker(sub)
+	if _, ok := w.subscriber.(ReadModel); !ok {
+		return nil, fmt.Errorf("daemon: rebuild %s: %w", name, ErrNotReadModel)
 	}
+	return w, nil
+}
+
+// rebuild recreates the read model and replays every event through w, which
+// holds the projection's lock.
+func (d *Daemon) rebuild(ctx context.Context, name string, w *Worker) error {
+	exec := d.store.DBExecutor()
 
-	d.store.SchemaBootstrap().InvalidateTable("whisker_" + name)
-	if err := d.store.SchemaBootstrap().EnsureCollection(ctx, exec, name); err != nil {
-		return fmt.Errorf("daemon: recreate table whisker_%s: %w", name, err)
+	for _, table := range readModelNames(w.subscriber) {
+		if d.store.SchemaBootstrap().AutoMigrate() {
+			_, err := exec.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS whisker_%s", table))
+			if err != nil {
+				return fmt.Errorf("daemon: drop table whisker_%s: %w", table, err)
+			}
+
+			d.store.SchemaBootstrap().InvalidateTable("whisker_" + table)
+			if err := d.store.SchemaBootstrap().EnsureCollection(ctx, exec, table); err != nil {
+				return fmt.Errorf("daemon: recreate table whisker_%s: %w", table, err)
+			}
+		} else {
+			// the schema is managed externally, so keep the table and empty it
+			if _, err := exec.Exec(ctx, fmt.Sprintf("TRUNCATE TABLE whisker_%s", table)); err != nil {
+				return fmt.Errorf("daemon: truncate table whisker_%s: %w", table, err)
+			}
+		}
 	}
 
 	cs := NewCheckpointStore(d.store)
-	if err := cs.Reset(ctx, name); err != nil {
+	if err := cs.Reset(ctx, w.name()); err != nil {
 		return fmt.Errorf("daemon: reset checkpoint %s: %w", name, err)
 	}
 
+	if err := d.replaySnapshot(ctx, w); err != nil {
+		return fmt.Errorf("daemon: rebuild %s: %w", name, err)
+	}
+	if err := w.FlushCheckpoint(ctx); err != nil {
+		return fmt.Errorf("daemon: rebuild %s: %w", name, err)
+	}
+	if err := cs.SetStatus(ctx, w.name(), "running"); err != nil {
+		return fmt.Errorf("daemon: rebuild %s set status: %w", name, err)
+	}
+	if v := subscriberVersion(w.subscriber); v > 0 {
+		if err := cs.SaveVersion(ctx, w.name(), v); err != nil {
+			return fmt.Errorf("daemon: rebuild %s: %w", name, err)
+		}
+	}
+
+	// catch up incrementally on the events appended during the replay; the
+	// projection is already running again
 	for {
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
-		n, err := w.ProcessBatch(ctx)
+		n, err := w.processBatchTimed(ctx)
 		if err != nil {
 			return fmt.Errorf("daemon: rebuild %s: %w", name, err)
 		}
@@ -188,10 +681,54 @@ func (d *Daemon) Rebuild(ctx context.Context, name string) error {
 			break
 		}
 	}
+	if err := w.FlushCheckpoint(ctx); err != nil {
+		return fmt.Errorf("daemon: rebuild %s: %w", name, err)
+	}
+	return nil
+}
+
+// replaySnapshot is the bulk phase of a rebuild: it captures the head
+// position in a REPEATABLE READ snapshot and replays every event up to it
+// from that snapshot, in batches, so events appended meanwhile neither slow
+// the replay down nor keep it from finishing. Read-model writes and
+// checkpoints go through the store as usual.
+func (d *Daemon) replaySnapshot(ctx context.Context, w *Worker) error {
+	sess, err := d.store.Session(ctx, whisker.WithIsolation(whisker.RepeatableRead))
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	defer func() { _ = sess.Close(ctx) }()
 
-	if err := cs.SetStatus(ctx, name, "running"); err != nil {
-		return fmt.Errorf("daemon: rebuild %s set status: %w", name, err)
+	reader := events.NewNamed(sess, w.eventStore)
+	head, err := reader.HeadPosition(ctx)
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
 	}
 
+	var pos int64
+	for pos < head {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := pg.CheckWrite(ctx, d.store.DBExecutor()); err != nil {
+			return err
+		}
+		evts, err := reader.ReadAll(ctx, pos, d.config.batchSize)
+		if err != nil {
+			return fmt.Errorf("snapshot: %w", err)
+		}
+		if len(evts) == 0 {
+			break
+		}
+		start := time.Now()
+		n, err := w.processEvents(ctx, evts)
+		if err != nil {
+			return err
+		}
+		if w.throughput != nil {
+			w.throughput.record(n, time.Since(start))
+		}
+		pos = evts[len(evts)-1].GlobalPosition
+	}
 	return nil
 }
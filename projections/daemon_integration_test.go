@@ -3,11 +3,17 @@
 package projections_test
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ripkitten-co/whisker/documents"
 	"github.com/ripkitten-co/whisker/events"
 	"github.com/ripkitten-co/whisker/projections"
 )
@@ -92,6 +98,60 @@ func TestDaemon_SideEffectHandler(t *testing.T) {
 	}
 }
 
+func TestDaemon_CheckpointBatchingFlushesEachDrainCycle(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+	cs := projections.NewCheckpointStore(store)
+
+	var handled atomic.Int32
+	handler := projections.NewHandler("daemon_batched")
+	handler.On("OrderPaid", func(ctx context.Context, evt events.Event) error {
+		handled.Add(1)
+		return nil
+	})
+
+	daemon := projections.NewDaemon(store,
+		projections.WithPollingInterval(50*time.Millisecond),
+		projections.WithCheckpointBatching(100, time.Hour),
+	)
+	daemon.Add(handler)
+
+	runCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	go daemon.Run(runCtx)
+
+	// each event arrives in its own drain cycle, which ends caught up and
+	// saves its position although far fewer than 100 events were handled
+	for i := range 2 {
+		if err := es.Append(ctx, fmt.Sprintf("order-batched-%d", i), 0, []events.Event{{Type: "OrderPaid", Data: []byte(`{}`)}}); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+		head, err := es.HeadPosition(ctx)
+		if err != nil {
+			t.Fatalf("head: %v", err)
+		}
+		deadline := time.After(3 * time.Second)
+		for {
+			pos, _, err := cs.Load(ctx, "daemon_batched")
+			if err != nil {
+				t.Fatalf("load checkpoint: %v", err)
+			}
+			if pos == head {
+				break
+			}
+			select {
+			case <-deadline:
+				t.Fatalf("event %d: checkpoint at %d, want %d saved at the end of the cycle", i, pos, head)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	if got := handled.Load(); got != 2 {
+		t.Errorf("handled %d events, want 2", got)
+	}
+}
+
 func TestDaemon_Rebuild(t *testing.T) {
 	store := setupStore(t)
 	ctx := context.Background()
@@ -153,3 +213,705 @@ func TestDaemon_Rebuild(t *testing.T) {
 		t.Errorf("status after rebuild: got %q, want %q", status, "running")
 	}
 }
+
+func TestDaemon_RebuildWait(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	if err := events.New(store).Append(ctx, "order-rw", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{"id":"order-rw","status":"created","total":0}`)},
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	proj := projections.New[OrderSummary](store, "daemon_rebuild_wait")
+	proj.On("OrderCreated", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+		return &OrderSummary{ID: evt.StreamID, Status: "created"}, nil
+	})
+	daemon := projections.NewDaemon(store)
+	daemon.Add(proj)
+
+	// another instance holds the projection's lock
+	holder := projections.NewWorker(store, proj)
+	if err := holder.AcquireLock(ctx); err != nil {
+		t.Fatalf("hold lock: %v", err)
+	}
+
+	err := daemon.RebuildWait(ctx, "daemon_rebuild_wait", 100*time.Millisecond)
+	if !errors.Is(err, projections.ErrLockTimeout) {
+		t.Fatalf("got %v, want ErrLockTimeout", err)
+	}
+
+	time.AfterFunc(200*time.Millisecond, func() { _ = holder.ReleaseLock(context.Background()) })
+	if err := daemon.RebuildWait(ctx, "daemon_rebuild_wait", 5*time.Second); err != nil {
+		t.Fatalf("rebuild after release: %v", err)
+	}
+	doc, err := documents.Collection[OrderSummary](store, "daemon_rebuild_wait").Load(ctx, "order-rw")
+	if err != nil || doc.Status != "created" {
+		t.Fatalf("read model after rebuild: %+v, %v", doc, err)
+	}
+}
+
+func TestDaemon_RebuildWaitForceStopsHolder(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	if err := events.New(store).Append(ctx, "order-rf", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{"id":"order-rf","status":"created","total":0}`)},
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	proj := projections.New[OrderSummary](store, "daemon_rebuild_force")
+	proj.On("OrderCreated", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+		return &OrderSummary{ID: evt.StreamID, Status: "created"}, nil
+	})
+
+	// the holder releases its lock once it sees the stopped status, as a
+	// running daemon's worker does after its current batch
+	holder := projections.NewWorker(store, proj)
+	if err := holder.AcquireLock(ctx); err != nil {
+		t.Fatalf("hold lock: %v", err)
+	}
+	cs := projections.NewCheckpointStore(store)
+	go func() {
+		for {
+			if _, status, err := cs.Load(ctx, "daemon_rebuild_force"); err == nil && status == "stopped" {
+				_ = holder.ReleaseLock(ctx)
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	daemon := projections.NewDaemon(store)
+	daemon.Add(proj)
+	if err := daemon.RebuildWait(ctx, "daemon_rebuild_force", 5*time.Second, projections.WithForce()); err != nil {
+		t.Fatalf("forced rebuild: %v", err)
+	}
+	if _, status, err := cs.Load(ctx, "daemon_rebuild_force"); err != nil || status != "running" {
+		t.Errorf("status after forced rebuild: got %q, %v; want running", status, err)
+	}
+}
+
+func TestDaemon_RebuildReplaysSnapshotThenCatchesUp(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+	cs := projections.NewCheckpointStore(store)
+
+	if err := es.Append(ctx, "tick-0", 0, []events.Event{{Type: "Tick", Data: []byte(`{}`)}}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	// each tick appends the next while the rebuild runs, up to three; the
+	// status seen by each shows which phase processed it
+	var statuses []string
+	proj := projections.New[OrderSummary](store, "daemon_rebuild_phases")
+	proj.On("Tick", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+		_, status, err := cs.Load(ctx, "daemon_rebuild_phases")
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+		if n := len(statuses); n < 3 {
+			err = es.Append(ctx, fmt.Sprintf("tick-%d", n), 0, []events.Event{{Type: "Tick", Data: []byte(`{}`)}})
+		}
+		return &OrderSummary{ID: evt.StreamID}, err
+	})
+	daemon := projections.NewDaemon(store)
+	daemon.Add(proj)
+
+	if err := daemon.Rebuild(ctx, "daemon_rebuild_phases"); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	want := []string{"rebuilding", "running", "running"}
+	if !slices.Equal(statuses, want) {
+		t.Errorf("statuses: got %v, want %v", statuses, want)
+	}
+	pos, status, err := cs.Load(ctx, "daemon_rebuild_phases")
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+	head, _ := es.HeadPosition(ctx)
+	if status != "running" || pos != head {
+		t.Errorf("checkpoint: got %d %q, want %d running", pos, status, head)
+	}
+}
+
+func TestDaemon_RebuildDryRun(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	if err := events.New(store).Append(ctx, "order-dry", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{"id":"order-dry","status":"created","total":0}`)},
+		{Type: "OrderNoted", Data: []byte(`{}`)},
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	proj := projections.New[OrderSummary](store, "daemon_rebuild_dry")
+	proj.On("OrderCreated", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+		return &OrderSummary{ID: evt.StreamID, Status: "created"}, nil
+	})
+	daemon := projections.NewDaemon(store)
+	daemon.Add(proj)
+	if err := daemon.Rebuild(ctx, "daemon_rebuild_dry"); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+
+	var plan projections.RebuildPlan
+	if err := daemon.Rebuild(ctx, "daemon_rebuild_dry", projections.DryRun(&plan)); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if plan.Events != 1 || plan.HeadPosition == 0 || plan.Table != "whisker_daemon_rebuild_dry" || plan.Truncate {
+		t.Errorf("unexpected plan: %+v", plan)
+	}
+	if plan.EstimatedDuration <= 0 {
+		t.Errorf("expected an estimate from the rebuild's throughput, got %s", plan.EstimatedDuration)
+	}
+	if len(plan.Indexes) == 0 || plan.Indexes[0] != "whisker_daemon_rebuild_dry_pkey" {
+		t.Errorf("indexes: got %v, want the primary key", plan.Indexes)
+	}
+
+	// the read model is untouched
+	if _, err := documents.Collection[OrderSummary](store, "daemon_rebuild_dry").Load(ctx, "order-dry"); err != nil {
+		t.Errorf("read model changed by dry run: %v", err)
+	}
+}
+
+func TestDaemon_StatusReportsOwner(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	var fired atomic.Bool
+	handler := projections.NewHandler("daemon_owned")
+	handler.On("OrderPaid", func(ctx context.Context, evt events.Event) error {
+		fired.Store(true)
+		return nil
+	})
+	if err := events.New(store).Append(ctx, "order-owned", 0, []events.Event{
+		{Type: "OrderPaid", Data: []byte(`{}`)},
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	daemon := projections.NewDaemon(store,
+		projections.WithPollingInterval(50*time.Millisecond),
+		projections.WithInstanceID("worker-a"),
+	)
+	daemon.Add(handler)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		daemon.Run(runCtx)
+		close(done)
+	}()
+
+	deadline := time.After(2 * time.Second)
+	for !fired.Load() {
+		select {
+		case <-deadline:
+			t.Fatal("timed out waiting for handler")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	status, err := daemon.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if len(status) != 1 || status[0].Owner == nil || status[0].Owner.InstanceID != "worker-a" {
+		t.Fatalf("got %+v, want daemon_owned owned by worker-a", status)
+	}
+
+	cancel()
+	<-done
+	status, err = daemon.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if status[0].Owner != nil {
+		t.Errorf("owner not cleared after Run returned: %+v", status[0].Owner)
+	}
+}
+
+func TestDaemon_WithEventStore(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	err := events.NewNamed(store, "billing").Append(ctx, "invoice-1", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{}`)},
+	})
+	if err != nil {
+		t.Fatalf("append billing: %v", err)
+	}
+	err = events.New(store).Append(ctx, "order-1", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{}`)},
+		{Type: "OrderCreated", Data: []byte(`{}`)},
+	})
+	if err != nil {
+		t.Fatalf("append default: %v", err)
+	}
+
+	var seen atomic.Int64
+	h := projections.NewHandler("billing_mailer")
+	h.On("OrderCreated", func(ctx context.Context, evt events.Event) error {
+		if evt.StreamID != "invoice-1" {
+			t.Errorf("handled event from the default store: %+v", evt)
+		}
+		seen.Add(1)
+		return nil
+	})
+
+	daemon := projections.NewDaemon(store,
+		projections.WithEventStore("billing"),
+		projections.WithPollingInterval(50*time.Millisecond),
+	)
+	daemon.Add(h)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() { daemon.Run(runCtx); close(done) }()
+
+	deadline := time.After(2 * time.Second)
+	for seen.Load() < 1 {
+		select {
+		case <-deadline:
+			t.Fatal("timed out waiting for the billing event")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	pos, _, err := projections.NewCheckpointStore(store).Load(ctx, "billing:billing_mailer")
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+	if pos != 1 {
+		t.Errorf("checkpoint: got %d, want 1", pos)
+	}
+	if seen.Load() != 1 {
+		t.Errorf("handled %d events, want 1", seen.Load())
+	}
+}
+
+func TestDaemon_SharedPolling(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	err := events.New(store).Append(ctx, "order-sp1", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{}`)},
+		{Type: "OrderShipped", Data: []byte(`{}`)},
+	})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	var created, shipped atomic.Int64
+	daemon := projections.NewDaemon(store,
+		projections.WithSharedPolling(),
+		projections.WithPollingInterval(50*time.Millisecond),
+	)
+	for i := range 3 {
+		h := projections.NewHandler(fmt.Sprintf("shared_created_%d", i))
+		h.On("OrderCreated", func(context.Context, events.Event) error { created.Add(1); return nil })
+		daemon.Add(h)
+	}
+	shipper := projections.NewHandler("shared_shipped")
+	shipper.On("OrderShipped", func(context.Context, events.Event) error { shipped.Add(1); return nil })
+	daemon.Add(shipper)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() { daemon.Run(runCtx); close(done) }()
+
+	deadline := time.After(2 * time.Second)
+	for created.Load() < 3 || shipped.Load() < 1 {
+		select {
+		case <-deadline:
+			t.Fatalf("timed out: created=%d shipped=%d", created.Load(), shipped.Load())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	cs := projections.NewCheckpointStore(store)
+	for _, name := range []string{"shared_created_0", "shared_created_1", "shared_created_2", "shared_shipped"} {
+		pos, _, err := cs.Load(ctx, name)
+		if err != nil {
+			t.Fatalf("load %s: %v", name, err)
+		}
+		if pos != 2 {
+			t.Errorf("%s checkpoint: got %d, want 2", name, pos)
+		}
+	}
+	if created.Load() != 3 || shipped.Load() != 1 {
+		t.Errorf("created=%d shipped=%d, want 3 and 1", created.Load(), shipped.Load())
+	}
+}
+
+type FeedUser struct {
+	ID      string
+	Name    string
+	Version int
+}
+
+type UserName struct {
+	ID   string `whisker:"id"`
+	Name string
+}
+
+func TestDaemon_CollectionChangeFeed(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	users := documents.Collection[FeedUser](store, "feed_users", documents.WithChangeFeed())
+
+	alice := &FeedUser{ID: "u1", Name: "Alice"}
+	if err := users.Insert(ctx, alice); err != nil {
+		t.Fatalf("insert u1: %v", err)
+	}
+	alice.Name = "Alicia"
+	if err := users.Update(ctx, alice); err != nil {
+		t.Fatalf("update u1: %v", err)
+	}
+	if err := users.Insert(ctx, &FeedUser{ID: "u2", Name: "Bob"}); err != nil {
+		t.Fatalf("insert u2: %v", err)
+	}
+	if err := users.Delete(ctx, "u2"); err != nil {
+		t.Fatalf("delete u2: %v", err)
+	}
+
+	upsert := func(ctx context.Context, evt events.Event, _ *UserName) (*UserName, error) {
+		u, err := users.DecodeChange(evt)
+		if err != nil {
+			return nil, err
+		}
+		return &UserName{ID: u.ID, Name: u.Name}, nil
+	}
+	names := projections.New[UserName](store, "user_names")
+	names.On(documents.DocumentInserted, upsert)
+	names.On(documents.DocumentUpdated, upsert)
+	names.On(documents.DocumentDeleted, func(context.Context, events.Event, *UserName) (*UserName, error) {
+		return nil, nil
+	})
+
+	daemon := projections.NewDaemon(store, projections.WithPollingInterval(50*time.Millisecond))
+	daemon.Add(projections.FromEventStore(documents.ChangeFeed("feed_users"), names))
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() { daemon.Run(runCtx); close(done) }()
+
+	cs := projections.NewCheckpointStore(store)
+	deadline := time.After(2 * time.Second)
+	for {
+		pos, _, err := cs.Load(ctx, "feed_users_changes:user_names")
+		if err != nil {
+			t.Fatalf("load checkpoint: %v", err)
+		}
+		if pos == 4 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("timed out at checkpoint %d, want 4", pos)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	views := documents.Collection[UserName](store, "user_names")
+	got, err := views.Load(ctx, "u1")
+	if err != nil {
+		t.Fatalf("load u1: %v", err)
+	}
+	if got.Name != "Alicia" {
+		t.Errorf("u1: got %+v, want Alicia", got)
+	}
+	if ok, err := views.Exists(ctx, "u2"); err != nil || ok {
+		t.Errorf("u2 should be deleted: exists=%v err=%v", ok, err)
+	}
+}
+
+func TestDecommission(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	views := documents.Collection[OrderSummary](store, "retired_summaries")
+	if err := views.Insert(ctx, &OrderSummary{ID: "o1", Status: "created"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	cs := projections.NewCheckpointStore(store)
+	if err := cs.Save(ctx, "retired_summaries", 7); err != nil {
+		t.Fatalf("save checkpoint: %v", err)
+	}
+
+	// without DropReadModel the table stays
+	if err := projections.Decommission(ctx, store, "retired_summaries"); err != nil {
+		t.Fatalf("decommission: %v", err)
+	}
+	if _, err := views.Load(ctx, "o1"); err != nil {
+		t.Errorf("table dropped without DropReadModel: %v", err)
+	}
+
+	retired := projections.New[OrderSummary](store, "retired_summaries")
+	if err := projections.Decommission(ctx, store, "retired_summaries", projections.DropReadModel(retired)); err != nil {
+		t.Fatalf("decommission: %v", err)
+	}
+	var exists bool
+	err := store.DBExecutor().QueryRow(ctx, "SELECT to_regclass('whisker_retired_summaries') IS NOT NULL").Scan(&exists)
+	if err != nil {
+		t.Fatalf("check table: %v", err)
+	}
+	if exists {
+		t.Error("read model table still exists")
+	}
+
+	cps, err := cs.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var found bool
+	for _, cp := range cps {
+		if cp.Name != "retired_summaries" {
+			continue
+		}
+		found = true
+		var rec projections.Decommissioned
+		if err := json.Unmarshal(cp.Metadata, &rec); err != nil {
+			t.Fatalf("decommission record: %v", err)
+		}
+		if cp.Status != projections.StatusDecommissioned || cp.Position != 0 || rec.Position != 7 || rec.At.IsZero() ||
+			len(rec.DroppedTables) != 1 || rec.DroppedTables[0] != "whisker_retired_summaries" {
+			t.Errorf("checkpoint: %+v, record %+v", cp, rec)
+		}
+	}
+	if !found {
+		t.Error("no decommission record")
+	}
+}
+
+func TestDaemon_ResetHandler(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	if err := es.Append(ctx, "order-reset", 0, []events.Event{
+		{Type: "OrderPaid", Data: []byte(`{"amount":10}`)},
+		{Type: "OrderPaid", Data: []byte(`{"amount":20}`)},
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	// a collection sharing the handler's name must survive a refused rebuild
+	docs := documents.Collection[OrderSummary](store, "daemon_reset_mailer")
+	if err := docs.Insert(ctx, &OrderSummary{ID: "o1", Status: "created"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	var sent atomic.Int32
+	handler := projections.NewHandler("daemon_reset_mailer")
+	handler.On("OrderPaid", func(ctx context.Context, evt events.Event) error {
+		sent.Add(1)
+		return nil
+	})
+	daemon := projections.NewDaemon(store, projections.WithPollingInterval(50*time.Millisecond))
+	daemon.Add(handler)
+
+	if err := daemon.Rebuild(ctx, "daemon_reset_mailer"); !errors.Is(err, projections.ErrNotReadModel) {
+		t.Fatalf("rebuild: got %v, want ErrNotReadModel", err)
+	}
+	if _, err := docs.Load(ctx, "o1"); err != nil {
+		t.Fatalf("collection touched by refused rebuild: %v", err)
+	}
+
+	runUntil := func(want int32) {
+		t.Helper()
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			daemon.Run(runCtx)
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+		deadline := time.After(3 * time.Second)
+		for sent.Load() < want {
+			select {
+			case <-deadline:
+				t.Fatalf("sent %d, want %d", sent.Load(), want)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	runUntil(2)
+
+	if err := daemon.ResetHandler(ctx, "daemon_reset_mailer", 0); !errors.Is(err, projections.ErrResetNotConfirmed) {
+		t.Fatalf("unconfirmed reset: got %v, want ErrResetNotConfirmed", err)
+	}
+	if err := daemon.ResetHandler(ctx, "daemon_reset_mailer", 0, projections.ConfirmReplay("daemon_reset_mailer")); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	pos, status, err := projections.NewCheckpointStore(store).Load(ctx, "daemon_reset_mailer")
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+	if pos != 0 || status != "running" {
+		t.Errorf("checkpoint: got %d %q, want 0 running", pos, status)
+	}
+	runUntil(4)
+}
+
+func TestDaemon_VersionChange(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+	cs := projections.NewCheckpointStore(store)
+
+	err := es.Append(ctx, "order-v1", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{"id":"order-v1"}`)},
+	})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	var applied atomic.Int64
+	versioned := func(v int) *projections.Projection[OrderSummary] {
+		return projections.New[OrderSummary](store, "versioned_summaries").WithVersion(v).
+			On("OrderCreated", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+				applied.Add(1)
+				return &OrderSummary{ID: evt.StreamID, Status: fmt.Sprintf("v%d", v)}, nil
+			})
+	}
+	// runUntil runs a daemon with proj until done reports true
+	runUntil := func(proj projections.Subscriber, done func() bool, opts ...projections.DaemonOption) {
+		t.Helper()
+		daemon := projections.NewDaemon(store, append(opts, projections.WithPollingInterval(50*time.Millisecond))...)
+		daemon.Add(proj)
+		runCtx, cancel := context.WithCancel(ctx)
+		stopped := make(chan struct{})
+		go func() { daemon.Run(runCtx); close(stopped) }()
+		defer func() { cancel(); <-stopped }()
+
+		deadline := time.After(3 * time.Second)
+		for !done() {
+			select {
+			case <-deadline:
+				t.Fatal("timed out")
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	checkpoint := func() (string, int) {
+		_, status, err := cs.Load(ctx, "versioned_summaries")
+		if err != nil {
+			t.Fatalf("load checkpoint: %v", err)
+		}
+		version, _, err := cs.LoadVersion(ctx, "versioned_summaries")
+		if err != nil {
+			t.Fatalf("load version: %v", err)
+		}
+		return status, version
+	}
+
+	runUntil(versioned(1), func() bool { _, v := checkpoint(); return applied.Load() == 1 && v == 1 })
+
+	// a new version without auto-rebuild is flagged, not replayed
+	applied.Store(0)
+	runUntil(versioned(2), func() bool { s, _ := checkpoint(); return s == "outdated" })
+	if applied.Load() != 0 {
+		t.Errorf("outdated projection replayed %d events", applied.Load())
+	}
+
+	daemon := projections.NewDaemon(store)
+	daemon.Add(versioned(2))
+	if err := daemon.AcceptVersion(ctx, "versioned_summaries"); err != nil {
+		t.Fatalf("accept version: %v", err)
+	}
+	if status, version := checkpoint(); status != "running" || version != 2 {
+		t.Errorf("after accept: status %q version %d, want running 2", status, version)
+	}
+
+	// with auto-rebuild the read model is rebuilt from the first event
+	runUntil(versioned(3), func() bool { _, v := checkpoint(); return applied.Load() == 1 && v == 3 },
+		projections.WithAutoRebuild())
+	got, err := documents.Collection[OrderSummary](store, "versioned_summaries").Load(ctx, "order-v1")
+	if err != nil {
+		t.Fatalf("load read model: %v", err)
+	}
+	if got.Status != "v3" {
+		t.Errorf("read model status %q, want v3", got.Status)
+	}
+}
+
+func TestDaemon_ShardedProjection(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	for i := range 8 {
+		id := fmt.Sprintf("order-sh%d", i)
+		err := es.Append(ctx, id, 0, []events.Event{
+			{Type: "OrderCreated", Data: []byte(fmt.Sprintf(`{"total":%d}`, i))},
+		})
+		if err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	proj := projections.New[OrderSummary](store, "sharded_orders").Sharded(4).
+		On("OrderCreated", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+			var data struct{ Total float64 }
+			if err := json.Unmarshal(evt.Data, &data); err != nil {
+				return nil, err
+			}
+			return &OrderSummary{ID: evt.StreamID, Status: "created", Total: data.Total}, nil
+		})
+	if _, err := projections.NewWorker(store, proj).ProcessBatch(ctx); err != nil {
+		t.Fatalf("process batch: %v", err)
+	}
+
+	orders := documents.Sharded[OrderSummary](store, "sharded_orders", 4)
+	got, err := orders.Load(ctx, "order-sh5")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Total != 5 {
+		t.Errorf("got %+v", got)
+	}
+	for i, shard := range orders.Shards() {
+		n, err := shard.Count(ctx)
+		if err != nil {
+			t.Fatalf("count shard %d: %v", i, err)
+		}
+		if n == 8 {
+			t.Errorf("shard %d holds every document", i)
+		}
+	}
+
+	top, err := orders.Query(func(q *documents.Query[OrderSummary]) *documents.Query[OrderSummary] {
+		return q.Where("total", ">=", 2).OrderBy("total", documents.Desc).Limit(3)
+	}).SortFunc(func(a, b *OrderSummary) int { return cmp.Compare(b.Total, a.Total) }).Execute(ctx)
+	if err != nil {
+		t.Fatalf("sharded query: %v", err)
+	}
+	if len(top) != 3 || top[0].Total != 7 || top[1].Total != 6 || top[2].Total != 5 {
+		t.Errorf("top 3: got %+v", top)
+	}
+
+	daemon := projections.NewDaemon(store)
+	daemon.Add(proj)
+	if err := daemon.Rebuild(ctx, "sharded_orders"); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	n, err := orders.Query(nil).Count(ctx)
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != 8 {
+		t.Errorf("after rebuild: %d documents, want 8", n)
+	}
+}
@@ -0,0 +1,347 @@
+package projections
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// Definitions is the document format read by ParseDefinitions.
+//
+//	projections:
+//	  - name: order_status
+//	    events:
+//	      OrderCreated:
+//	        set:
+//	          customer: $.customer.name
+//	          status: created
+//	      OrderPaid:
+//	        set:
+//	          status: paid
+//	          paid_at: $created_at
+//	      OrderDeleted:
+//	        delete: true
+type Definitions struct {
+	Projections []Definition `json:"projections" yaml:"projections"`
+}
+
+// Definition declares a read-model projection without code. Each handled
+// event type maps to the fields it sets on the stream's document, or deletes
+// it. Version, when set, is bumped with every change to the mappings; see
+// Projection.WithVersion.
+type Definition struct {
+	Name       string                  `json:"name" yaml:"name"`
+	Tombstones bool                    `json:"tombstones,omitempty" yaml:"tombstones,omitempty"`
+	Version    int                     `json:"version,omitempty" yaml:"version,omitempty"`
+	Events     map[string]EventMapping `json:"events" yaml:"events"`
+}
+
+// EventMapping describes what one event type does to the read model.
+//
+// Set maps a top-level document field to a value. String values starting with
+// "$" are expressions:
+//
+//	$.a.b         field a.b of the event data; array elements by index, $.items.0
+//	$metadata.a   field a of the event metadata
+//	$stream_id    the event's stream ID
+//	$type         the event type
+//	$version      the event's version within its stream
+//	$position     the event's global position
+//	$created_at   the event timestamp
+//
+// Any other value, including a string starting with "$$" (written as a
+// literal "$..."), is copied as is. A missing path sets the field to null.
+// Numbers are handled as float64, as they are when documents are reloaded.
+type EventMapping struct {
+	Set    map[string]any `json:"set,omitempty" yaml:"set,omitempty"`
+	Delete bool           `json:"delete,omitempty" yaml:"delete,omitempty"`
+}
+
+// ParseDefinitions reads projection definitions from YAML or JSON and
+// validates them. Unknown keys are rejected so typos fail at startup.
+func ParseDefinitions(r io.Reader) ([]Definition, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	var defs Definitions
+	if err := dec.Decode(&defs); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("projections: parse definitions: %w", err)
+	}
+	for _, def := range defs.Projections {
+		if err := def.validate(); err != nil {
+			return nil, fmt.Errorf("projections: parse definitions: %w", err)
+		}
+	}
+	return defs.Projections, nil
+}
+
+func (def Definition) validate() error {
+	if err := schema.ValidateCollectionName(def.Name); err != nil {
+		return fmt.Errorf("projection %q: %w", def.Name, err)
+	}
+	if len(def.Events) == 0 {
+		return fmt.Errorf("projection %s: no events mapped", def.Name)
+	}
+	for typ, m := range def.Events {
+		if m.Delete && len(m.Set) > 0 {
+			return fmt.Errorf("projection %s: event %s: delete and set are exclusive", def.Name, typ)
+		}
+		if !m.Delete && len(m.Set) == 0 {
+			return fmt.Errorf("projection %s: event %s: nothing to set", def.Name, typ)
+		}
+		for field, v := range m.Set {
+			if field == "" {
+				return fmt.Errorf("projection %s: event %s: empty field name", def.Name, typ)
+			}
+			if _, err := parseExpr(v); err != nil {
+				return fmt.Errorf("projection %s: event %s: field %s: %w", def.Name, typ, field, err)
+			}
+		}
+	}
+	return nil
+}
+
+// DefinitionOption limits what a declarative projection, or one built from a
+// Module, may do. Definitions and modules often come from teams other than
+// the one running the daemon; limits keep a bad one from affecting anything
+// but its own projection.
+type DefinitionOption func(*definitionConfig)
+
+type definitionConfig struct {
+	maxDocumentSize int
+	maxFields       int
+	applyTimeout    time.Duration
+}
+
+func newDefinitionConfig(opts []DefinitionOption) definitionConfig {
+	cfg := definitionConfig{maxDocumentSize: 1 << 20, maxFields: 256, applyTimeout: time.Second}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return cfg
+}
+
+// WithMaxDocumentSize caps the encoded size in bytes of a projected document.
+// An event that would grow a document past it fails the batch, which counts
+// towards the projection's dead-letter limit. Defaults to 1 MiB; zero or
+// less disables the check.
+func WithMaxDocumentSize(n int) DefinitionOption {
+	return func(c *definitionConfig) { c.maxDocumentSize = n }
+}
+
+// WithMaxFields caps the number of top-level fields in a projected document,
+// with the same failure mode as WithMaxDocumentSize. Defaults to 256; zero or
+// less disables the check.
+func WithMaxFields(n int) DefinitionOption {
+	return func(c *definitionConfig) { c.maxFields = n }
+}
+
+// WithApplyTimeout caps how long a Module may take to apply one event, with
+// the same failure mode as WithMaxDocumentSize. Defaults to one second; zero
+// or less disables the check. Definitions, which cannot block, ignore it.
+func WithApplyTimeout(d time.Duration) DefinitionOption {
+	return func(c *definitionConfig) { c.applyTimeout = d }
+}
+
+// FromDefinition builds a projection from def. Documents are stored as JSON
+// objects in whisker_{name}, so they can be queried with a map or struct
+// collection. Definitions can only copy values from events into documents:
+// they cannot loop, call out or touch other projections, and the document
+// limits in opts bound the data they write.
+func FromDefinition(b whisker.Backend, def Definition, opts ...DefinitionOption) (*Projection[map[string]any], error) {
+	if err := def.validate(); err != nil {
+		return nil, fmt.Errorf("projections: %w", err)
+	}
+	cfg := newDefinitionConfig(opts)
+	p := New[map[string]any](b, def.Name).WithVersion(def.Version)
+	if def.Tombstones {
+		p.Tombstones()
+	}
+	for typ, m := range def.Events {
+		if m.Delete {
+			p.On(typ, func(context.Context, events.Event, *map[string]any) (*map[string]any, error) {
+				return nil, nil
+			})
+			continue
+		}
+		exprs := make(map[string]expr, len(m.Set))
+		for field, v := range m.Set {
+			e, _ := parseExpr(v)
+			exprs[field] = e
+		}
+		p.On(typ, func(_ context.Context, evt events.Event, state *map[string]any) (*map[string]any, error) {
+			doc := map[string]any{}
+			if state != nil && *state != nil {
+				doc = *state
+			}
+			for field, e := range exprs {
+				v, err := e.eval(evt)
+				if err != nil {
+					return nil, fmt.Errorf("field %s: %w", field, err)
+				}
+				doc[field] = v
+			}
+			if err := cfg.check(doc); err != nil {
+				return nil, err
+			}
+			return &doc, nil
+		})
+	}
+	return p, nil
+}
+
+func (c definitionConfig) check(doc map[string]any) error {
+	if err := c.checkFields(len(doc)); err != nil {
+		return err
+	}
+	if c.maxDocumentSize > 0 {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("encode document: %w", err)
+		}
+		return c.checkSize(len(data))
+	}
+	return nil
+}
+
+func (c definitionConfig) checkFields(n int) error {
+	if c.maxFields > 0 && n > c.maxFields {
+		return fmt.Errorf("document has %d fields, limit is %d", n, c.maxFields)
+	}
+	return nil
+}
+
+func (c definitionConfig) checkSize(n int) error {
+	if c.maxDocumentSize > 0 && n > c.maxDocumentSize {
+		return fmt.Errorf("document is %d bytes, limit is %d", n, c.maxDocumentSize)
+	}
+	return nil
+}
+
+// AddDefinitions builds a projection from each definition and registers it.
+// Call it before Run, typically with the result of ParseDefinitions. The
+// options apply to every definition. Unlike Add, it returns an error when a
+// read model is already written by a registered subscriber.
+func (d *Daemon) AddDefinitions(defs []Definition, opts ...DefinitionOption) error {
+	for _, def := range defs {
+		p, err := FromDefinition(d.store, def, opts...)
+		if err != nil {
+			return err
+		}
+		if err := d.claimReadModels(p); err != nil {
+			return err
+		}
+		d.subscribers = append(d.subscribers, p)
+	}
+	return nil
+}
+
+type exprSource int
+
+const (
+	exprLiteral exprSource = iota
+	exprData
+	exprMetadata
+	exprStreamID
+	exprType
+	exprVersion
+	exprPosition
+	exprCreatedAt
+)
+
+// expr is a parsed EventMapping value.
+type expr struct {
+	source  exprSource
+	path    []string
+	literal any
+}
+
+var exprRefs = map[string]exprSource{
+	"$stream_id":  exprStreamID,
+	"$type":       exprType,
+	"$version":    exprVersion,
+	"$position":   exprPosition,
+	"$created_at": exprCreatedAt,
+}
+
+func parseExpr(v any) (expr, error) {
+	s, ok := v.(string)
+	if !ok || !strings.HasPrefix(s, "$") {
+		return expr{source: exprLiteral, literal: v}, nil
+	}
+	if strings.HasPrefix(s, "$$") {
+		return expr{source: exprLiteral, literal: s[1:]}, nil
+	}
+	if src, ok := exprRefs[s]; ok {
+		return expr{source: src}, nil
+	}
+	for prefix, src := range map[string]exprSource{"$.": exprData, "$metadata.": exprMetadata} {
+		if rest, ok := strings.CutPrefix(s, prefix); ok {
+			path := strings.Split(rest, ".")
+			for _, seg := range path {
+				if seg == "" {
+					return expr{}, fmt.Errorf("invalid path %q", s)
+				}
+			}
+			return expr{source: src, path: path}, nil
+		}
+	}
+	return expr{}, fmt.Errorf("unknown expression %q", s)
+}
+
+func (e expr) eval(evt events.Event) (any, error) {
+	switch e.source {
+	case exprData:
+		return lookup(evt.Data, e.path)
+	case exprMetadata:
+		return lookup(evt.Metadata, e.path)
+	case exprStreamID:
+		return evt.StreamID, nil
+	case exprType:
+		return evt.Type, nil
+	case exprVersion:
+		return evt.Version, nil
+	case exprPosition:
+		return evt.GlobalPosition, nil
+	case exprCreatedAt:
+		return evt.CreatedAt, nil
+	default:
+		return e.literal, nil
+	}
+}
+
+// lookup walks path through a JSON document, returning nil when a segment is
+// missing.
+func lookup(data []byte, path []string) (any, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var cur any
+	if err := json.Unmarshal(data, &cur); err != nil {
+		return nil, fmt.Errorf("decode event json: %w", err)
+	}
+	for _, seg := range path {
+		switch node := cur.(type) {
+		case map[string]any:
+			cur = node[seg]
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, nil
+			}
+			cur = node[i]
+		default:
+			return nil, nil
+		}
+	}
+	return cur, nil
+}
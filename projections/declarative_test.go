@@ -0,0 +1,191 @@
+package projections
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+const orderDefinitions = `
+projections:
+  - name: order_status
+    events:
+      OrderCreated:
+        set:
+          customer: $.customer.name
+          first_item: $.items.0
+          amount: $.amount
+          status: created
+          stream: $stream_id
+          note: $$literal
+      OrderPaid:
+        set:
+          status: paid
+          channel: $metadata.channel
+      OrderDeleted:
+        delete: true
+`
+
+// memoryStore is an in-memory ProcessingStore.
+type memoryStore struct {
+	docs map[string][]byte
+}
+
+func (m *memoryStore) LoadState(_ context.Context, _, id string) ([]byte, int, error) {
+	return m.docs[id], 0, nil
+}
+
+func (m *memoryStore) UpsertState(_ context.Context, _, id string, data []byte, _ int) error {
+	m.docs[id] = data
+	return nil
+}
+
+func (m *memoryStore) DeleteState(_ context.Context, _, id string) error {
+	delete(m.docs, id)
+	return nil
+}
+
+func TestParseDefinitions_YAMLAndJSON(t *testing.T) {
+	defs, err := ParseDefinitions(strings.NewReader(orderDefinitions))
+	if err != nil {
+		t.Fatalf("parse yaml: %v", err)
+	}
+	if len(defs) != 1 || defs[0].Name != "order_status" || len(defs[0].Events) != 3 {
+		t.Fatalf("got %+v", defs)
+	}
+
+	js := `{"projections":[{"name":"totals","events":{"OrderPaid":{"set":{"total":"$.amount"}}}}]}`
+	defs, err = ParseDefinitions(strings.NewReader(js))
+	if err != nil {
+		t.Fatalf("parse json: %v", err)
+	}
+	if len(defs) != 1 || defs[0].Events["OrderPaid"].Set["total"] != "$.amount" {
+		t.Fatalf("got %+v", defs)
+	}
+}
+
+func TestParseDefinitions_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"bad name":        "projections: [{name: 'bad-name', events: {A: {set: {x: 1}}}}]",
+		"no events":       "projections: [{name: orders}]",
+		"empty mapping":   "projections: [{name: orders, events: {A: {}}}]",
+		"delete and set":  "projections: [{name: orders, events: {A: {delete: true, set: {x: 1}}}}]",
+		"unknown ref":     "projections: [{name: orders, events: {A: {set: {x: $nope}}}}]",
+		"empty path":      "projections: [{name: orders, events: {A: {set: {x: $.a..b}}}}]",
+		"unknown key":     "projections: [{name: orders, evnets: {A: {set: {x: 1}}}}]",
+		"malformed input": "projections: [",
+	}
+	for name, in := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseDefinitions(strings.NewReader(in)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestFromDefinition_AppliesMappings(t *testing.T) {
+	defs, err := ParseDefinitions(strings.NewReader(orderDefinitions))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	p, err := FromDefinition(newFakeStore(), defs[0])
+	if err != nil {
+		t.Fatalf("from definition: %v", err)
+	}
+	ps := &memoryStore{docs: map[string][]byte{}}
+	ctx := context.Background()
+
+	err = p.Process(ctx, []events.Event{
+		{StreamID: "order-1", Type: "OrderCreated", Data: []byte(`{"customer":{"name":"Ada"},"items":["tea"],"amount":42.5}`)},
+		{StreamID: "order-1", Type: "OrderPaid", Data: []byte(`{}`), Metadata: []byte(`{"channel":"web"}`)},
+		{StreamID: "order-2", Type: "OrderCreated", Data: []byte(`{}`)},
+		{StreamID: "order-2", Type: "OrderDeleted", Data: []byte(`{}`)},
+	}, ps)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	var doc map[string]any
+	dec := json.NewDecoder(strings.NewReader(string(ps.docs["order-1"])))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := map[string]string{
+		"customer":   "Ada",
+		"first_item": "tea",
+		"amount":     "42.5",
+		"status":     "paid",
+		"stream":     "order-1",
+		"note":       "$literal",
+		"channel":    "web",
+	}
+	for k, v := range want {
+		if got := doc[k]; got == nil || (func() string {
+			if n, ok := got.(json.Number); ok {
+				return n.String()
+			}
+			return got.(string)
+		})() != v {
+			t.Errorf("%s: got %v, want %s", k, got, v)
+		}
+	}
+	if _, ok := ps.docs["order-2"]; ok {
+		t.Error("order-2 should have been deleted")
+	}
+}
+
+func TestDaemon_AddDefinitions(t *testing.T) {
+	defs, err := ParseDefinitions(strings.NewReader(orderDefinitions))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	d := NewDaemon(newFakeStore())
+	if err := d.AddDefinitions(defs); err != nil {
+		t.Fatalf("add definitions: %v", err)
+	}
+	if _, err := d.findSubscriber("order_status"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFromDefinition_EnforcesLimits(t *testing.T) {
+	def := Definition{
+		Name: "copies",
+		Events: map[string]EventMapping{
+			"Copied": {Set: map[string]any{"blob": "$.blob", "kind": "copy"}},
+		},
+	}
+	ctx := context.Background()
+	evt := events.Event{StreamID: "s-1", Type: "Copied", Data: []byte(`{"blob":"` + strings.Repeat("x", 100) + `"}`)}
+
+	small, err := FromDefinition(newFakeStore(), def, WithMaxDocumentSize(64))
+	if err != nil {
+		t.Fatalf("from definition: %v", err)
+	}
+	err = small.Process(ctx, []events.Event{evt}, &memoryStore{docs: map[string][]byte{}})
+	if err == nil || !strings.Contains(err.Error(), "limit is 64") {
+		t.Errorf("got %v, want document size error", err)
+	}
+
+	narrow, err := FromDefinition(newFakeStore(), def, WithMaxFields(1))
+	if err != nil {
+		t.Fatalf("from definition: %v", err)
+	}
+	err = narrow.Process(ctx, []events.Event{evt}, &memoryStore{docs: map[string][]byte{}})
+	if err == nil || !strings.Contains(err.Error(), "2 fields, limit is 1") {
+		t.Errorf("got %v, want field count error", err)
+	}
+
+	unlimited, err := FromDefinition(newFakeStore(), def, WithMaxDocumentSize(0), WithMaxFields(0))
+	if err != nil {
+		t.Fatalf("from definition: %v", err)
+	}
+	if err := unlimited.Process(ctx, []events.Event{evt}, &memoryStore{docs: map[string][]byte{}}); err != nil {
+		t.Errorf("unlimited: %v", err)
+	}
+}
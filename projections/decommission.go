@@ -0,0 +1,106 @@
+package projections
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+// DecommissionOption configures Decommission.
+type DecommissionOption func(*decommissionConfig)
+
+type decommissionConfig struct {
+	readModel ReadModel
+}
+
+// DropReadModel makes Decommission drop the read model table of rm,
+// whisker_{name}, or its shard tables when rm is sharded (see
+// Projection.Sharded). rm must be the retired projection, or one built with
+// the same name and sharding; handlers and links, which are not read models,
+// have no table to drop, so a collection sharing their name is never lost.
+func DropReadModel(rm ReadModel) DecommissionOption {
+	return func(c *decommissionConfig) { c.readModel = rm }
+}
+
+// Decommission retires a projection once its code is gone: it resets the
+// checkpoint and marks it "decommissioned", with a Decommissioned record as
+// its metadata, so Status keeps showing when it was retired and where it had
+// got to. With DropReadModel it also drops the read model table, in the same
+// transaction; otherwise the table is left in place. name is the checkpoint
+// name as Status lists it, "store:subscriber" for a subscriber of a named
+// event store. A subscriber registered again under the name starts over from
+// the beginning of the log.
+//
+// It takes the projection's advisory lock first and fails if another
+// instance holds it, so stop every daemon running the projection before
+// decommissioning it; the lock is released on return. With auto-migrate
+// disabled the table is left for the external schema tooling to drop. The
+// action is also logged through the store's logger.
+func Decommission(ctx context.Context, store Store, name string, opts ...DecommissionOption) error {
+	var cfg decommissionConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	subscriber := name
+	if i := strings.LastIndexByte(name, ':'); i >= 0 {
+		subscriber = name[i+1:]
+	}
+	if err := schema.ValidateCollectionName(subscriber); err != nil {
+		return fmt.Errorf("decommission %s: %w", name, err)
+	}
+	var tables []string
+	if rm := cfg.readModel; rm != nil {
+		if rm.Name() != subscriber {
+			return fmt.Errorf("decommission %s: drop read model: got %s, want %s", name, rm.Name(), subscriber)
+		}
+		tables = readModelNames(rm)
+	}
+
+	unlock, acquired, err := store.TryAdvisoryLock(ctx, lockHash(name))
+	if err != nil {
+		return fmt.Errorf("decommission %s: acquire lock: %w", name, err)
+	}
+	if !acquired {
+		return fmt.Errorf("decommission %s: another instance holds the lock", name)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lockReleaseTimeout)
+		defer cancel()
+		if err := unlock(ctx); err != nil {
+			store.Logger().Error("release lock", "projection", name, "error", err)
+		}
+	}()
+
+	sess, err := store.Session(ctx)
+	if err != nil {
+		return fmt.Errorf("decommission %s: %w", name, err)
+	}
+	defer func() { _ = sess.Close(ctx) }()
+
+	rec := Decommissioned{At: time.Now().UTC()}
+	if store.SchemaBootstrap().AutoMigrate() {
+		for _, table := range tables {
+			if _, err := sess.DBExecutor().Exec(ctx, "DROP TABLE IF EXISTS whisker_"+table); err != nil {
+				return fmt.Errorf("decommission %s: drop table whisker_%s: %w", name, table, err)
+			}
+			rec.DroppedTables = append(rec.DroppedTables, "whisker_"+table)
+		}
+	}
+	existed, err := NewCheckpointStore(sess).Decommission(ctx, name, rec)
+	if err != nil {
+		return fmt.Errorf("decommission %s: %w", name, err)
+	}
+	if err := sess.Commit(ctx); err != nil {
+		return fmt.Errorf("decommission %s: %w", name, err)
+	}
+	for _, table := range rec.DroppedTables {
+		store.SchemaBootstrap().InvalidateTable(table)
+	}
+
+	store.Logger().Info("projection decommissioned",
+		"projection", name, "dropped_tables", rec.DroppedTables, "had_checkpoint", existed)
+	return nil
+}
@@ -0,0 +1,117 @@
+package projections
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+// maxSharedFreshness bounds how long the dispatcher trusts that a read found
+// no new events.
+const maxSharedFreshness = 100 * time.Millisecond
+
+// readFunc reads up to limit events after a global position.
+type readFunc func(ctx context.Context, after int64, limit int) ([]events.Event, error)
+
+// dispatcher shares event reads between the workers of one daemon. Workers
+// that poll the same position at the same time wait for a single query, and
+// the latest batch read is kept so that workers at or behind its start are
+// served from memory. Each worker still filters and checkpoints on its own.
+//
+// Only the latest batch is kept: a worker far behind the others reads past
+// it and replaces it, as it would without sharing.
+type dispatcher struct {
+	read      readFunc
+	batchSize int
+	freshness time.Duration
+
+	mu sync.Mutex
+	// window holds every event in (from, to] as of readAt
+	window   []events.Event
+	from, to int64
+	readAt   time.Time
+	loaded   bool
+	// reading is closed when the read in flight completes
+	reading chan struct{}
+}
+
+func newDispatcher(read readFunc, batchSize int, pollingInterval time.Duration) *dispatcher {
+	return &dispatcher{
+		read:      read,
+		batchSize: batchSize,
+		freshness: min(pollingInterval/2, maxSharedFreshness),
+	}
+}
+
+// poll returns up to limit events after the given position.
+func (d *dispatcher) poll(ctx context.Context, after int64, limit int) ([]events.Event, error) {
+	for {
+		d.mu.Lock()
+		if evts, ok := d.cached(after, limit); ok {
+			d.mu.Unlock()
+			return evts, nil
+		}
+		if wait := d.reading; wait != nil {
+			d.mu.Unlock()
+			select {
+			case <-wait:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		done := make(chan struct{})
+		d.reading = done
+		d.mu.Unlock()
+
+		evts, err := d.read(ctx, after, max(limit, d.batchSize))
+
+		d.mu.Lock()
+		d.reading = nil
+		if err == nil {
+			d.window, d.from, d.to, d.readAt, d.loaded = evts, after, after, time.Now(), true
+			if len(evts) > 0 {
+				d.to = evts[len(evts)-1].GlobalPosition
+			}
+		}
+		close(done)
+		d.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return limitEvents(evts, limit), nil
+	}
+}
+
+// cached serves a poll from the window: events after a position inside it,
+// or nothing for a worker at its end while the read is fresh. Called with
+// d.mu held.
+func (d *dispatcher) cached(after int64, limit int) ([]events.Event, bool) {
+	if !d.loaded || after < d.from || after > d.to {
+		return nil, false
+	}
+	if after == d.to {
+		return nil, time.Since(d.readAt) < d.freshness
+	}
+	i := 0
+	for i < len(d.window) && d.window[i].GlobalPosition <= after {
+		i++
+	}
+	return limitEvents(d.window[i:], limit), true
+}
+
+// limitEvents returns a copy of at most limit of evts, so callers never share
+// the window's backing array.
+func limitEvents(evts []events.Event, limit int) []events.Event {
+	if len(evts) > limit {
+		evts = evts[:limit]
+	}
+	if len(evts) == 0 {
+		return nil
+	}
+	out := make([]events.Event, len(evts))
+	copy(out, evts)
+	return out
+}
@@ -0,0 +1,136 @@
+package projections
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+// fakeLog serves reads from positions 1..head, counting queries.
+type fakeLog struct {
+	head  atomic.Int64
+	reads atomic.Int32
+	delay time.Duration
+	err   error
+}
+
+func (l *fakeLog) read(_ context.Context, after int64, limit int) ([]events.Event, error) {
+	l.reads.Add(1)
+	time.Sleep(l.delay)
+	if l.err != nil {
+		return nil, l.err
+	}
+	var out []events.Event
+	for pos := after + 1; pos <= l.head.Load() && len(out) < limit; pos++ {
+		out = append(out, events.Event{GlobalPosition: pos, Type: "Tick"})
+	}
+	return out, nil
+}
+
+func TestDispatcher_ConcurrentPollsShareOneRead(t *testing.T) {
+	log := &fakeLog{delay: 20 * time.Millisecond}
+	log.head.Store(3)
+	d := newDispatcher(log.read, 10, time.Second)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			evts, err := d.poll(context.Background(), 0, 10)
+			if err != nil || len(evts) != 3 {
+				t.Errorf("poll: %d events, %v", len(evts), err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := log.reads.Load(); n != 1 {
+		t.Errorf("got %d reads, want 1", n)
+	}
+}
+
+func TestDispatcher_ServesPositionsInsideWindow(t *testing.T) {
+	log := &fakeLog{}
+	log.head.Store(5)
+	d := newDispatcher(log.read, 10, time.Second)
+	ctx := context.Background()
+
+	if _, err := d.poll(ctx, 0, 10); err != nil {
+		t.Fatal(err)
+	}
+	evts, err := d.poll(ctx, 3, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evts) != 2 || evts[0].GlobalPosition != 4 {
+		t.Errorf("got %+v, want positions 4 and 5", evts)
+	}
+	evts, err = d.poll(ctx, 0, 2)
+	if err != nil || len(evts) != 2 {
+		t.Errorf("limit: got %d events, %v", len(evts), err)
+	}
+	if n := log.reads.Load(); n != 1 {
+		t.Errorf("got %d reads, want 1", n)
+	}
+}
+
+func TestDispatcher_CaughtUpPollsTrustFreshRead(t *testing.T) {
+	log := &fakeLog{}
+	log.head.Store(2)
+	d := newDispatcher(log.read, 10, time.Second)
+	d.freshness = 30 * time.Millisecond
+	ctx := context.Background()
+
+	if _, err := d.poll(ctx, 0, 10); err != nil {
+		t.Fatal(err)
+	}
+	log.head.Store(3)
+	if evts, _ := d.poll(ctx, 2, 10); len(evts) != 0 {
+		t.Errorf("fresh read: got %d events, want none", len(evts))
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	evts, err := d.poll(ctx, 2, 10)
+	if err != nil || len(evts) != 1 || evts[0].GlobalPosition != 3 {
+		t.Errorf("stale read: got %+v, %v", evts, err)
+	}
+	if n := log.reads.Load(); n != 2 {
+		t.Errorf("got %d reads, want 2", n)
+	}
+}
+
+func TestDispatcher_ReadErrorIsNotCached(t *testing.T) {
+	boom := errors.New("boom")
+	log := &fakeLog{err: boom}
+	log.head.Store(1)
+	d := newDispatcher(log.read, 10, time.Second)
+	ctx := context.Background()
+
+	if _, err := d.poll(ctx, 0, 10); !errors.Is(err, boom) {
+		t.Fatalf("got %v, want boom", err)
+	}
+	log.err = nil
+	evts, err := d.poll(ctx, 0, 10)
+	if err != nil || len(evts) != 1 {
+		t.Errorf("after error: got %d events, %v", len(evts), err)
+	}
+}
+
+func TestDispatcher_CopiesWindow(t *testing.T) {
+	log := &fakeLog{}
+	log.head.Store(2)
+	d := newDispatcher(log.read, 10, time.Second)
+	ctx := context.Background()
+
+	first, _ := d.poll(ctx, 0, 10)
+	first[0].Type = "Changed"
+	second, _ := d.poll(ctx, 0, 10)
+	if second[0].Type != "Tick" {
+		t.Errorf("window modified through a returned slice: %+v", second[0])
+	}
+}
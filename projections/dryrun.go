@@ -0,0 +1,162 @@
+package projections
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+// DryRun replays the events of streamID from the default event store through
+// the projection's handlers and returns the read model they build, without
+// writing anything: state is kept in memory, round-tripped through the
+// store's codec between events as Process does. Use it to see why a document
+// looks wrong, or what changed handlers would make of a stream before
+// rebuilding. Returns nil if the handlers delete the document or the stream
+// has no events they handle.
+func (p *Projection[T]) DryRun(ctx context.Context, streamID string) (*T, error) {
+	evts, err := events.New(p.store).ReadStream(ctx, streamID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("projection %s: dry run %s: %w", p.name, streamID, err)
+	}
+	return p.dryRun(ctx, streamID, evts)
+}
+
+// dryRun processes evts into an in-memory store and decodes the document
+// left for streamID.
+func (p *Projection[T]) dryRun(ctx context.Context, streamID string, evts []events.Event) (*T, error) {
+	ps := &dryRunStore{docs: make(map[string][]byte)}
+	if err := p.Process(ctx, evts, ps); err != nil {
+		return nil, fmt.Errorf("projection %s: dry run %s: %w", p.name, streamID, err)
+	}
+	data, ok := ps.docs[streamID]
+	if !ok {
+		return nil, nil
+	}
+	state := new(T)
+	if err := p.store.JSONCodec().Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("projection %s: dry run %s: unmarshal state: %w", p.name, streamID, err)
+	}
+	return state, nil
+}
+
+// dryRunStore is the in-memory ProcessingStore of a dry run. Tombstoned
+// documents are dropped like deleted ones.
+type dryRunStore struct {
+	docs map[string][]byte
+}
+
+func (s *dryRunStore) LoadState(_ context.Context, _, id string) ([]byte, int, error) {
+	return s.docs[id], 0, nil
+}
+
+func (s *dryRunStore) UpsertState(_ context.Context, _, id string, data []byte, _ int) error {
+	s.docs[id] = data
+	return nil
+}
+
+func (s *dryRunStore) DeleteState(_ context.Context, _, id string) error {
+	delete(s.docs, id)
+	return nil
+}
+
+func (s *dryRunStore) TombstoneState(ctx context.Context, collection, id string) error {
+	return s.DeleteState(ctx, collection, id)
+}
+
+// AppendToArray appends elem to the field as the database does, reviving
+// the document as just the new array when it is missing.
+func (s *dryRunStore) AppendToArray(_ context.Context, _, id, field string, elem []byte) error {
+	doc := map[string]json.RawMessage{}
+	if data, ok := s.docs[id]; ok {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+	}
+	var arr []json.RawMessage
+	if raw, ok := doc[field]; ok {
+		if err := json.Unmarshal(raw, &arr); err != nil {
+			arr = nil
+		}
+	}
+	return s.setField(id, doc, field, append(arr, elem))
+}
+
+// RemoveFromArray removes the elements of the field containing elem.
+func (s *dryRunStore) RemoveFromArray(_ context.Context, _, id, field string, elem []byte) error {
+	data, ok := s.docs[id]
+	if !ok {
+		return nil
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	var arr []json.RawMessage
+	if err := json.Unmarshal(doc[field], &arr); err != nil {
+		return nil
+	}
+	var want any
+	if err := json.Unmarshal(elem, &want); err != nil {
+		return err
+	}
+	kept := make([]json.RawMessage, 0, len(arr))
+	for _, e := range arr {
+		var v any
+		if err := json.Unmarshal(e, &v); err != nil {
+			return err
+		}
+		if !jsonContains(v, want) {
+			kept = append(kept, e)
+		}
+	}
+	return s.setField(id, doc, field, kept)
+}
+
+func (s *dryRunStore) setField(id string, doc map[string]json.RawMessage, field string, arr []json.RawMessage) error {
+	raw, err := json.Marshal(arr)
+	if err != nil {
+		return err
+	}
+	doc[field] = raw
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	s.docs[id] = data
+	return nil
+}
+
+// jsonContains reports whether the decoded JSON value a contains b, as the
+// JSONB @> operator does: objects contain the keys of b with contained
+// values, arrays contain each element of b, and scalars are equal.
+func jsonContains(a, b any) bool {
+	switch bv := b.(type) {
+	case map[string]any:
+		av, ok := a.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, v := range bv {
+			if sub, ok := av[k]; !ok || !jsonContains(sub, v) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		av, ok := a.([]any)
+		if !ok {
+			return false
+		}
+		for _, v := range bv {
+			if !slices.ContainsFunc(av, func(e any) bool { return jsonContains(e, v) }) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
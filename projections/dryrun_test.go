@@ -0,0 +1,62 @@
+package projections
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+func dryRunProjection() *Projection[OrderSummary] {
+	return New[OrderSummary](newFakeStore(), "order_summaries").
+		On("OrderCreated", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+			return &OrderSummary{ID: evt.StreamID, Status: "created"}, nil
+		}).
+		On("OrderPaid", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+			state.Status = "paid"
+			state.Total += 10
+			return state, nil
+		}).
+		On("OrderCancelled", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+			return nil, nil
+		})
+}
+
+func TestProjection_DryRunFoldsStream(t *testing.T) {
+	evts := []events.Event{
+		{StreamID: "o1", Type: "OrderCreated"},
+		{StreamID: "o1", Type: "OrderNoted"},
+		{StreamID: "o1", Type: "OrderPaid"},
+		{StreamID: "o1", Type: "OrderPaid"},
+	}
+	got, err := dryRunProjection().dryRun(context.Background(), "o1", evts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.Status != "paid" || got.Total != 20 {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestProjection_DryRunDeleted(t *testing.T) {
+	evts := []events.Event{
+		{StreamID: "o1", Type: "OrderCreated"},
+		{StreamID: "o1", Type: "OrderCancelled"},
+	}
+	got, err := dryRunProjection().Tombstones().dryRun(context.Background(), "o1", evts)
+	if err != nil || got != nil {
+		t.Errorf("got %+v, %v; want nil, nil", got, err)
+	}
+}
+
+func TestProjection_DryRunHandlerError(t *testing.T) {
+	boom := errors.New("boom")
+	p := dryRunProjection().On("OrderShipped", func(context.Context, events.Event, *OrderSummary) (*OrderSummary, error) {
+		return nil, boom
+	})
+	_, err := p.dryRun(context.Background(), "o1", []events.Event{{StreamID: "o1", Type: "OrderShipped"}})
+	if !errors.Is(err, boom) {
+		t.Errorf("got %v, want boom", err)
+	}
+}
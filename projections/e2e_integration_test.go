@@ -128,3 +128,55 @@ func TestE2E_ProjectionDeletesReadModel(t *testing.T) {
 		}
 	}
 }
+
+func TestE2E_ProjectionTombstonesReadModel(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	proj := projections.New[E2EOrder](store, "e2e_tombstone_test").Tombstones()
+	proj.On("OrderCreated", func(_ context.Context, evt events.Event, _ *E2EOrder) (*E2EOrder, error) {
+		return &E2EOrder{ID: evt.StreamID, Status: "active"}, nil
+	})
+	proj.On("OrderCancelled", func(_ context.Context, _ events.Event, _ *E2EOrder) (*E2EOrder, error) {
+		return nil, nil
+	})
+
+	es := events.New(store)
+	err := es.Append(ctx, "order-ts1", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{}`)},
+		{Type: "OrderCancelled", Data: []byte(`{}`)},
+	})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	w := projections.NewWorker(store, proj)
+	if _, err := w.ProcessBatch(ctx); err != nil {
+		t.Fatalf("process batch: %v", err)
+	}
+
+	col := documents.Collection[E2EOrder](store, "e2e_tombstone_test")
+	exists, err := col.Exists(ctx, "order-ts1")
+	if err != nil {
+		t.Fatalf("exists: %v", err)
+	}
+	if !exists {
+		t.Fatal("tombstoned row should still exist")
+	}
+
+	live, err := col.Query().Deleted(documents.ExcludeDeleted).Count(ctx)
+	if err != nil {
+		t.Fatalf("count live: %v", err)
+	}
+	if live != 0 {
+		t.Errorf("live count: got %d, want 0", live)
+	}
+
+	deleted, err := col.Query().Deleted(documents.OnlyDeleted).Execute(ctx)
+	if err != nil {
+		t.Fatalf("query deleted: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0].ID != "order-ts1" {
+		t.Errorf("deleted: got %+v", deleted)
+	}
+}
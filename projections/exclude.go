@@ -0,0 +1,57 @@
+package projections
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+// Except makes the subscriber skip events of the given types, even those its
+// EventTypes lists. Like ExceptStreams, the events are left out by the
+// query that polls for the subscriber, so noisy internal events such as
+// heartbeats take none of its throughput. A subscriber with exclusions polls
+// on its own instead of sharing reads with the daemon's other workers (see
+// WithSharedPolling).
+func Except(types ...string) SubscriberOption {
+	return func(c *subscriberConfig) {
+		c.exclude.Types = append(c.exclude.Types, types...)
+	}
+}
+
+// ExceptStreams makes the subscriber skip the events of streams whose ID
+// starts with any of the prefixes, e.g. ExceptStreams("test-"). See Except.
+func ExceptStreams(prefixes ...string) SubscriberOption {
+	return func(c *subscriberConfig) {
+		c.exclude.StreamPrefixes = append(c.exclude.StreamPrefixes, prefixes...)
+	}
+}
+
+// SetExclude makes the worker skip the events x selects, filtering them out
+// in SQL when it polls and moving its checkpoint past them. Call it before
+// processing.
+func (w *Worker) SetExclude(x events.Exclude) {
+	w.exclude = x
+}
+
+// processExcept is the rest of ProcessBatch for a worker with exclusions: it
+// polls after pos without the excluded events, processes the batch, and
+// checkpoints past excluded events no polled event follows.
+func (w *Worker) processExcept(ctx context.Context, pos int64) (int, error) {
+	evts, through, err := w.poller.PollExcept(ctx, pos, w.exclude)
+	if err != nil {
+		return 0, fmt.Errorf("worker %s: poll: %w", w.name(), err)
+	}
+	n, err := w.processEvents(ctx, evts)
+	if err != nil {
+		return n, err
+	}
+	if len(evts) > 0 {
+		pos = evts[len(evts)-1].GlobalPosition
+	}
+	if through <= pos {
+		return n, nil
+	}
+	// the skipped events count as one towards checkpoint batching
+	return n, w.saveCheckpoint(ctx, through, 1)
+}
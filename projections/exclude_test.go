@@ -0,0 +1,38 @@
+package projections
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+func TestDaemon_AddExcept(t *testing.T) {
+	d := NewDaemon(newFakeStore())
+	d.Add(NewHandler("mailer"), Except("Heartbeat"), ExceptStreams("test-"), Except("Ping"))
+	d.Add(NewHandler("auditor"))
+
+	w := d.newWorker(NewHandler("mailer"))
+	if !slices.Equal(w.exclude.Types, []string{"Heartbeat", "Ping"}) || !slices.Equal(w.exclude.StreamPrefixes, []string{"test-"}) {
+		t.Errorf("mailer: got %+v", w.exclude)
+	}
+	if w := d.newWorker(NewHandler("auditor")); !w.exclude.IsZero() {
+		t.Errorf("auditor: got %+v, want no exclusions", w.exclude)
+	}
+}
+
+func TestWorker_FilterEventsExcluded(t *testing.T) {
+	h := NewHandler("mailer").On("OrderCreated", nil).On("Heartbeat", nil)
+	w := NewWorker(newFakeStore(), h)
+	w.SetExclude(events.Exclude{Types: []string{"Heartbeat"}, StreamPrefixes: []string{"test-"}})
+
+	got := w.filterEvents([]events.Event{
+		{StreamID: "order-1", Type: "OrderCreated"},
+		{StreamID: "order-1", Type: "Heartbeat"},
+		{StreamID: "test-1", Type: "OrderCreated"},
+		{StreamID: "order-2", Type: "OrderShipped"},
+	})
+	if len(got) != 1 || got[0].StreamID != "order-1" || got[0].Type != "OrderCreated" {
+		t.Errorf("got %+v, want only order-1 OrderCreated", got)
+	}
+}
@@ -0,0 +1,49 @@
+package projections
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+// EventError reports which event of a batch a subscriber failed on. The
+// built-in projections, handlers and links return it; custom subscribers can
+// too, so a dead-lettered checkpoint's Failure names the event at fault
+// instead of the first of the batch.
+type EventError struct {
+	Event events.Event
+	Err   error
+}
+
+func (e *EventError) Error() string { return e.Err.Error() }
+
+func (e *EventError) Unwrap() error { return e.Err }
+
+// Failure describes the failed batch that moved a projection to dead_letter,
+// so recovery can start from the event instead of the logs. It is cleared
+// when the projection runs again or is rebuilt.
+type Failure struct {
+	// Position, StreamID and EventType identify the event that failed, or
+	// the first event of the batch when the error is not an EventError.
+	Position  int64  `json:"position"`
+	StreamID  string `json:"streamId"`
+	EventType string `json:"eventType"`
+	// Error is the last error's message.
+	Error string `json:"error"`
+	// Attempts is the number of consecutive failed attempts.
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+// newFailure describes err, the failure of batch after attempts tries.
+func newFailure(batch []events.Event, err error, attempts int) Failure {
+	f := Failure{Error: err.Error(), Attempts: attempts, FailedAt: time.Now().UTC()}
+	evt := batch[0]
+	var ee *EventError
+	if errors.As(err, &ee) {
+		evt = ee.Event
+	}
+	f.Position, f.StreamID, f.EventType = evt.GlobalPosition, evt.StreamID, evt.Type
+	return f
+}
@@ -0,0 +1,46 @@
+package projections
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+func TestNewFailure(t *testing.T) {
+	batch := []events.Event{
+		{StreamID: "a", Type: "Created", GlobalPosition: 10},
+		{StreamID: "b", Type: "Paid", GlobalPosition: 11},
+	}
+
+	f := newFailure(batch, errors.New("boom"), 3)
+	if f.Position != 10 || f.StreamID != "a" || f.EventType != "Created" || f.Error != "boom" || f.Attempts != 3 {
+		t.Errorf("untyped error: got %+v, want the first event", f)
+	}
+
+	err := fmt.Errorf("worker x: process: %w", &EventError{Event: batch[1], Err: errors.New("declined")})
+	f = newFailure(batch, err, 1)
+	if f.Position != 11 || f.StreamID != "b" || f.EventType != "Paid" {
+		t.Errorf("EventError: got %+v, want the failing event", f)
+	}
+	if f.Error != "worker x: process: declined" {
+		t.Errorf("error = %q", f.Error)
+	}
+}
+
+func TestHandler_ProcessReportsFailingEvent(t *testing.T) {
+	cause := errors.New("smtp down")
+	h := NewHandler("mailer").On("Paid", func(context.Context, events.Event) error { return cause })
+	batch := []events.Event{{StreamID: "a", Type: "Created"}, {StreamID: "b", Type: "Paid", GlobalPosition: 7}}
+
+	err := h.Process(context.Background(), batch, nil)
+	var ee *EventError
+	if !errors.As(err, &ee) || ee.Event.GlobalPosition != 7 {
+		t.Fatalf("got %v, want EventError for position 7", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("EventError does not unwrap to the cause: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package projections
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/events"
+)
+
+// Group runs several subscribers under a single checkpoint and advisory lock,
+// both keyed by name. Events are handled one at a time: each is passed to
+// every member subscribed to its type, in the order the members are given,
+// before the next event is handled. A handler placed after a projection
+// therefore observes the read model the projection just wrote for the same
+// event, which separate subscribers, each with its own checkpoint, cannot
+// guarantee.
+//
+// Each member keeps its own read model, named after the member. The group's
+// version is derived from the names and versions of its members, in order,
+// so bumping, adding, removing or reordering a versioned member marks the
+// group outdated; it is 0 when no member is Versioned. A group is not a
+// ReadModel, even when it only holds projections, because its members may
+// have side effects: Rebuild and ReplayStream refuse it and ResetHandler
+// replays it. When a member emits events, such as a Link, the whole batch
+// runs in one transaction like any Emitter. Bind the group, not its members,
+// with FromEventStore: Group panics when a member is bound, since every
+// member reads the group's store.
+func Group(name string, members ...Subscriber) Subscriber {
+	g := &group{name: name}
+	for _, m := range members {
+		if inner, store := unwrapEventStore(m); store != "" {
+			panic(fmt.Sprintf("projections: group %s: member %s is bound to event store %s; bind the group instead", name, inner.Name(), store))
+		}
+		g.members = append(g.members, m)
+	}
+	for _, m := range g.members {
+		if _, ok := m.(Emitter); ok {
+			return &emitterGroup{g}
+		}
+	}
+	return g
+}
+
+type group struct {
+	name    string
+	members []Subscriber
+}
+
+func (g *group) Name() string { return g.name }
+
+// EventTypes returns the event types of every member, without duplicates.
+func (g *group) EventTypes() []string {
+	seen := make(map[string]struct{})
+	var types []string
+	for _, m := range g.members {
+		for _, t := range m.EventTypes() {
+			if _, ok := seen[t]; !ok {
+				seen[t] = struct{}{}
+				types = append(types, t)
+			}
+		}
+	}
+	return types
+}
+
+// Version returns a positive hash of the members' names and versions, in
+// order, or 0 when no member is versioned.
+func (g *group) Version() int {
+	h := fnv.New32a()
+	versioned := false
+	for _, m := range g.members {
+		v := max(subscriberVersion(m), 0)
+		versioned = versioned || v > 0
+		fmt.Fprintf(h, "%s\x00%d\x00", m.Name(), v)
+	}
+	if !versioned {
+		return 0
+	}
+	// the checkpoint stores versions as a 32-bit integer
+	return int(h.Sum32()&math.MaxInt32) | 1
+}
+
+// Process hands each event to the members subscribed to its type, in order.
+func (g *group) Process(ctx context.Context, evts []events.Event, store ProcessingStore) error {
+	return g.process(ctx, evts, store, nil)
+}
+
+func (g *group) process(ctx context.Context, evts []events.Event, store ProcessingStore, sink EventSink) error {
+	types := make([]map[string]struct{}, len(g.members))
+	for i, m := range g.members {
+		types[i] = make(map[string]struct{})
+		for _, t := range m.EventTypes() {
+			types[i][t] = struct{}{}
+		}
+	}
+
+	for _, evt := range evts {
+		batch := []events.Event{evt}
+		for i, m := range g.members {
+			if _, ok := types[i][evt.Type]; !ok {
+				continue
+			}
+			ps := g.memberStore(store, i)
+			var err error
+			if em, ok := m.(Emitter); ok && sink != nil {
+				err = em.ProcessEmit(ctx, batch, ps, sink)
+			} else {
+				err = m.Process(ctx, batch, ps)
+			}
+			if err != nil {
+				var ee *EventError
+				if !errors.As(err, &ee) {
+					err = &EventError{Event: evt, Err: err}
+				}
+				return fmt.Errorf("group %s: %s: %w", g.name, m.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// memberStore returns the store of member i: its own when store was built
+// for the group by processingStoreFor, and store itself otherwise.
+func (g *group) memberStore(store ProcessingStore, i int) ProcessingStore {
+	if gs, ok := store.(*groupProcessingStore); ok {
+		return gs.members[i]
+	}
+	return store
+}
+
+// emitterGroup is a group with at least one Emitter member, so the worker
+// runs its batches in a transaction and passes it a sink.
+type emitterGroup struct {
+	*group
+}
+
+// ProcessEmit hands each event to the members subscribed to its type, in
+// order, passing the sink to the members that emit events.
+func (g *emitterGroup) ProcessEmit(ctx context.Context, evts []events.Event, store ProcessingStore, sink EventSink) error {
+	return g.process(ctx, evts, store, sink)
+}
+
+// errGroupStore is returned when a group's store is used directly rather
+// than through one of its members.
+var errGroupStore = errors.New("projections: a group's store is split by member")
+
+// groupProcessingStore holds the processing store of each member of a
+// group, each writing the member's own read model.
+type groupProcessingStore struct {
+	members []ProcessingStore
+}
+
+func newGroupProcessingStore(b whisker.Backend, g *group) *groupProcessingStore {
+	gs := &groupProcessingStore{members: make([]ProcessingStore, len(g.members))}
+	for i, m := range g.members {
+		gs.members[i] = processingStoreFor(b, m)
+	}
+	return gs
+}
+
+func (gs *groupProcessingStore) LoadState(context.Context, string, string) ([]byte, int, error) {
+	return nil, 0, errGroupStore
+}
+
+func (gs *groupProcessingStore) UpsertState(context.Context, string, string, []byte, int) error {
+	return errGroupStore
+}
+
+func (gs *groupProcessingStore) DeleteState(context.Context, string, string) error {
+	return errGroupStore
+}
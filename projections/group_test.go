@@ -0,0 +1,137 @@
+package projections
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+func TestGroup_EventTypesAndVersion(t *testing.T) {
+	a := NewHandler("a")
+	a.On("OrderPlaced", func(ctx context.Context, evt events.Event) error { return nil })
+	b := NewHandler("b")
+	b.On("OrderPlaced", func(ctx context.Context, evt events.Event) error { return nil })
+	b.On("OrderPaid", func(ctx context.Context, evt events.Event) error { return nil })
+
+	g := Group("billing", a, b)
+	if g.Name() != "billing" {
+		t.Errorf("name: got %q, want billing", g.Name())
+	}
+	if got := g.EventTypes(); !reflect.DeepEqual(got, []string{"OrderPlaced", "OrderPaid"}) {
+		t.Errorf("event types: got %v", got)
+	}
+	if _, ok := g.(Emitter); ok {
+		t.Error("a group of handlers should not be an Emitter")
+	}
+	if _, ok := g.(ReadModel); ok {
+		t.Error("a group should not be a ReadModel")
+	}
+}
+
+func TestGroup_ProcessesEachEventInMemberOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) *Handler {
+		h := NewHandler(name)
+		h.On("OrderPlaced", func(ctx context.Context, evt events.Event) error {
+			calls = append(calls, name+":"+evt.StreamID)
+			return nil
+		})
+		return h
+	}
+	paid := NewHandler("paid")
+	paid.On("OrderPaid", func(ctx context.Context, evt events.Event) error {
+		calls = append(calls, "paid:"+evt.StreamID)
+		return nil
+	})
+
+	g := Group("billing", record("first"), paid, record("second"))
+	err := g.Process(context.Background(), []events.Event{
+		{Type: "OrderPlaced", StreamID: "o1"},
+		{Type: "OrderPaid", StreamID: "o1"},
+		{Type: "OrderPlaced", StreamID: "o2"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	want := []string{"first:o1", "second:o1", "paid:o1", "first:o2", "second:o2"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls: got %v, want %v", calls, want)
+	}
+}
+
+func TestGroup_FailureNamesTheEvent(t *testing.T) {
+	h := NewHandler("fails")
+	h.On("OrderPaid", func(ctx context.Context, evt events.Event) error { return errors.New("boom") })
+
+	g := Group("billing", h)
+	err := g.Process(context.Background(), []events.Event{
+		{Type: "OrderPaid", StreamID: "o1", GlobalPosition: 1},
+		{Type: "OrderPaid", StreamID: "o2", GlobalPosition: 2},
+	}, nil)
+	var ee *EventError
+	if !errors.As(err, &ee) {
+		t.Fatalf("got %v, want an EventError", err)
+	}
+	if ee.Event.StreamID != "o1" {
+		t.Errorf("failed event: got %s, want o1", ee.Event.StreamID)
+	}
+}
+
+func TestGroup_EmitterMembersGetTheSink(t *testing.T) {
+	l := NewLink("fulfillment")
+	l.On("OrderPaid", func(ctx context.Context, evt events.Event, sink EventSink) error {
+		return sink.Emit(ctx, "fulfillment-"+evt.StreamID, events.Event{Type: "ShipmentRequested"})
+	})
+
+	g := Group("billing", NewHandler("noop"), l)
+	em, ok := g.(Emitter)
+	if !ok {
+		t.Fatal("a group with a link should be an Emitter")
+	}
+	sink := &recordingSink{}
+	if err := em.ProcessEmit(context.Background(), []events.Event{{Type: "OrderPaid", StreamID: "o1"}}, nil, sink); err != nil {
+		t.Fatalf("process emit: %v", err)
+	}
+	if got := sink.emitted["fulfillment-o1"]; len(got) != 1 {
+		t.Errorf("emitted: got %+v", sink.emitted)
+	}
+}
+
+func TestGroup_VersionFollowsMembersInOrder(t *testing.T) {
+	version := func(members ...Subscriber) int {
+		return Group("billing", members...).(Versioned).Version()
+	}
+	a := New[OrderSummary](nil, "a").WithVersion(1)
+	b := New[OrderSummary](nil, "b").WithVersion(2)
+
+	if v := version(NewHandler("x"), New[OrderSummary](nil, "y")); v != 0 {
+		t.Errorf("unversioned members: got %d, want 0", v)
+	}
+	v := version(a, b)
+	if v <= 0 || v != version(a, b) {
+		t.Fatalf("got %d, want a stable positive version", v)
+	}
+	if version(b, a) == v {
+		t.Error("reordering members should change the version")
+	}
+	// a sum would not tell these apart
+	if version(New[OrderSummary](nil, "a").WithVersion(2), New[OrderSummary](nil, "b").WithVersion(1)) == v {
+		t.Error("moving a version between members should change the version")
+	}
+	if version(a, b, NewHandler("c")) == v {
+		t.Error("adding a member should change the version")
+	}
+}
+
+func TestGroup_PanicsOnBoundMember(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a member bound to an event store")
+		}
+	}()
+	Group("billing", NewHandler("a"), FromEventStore("billing", NewHandler("b")))
+}
@@ -49,7 +49,8 @@ func (h *Handler) EventTypes() []string {
 }
 
 // Process calls registered handlers for matching events. The ProcessingStore
-// argument is ignored since side-effect handlers don't maintain state.
+// argument is ignored since side-effect handlers don't maintain state. A
+// failure is returned as an *EventError naming the event.
 func (h *Handler) Process(ctx context.Context, evts []events.Event, _ ProcessingStore) error {
 	for _, evt := range evts {
 		fn, ok := h.handlers[evt.Type]
@@ -57,7 +58,7 @@ func (h *Handler) Process(ctx context.Context, evts []events.Event, _ Processing
 			continue
 		}
 		if err := fn(ctx, evt); err != nil {
-			return fmt.Errorf("handler %s: handle %s: %w", h.name, evt.Type, err)
+			return &EventError{Event: evt, Err: fmt.Errorf("handler %s: handle %s: %w", h.name, evt.Type, err)}
 		}
 	}
 	return nil
@@ -0,0 +1,107 @@
+package projections
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+// EventSink receives derived events emitted by a subscriber. Emitted events
+// are appended to the end of the target stream in the same transaction as
+// the subscriber's checkpoint.
+type EventSink interface {
+	Emit(ctx context.Context, streamID string, evts ...events.Event) error
+}
+
+// Emitter is implemented by subscribers that append derived events while
+// processing. The worker runs their batches in a single transaction so that
+// emitted events, read-model writes, and the checkpoint commit atomically.
+type Emitter interface {
+	Subscriber
+	ProcessEmit(ctx context.Context, evts []events.Event, store ProcessingStore, sink EventSink) error
+}
+
+// LinkFunc is the callback signature for links. It receives a source event and
+// emits zero or more derived events through the sink.
+type LinkFunc func(ctx context.Context, evt events.Event, sink EventSink) error
+
+// Link turns source events into derived events on other streams, enabling
+// enrichment pipelines (OrderPlaced + PaymentCaptured ->
+// OrderFulfillmentRequested) without leaving Whisker. A link that emits event
+// types it also consumes will see its own output on the next batch.
+type Link struct {
+	name     string
+	handlers map[string]LinkFunc
+}
+
+// NewLink creates a link with the given name, used for checkpointing.
+func NewLink(name string) *Link {
+	return &Link{
+		name:     name,
+		handlers: make(map[string]LinkFunc),
+	}
+}
+
+// On registers a handler for the given event type. Returns the link for
+// method chaining.
+func (l *Link) On(eventType string, fn LinkFunc) *Link {
+	l.handlers[eventType] = fn
+	return l
+}
+
+// Name returns the link identifier, used for checkpointing.
+func (l *Link) Name() string {
+	return l.name
+}
+
+// EventTypes returns the event types this link responds to.
+func (l *Link) EventTypes() []string {
+	types := make([]string, 0, len(l.handlers))
+	for t := range l.handlers {
+		types = append(types, t)
+	}
+	return types
+}
+
+// Process emits through the ProcessingStore when it also implements
+// EventSink. Workers call ProcessEmit directly, so this path only matters
+// when a link is driven by hand.
+func (l *Link) Process(ctx context.Context, evts []events.Event, store ProcessingStore) error {
+	sink, ok := store.(EventSink)
+	if !ok {
+		return fmt.Errorf("link %s: process: store does not accept emitted events", l.name)
+	}
+	return l.ProcessEmit(ctx, evts, store, sink)
+}
+
+// ProcessEmit calls registered handlers for matching events, passing the sink
+// for derived events.
+func (l *Link) ProcessEmit(ctx context.Context, evts []events.Event, _ ProcessingStore, sink EventSink) error {
+	for _, evt := range evts {
+		fn, ok := l.handlers[evt.Type]
+		if !ok {
+			continue
+		}
+		if err := fn(ctx, evt, sink); err != nil {
+			return &EventError{Event: evt, Err: fmt.Errorf("link %s: handle %s for %s: %w", l.name, evt.Type, evt.StreamID, err)}
+		}
+	}
+	return nil
+}
+
+// streamSink appends emitted events to the end of their target stream.
+type streamSink struct {
+	es *events.Store
+}
+
+func (s *streamSink) Emit(ctx context.Context, streamID string, evts ...events.Event) error {
+	if len(evts) == 0 {
+		return nil
+	}
+	version, err := s.es.StreamVersion(ctx, streamID)
+	if err != nil {
+		return err
+	}
+	return s.es.Append(ctx, streamID, version, evts)
+}
@@ -0,0 +1,97 @@
+//go:build integration
+
+package projections_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/projections"
+)
+
+func TestLink_EmitsDerivedEventsWithCheckpoint(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	err := es.Append(ctx, "order-l1", 0, []events.Event{
+		{Type: "OrderPlaced", Data: []byte(`{}`)},
+		{Type: "PaymentCaptured", Data: []byte(`{}`)},
+	})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	link := projections.NewLink("fulfillment_link").
+		On("PaymentCaptured", func(ctx context.Context, evt events.Event, sink projections.EventSink) error {
+			return sink.Emit(ctx, "fulfillment-"+evt.StreamID, events.Event{
+				Type: "OrderFulfillmentRequested",
+				Data: []byte(`{}`),
+			})
+		})
+
+	w := projections.NewWorker(store, link)
+	if _, err := w.ProcessBatch(ctx); err != nil {
+		t.Fatalf("process batch: %v", err)
+	}
+
+	derived, err := es.ReadStream(ctx, "fulfillment-order-l1", 0)
+	if err != nil {
+		t.Fatalf("read derived: %v", err)
+	}
+	if len(derived) != 1 || derived[0].Type != "OrderFulfillmentRequested" {
+		t.Fatalf("derived: got %+v", derived)
+	}
+
+	pos, _, err := projections.NewCheckpointStore(store).Load(ctx, "fulfillment_link")
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+	if pos <= 0 {
+		t.Errorf("checkpoint position: got %d, want > 0", pos)
+	}
+}
+
+func TestLink_FailureRollsBackEmittedEvents(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	err := es.Append(ctx, "order-l2", 0, []events.Event{
+		{Type: "PaymentCaptured", Data: []byte(`{}`)},
+	})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	link := projections.NewLink("failing_link").
+		On("PaymentCaptured", func(ctx context.Context, evt events.Event, sink projections.EventSink) error {
+			if err := sink.Emit(ctx, "derived-"+evt.StreamID, events.Event{Type: "Derived", Data: []byte(`{}`)}); err != nil {
+				return err
+			}
+			return errors.New("downstream failure")
+		})
+
+	w := projections.NewWorker(store, link)
+	if _, err := w.ProcessBatch(ctx); err == nil {
+		t.Fatal("expected process error")
+	}
+
+	derived, err := es.ReadStream(ctx, "derived-order-l2", 0)
+	if err != nil {
+		t.Fatalf("read derived: %v", err)
+	}
+	if len(derived) != 0 {
+		t.Errorf("derived events should be rolled back, got %d", len(derived))
+	}
+
+	pos, _, err := projections.NewCheckpointStore(store).Load(ctx, "failing_link")
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+	if pos != 0 {
+		t.Errorf("checkpoint position: got %d, want 0", pos)
+	}
+}
@@ -0,0 +1,67 @@
+package projections
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+type recordingSink struct {
+	emitted map[string][]events.Event
+}
+
+func (s *recordingSink) Emit(_ context.Context, streamID string, evts ...events.Event) error {
+	if s.emitted == nil {
+		s.emitted = make(map[string][]events.Event)
+	}
+	s.emitted[streamID] = append(s.emitted[streamID], evts...)
+	return nil
+}
+
+func TestLink_ImplementsEmitter(t *testing.T) {
+	l := NewLink("test")
+	var _ Emitter = l
+}
+
+func TestLink_EventTypes(t *testing.T) {
+	l := NewLink("fulfillment")
+	l.On("OrderPlaced", func(ctx context.Context, evt events.Event, sink EventSink) error { return nil })
+	l.On("PaymentCaptured", func(ctx context.Context, evt events.Event, sink EventSink) error { return nil })
+
+	if l.Name() != "fulfillment" {
+		t.Errorf("got %q, want %q", l.Name(), "fulfillment")
+	}
+	if types := l.EventTypes(); len(types) != 2 {
+		t.Fatalf("got %d types, want 2", len(types))
+	}
+}
+
+func TestLink_ProcessEmitForwardsDerivedEvents(t *testing.T) {
+	l := NewLink("fulfillment")
+	l.On("PaymentCaptured", func(ctx context.Context, evt events.Event, sink EventSink) error {
+		return sink.Emit(ctx, "fulfillment-"+evt.StreamID, events.Event{Type: "OrderFulfillmentRequested"})
+	})
+
+	sink := &recordingSink{}
+	err := l.ProcessEmit(context.Background(), []events.Event{
+		{Type: "OrderPlaced", StreamID: "order-1"},
+		{Type: "PaymentCaptured", StreamID: "order-1"},
+	}, nil, sink)
+	if err != nil {
+		t.Fatalf("process emit: %v", err)
+	}
+
+	got := sink.emitted["fulfillment-order-1"]
+	if len(got) != 1 || got[0].Type != "OrderFulfillmentRequested" {
+		t.Errorf("emitted: got %+v", sink.emitted)
+	}
+}
+
+func TestLink_ProcessWithoutSinkFails(t *testing.T) {
+	l := NewLink("fulfillment")
+	err := l.Process(context.Background(), []events.Event{{Type: "OrderPlaced"}}, nil)
+	if err == nil {
+		t.Fatal("expected error when store does not accept emitted events")
+	}
+}
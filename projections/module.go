@@ -0,0 +1,100 @@
+package projections
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+// Module is a projection handler that runs outside the program's own code,
+// typically a WebAssembly module executed by a runtime such as wazero.
+// Whisker ships no runtime: implement Module over the one you use, and load
+// the module with FromModule or Daemon.AddModule, which bound each call with
+// the limits in their DefinitionOptions.
+type Module interface {
+	// EventTypes returns the event types the module handles.
+	EventTypes() []string
+	// Apply returns the JSON object the document of evt's stream holds after
+	// evt, given the one it held before, nil when there was none. Returning
+	// nil deletes the document. Apply must stop when ctx is done, as runtimes
+	// that can interrupt a module on cancellation do.
+	Apply(ctx context.Context, evt events.Event, state []byte) ([]byte, error)
+}
+
+// FromModule builds a read-model projection named name whose documents m
+// computes. Documents are stored as JSON objects in whisker_{name}, as for
+// FromDefinition. Each call to Apply runs with the apply timeout in opts, and
+// a document it returns must be a JSON object within the document limits;
+// anything else fails the batch, which counts towards the projection's
+// dead-letter limit, so a faulty module stops only its own projection. Panics
+// are recovered by the worker like those of any subscriber.
+func FromModule(b whisker.Backend, name string, m Module, opts ...DefinitionOption) (*Projection[map[string]any], error) {
+	if err := schema.ValidateCollectionName(name); err != nil {
+		return nil, fmt.Errorf("projections: module %q: %w", name, err)
+	}
+	types := m.EventTypes()
+	if len(types) == 0 {
+		return nil, fmt.Errorf("projections: module %s: no event types", name)
+	}
+	cfg := newDefinitionConfig(opts)
+	p := New[map[string]any](b, name)
+	apply := func(ctx context.Context, evt events.Event, state *map[string]any) (*map[string]any, error) {
+		var in []byte
+		if state != nil && *state != nil {
+			var err error
+			if in, err = json.Marshal(*state); err != nil {
+				return nil, fmt.Errorf("encode document: %w", err)
+			}
+		}
+		if cfg.applyTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.applyTimeout)
+			defer cancel()
+		}
+		out, err := m.Apply(ctx, evt, in)
+		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("module %s: apply timed out after %s: %w", name, cfg.applyTimeout, err)
+			}
+			return nil, fmt.Errorf("module %s: %w", name, err)
+		}
+		if out == nil {
+			return nil, nil
+		}
+		if err := cfg.checkSize(len(out)); err != nil {
+			return nil, fmt.Errorf("module %s: %w", name, err)
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(out, &doc); err != nil || doc == nil {
+			return nil, fmt.Errorf("module %s: returned a document that is not a JSON object", name)
+		}
+		if err := cfg.checkFields(len(doc)); err != nil {
+			return nil, fmt.Errorf("module %s: %w", name, err)
+		}
+		return &doc, nil
+	}
+	for _, typ := range types {
+		p.On(typ, apply)
+	}
+	return p, nil
+}
+
+// AddModule builds a projection from m with FromModule and registers it.
+// Call it before Run. Like AddDefinitions, it returns an error when the read
+// model is already written by a registered subscriber.
+func (d *Daemon) AddModule(name string, m Module, opts ...DefinitionOption) error {
+	p, err := FromModule(d.store, name, m, opts...)
+	if err != nil {
+		return err
+	}
+	if err := d.claimReadModels(p); err != nil {
+		return err
+	}
+	d.subscribers = append(d.subscribers, p)
+	return nil
+}
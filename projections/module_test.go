@@ -0,0 +1,138 @@
+package projections
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+// funcModule is a Module whose Apply is a Go function, standing in for one
+// run by a WebAssembly runtime.
+type funcModule struct {
+	types []string
+	apply func(ctx context.Context, evt events.Event, state []byte) ([]byte, error)
+}
+
+func (m funcModule) EventTypes() []string { return m.types }
+
+func (m funcModule) Apply(ctx context.Context, evt events.Event, state []byte) ([]byte, error) {
+	return m.apply(ctx, evt, state)
+}
+
+func TestFromModule_AppliesEvents(t *testing.T) {
+	counter := funcModule{
+		types: []string{"Clicked", "Cleared"},
+		apply: func(_ context.Context, evt events.Event, state []byte) ([]byte, error) {
+			if evt.Type == "Cleared" {
+				return nil, nil
+			}
+			if state == nil {
+				return []byte(`{"clicks":1}`), nil
+			}
+			return []byte(`{"clicks":2,"before":` + string(state) + `}`), nil
+		},
+	}
+	p, err := FromModule(newFakeStore(), "clicks", counter)
+	if err != nil {
+		t.Fatalf("from module: %v", err)
+	}
+
+	ctx := context.Background()
+	ps := &memoryStore{docs: map[string][]byte{}}
+	err = p.Process(ctx, []events.Event{
+		{StreamID: "a", Type: "Clicked"},
+		{StreamID: "a", Type: "Clicked"},
+		{StreamID: "b", Type: "Clicked"},
+		{StreamID: "b", Type: "Cleared"},
+	}, ps)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if got := string(ps.docs["a"]); got != `{"before":{"clicks":1},"clicks":2}` {
+		t.Errorf("a: got %s", got)
+	}
+	if _, ok := ps.docs["b"]; ok {
+		t.Error("b should have been deleted")
+	}
+}
+
+func TestFromModule_EnforcesLimits(t *testing.T) {
+	returning := func(out string) funcModule {
+		return funcModule{
+			types: []string{"Set"},
+			apply: func(context.Context, events.Event, []byte) ([]byte, error) { return []byte(out), nil },
+		}
+	}
+	blocking := funcModule{
+		types: []string{"Set"},
+		apply: func(ctx context.Context, _ events.Event, _ []byte) ([]byte, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	tests := []struct {
+		name    string
+		module  funcModule
+		opts    []DefinitionOption
+		wantErr string
+	}{
+		{"document size", returning(`{"blob":"` + strings.Repeat("x", 100) + `"}`), []DefinitionOption{WithMaxDocumentSize(64)}, "limit is 64"},
+		{"field count", returning(`{"a":1,"b":2}`), []DefinitionOption{WithMaxFields(1)}, "2 fields, limit is 1"},
+		{"not an object", returning(`[1,2]`), nil, "not a JSON object"},
+		{"null", returning(`null`), nil, "not a JSON object"},
+		{"timeout", blocking, []DefinitionOption{WithApplyTimeout(10 * time.Millisecond)}, "timed out after 10ms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := FromModule(newFakeStore(), "guarded", tt.module, tt.opts...)
+			if err != nil {
+				t.Fatalf("from module: %v", err)
+			}
+			err = p.Process(context.Background(), []events.Event{{StreamID: "s-1", Type: "Set"}}, &memoryStore{docs: map[string][]byte{}})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFromModule_ModuleErrorNamesTheModule(t *testing.T) {
+	failing := funcModule{
+		types: []string{"Set"},
+		apply: func(context.Context, events.Event, []byte) ([]byte, error) { return nil, errors.New("trap") },
+	}
+	p, err := FromModule(newFakeStore(), "failing", failing)
+	if err != nil {
+		t.Fatalf("from module: %v", err)
+	}
+	err = p.Process(context.Background(), []events.Event{{StreamID: "s-1", Type: "Set"}}, &memoryStore{docs: map[string][]byte{}})
+	if err == nil || !strings.Contains(err.Error(), "module failing: trap") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestDaemon_AddModule(t *testing.T) {
+	d := NewDaemon(newFakeStore())
+	m := funcModule{types: []string{"Set"}}
+	if err := d.AddModule("clicks", m); err != nil {
+		t.Fatalf("add module: %v", err)
+	}
+	if _, err := d.findSubscriber("clicks"); err != nil {
+		t.Error(err)
+	}
+	if err := d.AddModule("clicks", m); err == nil {
+		t.Error("expected an error for a read model written twice")
+	}
+	if err := d.AddModule("bad name", m); err == nil {
+		t.Error("expected an error for an invalid name")
+	}
+	if err := d.AddModule("idle", funcModule{}); err == nil {
+		t.Error("expected an error for a module without event types")
+	}
+}
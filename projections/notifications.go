@@ -0,0 +1,195 @@
+package projections
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ripkitten-co/whisker"
+)
+
+// Notification wakes a consumer of Poller.Notifications.
+type Notification struct {
+	// Payload is the NOTIFY payload; empty for synthesized notifications.
+	Payload string
+	// Fallback is true when no NOTIFY was received: the keepalive interval
+	// passed quietly, or the listener reconnected and notifications may have
+	// been missed. Consumers should poll either way.
+	Fallback bool
+}
+
+// NotifyOption configures Poller.Notifications.
+type NotifyOption func(*notifyConfig)
+
+type notifyConfig struct {
+	keepalive       time.Duration
+	reconnectDelay  time.Duration
+	maxReconnectDel time.Duration
+}
+
+// WithKeepalive sets how long the listener waits without a NOTIFY before it
+// pings the connection and emits a fallback notification. Defaults to 30
+// seconds.
+func WithKeepalive(d time.Duration) NotifyOption {
+	return func(c *notifyConfig) { c.keepalive = d }
+}
+
+// WithReconnectDelay sets the initial and maximum delay between reconnection
+// attempts after the listener connection is lost. The delay doubles after
+// each failed attempt. Defaults to 1 and 30 seconds.
+func WithReconnectDelay(initial, maxDelay time.Duration) NotifyOption {
+	return func(c *notifyConfig) {
+		c.reconnectDelay = initial
+		c.maxReconnectDel = maxDelay
+	}
+}
+
+// Notifications subscribes to event-append notifications on a single
+// long-lived connection. The returned channel receives a Notification for
+// every NOTIFY, plus fallback notifications on keepalive and after
+// reconnecting, so a consumer that polls on each receive never misses events
+// for longer than the keepalive interval. Wakeups are coalesced: when the
+// consumer has not yet received the previous notification, new ones are
+// dropped. A lost connection is re-established with backoff. The channel is
+// closed when ctx is cancelled or the store shuts down.
+func (p *Poller) Notifications(ctx context.Context, opts ...NotifyOption) (<-chan Notification, error) {
+	ch, err := notifications(ctx, p.store, p.channel(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("poller: notifications: %w", err)
+	}
+	return ch, nil
+}
+
+// notifications is Poller.Notifications for any channel.
+func notifications(ctx context.Context, store Store, channel string, opts []NotifyOption) (<-chan Notification, error) {
+	cfg := notifyConfig{
+		keepalive:       30 * time.Second,
+		reconnectDelay:  time.Second,
+		maxReconnectDel: 30 * time.Second,
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	l, err := listen(ctx, store, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan Notification, 1)
+	nl := &notifyListener{store: store, channel: channel, cfg: cfg}
+	go nl.listen(ctx, l, ch)
+	return ch, nil
+}
+
+// notifyListener keeps a listener on channel alive for notifications.
+type notifyListener struct {
+	store   Store
+	channel string
+	cfg     notifyConfig
+}
+
+func (nl *notifyListener) listen(ctx context.Context, l whisker.Listener, ch chan<- Notification) {
+	defer close(ch)
+	defer func() {
+		if l != nil {
+			closeListener(ctx, l)
+		}
+	}()
+
+	for {
+		if l == nil {
+			l = nl.reconnect(ctx)
+			if l == nil {
+				return
+			}
+			send(ch, Notification{Fallback: true})
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, nl.cfg.keepalive)
+		payload, err := l.Wait(waitCtx)
+		cancel()
+
+		switch {
+		case err == nil:
+			send(ch, Notification{Payload: payload})
+		case ctx.Err() != nil, errors.Is(err, whisker.ErrStoreClosed):
+			return
+		case errors.Is(err, context.DeadlineExceeded):
+			if err := l.Ping(ctx); err != nil {
+				nl.store.Logger().Warn("notification listener lost", "error", err)
+				closeListener(ctx, l)
+				l = nil
+				continue
+			}
+			send(ch, Notification{Fallback: true})
+		default:
+			nl.store.Logger().Warn("notification listener lost", "error", err)
+			closeListener(ctx, l)
+			l = nil
+		}
+	}
+}
+
+// reconnect retries Listen with exponential backoff. It returns nil once ctx
+// is cancelled or the store shuts down.
+func (nl *notifyListener) reconnect(ctx context.Context) whisker.Listener {
+	delay := nl.cfg.reconnectDelay
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		l, err := listen(ctx, nl.store, nl.channel)
+		if err == nil {
+			return l
+		}
+		if errors.Is(err, whisker.ErrStoreClosed) {
+			return nil
+		}
+		nl.store.Logger().Warn("notification listener reconnect", "error", err, "retry_in", delay)
+		delay = min(delay*2, nl.cfg.maxReconnectDel)
+	}
+}
+
+// listen subscribes to channel with store's Listen when it is a
+// ListeningNotifier, and otherwise with a listener that calls
+// WaitForNotification for each Wait.
+func listen(ctx context.Context, store Store, channel string) (whisker.Listener, error) {
+	if ln, ok := store.(ListeningNotifier); ok {
+		return ln.Listen(ctx, channel)
+	}
+	return waitListener{store: store, channel: channel}, nil
+}
+
+// waitListener is a whisker.Listener on a store that only implements
+// Notifier. It holds no connection, so a NOTIFY sent between two calls to
+// Wait is missed.
+type waitListener struct {
+	store   Notifier
+	channel string
+}
+
+func (l waitListener) Wait(ctx context.Context) (string, error) {
+	return "", l.store.WaitForNotification(ctx, l.channel)
+}
+
+func (l waitListener) Ping(context.Context) error  { return nil }
+func (l waitListener) Close(context.Context) error { return nil }
+
+func closeListener(ctx context.Context, l whisker.Listener) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	_ = l.Close(ctx)
+}
+
+// send delivers n unless a notification is already pending.
+func send(ch chan<- Notification, n Notification) {
+	select {
+	case ch <- n:
+	default:
+	}
+}
@@ -0,0 +1,150 @@
+package projections
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, ch <-chan Notification) Notification {
+	t.Helper()
+	select {
+	case n, ok := <-ch:
+		if !ok {
+			t.Fatal("notification channel closed")
+		}
+		return n
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for notification")
+	}
+	return Notification{}
+}
+
+func TestNotifications_DeliversPayloads(t *testing.T) {
+	store := newFakeStore()
+	store.listeners = []*fakeListener{newFakeListener(fakeWait{payload: "orders"})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := NewPoller(store, 10).Notifications(ctx)
+	if err != nil {
+		t.Fatalf("notifications: %v", err)
+	}
+
+	n := receive(t, ch)
+	if n.Payload != "orders" || n.Fallback {
+		t.Errorf("got %+v, want payload orders", n)
+	}
+	if store.channels[0] != "whisker_events" {
+		t.Errorf("listened on %q, want whisker_events", store.channels[0])
+	}
+
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel should close when ctx is cancelled")
+	}
+	select {
+	case <-store.listeners[0].closed:
+	case <-time.After(time.Second):
+		t.Fatal("listener was not closed")
+	}
+}
+
+func TestNotifications_KeepaliveFallback(t *testing.T) {
+	store := newFakeStore()
+	store.listeners = []*fakeListener{newFakeListener()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := NewPoller(store, 10).Notifications(ctx, WithKeepalive(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("notifications: %v", err)
+	}
+
+	if n := receive(t, ch); !n.Fallback {
+		t.Errorf("got %+v, want a fallback notification on keepalive", n)
+	}
+}
+
+func TestNotifications_ReconnectsAfterLostConnection(t *testing.T) {
+	store := newFakeStore()
+	store.listeners = []*fakeListener{
+		newFakeListener(fakeWait{err: errors.New("connection reset")}),
+		newFakeListener(fakeWait{payload: "after"}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := NewPoller(store, 10).Notifications(ctx,
+		WithReconnectDelay(time.Millisecond, 10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("notifications: %v", err)
+	}
+
+	if n := receive(t, ch); !n.Fallback {
+		t.Errorf("got %+v, want a fallback notification after reconnecting", n)
+	}
+	if n := receive(t, ch); n.Payload != "after" {
+		t.Errorf("got %+v, want payload from the new listener", n)
+	}
+	if store.listens != 2 {
+		t.Errorf("got %d listens, want 2", store.listens)
+	}
+}
+
+func TestNotifications_ClosesWhenStoreCloses(t *testing.T) {
+	store := newFakeStore()
+	store.closed = true
+
+	if _, err := NewPoller(store, 10).Notifications(context.Background()); err == nil {
+		t.Fatal("expected error from a closed store")
+	}
+}
+
+// waitOnlyStore hides the Listen method of the store it wraps. Its
+// WaitForNotification returns at once the first time and then blocks until
+// ctx is done.
+type waitOnlyStore struct {
+	Store
+	channels chan string
+}
+
+func (s waitOnlyStore) WaitForNotification(ctx context.Context, channel string) error {
+	select {
+	case s.channels <- channel:
+		return nil
+	default:
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestNotifications_FallsBackToWaitForNotification(t *testing.T) {
+	inner := newFakeStore()
+	store := waitOnlyStore{Store: inner, channels: make(chan string, 1)}
+	if _, ok := Store(store).(ListeningNotifier); ok {
+		t.Fatal("waitOnlyStore should not be a ListeningNotifier")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := NewPoller(store, 10).Notifications(ctx)
+	if err != nil {
+		t.Fatalf("notifications: %v", err)
+	}
+
+	if n := receive(t, ch); n.Fallback {
+		t.Errorf("got %+v, want a notification from WaitForNotification", n)
+	}
+	if c := <-store.channels; c != "whisker_events" {
+		t.Errorf("waited on %q, want whisker_events", c)
+	}
+	if inner.listens != 0 {
+		t.Errorf("Listen called %d times, want 0", inner.listens)
+	}
+
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel should close when ctx is cancelled")
+	}
+}
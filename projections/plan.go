@@ -0,0 +1,150 @@
+package projections
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+// RebuildPlan describes what a Rebuild of one projection would do. See
+// DryRun.
+type RebuildPlan struct {
+	Projection string
+	// Events is the number of events of the projection's types that would be
+	// replayed, up to HeadPosition.
+	Events       int64
+	HeadPosition int64
+	// EstimatedDuration extrapolates Events from the throughput this daemon's
+	// worker has recently achieved for the projection. Zero when the daemon
+	// has not processed any of its batches yet.
+	EstimatedDuration time.Duration
+	// Table is the read-model table that would be dropped and recreated, or
+	// truncated when auto-migrate is disabled (see Truncate).
+	Table    string
+	Truncate bool
+	// Shards lists every table of a sharded projection (see
+	// Projection.Sharded), all of which would be dropped or truncated. Table
+	// and Indexes then describe the first shard.
+	Shards []string
+	// Indexes lists the indexes on Table now. Dropping the table drops them;
+	// those declared on the read-model type are created again as it is
+	// rebuilt.
+	Indexes []string
+}
+
+// DryRun makes Rebuild and RebuildWait fill plan with what they would do and
+// return without taking the projection's lock or touching any data.
+func DryRun(plan *RebuildPlan) RebuildOption {
+	return func(c *rebuildConfig) { c.plan = plan }
+}
+
+// PlanRebuild reports what Rebuild would do for the named projection without
+// changing anything.
+func (d *Daemon) PlanRebuild(ctx context.Context, name string) (*RebuildPlan, error) {
+	w, err := d.rebuildWorker(name)
+	if err != nil {
+		return nil, err
+	}
+	plan := &RebuildPlan{
+		Projection: name,
+		Table:      "whisker_" + name,
+		Truncate:   !d.store.SchemaBootstrap().AutoMigrate(),
+	}
+	if readModelShards(w.subscriber) > 0 {
+		for _, table := range readModelNames(w.subscriber) {
+			plan.Shards = append(plan.Shards, "whisker_"+table)
+		}
+		plan.Table = plan.Shards[0]
+	}
+	exec := d.store.DBExecutor()
+
+	if err := d.store.SchemaBootstrap().EnsureEventStore(ctx, exec, w.eventStore); err != nil {
+		return nil, fmt.Errorf("daemon: plan rebuild %s: %w", name, err)
+	}
+	var scanned int64
+	err = exec.QueryRow(ctx,
+		`SELECT COALESCE(max(global_position), 0), count(*), count(*) FILTER (WHERE type = ANY($1)) FROM `+schema.EventsTable(w.eventStore),
+		w.subscriber.EventTypes(),
+	).Scan(&plan.HeadPosition, &scanned, &plan.Events)
+	if err != nil {
+		return nil, fmt.Errorf("daemon: plan rebuild %s: count events: %w", name, err)
+	}
+
+	rows, err := exec.Query(ctx,
+		`SELECT indexname FROM pg_indexes WHERE schemaname = current_schema() AND tablename = $1 ORDER BY indexname`,
+		plan.Table,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("daemon: plan rebuild %s: list indexes: %w", name, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var idx string
+		if err := rows.Scan(&idx); err != nil {
+			return nil, fmt.Errorf("daemon: plan rebuild %s: list indexes: %w", name, err)
+		}
+		plan.Indexes = append(plan.Indexes, idx)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("daemon: plan rebuild %s: list indexes: %w", name, err)
+	}
+
+	// workers read every event and skip other types, so the replay time
+	// scales with all events, not just the projection's
+	plan.EstimatedDuration = w.throughput.estimate(scanned)
+	return plan, nil
+}
+
+// throughput tracks how fast a worker has recently processed events, as an
+// exponentially weighted moving average of events per second.
+type throughput struct {
+	mu   sync.Mutex
+	rate float64
+}
+
+// throughputWeight is the weight of the newest batch in the moving average.
+const throughputWeight = 0.2
+
+// record adds a batch of n events processed in d.
+func (t *throughput) record(n int, d time.Duration) {
+	if n <= 0 || d <= 0 {
+		return
+	}
+	rate := float64(n) / d.Seconds()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rate == 0 {
+		t.rate = rate
+		return
+	}
+	t.rate = throughputWeight*rate + (1-throughputWeight)*t.rate
+}
+
+// estimate returns how long n events would take at the recent rate, or 0
+// when nothing has been recorded.
+func (t *throughput) estimate(n int64) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rate == 0 {
+		return 0
+	}
+	return time.Duration(float64(n) / t.rate * float64(time.Second))
+}
+
+// throughputFor returns the throughput tracker of the named subscriber.
+func (d *Daemon) throughputFor(name string) *throughput {
+	d.statsMu.Lock()
+	defer d.statsMu.Unlock()
+	if d.throughput == nil {
+		d.throughput = map[string]*throughput{}
+	}
+	t, ok := d.throughput[name]
+	if !ok {
+		t = &throughput{}
+		d.throughput[name] = t
+	}
+	return t
+}
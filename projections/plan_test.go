@@ -0,0 +1,43 @@
+package projections
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThroughput_Estimate(t *testing.T) {
+	var tp throughput
+	if got := tp.estimate(1000); got != 0 {
+		t.Errorf("estimate without data: got %s, want 0", got)
+	}
+
+	tp.record(100, time.Second)
+	if got := tp.estimate(1000); got != 10*time.Second {
+		t.Errorf("estimate at 100/s: got %s, want 10s", got)
+	}
+
+	// empty batches say nothing about speed
+	tp.record(0, time.Second)
+	if got := tp.estimate(1000); got != 10*time.Second {
+		t.Errorf("estimate after empty batch: got %s, want 10s", got)
+	}
+
+	tp.record(600, time.Second)
+	if got := tp.estimate(1000); got != 5*time.Second {
+		t.Errorf("estimate after faster batch: got %s, want 5s at 200/s", got)
+	}
+}
+
+func TestDaemon_WorkersShareThroughput(t *testing.T) {
+	d := NewDaemon(newFakeStore())
+	d.Add(NewHandler("orders"))
+
+	a := d.newWorker(NewHandler("orders"))
+	b := d.newWorker(NewHandler("orders"))
+	if a.throughput == nil || a.throughput != b.throughput {
+		t.Error("workers of one subscriber should share its throughput")
+	}
+	if c := d.newWorker(NewHandler("mailer")); c.throughput == a.throughput {
+		t.Error("subscribers should not share throughput")
+	}
+}
@@ -4,51 +4,93 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/ripkitten-co/whisker"
 	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/schema"
 )
 
 // Poller reads batches of events from the event store and supports
 // LISTEN/NOTIFY for low-latency wakeups.
 type Poller struct {
-	store     *whisker.Store
-	pool      *pgxpool.Pool
-	batchSize int
+	store      Store
+	batchSize  int
+	eventStore string
+	// shared, when set by a daemon, serves Poll from reads shared with the
+	// daemon's other workers
+	shared *dispatcher
 }
 
 // NewPoller creates a poller that reads up to batchSize events per poll.
-func NewPoller(store *whisker.Store, batchSize int) *Poller {
+func NewPoller(store Store, batchSize int) *Poller {
+	return NewNamedPoller(store, "", batchSize)
+}
+
+// NewNamedPoller creates a poller over the named event store (see
+// events.NewNamed) that reads up to batchSize events per poll. It listens
+// for that store's append notifications only.
+func NewNamedPoller(store Store, eventStore string, batchSize int) *Poller {
 	return &Poller{
-		store:     store,
-		pool:      store.PgxPool(),
-		batchSize: batchSize,
+		store:      store,
+		batchSize:  batchSize,
+		eventStore: eventStore,
 	}
 }
 
+func (p *Poller) events() *events.Store {
+	return events.NewNamed(p.store, p.eventStore)
+}
+
+// channel is the NOTIFY channel events.Store.Append signals on: the name of
+// the store's table, qualified by the store's notify namespace.
+func (p *Poller) channel() string {
+	return p.store.SchemaBootstrap().NotifyChannel(schema.EventsTable(p.eventStore))
+}
+
 // Poll returns events with global_position greater than afterPosition.
 func (p *Poller) Poll(ctx context.Context, afterPosition int64) ([]events.Event, error) {
-	es := events.New(p.store)
-	return es.ReadAll(ctx, afterPosition, p.batchSize)
+	if p.shared != nil {
+		return p.shared.poll(ctx, afterPosition, p.batchSize)
+	}
+	return p.events().ReadAll(ctx, afterPosition, p.batchSize)
 }
 
-// WaitForNotification blocks until a NOTIFY arrives on the whisker_events
-// channel or the context is cancelled.
-func (p *Poller) WaitForNotification(ctx context.Context) error {
-	conn, err := p.pool.Acquire(ctx)
+// PollExcept is Poll without the events x skips, filtered out in SQL. It
+// always reads on its own, never from reads shared with other workers, and
+// also returns the position the read covers; see events.Store.ReadAllExcept.
+func (p *Poller) PollExcept(ctx context.Context, afterPosition int64, x events.Exclude) ([]events.Event, int64, error) {
+	return p.events().ReadAllExcept(ctx, afterPosition, p.batchSize, x)
+}
+
+// Head returns the current high-water mark: the highest global_position in
+// the event store, or 0 if it is empty. Subtract a checkpoint position to get
+// a subscriber's lag.
+func (p *Poller) Head(ctx context.Context) (int64, error) {
+	pos, err := p.events().HeadPosition(ctx)
 	if err != nil {
-		return fmt.Errorf("poller: acquire conn: %w", err)
+		return 0, fmt.Errorf("poller: head: %w", err)
 	}
-	defer conn.Release()
+	return pos, nil
+}
 
-	_, err = conn.Exec(ctx, "LISTEN whisker_events")
+// Peek returns up to n events with global_position greater than after,
+// independently of the poller's batch size. It is meant for tooling that
+// samples upcoming events; nothing is checkpointed.
+func (p *Poller) Peek(ctx context.Context, after int64, n int) ([]events.Event, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	evts, err := p.events().ReadAll(ctx, after, n)
 	if err != nil {
-		return fmt.Errorf("poller: listen: %w", err)
+		return nil, fmt.Errorf("poller: peek: %w", err)
 	}
+	return evts, nil
+}
 
-	_, err = conn.Conn().WaitForNotification(ctx)
-	if err != nil {
-		return fmt.Errorf("poller: wait: %w", err)
+// WaitForNotification blocks until a NOTIFY arrives on the event store's
+// channel or the context is cancelled. It subscribes a new connection on every
+// call; long-lived consumers should use Notifications.
+func (p *Poller) WaitForNotification(ctx context.Context) error {
+	if err := p.store.WaitForNotification(ctx, p.channel()); err != nil {
+		return fmt.Errorf("poller: %w", err)
 	}
 	return nil
 }
@@ -7,7 +7,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ripkitten-co/whisker"
 	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/internal/testutil"
 	"github.com/ripkitten-co/whisker/projections"
 )
 
@@ -109,3 +111,124 @@ func TestPoller_WaitForNotification(t *testing.T) {
 		t.Fatal("timed out waiting for notification")
 	}
 }
+
+func TestPoller_NotifyNamespacesAreIsolated(t *testing.T) {
+	connStr := testutil.SetupPostgres(t)
+	newStore := func(ns string) *whisker.Store {
+		store, err := whisker.New(context.Background(), connStr, whisker.WithNotifyNamespace(ns))
+		if err != nil {
+			t.Fatalf("create store: %v", err)
+		}
+		t.Cleanup(store.Close)
+		return store
+	}
+	storeA, storeB := newStore("tenant_a"), newStore("tenant_b")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	waitB, cancelB := context.WithTimeout(ctx, time.Second)
+	defer cancelB()
+
+	errA := make(chan error, 1)
+	errB := make(chan error, 1)
+	go func() { errA <- projections.NewPoller(storeA, 100).WaitForNotification(ctx) }()
+	go func() { errB <- projections.NewPoller(storeB, 100).WaitForNotification(waitB) }()
+
+	// give the listeners time to set up
+	time.Sleep(200 * time.Millisecond)
+
+	if err := events.New(storeA).Append(ctx, "tenant-stream", 0, []events.Event{
+		{Type: "Triggered", Data: []byte(`{}`)},
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	if err := <-errA; err != nil {
+		t.Fatalf("tenant a: wait for notification: %v", err)
+	}
+	if err := <-errB; err == nil {
+		t.Error("tenant b was woken by tenant a's append")
+	}
+}
+
+func TestPoller_HeadAndPeek(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	poller := projections.NewPoller(store, 1)
+
+	head, err := poller.Head(ctx)
+	if err != nil {
+		t.Fatalf("head: %v", err)
+	}
+	if head != 0 {
+		t.Fatalf("empty store head: got %d, want 0", head)
+	}
+
+	err = events.New(store).Append(ctx, "peek-stream", 0, []events.Event{
+		{Type: "A", Data: []byte(`{}`)},
+		{Type: "B", Data: []byte(`{}`)},
+		{Type: "C", Data: []byte(`{}`)},
+	})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	head, err = poller.Head(ctx)
+	if err != nil {
+		t.Fatalf("head: %v", err)
+	}
+
+	peeked, err := poller.Peek(ctx, 0, 2)
+	if err != nil {
+		t.Fatalf("peek: %v", err)
+	}
+	if len(peeked) != 2 {
+		t.Fatalf("got %d peeked events, want 2 regardless of batch size", len(peeked))
+	}
+	if peeked[0].Type != "A" || peeked[1].Type != "B" {
+		t.Errorf("got %q, %q, want A, B", peeked[0].Type, peeked[1].Type)
+	}
+
+	rest, err := poller.Peek(ctx, peeked[1].GlobalPosition, 10)
+	if err != nil {
+		t.Fatalf("peek rest: %v", err)
+	}
+	if len(rest) != 1 || rest[0].GlobalPosition != head {
+		t.Errorf("last event should be at head %d, got %+v", head, rest)
+	}
+}
+
+func TestPoller_Notifications(t *testing.T) {
+	store := setupStore(t)
+	es := events.New(store)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	poller := projections.NewPoller(store, 100)
+	ch, err := poller.Notifications(ctx, projections.WithKeepalive(time.Minute))
+	if err != nil {
+		t.Fatalf("notifications: %v", err)
+	}
+
+	for i, id := range []string{"notify-a", "notify-b"} {
+		if err := es.Append(ctx, id, 0, []events.Event{{Type: "Triggered", Data: []byte(`{}`)}}); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+		select {
+		case n, ok := <-ch:
+			if !ok {
+				t.Fatalf("channel closed before notification %d", i)
+			}
+			if n.Fallback {
+				t.Errorf("notification %d: got fallback, want a real NOTIFY", i)
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for notification %d", i)
+		}
+	}
+
+	cancel()
+	for range ch {
+	}
+}
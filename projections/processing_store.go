@@ -7,6 +7,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/internal/ident"
 	"github.com/ripkitten-co/whisker/internal/pg"
 	"github.com/ripkitten-co/whisker/schema"
 )
@@ -14,15 +15,23 @@ import (
 type pgProcessingStore struct {
 	exec   pg.Executor
 	schema *schema.Bootstrap
+	clock  whisker.Clock
 	name   string
+	// notify makes writes notify Watch; see Projection.Watchable
+	notify bool
 }
 
 // NewProcessingStoreFromBackend creates a ProcessingStore backed by the
 // whisker_{name} collection table.
 func NewProcessingStoreFromBackend(b whisker.Backend, name string) ProcessingStore {
+	return newPGProcessingStore(b, name)
+}
+
+func newPGProcessingStore(b whisker.Backend, name string) *pgProcessingStore {
 	return &pgProcessingStore{
 		exec:   b.DBExecutor(),
 		schema: b.SchemaBootstrap(),
+		clock:  whisker.ClockOf(b),
 		name:   name,
 	}
 }
@@ -31,12 +40,29 @@ func (ps *pgProcessingStore) table() string {
 	return "whisker_" + ps.name
 }
 
+// changed notifies Watch callers that document id changed, when the read
+// model is watched. Like event append notifications it is best-effort:
+// watchers also reload on their keepalive interval.
+func (ps *pgProcessingStore) changed(ctx context.Context, id string) {
+	if ps.notify {
+		_, _ = ps.exec.Exec(ctx, "SELECT pg_notify($1, $2)", watchChannel(ps.schema, ps.name), id)
+	}
+}
+
+// ensure creates the read model's table. Tables created before tombstones
+// lack deleted_at, which every read and write here uses; EnsureDeletedAt
+// adds it to those and leaves newer tables untouched.
 func (ps *pgProcessingStore) ensure(ctx context.Context) error {
-	return ps.schema.EnsureCollection(ctx, ps.exec, ps.name)
+	if err := ps.schema.EnsureCollection(ctx, ps.exec, ps.name); err != nil {
+		return err
+	}
+	return ps.schema.EnsureDeletedAt(ctx, ps.exec, ps.name)
 }
 
 // LoadState reads the projected document and its version from the collection.
-// Returns (nil, 0, nil) when the document does not exist.
+// Returns (nil, 0, nil) when the document does not exist. A tombstoned
+// document returns nil data with its last version, so the next upsert
+// revives it without resetting the version.
 func (ps *pgProcessingStore) LoadState(ctx context.Context, _ string, id string) ([]byte, int, error) {
 	if err := ps.ensure(ctx); err != nil {
 		return nil, 0, fmt.Errorf("processing store %s: ensure table: %w", ps.name, err)
@@ -44,10 +70,11 @@ func (ps *pgProcessingStore) LoadState(ctx context.Context, _ string, id string)
 
 	var data []byte
 	var version int
+	var deleted bool
 	err := ps.exec.QueryRow(ctx,
-		fmt.Sprintf(`SELECT data, version FROM %s WHERE id = $1`, ps.table()),
+		fmt.Sprintf(`SELECT data, version, deleted_at IS NOT NULL FROM %s WHERE id = $1`, ps.table()),
 		id,
-	).Scan(&data, &version)
+	).Scan(&data, &version, &deleted)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, 0, nil
@@ -55,6 +82,9 @@ func (ps *pgProcessingStore) LoadState(ctx context.Context, _ string, id string)
 	if err != nil {
 		return nil, 0, fmt.Errorf("processing store %s: load %s: %w", ps.name, id, err)
 	}
+	if deleted {
+		return nil, version, nil
+	}
 	return data, version, nil
 }
 
@@ -66,14 +96,15 @@ func (ps *pgProcessingStore) UpsertState(ctx context.Context, _ string, id strin
 	}
 
 	_, err := ps.exec.Exec(ctx,
-		fmt.Sprintf(`INSERT INTO %s (id, data, version, created_at, updated_at)
-		 VALUES ($1, $2, $3, now(), now())
-		 ON CONFLICT (id) DO UPDATE SET data = $2, version = $3, updated_at = now()`, ps.table()),
-		id, data, version+1,
+		fmt.Sprintf(`INSERT INTO %[1]s (id, data, version, created_at, updated_at)
+		 VALUES ($1, $2, $3, %[2]s, %[2]s)
+		 ON CONFLICT (id) DO UPDATE SET data = $2, version = $3, updated_at = %[2]s, deleted_at = NULL`, ps.table(), pg.NowExpr("$4")),
+		id, data, version+1, pg.Timestamp(ps.clock),
 	)
 	if err != nil {
 		return fmt.Errorf("processing store %s: upsert %s: %w", ps.name, id, err)
 	}
+	ps.changed(ctx, id)
 	return nil
 }
 
@@ -90,5 +121,205 @@ func (ps *pgProcessingStore) DeleteState(ctx context.Context, _ string, id strin
 	if err != nil {
 		return fmt.Errorf("processing store %s: delete %s: %w", ps.name, id, err)
 	}
+	ps.changed(ctx, id)
+	return nil
+}
+
+// TombstoneState marks a projected document as deleted without removing the
+// row. The version is bumped so change-tracking consumers see the deletion.
+func (ps *pgProcessingStore) TombstoneState(ctx context.Context, _ string, id string) error {
+	if err := ps.ensure(ctx); err != nil {
+		return fmt.Errorf("processing store %s: ensure table: %w", ps.name, err)
+	}
+
+	_, err := ps.exec.Exec(ctx,
+		fmt.Sprintf(`UPDATE %[1]s SET deleted_at = %[2]s, updated_at = %[2]s, version = version + 1
+		 WHERE id = $1 AND deleted_at IS NULL`, ps.table(), pg.NowExpr("$2")),
+		id, pg.Timestamp(ps.clock),
+	)
+	if err != nil {
+		return fmt.Errorf("processing store %s: tombstone %s: %w", ps.name, id, err)
+	}
+	ps.changed(ctx, id)
+	return nil
+}
+
+// AppendToArray appends elem to the array field of a projected document in
+// one statement, creating the document or the field when missing. A
+// tombstoned document is revived holding just the new array. The version is
+// bumped as UpsertState bumps it.
+func (ps *pgProcessingStore) AppendToArray(ctx context.Context, _ string, id, field string, elem []byte) error {
+	if !ident.IsField(field) {
+		return fmt.Errorf("processing store %s: append to %s: invalid field %q", ps.name, id, field)
+	}
+	if err := ps.ensure(ctx); err != nil {
+		return fmt.Errorf("processing store %s: ensure table: %w", ps.name, err)
+	}
+
+	_, err := ps.exec.Exec(ctx,
+		fmt.Sprintf(`INSERT INTO %[1]s AS t (id, data, version, created_at, updated_at)
+		 VALUES ($1, jsonb_build_object($2::text, jsonb_build_array($3::jsonb)), 1, %[2]s, %[2]s)
+		 ON CONFLICT (id) DO UPDATE SET data = CASE
+		   WHEN t.deleted_at IS NOT NULL THEN EXCLUDED.data
+		   WHEN jsonb_typeof(t.data->$2::text) = 'array' THEN jsonb_set(t.data, ARRAY[$2::text], (t.data->$2::text) || jsonb_build_array($3::jsonb))
+		   ELSE t.data || EXCLUDED.data
+		 END, version = t.version + 1, updated_at = %[2]s, deleted_at = NULL`, ps.table(), pg.NowExpr("$4")),
+		id, field, elem, pg.Timestamp(ps.clock),
+	)
+	if err != nil {
+		return fmt.Errorf("processing store %s: append to %s: %w", ps.name, id, err)
+	}
+	ps.changed(ctx, id)
+	return nil
+}
+
+// RemoveFromArray removes the elements containing elem from the array field
+// of a projected document in one statement. Missing and tombstoned documents
+// are left alone.
+func (ps *pgProcessingStore) RemoveFromArray(ctx context.Context, _ string, id, field string, elem []byte) error {
+	if !ident.IsField(field) {
+		return fmt.Errorf("processing store %s: remove from %s: invalid field %q", ps.name, id, field)
+	}
+	if err := ps.ensure(ctx); err != nil {
+		return fmt.Errorf("processing store %s: ensure table: %w", ps.name, err)
+	}
+
+	_, err := ps.exec.Exec(ctx,
+		fmt.Sprintf(`UPDATE %[1]s SET data = jsonb_set(data, ARRAY[$2::text], COALESCE(
+		   (SELECT jsonb_agg(e ORDER BY n) FROM jsonb_array_elements(data->$2::text) WITH ORDINALITY AS a(e, n) WHERE NOT e @> $3::jsonb),
+		   '[]'::jsonb)), version = version + 1, updated_at = %[2]s
+		 WHERE id = $1 AND deleted_at IS NULL AND jsonb_typeof(data->$2::text) = 'array'`, ps.table(), pg.NowExpr("$4")),
+		id, field, elem, pg.Timestamp(ps.clock),
+	)
+	if err != nil {
+		return fmt.Errorf("processing store %s: remove from %s: %w", ps.name, id, err)
+	}
+	ps.changed(ctx, id)
 	return nil
 }
+
+// NewShardedProcessingStore creates a ProcessingStore that spreads the
+// documents of the name collection over shards tables, whisker_{name}_0 to
+// whisker_{name}_{shards-1}, routing each ID to its shard with
+// schema.ShardOf. Read the shards back with documents.Sharded. Fewer than
+// one shard is taken as one, as documents.Sharded does.
+func NewShardedProcessingStore(b whisker.Backend, name string, shards int) ProcessingStore {
+	s := &shardedProcessingStore{shards: make([]*pgProcessingStore, max(shards, 1))}
+	for i := range s.shards {
+		s.shards[i] = newPGProcessingStore(b, schema.ShardName(name, i))
+	}
+	return s
+}
+
+type shardedProcessingStore struct {
+	shards []*pgProcessingStore
+}
+
+func (s *shardedProcessingStore) shard(id string) *pgProcessingStore {
+	return s.shards[schema.ShardOf(id, len(s.shards))]
+}
+
+func (s *shardedProcessingStore) LoadState(ctx context.Context, collection, id string) ([]byte, int, error) {
+	return s.shard(id).LoadState(ctx, collection, id)
+}
+
+func (s *shardedProcessingStore) UpsertState(ctx context.Context, collection, id string, data []byte, version int) error {
+	return s.shard(id).UpsertState(ctx, collection, id, data, version)
+}
+
+func (s *shardedProcessingStore) DeleteState(ctx context.Context, collection, id string) error {
+	return s.shard(id).DeleteState(ctx, collection, id)
+}
+
+func (s *shardedProcessingStore) TombstoneState(ctx context.Context, collection, id string) error {
+	return s.shard(id).TombstoneState(ctx, collection, id)
+}
+
+func (s *shardedProcessingStore) AppendToArray(ctx context.Context, collection, id, field string, elem []byte) error {
+	return s.shard(id).AppendToArray(ctx, collection, id, field, elem)
+}
+
+func (s *shardedProcessingStore) RemoveFromArray(ctx context.Context, collection, id, field string, elem []byte) error {
+	return s.shard(id).RemoveFromArray(ctx, collection, id, field, elem)
+}
+
+// shardedReadModel is implemented by subscribers whose read model may be
+// sharded. See Projection.Sharded.
+type shardedReadModel interface {
+	Shards() int
+}
+
+// readModelShards returns the number of tables sub's read model is spread
+// over, or 0 when it is a single whisker_{name} table.
+func readModelShards(sub Subscriber) int {
+	if s, ok := sub.(shardedReadModel); ok && s.Shards() > 1 {
+		return s.Shards()
+	}
+	return 0
+}
+
+// readModelNames returns the collections sub's read model is stored in: its
+// shards, or just its name.
+func readModelNames(sub Subscriber) []string {
+	n := readModelShards(sub)
+	if n == 0 {
+		return []string{sub.Name()}
+	}
+	names := make([]string, n)
+	for i := range names {
+		names[i] = schema.ShardName(sub.Name(), i)
+	}
+	return names
+}
+
+// readModelTables returns the collections of the read models sub writes:
+// those of a ReadModel, and of the members of a group.
+func readModelTables(sub Subscriber) []string {
+	var members []Subscriber
+	switch g := sub.(type) {
+	case *group:
+		members = g.members
+	case *emitterGroup:
+		members = g.members
+	default:
+		if _, ok := sub.(ReadModel); ok {
+			return readModelNames(sub)
+		}
+		return nil
+	}
+	var tables []string
+	for _, m := range members {
+		tables = append(tables, readModelTables(m)...)
+	}
+	return tables
+}
+
+// watchedReadModel is implemented by subscribers whose read-model writes
+// may notify Watch. See Projection.Watchable.
+type watchedReadModel interface {
+	Watched() bool
+}
+
+// processingStoreFor returns the ProcessingStore sub writes its read model
+// through on b, sharded when sub is, and notifying Watch when sub is watched.
+// A group gets one store per member.
+func processingStoreFor(b whisker.Backend, sub Subscriber) ProcessingStore {
+	switch g := sub.(type) {
+	case *group:
+		return newGroupProcessingStore(b, g)
+	case *emitterGroup:
+		return newGroupProcessingStore(b, g.group)
+	}
+	w, ok := sub.(watchedReadModel)
+	notify := ok && w.Watched()
+	if n := readModelShards(sub); n > 0 {
+		s := NewShardedProcessingStore(b, sub.Name(), n).(*shardedProcessingStore)
+		for _, shard := range s.shards {
+			shard.notify = notify
+		}
+		return s
+	}
+	ps := newPGProcessingStore(b, sub.Name())
+	ps.notify = notify
+	return ps
+}
@@ -97,3 +97,44 @@ func TestProcessingStore_Delete(t *testing.T) {
 		t.Errorf("version after delete: got %d, want 0", version)
 	}
 }
+
+func TestProcessingStore_AppendAndRemoveFromArray(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	ps := projections.NewProcessingStoreFromBackend(store, "ps_test_arrays").(projections.ArrayStore)
+	loader := ps.(projections.ProcessingStore)
+
+	for _, elem := range []string{`{"sku":"A1","qty":1}`, `{"sku":"B2","qty":3}`, `{"sku":"A1","qty":2}`} {
+		if err := ps.AppendToArray(ctx, "", "cart-1", "lines", []byte(elem)); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	if err := ps.RemoveFromArray(ctx, "", "cart-1", "lines", []byte(`{"sku":"A1"}`)); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	got, version, err := loader.LoadState(ctx, "", "cart-1")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if want := []byte(`{"lines":[{"sku":"B2","qty":3}]}`); !jsonEqual(got, want) {
+		t.Errorf("data: got %s, want %s", got, want)
+	}
+	if version != 4 {
+		t.Errorf("version: got %d, want 4", version)
+	}
+
+	if err := loader.UpsertState(ctx, "", "cart-2", []byte(`{"owner":"ann","lines":"none"}`), 0); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := ps.AppendToArray(ctx, "", "cart-2", "lines", []byte(`"x"`)); err != nil {
+		t.Fatalf("append to non-array: %v", err)
+	}
+	got, _, err = loader.LoadState(ctx, "", "cart-2")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if want := []byte(`{"owner":"ann","lines":["x"]}`); !jsonEqual(got, want) {
+		t.Errorf("data: got %s, want %s", got, want)
+	}
+}
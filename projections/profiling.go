@@ -0,0 +1,139 @@
+package projections
+
+import (
+	"cmp"
+	"context"
+	"runtime/metrics"
+	"runtime/pprof"
+	"slices"
+	"time"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+// slowBatchTopStreams is the number of busiest streams a slow-batch log
+// lists.
+const slowBatchTopStreams = 5
+
+// heapAllocsMetric counts bytes allocated on the heap since the process
+// started. Reading it does not stop the world, unlike runtime.ReadMemStats.
+const heapAllocsMetric = "/gc/heap/allocs:bytes"
+
+// SetSlowBatchThreshold makes the worker log, at warn level, every batch
+// whose Process call takes at least d: the batch's size and positions, how
+// many events of each type it held, its busiest streams and the bytes the
+// process allocated meanwhile. Allocations are counted process-wide, so they
+// include other workers running at the same time. Zero, the default,
+// disables the log.
+func (w *Worker) SetSlowBatchThreshold(d time.Duration) {
+	w.slowBatch = d
+}
+
+// SetProfileLabels makes the worker run the subscriber's Process under the
+// pprof label whisker_subscriber set to its checkpoint name, so CPU and
+// goroutine profiles attribute time to projections.
+func (w *Worker) SetProfileLabels(enabled bool) {
+	w.profileLabels = enabled
+}
+
+// labels returns the pprof labels the worker processes under.
+func (w *Worker) labels() pprof.LabelSet {
+	return pprof.Labels("whisker_subscriber", w.name())
+}
+
+// observe calls run for batch, under the worker's pprof labels, and logs
+// the batch if it is slow.
+func (w *Worker) observe(ctx context.Context, batch []events.Event, run func(context.Context) error) error {
+	if w.profileLabels {
+		inner := run
+		run = func(ctx context.Context) (err error) {
+			pprof.Do(ctx, w.labels(), func(ctx context.Context) { err = inner(ctx) })
+			return err
+		}
+	}
+	if w.slowBatch <= 0 {
+		return run(ctx)
+	}
+
+	start, allocs := time.Now(), heapAllocs()
+	err := run(ctx)
+	if elapsed := time.Since(start); elapsed >= w.slowBatch {
+		w.logSlowBatch(batch, elapsed, heapAllocs()-allocs, err)
+	}
+	return err
+}
+
+func (w *Worker) logSlowBatch(batch []events.Event, elapsed time.Duration, allocated uint64, err error) {
+	p := profileBatch(batch)
+	attrs := []any{
+		"worker", w.name(),
+		"duration", elapsed,
+		"threshold", w.slowBatch,
+		"events", len(batch),
+		"event_types", p.types,
+		"streams", p.streams,
+		"top_streams", p.top,
+		"heap_allocated_bytes", allocated,
+	}
+	if len(batch) > 0 {
+		attrs = append(attrs, "first_position", batch[0].GlobalPosition, "last_position", batch[len(batch)-1].GlobalPosition)
+	}
+	if w.profileLabels {
+		attrs = append(attrs, "pprof_labels", map[string]string{"whisker_subscriber": w.name()})
+	}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+	w.store.Logger().Warn("slow batch", attrs...)
+}
+
+// streamCount is the number of events of one stream in a batch.
+type streamCount struct {
+	Stream string `json:"stream"`
+	Events int    `json:"events"`
+}
+
+// batchProfile describes the make-up of a batch for the slow-batch log.
+type batchProfile struct {
+	types   map[string]int
+	streams int
+	top     []streamCount
+}
+
+// profileBatch counts a batch's events by type and stream and picks its
+// busiest streams, ties broken by stream ID.
+func profileBatch(batch []events.Event) batchProfile {
+	p := batchProfile{types: make(map[string]int)}
+	perStream := make(map[string]int)
+	for _, evt := range batch {
+		p.types[evt.Type]++
+		perStream[evt.StreamID]++
+	}
+	p.streams = len(perStream)
+
+	p.top = make([]streamCount, 0, len(perStream))
+	for stream, n := range perStream {
+		p.top = append(p.top, streamCount{Stream: stream, Events: n})
+	}
+	slices.SortFunc(p.top, func(a, b streamCount) int {
+		if c := cmp.Compare(b.Events, a.Events); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Stream, b.Stream)
+	})
+	if len(p.top) > slowBatchTopStreams {
+		p.top = p.top[:slowBatchTopStreams]
+	}
+	return p
+}
+
+// heapAllocs returns the bytes allocated on the heap since the process
+// started, or 0 if the runtime does not report them.
+func heapAllocs() uint64 {
+	sample := []metrics.Sample{{Name: heapAllocsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,102 @@
+package projections
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+func TestProfileBatch(t *testing.T) {
+	var batch []events.Event
+	for i := range 7 {
+		batch = append(batch, events.Event{StreamID: fmt.Sprintf("s%d", i), Type: "Tick"})
+	}
+	for range 3 {
+		batch = append(batch, events.Event{StreamID: "hot", Type: "Tock"})
+	}
+
+	p := profileBatch(batch)
+	if p.types["Tick"] != 7 || p.types["Tock"] != 3 {
+		t.Errorf("types = %v", p.types)
+	}
+	if p.streams != 8 {
+		t.Errorf("streams = %d, want 8", p.streams)
+	}
+	want := []streamCount{{"hot", 3}, {"s0", 1}, {"s1", 1}, {"s2", 1}, {"s3", 1}}
+	if fmt.Sprint(p.top) != fmt.Sprint(want) {
+		t.Errorf("top = %v, want %v", p.top, want)
+	}
+}
+
+func TestWorker_SlowBatchLog(t *testing.T) {
+	var buf bytes.Buffer
+	store := newFakeStore()
+	store.logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	w := NewWorker(store, NewHandler("mailer"))
+	w.SetSlowBatchThreshold(time.Nanosecond)
+	w.SetProfileLabels(true)
+
+	batch := []events.Event{
+		{StreamID: "order-1", Type: "OrderPaid", GlobalPosition: 4},
+		{StreamID: "order-1", Type: "OrderShipped", GlobalPosition: 9},
+	}
+	boom := errors.New("boom")
+	var label string
+	err := w.process(context.Background(), batch, func(ctx context.Context) error {
+		label, _ = pprof.Label(ctx, "whisker_subscriber")
+		time.Sleep(time.Millisecond)
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("got %v, want boom", err)
+	}
+	if label != "mailer" {
+		t.Errorf("pprof label = %q, want mailer", label)
+	}
+
+	var entry struct {
+		Msg           string
+		Worker        string
+		Events        int
+		EventTypes    map[string]int `json:"event_types"`
+		TopStreams    []streamCount  `json:"top_streams"`
+		FirstPosition int64          `json:"first_position"`
+		LastPosition  int64          `json:"last_position"`
+		Error         string
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("decode log %q: %v", buf.String(), err)
+	}
+	if entry.Msg != "slow batch" || entry.Worker != "mailer" || entry.Events != 2 {
+		t.Errorf("got %+v", entry)
+	}
+	if entry.EventTypes["OrderPaid"] != 1 || len(entry.TopStreams) != 1 || entry.TopStreams[0].Events != 2 {
+		t.Errorf("distribution: %+v", entry)
+	}
+	if entry.FirstPosition != 4 || entry.LastPosition != 9 || entry.Error != "boom" {
+		t.Errorf("positions or error: %+v", entry)
+	}
+}
+
+func TestWorker_FastBatchNotLogged(t *testing.T) {
+	var buf bytes.Buffer
+	store := newFakeStore()
+	store.logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	w := NewWorker(store, NewHandler("mailer"))
+	w.SetSlowBatchThreshold(time.Hour)
+
+	if err := w.process(context.Background(), nil, func(context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected log: %s", buf.String())
+	}
+}
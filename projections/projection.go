@@ -2,10 +2,13 @@ package projections
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/ripkitten-co/whisker"
 	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/internal/codecs"
 )
 
 // ApplyFunc is the callback signature for read-model projections. It receives
@@ -13,20 +16,37 @@ import (
 // state. Returning nil deletes the read model for that stream.
 type ApplyFunc[T any] func(ctx context.Context, evt events.Event, state *T) (*T, error)
 
+// ElemFunc returns the array element an event adds to or removes from a read
+// model. See Projection.AppendOn.
+type ElemFunc func(ctx context.Context, evt events.Event) (any, error)
+
+// arrayHandler edits an array field of the read model for an event type.
+type arrayHandler struct {
+	field  string
+	elem   ElemFunc
+	remove bool
+}
+
 // Projection builds a read model from event streams. Register event handlers
 // with On, then add the projection to a Daemon for continuous processing.
 type Projection[T any] struct {
-	name     string
-	store    *whisker.Store
-	handlers map[string]ApplyFunc[T]
+	name       string
+	store      whisker.Backend
+	handlers   map[string]ApplyFunc[T]
+	arrays     map[string]arrayHandler
+	tombstones bool
+	version    int
+	shards     int
+	watched    bool
 }
 
 // New creates a projection that writes to the whisker_{name} collection.
-func New[T any](store *whisker.Store, name string) *Projection[T] {
+func New[T any](store whisker.Backend, name string) *Projection[T] {
 	return &Projection[T]{
 		name:     name,
 		store:    store,
 		handlers: make(map[string]ApplyFunc[T]),
+		arrays:   make(map[string]arrayHandler),
 	}
 }
 
@@ -34,9 +54,89 @@ func New[T any](store *whisker.Store, name string) *Projection[T] {
 // for method chaining.
 func (p *Projection[T]) On(eventType string, fn ApplyFunc[T]) *Projection[T] {
 	p.handlers[eventType] = fn
+	delete(p.arrays, eventType)
+	return p
+}
+
+// AppendOn registers a handler for the given event type that appends the
+// element fn returns to the array field of the read model, named by its JSON
+// key. The element is appended in the database, so list-building projections
+// do not load and rewrite the whole growing array on every event:
+//
+//	p.AppendOn("ItemAdded", "items", func(_ context.Context, evt events.Event) (any, error) {
+//		var item Item
+//		return item, json.Unmarshal(evt.Data, &item)
+//	})
+//
+// The document and the field are created when missing. It replaces any
+// handler registered for the event type with On or RemoveOn.
+func (p *Projection[T]) AppendOn(eventType, field string, fn ElemFunc) *Projection[T] {
+	p.arrays[eventType] = arrayHandler{field: field, elem: fn}
+	delete(p.handlers, eventType)
+	return p
+}
+
+// RemoveOn registers a handler for the given event type that removes from
+// the array field of the read model every element containing the one fn
+// returns, as the JSONB @> operator tests: returning
+// map[string]any{"sku": "A1"} removes the items whose sku is A1. It replaces
+// any handler registered for the event type with On or AppendOn.
+func (p *Projection[T]) RemoveOn(eventType, field string, fn ElemFunc) *Projection[T] {
+	p.arrays[eventType] = arrayHandler{field: field, elem: fn, remove: true}
+	delete(p.handlers, eventType)
+	return p
+}
+
+// Tombstones switches deletion to soft-delete mode: when a handler returns nil
+// the read model row is kept with deleted_at set instead of being removed.
+// Query the collection with documents.ExcludeDeleted to hide tombstones.
+func (p *Projection[T]) Tombstones() *Projection[T] {
+	p.tombstones = true
+	return p
+}
+
+// Sharded spreads the read model over n tables, whisker_{name}_0 to
+// whisker_{name}_{n-1}, each stream's document going to the shard
+// schema.ShardOf picks for its ID, for read models too large for one table.
+// Rebuild recreates every shard. Read them with documents.Sharded, which
+// routes by the same hash. Changing n moves documents between shards, so it
+// needs a rebuild.
+func (p *Projection[T]) Sharded(n int) *Projection[T] {
+	p.shards = n
 	return p
 }
 
+// Shards returns the number of tables set with Sharded, or 0.
+func (p *Projection[T]) Shards() int {
+	return p.shards
+}
+
+// Watchable makes every write to the read model notify the callers of Watch
+// following the document, at the cost of one more statement per write.
+func (p *Projection[T]) Watchable() *Projection[T] {
+	p.watched = true
+	return p
+}
+
+// Watched reports whether Watchable was set.
+func (p *Projection[T]) Watched() bool {
+	return p.watched
+}
+
+// WithVersion declares the version of the projection's handlers, which the
+// daemon checks against the version its read model was built with. Bump it
+// when a change to the handlers would build a different read model. See
+// Versioned.
+func (p *Projection[T]) WithVersion(n int) *Projection[T] {
+	p.version = n
+	return p
+}
+
+// Version returns the version set with WithVersion, or 0.
+func (p *Projection[T]) Version() int {
+	return p.version
+}
+
 // Name returns the projection identifier, used for checkpointing and table naming.
 func (p *Projection[T]) Name() string {
 	return p.name
@@ -44,55 +144,119 @@ func (p *Projection[T]) Name() string {
 
 // EventTypes returns the event types this projection handles.
 func (p *Projection[T]) EventTypes() []string {
-	types := make([]string, 0, len(p.handlers))
+	types := make([]string, 0, len(p.handlers)+len(p.arrays))
 	for t := range p.handlers {
 		types = append(types, t)
 	}
+	for t := range p.arrays {
+		types = append(types, t)
+	}
 	return types
 }
 
 // Process applies matching events to the read model. For each event it loads
 // current state, calls the registered handler, then upserts or deletes the
-// result.
+// result. A failure is returned as an *EventError naming the event.
 func (p *Projection[T]) Process(ctx context.Context, evts []events.Event, ps ProcessingStore) error {
 	codec := p.store.JSONCodec()
 	for _, evt := range evts {
-		fn, ok := p.handlers[evt.Type]
-		if !ok {
-			continue
+		if err := p.apply(ctx, codec, evt, ps); err != nil {
+			return &EventError{Event: evt, Err: err}
 		}
+	}
+	return nil
+}
 
-		var state *T
-		data, version, err := ps.LoadState(ctx, p.name, evt.StreamID)
-		if err != nil {
-			return fmt.Errorf("projection %s: load state for %s: %w", p.name, evt.StreamID, err)
-		}
-		if data != nil {
-			state = new(T)
-			if err := codec.Unmarshal(data, state); err != nil {
-				return fmt.Errorf("projection %s: unmarshal state for %s: %w", p.name, evt.StreamID, err)
-			}
-		}
+func (p *Projection[T]) apply(ctx context.Context, codec codecs.Codec, evt events.Event, ps ProcessingStore) error {
+	if ah, ok := p.arrays[evt.Type]; ok {
+		return p.applyArray(ctx, evt, ah, ps)
+	}
+	fn, ok := p.handlers[evt.Type]
+	if !ok {
+		return nil
+	}
 
-		result, err := fn(ctx, evt, state)
-		if err != nil {
-			return fmt.Errorf("projection %s: handle %s for %s: %w", p.name, evt.Type, evt.StreamID, err)
+	var state *T
+	data, version, err := ps.LoadState(ctx, p.name, evt.StreamID)
+	if err != nil {
+		return fmt.Errorf("projection %s: load state for %s: %w", p.name, evt.StreamID, err)
+	}
+	if data != nil {
+		state = new(T)
+		if err := codec.Unmarshal(data, state); err != nil {
+			return fmt.Errorf("projection %s: unmarshal state for %s: %w", p.name, evt.StreamID, err)
 		}
+	}
 
-		if result == nil {
-			if err := ps.DeleteState(ctx, p.name, evt.StreamID); err != nil {
-				return fmt.Errorf("projection %s: delete state for %s: %w", p.name, evt.StreamID, err)
-			}
-			continue
-		}
+	result, err := fn(ctx, evt, state)
+	if err != nil {
+		return fmt.Errorf("projection %s: handle %s for %s: %w", p.name, evt.Type, evt.StreamID, err)
+	}
 
-		out, err := codec.Marshal(result)
-		if err != nil {
-			return fmt.Errorf("projection %s: marshal state for %s: %w", p.name, evt.StreamID, err)
-		}
-		if err := ps.UpsertState(ctx, p.name, evt.StreamID, out, version); err != nil {
-			return fmt.Errorf("projection %s: upsert state for %s: %w", p.name, evt.StreamID, err)
+	if result == nil {
+		if err := p.deleteState(ctx, ps, evt.StreamID); err != nil {
+			return fmt.Errorf("projection %s: delete state for %s: %w", p.name, evt.StreamID, err)
 		}
+		return nil
+	}
+
+	out, err := codec.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("projection %s: marshal state for %s: %w", p.name, evt.StreamID, err)
+	}
+	if err := ps.UpsertState(ctx, p.name, evt.StreamID, out, version); err != nil {
+		return fmt.Errorf("projection %s: upsert state for %s: %w", p.name, evt.StreamID, err)
+	}
+	return nil
+}
+
+func (p *Projection[T]) deleteState(ctx context.Context, ps ProcessingStore, id string) error {
+	if !p.tombstones {
+		return ps.DeleteState(ctx, p.name, id)
+	}
+	ts, ok := ps.(TombstoneStore)
+	if !ok {
+		return errors.New("projections: processing store does not support tombstones")
+	}
+	return ts.TombstoneState(ctx, p.name, id)
+}
+
+// applyArray runs an AppendOn or RemoveOn handler through ps, which must be
+// an ArrayStore.
+func (p *Projection[T]) applyArray(ctx context.Context, evt events.Event, ah arrayHandler, ps ProcessingStore) error {
+	as, ok := ps.(ArrayStore)
+	if !ok {
+		return fmt.Errorf("projection %s: handle %s for %s: processing store does not support array updates", p.name, evt.Type, evt.StreamID)
+	}
+	elem, err := ah.elem(ctx, evt)
+	if err != nil {
+		return fmt.Errorf("projection %s: handle %s for %s: %w", p.name, evt.Type, evt.StreamID, err)
+	}
+	data, err := marshalElem(p.store.JSONCodec(), elem)
+	if err != nil {
+		return fmt.Errorf("projection %s: marshal element for %s: %w", p.name, evt.StreamID, err)
+	}
+	if ah.remove {
+		err = as.RemoveFromArray(ctx, p.name, evt.StreamID, ah.field, data)
+	} else {
+		err = as.AppendToArray(ctx, p.name, evt.StreamID, ah.field, data)
+	}
+	if err != nil {
+		return fmt.Errorf("projection %s: update %s for %s: %w", p.name, ah.field, evt.StreamID, err)
 	}
 	return nil
 }
+
+// marshalElem encodes elem as it is encoded inside a document's array, which
+// the codec may encode differently from a top-level struct.
+func marshalElem(codec codecs.Codec, elem any) ([]byte, error) {
+	data, err := codec.Marshal([]any{elem})
+	if err != nil {
+		return nil, err
+	}
+	var arr []json.RawMessage
+	if err := json.Unmarshal(data, &arr); err != nil {
+		return nil, err
+	}
+	return arr[0], nil
+}
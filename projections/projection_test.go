@@ -2,9 +2,11 @@ package projections
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/schema"
 )
 
 type OrderSummary struct {
@@ -42,6 +44,29 @@ func TestProjection_Name(t *testing.T) {
 	}
 }
 
+func TestProjection_WithVersion(t *testing.T) {
+	p := New[OrderSummary](nil, "order_summaries")
+	if got := subscriberVersion(p); got != 0 {
+		t.Errorf("default version = %d, want 0", got)
+	}
+	if got := subscriberVersion(p.WithVersion(3)); got != 3 {
+		t.Errorf("version = %d, want 3", got)
+	}
+	if got := subscriberVersion(NewHandler("mailer")); got != 0 {
+		t.Errorf("handler version = %d, want 0", got)
+	}
+}
+
+func TestDaemon_AcceptVersionRejectsUnversioned(t *testing.T) {
+	d := NewDaemon(newFakeStore())
+	d.Add(New[OrderSummary](nil, "order_summaries"))
+
+	err := d.AcceptVersion(context.Background(), "order_summaries")
+	if err == nil || !strings.Contains(err.Error(), "not versioned") {
+		t.Errorf("got %v, want not versioned error", err)
+	}
+}
+
 func TestProjection_EventTypes(t *testing.T) {
 	p := New[OrderSummary](nil, "order_summaries")
 	p.On("OrderCreated", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
@@ -56,3 +81,37 @@ func TestProjection_EventTypes(t *testing.T) {
 		t.Fatalf("got %d types, want 2", len(types))
 	}
 }
+
+func TestProjection_ShardedReadModel(t *testing.T) {
+	p := New[OrderSummary](newFakeStore(), "order_summaries")
+	if got := readModelNames(p); len(got) != 1 || got[0] != "order_summaries" {
+		t.Errorf("unsharded: got %v", got)
+	}
+	if _, ok := processingStoreFor(newFakeStore(), p).(*pgProcessingStore); !ok {
+		t.Error("unsharded projection should use a plain processing store")
+	}
+
+	p.Sharded(3)
+	want := []string{"order_summaries_0", "order_summaries_1", "order_summaries_2"}
+	if got := readModelNames(p); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("sharded: got %v, want %v", got, want)
+	}
+	ps, ok := processingStoreFor(newFakeStore(), p).(*shardedProcessingStore)
+	if !ok {
+		t.Fatal("sharded projection should use a sharded processing store")
+	}
+	for _, id := range []string{"o1", "o2", "o3"} {
+		if got, want := ps.shard(id).name, want[schema.ShardOf(id, 3)]; got != want {
+			t.Errorf("%s routed to %s, want %s", id, got, want)
+		}
+	}
+}
+
+func TestNewShardedProcessingStore_ClampsShards(t *testing.T) {
+	for _, n := range []int{0, -2} {
+		ps := NewShardedProcessingStore(newFakeStore(), "order_summaries", n).(*shardedProcessingStore)
+		if len(ps.shards) != 1 || ps.shard("o1").name != "order_summaries_0" {
+			t.Errorf("%d shards: got %d", n, len(ps.shards))
+		}
+	}
+}
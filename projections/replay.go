@@ -0,0 +1,96 @@
+package projections
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+// ReadModel is implemented by subscribers whose Process only writes read
+// models through its ProcessingStore, so their events can be replayed
+// without repeating side effects. Projections are read models; handlers and
+// links are not. Rebuild, ReplayStream and WithAutoRebuild only replay read
+// models, and a custom subscriber opts in by implementing ReadModelOnly.
+type ReadModel interface {
+	Subscriber
+	ReadModelOnly()
+}
+
+// ReadModelOnly marks the projection as a ReadModel.
+func (p *Projection[T]) ReadModelOnly() {}
+
+// ReplayStream rebuilds the read model of a single stream: it deletes the
+// projection's document for streamID and re-applies that stream's events up
+// to the projection's checkpoint, in one transaction. Events past the
+// checkpoint are left for the daemon. Use it to repair one corrupted document
+// without a full Rebuild. The read model's version restarts from the replayed
+// events.
+//
+// Only read-model projections can be replayed; handlers and links are
+// rejected because replaying them would repeat side effects or emitted
+// events. Fails if another instance holds the projection's lock. It reads
+// the default event store, or the one sub was bound to with FromEventStore.
+func ReplayStream(ctx context.Context, store Store, sub Subscriber, streamID string) error {
+	sub, eventStore := unwrapEventStore(sub)
+	name := sub.Name()
+	if _, ok := sub.(ReadModel); !ok {
+		return fmt.Errorf("replay %s/%s: only read-model projections can be replayed", name, streamID)
+	}
+
+	w := NewWorker(store, sub)
+	w.SetEventStore(eventStore)
+	acquired, err := w.TryAcquireLock(ctx)
+	if err != nil {
+		return fmt.Errorf("replay %s/%s: %w", name, streamID, err)
+	}
+	if !acquired {
+		return fmt.Errorf("replay %s/%s: another instance holds the lock", name, streamID)
+	}
+	defer releaseLock(ctx, w)
+
+	sess, err := store.Session(ctx)
+	if err != nil {
+		return fmt.Errorf("replay %s/%s: %w", name, streamID, err)
+	}
+	defer func() { _ = sess.Close(ctx) }()
+
+	position, _, err := NewCheckpointStore(sess).Load(ctx, w.name())
+	if err != nil {
+		return fmt.Errorf("replay %s/%s: %w", name, streamID, err)
+	}
+
+	evts, err := events.NewNamed(sess, eventStore).ReadStream(ctx, streamID, 0)
+	if err != nil {
+		return fmt.Errorf("replay %s/%s: %w", name, streamID, err)
+	}
+	var applied []events.Event
+	for _, evt := range evts {
+		if evt.GlobalPosition <= position {
+			applied = append(applied, evt)
+		}
+	}
+
+	ps := processingStoreFor(sess, sub)
+	if err := ps.DeleteState(ctx, name, streamID); err != nil {
+		return fmt.Errorf("replay %s/%s: %w", name, streamID, err)
+	}
+	if err := sub.Process(ctx, w.filterEvents(applied), ps); err != nil {
+		return fmt.Errorf("replay %s/%s: %w", name, streamID, err)
+	}
+
+	if err := sess.Commit(ctx); err != nil {
+		return fmt.Errorf("replay %s/%s: %w", name, streamID, err)
+	}
+	return nil
+}
+
+// ReplayStream replays one stream through the registered projection name.
+// See the package-level ReplayStream.
+func (d *Daemon) ReplayStream(ctx context.Context, name, streamID string) error {
+	sub, err := d.findSubscriber(name)
+	if err != nil {
+		return err
+	}
+	return ReplayStream(ctx, d.store, sub, streamID)
+}
@@ -0,0 +1,125 @@
+//go:build integration
+
+package projections_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/documents"
+	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/projections"
+)
+
+func TestReplayStream_RepairsOneDocument(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	for _, id := range []string{"order-r1", "order-r2"} {
+		err := es.Append(ctx, id, 0, []events.Event{
+			{Type: "OrderCreated", Data: []byte(`{}`)},
+			{Type: "OrderPaid", Data: []byte(`{}`)},
+		})
+		if err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	proj := projections.New[OrderSummary](store, "replay_orders").
+		On("OrderCreated", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+			return &OrderSummary{ID: evt.StreamID, Status: "created"}, nil
+		}).
+		On("OrderPaid", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+			state.Status = "paid"
+			return state, nil
+		}).
+		On("OrderShipped", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+			state.Status = "shipped"
+			return state, nil
+		})
+
+	if _, err := projections.NewWorker(store, proj).ProcessBatch(ctx); err != nil {
+		t.Fatalf("process batch: %v", err)
+	}
+
+	// corrupt one read model, then append an event the projection has not seen
+	if _, err := store.DBExecutor().Exec(ctx,
+		`UPDATE whisker_replay_orders SET data = '{"Status":"garbage"}' WHERE id = 'order-r1'`,
+	); err != nil {
+		t.Fatalf("corrupt: %v", err)
+	}
+	if err := es.Append(ctx, "order-r1", 2, []events.Event{{Type: "OrderShipped", Data: []byte(`{}`)}}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	daemon := projections.NewDaemon(store)
+	daemon.Add(proj)
+	if err := daemon.ReplayStream(ctx, "replay_orders", "order-r1"); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+
+	col := documents.Collection[OrderSummary](store, "replay_orders")
+	got, err := col.Load(ctx, "order-r1")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Status != "paid" {
+		t.Errorf("got status %q, want paid: events past the checkpoint must be left to the daemon", got.Status)
+	}
+}
+
+func TestReplayStream_RejectsHandlers(t *testing.T) {
+	store := setupStore(t)
+	h := projections.NewHandler("replay_mailer").
+		On("OrderPaid", func(ctx context.Context, evt events.Event) error { return nil })
+
+	err := projections.ReplayStream(context.Background(), store, h, "order-1")
+	if err == nil || !strings.Contains(err.Error(), "only read-model projections") {
+		t.Fatalf("got %v, want rejection of handler", err)
+	}
+}
+
+func TestProjection_DryRun(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	err := es.Append(ctx, "order-dry", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{}`)},
+		{Type: "OrderPaid", Data: []byte(`{"amount":25}`)},
+	})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	proj := projections.New[OrderSummary](store, "dry_orders").
+		On("OrderCreated", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+			return &OrderSummary{ID: evt.StreamID, Status: "created"}, nil
+		}).
+		On("OrderPaid", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+			state.Status = "paid"
+			return state, nil
+		})
+
+	got, err := proj.DryRun(ctx, "order-dry")
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if got == nil || got.Status != "paid" {
+		t.Errorf("got %+v, want paid", got)
+	}
+
+	var exists bool
+	if err := store.DBExecutor().QueryRow(ctx, "SELECT to_regclass('whisker_dry_orders') IS NOT NULL").Scan(&exists); err != nil {
+		t.Fatalf("check table: %v", err)
+	}
+	if exists {
+		t.Error("dry run created the read model table")
+	}
+
+	if got, err := proj.DryRun(ctx, "order-missing"); err != nil || got != nil {
+		t.Errorf("missing stream: got %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,102 @@
+package projections
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+// ErrResetNotConfirmed is returned by ResetHandler when it is not given
+// ConfirmReplay with the handler's name.
+var ErrResetNotConfirmed = errors.New("projections: reset not confirmed")
+
+// ResetOption configures ResetHandler.
+type ResetOption func(*resetConfig)
+
+type resetConfig struct {
+	confirmed string
+}
+
+// ConfirmReplay acknowledges that ResetHandler will deliver every event past
+// the new position to the named handler again, repeating its side effects.
+// name must match the handler being reset, so a confirmation cannot be
+// reused for another handler by accident.
+func ConfirmReplay(name string) ResetOption {
+	return func(c *resetConfig) { c.confirmed = name }
+}
+
+// ResetHandler moves the checkpoint of the named handler or link to
+// toPosition, so the daemon delivers every event after it again; 0 replays
+// the whole log. Unlike Rebuild nothing is dropped, and the handler is
+// responsible for the side effects it repeats. Moving forward skips events
+// instead. The status goes back to running, clearing a dead letter.
+//
+// Because it repeats side effects it requires ConfirmReplay(name) and fails
+// with ErrResetNotConfirmed otherwise. A ReadModel is rejected with a hint to
+// Rebuild it, as is a position past the head of the event log. It takes the
+// subscriber's advisory lock and fails if another instance holds it, so stop
+// the workers running the handler first; they resume from the new position
+// once restarted. The reset is logged through the store's logger.
+func (d *Daemon) ResetHandler(ctx context.Context, name string, toPosition int64, opts ...ResetOption) error {
+	var cfg resetConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cfg.confirmed != name {
+		return fmt.Errorf("daemon: reset %s: %w", name, ErrResetNotConfirmed)
+	}
+	if toPosition < 0 {
+		return fmt.Errorf("daemon: reset %s: negative position %d", name, toPosition)
+	}
+	sub, err := d.findSubscriber(name)
+	if err != nil {
+		return err
+	}
+	w := d.newWorker(sub)
+	if _, ok := w.subscriber.(ReadModel); ok {
+		return fmt.Errorf("daemon: reset %s: read models are replayed with Rebuild", name)
+	}
+
+	acquired, err := w.TryAcquireLock(ctx)
+	if err != nil {
+		return fmt.Errorf("daemon: reset %s: acquire lock: %w", name, err)
+	}
+	if !acquired {
+		return fmt.Errorf("daemon: reset %s: another instance holds the lock", name)
+	}
+	defer releaseLock(ctx, w)
+
+	sess, err := d.store.Session(ctx)
+	if err != nil {
+		return fmt.Errorf("daemon: reset %s: %w", name, err)
+	}
+	defer func() { _ = sess.Close(ctx) }()
+
+	head, err := events.NewNamed(sess, w.eventStore).HeadPosition(ctx)
+	if err != nil {
+		return fmt.Errorf("daemon: reset %s: %w", name, err)
+	}
+	if toPosition > head {
+		return fmt.Errorf("daemon: reset %s: position %d is past the head %d", name, toPosition, head)
+	}
+	cs := NewCheckpointStore(sess)
+	from, _, err := cs.Load(ctx, w.name())
+	if err != nil {
+		return fmt.Errorf("daemon: reset %s: %w", name, err)
+	}
+	if err := cs.Save(ctx, w.name(), toPosition); err != nil {
+		return fmt.Errorf("daemon: reset %s: %w", name, err)
+	}
+	if err := cs.SetStatus(ctx, w.name(), "running"); err != nil {
+		return fmt.Errorf("daemon: reset %s: %w", name, err)
+	}
+	if err := sess.Commit(ctx); err != nil {
+		return fmt.Errorf("daemon: reset %s: %w", name, err)
+	}
+
+	d.store.Logger().Warn("handler reset",
+		"projection", w.name(), "from_position", from, "to_position", toPosition)
+	return nil
+}
@@ -0,0 +1,65 @@
+package projections
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDaemon_RebuildRefusesHandlers(t *testing.T) {
+	store := newFakeStore()
+	d := NewDaemon(store)
+	d.Add(NewHandler("mailer"))
+	d.Add(FromEventStore("billing", NewHandler("invoicer")))
+
+	for _, name := range []string{"mailer", "invoicer"} {
+		if err := d.Rebuild(context.Background(), name); !errors.Is(err, ErrNotReadModel) {
+			t.Errorf("rebuild %s: got %v, want ErrNotReadModel", name, err)
+		}
+		if err := d.RebuildWait(context.Background(), name, 0); !errors.Is(err, ErrNotReadModel) {
+			t.Errorf("rebuild wait %s: got %v, want ErrNotReadModel", name, err)
+		}
+	}
+	if len(store.locked) != 0 || store.unlocks != 0 {
+		t.Error("refused rebuild should not take the lock")
+	}
+}
+
+func TestDaemon_ResetHandlerRequiresConfirmation(t *testing.T) {
+	d := NewDaemon(newFakeStore())
+	d.Add(NewHandler("mailer"))
+
+	for _, opts := range [][]ResetOption{nil, {ConfirmReplay("billing")}} {
+		err := d.ResetHandler(context.Background(), "mailer", 0, opts...)
+		if !errors.Is(err, ErrResetNotConfirmed) {
+			t.Errorf("got %v, want ErrResetNotConfirmed", err)
+		}
+	}
+}
+
+func TestDaemon_ResetHandlerRejects(t *testing.T) {
+	store := newFakeStore()
+	store.locked[lockHash("locked")] = true
+	d := NewDaemon(store)
+	d.Add(NewHandler("mailer"))
+	d.Add(NewHandler("locked"))
+	d.Add(New[OrderSummary](store, "order_summaries"))
+
+	tests := []struct {
+		name     string
+		position int64
+		want     string
+	}{
+		{"mailer", -1, "negative position"},
+		{"order_summaries", 0, "replayed with Rebuild"},
+		{"locked", 0, "another instance holds the lock"},
+		{"missing", 0, "not found"},
+	}
+	for _, tt := range tests {
+		err := d.ResetHandler(context.Background(), tt.name, tt.position, ConfirmReplay(tt.name))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("reset %s: got %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
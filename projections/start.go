@@ -0,0 +1,122 @@
+package projections
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ripkitten-co/whisker/events"
+)
+
+// Start is where a subscriber without a checkpoint begins processing; see
+// StartFrom. The zero value is Beginning.
+type Start struct {
+	kind     startKind
+	position int64
+	at       time.Time
+}
+
+type startKind int
+
+const (
+	startBeginning startKind = iota
+	startEnd
+	startPosition
+	startTimestamp
+)
+
+var (
+	// Beginning processes the whole event log, the default.
+	Beginning = Start{}
+	// End skips the events appended before the subscriber first runs and
+	// only processes new ones.
+	End = Start{kind: startEnd}
+)
+
+// Position processes the events after global position n.
+func Position(n int64) Start {
+	return Start{kind: startPosition, position: n}
+}
+
+// Timestamp processes the events appended after t, skipping those whose
+// created_at is at or before it.
+func Timestamp(t time.Time) Start {
+	return Start{kind: startTimestamp, at: t}
+}
+
+func (s Start) String() string {
+	switch s.kind {
+	case startEnd:
+		return "end"
+	case startPosition:
+		return fmt.Sprintf("position %d", s.position)
+	case startTimestamp:
+		return "timestamp " + s.at.Format(time.RFC3339Nano)
+	default:
+		return "beginning"
+	}
+}
+
+// resolve returns the checkpoint position s stands for in es.
+func (s Start) resolve(ctx context.Context, es *events.Store) (int64, error) {
+	switch s.kind {
+	case startEnd:
+		return es.HeadPosition(ctx)
+	case startPosition:
+		if s.position < 0 {
+			return 0, fmt.Errorf("negative start position %d", s.position)
+		}
+		return s.position, nil
+	case startTimestamp:
+		return es.HeadPositionAsOf(ctx, s.at)
+	default:
+		return 0, nil
+	}
+}
+
+// SubscriberOption configures a subscriber added to a Daemon.
+type SubscriberOption func(*subscriberConfig)
+
+type subscriberConfig struct {
+	start   Start
+	exclude events.Exclude
+}
+
+// StartFrom sets where the subscriber begins when it has no checkpoint yet,
+// e.g. StartFrom(End) for a new handler that must not fire for years-old
+// events. Once the subscriber has a checkpoint the option has no effect;
+// ResetHandler or Rebuild move it afterwards. Rebuild always replays from
+// the beginning.
+func StartFrom(s Start) SubscriberOption {
+	return func(c *subscriberConfig) { c.start = s }
+}
+
+// SetStartFrom makes the worker create a missing checkpoint at s before it
+// first processes, instead of starting from the beginning of the log. Call
+// it before processing.
+func (w *Worker) SetStartFrom(s Start) {
+	w.start = s
+	w.startChecked = false
+}
+
+// initCheckpoint creates the worker's checkpoint at its start position if it
+// has none yet. It runs once per worker and must run before anything else
+// creates the checkpoint row, such as Claim.
+func (w *Worker) initCheckpoint(ctx context.Context) error {
+	if w.startChecked || w.start.kind == startBeginning {
+		return nil
+	}
+	pos, err := w.start.resolve(ctx, events.NewNamed(w.store, w.eventStore))
+	if err != nil {
+		return fmt.Errorf("worker %s: start from %s: %w", w.name(), w.start, err)
+	}
+	created, err := w.checkpoint.Init(ctx, w.name(), pos)
+	if err != nil {
+		return fmt.Errorf("worker %s: %w", w.name(), err)
+	}
+	if created {
+		w.store.Logger().Info("checkpoint created", "worker", w.name(), "start", w.start.String(), "position", pos)
+	}
+	w.startChecked = true
+	return nil
+}
@@ -0,0 +1,62 @@
+package projections
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStart_Resolve(t *testing.T) {
+	ctx := context.Background()
+	for _, s := range []Start{Beginning, Position(0)} {
+		if pos, err := s.resolve(ctx, nil); err != nil || pos != 0 {
+			t.Errorf("%s: got %d %v, want 0", s, pos, err)
+		}
+	}
+	if pos, err := Position(42).resolve(ctx, nil); err != nil || pos != 42 {
+		t.Errorf("position 42: got %d %v", pos, err)
+	}
+	if _, err := Position(-1).resolve(ctx, nil); err == nil {
+		t.Error("expected an error for a negative position")
+	}
+}
+
+func TestStart_String(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[Start]string{
+		Beginning:     "beginning",
+		End:           "end",
+		Position(7):   "position 7",
+		Timestamp(at): "timestamp 2024-03-01T12:00:00Z",
+	}
+	for s, want := range tests {
+		if got := s.String(); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestDaemon_AddStartFrom(t *testing.T) {
+	d := NewDaemon(newFakeStore())
+	d.Add(NewHandler("mailer"), StartFrom(End))
+	d.Add(FromEventStore("billing", NewHandler("mailer")), StartFrom(Position(9)))
+	d.Add(NewHandler("auditor"))
+
+	if w := d.newWorker(NewHandler("mailer")); w.start != End {
+		t.Errorf("mailer: got %s, want end", w.start)
+	}
+	if w := d.newWorker(FromEventStore("billing", NewHandler("mailer"))); w.start != Position(9) {
+		t.Errorf("billing:mailer: got %s, want position 9", w.start)
+	}
+	if w := d.newWorker(NewHandler("auditor")); w.start != Beginning {
+		t.Errorf("auditor: got %s, want beginning", w.start)
+	}
+}
+
+func TestWorker_InitCheckpointSkippedFromBeginning(t *testing.T) {
+	// the fake store has no executor, so any checkpoint query would panic
+	w := NewWorker(newFakeStore(), NewHandler("mailer"))
+	if err := w.initCheckpoint(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
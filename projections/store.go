@@ -0,0 +1,60 @@
+package projections
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ripkitten-co/whisker"
+)
+
+// LockManager grants PostgreSQL session-level advisory locks. The daemon
+// takes one lock per subscriber so only one instance processes it at a time.
+type LockManager interface {
+	// TryAdvisoryLock attempts the lock on key without blocking. When acquired
+	// the returned unlock releases it.
+	TryAdvisoryLock(ctx context.Context, key int64) (unlock func(context.Context) error, acquired bool, err error)
+}
+
+// BlockingLockManager is implemented by stores that can wait for an advisory
+// lock. Worker.AcquireLock uses it when the store provides it and otherwise
+// retries TryAdvisoryLock until the lock is free.
+type BlockingLockManager interface {
+	// AdvisoryLock waits for the lock on key until ctx is done. The returned
+	// unlock releases it.
+	AdvisoryLock(ctx context.Context, key int64) (unlock func(context.Context) error, err error)
+}
+
+// Notifier delivers LISTEN/NOTIFY wakeups.
+type Notifier interface {
+	// WaitForNotification blocks until a NOTIFY arrives on channel or the
+	// context is cancelled.
+	WaitForNotification(ctx context.Context, channel string) error
+}
+
+// ListeningNotifier is implemented by stores that can hold a long-lived
+// listener. Poller.Notifications and Watch use it when the store provides it
+// and otherwise call WaitForNotification in a loop, which can miss a NOTIFY
+// sent between two calls; the keepalive fallback covers those.
+type ListeningNotifier interface {
+	// Listen subscribes a long-lived listener to channel.
+	Listen(ctx context.Context, channel string) (whisker.Listener, error)
+}
+
+// Store is what workers, pollers and the daemon need from a Whisker store:
+// a Backend for reads and writes, sessions for transactional Emitter batches,
+// advisory locks, notifications, and a logger for background errors.
+// *whisker.Store implements it; wrap it to decorate a store or implement it to
+// test with a fake, whose Session returns any whisker.Tx.
+type Store interface {
+	whisker.Backend
+	LockManager
+	Notifier
+	Session(ctx context.Context, opts ...whisker.SessionOption) (whisker.Tx, error)
+	Logger() *slog.Logger
+}
+
+var (
+	_ Store               = (*whisker.Store)(nil)
+	_ BlockingLockManager = (*whisker.Store)(nil)
+	_ ListeningNotifier   = (*whisker.Store)(nil)
+)
@@ -0,0 +1,376 @@
+package projections
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/internal/codecs"
+	"github.com/ripkitten-co/whisker/internal/pg"
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+type fakeStore struct {
+	locked   map[int64]bool
+	unlocks  int
+	channels []string
+	closed   bool
+	// listeners are handed out by Listen in order
+	listeners []*fakeListener
+	listens   int
+	// logger replaces slog.Default when set
+	logger *slog.Logger
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{locked: map[int64]bool{}}
+}
+
+func (f *fakeStore) DBExecutor() pg.Executor            { return nil }
+func (f *fakeStore) JSONCodec() codecs.Codec            { return codecs.NewWhisker(codecs.NewJSONIter()) }
+func (f *fakeStore) SchemaBootstrap() *schema.Bootstrap { return schema.New() }
+func (f *fakeStore) MaxBatchSize() int                  { return 0 }
+
+func (f *fakeStore) Logger() *slog.Logger {
+	if f.logger != nil {
+		return f.logger
+	}
+	return slog.Default()
+}
+
+func (f *fakeStore) Session(context.Context, ...whisker.SessionOption) (whisker.Tx, error) {
+	return nil, errors.New("fake store: sessions not supported")
+}
+
+func (f *fakeStore) TryAdvisoryLock(_ context.Context, key int64) (func(context.Context) error, bool, error) {
+	if f.closed {
+		return nil, false, whisker.ErrStoreClosed
+	}
+	if f.locked[key] {
+		return nil, false, nil
+	}
+	f.locked[key] = true
+	return func(context.Context) error {
+		f.unlocks++
+		delete(f.locked, key)
+		return nil
+	}, true, nil
+}
+
+func (f *fakeStore) WaitForNotification(_ context.Context, channel string) error {
+	f.channels = append(f.channels, channel)
+	return nil
+}
+
+func (f *fakeStore) Listen(_ context.Context, channel string) (whisker.Listener, error) {
+	if f.closed {
+		return nil, whisker.ErrStoreClosed
+	}
+	f.channels = append(f.channels, channel)
+	if f.listens >= len(f.listeners) {
+		return nil, errors.New("fake store: no listener")
+	}
+	l := f.listeners[f.listens]
+	f.listens++
+	return l, nil
+}
+
+// fakeListener returns each scripted result from Wait in turn, then blocks
+// until the context ends.
+type fakeListener struct {
+	results chan fakeWait
+	pingErr error
+	closed  chan struct{}
+}
+
+type fakeWait struct {
+	payload string
+	err     error
+}
+
+func newFakeListener(results ...fakeWait) *fakeListener {
+	l := &fakeListener{results: make(chan fakeWait, len(results)), closed: make(chan struct{})}
+	for _, r := range results {
+		l.results <- r
+	}
+	return l
+}
+
+func (l *fakeListener) Wait(ctx context.Context) (string, error) {
+	select {
+	case r := <-l.results:
+		return r.payload, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (l *fakeListener) Ping(context.Context) error { return l.pingErr }
+
+func (l *fakeListener) Close(context.Context) error {
+	close(l.closed)
+	return nil
+}
+
+func TestWorker_LockLifecycleWithFakeStore(t *testing.T) {
+	store := newFakeStore()
+	ctx := context.Background()
+
+	w1 := NewWorker(store, NewHandler("mailer"))
+	w2 := NewWorker(store, NewHandler("mailer"))
+
+	ok, err := w1.TryAcquireLock(ctx)
+	if err != nil || !ok {
+		t.Fatalf("first acquire: ok=%v err=%v", ok, err)
+	}
+	ok, err = w2.TryAcquireLock(ctx)
+	if err != nil {
+		t.Fatalf("second acquire: %v", err)
+	}
+	if ok {
+		t.Fatal("second worker acquired a held lock")
+	}
+
+	if err := w1.ReleaseLock(ctx); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if err := w1.ReleaseLock(ctx); err != nil {
+		t.Fatalf("second release: %v", err)
+	}
+	if store.unlocks != 1 {
+		t.Errorf("got %d unlocks, want 1", store.unlocks)
+	}
+
+	ok, err = w2.TryAcquireLock(ctx)
+	if err != nil || !ok {
+		t.Fatalf("acquire after release: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestDaemon_RebuildFailsWhenLockHeld(t *testing.T) {
+	store := newFakeStore()
+	store.locked[lockHash("orders")] = true
+
+	d := NewDaemon(store)
+	d.Add(New[OrderSummary](store, "orders"))
+
+	err := d.Rebuild(context.Background(), "orders")
+	if err == nil || !strings.Contains(err.Error(), "another instance holds the lock") {
+		t.Fatalf("got %v, want lock contention error", err)
+	}
+}
+
+func TestDecommission_FailsWhenLockHeld(t *testing.T) {
+	store := newFakeStore()
+	store.locked[lockHash("billing:invoices")] = true
+
+	err := Decommission(context.Background(), store, "billing:invoices")
+	if err == nil || !strings.Contains(err.Error(), "another instance holds the lock") {
+		t.Fatalf("got %v, want lock contention error", err)
+	}
+}
+
+func TestDecommission_ReleasesLockOnError(t *testing.T) {
+	store := newFakeStore()
+
+	err := Decommission(context.Background(), store, "invoices")
+	if err == nil || !strings.Contains(err.Error(), "sessions not supported") {
+		t.Fatalf("got %v, want session error", err)
+	}
+	if store.unlocks != 1 || store.locked[lockHash("invoices")] {
+		t.Errorf("lock not released: unlocks=%d", store.unlocks)
+	}
+}
+
+func TestDecommission_DropReadModelMustMatchName(t *testing.T) {
+	store := newFakeStore()
+	err := Decommission(context.Background(), store, "billing:invoices", DropReadModel(New[struct{ ID string }](store, "orders")))
+	if err == nil || !strings.Contains(err.Error(), "want invoices") {
+		t.Fatalf("got %v, want a name mismatch error", err)
+	}
+	if len(store.locked) != 0 {
+		t.Error("lock taken for a mismatched read model")
+	}
+}
+
+func TestDecommission_RejectsInvalidName(t *testing.T) {
+	store := newFakeStore()
+	err := Decommission(context.Background(), store, "billing:drop table")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if len(store.locked) != 0 {
+		t.Error("lock taken for an invalid name")
+	}
+}
+
+func TestWorker_AcquireLockRetriesUntilContextDone(t *testing.T) {
+	store := newFakeStore()
+	ctx := context.Background()
+
+	w := NewWorker(store, NewHandler("mailer"))
+	if err := w.AcquireLock(ctx); err != nil {
+		t.Fatalf("acquire free lock: %v", err)
+	}
+
+	other := NewWorker(store, NewHandler("mailer"))
+	waitCtx, cancel := context.WithTimeout(ctx, 150*time.Millisecond)
+	defer cancel()
+	if err := other.AcquireLock(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestDaemon_RebuildWaitTimesOut(t *testing.T) {
+	store := newFakeStore()
+	store.locked[lockHash("orders")] = true
+
+	d := NewDaemon(store)
+	d.Add(New[OrderSummary](store, "orders"))
+
+	start := time.Now()
+	err := d.RebuildWait(context.Background(), "orders", 100*time.Millisecond)
+	if !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("got %v, want ErrLockTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("returned after %s, before the timeout", elapsed)
+	}
+}
+
+func TestPoller_WaitForNotificationListensOnEventsChannel(t *testing.T) {
+	store := newFakeStore()
+	p := NewPoller(store, 10)
+
+	if err := p.WaitForNotification(context.Background()); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if len(store.channels) != 1 || store.channels[0] != "whisker_events" {
+		t.Errorf("got channels %v, want [whisker_events]", store.channels)
+	}
+}
+
+func TestPoller_NamedStoreListensOnItsChannel(t *testing.T) {
+	store := newFakeStore()
+	p := NewNamedPoller(store, "billing", 10)
+
+	if err := p.WaitForNotification(context.Background()); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if len(store.channels) != 1 || store.channels[0] != "whisker_events_billing" {
+		t.Errorf("got channels %v, want [whisker_events_billing]", store.channels)
+	}
+}
+
+func TestWorker_EventStoreNamespacesLock(t *testing.T) {
+	store := newFakeStore()
+	ctx := context.Background()
+
+	def := NewWorker(store, NewHandler("mailer"))
+	billing := NewWorker(store, NewHandler("mailer"))
+	billing.SetEventStore("billing")
+	if billing.name() != "billing:mailer" {
+		t.Errorf("got name %q, want billing:mailer", billing.name())
+	}
+
+	for _, w := range []*Worker{def, billing} {
+		ok, err := w.TryAcquireLock(ctx)
+		if err != nil || !ok {
+			t.Fatalf("acquire %s: ok=%v err=%v", w.name(), ok, err)
+		}
+	}
+}
+
+func TestDaemon_FromEventStoreOverridesDaemonStore(t *testing.T) {
+	store := newFakeStore()
+	d := NewDaemon(store, WithEventStore("billing"))
+
+	feed := d.newWorker(FromEventStore("users_changes", New[OrderSummary](nil, "user_views")))
+	if feed.name() != "users_changes:user_views" {
+		t.Errorf("got name %q, want users_changes:user_views", feed.name())
+	}
+	if _, wrapped := feed.subscriber.(*storeSubscriber); wrapped {
+		t.Error("worker should run the unwrapped subscriber")
+	}
+	if def := d.newWorker(NewHandler("mailer")); def.name() != "billing:mailer" {
+		t.Errorf("got name %q, want billing:mailer", def.name())
+	}
+}
+
+func TestDaemon_AddRejectsSharedReadModel(t *testing.T) {
+	d := NewDaemon(newFakeStore())
+	d.Add(New[OrderSummary](nil, "user_views"))
+	// handlers have no read model, so their names may repeat across stores
+	d.Add(NewHandler("mailer"))
+	d.Add(FromEventStore("billing", NewHandler("mailer")))
+
+	for name, sub := range map[string]Subscriber{
+		"other store":  FromEventStore("users_changes", New[OrderSummary](nil, "user_views")),
+		"group member": Group("views", New[OrderSummary](nil, "user_views")),
+	} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if err == nil || !strings.Contains(err.Error(), "both write the read model whisker_user_views") {
+					t.Errorf("%s: got %v, want a shared read model panic", name, err)
+				}
+			}()
+			d.Add(sub)
+		}()
+	}
+	if len(d.subscribers) != 3 {
+		t.Errorf("registered %d subscribers, want 3", len(d.subscribers))
+	}
+}
+
+func TestDaemon_RunStopsWhenStoreCloses(t *testing.T) {
+	store := newFakeStore()
+	store.closed = true
+
+	d := NewDaemon(store, WithPollingInterval(time.Millisecond))
+	d.Add(NewHandler("mailer"))
+
+	done := make(chan struct{})
+	go func() {
+		d.Run(context.Background())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("daemon kept running after the store closed")
+	}
+}
+
+func TestPoller_PeekNothing(t *testing.T) {
+	p := NewPoller(newFakeStore(), 10)
+
+	evts, err := p.Peek(context.Background(), 0, 0)
+	if err != nil || evts != nil {
+		t.Errorf("got %v, %v, want no events and no error", evts, err)
+	}
+}
+
+func TestDaemon_InstanceID(t *testing.T) {
+	d := NewDaemon(newFakeStore(), WithInstanceID("worker-a"))
+	if d.InstanceID() != "worker-a" {
+		t.Errorf("got %q, want worker-a", d.InstanceID())
+	}
+
+	a, b := NewDaemon(newFakeStore()), NewDaemon(newFakeStore())
+	if a.InstanceID() == "" || a.InstanceID() == b.InstanceID() {
+		t.Errorf("default instance IDs should be unique, got %q and %q", a.InstanceID(), b.InstanceID())
+	}
+}
+
+func TestReplayStream_RejectsLinks(t *testing.T) {
+	err := ReplayStream(context.Background(), newFakeStore(), NewLink("fulfillment"), "order-1")
+	if err == nil || !strings.Contains(err.Error(), "only read-model projections") {
+		t.Fatalf("got %v, want rejection of link", err)
+	}
+}
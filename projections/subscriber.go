@@ -14,6 +14,26 @@ type Subscriber interface {
 	Process(ctx context.Context, evts []events.Event, store ProcessingStore) error
 }
 
+// Versioned is implemented by subscribers that declare the version of their
+// processing code. Bump it whenever a change to the code would build a
+// different read model from the same events. The daemon records the version
+// in the checkpoint; when a deployed version no longer matches, it marks the
+// checkpoint "outdated", or rebuilds the read model with WithAutoRebuild, so
+// the change is not silently applied to new events only. Zero or less opts
+// out.
+type Versioned interface {
+	Version() int
+}
+
+// subscriberVersion returns the version sub declares, or 0 if it is not
+// Versioned.
+func subscriberVersion(sub Subscriber) int {
+	if v, ok := sub.(Versioned); ok {
+		return v.Version()
+	}
+	return 0
+}
+
 // ProcessingStore abstracts read-model persistence so projections don't depend
 // on the documents package directly. Side-effect handlers ignore it.
 type ProcessingStore interface {
@@ -21,3 +41,47 @@ type ProcessingStore interface {
 	UpsertState(ctx context.Context, collection, id string, data []byte, version int) error
 	DeleteState(ctx context.Context, collection, id string) error
 }
+
+// TombstoneStore is implemented by processing stores that can soft-delete a
+// read model, keeping the row with deleted_at set so downstream consumers of
+// the collection can observe the deletion.
+type TombstoneStore interface {
+	TombstoneState(ctx context.Context, collection, id string) error
+}
+
+// ArrayStore is implemented by processing stores that can edit an array
+// field of a read model in place, without loading and rewriting the whole
+// document. elem is the JSON of one element. See Projection.AppendOn.
+type ArrayStore interface {
+	// AppendToArray appends elem to the array field of the document id,
+	// creating the document or the field when missing.
+	AppendToArray(ctx context.Context, collection, id, field string, elem []byte) error
+	// RemoveFromArray removes every element of the array field of the
+	// document id that contains elem, in the sense of the JSONB @> operator:
+	// an object elem matches elements having all of its keys and values.
+	RemoveFromArray(ctx context.Context, collection, id, field string, elem []byte) error
+}
+
+// FromEventStore returns sub set to read the named event store (see
+// events.NewNamed) instead of the daemon's, so one daemon can run
+// subscribers over several stores, such as the change feed of a document
+// collection (see documents.WithChangeFeed). Its checkpoint is named
+// "store:subscriber", as with WithEventStore.
+func FromEventStore(name string, sub Subscriber) Subscriber {
+	return &storeSubscriber{Subscriber: sub, eventStore: name}
+}
+
+// storeSubscriber is a subscriber bound to an event store by FromEventStore.
+type storeSubscriber struct {
+	Subscriber
+	eventStore string
+}
+
+// unwrapEventStore returns the subscriber wrapped by FromEventStore and the
+// store it was bound to, or sub and "" when it was not bound.
+func unwrapEventStore(sub Subscriber) (Subscriber, string) {
+	if s, ok := sub.(*storeSubscriber); ok {
+		return s.Subscriber, s.eventStore
+	}
+	return sub, ""
+}
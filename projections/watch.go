@@ -0,0 +1,92 @@
+package projections
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+// Watch follows the read-model document id of the named projection, which
+// must be Watchable, for pushing live updates to websocket or SSE clients
+// without polling:
+//
+//	docs, err := projections.Watch(ctx, store, "order_summaries", orderID)
+//	for data := range docs {
+//		fmt.Fprintf(w, "data: %s\n\n", data)
+//		flusher.Flush()
+//	}
+//
+// The channel receives the document's current JSON, if it exists, then its
+// new JSON each time the projection changes it, and nil once it is deleted.
+// A receiver that falls behind gets the latest document, not every state in
+// between. Watch holds one connection for LISTEN and reconnects as
+// Poller.Notifications does, taking the same options; it also reloads the
+// document on every keepalive, so a missed notification delays an update by
+// at most the keepalive interval. The channel is closed when ctx is
+// cancelled or the store shuts down.
+//
+// For a Sharded projection, watch the shard holding the document:
+// schema.ShardName(name, schema.ShardOf(id, n)).
+func Watch(ctx context.Context, store Store, name, id string, opts ...NotifyOption) (<-chan []byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	// listen before the first load so no change falls between them
+	wakeups, err := notifications(ctx, store, watchChannel(store.SchemaBootstrap(), name), opts)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("projections: watch %s %s: %w", name, id, err)
+	}
+	ps := newPGProcessingStore(store, name)
+	data, version, err := ps.LoadState(ctx, name, id)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("projections: watch %s %s: %w", name, id, err)
+	}
+
+	out := make(chan []byte, 1)
+	go func() {
+		defer close(out)
+		defer cancel()
+		if data != nil && !deliver(ctx, out, data) {
+			return
+		}
+		for n := range wakeups {
+			if !n.Fallback && n.Payload != id {
+				continue
+			}
+			next, nextVersion, err := ps.LoadState(ctx, name, id)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				store.Logger().Warn("watch: load", "projection", name, "id", id, "error", err)
+				continue
+			}
+			if nextVersion == version && bytes.Equal(next, data) {
+				continue
+			}
+			data, version = next, nextVersion
+			if !deliver(ctx, out, data) {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// deliver sends data on out unless ctx is done first.
+func deliver(ctx context.Context, out chan<- []byte, data []byte) bool {
+	select {
+	case out <- data:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// watchChannel is the NOTIFY channel the writes to a watched read model
+// signal on, with the ID of the changed document as payload.
+func watchChannel(sch *schema.Bootstrap, name string) string {
+	return sch.NotifyChannel("whisker_" + name + "_watch")
+}
@@ -0,0 +1,93 @@
+//go:build integration
+
+package projections_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/projections"
+)
+
+func nextDoc(t *testing.T, docs <-chan []byte) []byte {
+	t.Helper()
+	select {
+	case data, ok := <-docs:
+		if !ok {
+			t.Fatal("watch channel closed")
+		}
+		return data
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for document")
+	}
+	return nil
+}
+
+func TestWatch_EmitsProjectedDocument(t *testing.T) {
+	store := setupStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	es := events.New(store)
+
+	proj := projections.New[OrderSummary](store, "watched_orders").Watchable()
+	proj.On("OrderCreated", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+		return &OrderSummary{ID: evt.StreamID, Status: "created"}, nil
+	})
+	proj.On("OrderShipped", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+		state.Status = "shipped"
+		return state, nil
+	})
+	proj.On("OrderCancelled", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+		return nil, nil
+	})
+	w := projections.NewWorker(store, proj)
+
+	if err := es.Append(ctx, "order-1", 0, []events.Event{{Type: "OrderCreated", Data: []byte(`{}`)}}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if _, err := w.ProcessBatch(ctx); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	docs, err := projections.Watch(ctx, store, "watched_orders", "order-1")
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	status := func(data []byte) string {
+		var doc OrderSummary
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatalf("unmarshal %s: %v", data, err)
+		}
+		return doc.Status
+	}
+	if got := status(nextDoc(t, docs)); got != "created" {
+		t.Errorf("initial status: got %q, want created", got)
+	}
+
+	if err := es.Append(ctx, "order-1", 1, []events.Event{{Type: "OrderShipped", Data: []byte(`{}`)}}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if _, err := w.ProcessBatch(ctx); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if got := status(nextDoc(t, docs)); got != "shipped" {
+		t.Errorf("updated status: got %q, want shipped", got)
+	}
+
+	if err := es.Append(ctx, "order-1", 2, []events.Event{{Type: "OrderCancelled", Data: []byte(`{}`)}}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if _, err := w.ProcessBatch(ctx); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if data := nextDoc(t, docs); data != nil {
+		t.Errorf("after delete: got %s, want nil", data)
+	}
+
+	cancel()
+	for range docs {
+	}
+}
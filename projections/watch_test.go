@@ -0,0 +1,37 @@
+package projections
+
+import (
+	"testing"
+
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+func TestProcessingStoreFor_NotifiesOnlyWhenWatchable(t *testing.T) {
+	store := newFakeStore()
+
+	plain := processingStoreFor(store, New[OrderSummary](store, "orders")).(*pgProcessingStore)
+	if plain.notify {
+		t.Error("read model of a projection without Watchable notifies")
+	}
+	watched := processingStoreFor(store, New[OrderSummary](store, "orders").Watchable()).(*pgProcessingStore)
+	if !watched.notify {
+		t.Error("read model of a Watchable projection does not notify")
+	}
+
+	sharded := processingStoreFor(store, New[OrderSummary](store, "orders").Sharded(4).Watchable()).(*shardedProcessingStore)
+	for _, shard := range sharded.shards {
+		if !shard.notify {
+			t.Errorf("shard %s does not notify", shard.name)
+		}
+	}
+}
+
+func TestWatchChannel(t *testing.T) {
+	sch := schema.New()
+	if got := watchChannel(sch, "orders"); got != "whisker_orders_watch" {
+		t.Errorf("got %q, want whisker_orders_watch", got)
+	}
+	if watchChannel(sch, schema.ShardName("orders", 0)) == watchChannel(sch, schema.ShardName("orders", 1)) {
+		t.Error("shards share a watch channel")
+	}
+}
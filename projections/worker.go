@@ -2,34 +2,70 @@ package projections
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"hash/fnv"
+	"runtime/debug"
+	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ripkitten-co/whisker"
 	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/internal/pg"
 )
 
+// ErrPanic wraps a panic recovered from a subscriber's Process. The batch
+// fails and counts towards dead_letter, so a poison event is isolated to its
+// own projection instead of crashing the daemon.
+var ErrPanic = errors.New("subscriber panicked")
+
 // Worker drives a single subscriber: poll events, filter, process, checkpoint.
 // Each worker runs in its own goroutine, coordinated by the Daemon.
 type Worker struct {
-	store               *whisker.Store
-	pool                *pgxpool.Pool
+	store               Store
 	subscriber          Subscriber
 	checkpoint          *CheckpointStore
 	poller              *Poller
 	batchSize           int
 	maxRetries          int
+	processTimeout      time.Duration
 	consecutiveFailures int
-	lockConn            *pgxpool.Conn
+	unlock              func(context.Context) error
+	instanceID          string
+	hostname            string
+	claimed             bool
+	versionChecked      bool
+
+	// checkpoint batching; see SetCheckpointBatching
+	checkpointEvery    int
+	checkpointInterval time.Duration
+	pendingPosition    int64
+	pendingEvents      int
+	lastSave           time.Time
+
+	// throughput, when set by a daemon, records how fast batches go
+	throughput *throughput
+
+	// eventStore is the named event store the subscriber reads; see
+	// SetEventStore
+	eventStore string
+
+	// start is where a missing checkpoint is created; see SetStartFrom
+	start        Start
+	startChecked bool
+
+	// exclude are the events the worker skips; see SetExclude
+	exclude events.Exclude
+
+	// diagnostics; see SetSlowBatchThreshold and SetProfileLabels
+	slowBatch     time.Duration
+	profileLabels bool
 }
 
 // NewWorker creates a worker for the given subscriber with sensible defaults
 // (batch size 100, max retries 5).
-func NewWorker(store *whisker.Store, sub Subscriber) *Worker {
+func NewWorker(store Store, sub Subscriber) *Worker {
 	return &Worker{
 		store:      store,
-		pool:       store.PgxPool(),
 		subscriber: sub,
 		checkpoint: NewCheckpointStore(store),
 		poller:     NewPoller(store, 100),
@@ -38,18 +74,126 @@ func NewWorker(store *whisker.Store, sub Subscriber) *Worker {
 	}
 }
 
+// SetEventStore makes the worker read events from the named event store (see
+// events.NewNamed) instead of the default one. Its checkpoint and advisory
+// lock are keyed by the store and subscriber names together, so the same
+// subscriber can follow several stores independently; derived events a Link
+// emits go to the same store. Call it before processing.
+func (w *Worker) SetEventStore(name string) {
+	w.eventStore = name
+	w.poller = NewNamedPoller(w.store, name, w.batchSize)
+}
+
+// name returns the key of the worker's checkpoint and advisory lock: the
+// subscriber's name, prefixed with the event store's for a named store.
+func (w *Worker) name() string {
+	return checkpointName(w.eventStore, w.subscriber.Name())
+}
+
+func checkpointName(eventStore, subscriber string) string {
+	if eventStore == "" {
+		return subscriber
+	}
+	return eventStore + ":" + subscriber
+}
+
 // SetMaxRetries configures the number of consecutive failures before the
 // worker transitions the projection to dead_letter status.
 func (w *Worker) SetMaxRetries(n int) {
 	w.maxRetries = n
 }
 
+// SetCheckpointBatching saves the checkpoint at most once every n events or
+// interval, whichever comes first, instead of after every batch. Positions
+// not yet saved are kept in memory and flushed before the worker releases its
+// lock. A daemon releases it at the end of every drain cycle, once the worker
+// has caught up, so batching only spans the batches of one cycle: it saves
+// upserts while a backlog is worked off, but a trickle of events that each
+// poll finds a few of is still saved once per poll. If the process dies
+// first, up to n events (or interval's worth) are processed again on
+// restart, so projections must tolerate replays and handlers may repeat side
+// effects. Emitters always save with their batch, since their checkpoint
+// commits in the same transaction. Zero for both, the default, saves after
+// every batch.
+func (w *Worker) SetCheckpointBatching(n int, interval time.Duration) {
+	w.checkpointEvery = n
+	w.checkpointInterval = interval
+}
+
+// saveCheckpoint records that events up to position have been processed,
+// saving now or deferring to a later batch according to the batching
+// settings.
+func (w *Worker) saveCheckpoint(ctx context.Context, position int64, n int) error {
+	w.pendingPosition = position
+	w.pendingEvents += n
+
+	unbatched := w.checkpointEvery <= 0 && w.checkpointInterval <= 0
+	enoughEvents := w.checkpointEvery > 0 && w.pendingEvents >= w.checkpointEvery
+	intervalPassed := w.checkpointInterval > 0 && time.Since(w.lastSave) >= w.checkpointInterval
+	if !unbatched && !enoughEvents && !intervalPassed {
+		return nil
+	}
+	return w.FlushCheckpoint(ctx)
+}
+
+// FlushCheckpoint saves a position deferred by checkpoint batching. It is a
+// no-op when nothing is pending.
+func (w *Worker) FlushCheckpoint(ctx context.Context) error {
+	if w.pendingEvents == 0 {
+		return nil
+	}
+	if err := w.checkpoint.Save(ctx, w.name(), w.pendingPosition); err != nil {
+		return err
+	}
+	w.pendingEvents = 0
+	w.lastSave = time.Now()
+	return nil
+}
+
+// SetProcessTimeout bounds each call to the subscriber's Process. A batch
+// that runs longer has its context cancelled and fails like any other error,
+// counting towards dead_letter, so a stuck call in a handler cannot hold the
+// worker and its lock indefinitely. Handlers must pass the context to the
+// calls they make for the deadline to take effect. Zero, the default, means
+// no timeout.
+func (w *Worker) SetProcessTimeout(d time.Duration) {
+	w.processTimeout = d
+}
+
+// process runs fn, which processes batch, with the configured process
+// timeout applied to ctx, recovering panics as ErrPanic. See observe for the
+// diagnostics it records.
+func (w *Worker) process(ctx context.Context, batch []events.Event, fn func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			w.store.Logger().Error("subscriber panicked", "worker", w.name(), "panic", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+
+	return w.observe(ctx, batch, func(ctx context.Context) error {
+		if w.processTimeout <= 0 {
+			return fn(ctx)
+		}
+		pctx, cancel := context.WithTimeout(ctx, w.processTimeout)
+		defer cancel()
+		err := fn(pctx)
+		if err != nil && ctx.Err() == nil && errors.Is(pctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timed out after %s: %w", w.processTimeout, err)
+		}
+		return err
+	})
+}
+
 // ProcessBatch polls for events after the last checkpoint position and processes
 // them through the subscriber. Returns the number of events polled (before
 // filtering) so callers can decide whether to keep draining.
 func (w *Worker) ProcessBatch(ctx context.Context) (int, error) {
-	name := w.subscriber.Name()
+	name := w.name()
 
+	if err := w.initCheckpoint(ctx); err != nil {
+		return 0, err
+	}
 	pos, status, err := w.checkpoint.Load(ctx, name)
 	if err != nil {
 		return 0, fmt.Errorf("worker %s: load checkpoint: %w", name, err)
@@ -58,11 +202,43 @@ func (w *Worker) ProcessBatch(ctx context.Context) (int, error) {
 	if status == "dead_letter" || status == "stopped" {
 		return 0, nil
 	}
+	if status == StatusDecommissioned {
+		// the name is in use again: start over from the beginning
+		if err := w.checkpoint.Reset(ctx, name); err != nil {
+			return 0, fmt.Errorf("worker %s: %w", name, err)
+		}
+		if err := w.checkpoint.SetStatus(ctx, name, "running"); err != nil {
+			return 0, fmt.Errorf("worker %s: %w", name, err)
+		}
+	}
+	if w.pendingEvents > 0 {
+		pos = max(pos, w.pendingPosition)
+	}
+
+	// skip the cycle while the store is quiesced rather than fail towards
+	// dead_letter
+	if err := pg.CheckWrite(ctx, w.store.DBExecutor()); err != nil {
+		if errors.Is(err, whisker.ErrMaintenance) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("worker %s: %w", name, err)
+	}
 
+	if !w.exclude.IsZero() {
+		return w.processExcept(ctx, pos)
+	}
 	evts, err := w.poller.Poll(ctx, pos)
 	if err != nil {
 		return 0, fmt.Errorf("worker %s: poll: %w", name, err)
 	}
+	return w.processEvents(ctx, evts)
+}
+
+// processEvents runs a batch of events, in global position order, through
+// the subscriber and checkpoints the last one. Returns the number of events
+// in the batch.
+func (w *Worker) processEvents(ctx context.Context, evts []events.Event) (int, error) {
+	name := w.name()
 	if len(evts) == 0 {
 		return 0, nil
 	}
@@ -70,64 +246,150 @@ func (w *Worker) ProcessBatch(ctx context.Context) (int, error) {
 	filtered := w.filterEvents(evts)
 
 	if len(filtered) == 0 {
-		return len(evts), w.checkpoint.Save(ctx, name, evts[len(evts)-1].GlobalPosition)
+		return len(evts), w.saveCheckpoint(ctx, evts[len(evts)-1].GlobalPosition, len(evts))
 	}
 
-	ps := NewProcessingStoreFromBackend(w.store, name)
-	if err := w.subscriber.Process(ctx, filtered, ps); err != nil {
-		w.consecutiveFailures++
-		if w.consecutiveFailures >= w.maxRetries {
-			_ = w.checkpoint.SetStatus(ctx, name, "dead_letter")
-		}
+	if em, ok := w.subscriber.(Emitter); ok {
+		return w.processEmitter(ctx, em, filtered, evts)
+	}
+
+	ps := processingStoreFor(w.store, w.subscriber)
+	err := w.process(ctx, filtered, func(ctx context.Context) error {
+		return w.subscriber.Process(ctx, filtered, ps)
+	})
+	if err != nil {
+		w.recordFailure(ctx, filtered, err)
 		return 0, fmt.Errorf("worker %s: process: %w", name, err)
 	}
 
 	w.consecutiveFailures = 0
-	return len(evts), w.checkpoint.Save(ctx, name, evts[len(evts)-1].GlobalPosition)
+	return len(evts), w.saveCheckpoint(ctx, evts[len(evts)-1].GlobalPosition, len(evts))
 }
 
-// TryAcquireLock acquires a dedicated connection from the pool and attempts a
-// PostgreSQL session-level advisory lock keyed by the subscriber name. The
-// connection is held until ReleaseLock is called, ensuring the lock protects
-// the entire processing cycle. Returns false if another instance holds the lock.
-func (w *Worker) TryAcquireLock(ctx context.Context) (bool, error) {
-	conn, err := w.pool.Acquire(ctx)
+// processBatchTimed is ProcessBatch, recording the batch's throughput for
+// the daemon's rebuild estimates.
+func (w *Worker) processBatchTimed(ctx context.Context) (int, error) {
+	start := time.Now()
+	n, err := w.ProcessBatch(ctx)
+	if err == nil && w.throughput != nil {
+		w.throughput.record(n, time.Since(start))
+	}
+	return n, err
+}
+
+// processEmitter runs an Emitter batch inside a session so derived events,
+// read-model writes, and the checkpoint commit or roll back together.
+func (w *Worker) processEmitter(ctx context.Context, em Emitter, filtered, evts []events.Event) (int, error) {
+	name := w.name()
+
+	sess, err := w.store.Session(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("worker %s: %w", name, err)
+	}
+	defer func() { _ = sess.Close(ctx) }()
+
+	ps := processingStoreFor(sess, w.subscriber)
+	sink := &streamSink{es: events.NewNamed(sess, w.eventStore)}
+	err = w.process(ctx, filtered, func(ctx context.Context) error {
+		return em.ProcessEmit(ctx, filtered, ps, sink)
+	})
 	if err != nil {
-		return false, fmt.Errorf("worker %s: acquire conn: %w", w.subscriber.Name(), err)
+		_ = sess.Rollback(ctx)
+		w.recordFailure(ctx, filtered, err)
+		return 0, fmt.Errorf("worker %s: process: %w", name, err)
+	}
+
+	if err := NewCheckpointStore(sess).Save(ctx, name, evts[len(evts)-1].GlobalPosition); err != nil {
+		return 0, err
+	}
+	if err := sess.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("worker %s: %w", name, err)
+	}
+
+	// the committed position supersedes any deferred one
+	w.pendingEvents = 0
+	w.lastSave = time.Now()
+	w.consecutiveFailures = 0
+	return len(evts), nil
+}
+
+// recordFailure counts a failed batch towards dead_letter, recording the
+// failure with the checkpoint once it gets there. Writes refused because the
+// store is quiesced are not the subscriber's fault and don't count.
+func (w *Worker) recordFailure(ctx context.Context, batch []events.Event, err error) {
+	if errors.Is(err, whisker.ErrMaintenance) {
+		return
+	}
+	w.consecutiveFailures++
+	if w.consecutiveFailures >= w.maxRetries {
+		_ = w.checkpoint.DeadLetter(ctx, w.name(), newFailure(batch, err, w.consecutiveFailures))
 	}
+}
 
-	lockID := lockHash(w.subscriber.Name())
-	var acquired bool
-	err = conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", lockID).Scan(&acquired)
+// TryAcquireLock attempts an advisory lock keyed by the subscriber name. The
+// lock is held until ReleaseLock is called, ensuring it protects the entire
+// processing cycle. Returns false if another instance holds the lock.
+func (w *Worker) TryAcquireLock(ctx context.Context) (bool, error) {
+	unlock, acquired, err := w.store.TryAdvisoryLock(ctx, lockHash(w.name()))
 	if err != nil {
-		conn.Release()
-		return false, fmt.Errorf("worker %s: acquire lock: %w", w.subscriber.Name(), err)
+		return false, fmt.Errorf("worker %s: acquire lock: %w", w.name(), err)
 	}
 	if !acquired {
-		conn.Release()
 		return false, nil
 	}
-
-	w.lockConn = conn
+	w.unlock = unlock
 	return true, nil
 }
 
-// ReleaseLock releases the advisory lock and returns the dedicated connection
-// to the pool.
-func (w *Worker) ReleaseLock(ctx context.Context) error {
-	if w.lockConn == nil {
+// Bounds of the interval at which AcquireLock retries TryAcquireLock on
+// stores that cannot wait for a lock.
+const (
+	minLockRetry = 50 * time.Millisecond
+	maxLockRetry = time.Second
+)
+
+// AcquireLock waits for the advisory lock keyed by the subscriber name until
+// it is free or ctx is done. Like TryAcquireLock, the lock is held until
+// ReleaseLock is called.
+func (w *Worker) AcquireLock(ctx context.Context) error {
+	name := w.name()
+	if bl, ok := w.store.(BlockingLockManager); ok {
+		unlock, err := bl.AdvisoryLock(ctx, lockHash(name))
+		if err != nil {
+			return fmt.Errorf("worker %s: acquire lock: %w", name, err)
+		}
+		w.unlock = unlock
 		return nil
 	}
-	defer func() {
-		w.lockConn.Release()
-		w.lockConn = nil
-	}()
 
-	lockID := lockHash(w.subscriber.Name())
-	var released bool
-	err := w.lockConn.QueryRow(ctx, "SELECT pg_advisory_unlock($1)", lockID).Scan(&released)
-	if err != nil {
-		return fmt.Errorf("worker %s: release lock: %w", w.subscriber.Name(), err)
+	delay := minLockRetry
+	for {
+		acquired, err := w.TryAcquireLock(ctx)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("worker %s: acquire lock: %w", name, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxLockRetry)
+	}
+}
+
+// ReleaseLock releases the advisory lock taken by TryAcquireLock or
+// AcquireLock.
+func (w *Worker) ReleaseLock(ctx context.Context) error {
+	if w.unlock == nil {
+		return nil
+	}
+	unlock := w.unlock
+	w.unlock = nil
+	if err := unlock(ctx); err != nil {
+		return fmt.Errorf("worker %s: release lock: %w", w.name(), err)
 	}
 	return nil
 }
@@ -140,7 +402,7 @@ func (w *Worker) filterEvents(evts []events.Event) []events.Event {
 
 	var filtered []events.Event
 	for _, evt := range evts {
-		if _, ok := types[evt.Type]; ok {
+		if _, ok := types[evt.Type]; ok && !w.exclude.Excludes(evt) {
 			filtered = append(filtered, evt)
 		}
 	}
@@ -4,9 +4,12 @@ package projections_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ripkitten-co/whisker/events"
 	"github.com/ripkitten-co/whisker/projections"
@@ -214,3 +217,339 @@ func TestWorker_DeadLetterAfterMaxRetries(t *testing.T) {
 		t.Errorf("attempts: got %d, want 3", got)
 	}
 }
+
+func TestWorker_DeadLetterRecordsFailure(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	err := es.Append(ctx, "order-poison", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{}`)},
+		{Type: "OrderPaid", Data: []byte(`{}`)},
+	})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	proj := projections.New[OrderSummary](store, "dead_letter_failure")
+	proj.On("OrderCreated", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+		return &OrderSummary{ID: evt.StreamID}, nil
+	})
+	proj.On("OrderPaid", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+		return nil, fmt.Errorf("payment gateway down")
+	})
+
+	w := projections.NewWorker(store, proj)
+	w.SetMaxRetries(2)
+	for range 2 {
+		if _, err := w.ProcessBatch(ctx); err == nil {
+			t.Fatal("expected batch to fail")
+		}
+	}
+
+	cs := projections.NewCheckpointStore(store)
+	cps, err := cs.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var cp *projections.Checkpoint
+	for i := range cps {
+		if cps[i].Name == "dead_letter_failure" {
+			cp = &cps[i]
+		}
+	}
+	if cp == nil || cp.Status != "dead_letter" || cp.Failure == nil {
+		t.Fatalf("checkpoint = %+v, want dead_letter with a failure", cp)
+	}
+	f := cp.Failure
+	if f.StreamID != "order-poison" || f.EventType != "OrderPaid" || f.Attempts != 2 || f.Position == 0 {
+		t.Errorf("failure = %+v, want OrderPaid on order-poison after 2 attempts", f)
+	}
+	if !strings.Contains(f.Error, "payment gateway down") {
+		t.Errorf("failure error = %q", f.Error)
+	}
+
+	if err := cs.SetStatus(ctx, "dead_letter_failure", "running"); err != nil {
+		t.Fatalf("set status: %v", err)
+	}
+	cps, err = cs.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	for _, c := range cps {
+		if c.Name == "dead_letter_failure" && c.Failure != nil {
+			t.Errorf("failure not cleared on running: %+v", c.Failure)
+		}
+	}
+}
+
+func TestWorker_ProcessTimeoutFailsStuckBatch(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	err := es.Append(ctx, "order-stuck", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{"id":"order-stuck"}`)},
+	})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	h := projections.NewHandler("stuck_mailer")
+	h.On("OrderCreated", func(ctx context.Context, evt events.Event) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	w := projections.NewWorker(store, h)
+	w.SetProcessTimeout(50 * time.Millisecond)
+	w.SetMaxRetries(1)
+
+	if _, err := w.ProcessBatch(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want deadline exceeded", err)
+	}
+
+	pos, status, err := projections.NewCheckpointStore(store).Load(ctx, "stuck_mailer")
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+	if pos != 0 || status != "dead_letter" {
+		t.Errorf("got position %d status %q, want 0 and dead_letter", pos, status)
+	}
+}
+
+func TestWorker_PanicCountsTowardsDeadLetter(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	err := es.Append(ctx, "order-poison", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{"id":"order-poison"}`)},
+	})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	proj := projections.New[OrderSummary](store, "poison_summaries")
+	proj.On("OrderCreated", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+		return nil, fmt.Errorf("unreachable: %s", state.Status) // state is nil
+	})
+
+	w := projections.NewWorker(store, proj)
+	w.SetMaxRetries(2)
+
+	for i := 0; i < 2; i++ {
+		if _, err := w.ProcessBatch(ctx); !errors.Is(err, projections.ErrPanic) {
+			t.Fatalf("attempt %d: got %v, want ErrPanic", i, err)
+		}
+	}
+
+	_, status, err := projections.NewCheckpointStore(store).Load(ctx, "poison_summaries")
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+	if status != "dead_letter" {
+		t.Errorf("status: got %q, want dead_letter", status)
+	}
+}
+
+func TestWorker_CheckpointBatching(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	h := projections.NewHandler("batched_mailer")
+	var handled atomic.Int32
+	h.On("OrderCreated", func(ctx context.Context, evt events.Event) error {
+		handled.Add(1)
+		return nil
+	})
+
+	w := projections.NewWorker(store, h)
+	w.SetCheckpointBatching(100, time.Hour)
+	cs := projections.NewCheckpointStore(store)
+
+	for i := range 2 {
+		stream := fmt.Sprintf("order-batched-%d", i)
+		if err := es.Append(ctx, stream, 0, []events.Event{{Type: "OrderCreated", Data: []byte(`{}`)}}); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+		if _, err := w.ProcessBatch(ctx); err != nil {
+			t.Fatalf("process batch: %v", err)
+		}
+	}
+
+	if got := handled.Load(); got != 2 {
+		t.Fatalf("handled %d events, want 2 (pending position not used)", got)
+	}
+	pos, _, err := cs.Load(ctx, "batched_mailer")
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+	if pos != 0 {
+		t.Errorf("checkpoint saved before flush: got %d", pos)
+	}
+
+	if err := w.FlushCheckpoint(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	pos, _, err = cs.Load(ctx, "batched_mailer")
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+	if pos == 0 {
+		t.Error("checkpoint not saved by flush")
+	}
+}
+
+func TestWorker_StartFromEnd(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	if err := es.Append(ctx, "order-old", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{"id":"order-old"}`)},
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	var handled []string
+	h := projections.NewHandler("start_end_mailer")
+	h.On("OrderCreated", func(ctx context.Context, evt events.Event) error {
+		handled = append(handled, evt.StreamID)
+		return nil
+	})
+	w := projections.NewWorker(store, h)
+	w.SetStartFrom(projections.End)
+
+	if _, err := w.ProcessBatch(ctx); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if err := es.Append(ctx, "order-new", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{"id":"order-new"}`)},
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if _, err := w.ProcessBatch(ctx); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(handled) != 1 || handled[0] != "order-new" {
+		t.Errorf("handled %v, want only order-new", handled)
+	}
+
+	// an existing checkpoint wins over the start position
+	if err := projections.NewCheckpointStore(store).Save(ctx, "start_end_mailer", 0); err != nil {
+		t.Fatalf("save checkpoint: %v", err)
+	}
+	w = projections.NewWorker(store, h)
+	w.SetStartFrom(projections.End)
+	handled = nil
+	if _, err := w.ProcessBatch(ctx); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(handled) != 2 {
+		t.Errorf("handled %v after a reset to 0, want both orders", handled)
+	}
+}
+
+func TestWorker_Exclude(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	for _, stream := range []string{"order-1", "test-order", "order-2"} {
+		if err := es.Append(ctx, stream, 0, []events.Event{
+			{Type: "OrderCreated", Data: []byte(`{}`)},
+			{Type: "Heartbeat", Data: []byte(`{}`)},
+		}); err != nil {
+			t.Fatalf("append %s: %v", stream, err)
+		}
+	}
+
+	var handled []string
+	h := projections.NewHandler("exclude_mailer")
+	h.On("OrderCreated", func(ctx context.Context, evt events.Event) error {
+		handled = append(handled, evt.StreamID)
+		return nil
+	})
+	h.On("Heartbeat", func(ctx context.Context, evt events.Event) error {
+		handled = append(handled, "heartbeat")
+		return nil
+	})
+	w := projections.NewWorker(store, h)
+	w.SetExclude(events.Exclude{Types: []string{"Heartbeat"}, StreamPrefixes: []string{"test-"}})
+
+	n, err := w.ProcessBatch(ctx)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if n != 2 || len(handled) != 2 || handled[0] != "order-1" || handled[1] != "order-2" {
+		t.Errorf("polled %d, handled %v, want order-1 and order-2", n, handled)
+	}
+
+	// the trailing heartbeat was skipped in SQL, yet the checkpoint is at the head
+	head, err := es.HeadPosition(ctx)
+	if err != nil {
+		t.Fatalf("head: %v", err)
+	}
+	pos, _, err := projections.NewCheckpointStore(store).Load(ctx, "exclude_mailer")
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+	if pos != head {
+		t.Errorf("checkpoint at %d, want the head %d", pos, head)
+	}
+}
+
+func TestWorker_Group(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	es := events.New(store)
+
+	err := es.Append(ctx, "order-1", 0, []events.Event{
+		{Type: "OrderCreated", Data: []byte(`{}`)},
+		{Type: "OrderPaid", Data: []byte(`{}`)},
+	})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	proj := projections.New[OrderSummary](store, "group_summaries")
+	proj.On("OrderCreated", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+		return &OrderSummary{ID: evt.StreamID, Status: "created"}, nil
+	})
+	proj.On("OrderPaid", func(ctx context.Context, evt events.Event, state *OrderSummary) (*OrderSummary, error) {
+		state.Status = "paid"
+		return state, nil
+	})
+
+	// the mailer runs after the projection, so it sees the status each event set
+	summaries := projections.NewProcessingStoreFromBackend(store, "group_summaries")
+	var seen []string
+	mailer := projections.NewHandler("group_mailer")
+	mailer.On("OrderPaid", func(ctx context.Context, evt events.Event) error {
+		data, _, err := summaries.LoadState(ctx, "group_summaries", evt.StreamID)
+		if err != nil {
+			return err
+		}
+		seen = append(seen, string(data))
+		return nil
+	})
+
+	w := projections.NewWorker(store, projections.Group("billing", proj, mailer))
+	if _, err := w.ProcessBatch(ctx); err != nil {
+		t.Fatalf("process batch: %v", err)
+	}
+	if len(seen) != 1 || !strings.Contains(seen[0], `"paid"`) {
+		t.Errorf("mailer saw %v, want the paid summary", seen)
+	}
+
+	cs := projections.NewCheckpointStore(store)
+	pos, _, err := cs.Load(ctx, "billing")
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+	if pos != 2 {
+		t.Errorf("group checkpoint at %d, want 2", pos)
+	}
+}
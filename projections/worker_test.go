@@ -0,0 +1,78 @@
+package projections
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorker_ProcessTimeout(t *testing.T) {
+	w := NewWorker(newFakeStore(), NewHandler("mailer"))
+	w.SetProcessTimeout(10 * time.Millisecond)
+
+	err := w.process(context.Background(), nil, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want deadline exceeded", err)
+	}
+	if !strings.Contains(err.Error(), "timed out after 10ms") {
+		t.Errorf("got %q, want timeout in message", err)
+	}
+}
+
+func TestWorker_ProcessTimeoutDisabled(t *testing.T) {
+	w := NewWorker(newFakeStore(), NewHandler("mailer"))
+
+	err := w.process(context.Background(), nil, func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); ok {
+			return errors.New("unexpected deadline")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWorker_ProcessTimeoutKeepsParentCancellation(t *testing.T) {
+	w := NewWorker(newFakeStore(), NewHandler("mailer"))
+	w.SetProcessTimeout(time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := w.process(ctx, nil, func(ctx context.Context) error { return ctx.Err() })
+	if !errors.Is(err, context.Canceled) || strings.Contains(err.Error(), "timed out") {
+		t.Errorf("got %v, want plain cancellation", err)
+	}
+}
+
+func TestWorker_ProcessRecoversPanic(t *testing.T) {
+	w := NewWorker(newFakeStore(), NewHandler("mailer"))
+	w.SetProcessTimeout(time.Minute)
+
+	err := w.process(context.Background(), nil, func(context.Context) error {
+		panic("poison event")
+	})
+	if !errors.Is(err, ErrPanic) || !strings.Contains(err.Error(), "poison event") {
+		t.Errorf("got %v, want ErrPanic with the panic value", err)
+	}
+}
+
+func TestWorker_CheckpointBatchingDefersSaves(t *testing.T) {
+	w := NewWorker(newFakeStore(), NewHandler("mailer"))
+	w.SetCheckpointBatching(10, time.Hour)
+	w.lastSave = time.Now()
+
+	for i, pos := range []int64{3, 7, 9} {
+		if err := w.saveCheckpoint(context.Background(), pos, 3); err != nil {
+			t.Fatalf("save %d: %v", i, err)
+		}
+	}
+	if w.pendingPosition != 9 || w.pendingEvents != 9 {
+		t.Errorf("got pending position %d events %d, want 9 and 9", w.pendingPosition, w.pendingEvents)
+	}
+}
@@ -0,0 +1,88 @@
+package whisker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ripkitten-co/whisker/internal/pg"
+)
+
+// quiesceLockKey is the advisory lock key behind the maintenance flag. Quiesce
+// holds it exclusively; gated writes take it shared.
+const quiesceLockKey int64 = 0x7768_6973_6b65_7251 // "whiskerQ"
+
+// gatedPool is the store executor when EnableQuiesce is set.
+type gatedPool struct {
+	*pg.Pool
+}
+
+// CheckWrite fails fast while any instance holds the maintenance lock.
+func (p gatedPool) CheckWrite(ctx context.Context) error {
+	return checkWritable(ctx, p.Pool)
+}
+
+func checkWritable(ctx context.Context, exec pg.Executor) error {
+	var ok bool
+	if err := exec.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock_shared($1)", quiesceLockKey).Scan(&ok); err != nil {
+		return fmt.Errorf("check maintenance: %w", err)
+	}
+	if !ok {
+		return ErrMaintenance
+	}
+	return nil
+}
+
+// Quiesce pauses writers across every instance created with WithQuiesce:
+// their document writes and event appends fail with ErrMaintenance until
+// Resume is called. Quiesce waits for sessions that have already written to
+// commit or roll back, so none is left half-applied; ctx bounds the wait.
+// A write outside a session may still land if it passed its check just
+// before Quiesce returned. The flag is a PostgreSQL advisory lock held on a
+// dedicated connection: it is released by Resume, or by the server if this
+// process dies, so a crashed maintenance job cannot leave the cluster paused.
+func (s *Store) Quiesce(ctx context.Context) error {
+	s.quiesceMu.Lock()
+	defer s.quiesceMu.Unlock()
+	if s.quiesceConn != nil {
+		return errors.New("whisker: quiesce: already quiesced")
+	}
+	if err := s.lc.acquire(); err != nil {
+		return fmt.Errorf("whisker: quiesce: %w", err)
+	}
+
+	conn, err := s.pool.PgxPool().Acquire(ctx)
+	if err != nil {
+		s.lc.release()
+		return fmt.Errorf("whisker: quiesce: acquire conn: %w", err)
+	}
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", quiesceLockKey); err != nil {
+		// the lock may have been granted as the context expired
+		_ = conn.Hijack().Close(context.Background())
+		s.lc.release()
+		return fmt.Errorf("whisker: quiesce: %w", err)
+	}
+	s.quiesceConn = conn
+	return nil
+}
+
+// Resume lifts a Quiesce taken by this store. It is a no-op when the store is
+// not quiesced.
+func (s *Store) Resume(ctx context.Context) error {
+	s.quiesceMu.Lock()
+	defer s.quiesceMu.Unlock()
+	conn := s.quiesceConn
+	if conn == nil {
+		return nil
+	}
+	s.quiesceConn = nil
+	defer s.lc.release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", quiesceLockKey); err != nil {
+		// closing the connection drops the lock server-side
+		_ = conn.Hijack().Close(context.Background())
+		return fmt.Errorf("whisker: resume: %w", err)
+	}
+	conn.Release()
+	return nil
+}
@@ -0,0 +1,102 @@
+//go:build integration
+
+package whisker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/documents"
+	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/internal/testutil"
+)
+
+func setupQuiesceStores(t *testing.T) (admin, writer *whisker.Store) {
+	t.Helper()
+	connStr := testutil.SetupPostgres(t)
+	ctx := context.Background()
+	admin, err := whisker.New(ctx, connStr, whisker.WithQuiesce(true))
+	if err != nil {
+		t.Fatalf("create admin store: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	writer, err = whisker.New(ctx, connStr, whisker.WithQuiesce(true))
+	if err != nil {
+		t.Fatalf("create writer store: %v", err)
+	}
+	t.Cleanup(func() { writer.Close() })
+	return admin, writer
+}
+
+func TestStore_QuiesceBlocksWritersAcrossStores(t *testing.T) {
+	admin, writer := setupQuiesceStores(t)
+	ctx := context.Background()
+	orders := documents.Collection[Order](writer, "quiesce_orders")
+
+	if err := orders.Insert(ctx, &Order{ID: "o1", Item: "before"}); err != nil {
+		t.Fatalf("insert before quiesce: %v", err)
+	}
+
+	if err := admin.Quiesce(ctx); err != nil {
+		t.Fatalf("quiesce: %v", err)
+	}
+
+	if err := orders.Insert(ctx, &Order{ID: "o2", Item: "during"}); !errors.Is(err, whisker.ErrMaintenance) {
+		t.Errorf("insert during quiesce: got %v, want ErrMaintenance", err)
+	}
+	err := events.New(writer).Append(ctx, "quiesce-stream", 0, []events.Event{{Type: "A", Data: []byte(`{}`)}})
+	if !errors.Is(err, whisker.ErrMaintenance) {
+		t.Errorf("append during quiesce: got %v, want ErrMaintenance", err)
+	}
+	if _, err := orders.Load(ctx, "o1"); err != nil {
+		t.Errorf("reads should continue during quiesce: %v", err)
+	}
+
+	if err := admin.Resume(ctx); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if err := orders.Insert(ctx, &Order{ID: "o2", Item: "after"}); err != nil {
+		t.Fatalf("insert after resume: %v", err)
+	}
+}
+
+func TestStore_QuiesceWaitsForWritingSessions(t *testing.T) {
+	admin, writer := setupQuiesceStores(t)
+	ctx := context.Background()
+
+	sess, err := writer.Session(ctx)
+	if err != nil {
+		t.Fatalf("session: %v", err)
+	}
+	defer sess.Close(ctx)
+	if err := documents.Collection[Order](sess, "quiesce_sessions").Insert(ctx, &Order{ID: "s1"}); err != nil {
+		t.Fatalf("insert in session: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- admin.Quiesce(ctx) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("quiesce returned while a session was writing: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if err := sess.Commit(ctx); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("quiesce: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("quiesce did not finish after the session committed")
+	}
+	if err := admin.Resume(ctx); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+}
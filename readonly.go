@@ -0,0 +1,149 @@
+package whisker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ripkitten-co/whisker/internal/codecs"
+	"github.com/ripkitten-co/whisker/internal/pg"
+	"github.com/ripkitten-co/whisker/schema"
+)
+
+// ReadOnly returns a handle on the store that cannot modify data, for
+// reporting components and plugins that should only read. Document writes,
+// event appends and checkpoint saves through it fail with ErrReadOnly; so
+// does any raw Exec on its executor. Queries each run in their own READ ONLY
+// transaction, so PostgreSQL rejects any that would write. Schema is never
+// created through the handle: collections and streams must already exist.
+func (s *Store) ReadOnly() Backend {
+	return &readOnly{be: backend{
+		exec:         readOnlyExecutor{pool: s.pool},
+		codec:        s.be.codec,
+		schema:       schema.New(schema.WithAutoMigrate(false)),
+		maxBatchSize: s.be.maxBatchSize,
+		clock:        s.be.clock,
+	}}
+}
+
+type readOnly struct {
+	be backend
+}
+
+func (r *readOnly) DBExecutor() pg.Executor            { return r.be.exec }
+func (r *readOnly) JSONCodec() codecs.Codec            { return r.be.codec }
+func (r *readOnly) SchemaBootstrap() *schema.Bootstrap { return r.be.schema }
+func (r *readOnly) MaxBatchSize() int                  { return r.be.maxBatchSize }
+func (r *readOnly) Clock() Clock                       { return r.be.clock }
+
+// readOnlyExecutor runs every query in a READ ONLY transaction on the pool.
+type readOnlyExecutor struct {
+	pool *pg.Pool
+}
+
+// Exec is refused outright: with the simple protocol one call can carry
+// several statements, including one that ends the transaction.
+func (e readOnlyExecutor) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, fmt.Errorf("whisker: exec: %w", ErrReadOnly)
+}
+
+// Query runs on the extended protocol, which allows a single statement, so
+// the query cannot leave the read-only transaction.
+func (e readOnlyExecutor) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	tx, err := e.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("whisker: read-only query: %w", err)
+	}
+	rows, err := tx.Query(ctx, sql, e.extendedProtocol(args)...)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, err
+	}
+	return &readOnlyRows{Rows: rows, ctx: ctx, tx: tx}, nil
+}
+
+// extendedProtocol replaces the simple protocol, whether it is the pool's
+// default or requested by a pgx.QueryExecMode argument.
+func (e readOnlyExecutor) extendedProtocol(args []any) []any {
+	out := make([]any, 0, len(args)+1)
+	if e.pool.PgxPool().Config().ConnConfig.DefaultQueryExecMode == pgx.QueryExecModeSimpleProtocol {
+		out = append(out, pgx.QueryExecModeDescribeExec)
+	}
+	for _, arg := range args {
+		if mode, ok := arg.(pgx.QueryExecMode); ok && mode == pgx.QueryExecModeSimpleProtocol {
+			arg = pgx.QueryExecModeDescribeExec
+		}
+		out = append(out, arg)
+	}
+	return out
+}
+
+func (e readOnlyExecutor) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	rows, err := e.Query(ctx, sql, args...)
+	return readOnlyRow{rows: rows, err: err}
+}
+
+// Begin starts a READ ONLY transaction, for reads that span several
+// statements such as a document query cursor.
+func (e readOnlyExecutor) Begin(ctx context.Context) (pgx.Tx, error) {
+	return e.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+}
+
+// CheckWrite fails every write before it reaches the database.
+func (e readOnlyExecutor) CheckWrite(context.Context) error {
+	return ErrReadOnly
+}
+
+// readOnlyRows ends its transaction once the rows are exhausted or closed.
+type readOnlyRows struct {
+	pgx.Rows
+	ctx  context.Context
+	tx   pgx.Tx
+	done bool
+}
+
+func (r *readOnlyRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.finish()
+	return false
+}
+
+func (r *readOnlyRows) Close() {
+	r.Rows.Close()
+	r.finish()
+}
+
+func (r *readOnlyRows) finish() {
+	if r.done {
+		return
+	}
+	r.done = true
+	// nothing was written, so rolling back is as good as committing
+	_ = r.tx.Rollback(context.WithoutCancel(r.ctx))
+}
+
+// readOnlyRow gives QueryRow semantics on top of readOnlyExecutor.Query.
+type readOnlyRow struct {
+	rows pgx.Rows
+	err  error
+}
+
+func (r readOnlyRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	return r.rows.Err()
+}
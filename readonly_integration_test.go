@@ -0,0 +1,62 @@
+//go:build integration
+
+package whisker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/documents"
+	"github.com/ripkitten-co/whisker/events"
+)
+
+func TestStore_ReadOnlyReadsButCannotWrite(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+	if err := documents.Collection[Order](store, "ro_orders").Insert(ctx, &Order{ID: "o1", Item: "widget"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := events.New(store).Append(ctx, "ro-stream", 0, []events.Event{{Type: "A", Data: []byte(`{}`)}}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	ro := store.ReadOnly()
+	orders := documents.Collection[Order](ro, "ro_orders")
+
+	got, err := orders.Load(ctx, "o1")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Item != "widget" {
+		t.Errorf("item: got %q, want widget", got.Item)
+	}
+	if _, err := orders.Load(ctx, "missing"); !errors.Is(err, whisker.ErrNotFound) {
+		t.Errorf("load missing: got %v, want ErrNotFound", err)
+	}
+	evts, err := events.New(ro).ReadStream(ctx, "ro-stream", 0)
+	if err != nil || len(evts) != 1 {
+		t.Fatalf("read stream: got %d events, err %v", len(evts), err)
+	}
+
+	if err := orders.Insert(ctx, &Order{ID: "o2"}); !errors.Is(err, whisker.ErrReadOnly) {
+		t.Errorf("insert: got %v, want ErrReadOnly", err)
+	}
+	err = events.New(ro).Append(ctx, "ro-stream", 1, []events.Event{{Type: "B", Data: []byte(`{}`)}})
+	if !errors.Is(err, whisker.ErrReadOnly) {
+		t.Errorf("append: got %v, want ErrReadOnly", err)
+	}
+
+	// raw SQL cannot write either, even with a mode that would allow several
+	// statements
+	var id string
+	err = ro.DBExecutor().QueryRow(ctx, "DELETE FROM whisker_ro_orders RETURNING id", pgx.QueryExecModeSimpleProtocol).Scan(&id)
+	if err == nil {
+		t.Error("delete through read-only executor succeeded")
+	}
+	if _, err := orders.Load(ctx, "o1"); err != nil {
+		t.Errorf("document gone after refused delete: %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package whisker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/internal/pg"
+)
+
+func TestReadOnly_RefusesWrites(t *testing.T) {
+	s := &Store{be: backend{maxBatchSize: 50}}
+	ro := s.ReadOnly()
+
+	if _, err := ro.DBExecutor().Exec(context.Background(), "DELETE FROM whisker_orders"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("exec: got %v, want ErrReadOnly", err)
+	}
+	if err := pg.CheckWrite(context.Background(), ro.DBExecutor()); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("check write: got %v, want ErrReadOnly", err)
+	}
+	if ro.SchemaBootstrap().AutoMigrate() {
+		t.Error("read-only handle should not run DDL")
+	}
+	if ro.MaxBatchSize() != 50 {
+		t.Errorf("max batch size: got %d, want 50", ro.MaxBatchSize())
+	}
+}
@@ -0,0 +1,123 @@
+package whisker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Registry manages several named Stores in one process, for services that
+// span more than one PostgreSQL cluster (per region, per domain). Collections
+// are addressed as "db:collection"; see Resolve.
+type Registry struct {
+	mu     sync.RWMutex
+	stores map[string]*Store
+	names  []string
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{stores: map[string]*Store{}}
+}
+
+// Add registers s under name. Names must be non-empty, must not contain ':'
+// and must be unique within the registry.
+func (r *Registry) Add(name string, s *Store) error {
+	if name == "" || strings.Contains(name, ":") {
+		return fmt.Errorf("whisker: registry: invalid store name %q", name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.stores[name]; ok {
+		return fmt.Errorf("whisker: registry: store %q already registered", name)
+	}
+	r.stores[name] = s
+	r.names = append(r.names, name)
+	return nil
+}
+
+// Open connects a new Store and registers it under name. The store is closed
+// again if it cannot be registered.
+func (r *Registry) Open(ctx context.Context, name, connString string, opts ...Option) (*Store, error) {
+	s, err := New(ctx, connString, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("whisker: registry: open %s: %w", name, err)
+	}
+	if err := r.Add(name, s); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Store returns the store registered under name.
+func (r *Registry) Store(name string) (*Store, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.stores[name]
+	if !ok {
+		return nil, fmt.Errorf("whisker: registry: unknown store %q", name)
+	}
+	return s, nil
+}
+
+// Resolve splits an address of the form "db:collection" and returns the
+// store registered as db along with the collection name.
+func (r *Registry) Resolve(addr string) (*Store, string, error) {
+	db, name, ok := strings.Cut(addr, ":")
+	if !ok || db == "" || name == "" {
+		return nil, "", fmt.Errorf("whisker: registry: address %q: want db:collection", addr)
+	}
+	s, err := r.Store(db)
+	if err != nil {
+		return nil, "", err
+	}
+	return s, name, nil
+}
+
+// Names returns the registered store names in registration order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.names...)
+}
+
+// Shutdown shuts every registered store down concurrently (see
+// Store.Shutdown) and returns their errors joined.
+func (r *Registry) Shutdown(ctx context.Context) error {
+	r.mu.RLock()
+	stores := make(map[string]*Store, len(r.stores))
+	for name, s := range r.stores {
+		stores[name] = s
+	}
+	r.mu.RUnlock()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for name, s := range stores {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Shutdown(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Close closes every registered store (see Store.Close).
+func (r *Registry) Close() {
+	for _, name := range r.Names() {
+		s, _ := r.Store(name)
+		s.Close()
+	}
+}
@@ -0,0 +1,50 @@
+//go:build integration
+
+package whisker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/documents"
+	"github.com/ripkitten-co/whisker/internal/testutil"
+)
+
+func TestRegistry_CollectionsAcrossStores(t *testing.T) {
+	ctx := context.Background()
+	reg := whisker.NewRegistry()
+	for _, name := range []string{"eu", "us"} {
+		if _, err := reg.Open(ctx, name, testutil.SetupPostgres(t)); err != nil {
+			t.Fatalf("open %s: %v", name, err)
+		}
+	}
+	t.Cleanup(reg.Close)
+
+	eu, err := documents.CollectionIn[Order](reg, "eu:registry_orders")
+	if err != nil {
+		t.Fatalf("collection: %v", err)
+	}
+	us, err := documents.CollectionIn[Order](reg, "us:registry_orders")
+	if err != nil {
+		t.Fatalf("collection: %v", err)
+	}
+
+	if err := eu.Insert(ctx, &Order{ID: "o1", Item: "eu"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, err := us.Load(ctx, "o1"); !errors.Is(err, whisker.ErrNotFound) {
+		t.Errorf("us load: got %v, want ErrNotFound", err)
+	}
+
+	if err := reg.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	for _, name := range reg.Names() {
+		s, _ := reg.Store(name)
+		if _, err := s.Session(ctx); !errors.Is(err, whisker.ErrStoreClosed) {
+			t.Errorf("%s session after shutdown: got %v, want ErrStoreClosed", name, err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package whisker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistry_AddAndResolve(t *testing.T) {
+	r := NewRegistry()
+	eu, us := &Store{}, &Store{}
+	if err := r.Add("eu", eu); err != nil {
+		t.Fatalf("add eu: %v", err)
+	}
+	if err := r.Add("us", us); err != nil {
+		t.Fatalf("add us: %v", err)
+	}
+
+	s, name, err := r.Resolve("us:orders")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if s != us || name != "orders" {
+		t.Errorf("got store %p collection %q, want us store and orders", s, name)
+	}
+	if got := strings.Join(r.Names(), ","); got != "eu,us" {
+		t.Errorf("names: got %s, want eu,us", got)
+	}
+}
+
+func TestRegistry_Errors(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Add("eu", &Store{}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"duplicate", r.Add("eu", &Store{}), "already registered"},
+		{"empty name", r.Add("", &Store{}), "invalid store name"},
+		{"colon in name", r.Add("a:b", &Store{}), "invalid store name"},
+		{"unknown store", errOf(r.Resolve("ap:orders")), `unknown store "ap"`},
+		{"unqualified", errOf(r.Resolve("orders")), "want db:collection"},
+		{"empty collection", errOf(r.Resolve("eu:")), "want db:collection"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err == nil || !strings.Contains(tt.err.Error(), tt.want) {
+				t.Errorf("got %v, want error containing %q", tt.err, tt.want)
+			}
+		})
+	}
+}
+
+func errOf(_ *Store, _ string, err error) error { return err }
@@ -3,19 +3,20 @@ package schema
 import (
 	"context"
 	"fmt"
-	"regexp"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/ripkitten-co/whisker/internal/ident"
 	"github.com/ripkitten-co/whisker/internal/pg"
 )
 
-var validName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,54}$`)
-
 // ValidateCollectionName checks that name is a valid collection identifier
 // (alphanumeric + underscores, max 55 characters, starts with a letter).
 func ValidateCollectionName(name string) error {
-	if !validName.MatchString(name) {
-		return fmt.Errorf("schema: invalid collection name %q: must be alphanumeric with underscores, max 55 chars", name)
+	if err := ident.ValidateCollection(name); err != nil {
+		return fmt.Errorf("schema: %w", err)
 	}
 	return nil
 }
@@ -26,21 +27,58 @@ func collectionDDL(name string) string {
 	data JSONB NOT NULL,
 	version INTEGER NOT NULL DEFAULT 1,
 	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
-	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	deleted_at TIMESTAMPTZ,
+	schema_version INTEGER NOT NULL DEFAULT 1
 )`, name)
 }
 
-func eventsDDL() string {
-	return `CREATE TABLE IF NOT EXISTS whisker_events (
+func schemaVersionDDL(name string) string {
+	return fmt.Sprintf(`ALTER TABLE whisker_%s ADD COLUMN IF NOT EXISTS schema_version INTEGER NOT NULL DEFAULT 1`, name)
+}
+
+func deletedAtDDL(name string) string {
+	return fmt.Sprintf(`ALTER TABLE whisker_%s ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ`, name)
+}
+
+// EventsTable returns the table of the named event store: whisker_events for
+// the default store (empty name), whisker_events_{name} otherwise.
+func EventsTable(name string) string {
+	if name == "" {
+		return "whisker_events"
+	}
+	return "whisker_events_" + name
+}
+
+func eventsDDL(table string) string {
+	return eventsTableDDL(table, "JSONB")
+}
+
+// binaryEventsDDL creates an events table whose data column is BYTEA, for
+// payloads that are not JSON, and fails if the table already exists with
+// another data type.
+func binaryEventsDDL(table string) string {
+	return eventsTableDDL(table, "BYTEA") + fmt.Sprintf(`;
+DO $$
+BEGIN
+	IF (SELECT data_type FROM information_schema.columns
+	    WHERE table_schema = current_schema() AND table_name = '%[1]s' AND column_name = 'data') <> 'bytea' THEN
+		RAISE EXCEPTION '%[1]s exists with a JSONB data column';
+	END IF;
+END $$`, table)
+}
+
+func eventsTableDDL(table, dataType string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
 	stream_id TEXT NOT NULL,
 	version INTEGER NOT NULL,
 	type TEXT NOT NULL,
-	data JSONB NOT NULL,
+	data %s NOT NULL,
 	metadata JSONB,
 	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
 	global_position BIGINT GENERATED ALWAYS AS IDENTITY,
 	PRIMARY KEY (stream_id, version)
-)`
+)`, table, dataType)
 }
 
 func projectionCheckpointsDDL() string {
@@ -48,20 +86,109 @@ func projectionCheckpointsDDL() string {
 	projection_name TEXT PRIMARY KEY,
 	last_position BIGINT NOT NULL DEFAULT 0,
 	status TEXT NOT NULL DEFAULT 'running',
-	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	owner_instance TEXT,
+	owner_host TEXT,
+	owner_acquired_at TIMESTAMPTZ,
+	metadata JSONB,
+	subscriber_version INTEGER,
+	failure JSONB
 )`
 }
 
+// projectionCheckpointOwnerDDL adds the ownership, metadata, version and
+// failure columns to checkpoint tables created before they existed.
+func projectionCheckpointOwnerDDL() string {
+	return `ALTER TABLE whisker_projection_checkpoints
+	ADD COLUMN IF NOT EXISTS owner_instance TEXT,
+	ADD COLUMN IF NOT EXISTS owner_host TEXT,
+	ADD COLUMN IF NOT EXISTS owner_acquired_at TIMESTAMPTZ,
+	ADD COLUMN IF NOT EXISTS metadata JSONB,
+	ADD COLUMN IF NOT EXISTS subscriber_version INTEGER,
+	ADD COLUMN IF NOT EXISTS failure JSONB`
+}
+
 // Bootstrap manages idempotent creation of Whisker tables and indexes.
 // It caches which tables and indexes have been created to avoid repeated DDL.
 type Bootstrap struct {
-	tables  sync.Map
-	indexes sync.Map
+	tables     sync.Map
+	indexes    sync.Map
+	columns    sync.Map
+	extensions sync.Map
+	schemas    sync.Map
+	// policies holds the EnsureRLS policies installed, keyed by table and
+	// expression.
+	policies    sync.Map
+	autoMigrate bool
+
+	notifyNamespace string
+	schemaName      string
+	extensionSchema string
+	tablePolicy     func(table string) string
+
+	// flights holds a one-slot semaphore per cache key, so concurrent first
+	// uses of a table run its DDL once instead of stampeding.
+	flights  sync.Map
+	observer func(EnsureStat)
+}
+
+// EnsureStat reports one DDL run by an Ensure method, for metrics on the cost
+// of first use.
+type EnsureStat struct {
+	// Object is the table, index, column (table.column), policy
+	// ("table.rls(expression)"), extension ("extension name") or function
+	// ("function name") ensured.
+	Object string
+	// Wait is how long the caller queued behind a concurrent caller ensuring
+	// the same object.
+	Wait time.Duration
+	// Duration is how long the DDL took.
+	Duration time.Duration
+	Err      error
+}
+
+// Option configures a Bootstrap.
+type Option func(*Bootstrap)
+
+// WithAutoMigrate controls whether Ensure* methods run DDL. When disabled
+// they only validate names and assume the schema already exists. Enabled by
+// default.
+func WithAutoMigrate(enabled bool) Option {
+	return func(b *Bootstrap) { b.autoMigrate = enabled }
+}
+
+// WithObserver registers fn to receive an EnsureStat after each DDL run.
+// Calls answered from the cache are not reported. fn must be safe for
+// concurrent use.
+func WithObserver(fn func(EnsureStat)) Option {
+	return func(b *Bootstrap) { b.observer = fn }
 }
 
 // New returns a Bootstrap with empty caches.
-func New() *Bootstrap {
-	return &Bootstrap{}
+func New(opts ...Option) *Bootstrap {
+	b := &Bootstrap{autoMigrate: true}
+	for _, o := range opts {
+		o(b)
+	}
+	return b
+}
+
+// Derive returns a Bootstrap with b's options and empty caches, for a
+// transaction whose DDL may be rolled back and must not be cached in b.
+func (b *Bootstrap) Derive() *Bootstrap {
+	return &Bootstrap{
+		autoMigrate:     b.autoMigrate,
+		notifyNamespace: b.notifyNamespace,
+		schemaName:      b.schemaName,
+		extensionSchema: b.extensionSchema,
+		tablePolicy:     b.tablePolicy,
+		observer:        b.observer,
+	}
+}
+
+// AutoMigrate reports whether DDL is run on first use.
+func (b *Bootstrap) AutoMigrate() bool {
+	return b.autoMigrate
 }
 
 // IsCreated reports whether the named table has been created in this session.
@@ -81,11 +208,39 @@ func (b *Bootstrap) IsIndexCreated(name string) bool {
 	return ok
 }
 
-// InvalidateTable removes a table from the creation cache so the next
-// EnsureCollection call will re-run the DDL. Used by Rebuild after dropping
-// a projection table.
+// IsColumnCreated reports whether the named column (table.column) has been
+// added in this session.
+func (b *Bootstrap) IsColumnCreated(key string) bool {
+	_, ok := b.columns.Load(key)
+	return ok
+}
+
+// MarkColumnCreated records that the named column (table.column) exists.
+func (b *Bootstrap) MarkColumnCreated(key string) {
+	b.columns.Store(key, true)
+}
+
+// InvalidateTable removes a table, its columns and its indexes from the
+// creation caches so the next Ensure calls will re-run the DDL. Used by
+// Rebuild after dropping a projection table.
 func (b *Bootstrap) InvalidateTable(table string) {
 	b.tables.Delete(table)
+	prefix := table + "."
+	b.columns.Range(func(k, _ any) bool {
+		if strings.HasPrefix(k.(string), prefix) {
+			b.columns.Delete(k)
+		}
+		return true
+	})
+	// index names start idx_<table>_; a longer table name sharing the prefix
+	// only costs a redundant CREATE INDEX IF NOT EXISTS
+	indexPrefix := "idx_" + table + "_"
+	b.indexes.Range(func(k, _ any) bool {
+		if strings.HasPrefix(k.(string), indexPrefix) {
+			b.indexes.Delete(k)
+		}
+		return true
+	})
 }
 
 // MarkIndexCreated records that the named index has been created.
@@ -93,65 +248,715 @@ func (b *Bootstrap) MarkIndexCreated(name string) {
 	b.indexes.Store(name, true)
 }
 
+// ensure runs ddl unless cache holds value under key. Concurrent callers for
+// the same key queue behind the first and then find the cache filled, so the
+// DDL runs once; a failed run leaves the key unset for the next caller.
+// Waiting honours ctx.
+func (b *Bootstrap) ensure(ctx context.Context, exec pg.Executor, cache *sync.Map, key string, value any, ddl func() error) error {
+	if cached, ok := cache.Load(key); ok && cached == value {
+		return nil
+	}
+	// unqualified DDL lands in the first existing schema of the search_path,
+	// so the store's schema has to exist before anything else is created
+	if b.schemaName != "" && cache != &b.schemas {
+		if err := b.ensureSchema(ctx, exec); err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	flight, _ := b.flights.LoadOrStore(key, make(chan struct{}, 1))
+	sem := flight.(chan struct{})
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("schema: ensure %s: %w", key, ctx.Err())
+	}
+	defer func() { <-sem }()
+	wait := time.Since(start)
+
+	if cached, ok := cache.Load(key); ok && cached == value {
+		return nil
+	}
+	began := time.Now()
+	err := ddl()
+	if b.observer != nil {
+		b.observer(EnsureStat{Object: key, Wait: wait, Duration: time.Since(began), Err: err})
+	}
+	if err != nil {
+		return err
+	}
+	cache.Store(key, value)
+	return nil
+}
+
+// WithSchemaName makes the Ensure methods create the PostgreSQL schema name
+// before their first DDL. The connections' search_path must list it first,
+// so the unqualified tables land in it.
+func WithSchemaName(name string) Option {
+	return func(b *Bootstrap) { b.schemaName = name }
+}
+
+// WithExtensionSchema makes EnsureExtension install extensions into the
+// PostgreSQL schema name instead of public. The schema must exist and be on
+// the connections' search_path, so the extensions' functions and operator
+// classes resolve unqualified.
+func WithExtensionSchema(name string) Option {
+	return func(b *Bootstrap) { b.extensionSchema = name }
+}
+
+// SchemaName returns the schema set with WithSchemaName.
+func (b *Bootstrap) SchemaName() string {
+	return b.schemaName
+}
+
+// ensureSchema creates the schema set with WithSchemaName if it is missing.
+func (b *Bootstrap) ensureSchema(ctx context.Context, exec pg.Executor) error {
+	return b.ensure(ctx, exec, &b.schemas, "schema "+b.schemaName, true, func() error {
+		sql := "CREATE SCHEMA IF NOT EXISTS " + ident.QuoteIdent(b.schemaName)
+		if _, err := exec.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("schema: create schema %s: %w", b.schemaName, err)
+		}
+		return nil
+	})
+}
+
+// EnsureIndex runs ddl, a CREATE INDEX statement for the named index, unless
+// the index was created in this session. Like the other Ensure methods it
+// runs the DDL once for concurrent callers. Once the index exists, the
+// indexes named in replaces, which it supersedes, are dropped when present.
+func (b *Bootstrap) EnsureIndex(ctx context.Context, exec pg.Executor, name, ddl string, replaces ...string) error {
+	if !b.autoMigrate {
+		return nil
+	}
+	return b.ensure(ctx, exec, &b.indexes, name, true, func() error {
+		if _, err := exec.Exec(ctx, ddl); err != nil {
+			return fmt.Errorf("schema: create index %s: %w", name, err)
+		}
+		for _, old := range replaces {
+			if _, err := exec.Exec(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+old); err != nil {
+				return fmt.Errorf("schema: drop index %s superseded by %s: %w", old, name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// EnsureExtension creates the named PostgreSQL extension, such as pg_trgm,
+// unless it was created in this session. It is installed into public, or the
+// schema set with WithExtensionSchema, rather than the first schema of the
+// search_path, which may be a tenant's; an extension installed elsewhere
+// already is left where it is. Creating an extension may need privileges the
+// application role lacks; install it ahead of time then.
+func (b *Bootstrap) EnsureExtension(ctx context.Context, exec pg.Executor, name string) error {
+	if !b.autoMigrate {
+		return nil
+	}
+	if !ident.IsIdentifier(name) {
+		return fmt.Errorf("schema: invalid extension name %q", name)
+	}
+	extSchema := b.extensionSchema
+	if extSchema == "" {
+		extSchema = "public"
+	}
+	if !ident.IsIdentifier(extSchema) {
+		return fmt.Errorf("schema: invalid extension schema %q", extSchema)
+	}
+	return b.ensure(ctx, exec, &b.extensions, "extension "+name, true, func() error {
+		if _, err := exec.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS "+name+" SCHEMA "+extSchema); err != nil {
+			return fmt.Errorf("schema: create extension %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// timestamptzFuncDDL creates whisker_timestamptz, the IMMUTABLE wrapper of
+// text::timestamptz that indexes and queries on time fields share. The cast
+// is only stable because text without an offset is read in the session's
+// time zone; encoding/json always writes the offset, so for documents the
+// result does not vary.
+func timestamptzFuncDDL() string {
+	return `DO $$ BEGIN
+	IF to_regprocedure('whisker_timestamptz(text)') IS NULL THEN
+		CREATE FUNCTION whisker_timestamptz(text) RETURNS timestamptz
+			AS 'SELECT $1::timestamptz' LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE;
+	END IF;
+END $$`
+}
+
+// EnsureTimestamptzFunc creates the whisker_timestamptz function that
+// comparisons and indexes on time.Time fields call. Without auto-migration
+// it must be created ahead of time with the same definition.
+func (b *Bootstrap) EnsureTimestamptzFunc(ctx context.Context, exec pg.Executor) error {
+	if !b.autoMigrate {
+		return nil
+	}
+	return b.ensure(ctx, exec, &b.extensions, "function whisker_timestamptz", true, func() error {
+		if _, err := exec.Exec(ctx, timestamptzFuncDDL()); err != nil {
+			return fmt.Errorf("schema: create function whisker_timestamptz: %w", err)
+		}
+		return nil
+	})
+}
+
 // EnsureCollection creates the whisker_{name} table if it doesn't exist.
 func (b *Bootstrap) EnsureCollection(ctx context.Context, exec pg.Executor, name string) error {
 	if err := ValidateCollectionName(name); err != nil {
 		return err
 	}
+	if !b.autoMigrate {
+		return nil
+	}
 	table := "whisker_" + name
-	if _, ok := b.tables.Load(table); ok {
+	return b.ensure(ctx, exec, &b.tables, table, true, func() error {
+		if _, err := exec.Exec(ctx, collectionDDL(name)); err != nil {
+			return fmt.Errorf("schema: create table %s: %w", table, err)
+		}
+		return b.applyTablePolicy(ctx, exec, table)
+	})
+}
+
+// EnsureDeletedAt adds the deleted_at tombstone column to whisker_{name} if it
+// is missing. Tables created before tombstone support lack the column; new
+// tables already have it, so for them it only reads the catalog and runs no
+// ALTER TABLE.
+func (b *Bootstrap) EnsureDeletedAt(ctx context.Context, exec pg.Executor, name string) error {
+	if err := ValidateCollectionName(name); err != nil {
+		return err
+	}
+	if !b.autoMigrate {
+		return nil
+	}
+	key := "whisker_" + name + ".deleted_at"
+	return b.ensure(ctx, exec, &b.columns, key, true, func() error {
+		exists, err := hasColumn(ctx, exec, "whisker_"+name, "deleted_at")
+		if err != nil {
+			return fmt.Errorf("schema: check deleted_at of whisker_%s: %w", name, err)
+		}
+		if exists {
+			return nil
+		}
+		if _, err := exec.Exec(ctx, deletedAtDDL(name)); err != nil {
+			return fmt.Errorf("schema: add deleted_at to whisker_%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// EnsureSchemaVersion adds the schema_version column, which records the
+// document data-schema version used by migrations, to whisker_{name} if it is
+// missing. Existing rows start at version 1.
+func (b *Bootstrap) EnsureSchemaVersion(ctx context.Context, exec pg.Executor, name string) error {
+	if err := ValidateCollectionName(name); err != nil {
+		return err
+	}
+	if !b.autoMigrate {
+		return nil
+	}
+	key := "whisker_" + name + ".schema_version"
+	return b.ensure(ctx, exec, &b.columns, key, true, func() error {
+		if _, err := exec.Exec(ctx, schemaVersionDDL(name)); err != nil {
+			return fmt.Errorf("schema: add schema_version to whisker_%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// migratedQuery returns the data-schema version recorded in the comment of
+// the schema_version column of table $1, or "" when there is none.
+const migratedQuery = `SELECT COALESCE((
+	SELECT col_description(a.attrelid, a.attnum) FROM pg_attribute a
+	WHERE a.attrelid = to_regclass($1) AND a.attname = 'schema_version'), '')`
+
+// EnsureMigrated runs migrate, which rewrites the documents of whisker_{name}
+// stored below data-schema version latest, unless it has already run for
+// latest. Once it has, latest is recorded in the comment of the
+// schema_version column, so other processes and later restarts skip it. The
+// column must already exist; see EnsureSchemaVersion.
+func (b *Bootstrap) EnsureMigrated(ctx context.Context, exec pg.Executor, name string, latest int, migrate func() error) error {
+	if err := ValidateCollectionName(name); err != nil {
+		return err
+	}
+	if !b.autoMigrate {
+		return nil
+	}
+	key := "whisker_" + name + ".migrated"
+	return b.ensure(ctx, exec, &b.columns, key, latest, func() error {
+		var recorded string
+		if err := exec.QueryRow(ctx, migratedQuery, "whisker_"+name).Scan(&recorded); err != nil {
+			return fmt.Errorf("schema: check migrations of whisker_%s: %w", name, err)
+		}
+		if done, err := strconv.Atoi(recorded); err == nil && done >= latest {
+			return nil
+		}
+		if err := migrate(); err != nil {
+			return fmt.Errorf("schema: migrate whisker_%s to version %d: %w", name, latest, err)
+		}
+		if _, err := exec.Exec(ctx, fmt.Sprintf(`COMMENT ON COLUMN whisker_%s.schema_version IS '%d'`, name, latest)); err != nil {
+			return fmt.Errorf("schema: record migrations of whisker_%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+func attachmentsDDL(name string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS whisker_%[1]s_attachments (
+	doc_id TEXT NOT NULL REFERENCES whisker_%[1]s (id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	chunk INTEGER NOT NULL,
+	data BYTEA NOT NULL,
+	PRIMARY KEY (doc_id, name, chunk)
+)`, name)
+}
+
+// EnsureAttachments creates the whisker_{name}_attachments table if it doesn't
+// exist. Attachment rows reference the parent document and are removed with
+// it. The parent collection table must already exist.
+func (b *Bootstrap) EnsureAttachments(ctx context.Context, exec pg.Executor, name string) error {
+	if err := ValidateCollectionName(name); err != nil {
+		return err
+	}
+	if !b.autoMigrate {
+		return nil
+	}
+	table := "whisker_" + name + "_attachments"
+	return b.ensure(ctx, exec, &b.tables, table, true, func() error {
+		if _, err := exec.Exec(ctx, attachmentsDDL(name)); err != nil {
+			return fmt.Errorf("schema: create table %s: %w", table, err)
+		}
+		return b.applyTablePolicy(ctx, exec, table)
+	})
+}
+
+func tenantDDL(name string) string {
+	return fmt.Sprintf(`ALTER TABLE whisker_%[1]s ADD COLUMN IF NOT EXISTS tenant_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_whisker_%[1]s_tenant_id ON whisker_%[1]s (tenant_id)`, name)
+}
+
+// EnsureTenant adds the tenant_id column that tenant-scoped collections and
+// event stores record each row's tenant in to whisker_{name}, with an index
+// on it, if it is missing. Rows written before have no tenant.
+func (b *Bootstrap) EnsureTenant(ctx context.Context, exec pg.Executor, name string) error {
+	if err := ValidateCollectionName(name); err != nil {
+		return err
+	}
+	if !b.autoMigrate {
+		return nil
+	}
+	key := "whisker_" + name + ".tenant_id"
+	return b.ensure(ctx, exec, &b.columns, key, true, func() error {
+		if _, err := exec.Exec(ctx, tenantDDL(name)); err != nil {
+			return fmt.Errorf("schema: add tenant_id to whisker_%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// tableRLSDDL installs policy as the {table}_rls policy and records the
+// expression as written in the policy's comment, since PostgreSQL stores a
+// reformatted copy.
+func tableRLSDDL(table, policy string) string {
+	return fmt.Sprintf(`ALTER TABLE %[1]s ENABLE ROW LEVEL SECURITY;
+ALTER TABLE %[1]s FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS %[1]s_rls ON %[1]s;
+CREATE POLICY %[1]s_rls ON %[1]s USING (%[2]s) WITH CHECK (%[2]s);
+COMMENT ON POLICY %[1]s_rls ON %[1]s IS '%[3]s'`, table, policy, strings.ReplaceAll(policy, "'", "''"))
+}
+
+// hasColumnQuery reports whether table $1 has the column $2.
+const hasColumnQuery = `SELECT EXISTS (
+	SELECT 1 FROM pg_attribute
+	WHERE attrelid = to_regclass($1) AND attname = $2 AND NOT attisdropped)`
+
+// hasColumn reports whether table has column, so that ALTER TABLE, which
+// locks the table even when it changes nothing, only runs on tables that
+// lack it.
+func hasColumn(ctx context.Context, exec pg.Executor, table, column string) (bool, error) {
+	var ok bool
+	err := exec.QueryRow(ctx, hasColumnQuery, table, column).Scan(&ok)
+	return ok, err
+}
+
+// hasTablePolicyQuery reports whether row-level security is enabled and
+// forced on table $1 and its policy $2 was installed with the expression $3.
+const hasTablePolicyQuery = `SELECT EXISTS (
+	SELECT 1 FROM pg_class c JOIN pg_policy p ON p.polrelid = c.oid
+	WHERE c.oid = to_regclass($1) AND p.polname = $2
+	  AND c.relrowsecurity AND c.relforcerowsecurity
+	  AND obj_description(p.oid, 'pg_policy') = $3)`
+
+// hasTablePolicy reports whether table already has policy, installed by
+// tableRLSDDL.
+func hasTablePolicy(ctx context.Context, exec pg.Executor, table, policy string) (bool, error) {
+	var ok bool
+	err := exec.QueryRow(ctx, hasTablePolicyQuery, table, table+"_rls", policy).Scan(&ok)
+	return ok, err
+}
+
+// WithTablePolicy makes the Ensure methods enable row-level security on
+// every table they create and install the {table}_rls policy with the
+// expression policy returns for the table's name, e.g. "whisker_orders" or
+// "whisker_events". Return "" to leave a table without one. It suits
+// deployments with a database role per tenant, such as
+//
+//	schema.WithTablePolicy(func(string) string { return "pg_has_role('app_tenants', 'MEMBER')" })
+//
+// The policy runs right after CREATE TABLE, so it can only refer to the
+// columns the table is created with.
+// The policy is checked when the table is first ensured in this process, so
+// tables that already exist get it on the first use after a restart; a table
+// that already has the same expression is left alone. A collection's own
+// WithRLS policy replaces it, having the same name. As with EnsureRLS, FORCE
+// ROW LEVEL SECURITY applies it to the table owner.
+func WithTablePolicy(policy func(table string) string) Option {
+	return func(b *Bootstrap) { b.tablePolicy = policy }
+}
+
+// applyTablePolicy installs the WithTablePolicy policy on table, unless the
+// table already has it. The table Ensure methods call it after creating
+// their table.
+func (b *Bootstrap) applyTablePolicy(ctx context.Context, exec pg.Executor, table string) error {
+	if b.tablePolicy == nil {
+		return nil
+	}
+	policy := b.tablePolicy(table)
+	if policy == "" {
 		return nil
 	}
-	_, err := exec.Exec(ctx, collectionDDL(name))
+	return installPolicy(ctx, exec, table, policy)
+}
+
+// installPolicy enables row-level security on table with policy, unless the
+// table already has it.
+func installPolicy(ctx context.Context, exec pg.Executor, table, policy string) error {
+	installed, err := hasTablePolicy(ctx, exec, table, policy)
 	if err != nil {
-		return fmt.Errorf("schema: create table %s: %w", table, err)
+		return fmt.Errorf("schema: check rls on %s: %w", table, err)
+	}
+	if installed {
+		return nil
+	}
+	if _, err := exec.Exec(ctx, tableRLSDDL(table, policy)); err != nil {
+		return fmt.Errorf("schema: enable rls on %s: %w", table, err)
 	}
-	b.tables.Store(table, true)
 	return nil
 }
 
+// EnsureRLS enables row-level security on whisker_{name} and installs the
+// whisker_{name}_rls policy with the given expression, replacing a policy
+// with another expression. FORCE ROW LEVEL SECURITY makes the policy apply to
+// the table owner too.
+func (b *Bootstrap) EnsureRLS(ctx context.Context, exec pg.Executor, name, policy string) error {
+	if err := ValidateCollectionName(name); err != nil {
+		return err
+	}
+	if !b.autoMigrate {
+		return nil
+	}
+	prefix := "whisker_" + name + ".rls("
+	key := prefix + policy + ")"
+	return b.ensure(ctx, exec, &b.policies, key, true, func() error {
+		if err := installPolicy(ctx, exec, "whisker_"+name, policy); err != nil {
+			return err
+		}
+		// the policy replaced any other expression cached for the table
+		b.policies.Range(func(k, _ any) bool {
+			if k != key && strings.HasPrefix(k.(string), prefix) {
+				b.policies.Delete(k)
+			}
+			return true
+		})
+		return nil
+	})
+}
+
+// ChangeFeedStore returns the name of the event store that receives the
+// change feed of the named collection: {name}_changes.
+func ChangeFeedStore(name string) string {
+	return name + "_changes"
+}
+
+// changeFeedDDL installs a trigger on whisker_{name} that appends every
+// insert, update and delete to the change feed's events table, one stream
+// per document id. Updates that change neither data nor deleted_at are
+// skipped, and setting deleted_at is recorded as a delete. Each event's
+// metadata holds the document's version and schema_version. Appends are
+// signalled on channel.
+func changeFeedDDL(name, channel string) string {
+	return fmt.Sprintf(`CREATE OR REPLACE FUNCTION whisker_%[1]s_changes() RETURNS trigger AS $$
+DECLARE
+	doc RECORD;
+	change TEXT;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		doc := OLD;
+		change := 'DocumentDeleted';
+	ELSIF TG_OP = 'INSERT' THEN
+		doc := NEW;
+		change := 'DocumentInserted';
+	ELSIF NEW.data IS NOT DISTINCT FROM OLD.data AND NEW.deleted_at IS NOT DISTINCT FROM OLD.deleted_at THEN
+		RETURN NULL;
+	ELSE
+		doc := NEW;
+		change := CASE WHEN NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN 'DocumentDeleted' ELSE 'DocumentUpdated' END;
+	END IF;
+	INSERT INTO %[2]s (stream_id, version, type, data, metadata)
+	SELECT doc.id, COALESCE(max(version), 0) + 1, change, doc.data,
+		jsonb_build_object('version', doc.version, 'schema_version', doc.schema_version)
+	FROM %[2]s WHERE stream_id = doc.id;
+	PERFORM pg_notify('%[3]s', '');
+	RETURN NULL;
+END $$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS whisker_%[1]s_changes ON whisker_%[1]s;
+CREATE TRIGGER whisker_%[1]s_changes AFTER INSERT OR UPDATE OR DELETE ON whisker_%[1]s
+	FOR EACH ROW EXECUTE FUNCTION whisker_%[1]s_changes()`, name, EventsTable(ChangeFeedStore(name)),
+		strings.ReplaceAll(channel, "'", "''"))
+}
+
+// EnsureChangeFeed creates the change feed event store of whisker_{name} and
+// installs the trigger that fills it. The collection table must already
+// exist; its deleted_at and schema_version columns are added if missing.
+func (b *Bootstrap) EnsureChangeFeed(ctx context.Context, exec pg.Executor, name string) error {
+	if err := ValidateCollectionName(name); err != nil {
+		return err
+	}
+	if err := b.EnsureEventStore(ctx, exec, ChangeFeedStore(name)); err != nil {
+		return err
+	}
+	if err := b.EnsureDeletedAt(ctx, exec, name); err != nil {
+		return err
+	}
+	if err := b.EnsureSchemaVersion(ctx, exec, name); err != nil {
+		return err
+	}
+	if !b.autoMigrate {
+		return nil
+	}
+	key := "whisker_" + name + ".changes"
+	return b.ensure(ctx, exec, &b.columns, key, true, func() error {
+		if _, err := exec.Exec(ctx, changeFeedDDL(name, b.NotifyChannel(EventsTable(ChangeFeedStore(name))))); err != nil {
+			return fmt.Errorf("schema: install change feed on whisker_%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+func historyDDL(name string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS whisker_%[1]s_history (
+	seq BIGSERIAL PRIMARY KEY,
+	doc_id TEXT NOT NULL,
+	operation TEXT NOT NULL,
+	version INTEGER,
+	schema_version INTEGER,
+	data JSONB,
+	actor TEXT,
+	tenant_id TEXT,
+	recorded_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_whisker_%[1]s_history_doc_id ON whisker_%[1]s_history (doc_id, seq)`, name)
+}
+
+// historyTriggerDDL installs a trigger on whisker_{name} that records every
+// insert, update and delete in whisker_{name}_history: the operation, the
+// document's state before it (none for an insert), the actor set on the
+// transaction and the document's tenant, if any. Updates are classified as
+// in changeFeedDDL.
+func historyTriggerDDL(name string) string {
+	return fmt.Sprintf(`CREATE OR REPLACE FUNCTION whisker_%[1]s_history() RETURNS trigger AS $$
+DECLARE
+	op TEXT;
+	actor TEXT := NULLIF(current_setting('whisker.actor', true), '');
+BEGIN
+	IF TG_OP = 'INSERT' THEN
+		INSERT INTO whisker_%[1]s_history (doc_id, operation, actor, tenant_id)
+		VALUES (NEW.id, 'insert', actor, to_jsonb(NEW)->>'tenant_id');
+		RETURN NULL;
+	ELSIF TG_OP = 'DELETE' THEN
+		op := 'delete';
+	ELSIF NEW.data IS NOT DISTINCT FROM OLD.data AND NEW.deleted_at IS NOT DISTINCT FROM OLD.deleted_at THEN
+		RETURN NULL;
+	ELSE
+		op := CASE WHEN NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN 'delete' ELSE 'update' END;
+	END IF;
+	INSERT INTO whisker_%[1]s_history (doc_id, operation, version, schema_version, data, actor, tenant_id)
+	VALUES (OLD.id, op, OLD.version, OLD.schema_version, OLD.data, actor, to_jsonb(OLD)->>'tenant_id');
+	RETURN NULL;
+END $$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS whisker_%[1]s_history ON whisker_%[1]s;
+CREATE TRIGGER whisker_%[1]s_history AFTER INSERT OR UPDATE OR DELETE ON whisker_%[1]s
+	FOR EACH ROW EXECUTE FUNCTION whisker_%[1]s_history()`, name)
+}
+
+// EnsureHistory creates the whisker_{name}_history table and installs the
+// trigger that fills it. The collection table must already exist; its
+// deleted_at and schema_version columns are added if missing.
+func (b *Bootstrap) EnsureHistory(ctx context.Context, exec pg.Executor, name string) error {
+	if err := ValidateCollectionName(name); err != nil {
+		return err
+	}
+	if err := b.EnsureDeletedAt(ctx, exec, name); err != nil {
+		return err
+	}
+	if err := b.EnsureSchemaVersion(ctx, exec, name); err != nil {
+		return err
+	}
+	if !b.autoMigrate {
+		return nil
+	}
+	table := "whisker_" + name + "_history"
+	if err := b.ensure(ctx, exec, &b.tables, table, true, func() error {
+		if _, err := exec.Exec(ctx, historyDDL(name)); err != nil {
+			return fmt.Errorf("schema: create table %s: %w", table, err)
+		}
+		return b.applyTablePolicy(ctx, exec, table)
+	}); err != nil {
+		return err
+	}
+	key := "whisker_" + name + ".history"
+	return b.ensure(ctx, exec, &b.columns, key, true, func() error {
+		if _, err := exec.Exec(ctx, historyTriggerDDL(name)); err != nil {
+			return fmt.Errorf("schema: install history on whisker_%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// WatchChannel returns the channel the watch trigger of the named
+// collection signals its changes on.
+func (b *Bootstrap) WatchChannel(name string) string {
+	return b.NotifyChannel("whisker_" + name + "_changed")
+}
+
+// watchDDL installs a trigger on whisker_{name} that signals every insert,
+// update and delete on channel, with the change type, document id and
+// version as a JSON payload. Updates are classified as in changeFeedDDL.
+func watchDDL(name, channel string) string {
+	return fmt.Sprintf(`CREATE OR REPLACE FUNCTION whisker_%[1]s_watch() RETURNS trigger AS $$
+DECLARE
+	doc RECORD;
+	change TEXT;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		doc := OLD;
+		change := 'DocumentDeleted';
+	ELSIF TG_OP = 'INSERT' THEN
+		doc := NEW;
+		change := 'DocumentInserted';
+	ELSIF NEW.data IS NOT DISTINCT FROM OLD.data AND NEW.deleted_at IS NOT DISTINCT FROM OLD.deleted_at THEN
+		RETURN NULL;
+	ELSE
+		doc := NEW;
+		change := CASE WHEN NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN 'DocumentDeleted' ELSE 'DocumentUpdated' END;
+	END IF;
+	PERFORM pg_notify('%[2]s', json_build_object('type', change, 'id', doc.id, 'version', doc.version, 'tenant', to_jsonb(doc)->>'tenant_id')::text);
+	RETURN NULL;
+END $$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS whisker_%[1]s_watch ON whisker_%[1]s;
+CREATE TRIGGER whisker_%[1]s_watch AFTER INSERT OR UPDATE OR DELETE ON whisker_%[1]s
+	FOR EACH ROW EXECUTE FUNCTION whisker_%[1]s_watch()`, name, strings.ReplaceAll(channel, "'", "''"))
+}
+
+// EnsureWatch installs the trigger that signals the changes of whisker_{name}
+// on WatchChannel(name). The collection table must already exist; its
+// deleted_at column is added if missing.
+func (b *Bootstrap) EnsureWatch(ctx context.Context, exec pg.Executor, name string) error {
+	if err := ValidateCollectionName(name); err != nil {
+		return err
+	}
+	if err := b.EnsureDeletedAt(ctx, exec, name); err != nil {
+		return err
+	}
+	if !b.autoMigrate {
+		return nil
+	}
+	key := "whisker_" + name + ".watch"
+	return b.ensure(ctx, exec, &b.columns, key, true, func() error {
+		if _, err := exec.Exec(ctx, watchDDL(name, b.WatchChannel(name))); err != nil {
+			return fmt.Errorf("schema: install watch trigger on whisker_%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
 // EnsureEvents creates the whisker_events table if it doesn't exist.
 func (b *Bootstrap) EnsureEvents(ctx context.Context, exec pg.Executor) error {
-	if _, ok := b.tables.Load("whisker_events"); ok {
-		return nil
+	return b.EnsureEventStore(ctx, exec, "")
+}
+
+// EnsureEventStore creates the table of the named event store if it doesn't
+// exist. See EventsTable.
+func (b *Bootstrap) EnsureEventStore(ctx context.Context, exec pg.Executor, name string) error {
+	return b.ensureEventStore(ctx, exec, name, eventsDDL)
+}
+
+// EnsureBinaryEventStore is EnsureEventStore for a store whose payloads are
+// not JSON: its data column is BYTEA. It fails if the table already exists
+// with a JSONB data column.
+func (b *Bootstrap) EnsureBinaryEventStore(ctx context.Context, exec pg.Executor, name string) error {
+	return b.ensureEventStore(ctx, exec, name, binaryEventsDDL)
+}
+
+func (b *Bootstrap) ensureEventStore(ctx context.Context, exec pg.Executor, name string, ddl func(table string) string) error {
+	if name != "" {
+		if err := ValidateCollectionName(name); err != nil {
+			return err
+		}
 	}
-	_, err := exec.Exec(ctx, eventsDDL())
-	if err != nil {
-		return fmt.Errorf("schema: create events table: %w", err)
+	if !b.autoMigrate {
+		return nil
 	}
-	b.tables.Store("whisker_events", true)
-	return nil
+	table := EventsTable(name)
+	return b.ensure(ctx, exec, &b.tables, table, true, func() error {
+		if _, err := exec.Exec(ctx, ddl(table)); err != nil {
+			return fmt.Errorf("schema: create events table %s: %w", table, err)
+		}
+		return b.applyTablePolicy(ctx, exec, table)
+	})
 }
 
 // EnsureProjectionCheckpoints creates the whisker_projection_checkpoints table
 // if it doesn't exist.
 func (b *Bootstrap) EnsureProjectionCheckpoints(ctx context.Context, exec pg.Executor) error {
-	if _, ok := b.tables.Load("whisker_projection_checkpoints"); ok {
+	if !b.autoMigrate {
 		return nil
 	}
-	_, err := exec.Exec(ctx, projectionCheckpointsDDL())
-	if err != nil {
-		return fmt.Errorf("schema: create projection checkpoints table: %w", err)
-	}
-	b.tables.Store("whisker_projection_checkpoints", true)
-	return nil
+	return b.ensure(ctx, exec, &b.tables, "whisker_projection_checkpoints", true, func() error {
+		if _, err := exec.Exec(ctx, projectionCheckpointsDDL()); err != nil {
+			return fmt.Errorf("schema: create projection checkpoints table: %w", err)
+		}
+		if _, err := exec.Exec(ctx, projectionCheckpointOwnerDDL()); err != nil {
+			return fmt.Errorf("schema: add columns to projection checkpoints: %w", err)
+		}
+		return b.applyTablePolicy(ctx, exec, "whisker_projection_checkpoints")
+	})
 }
 
 // EnsureEventsGlobalPositionIndex creates an index on global_position for
-// ordered reads across all streams. Must be called with a pool-level executor,
-// not a session transaction — CREATE INDEX CONCURRENTLY cannot run inside a
-// transaction block.
+// ordered reads across all streams. CREATE INDEX CONCURRENTLY cannot run
+// inside a transaction block, so with a session executor it does nothing and
+// leaves the index to the next call outside a session.
 func (b *Bootstrap) EnsureEventsGlobalPositionIndex(ctx context.Context, exec pg.Executor) error {
-	const name = "idx_whisker_events_global_position"
-	if _, ok := b.indexes.Load(name); ok {
+	return b.EnsureEventStoreGlobalPositionIndex(ctx, exec, "")
+}
+
+// EnsureEventStoreGlobalPositionIndex is EnsureEventsGlobalPositionIndex for
+// the named event store.
+func (b *Bootstrap) EnsureEventStoreGlobalPositionIndex(ctx context.Context, exec pg.Executor, name string) error {
+	if !b.autoMigrate {
 		return nil
 	}
-	_, err := exec.Exec(ctx,
-		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_whisker_events_global_position ON whisker_events (global_position)`,
-	)
-	if err != nil {
-		return fmt.Errorf("schema: create events global_position index: %w", err)
+	if tx, ok := exec.(pg.Transactional); ok && tx.InTransaction() {
+		return nil
 	}
-	b.indexes.Store(name, true)
-	return nil
+	table := EventsTable(name)
+	index := "idx_" + table + "_global_position"
+	return b.ensure(ctx, exec, &b.indexes, index, true, func() error {
+		_, err := exec.Exec(ctx,
+			fmt.Sprintf(`CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON %s (global_position)`, index, table),
+		)
+		if err != nil {
+			return fmt.Errorf("schema: create %s global_position index: %w", table, err)
+		}
+		return nil
+	})
 }
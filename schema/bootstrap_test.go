@@ -1,6 +1,18 @@
 package schema
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
 
 func TestCollectionDDL(t *testing.T) {
 	ddl := collectionDDL("users")
@@ -9,15 +21,67 @@ func TestCollectionDDL(t *testing.T) {
 	data JSONB NOT NULL,
 	version INTEGER NOT NULL DEFAULT 1,
 	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
-	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	deleted_at TIMESTAMPTZ,
+	schema_version INTEGER NOT NULL DEFAULT 1
 )`
 	if ddl != want {
 		t.Errorf("got:\n%s\nwant:\n%s", ddl, want)
 	}
 }
 
+func TestDeletedAtDDL(t *testing.T) {
+	got := deletedAtDDL("users")
+	want := "ALTER TABLE whisker_users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSchemaVersionDDL(t *testing.T) {
+	got := schemaVersionDDL("users")
+	want := "ALTER TABLE whisker_users ADD COLUMN IF NOT EXISTS schema_version INTEGER NOT NULL DEFAULT 1"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAttachmentsDDL(t *testing.T) {
+	got := attachmentsDDL("users")
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS whisker_users_attachments",
+		"REFERENCES whisker_users (id) ON DELETE CASCADE",
+		"PRIMARY KEY (doc_id, name, chunk)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("DDL missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestTenantDDL(t *testing.T) {
+	got := tenantDDL("users")
+	want := `ALTER TABLE whisker_users ADD COLUMN IF NOT EXISTS tenant_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_whisker_users_tenant_id ON whisker_users (tenant_id)`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRLSDDL(t *testing.T) {
+	got := tableRLSDDL("whisker_users", "data->>'tenantId' = current_setting('whisker.tenant_id', true)")
+	want := `ALTER TABLE whisker_users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE whisker_users FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS whisker_users_rls ON whisker_users;
+CREATE POLICY whisker_users_rls ON whisker_users USING (data->>'tenantId' = current_setting('whisker.tenant_id', true)) WITH CHECK (data->>'tenantId' = current_setting('whisker.tenant_id', true));
+COMMENT ON POLICY whisker_users_rls ON whisker_users IS 'data->>''tenantId'' = current_setting(''whisker.tenant_id'', true)'`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
 func TestEventsDDL(t *testing.T) {
-	ddl := eventsDDL()
+	ddl := eventsDDL("whisker_events")
 	want := `CREATE TABLE IF NOT EXISTS whisker_events (
 	stream_id TEXT NOT NULL,
 	version INTEGER NOT NULL,
@@ -33,13 +97,112 @@ func TestEventsDDL(t *testing.T) {
 	}
 }
 
+func TestBinaryEventsDDL(t *testing.T) {
+	ddl := binaryEventsDDL("whisker_events_telemetry")
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS whisker_events_telemetry (",
+		"data BYTEA NOT NULL,",
+		"table_name = 'whisker_events_telemetry' AND column_name = 'data') <> 'bytea'",
+		"RAISE EXCEPTION 'whisker_events_telemetry exists with a JSONB data column'",
+	} {
+		if !strings.Contains(ddl, want) {
+			t.Errorf("missing %q in:\n%s", want, ddl)
+		}
+	}
+}
+
+func TestChangeFeedDDL(t *testing.T) {
+	if got := ChangeFeedStore("users"); got != "users_changes" {
+		t.Errorf("ChangeFeedStore = %q, want users_changes", got)
+	}
+	ddl := changeFeedDDL("users", "whisker_events_users_changes")
+	for _, want := range []string{
+		"CREATE OR REPLACE FUNCTION whisker_users_changes() RETURNS trigger",
+		"INSERT INTO whisker_events_users_changes (stream_id, version, type, data, metadata)",
+		"FROM whisker_events_users_changes WHERE stream_id = doc.id",
+		"PERFORM pg_notify('whisker_events_users_changes', '')",
+		"DROP TRIGGER IF EXISTS whisker_users_changes ON whisker_users",
+		"AFTER INSERT OR UPDATE OR DELETE ON whisker_users",
+	} {
+		if !strings.Contains(ddl, want) {
+			t.Errorf("missing %q in:\n%s", want, ddl)
+		}
+	}
+
+	ddl = changeFeedDDL("users", "it's.whisker_events_users_changes")
+	if want := "PERFORM pg_notify('it''s.whisker_events_users_changes', '')"; !strings.Contains(ddl, want) {
+		t.Errorf("missing %q in:\n%s", want, ddl)
+	}
+}
+
+func TestTimestamptzFuncDDL(t *testing.T) {
+	ddl := timestamptzFuncDDL()
+	for _, want := range []string{
+		"IF to_regprocedure('whisker_timestamptz(text)') IS NULL THEN",
+		"CREATE FUNCTION whisker_timestamptz(text) RETURNS timestamptz",
+		"AS 'SELECT $1::timestamptz' LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE",
+	} {
+		if !strings.Contains(ddl, want) {
+			t.Errorf("missing %q in:\n%s", want, ddl)
+		}
+	}
+}
+
+func TestWatchDDL(t *testing.T) {
+	if got := New().WatchChannel("users"); got != "whisker_users_changed" {
+		t.Errorf("WatchChannel = %q, want whisker_users_changed", got)
+	}
+	ddl := watchDDL("users", "it's.whisker_users_changed")
+	for _, want := range []string{
+		"CREATE OR REPLACE FUNCTION whisker_users_watch() RETURNS trigger",
+		"PERFORM pg_notify('it''s.whisker_users_changed', json_build_object('type', change, 'id', doc.id, 'version', doc.version, 'tenant', to_jsonb(doc)->>'tenant_id')::text)",
+		"DROP TRIGGER IF EXISTS whisker_users_watch ON whisker_users",
+		"AFTER INSERT OR UPDATE OR DELETE ON whisker_users",
+	} {
+		if !strings.Contains(ddl, want) {
+			t.Errorf("missing %q in:\n%s", want, ddl)
+		}
+	}
+}
+
+func TestHistoryDDL(t *testing.T) {
+	ddl := historyDDL("users")
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS whisker_users_history (",
+		"CREATE INDEX IF NOT EXISTS idx_whisker_users_history_doc_id ON whisker_users_history (doc_id, seq)",
+	} {
+		if !strings.Contains(ddl, want) {
+			t.Errorf("missing %q in:\n%s", want, ddl)
+		}
+	}
+	ddl = historyTriggerDDL("users")
+	for _, want := range []string{
+		"CREATE OR REPLACE FUNCTION whisker_users_history() RETURNS trigger",
+		"actor TEXT := NULLIF(current_setting('whisker.actor', true), '');",
+		"VALUES (NEW.id, 'insert', actor, to_jsonb(NEW)->>'tenant_id');",
+		"VALUES (OLD.id, op, OLD.version, OLD.schema_version, OLD.data, actor, to_jsonb(OLD)->>'tenant_id');",
+		"DROP TRIGGER IF EXISTS whisker_users_history ON whisker_users",
+		"AFTER INSERT OR UPDATE OR DELETE ON whisker_users",
+	} {
+		if !strings.Contains(ddl, want) {
+			t.Errorf("missing %q in:\n%s", want, ddl)
+		}
+	}
+}
+
 func TestProjectionCheckpointsDDL(t *testing.T) {
 	ddl := projectionCheckpointsDDL()
 	want := `CREATE TABLE IF NOT EXISTS whisker_projection_checkpoints (
 	projection_name TEXT PRIMARY KEY,
 	last_position BIGINT NOT NULL DEFAULT 0,
 	status TEXT NOT NULL DEFAULT 'running',
-	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	owner_instance TEXT,
+	owner_host TEXT,
+	owner_acquired_at TIMESTAMPTZ,
+	metadata JSONB,
+	subscriber_version INTEGER,
+	failure JSONB
 )`
 	if ddl != want {
 		t.Errorf("got:\n%s\nwant:\n%s", ddl, want)
@@ -89,3 +252,437 @@ func TestBootstrap_TracksIndexes(t *testing.T) {
 		t.Error("should be created")
 	}
 }
+
+func TestBootstrap_InvalidateTable(t *testing.T) {
+	b := New()
+	b.MarkCreated("whisker_orders")
+	b.MarkColumnCreated("whisker_orders.total")
+	b.MarkIndexCreated("idx_whisker_orders_total")
+	b.MarkIndexCreated("idx_whisker_users_name")
+
+	b.InvalidateTable("whisker_orders")
+	if b.IsCreated("whisker_orders") || b.IsColumnCreated("whisker_orders.total") || b.IsIndexCreated("idx_whisker_orders_total") {
+		t.Error("table, column and index should be forgotten")
+	}
+	if !b.IsIndexCreated("idx_whisker_users_name") {
+		t.Error("other tables' indexes should be kept")
+	}
+}
+
+func TestBootstrap_AutoMigrateDisabled(t *testing.T) {
+	b := New(WithAutoMigrate(false))
+	if b.AutoMigrate() {
+		t.Fatal("expected auto-migrate to be disabled")
+	}
+	// a nil executor would panic if any DDL ran
+	if err := b.EnsureCollection(context.Background(), nil, "users"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := b.EnsureEvents(context.Background(), nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := b.EnsureCollection(context.Background(), nil, "bad name"); err == nil {
+		t.Error("expected names to be validated without DDL")
+	}
+	if !New().AutoMigrate() {
+		t.Error("expected auto-migrate enabled by default")
+	}
+}
+
+// countingExec counts Exec calls, sleeping in each so concurrent callers
+// overlap, and fails while fail is set.
+type countingExec struct {
+	execs atomic.Int32
+	fail  atomic.Bool
+}
+
+func (e *countingExec) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	e.execs.Add(1)
+	time.Sleep(10 * time.Millisecond)
+	if e.fail.Load() {
+		return pgconn.CommandTag{}, errors.New("boom")
+	}
+	return pgconn.CommandTag{}, nil
+}
+
+func (e *countingExec) Query(context.Context, string, ...any) (pgx.Rows, error) { return nil, nil }
+
+func (e *countingExec) QueryRow(context.Context, string, ...any) pgx.Row { return nil }
+
+func TestBootstrap_EnsureSingleFlight(t *testing.T) {
+	var mu sync.Mutex
+	var stats []EnsureStat
+	b := New(WithObserver(func(s EnsureStat) {
+		mu.Lock()
+		defer mu.Unlock()
+		stats = append(stats, s)
+	}))
+	exec := &countingExec{}
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := b.EnsureCollection(context.Background(), exec, "users"); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := exec.execs.Load(); n != 1 {
+		t.Errorf("DDL ran %d times, want 1", n)
+	}
+	if len(stats) != 1 || stats[0].Object != "whisker_users" || stats[0].Duration <= 0 || stats[0].Err != nil {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestBootstrap_EnsureRetriesAfterFailure(t *testing.T) {
+	var observed error
+	b := New(WithObserver(func(s EnsureStat) { observed = s.Err }))
+	exec := &countingExec{}
+	exec.fail.Store(true)
+
+	if err := b.EnsureEvents(context.Background(), exec); err == nil {
+		t.Fatal("expected error")
+	}
+	if observed == nil {
+		t.Error("expected the failure to be observed")
+	}
+	if b.IsCreated("whisker_events") {
+		t.Error("failed DDL should not be cached")
+	}
+
+	exec.fail.Store(false)
+	if err := b.EnsureEvents(context.Background(), exec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.EnsureEvents(context.Background(), exec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := exec.execs.Load(); n != 2 {
+		t.Errorf("DDL ran %d times, want 2", n)
+	}
+}
+
+func TestBootstrap_EnsureIndex(t *testing.T) {
+	b := New()
+	exec := &countingExec{}
+	for range 2 {
+		if err := b.EnsureIndex(context.Background(), exec, "idx_whisker_users_name", "CREATE INDEX ..."); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n := exec.execs.Load(); n != 1 || !b.IsIndexCreated("idx_whisker_users_name") {
+		t.Errorf("DDL ran %d times, want 1 and the index marked created", n)
+	}
+}
+
+func TestBootstrap_EnsureIndexDropsSupersededIndexes(t *testing.T) {
+	b := New()
+	exec := &recordingExec{}
+	for range 2 {
+		err := b.EnsureIndex(context.Background(), exec, "idx_whisker_users_age_numeric", "CREATE INDEX ...", "idx_whisker_users_age")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	want := []string{"CREATE INDEX ...", "DROP INDEX CONCURRENTLY IF EXISTS idx_whisker_users_age"}
+	if !slices.Equal(exec.sql, want) {
+		t.Errorf("got %q, want %q", exec.sql, want)
+	}
+}
+
+type txExec struct{ countingExec }
+
+func (*txExec) InTransaction() bool { return true }
+
+func TestBootstrap_GlobalPositionIndexSkippedInTransaction(t *testing.T) {
+	b := New()
+	exec := &txExec{}
+	if err := b.EnsureEventsGlobalPositionIndex(context.Background(), exec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := exec.execs.Load(); n != 0 {
+		t.Errorf("ran %d statements inside a transaction, want 0", n)
+	}
+	if b.IsIndexCreated("idx_whisker_events_global_position") {
+		t.Error("skipped index should not be cached")
+	}
+}
+
+func TestBootstrap_EnsureEventStore(t *testing.T) {
+	b := New()
+	exec := &countingExec{}
+	ctx := context.Background()
+	if err := b.EnsureEventStore(ctx, exec, "billing"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.EnsureEventStoreGlobalPositionIndex(ctx, exec, "billing"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !b.IsCreated("whisker_events_billing") || !b.IsIndexCreated("idx_whisker_events_billing_global_position") {
+		t.Error("expected the named store's table and index to be marked created")
+	}
+	if b.IsCreated("whisker_events") {
+		t.Error("the default store's table should be untouched")
+	}
+	if err := b.EnsureEventStore(ctx, exec, "bad-name"); err == nil {
+		t.Error("expected an invalid store name to be rejected")
+	}
+}
+
+// recordingExec records the statements run through it.
+type recordingExec struct {
+	countingExec
+	mu  sync.Mutex
+	sql []string
+	// policies holds the table policies reported as installed, by table.
+	policies map[string]string
+	// columns holds the columns reported as present, as table.column.
+	columns map[string]bool
+	// migrated holds the schema_version comments, by table.
+	migrated map[string]string
+}
+
+func (e *recordingExec) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sql = append(e.sql, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+// QueryRow answers hasTablePolicyQuery from policies and hasColumnQuery
+// from columns.
+func (e *recordingExec) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if sql == hasColumnQuery {
+		return boolRow(e.columns[args[0].(string)+"."+args[1].(string)])
+	}
+	if sql == migratedQuery {
+		return stringRow(e.migrated[args[0].(string)])
+	}
+	policy, ok := e.policies[args[0].(string)]
+	return boolRow(ok && sql == hasTablePolicyQuery && policy == args[2].(string))
+}
+
+type boolRow bool
+
+type stringRow string
+
+func (r stringRow) Scan(dest ...any) error {
+	*dest[0].(*string) = string(r)
+	return nil
+}
+
+func (r boolRow) Scan(dest ...any) error {
+	*dest[0].(*bool) = bool(r)
+	return nil
+}
+
+func TestBootstrap_CreatesSchemaFirst(t *testing.T) {
+	b := New(WithSchemaName(`tenant"a`))
+	exec := &recordingExec{}
+	ctx := context.Background()
+	if err := b.EnsureTimestamptzFunc(ctx, exec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.EnsureCollection(ctx, exec, "users"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exec.sql) != 3 || exec.sql[0] != `CREATE SCHEMA IF NOT EXISTS "tenant""a"` {
+		t.Errorf("expected the schema created once, before the other DDL: %q", exec.sql)
+	}
+	if b.SchemaName() != `tenant"a` {
+		t.Errorf("schema name: got %q", b.SchemaName())
+	}
+
+	exec = &recordingExec{}
+	if err := New().EnsureCollection(ctx, exec, "users"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exec.sql) != 1 || strings.Contains(exec.sql[0], "SCHEMA") {
+		t.Errorf("expected no schema DDL without a schema name: %q", exec.sql)
+	}
+}
+
+func TestBootstrap_EnsureExtensionSchema(t *testing.T) {
+	ctx := context.Background()
+	exec := &recordingExec{}
+	if err := New(WithSchemaName("tenant_a")).EnsureExtension(ctx, exec, "pg_trgm"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exec.sql) != 2 || exec.sql[1] != "CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA public" {
+		t.Errorf("expected the extension in public, not the tenant schema: %q", exec.sql)
+	}
+
+	exec = &recordingExec{}
+	if err := New(WithExtensionSchema("extensions")).EnsureExtension(ctx, exec, "pg_trgm"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exec.sql) != 1 || exec.sql[0] != "CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA extensions" {
+		t.Errorf("expected the configured extension schema: %q", exec.sql)
+	}
+
+	if err := New(WithExtensionSchema("bad schema")).EnsureExtension(ctx, &recordingExec{}, "pg_trgm"); err == nil {
+		t.Error("expected an invalid extension schema to be rejected")
+	}
+}
+
+func TestBootstrap_TablePolicy(t *testing.T) {
+	b := New(WithTablePolicy(func(table string) string {
+		if table == "whisker_projection_checkpoints" {
+			return ""
+		}
+		return "pg_has_role('app_tenants', 'MEMBER')"
+	}))
+	exec := &recordingExec{}
+	ctx := context.Background()
+	if err := b.EnsureCollection(ctx, exec, "users"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.EnsureDeletedAt(ctx, exec, "users"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.EnsureProjectionCheckpoints(ctx, exec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// collection table, its policy, the column, then the checkpoints table
+	// and its extra columns without a policy
+	if len(exec.sql) != 5 {
+		t.Fatalf("got %d statements: %q", len(exec.sql), exec.sql)
+	}
+	want := tableRLSDDL("whisker_users", "pg_has_role('app_tenants', 'MEMBER')")
+	if exec.sql[1] != want {
+		t.Errorf("policy:\ngot:  %s\nwant: %s", exec.sql[1], want)
+	}
+	if !strings.Contains(want, "CREATE POLICY whisker_users_rls ON whisker_users USING") {
+		t.Errorf("unexpected policy DDL: %s", want)
+	}
+
+	d := b.Derive()
+	exec = &recordingExec{}
+	if err := d.EnsureCollection(ctx, exec, "users"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exec.sql) != 2 {
+		t.Errorf("derived bootstrap: expected fresh caches and the policy, got %q", exec.sql)
+	}
+	if !strings.Contains(exec.sql[1], "COMMENT ON POLICY whisker_users_rls ON whisker_users IS 'pg_has_role(''app_tenants'', ''MEMBER'')'") {
+		t.Errorf("expected the expression recorded in the policy's comment: %s", exec.sql[1])
+	}
+
+	// a table that already has the policy, after a restart, is left alone
+	exec = &recordingExec{policies: map[string]string{"whisker_users": "pg_has_role('app_tenants', 'MEMBER')"}}
+	if err := b.Derive().EnsureCollection(ctx, exec, "users"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exec.sql) != 1 {
+		t.Errorf("installed policy: expected only the table DDL, got %q", exec.sql)
+	}
+	exec = &recordingExec{policies: map[string]string{"whisker_users": "true"}}
+	if err := b.Derive().EnsureCollection(ctx, exec, "users"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exec.sql) != 2 {
+		t.Errorf("changed policy: expected it reinstalled, got %q", exec.sql)
+	}
+}
+
+func TestBootstrap_EnsureRLS(t *testing.T) {
+	ctx := context.Background()
+	b := New()
+	exec := &recordingExec{}
+	for _, policy := range []string{"a = 1", "a = 1", "a = 2", "a = 1"} {
+		if err := b.EnsureRLS(ctx, exec, "users", policy); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	want := []string{
+		tableRLSDDL("whisker_users", "a = 1"),
+		tableRLSDDL("whisker_users", "a = 2"),
+		tableRLSDDL("whisker_users", "a = 1"),
+	}
+	if len(exec.sql) != len(want) {
+		t.Fatalf("expected each change of expression installed, got %q", exec.sql)
+	}
+	for i := range want {
+		if exec.sql[i] != want[i] {
+			t.Errorf("statement %d: got %s, want %s", i, exec.sql[i], want[i])
+		}
+	}
+	if _, ok := b.columns.Load("whisker_users.rls"); ok {
+		t.Error("policies should not be cached with the columns")
+	}
+
+	// another process finds the policy installed
+	exec = &recordingExec{policies: map[string]string{"whisker_users": "a = 1"}}
+	if err := New().EnsureRLS(ctx, exec, "users", "a = 1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exec.sql) != 0 {
+		t.Errorf("installed policy: expected no DDL, got %q", exec.sql)
+	}
+}
+
+func TestBootstrap_EnsureDeletedAtOnlyAltersLegacyTables(t *testing.T) {
+	ctx := context.Background()
+	exec := &recordingExec{columns: map[string]bool{"whisker_users.deleted_at": true}}
+	if err := New().EnsureDeletedAt(ctx, exec, "users"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exec.sql) != 0 {
+		t.Errorf("table with the column: expected no DDL, got %q", exec.sql)
+	}
+
+	exec = &recordingExec{}
+	if err := New().EnsureDeletedAt(ctx, exec, "users"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exec.sql) != 1 || exec.sql[0] != deletedAtDDL("users") {
+		t.Errorf("legacy table: expected the column added, got %q", exec.sql)
+	}
+}
+
+func TestBootstrap_EnsureMigrated(t *testing.T) {
+	ctx := context.Background()
+	runs := 0
+	migrate := func() error { runs++; return nil }
+
+	exec := &recordingExec{}
+	b := New()
+	for range 2 {
+		if err := b.EnsureMigrated(ctx, exec, "people", 2, migrate); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if runs != 1 || len(exec.sql) != 1 || exec.sql[0] != "COMMENT ON COLUMN whisker_people.schema_version IS '2'" {
+		t.Errorf("first use: got %d runs and %q", runs, exec.sql)
+	}
+
+	// another process finds the version recorded
+	exec = &recordingExec{migrated: map[string]string{"whisker_people": "2"}}
+	if err := New().EnsureMigrated(ctx, exec, "people", 2, migrate); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runs != 1 || len(exec.sql) != 0 {
+		t.Errorf("recorded version: got %d runs and %q", runs, exec.sql)
+	}
+
+	// a newer migration runs again
+	if err := New().EnsureMigrated(ctx, exec, "people", 3, migrate); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runs != 2 {
+		t.Errorf("newer version: got %d runs, want 2", runs)
+	}
+
+	failing := func() error { return errors.New("boom") }
+	exec = &recordingExec{}
+	if err := New().EnsureMigrated(ctx, exec, "people", 2, failing); err == nil || len(exec.sql) != 0 {
+		t.Errorf("failed migration: got %v and %q, want an error and no comment", err, exec.sql)
+	}
+}
@@ -0,0 +1,61 @@
+package schema
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// maxChannelLen is the longest NOTIFY channel PostgreSQL accepts: an
+// identifier of NAMEDATALEN-1 bytes.
+const maxChannelLen = 63
+
+// WithNotifyNamespace qualifies the NOTIFY channels of event stores with ns,
+// usually the schema Whisker's tables live in, so that apps or tenants
+// sharing a database don't wake each other's pollers. Empty keeps the
+// unqualified channels.
+func WithNotifyNamespace(ns string) Option {
+	return func(b *Bootstrap) { b.notifyNamespace = ns }
+}
+
+// NotifyNamespace returns the namespace set with WithNotifyNamespace.
+func (b *Bootstrap) NotifyNamespace() string {
+	return b.notifyNamespace
+}
+
+// NotifyChannel returns the channel appends to the events table are
+// signalled on: {namespace}.{table}, or table alone without a namespace.
+// Names longer than PostgreSQL allows are shortened with a hash of the full
+// name, so every process derives the same channel.
+func (b *Bootstrap) NotifyChannel(table string) string {
+	return notifyChannel(b.notifyNamespace, table)
+}
+
+func notifyChannel(ns, table string) string {
+	channel := table
+	if ns != "" {
+		channel = ns + "." + table
+	}
+	if len(channel) <= maxChannelLen {
+		return channel
+	}
+	h := fnv.New32a()
+	h.Write([]byte(channel))
+	return fmt.Sprintf("%s_%08x", channel[:maxChannelLen-9], h.Sum32())
+}
+
+// NamespaceFromSearchPath returns the first schema of a search_path setting,
+// unquoted, skipping "$user", whose schema depends on the connecting role.
+// It returns "" when there is none.
+func NamespaceFromSearchPath(path string) string {
+	for _, s := range strings.Split(path, ",") {
+		s = strings.TrimSpace(s)
+		if strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) && len(s) >= 2 {
+			s = strings.ReplaceAll(s[1:len(s)-1], `""`, `"`)
+		}
+		if s != "" && s != "$user" {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,49 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNotifyChannel(t *testing.T) {
+	if got := New().NotifyChannel("whisker_events"); got != "whisker_events" {
+		t.Errorf("without namespace: got %q, want whisker_events", got)
+	}
+	b := New(WithNotifyNamespace("billing"))
+	if got := b.NotifyChannel("whisker_events_orders"); got != "billing.whisker_events_orders" {
+		t.Errorf("got %q, want billing.whisker_events_orders", got)
+	}
+
+	ns := strings.Repeat("tenant", 8)
+	a := New(WithNotifyNamespace(ns)).NotifyChannel("whisker_events_a")
+	c := New(WithNotifyNamespace(ns)).NotifyChannel("whisker_events_b")
+	if len(a) > maxChannelLen || len(c) > maxChannelLen {
+		t.Errorf("channels exceed %d bytes: %q, %q", maxChannelLen, a, c)
+	}
+	if a == c {
+		t.Errorf("shortened channels collide: %q", a)
+	}
+	if a != New(WithNotifyNamespace(ns)).NotifyChannel("whisker_events_a") {
+		t.Error("shortened channel is not deterministic")
+	}
+}
+
+func TestNamespaceFromSearchPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"", ""},
+		{"app", "app"},
+		{"app, public", "app"},
+		{`"$user", public`, "public"},
+		{"$user", ""},
+		{`"Billing App", public`, "Billing App"},
+		{`"say ""hi"""`, `say "hi"`},
+	}
+	for _, tt := range tests {
+		if got := NamespaceFromSearchPath(tt.path); got != tt.want {
+			t.Errorf("NamespaceFromSearchPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package schema
+
+import (
+	"hash/fnv"
+	"strconv"
+)
+
+// ShardOf maps key, typically a stream or document ID, to one of shards
+// shards with jump consistent hashing: the same key always lands on the same
+// shard, and growing from n to n+1 shards moves only about 1/(n+1) of the
+// keys, all of them to the new shard. It returns 0 when shards is 1 or less.
+func ShardOf(key string, shards int) int {
+	if shards <= 1 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	k := h.Sum64()
+
+	// Lamping and Veach, "A Fast, Minimal Memory, Consistent Hash Algorithm"
+	b, j := int64(-1), int64(0)
+	for j < int64(shards) {
+		b = j
+		k = k*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((k>>33)+1)))
+	}
+	return int(b)
+}
+
+// ShardName returns the collection name of one shard of a sharded
+// collection: name_{shard}, stored in whisker_{name}_{shard}.
+func ShardName(name string, shard int) string {
+	return name + "_" + strconv.Itoa(shard)
+}
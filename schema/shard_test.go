@@ -0,0 +1,61 @@
+package schema
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestShardOf_StableAndInRange(t *testing.T) {
+	for i := range 1000 {
+		key := fmt.Sprintf("order-%d", i)
+		s := ShardOf(key, 16)
+		if s < 0 || s >= 16 {
+			t.Fatalf("ShardOf(%q, 16) = %d", key, s)
+		}
+		if again := ShardOf(key, 16); again != s {
+			t.Fatalf("ShardOf(%q) not stable: %d then %d", key, s, again)
+		}
+	}
+	if s := ShardOf("order-1", 1); s != 0 {
+		t.Errorf("one shard: got %d", s)
+	}
+	if s := ShardOf("order-1", 0); s != 0 {
+		t.Errorf("zero shards: got %d", s)
+	}
+}
+
+func TestShardOf_Spread(t *testing.T) {
+	counts := make([]int, 8)
+	for i := range 8000 {
+		counts[ShardOf(fmt.Sprintf("stream-%d", i), 8)]++
+	}
+	for shard, n := range counts {
+		if n < 800 || n > 1200 {
+			t.Errorf("shard %d got %d of 8000 keys", shard, n)
+		}
+	}
+}
+
+func TestShardOf_GrowingMovesKeysToNewShardOnly(t *testing.T) {
+	moved := 0
+	for i := range 4000 {
+		key := fmt.Sprintf("stream-%d", i)
+		before, after := ShardOf(key, 4), ShardOf(key, 5)
+		if before == after {
+			continue
+		}
+		if after != 4 {
+			t.Fatalf("%s moved from %d to %d, not to the new shard", key, before, after)
+		}
+		moved++
+	}
+	if moved < 600 || moved > 1000 {
+		t.Errorf("moved %d of 4000 keys, want about 800", moved)
+	}
+}
+
+func TestShardName(t *testing.T) {
+	if got := ShardName("orders", 3); got != "orders_3" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -15,33 +15,128 @@ import (
 // appends. Call Commit to persist all changes atomically, or Close/Rollback to
 // discard them.
 type Session struct {
-	tx     pgx.Tx
-	be     backend
-	closed bool
+	tx      pgx.Tx
+	be      backend
+	closed  bool
+	release func()
 }
 
-// Session begins a new transaction and returns a Session.
-func (s *Store) Session(ctx context.Context) (*Session, error) {
-	tx, err := s.pool.Begin(ctx)
+// Tx is a Backend whose operations run in one transaction until Commit,
+// Rollback or Close. Store.Session returns a *Session as a Tx, so code that
+// begins sessions through an interface, such as projections.Store, can be
+// given a fake.
+type Tx interface {
+	Backend
+	// Commit persists the transaction's operations atomically.
+	Commit(ctx context.Context) error
+	// Rollback discards them. It is safe to call more than once.
+	Rollback(ctx context.Context) error
+	// Close rolls back unless the transaction was committed. Safe to defer.
+	Close(ctx context.Context) error
+}
+
+var _ Tx = (*Session)(nil)
+
+// IsolationLevel is a PostgreSQL transaction isolation level.
+type IsolationLevel string
+
+const (
+	ReadCommitted  IsolationLevel = IsolationLevel(pgx.ReadCommitted)
+	RepeatableRead IsolationLevel = IsolationLevel(pgx.RepeatableRead)
+	Serializable   IsolationLevel = IsolationLevel(pgx.Serializable)
+)
+
+// SessionOption configures a Session when it begins.
+type SessionOption func(*sessionConfig)
+
+type sessionConfig struct {
+	txOptions pgx.TxOptions
+}
+
+// WithReadOnly begins the session READ ONLY, so PostgreSQL rejects any write
+// in it. Schema is never created through the session: collections and
+// streams must already exist.
+func WithReadOnly() SessionOption {
+	return func(cfg *sessionConfig) {
+		cfg.txOptions.AccessMode = pgx.ReadOnly
+	}
+}
+
+// WithIsolation sets the transaction isolation level. The default is the
+// server default, normally ReadCommitted, where each statement sees the data
+// committed before it started. Under RepeatableRead and Serializable every
+// Load, Query and ReadStream in the session sees one snapshot, taken at the
+// session's first statement.
+func WithIsolation(level IsolationLevel) SessionOption {
+	return func(cfg *sessionConfig) {
+		cfg.txOptions.IsoLevel = pgx.TxIsoLevel(level)
+	}
+}
+
+// Session begins a new transaction and returns it as a *Session. As for any
+// statement, a tenant carried by ctx (see WithTenant) sets TenantSetting so
+// row-level security policies apply, and an actor (see WithActor) sets
+// ActorSetting for document history.
+func (s *Store) Session(ctx context.Context, opts ...SessionOption) (Tx, error) {
+	sess, err := s.begin(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// begin is Session returning the *Session itself.
+func (s *Store) begin(ctx context.Context, opts ...SessionOption) (*Session, error) {
+	var cfg sessionConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if err := s.lc.acquire(); err != nil {
+		return nil, fmt.Errorf("whisker: begin session: %w", err)
+	}
+	tx, err := s.pool.BeginTx(ctx, cfg.txOptions)
 	if err != nil {
+		s.lc.release()
 		return nil, fmt.Errorf("whisker: begin session: %w", err)
 	}
 
+	sch := s.be.schema.Derive()
+	if cfg.txOptions.AccessMode == pgx.ReadOnly {
+		// DDL fails in a read-only transaction, even when it would do nothing
+		schema.WithAutoMigrate(false)(sch)
+	}
+
 	return &Session{
-		tx: tx,
+		tx:      tx,
+		release: s.lc.release,
 		be: backend{
-			exec:         txExecutor{tx},
+			exec:         txExecutor{tx: tx, gated: s.quiesce},
 			codec:        s.be.codec,
-			schema:       schema.New(),
+			schema:       sch,
 			maxBatchSize: s.be.maxBatchSize,
+			clock:        s.be.clock,
 		},
 	}, nil
 }
 
+// ConsistentRead runs fn in a REPEATABLE READ, READ ONLY session so that
+// reads across collections and event streams observe a single snapshot of
+// the database. Writes in fn fail, and the collections and streams it reads
+// must already exist. The session is always rolled back.
+func (s *Store) ConsistentRead(ctx context.Context, fn func(*Session) error) error {
+	sess, err := s.begin(ctx, WithIsolation(RepeatableRead), WithReadOnly())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = sess.Close(ctx) }()
+	return fn(sess)
+}
+
 func (s *Session) DBExecutor() pg.Executor            { return s.be.exec }
 func (s *Session) JSONCodec() codecs.Codec            { return s.be.codec }
 func (s *Session) SchemaBootstrap() *schema.Bootstrap { return s.be.schema }
 func (s *Session) MaxBatchSize() int                  { return s.be.maxBatchSize }
+func (s *Session) Clock() Clock                       { return s.be.clock }
 
 // Commit persists all operations in this session atomically.
 func (s *Session) Commit(ctx context.Context) error {
@@ -49,6 +144,7 @@ func (s *Session) Commit(ctx context.Context) error {
 		return fmt.Errorf("whisker: session already closed")
 	}
 	s.closed = true
+	defer s.release()
 	if err := s.tx.Commit(ctx); err != nil {
 		return fmt.Errorf("whisker: commit session: %w", err)
 	}
@@ -61,6 +157,7 @@ func (s *Session) Rollback(ctx context.Context) error {
 		return nil
 	}
 	s.closed = true
+	defer s.release()
 	if err := s.tx.Rollback(ctx); err != nil {
 		return fmt.Errorf("whisker: rollback session: %w", err)
 	}
@@ -76,7 +173,8 @@ func (s *Session) Close(ctx context.Context) error {
 }
 
 type txExecutor struct {
-	tx pgx.Tx
+	tx    pgx.Tx
+	gated bool
 }
 
 func (t txExecutor) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
@@ -91,4 +189,17 @@ func (t txExecutor) QueryRow(ctx context.Context, sql string, args ...any) pgx.R
 	return t.tx.QueryRow(ctx, sql, args...)
 }
 
+func (t txExecutor) CopyFrom(ctx context.Context, table pgx.Identifier, columns []string, rows pgx.CopyFromSource) (int64, error) {
+	return t.tx.CopyFrom(ctx, table, columns, rows)
+}
+
 func (t txExecutor) InTransaction() bool { return true }
+
+// CheckWrite takes the shared maintenance lock for the rest of the
+// transaction, so Quiesce waits for the session to finish.
+func (t txExecutor) CheckWrite(ctx context.Context) error {
+	if !t.gated {
+		return nil
+	}
+	return checkWritable(ctx, t)
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/ripkitten-co/whisker"
 	"github.com/ripkitten-co/whisker/documents"
@@ -111,3 +112,92 @@ func TestSession_CommitEmpty(t *testing.T) {
 		t.Errorf("commit empty session: %v", err)
 	}
 }
+
+func TestStore_ConsistentRead(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	orders := documents.Collection[Order](store, "orders")
+	if err := orders.Insert(ctx, &Order{ID: "o1", Item: "widget"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	err := store.ConsistentRead(ctx, func(sess *whisker.Session) error {
+		inSess := documents.Collection[Order](sess, "orders")
+		before, err := inSess.Count(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := orders.Insert(ctx, &Order{ID: "o2", Item: "gadget"}); err != nil {
+			return err
+		}
+
+		after, err := inSess.Count(ctx)
+		if err != nil {
+			return err
+		}
+		if before != 1 || after != 1 {
+			t.Errorf("counts inside snapshot: before=%d after=%d, want 1 and 1", before, after)
+		}
+		if _, err := inSess.Load(ctx, "o2"); !errors.Is(err, whisker.ErrNotFound) {
+			t.Errorf("load o2 inside snapshot: got %v, want ErrNotFound", err)
+		}
+		if err := inSess.Insert(ctx, &Order{ID: "o3", Item: "gizmo"}); err == nil {
+			t.Error("expected error writing inside a consistent read")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("consistent read: %v", err)
+	}
+
+	if n, _ := orders.Count(ctx); n != 2 {
+		t.Errorf("count after snapshot: got %d, want 2", n)
+	}
+}
+
+func TestStore_WithClock(t *testing.T) {
+	connStr := testutil.SetupPostgres(t)
+	ctx := context.Background()
+	frozen := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	store, err := whisker.New(ctx, connStr, whisker.WithClock(whisker.FixedClock(frozen)))
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	t.Cleanup(store.Close)
+
+	orders := documents.Collection[Order](store, "orders")
+	order := &Order{ID: "o1", Item: "widget"}
+	if err := orders.Insert(ctx, order); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	order.Item = "gadget"
+	if err := orders.Update(ctx, order); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	var created, updated time.Time
+	err = store.DBExecutor().QueryRow(ctx,
+		`SELECT created_at, updated_at FROM whisker_orders WHERE id = 'o1'`,
+	).Scan(&created, &updated)
+	if err != nil {
+		t.Fatalf("read timestamps: %v", err)
+	}
+	if !created.Equal(frozen) || !updated.Equal(frozen) {
+		t.Errorf("document timestamps: created %v updated %v, want %v", created, updated, frozen)
+	}
+
+	es := events.New(store)
+	if err := es.Append(ctx, "order-o1", 0, []events.Event{{Type: "OrderCreated", Data: []byte(`{}`)}}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	evts, err := es.ReadStream(ctx, "order-o1", 0)
+	if err != nil {
+		t.Fatalf("read stream: %v", err)
+	}
+	if len(evts) != 1 || !evts[0].CreatedAt.Equal(frozen) {
+		t.Errorf("event created_at: got %+v, want %v", evts, frozen)
+	}
+}
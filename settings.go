@@ -0,0 +1,58 @@
+package whisker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ripkitten-co/whisker/internal/ident"
+)
+
+// connSettingsKey is the key of a connection's CustomData under which
+// prepareConn records the settings it last applied.
+const connSettingsKey = "whisker.settings"
+
+// connSettings are the settings a connection carries for the context it was
+// acquired with.
+type connSettings struct {
+	tenant string
+	actor  string
+}
+
+func settingsFrom(ctx context.Context) connSettings {
+	tenant, _ := TenantFrom(ctx)
+	actor, _ := ActorFrom(ctx)
+	return connSettings{tenant: tenant, actor: actor}
+}
+
+// sql returns the statement applying s to a connection. Empty values are
+// reset, which leaves the setting unset or empty.
+func (s connSettings) sql() string {
+	return settingSQL(TenantSetting, s.tenant) + ";\n" + settingSQL(ActorSetting, s.actor)
+}
+
+func settingSQL(name, value string) string {
+	if value == "" {
+		return "RESET " + name
+	}
+	return "SET " + name + " = " + ident.Literal(value)
+}
+
+// prepareConn sets TenantSetting and ActorSetting on a pooled connection to
+// the tenant and actor of the context it is acquired with, before every
+// statement run outside a session as well as in one, so row-level security
+// policies and document history see them. Connections remember what they
+// were last set to, so only a change costs a round trip.
+func prepareConn(ctx context.Context, conn *pgx.Conn) (bool, error) {
+	want := settingsFrom(ctx)
+	data := conn.PgConn().CustomData()
+	if have, _ := data[connSettingsKey].(connSettings); have == want {
+		return true, nil
+	}
+	if _, err := conn.Exec(ctx, want.sql()); err != nil {
+		// what the connection is set to is unknown, so discard it
+		return false, fmt.Errorf("whisker: apply session settings: %w", err)
+	}
+	data[connSettingsKey] = want
+	return true, nil
+}
@@ -0,0 +1,40 @@
+//go:build integration
+
+package whisker_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/internal/testutil"
+)
+
+func TestStore_SetsTenantOutsideSessions(t *testing.T) {
+	// one connection, so every statement reuses it
+	store, err := whisker.New(context.Background(), testutil.SetupPostgres(t), whisker.WithPoolSize(1, 1))
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	ctx := context.Background()
+
+	tenant := func(ctx context.Context) *string {
+		t.Helper()
+		var got *string
+		if err := store.DBExecutor().QueryRow(ctx, "SELECT current_setting($1, true)", whisker.TenantSetting).Scan(&got); err != nil {
+			t.Fatalf("read setting: %v", err)
+		}
+		return got
+	}
+
+	if got := tenant(whisker.WithTenant(ctx, "acme")); got == nil || *got != "acme" {
+		t.Errorf("with a tenant: got %v, want acme", got)
+	}
+	if got := tenant(whisker.WithTenant(ctx, "it's")); got == nil || *got != "it's" {
+		t.Errorf("quoted tenant: got %v, want it's", got)
+	}
+	if got := tenant(ctx); got != nil && *got != "" {
+		t.Errorf("without a tenant: got %q, want it reset", *got)
+	}
+}
@@ -0,0 +1,20 @@
+package whisker
+
+import (
+	"context"
+	"testing"
+)
+
+func TestConnSettingsSQL(t *testing.T) {
+	if got, want := settingsFrom(context.Background()).sql(), "RESET whisker.tenant_id;\nRESET whisker.actor"; got != want {
+		t.Errorf("no tenant or actor: got %q, want %q", got, want)
+	}
+	got := settingsFrom(WithTenant(context.Background(), "it's")).sql()
+	if want := "SET whisker.tenant_id = 'it''s';\nRESET whisker.actor"; got != want {
+		t.Errorf("tenant: got %q, want %q", got, want)
+	}
+	got = settingsFrom(WithActor(context.Background(), "admin@example.com")).sql()
+	if want := "RESET whisker.tenant_id;\nSET whisker.actor = 'admin@example.com'"; got != want {
+		t.Errorf("actor: got %q, want %q", got, want)
+	}
+}
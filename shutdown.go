@@ -0,0 +1,102 @@
+package whisker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// lifecycle tracks the sessions, advisory locks and listeners holding pool
+// connections so that shutdown can drain them before closing the pool.
+type lifecycle struct {
+	mu      sync.Mutex
+	closing bool
+	active  int
+	drained chan struct{}
+
+	// ctx is cancelled when shutdown begins, which interrupts listeners.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newLifecycle() *lifecycle {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &lifecycle{drained: make(chan struct{}), ctx: ctx, cancel: cancel}
+}
+
+// acquire registers an operation. It fails once shutdown has begun.
+func (l *lifecycle) acquire() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closing {
+		return ErrStoreClosed
+	}
+	l.active++
+	return nil
+}
+
+// release marks an operation registered by acquire as finished.
+func (l *lifecycle) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	if l.closing && l.active == 0 {
+		close(l.drained)
+	}
+}
+
+// begin starts shutdown. It reports false if shutdown had already begun.
+func (l *lifecycle) begin() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closing {
+		return false
+	}
+	l.closing = true
+	l.cancel()
+	if l.active == 0 {
+		close(l.drained)
+	}
+	return true
+}
+
+func (l *lifecycle) activeCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active
+}
+
+// Shutdown closes the store in order: new sessions, advisory locks and
+// listeners are refused with ErrStoreClosed, listeners blocked in
+// WaitForNotification return immediately, and Shutdown waits for open
+// sessions to commit or roll back and held locks to be released before it
+// closes the pool. If ctx ends first, Shutdown returns the context error; the
+// pool is then closed as soon as the remaining operations finish. Safe to call
+// more than once.
+func (s *Store) Shutdown(ctx context.Context) error {
+	if s.lc.begin() {
+		go func() {
+			<-s.lc.drained
+			s.pool.Close()
+			close(s.closed)
+		}()
+	}
+
+	select {
+	case <-s.closed:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("whisker: shutdown: %d sessions or locks still active: %w", s.lc.activeCount(), ctx.Err())
+	}
+}
+
+// Close shuts the store down, waiting up to the configured shutdown timeout
+// (see WithShutdownTimeout) for open sessions and locks. A timeout is logged
+// rather than returned; use Shutdown to handle it.
+func (s *Store) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		s.logger.Error("close store", "error", err)
+	}
+}
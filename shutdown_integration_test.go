@@ -0,0 +1,112 @@
+//go:build integration
+
+package whisker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ripkitten-co/whisker"
+)
+
+func TestStore_ShutdownWaitsForSessions(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	sess, err := store.Session(ctx)
+	if err != nil {
+		t.Fatalf("session: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- store.Shutdown(ctx) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown returned with an open session: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if _, err := store.Session(ctx); !errors.Is(err, whisker.ErrStoreClosed) {
+		t.Errorf("new session during shutdown: got %v, want ErrStoreClosed", err)
+	}
+
+	if err := sess.Commit(ctx); err != nil {
+		t.Fatalf("commit during shutdown: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("shutdown: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not finish after the session committed")
+	}
+}
+
+func TestStore_ShutdownTimesOut(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	sess, err := store.Session(ctx)
+	if err != nil {
+		t.Fatalf("session: %v", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := store.Shutdown(shutdownCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+
+	if err := sess.Rollback(ctx); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if err := store.Shutdown(ctx); err != nil {
+		t.Fatalf("second shutdown: %v", err)
+	}
+}
+
+func TestStore_ShutdownInterruptsListenersAndReleasesLocks(t *testing.T) {
+	store := setupStore(t)
+	ctx := context.Background()
+
+	unlock, acquired, err := store.TryAdvisoryLock(ctx, 42)
+	if err != nil || !acquired {
+		t.Fatalf("lock: acquired=%v err=%v", acquired, err)
+	}
+
+	listening := make(chan error, 1)
+	go func() { listening <- store.WaitForNotification(ctx, "whisker_events") }()
+	time.Sleep(100 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() { done <- store.Shutdown(ctx) }()
+
+	select {
+	case err := <-listening:
+		if !errors.Is(err, whisker.ErrStoreClosed) {
+			t.Errorf("listener: got %v, want ErrStoreClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener was not interrupted by shutdown")
+	}
+
+	if _, _, err := store.TryAdvisoryLock(ctx, 43); !errors.Is(err, whisker.ErrStoreClosed) {
+		t.Errorf("lock during shutdown: got %v, want ErrStoreClosed", err)
+	}
+
+	if err := unlock(ctx); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("shutdown: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not finish after the lock was released")
+	}
+}
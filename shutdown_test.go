@@ -0,0 +1,59 @@
+package whisker
+
+import (
+	"errors"
+	"testing"
+)
+
+func drained(l *lifecycle) bool {
+	select {
+	case <-l.drained:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestLifecycle_DrainsAfterLastRelease(t *testing.T) {
+	l := newLifecycle()
+	if err := l.acquire(); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if err := l.acquire(); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	if !l.begin() {
+		t.Fatal("begin reported shutdown already started")
+	}
+	if l.ctx.Err() == nil {
+		t.Error("begin should cancel the lifecycle context")
+	}
+	if drained(l) {
+		t.Fatal("drained with operations still active")
+	}
+
+	l.release()
+	if drained(l) {
+		t.Fatal("drained with one operation still active")
+	}
+	l.release()
+	if !drained(l) {
+		t.Fatal("not drained after last release")
+	}
+}
+
+func TestLifecycle_RefusesAfterBegin(t *testing.T) {
+	l := newLifecycle()
+	l.begin()
+
+	if !drained(l) {
+		t.Error("idle lifecycle should drain immediately")
+	}
+	if err := l.acquire(); !errors.Is(err, ErrStoreClosed) {
+		t.Errorf("got %v, want ErrStoreClosed", err)
+	}
+	if l.begin() {
+		t.Error("second begin should report shutdown already started")
+	}
+}
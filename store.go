@@ -2,7 +2,11 @@ package whisker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ripkitten-co/whisker/internal/codecs"
@@ -13,8 +17,16 @@ import (
 // Store is the main entry point for Whisker. It holds a PostgreSQL connection
 // pool and provides access to document collections, event streams, and sessions.
 type Store struct {
-	pool *pg.Pool
-	be   backend
+	pool            *pg.Pool
+	be              backend
+	logger          *slog.Logger
+	lc              *lifecycle
+	closed          chan struct{}
+	shutdownTimeout time.Duration
+	quiesce         bool
+
+	quiesceMu   sync.Mutex
+	quiesceConn *pgxpool.Conn
 }
 
 // New connects to PostgreSQL and returns a configured Store.
@@ -24,26 +36,102 @@ func New(ctx context.Context, connString string, opts ...Option) (*Store, error)
 		o(cfg)
 	}
 
-	pool, err := pg.NewPool(ctx, connString)
+	poolCfg, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("whisker: parse connection string: %w", err)
+	}
+	if cfg.MaxConns > 0 {
+		poolCfg.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolCfg.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	for name, value := range cfg.runtimeParams() {
+		poolCfg.ConnConfig.RuntimeParams[name] = value
+	}
+	poolCfg.PrepareConn = prepareConn
+	if cfg.QueryObserver != nil {
+		poolCfg.ConnConfig.Tracer = queryTracer{observe: cfg.QueryObserver}
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	pool, err := pg.NewPoolFromConfig(ctx, poolCfg)
 	if err != nil {
 		return nil, fmt.Errorf("whisker: %w", err)
 	}
+	pool.SetRetries(cfg.MaxRetries, cfg.RetryBackoff)
+
+	// qualify NOTIFY channels by the schema the tables land in, so stores
+	// sharing a database don't wake each other's pollers
+	notifyNamespace := cfg.NotifyNamespace
+	if notifyNamespace == "" {
+		notifyNamespace = schema.NamespaceFromSearchPath(poolCfg.ConnConfig.RuntimeParams["search_path"])
+	}
+	sch := schema.New(
+		schema.WithAutoMigrate(!cfg.DisableAutoMigrate),
+		schema.WithObserver(cfg.EnsureObserver),
+		schema.WithNotifyNamespace(notifyNamespace),
+		schema.WithSchemaName(cfg.Schema),
+		schema.WithExtensionSchema(cfg.ExtensionSchema),
+		schema.WithTablePolicy(cfg.TablePolicy),
+	)
+
+	var exec pg.Executor = pool
+	if cfg.EnableQuiesce {
+		exec = gatedPool{pool}
+	}
 
 	s := &Store{
-		pool: pool,
+		pool:            pool,
+		logger:          logger,
+		lc:              newLifecycle(),
+		closed:          make(chan struct{}),
+		shutdownTimeout: cfg.ShutdownTimeout,
+		quiesce:         cfg.EnableQuiesce,
 		be: backend{
-			exec:         pool,
-			codec:        codecs.NewWhisker(cfg.codec),
-			schema:       schema.New(),
-			maxBatchSize: cfg.maxBatchSize,
+			exec:         exec,
+			codec:        codecs.NewWhisker(cfg.Codec),
+			schema:       sch,
+			maxBatchSize: cfg.MaxBatchSize,
+			clock:        cfg.Clock,
 		},
 	}
 	return s, nil
 }
 
-// Close shuts down the connection pool.
-func (s *Store) Close() {
-	s.pool.Close()
+// Warmer is implemented by collections and stores whose schema can be
+// created ahead of first use.
+type Warmer interface {
+	Warm(ctx context.Context) error
+}
+
+// Warm creates the schema of every target up front, so the first requests
+// after startup don't pay for DDL. Pass the collections and event store the
+// application uses:
+//
+//	err := store.Warm(ctx, documents.Collection[User](store, "users"), events.New(store))
+//
+// Every target is warmed even if one fails; the failures are joined.
+func (s *Store) Warm(ctx context.Context, targets ...Warmer) error {
+	var errs []error
+	for _, t := range targets {
+		if err := t.Warm(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("whisker: warm: %w", err)
+	}
+	return nil
 }
 
 // DBExecutor returns the underlying database executor.
@@ -58,5 +146,11 @@ func (s *Store) SchemaBootstrap() *schema.Bootstrap { return s.be.schema }
 // MaxBatchSize returns the maximum number of documents per batch operation.
 func (s *Store) MaxBatchSize() int { return s.be.maxBatchSize }
 
+// Clock returns the configured clock, or nil when database time is used.
+func (s *Store) Clock() Clock { return s.be.clock }
+
+// Logger returns the logger for background errors.
+func (s *Store) Logger() *slog.Logger { return s.logger }
+
 // PgxPool returns the underlying pgxpool.Pool for use with stdlib adapters.
 func (s *Store) PgxPool() *pgxpool.Pool { return s.pool.PgxPool() }
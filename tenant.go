@@ -0,0 +1,26 @@
+package whisker
+
+import "context"
+
+// TenantSetting is the PostgreSQL setting that carries the current tenant ID.
+// The store sets it on the connection of every statement, in a session or
+// not, to the tenant of the statement's context, and resets it without one,
+// so row-level security policies can reference
+// current_setting('whisker.tenant_id', true).
+const TenantSetting = "whisker.tenant_id"
+
+type tenantKey struct{}
+
+// WithTenant returns a context carrying the given tenant ID. Statements run
+// with this context scope row-level security policies to the tenant, and
+// collections and event stores opened with tenancy scope their operations
+// to it.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFrom returns the tenant ID carried by ctx, if any.
+func TenantFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && id != ""
+}
@@ -0,0 +1,20 @@
+package whisker
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTenantFrom(t *testing.T) {
+	if _, ok := TenantFrom(context.Background()); ok {
+		t.Fatal("expected no tenant on empty context")
+	}
+	ctx := WithTenant(context.Background(), "acme")
+	id, ok := TenantFrom(ctx)
+	if !ok || id != "acme" {
+		t.Errorf("got (%q, %v), want (acme, true)", id, ok)
+	}
+	if _, ok := TenantFrom(WithTenant(context.Background(), "")); ok {
+		t.Error("expected empty tenant to be ignored")
+	}
+}
@@ -0,0 +1,80 @@
+package whiskertest
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/documents"
+	"github.com/ripkitten-co/whisker/events"
+)
+
+// Seed inserts the documents in the JSON array at path into the named
+// collection and returns them. Each document must carry its ID.
+func Seed[T any](t testing.TB, b whisker.Backend, collection, path string) []*T {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("whiskertest: seed %s: %v", collection, err)
+	}
+	var docs []*T
+	if err := json.Unmarshal(raw, &docs); err != nil {
+		t.Fatalf("whiskertest: seed %s: parse %s: %v", collection, path, err)
+	}
+	col := documents.Collection[T](b, collection)
+	for i, doc := range docs {
+		if err := col.Insert(context.Background(), doc); err != nil {
+			t.Fatalf("whiskertest: seed %s: document %d: %v", collection, i, err)
+		}
+	}
+	return docs
+}
+
+// StreamBuilder builds the events of one stream for a test fixture.
+type StreamBuilder struct {
+	streamID string
+	evts     []events.Event
+	err      error
+}
+
+// Stream starts a fixture for the given stream.
+func Stream(streamID string) *StreamBuilder {
+	return &StreamBuilder{streamID: streamID}
+}
+
+// Event adds an event whose data is data marshaled as JSON. Returns the
+// builder for method chaining.
+func (s *StreamBuilder) Event(eventType string, data any) *StreamBuilder {
+	raw, err := json.Marshal(data)
+	if err != nil && s.err == nil {
+		s.err = err
+	}
+	s.evts = append(s.evts, events.Event{
+		StreamID: s.streamID,
+		Version:  len(s.evts) + 1,
+		Type:     eventType,
+		Data:     raw,
+	})
+	return s
+}
+
+// Events returns the built events with stream IDs and versions filled in, for
+// feeding a projection or handler directly.
+func (s *StreamBuilder) Events(t testing.TB) []events.Event {
+	t.Helper()
+	if s.err != nil {
+		t.Fatalf("whiskertest: stream %s: marshal event data: %v", s.streamID, s.err)
+	}
+	return s.evts
+}
+
+// Append writes the built events as a new stream.
+func (s *StreamBuilder) Append(t testing.TB, b whisker.Backend) {
+	t.Helper()
+	evts := s.Events(t)
+	if err := events.New(b).Append(context.Background(), s.streamID, 0, evts); err != nil {
+		t.Fatalf("whiskertest: append stream %s: %v", s.streamID, err)
+	}
+}
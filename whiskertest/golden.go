@@ -0,0 +1,59 @@
+package whiskertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ripkitten-co/whisker"
+	"github.com/ripkitten-co/whisker/documents"
+)
+
+// UpdateGoldenEnv names the environment variable that, when set to 1, makes
+// golden assertions rewrite their files instead of comparing.
+const UpdateGoldenEnv = "WHISKERTEST_UPDATE"
+
+// AssertGolden loads every document of the named collection, ordered by ID,
+// and compares them as indented JSON with the golden file at path. Works for
+// projection read models too, since they are stored as collections. Run with
+// WHISKERTEST_UPDATE=1 to write the current contents to the file.
+func AssertGolden[T any](t testing.TB, b whisker.Backend, collection, path string) {
+	t.Helper()
+	docs, err := documents.Collection[T](b, collection).Query().
+		OrderBy("id", documents.Asc).
+		Execute(context.Background())
+	if err != nil {
+		t.Fatalf("whiskertest: golden %s: load: %v", collection, err)
+	}
+	if docs == nil {
+		docs = []*T{}
+	}
+	got, err := json.MarshalIndent(docs, "", "  ")
+	if err != nil {
+		t.Fatalf("whiskertest: golden %s: marshal: %v", collection, err)
+	}
+	assertGoldenBytes(t, path, append(got, '\n'))
+}
+
+func assertGoldenBytes(t testing.TB, path string, got []byte) {
+	t.Helper()
+	if os.Getenv(UpdateGoldenEnv) == "1" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("whiskertest: update golden %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("whiskertest: update golden %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("whiskertest: read golden %s: %v (run with %s=1 to create it)", path, err, UpdateGoldenEnv)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("whiskertest: %s mismatch\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+	}
+}
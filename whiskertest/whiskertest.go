@@ -0,0 +1,94 @@
+// Package whiskertest provides helpers for integration tests against Whisker:
+// an isolated schema per test on a shared PostgreSQL server, collection
+// seeding from JSON fixtures, event fixture builders, and golden-file
+// assertions for collections and projection read models.
+package whiskertest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ripkitten-co/whisker"
+)
+
+// DatabaseURLEnv names the environment variable read by DatabaseURL.
+const DatabaseURLEnv = "WHISKER_TEST_DATABASE_URL"
+
+// DatabaseURL returns the connection string in WHISKER_TEST_DATABASE_URL and
+// skips the test when it is unset.
+func DatabaseURL(t testing.TB) string {
+	t.Helper()
+	connString := os.Getenv(DatabaseURLEnv)
+	if connString == "" {
+		t.Skipf("whiskertest: %s not set", DatabaseURLEnv)
+	}
+	return connString
+}
+
+// NewStore creates a schema with a random name on the server at connString
+// and returns a Store whose connections use it as their search_path, so every
+// Whisker table the test creates is private to it. The schema is dropped and
+// the store closed when the test finishes.
+func NewStore(t testing.TB, connString string, opts ...whisker.Option) *whisker.Store {
+	t.Helper()
+	ctx := context.Background()
+
+	schema := "whiskertest_" + randomSuffix(t)
+	admin, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		t.Fatalf("whiskertest: connect: %v", err)
+	}
+	defer admin.Close(ctx)
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("whiskertest: create schema %s: %v", schema, err)
+	}
+
+	t.Cleanup(func() {
+		conn, err := pgx.Connect(ctx, connString)
+		if err != nil {
+			t.Logf("whiskertest: drop schema %s: %v", schema, err)
+			return
+		}
+		defer conn.Close(ctx)
+		if _, err := conn.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Logf("whiskertest: drop schema %s: %v", schema, err)
+		}
+	})
+
+	store, err := whisker.New(ctx, withSearchPath(connString, schema), opts...)
+	if err != nil {
+		t.Fatalf("whiskertest: create store: %v", err)
+	}
+	t.Cleanup(store.Close)
+	return store
+}
+
+// withSearchPath adds a search_path runtime parameter to a URL or key/value
+// connection string.
+func withSearchPath(connString, schema string) string {
+	if strings.HasPrefix(connString, "postgres://") || strings.HasPrefix(connString, "postgresql://") {
+		u, err := url.Parse(connString)
+		if err == nil {
+			q := u.Query()
+			q.Set("search_path", schema)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return strings.TrimSpace(connString + " search_path=" + schema)
+}
+
+func randomSuffix(t testing.TB) string {
+	t.Helper()
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("whiskertest: random schema name: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,71 @@
+//go:build integration
+
+package whiskertest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ripkitten-co/whisker/documents"
+	"github.com/ripkitten-co/whisker/events"
+	"github.com/ripkitten-co/whisker/internal/testutil"
+	"github.com/ripkitten-co/whisker/projections"
+	"github.com/ripkitten-co/whisker/whiskertest"
+)
+
+type User struct {
+	ID      string
+	Name    string
+	Version int
+}
+
+type OrderCount struct {
+	ID      string
+	Orders  int
+	Version int
+}
+
+func TestNewStore_IsolatesSchemas(t *testing.T) {
+	connStr := testutil.SetupPostgres(t)
+	ctx := context.Background()
+
+	a := whiskertest.NewStore(t, connStr)
+	b := whiskertest.NewStore(t, connStr)
+
+	whiskertest.Seed[User](t, a, "users", "testdata/users.json")
+
+	n, err := documents.Collection[User](b, "users").Count(ctx)
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("second store sees %d users, want 0", n)
+	}
+
+	whiskertest.AssertGolden[User](t, a, "users", "testdata/users.golden.json")
+}
+
+func TestAssertGolden_ReadModel(t *testing.T) {
+	store := whiskertest.NewStore(t, testutil.SetupPostgres(t))
+	ctx := context.Background()
+
+	stream := whiskertest.Stream("customer-1").
+		Event("OrderPlaced", map[string]int{"total": 10}).
+		Event("OrderPlaced", map[string]int{"total": 25})
+	stream.Append(t, store)
+
+	proj := projections.New[OrderCount](store, "order_counts").
+		On("OrderPlaced", func(ctx context.Context, evt events.Event, state *OrderCount) (*OrderCount, error) {
+			if state == nil {
+				state = &OrderCount{}
+			}
+			state.Orders++
+			return state, nil
+		})
+	ps := projections.NewProcessingStoreFromBackend(store, "order_counts")
+	if err := proj.Process(ctx, stream.Events(t), ps); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	whiskertest.AssertGolden[OrderCount](t, store, "order_counts", "testdata/order_counts.golden.json")
+}
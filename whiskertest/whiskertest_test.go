@@ -0,0 +1,74 @@
+package whiskertest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithSearchPath(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"url", "postgres://u:p@localhost:5432/db?sslmode=disable", "postgres://u:p@localhost:5432/db?search_path=s1&sslmode=disable"},
+		{"url without query", "postgresql://localhost/db", "postgresql://localhost/db?search_path=s1"},
+		{"key value", "host=localhost dbname=db", "host=localhost dbname=db search_path=s1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withSearchPath(tt.in, "s1"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamBuilder(t *testing.T) {
+	evts := Stream("order-1").
+		Event("OrderPlaced", map[string]int{"total": 10}).
+		Event("OrderShipped", nil).
+		Events(t)
+
+	if len(evts) != 2 {
+		t.Fatalf("got %d events, want 2", len(evts))
+	}
+	for i, evt := range evts {
+		if evt.StreamID != "order-1" || evt.Version != i+1 {
+			t.Errorf("event %d: got stream %q version %d", i, evt.StreamID, evt.Version)
+		}
+	}
+	if string(evts[0].Data) != `{"total":10}` {
+		t.Errorf("data: got %s", evts[0].Data)
+	}
+}
+
+func TestAssertGoldenBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "golden.json")
+
+	t.Setenv(UpdateGoldenEnv, "1")
+	assertGoldenBytes(t, path, []byte("[]\n"))
+	if got, err := os.ReadFile(path); err != nil || string(got) != "[]\n" {
+		t.Fatalf("update: got %q, %v", got, err)
+	}
+
+	t.Setenv(UpdateGoldenEnv, "")
+	assertGoldenBytes(t, path, []byte("[]\n"))
+
+	rec := &recordingTB{TB: t}
+	assertGoldenBytes(rec, path, []byte("[1]\n"))
+	if !rec.failed {
+		t.Error("expected mismatch to fail")
+	}
+}
+
+// recordingTB captures failures instead of reporting them.
+type recordingTB struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recordingTB) Helper()               {}
+func (r *recordingTB) Errorf(string, ...any) { r.failed = true }
+func (r *recordingTB) Fatalf(string, ...any) { r.failed = true }